@@ -16,8 +16,11 @@ package bgmgr
 
 import (
 	"github.com/finogeeks/ligase/bgmgr/devicemgr"
+	"github.com/finogeeks/ligase/bgmgr/retentionmgr"
+	"github.com/finogeeks/ligase/bgmgr/searchmgr"
 	"github.com/finogeeks/ligase/bgmgr/txnmgr"
 	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
 	"github.com/finogeeks/ligase/common/filter"
 	"github.com/finogeeks/ligase/model/service"
 	"github.com/finogeeks/ligase/skunkworks/log"
@@ -25,10 +28,12 @@ import (
 )
 
 func SetupBgMgrComponent(
+	cfg *config.Dendrite,
 	deviceDB model.DeviceDatabase,
 	cache service.Cache,
 	encryptDB model.EncryptorAPIDatabase,
 	syncDB model.SyncAPIDatabase,
+	roomDB model.RoomServerDatabase,
 	rpcCli *common.RpcClient,
 	tokenFilter *filter.Filter,
 	scanUnActive int64,
@@ -39,4 +44,8 @@ func SetupBgMgrComponent(
 	deviceMgr.Start()
 	txnMgr := txnmgr.NewTxnMgr(cache)
 	txnMgr.Start()
+	retentionMgr := retentionmgr.NewRetentionMgr(cfg, roomDB, syncDB, cache, rpcCli)
+	retentionMgr.Start()
+	searchMgr := searchmgr.NewSearchMgr(cfg, syncDB)
+	searchMgr.Start()
 }
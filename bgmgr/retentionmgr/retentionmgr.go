@@ -0,0 +1,129 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package retentionmgr
+
+import (
+	"context"
+	"time"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+	jsoniter "github.com/json-iterator/go"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// RetentionMgr purges the events older than the retention lifetime of their
+// room. The sync servers hide them as soon as they expire, the purge frees
+// the storage.
+type RetentionMgr struct {
+	cfg    *config.Dendrite
+	roomDB model.RoomServerDatabase
+	syncDB model.SyncAPIDatabase
+	cache  service.Cache
+	rpcCli *common.RpcClient
+}
+
+func NewRetentionMgr(
+	cfg *config.Dendrite,
+	roomDB model.RoomServerDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	rpcCli *common.RpcClient,
+) *RetentionMgr {
+	rm := new(RetentionMgr)
+	rm.cfg = cfg
+	rm.roomDB = roomDB
+	rm.syncDB = syncDB
+	rm.cache = cache
+	rm.rpcCli = rpcCli
+	return rm
+}
+
+func (rm *RetentionMgr) Start() {
+	if !rm.cfg.Retention.Enable {
+		return
+	}
+	go func() {
+		t := time.NewTicker(time.Millisecond * time.Duration(rm.cfg.Retention.PurgeInterval))
+		for {
+			select {
+			case <-t.C:
+				func() {
+					span, ctx := common.StartSobSomSpan(context.Background(), "RetentionMgr.Start")
+					defer span.Finish()
+					rm.purgeExpiredEvents(ctx)
+				}()
+			}
+		}
+	}()
+}
+
+func (rm *RetentionMgr) purgeExpiredEvents(ctx context.Context) {
+	policies, err := rm.loadPolicies(ctx)
+	if err != nil {
+		log.Errorf("RetentionMgr load policies err:%v", err)
+		return
+	}
+
+	now := time.Now().UnixNano() / 1000000
+	for roomID, policy := range policies {
+		lifetime := common.RetentionMaxLifetime(rm.cfg, policy)
+		if lifetime <= 0 {
+			continue
+		}
+		purged, err := common.PurgeRoomHistory(ctx, roomID, now-lifetime, rm.roomDB, rm.syncDB, rm.cache, rm.rpcCli)
+		if err != nil {
+			log.Errorf("RetentionMgr purge room:%s err:%v", roomID, err)
+			continue
+		}
+		if purged > 0 {
+			log.Infof("RetentionMgr purged %d events of room:%s older than %d ms", purged, roomID, lifetime)
+		}
+	}
+}
+
+// loadPolicies returns the m.room.retention of the rooms to purge, nil for
+// the rooms only the server defaults apply to
+func (rm *RetentionMgr) loadPolicies(ctx context.Context) (map[string]*common.RetentionContent, error) {
+	events, err := rm.syncDB.SelectCurrentStateByType(ctx, "m.room.retention")
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[string]*common.RetentionContent, len(events))
+	for _, ev := range events {
+		policy := common.RetentionContent{}
+		if err := json.Unmarshal(ev.Content, &policy); err != nil {
+			log.Warnf("RetentionMgr room:%s bad retention %s err:%v", ev.RoomID, ev.Content, err)
+		}
+		policies[ev.RoomID] = &policy
+	}
+
+	if rm.cfg.Retention.DefaultMaxLifetime > 0 {
+		roomIDs, err := rm.syncDB.GetAllSyncRooms()
+		if err != nil {
+			return nil, err
+		}
+		for _, roomID := range roomIDs {
+			if _, ok := policies[roomID]; !ok {
+				policies[roomID] = nil
+			}
+		}
+	}
+	return policies, nil
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package searchmgr
+
+import (
+	"context"
+	"math"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// events read from the stream at a time
+const reindexBatchSize = 500
+
+// SearchMgr rebuilds the /search index from the stored events. The sync
+// writer only indexes the events it writes, the events stored before /search
+// existed or before a tokenizer change are indexed by the reindex.
+type SearchMgr struct {
+	cfg    *config.Dendrite
+	syncDB model.SyncAPIDatabase
+}
+
+func NewSearchMgr(
+	cfg *config.Dendrite,
+	syncDB model.SyncAPIDatabase,
+) *SearchMgr {
+	sm := new(SearchMgr)
+	sm.cfg = cfg
+	sm.syncDB = syncDB
+	return sm
+}
+
+func (sm *SearchMgr) Start() {
+	if !sm.cfg.Search.Reindex {
+		return
+	}
+	go func() {
+		span, ctx := common.StartSobSomSpan(context.Background(), "SearchMgr.Start")
+		defer span.Finish()
+		indexed, err := sm.reindex(ctx)
+		if err != nil {
+			log.Errorf("SearchMgr reindex stopped after %d events err:%v", indexed, err)
+			return
+		}
+		log.Infof("SearchMgr reindexed %d events", indexed)
+	}()
+}
+
+// reindex walks the stream of the indexed event types and indexes every
+// event again, it returns the number of events read.
+func (sm *SearchMgr) reindex(ctx context.Context) (int, error) {
+	types := common.SearchEventTypes()
+	after := int64(math.MinInt64)
+	indexed := 0
+	for {
+		events, offsets, err := sm.syncDB.SelectTypeEventsAfter(ctx, types, after, reindexBatchSize)
+		if err != nil {
+			return indexed, err
+		}
+		for i := range events {
+			if err := common.IndexSearchEvent(ctx, sm.syncDB, &events[i], offsets[i], sm.cfg.Search.Tokenizer); err != nil {
+				log.Errorf("SearchMgr index ev:%s err:%v", events[i].EventID, err)
+			}
+		}
+		indexed += len(events)
+		if len(events) < reindexBatchSize {
+			return indexed, nil
+		}
+		after = offsets[len(offsets)-1]
+	}
+}
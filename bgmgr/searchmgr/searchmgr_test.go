@@ -0,0 +1,110 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package searchmgr
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// streamSyncDB serves the stream from events and records what gets indexed
+type streamSyncDB struct {
+	model.SyncAPIDatabase
+	events  []gomatrixserverlib.ClientEvent
+	offsets []int64
+	indexed map[string][]string
+	reads   int
+}
+
+func (db *streamSyncDB) SelectTypeEventsAfter(
+	ctx context.Context, typ []string, after int64, limit int,
+) ([]gomatrixserverlib.ClientEvent, []int64, error) {
+	db.reads++
+	types := map[string]bool{}
+	for _, t := range typ {
+		types[t] = true
+	}
+	var events []gomatrixserverlib.ClientEvent
+	var offsets []int64
+	for i, ev := range db.events {
+		if db.offsets[i] > after && types[ev.Type] && len(events) < limit {
+			events = append(events, ev)
+			offsets = append(offsets, db.offsets[i])
+		}
+	}
+	return events, offsets, nil
+}
+
+func (db *streamSyncDB) UpsertEventSearch(
+	ctx context.Context, eventID, roomID, sender, eventType, key string, ts, offset int64, lexemes []string,
+) error {
+	db.indexed[eventID] = lexemes
+	return nil
+}
+
+func TestReindex(t *testing.T) {
+	db := &streamSyncDB{indexed: map[string][]string{}}
+	add := func(offset int64, typ, content string) {
+		db.events = append(db.events, gomatrixserverlib.ClientEvent{
+			EventID: fmt.Sprintf("$%d:a", offset),
+			RoomID:  "!r:a",
+			Sender:  "@u:a",
+			Type:    typ,
+			Content: []byte(content),
+		})
+		db.offsets = append(db.offsets, offset)
+	}
+	// a backfilled message, more messages than a batch, a redacted message
+	// and a member event which isn't indexed
+	add(-3, "m.room.message", `{"body":"backfilled hello"}`)
+	for i := int64(1); i <= reindexBatchSize; i++ {
+		add(i, "m.room.message", `{"body":"hello"}`)
+	}
+	add(reindexBatchSize+1, "m.room.message", `{}`)
+	add(reindexBatchSize+2, "m.room.member", `{"membership":"join","displayname":"hello"}`)
+	add(reindexBatchSize+3, "m.room.topic", `{"topic":"world"}`)
+
+	cfg := &config.Dendrite{}
+	cfg.Search.Tokenizer = "simple"
+	cfg.Search.Reindex = true
+	sm := NewSearchMgr(cfg, db)
+	read, err := sm.reindex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != reindexBatchSize+3 {
+		t.Fatalf("read %d events, want %d", read, reindexBatchSize+3)
+	}
+	if db.reads != 2 {
+		t.Fatalf("stream read %d times, want 2", db.reads)
+	}
+	if len(db.indexed) != reindexBatchSize+2 {
+		t.Fatalf("indexed %d events, want %d", len(db.indexed), reindexBatchSize+2)
+	}
+	if lexemes := db.indexed["$-3:a"]; len(lexemes) != 2 {
+		t.Fatalf("backfilled message lexemes = %v, want backfilled and hello", lexemes)
+	}
+	if _, ok := db.indexed[fmt.Sprintf("$%d:a", reindexBatchSize+1)]; ok {
+		t.Fatal("the redacted message was indexed")
+	}
+	if lexemes := db.indexed[fmt.Sprintf("$%d:a", reindexBatchSize+3)]; len(lexemes) != 1 || lexemes[0] != "world" {
+		t.Fatalf("topic lexemes = %v, want [world]", lexemes)
+	}
+}
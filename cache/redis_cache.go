@@ -191,6 +191,91 @@ func (rc *RedisCache) ExpirePwdChangeDevice(userID string) error {
 	return nil
 }
 
+func (rc *RedisCache) GetLoginFailCount(userID string) (int64, error) {
+	key := fmt.Sprintf("%s:%s", "login_fail", userID)
+	count, err := redis.Int64(rc.SafeDo("get", key))
+	if err == redis.ErrNil {
+		return 0, nil
+	}
+	return count, err
+}
+
+func (rc *RedisCache) IncrLoginFailCount(userID string, expire int64) (int64, error) {
+	conn := rc.pool().Get()
+	defer conn.Close()
+	key := fmt.Sprintf("%s:%s", "login_fail", userID)
+	count, err := redis.Int64(conn.Do("incr", key))
+	if err != nil {
+		return 0, err
+	}
+	_, err = conn.Do("expire", key, expire)
+	return count, err
+}
+
+func (rc *RedisCache) DelLoginFailCount(userID string) error {
+	key := fmt.Sprintf("%s:%s", "login_fail", userID)
+	_, err := rc.SafeDo("del", key)
+	return err
+}
+
+func (rc *RedisCache) SetSSOSession(state, session string, expire int64) error {
+	key := fmt.Sprintf("%s:%s", "sso_session", state)
+	_, err := rc.SafeDo("set", key, session, "EX", expire)
+	return err
+}
+
+// TakeSSOSession returns the session stored for state and removes it, so that
+// a state can only be used once. It returns "" if there is no such session.
+func (rc *RedisCache) TakeSSOSession(state string) (string, error) {
+	return rc.takeOnce(fmt.Sprintf("%s:%s", "sso_session", state))
+}
+
+func (rc *RedisCache) SetLoginToken(token, userID string, expire int64) error {
+	key := fmt.Sprintf("%s:%s", "login_token", token)
+	_, err := rc.SafeDo("set", key, userID, "EX", expire)
+	return err
+}
+
+// TakeLoginToken returns the user of a login token and removes the token. It
+// returns "" if the token is unknown, expired or already used.
+func (rc *RedisCache) TakeLoginToken(token string) (string, error) {
+	return rc.takeOnce(fmt.Sprintf("%s:%s", "login_token", token))
+}
+
+func (rc *RedisCache) SetOpenIDToken(token, userID string, expire int64) error {
+	key := fmt.Sprintf("%s:%s", "openid_token", token)
+	_, err := rc.SafeDo("set", key, userID, "EX", expire)
+	return err
+}
+
+// GetOpenIDToken returns the user of an OpenID token, or "" if the token is
+// unknown or expired. Unlike login tokens they can be checked many times.
+func (rc *RedisCache) GetOpenIDToken(token string) (string, error) {
+	userID, err := redis.String(rc.SafeDo("get", fmt.Sprintf("%s:%s", "openid_token", token)))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	return userID, err
+}
+
+func (rc *RedisCache) takeOnce(key string) (string, error) {
+	conn := rc.pool().Get()
+	defer conn.Close()
+	val, err := redis.String(conn.Do("get", key))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	// only the caller which actually deletes the key gets the value
+	deleted, err := redis.Int64(conn.Do("del", key))
+	if err != nil || deleted == 0 {
+		return "", err
+	}
+	return val, nil
+}
+
 func (rc *RedisCache) GetPushRuleEnabled(userID, ruleID string) (string, bool) {
 	context, err := redis.String(rc.SafeDo("hget", fmt.Sprintf("%s:%s:%s", "push_rule_enable", userID, ruleID), "enabled"))
 	if err != nil {
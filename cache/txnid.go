@@ -54,6 +54,10 @@ func (rc *RedisCache) PutTxnID(roomID, txnID, eventID string) error {
 	return rc.HSet(key, txnID, fmt.Sprintf("%d:%s", time.Now().Unix(), eventID))
 }
 
+func (rc *RedisCache) DelTxnID(roomID string) error {
+	return rc.Del(fmt.Sprintf("msgid:%s", roomID))
+}
+
 func (rc *RedisCache) ScanTxnID(cursor uint64, count int) ([]string, uint64, error) {
 	match := "msgid:*"
 	rs, next, err := rc.Scan(cursor, match, count)
@@ -0,0 +1,589 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/finogeeks/ligase/clientapi/routing"
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/apiconsumer"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/plugins/message/internals"
+)
+
+// The admin API is served under /_ligase/admin/v1 and only answers requests
+// carrying the token returned by ReqGetSuperAdminToken, which is only served
+// on the internal prefix.
+func init() {
+	apiconsumer.SetAPIProcessor(ReqGetAdminUsers{})
+	apiconsumer.SetAPIProcessor(ReqGetAdminUserDevices{})
+	apiconsumer.SetAPIProcessor(ReqPostAdminUserLogout{})
+	apiconsumer.SetAPIProcessor(ReqPutAdminUserLock{})
+	apiconsumer.SetAPIProcessor(ReqGetAdminRooms{})
+	apiconsumer.SetAPIProcessor(ReqGetAdminRoomState{})
+	apiconsumer.SetAPIProcessor(ReqPostAdminRoomMembership{})
+	apiconsumer.SetAPIProcessor(ReqPostAdminRegistrationToken{})
+	apiconsumer.SetAPIProcessor(ReqGetAdminRegistrationTokens{})
+	apiconsumer.SetAPIProcessor(ReqDelAdminRegistrationToken{})
+	apiconsumer.SetAPIProcessor(ReqGetAdminEventReports{})
+	apiconsumer.SetAPIProcessor(ReqGetAdminEventReport{})
+	apiconsumer.SetAPIProcessor(ReqPutAdminEventReportAssign{})
+	apiconsumer.SetAPIProcessor(ReqPostAdminEventReportResolve{})
+	apiconsumer.SetAPIProcessor(ReqPostAdminPurgeHistory{})
+	apiconsumer.SetAPIProcessor(ReqDelAdminRoom{})
+}
+
+// parsePage reads the from and limit query parameters, which are optional
+func parsePage(query url.Values) (from, limit int64, err error) {
+	if v := query.Get("from"); v != "" {
+		if from, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return
+		}
+	}
+	return
+}
+
+type ReqGetAdminUsers struct{}
+
+func (ReqGetAdminUsers) GetRoute() string                     { return "/users" }
+func (ReqGetAdminUsers) GetMetricsName() string               { return "admin_list_users" }
+func (ReqGetAdminUsers) GetMsgType() int32                    { return internals.MSG_GET_ADMIN_USERS }
+func (ReqGetAdminUsers) GetAPIType() int8                     { return apiconsumer.APITypeAuth }
+func (ReqGetAdminUsers) GetMethod() []string                  { return []string{http.MethodGet, http.MethodOptions} }
+func (ReqGetAdminUsers) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqGetAdminUsers) GetPrefix() []string                  { return []string{"admin"} }
+func (ReqGetAdminUsers) NewRequest() core.Coder               { return new(external.GetAdminUsersRequest) }
+func (ReqGetAdminUsers) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetAdminUsersRequest)
+	query := req.URL.Query()
+	msg.Search = query.Get("search")
+	var err error
+	msg.From, msg.Limit, err = parsePage(query)
+	return err
+}
+func (ReqGetAdminUsers) NewResponse(code int) core.Coder {
+	return new(external.GetAdminUsersResponse)
+}
+func (ReqGetAdminUsers) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetAdminUsersRequest)
+	return routing.AdminListUsers(ctx, req, device, c.accountDB)
+}
+
+type ReqGetAdminUserDevices struct{}
+
+func (ReqGetAdminUserDevices) GetRoute() string       { return "/users/{userID}/devices" }
+func (ReqGetAdminUserDevices) GetMetricsName() string { return "admin_list_user_devices" }
+func (ReqGetAdminUserDevices) GetMsgType() int32      { return internals.MSG_GET_ADMIN_USER_DEVICES }
+func (ReqGetAdminUserDevices) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqGetAdminUserDevices) GetMethod() []string {
+	return []string{http.MethodGet, http.MethodOptions}
+}
+func (ReqGetAdminUserDevices) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqGetAdminUserDevices) GetPrefix() []string                  { return []string{"admin"} }
+func (ReqGetAdminUserDevices) NewRequest() core.Coder {
+	return new(external.GetAdminUserDevicesRequest)
+}
+func (ReqGetAdminUserDevices) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetAdminUserDevicesRequest)
+	if vars != nil {
+		msg.UserID = vars["userID"]
+	}
+	return nil
+}
+func (ReqGetAdminUserDevices) NewResponse(code int) core.Coder {
+	return new(external.GetAdminUserDevicesResponse)
+}
+func (ReqGetAdminUserDevices) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetAdminUserDevicesRequest)
+	return routing.AdminGetUserDevices(req, device, c.cacheIn)
+}
+
+type ReqPostAdminUserLogout struct{}
+
+func (ReqPostAdminUserLogout) GetRoute() string       { return "/users/{userID}/logout" }
+func (ReqPostAdminUserLogout) GetMetricsName() string { return "admin_logout_user" }
+func (ReqPostAdminUserLogout) GetMsgType() int32      { return internals.MSG_POST_ADMIN_USER_LOGOUT }
+func (ReqPostAdminUserLogout) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqPostAdminUserLogout) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostAdminUserLogout) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostAdminUserLogout) GetPrefix() []string                  { return []string{"admin"} }
+func (ReqPostAdminUserLogout) NewRequest() core.Coder {
+	return new(external.PostAdminUserLogoutRequest)
+}
+func (ReqPostAdminUserLogout) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostAdminUserLogoutRequest)
+	if err := common.UnmarshalJSON(req, msg); err != nil {
+		return err
+	}
+	if vars != nil {
+		msg.UserID = vars["userID"]
+	}
+	return nil
+}
+func (ReqPostAdminUserLogout) NewResponse(code int) core.Coder {
+	return new(external.PostAdminUserLogoutResponse)
+}
+func (ReqPostAdminUserLogout) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAdminUserLogoutRequest)
+	return routing.AdminLogoutUser(
+		ctx, req, device, c.deviceDB, c.cacheIn, c.encryptDB, c.syncDB, c.tokenFilter, c.RpcCli,
+	)
+}
+
+type ReqPutAdminUserLock struct{}
+
+func (ReqPutAdminUserLock) GetRoute() string       { return "/users/{userID}/lock" }
+func (ReqPutAdminUserLock) GetMetricsName() string { return "admin_lock_user" }
+func (ReqPutAdminUserLock) GetMsgType() int32      { return internals.MSG_PUT_ADMIN_USER_LOCK }
+func (ReqPutAdminUserLock) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqPutAdminUserLock) GetMethod() []string {
+	return []string{http.MethodPut, http.MethodOptions}
+}
+func (ReqPutAdminUserLock) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPutAdminUserLock) GetPrefix() []string                  { return []string{"admin"} }
+func (ReqPutAdminUserLock) NewRequest() core.Coder {
+	return new(external.PutAdminUserLockRequest)
+}
+func (ReqPutAdminUserLock) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PutAdminUserLockRequest)
+	if err := common.UnmarshalJSON(req, msg); err != nil {
+		return err
+	}
+	if vars != nil {
+		msg.UserID = vars["userID"]
+	}
+	return nil
+}
+func (ReqPutAdminUserLock) NewResponse(code int) core.Coder {
+	return new(external.PutAdminUserLockResponse)
+}
+func (ReqPutAdminUserLock) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PutAdminUserLockRequest)
+	return routing.AdminLockUser(
+		ctx, req, device, &c.Cfg, c.accountDB, c.deviceDB, c.cacheIn,
+		c.encryptDB, c.syncDB, c.tokenFilter, c.RpcCli,
+	)
+}
+
+type ReqGetAdminRooms struct{}
+
+func (ReqGetAdminRooms) GetRoute() string                     { return "/rooms" }
+func (ReqGetAdminRooms) GetMetricsName() string               { return "admin_list_rooms" }
+func (ReqGetAdminRooms) GetMsgType() int32                    { return internals.MSG_GET_ADMIN_ROOMS }
+func (ReqGetAdminRooms) GetAPIType() int8                     { return apiconsumer.APITypeAuth }
+func (ReqGetAdminRooms) GetMethod() []string                  { return []string{http.MethodGet, http.MethodOptions} }
+func (ReqGetAdminRooms) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqGetAdminRooms) GetPrefix() []string                  { return []string{"admin"} }
+func (ReqGetAdminRooms) NewRequest() core.Coder               { return new(external.GetAdminRoomsRequest) }
+func (ReqGetAdminRooms) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetAdminRoomsRequest)
+	var err error
+	msg.From, msg.Limit, err = parsePage(req.URL.Query())
+	return err
+}
+func (ReqGetAdminRooms) NewResponse(code int) core.Coder {
+	return new(external.GetAdminRoomsResponse)
+}
+func (ReqGetAdminRooms) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetAdminRoomsRequest)
+	return routing.AdminListRooms(ctx, req, device, c.roomDB)
+}
+
+type ReqGetAdminRoomState struct{}
+
+func (ReqGetAdminRoomState) GetRoute() string       { return "/rooms/{roomID}/state" }
+func (ReqGetAdminRoomState) GetMetricsName() string { return "admin_room_state" }
+func (ReqGetAdminRoomState) GetMsgType() int32      { return internals.MSG_GET_ADMIN_ROOM_STATE }
+func (ReqGetAdminRoomState) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqGetAdminRoomState) GetMethod() []string {
+	return []string{http.MethodGet, http.MethodOptions}
+}
+func (ReqGetAdminRoomState) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqGetAdminRoomState) GetPrefix() []string                  { return []string{"admin"} }
+func (ReqGetAdminRoomState) NewRequest() core.Coder {
+	return new(external.GetAdminRoomStateRequest)
+}
+func (ReqGetAdminRoomState) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetAdminRoomStateRequest)
+	if vars != nil {
+		msg.RoomID = vars["roomID"]
+	}
+	return nil
+}
+func (ReqGetAdminRoomState) NewResponse(code int) core.Coder {
+	return new(external.GetAdminRoomStateResponse)
+}
+func (ReqGetAdminRoomState) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetAdminRoomStateRequest)
+	return routing.AdminGetRoomState(ctx, req, device, c.roomDB)
+}
+
+type ReqPostAdminRoomMembership struct{}
+
+func (ReqPostAdminRoomMembership) GetRoute() string {
+	return "/rooms/{roomID}/{membership:(?:join|leave)}"
+}
+func (ReqPostAdminRoomMembership) GetMetricsName() string { return "admin_room_membership" }
+func (ReqPostAdminRoomMembership) GetMsgType() int32      { return internals.MSG_POST_ADMIN_ROOM_MEMBER }
+func (ReqPostAdminRoomMembership) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqPostAdminRoomMembership) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostAdminRoomMembership) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostAdminRoomMembership) GetPrefix() []string                  { return []string{"admin"} }
+func (ReqPostAdminRoomMembership) NewRequest() core.Coder {
+	return new(external.PostAdminRoomMembershipRequest)
+}
+func (ReqPostAdminRoomMembership) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostAdminRoomMembershipRequest)
+	if err := common.UnmarshalJSON(req, msg); err != nil {
+		return err
+	}
+	if vars != nil {
+		msg.RoomID = vars["roomID"]
+		msg.Membership = vars["membership"]
+	}
+	return nil
+}
+func (ReqPostAdminRoomMembership) NewResponse(code int) core.Coder {
+	return new(external.PostRoomsJoinByAliasResponse)
+}
+func (ReqPostAdminRoomMembership) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAdminRoomMembershipRequest)
+	return routing.AdminUpdateRoomMembership(
+		ctx, req, device, &c.Cfg, c.accountDB, c.federation, c.rsRpcCli,
+		c.keyRing, c.cacheIn, c.idg, c.complexCache,
+	)
+}
+
+type ReqPostAdminRegistrationToken struct{}
+
+func (ReqPostAdminRegistrationToken) GetRoute() string { return "/registration_tokens/new" }
+func (ReqPostAdminRegistrationToken) GetMetricsName() string {
+	return "admin_create_registration_token"
+}
+func (ReqPostAdminRegistrationToken) GetMsgType() int32 { return internals.MSG_POST_ADMIN_REG_TOKEN }
+func (ReqPostAdminRegistrationToken) GetAPIType() int8  { return apiconsumer.APITypeAuth }
+func (ReqPostAdminRegistrationToken) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostAdminRegistrationToken) GetTopic(cfg *config.Dendrite) string {
+	return getProxyRpcTopic(cfg)
+}
+func (ReqPostAdminRegistrationToken) GetPrefix() []string { return []string{"admin"} }
+func (ReqPostAdminRegistrationToken) NewRequest() core.Coder {
+	return new(external.PostAdminRegistrationTokenRequest)
+}
+func (ReqPostAdminRegistrationToken) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostAdminRegistrationTokenRequest)
+	return common.UnmarshalJSON(req, msg)
+}
+func (ReqPostAdminRegistrationToken) NewResponse(code int) core.Coder {
+	return new(external.RegistrationToken)
+}
+func (ReqPostAdminRegistrationToken) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAdminRegistrationTokenRequest)
+	return routing.AdminCreateRegistrationToken(ctx, req, device, c.accountDB)
+}
+
+type ReqGetAdminRegistrationTokens struct{}
+
+func (ReqGetAdminRegistrationTokens) GetRoute() string       { return "/registration_tokens" }
+func (ReqGetAdminRegistrationTokens) GetMetricsName() string { return "admin_list_registration_tokens" }
+func (ReqGetAdminRegistrationTokens) GetMsgType() int32      { return internals.MSG_GET_ADMIN_REG_TOKENS }
+func (ReqGetAdminRegistrationTokens) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqGetAdminRegistrationTokens) GetMethod() []string {
+	return []string{http.MethodGet, http.MethodOptions}
+}
+func (ReqGetAdminRegistrationTokens) GetTopic(cfg *config.Dendrite) string {
+	return getProxyRpcTopic(cfg)
+}
+func (ReqGetAdminRegistrationTokens) GetPrefix() []string { return []string{"admin"} }
+func (ReqGetAdminRegistrationTokens) NewRequest() core.Coder {
+	return new(external.GetAdminRegistrationTokensRequest)
+}
+func (ReqGetAdminRegistrationTokens) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetAdminRegistrationTokensRequest)
+	msg.Valid = req.URL.Query().Get("valid")
+	return nil
+}
+func (ReqGetAdminRegistrationTokens) NewResponse(code int) core.Coder {
+	return new(external.GetAdminRegistrationTokensResponse)
+}
+func (ReqGetAdminRegistrationTokens) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetAdminRegistrationTokensRequest)
+	return routing.AdminListRegistrationTokens(ctx, req, device, c.accountDB)
+}
+
+type ReqDelAdminRegistrationToken struct{}
+
+func (ReqDelAdminRegistrationToken) GetRoute() string       { return "/registration_tokens/{token}" }
+func (ReqDelAdminRegistrationToken) GetMetricsName() string { return "admin_delete_registration_token" }
+func (ReqDelAdminRegistrationToken) GetMsgType() int32      { return internals.MSG_DEL_ADMIN_REG_TOKEN }
+func (ReqDelAdminRegistrationToken) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqDelAdminRegistrationToken) GetMethod() []string {
+	return []string{http.MethodDelete, http.MethodOptions}
+}
+func (ReqDelAdminRegistrationToken) GetTopic(cfg *config.Dendrite) string {
+	return getProxyRpcTopic(cfg)
+}
+func (ReqDelAdminRegistrationToken) GetPrefix() []string { return []string{"admin"} }
+func (ReqDelAdminRegistrationToken) NewRequest() core.Coder {
+	return new(external.DelAdminRegistrationTokenRequest)
+}
+func (ReqDelAdminRegistrationToken) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.DelAdminRegistrationTokenRequest)
+	if vars != nil {
+		msg.Token = vars["token"]
+	}
+	return nil
+}
+func (ReqDelAdminRegistrationToken) NewResponse(code int) core.Coder { return nil }
+func (ReqDelAdminRegistrationToken) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.DelAdminRegistrationTokenRequest)
+	return routing.AdminDeleteRegistrationToken(ctx, req, device, c.accountDB)
+}
+
+type ReqGetAdminEventReports struct{}
+
+func (ReqGetAdminEventReports) GetRoute() string       { return "/event_reports" }
+func (ReqGetAdminEventReports) GetMetricsName() string { return "admin_list_event_reports" }
+func (ReqGetAdminEventReports) GetMsgType() int32      { return internals.MSG_GET_ADMIN_EVENT_REPORTS }
+func (ReqGetAdminEventReports) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqGetAdminEventReports) GetMethod() []string {
+	return []string{http.MethodGet, http.MethodOptions}
+}
+func (ReqGetAdminEventReports) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqGetAdminEventReports) GetPrefix() []string                  { return []string{"admin"} }
+func (ReqGetAdminEventReports) NewRequest() core.Coder {
+	return new(external.GetAdminEventReportsRequest)
+}
+func (ReqGetAdminEventReports) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetAdminEventReportsRequest)
+	query := req.URL.Query()
+	msg.RoomID = query.Get("room_id")
+	msg.State = query.Get("state")
+	var err error
+	msg.From, msg.Limit, err = parsePage(query)
+	return err
+}
+func (ReqGetAdminEventReports) NewResponse(code int) core.Coder {
+	return new(external.GetAdminEventReportsResponse)
+}
+func (ReqGetAdminEventReports) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetAdminEventReportsRequest)
+	return routing.AdminListEventReports(ctx, req, device, c.roomDB)
+}
+
+type ReqGetAdminEventReport struct{}
+
+func (ReqGetAdminEventReport) GetRoute() string       { return "/event_reports/{reportID}" }
+func (ReqGetAdminEventReport) GetMetricsName() string { return "admin_event_report" }
+func (ReqGetAdminEventReport) GetMsgType() int32      { return internals.MSG_GET_ADMIN_EVENT_REPORT }
+func (ReqGetAdminEventReport) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqGetAdminEventReport) GetMethod() []string {
+	return []string{http.MethodGet, http.MethodOptions}
+}
+func (ReqGetAdminEventReport) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqGetAdminEventReport) GetPrefix() []string                  { return []string{"admin"} }
+func (ReqGetAdminEventReport) NewRequest() core.Coder {
+	return new(external.GetAdminEventReportRequest)
+}
+func (ReqGetAdminEventReport) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetAdminEventReportRequest)
+	if vars != nil {
+		msg.ReportID = vars["reportID"]
+	}
+	return nil
+}
+func (ReqGetAdminEventReport) NewResponse(code int) core.Coder {
+	return new(external.AdminEventReport)
+}
+func (ReqGetAdminEventReport) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetAdminEventReportRequest)
+	return routing.AdminGetEventReport(ctx, req, device, c.roomDB)
+}
+
+type ReqPutAdminEventReportAssign struct{}
+
+func (ReqPutAdminEventReportAssign) GetRoute() string       { return "/event_reports/{reportID}/assign" }
+func (ReqPutAdminEventReportAssign) GetMetricsName() string { return "admin_assign_event_report" }
+func (ReqPutAdminEventReportAssign) GetMsgType() int32 {
+	return internals.MSG_PUT_ADMIN_EVENT_REPORT_ASSIGN
+}
+func (ReqPutAdminEventReportAssign) GetAPIType() int8 { return apiconsumer.APITypeAuth }
+func (ReqPutAdminEventReportAssign) GetMethod() []string {
+	return []string{http.MethodPut, http.MethodOptions}
+}
+func (ReqPutAdminEventReportAssign) GetTopic(cfg *config.Dendrite) string {
+	return getProxyRpcTopic(cfg)
+}
+func (ReqPutAdminEventReportAssign) GetPrefix() []string { return []string{"admin"} }
+func (ReqPutAdminEventReportAssign) NewRequest() core.Coder {
+	return new(external.PutAdminEventReportAssignRequest)
+}
+func (ReqPutAdminEventReportAssign) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PutAdminEventReportAssignRequest)
+	if err := common.UnmarshalJSON(req, msg); err != nil {
+		return err
+	}
+	if vars != nil {
+		msg.ReportID = vars["reportID"]
+	}
+	return nil
+}
+func (ReqPutAdminEventReportAssign) NewResponse(code int) core.Coder { return nil }
+func (ReqPutAdminEventReportAssign) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PutAdminEventReportAssignRequest)
+	return routing.AdminAssignEventReport(ctx, req, device, c.roomDB)
+}
+
+type ReqPostAdminEventReportResolve struct{}
+
+func (ReqPostAdminEventReportResolve) GetRoute() string { return "/event_reports/{reportID}/resolve" }
+func (ReqPostAdminEventReportResolve) GetMetricsName() string {
+	return "admin_resolve_event_report"
+}
+func (ReqPostAdminEventReportResolve) GetMsgType() int32 {
+	return internals.MSG_POST_ADMIN_EVENT_REPORT_RESOLVE
+}
+func (ReqPostAdminEventReportResolve) GetAPIType() int8 { return apiconsumer.APITypeAuth }
+func (ReqPostAdminEventReportResolve) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostAdminEventReportResolve) GetTopic(cfg *config.Dendrite) string {
+	return getProxyRpcTopic(cfg)
+}
+func (ReqPostAdminEventReportResolve) GetPrefix() []string { return []string{"admin"} }
+func (ReqPostAdminEventReportResolve) NewRequest() core.Coder {
+	return new(external.PostAdminEventReportResolveRequest)
+}
+func (ReqPostAdminEventReportResolve) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostAdminEventReportResolveRequest)
+	if err := common.UnmarshalJSON(req, msg); err != nil {
+		return err
+	}
+	if vars != nil {
+		msg.ReportID = vars["reportID"]
+	}
+	return nil
+}
+func (ReqPostAdminEventReportResolve) NewResponse(code int) core.Coder {
+	return new(external.PostAdminEventReportResolveResponse)
+}
+func (ReqPostAdminEventReportResolve) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAdminEventReportResolveRequest)
+	return routing.AdminResolveEventReport(
+		ctx, req, device, &c.Cfg, c.accountDB, c.roomDB, c.federation, c.rsRpcCli,
+		c.cacheIn, c.idg, c.complexCache,
+	)
+}
+
+type ReqPostAdminPurgeHistory struct{}
+
+func (ReqPostAdminPurgeHistory) GetRoute() string       { return "/rooms/{roomID}/purge_history" }
+func (ReqPostAdminPurgeHistory) GetMetricsName() string { return "admin_purge_history" }
+func (ReqPostAdminPurgeHistory) GetMsgType() int32      { return internals.MSG_POST_ADMIN_PURGE_HISTORY }
+func (ReqPostAdminPurgeHistory) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqPostAdminPurgeHistory) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostAdminPurgeHistory) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostAdminPurgeHistory) GetPrefix() []string                  { return []string{"admin"} }
+func (ReqPostAdminPurgeHistory) NewRequest() core.Coder {
+	return new(external.PostAdminPurgeHistoryRequest)
+}
+func (ReqPostAdminPurgeHistory) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostAdminPurgeHistoryRequest)
+	if err := common.UnmarshalJSON(req, msg); err != nil {
+		return err
+	}
+	if vars != nil {
+		msg.RoomID = vars["roomID"]
+	}
+	return nil
+}
+func (ReqPostAdminPurgeHistory) NewResponse(code int) core.Coder {
+	return new(external.PostAdminPurgeHistoryResponse)
+}
+func (ReqPostAdminPurgeHistory) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAdminPurgeHistoryRequest)
+	return routing.AdminPurgeRoomHistory(ctx, req, device, c.roomDB, c.syncDB, c.cacheIn, c.RpcCli)
+}
+
+type ReqDelAdminRoom struct{}
+
+func (ReqDelAdminRoom) GetRoute() string       { return "/rooms/{roomID}" }
+func (ReqDelAdminRoom) GetMetricsName() string { return "admin_shutdown_room" }
+func (ReqDelAdminRoom) GetMsgType() int32      { return internals.MSG_DEL_ADMIN_ROOM }
+func (ReqDelAdminRoom) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqDelAdminRoom) GetMethod() []string {
+	return []string{http.MethodDelete, http.MethodOptions}
+}
+func (ReqDelAdminRoom) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqDelAdminRoom) GetPrefix() []string                  { return []string{"admin"} }
+func (ReqDelAdminRoom) NewRequest() core.Coder {
+	return new(external.DelAdminRoomRequest)
+}
+func (ReqDelAdminRoom) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.DelAdminRoomRequest)
+	if err := common.UnmarshalJSON(req, msg); err != nil {
+		return err
+	}
+	if vars != nil {
+		msg.RoomID = vars["roomID"]
+	}
+	return nil
+}
+func (ReqDelAdminRoom) NewResponse(code int) core.Coder {
+	return new(external.DelAdminRoomResponse)
+}
+func (ReqDelAdminRoom) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.DelAdminRoomRequest)
+	return routing.AdminShutdownRoom(
+		ctx, req, device, &c.Cfg, c.accountDB, c.roomDB, c.syncDB, c.federation, c.rsRpcCli,
+		c.keyRing, c.cacheIn, c.idg, c.complexCache, c.RpcCli,
+	)
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/finogeeks/ligase/common/apiconsumer"
+)
+
+// adminProcessors are the routes only the super admin may call
+var adminProcessors = []apiconsumer.APIProcessor{
+	ReqGetAdminUsers{},
+	ReqGetAdminUserDevices{},
+	ReqPostAdminUserLogout{},
+	ReqPutAdminUserLock{},
+	ReqGetAdminRooms{},
+	ReqGetAdminRoomState{},
+	ReqPostAdminRoomMembership{},
+	ReqPostAdminResetPassword{},
+	ReqPostAdminDeactivate{},
+	ReqPostAdminRegistrationToken{},
+	ReqGetAdminRegistrationTokens{},
+	ReqDelAdminRegistrationToken{},
+	ReqGetAdminEventReports{},
+	ReqGetAdminEventReport{},
+	ReqPutAdminEventReportAssign{},
+	ReqPostAdminEventReportResolve{},
+	ReqPostAdminPurgeHistory{},
+	ReqDelAdminRoom{},
+}
+
+// The admin routes trust the super admin token, so it must not be handed out
+// on a public route.
+func TestSuperAdminTokenIsInternal(t *testing.T) {
+	p := ReqGetSuperAdminToken{}
+	if p.GetAPIType() != apiconsumer.APITypeInternal || !reflect.DeepEqual(p.GetPrefix(), []string{"inr0"}) {
+		t.Fatalf("super_token is served as type %d on %v, want the internal prefix only", p.GetAPIType(), p.GetPrefix())
+	}
+}
+
+func TestAdminRoutesNeedAuth(t *testing.T) {
+	for _, p := range adminProcessors {
+		if p.GetAPIType() != apiconsumer.APITypeAuth || !reflect.DeepEqual(p.GetPrefix(), []string{"admin"}) {
+			t.Errorf("%s is served as type %d on %v, want an authenticated admin route", p.GetRoute(), p.GetAPIType(), p.GetPrefix())
+		}
+	}
+}
@@ -54,6 +54,7 @@ type InternalMsgConsumer struct {
 	rsRpcCli            roomserverapi.RoomserverRPCAPI
 	accountDB           model.AccountsDatabase
 	deviceDB            model.DeviceDatabase
+	pushDB              model.PushAPIDatabase
 	federation          *fed.Federation
 	keyRing             gomatrixserverlib.KeyRing
 	cacheIn             service.Cache
@@ -74,6 +75,7 @@ func NewInternalMsgConsumer(
 	rsRpcCli roomserverapi.RoomserverRPCAPI,
 	accountDB model.AccountsDatabase,
 	deviceDB model.DeviceDatabase,
+	pushDB model.PushAPIDatabase,
 	federation *fed.Federation,
 	keyRing gomatrixserverlib.KeyRing,
 	cacheIn service.Cache,
@@ -94,6 +96,7 @@ func NewInternalMsgConsumer(
 	c.rsRpcCli = rsRpcCli
 	c.accountDB = accountDB
 	c.deviceDB = deviceDB
+	c.pushDB = pushDB
 	c.federation = federation
 	c.keyRing = keyRing
 	c.cacheIn = cacheIn
@@ -122,6 +125,8 @@ func getProxyRpcTopic(cfg *config.Dendrite) string {
 
 func init() {
 	apiconsumer.SetAPIProcessor(ReqPostCreateRoom{})
+	apiconsumer.SetAPIProcessor(ReqPostRoomUpgrade{})
+	apiconsumer.SetAPIProcessor(ReqGetRoomHierarchy{})
 	apiconsumer.SetAPIProcessor(ReqPostJoinRoomByIDOrAlias{})
 	apiconsumer.SetAPIProcessor(ReqPostRoomMembership{})
 	apiconsumer.SetAPIProcessor(ReqPutRoomSendWithTypeAndTxnID{})
@@ -134,11 +139,15 @@ func init() {
 	apiconsumer.SetAPIProcessor(ReqPostRegister{})
 	apiconsumer.SetAPIProcessor(ReqPostRegisterLegacy{})
 	apiconsumer.SetAPIProcessor(ReqGetRegitsterAvailable{})
+	apiconsumer.SetAPIProcessor(ReqGetRegistrationTokenValidity{})
 	apiconsumer.SetAPIProcessor(ReqGetDirectoryRoomAlias{})
 	apiconsumer.SetAPIProcessor(ReqPutDirectoryRoomAlias{})
 	apiconsumer.SetAPIProcessor(ReqDelDirectoryRoomAlias{})
 	apiconsumer.SetAPIProcessor(ReqPostLogout{})
 	apiconsumer.SetAPIProcessor(ReqPostLogoutAll{})
+	apiconsumer.SetAPIProcessor(ReqPostRefresh{})
+	apiconsumer.SetAPIProcessor(ReqPostUserOpenID{})
+	apiconsumer.SetAPIProcessor(ReqPostRoomReport{})
 	apiconsumer.SetAPIProcessor(ReqGetLogin{})
 	apiconsumer.SetAPIProcessor(ReqPostLogin{})
 	apiconsumer.SetAPIProcessor(ReqGetLoginAdmin{})
@@ -155,6 +164,12 @@ func init() {
 	apiconsumer.SetAPIProcessor(ReqPostAssociated3PIDs{})
 	apiconsumer.SetAPIProcessor(ReqPostAssociated3PIDsDel{})
 	apiconsumer.SetAPIProcessor(ReqPostAccount3PIDEmail{})
+	apiconsumer.SetAPIProcessor(ReqGetAccount3PIDEmailSubmit{})
+	apiconsumer.SetAPIProcessor(ReqPostAccountPassword{})
+	apiconsumer.SetAPIProcessor(ReqPostAccountPasswordEmail{})
+	apiconsumer.SetAPIProcessor(ReqPostAdminResetPassword{})
+	apiconsumer.SetAPIProcessor(ReqPostAccountDeactivate{})
+	apiconsumer.SetAPIProcessor(ReqPostAdminDeactivate{})
 	apiconsumer.SetAPIProcessor(ReqGetVoipTurnServer{})
 	apiconsumer.SetAPIProcessor(ReqGetThirdpartyProtos{})
 	apiconsumer.SetAPIProcessor(ReqGetDevicesByUserID{})
@@ -218,6 +233,65 @@ func (ReqPostCreateRoom) Process(ctx context.Context, consumer interface{}, msg
 	)
 }
 
+type ReqPostRoomUpgrade struct{}
+
+func (ReqPostRoomUpgrade) GetRoute() string                     { return "/rooms/{roomId}/upgrade" }
+func (ReqPostRoomUpgrade) GetMetricsName() string               { return "room_upgrade" }
+func (ReqPostRoomUpgrade) GetMsgType() int32                    { return internals.MSG_POST_ROOM_UPGRADE }
+func (ReqPostRoomUpgrade) GetAPIType() int8                     { return apiconsumer.APITypeAuth }
+func (ReqPostRoomUpgrade) GetMethod() []string                  { return []string{http.MethodPost, http.MethodOptions} }
+func (ReqPostRoomUpgrade) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostRoomUpgrade) GetPrefix() []string                  { return []string{"r0"} }
+func (ReqPostRoomUpgrade) NewRequest() core.Coder               { return new(external.PostRoomUpgradeRequest) }
+func (ReqPostRoomUpgrade) NewResponse(code int) core.Coder {
+	return new(external.PostRoomUpgradeResponse)
+}
+func (ReqPostRoomUpgrade) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostRoomUpgradeRequest)
+	if err := common.UnmarshalJSON(req, msg); err != nil {
+		return err
+	}
+	msg.RoomID = vars["roomId"]
+	return nil
+}
+func (ReqPostRoomUpgrade) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostRoomUpgradeRequest)
+	return routing.UpgradeRoom(
+		ctx, req, device.UserID, c.Cfg, c.roomDB,
+		c.rsRpcCli, c.cacheIn, c.idg, c.complexCache,
+	)
+}
+
+type ReqGetRoomHierarchy struct{}
+
+func (ReqGetRoomHierarchy) GetRoute() string                     { return "/rooms/{roomId}/hierarchy" }
+func (ReqGetRoomHierarchy) GetMetricsName() string               { return "room_hierarchy" }
+func (ReqGetRoomHierarchy) GetMsgType() int32                    { return internals.MSG_GET_ROOM_HIERARCHY }
+func (ReqGetRoomHierarchy) GetAPIType() int8                     { return apiconsumer.APITypeAuth }
+func (ReqGetRoomHierarchy) GetMethod() []string                  { return []string{http.MethodGet, http.MethodOptions} }
+func (ReqGetRoomHierarchy) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqGetRoomHierarchy) GetPrefix() []string                  { return []string{"clientV1"} }
+func (ReqGetRoomHierarchy) NewRequest() core.Coder               { return new(external.GetRoomHierarchyRequest) }
+func (ReqGetRoomHierarchy) NewResponse(code int) core.Coder {
+	return new(external.GetRoomHierarchyResponse)
+}
+func (ReqGetRoomHierarchy) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetRoomHierarchyRequest)
+	query := req.URL.Query()
+	msg.RoomID = vars["roomId"]
+	msg.From = query.Get("from")
+	msg.Limit = query.Get("limit")
+	msg.MaxDepth = query.Get("max_depth")
+	msg.SuggestedOnly = query.Get("suggested_only") == "true"
+	return nil
+}
+func (ReqGetRoomHierarchy) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetRoomHierarchyRequest)
+	return routing.GetRoomHierarchy(ctx, req, device.UserID, c.rsRpcCli)
+}
+
 type ReqPostJoinRoomByIDOrAlias struct{}
 
 func (ReqPostJoinRoomByIDOrAlias) GetRoute() string       { return "/join/{roomIDOrAlias}" }
@@ -739,6 +813,40 @@ func (ReqGetRegitsterAvailable) Process(ctx context.Context, consumer interface{
 	return routing.RegisterAvailable()
 }
 
+type ReqGetRegistrationTokenValidity struct{}
+
+func (ReqGetRegistrationTokenValidity) GetRoute() string {
+	return "/register/m.login.registration_token/validity"
+}
+func (ReqGetRegistrationTokenValidity) GetMetricsName() string { return "registrationTokenValidity" }
+func (ReqGetRegistrationTokenValidity) GetMsgType() int32 {
+	return internals.MSG_GET_REG_TOKEN_VALIDITY
+}
+func (ReqGetRegistrationTokenValidity) GetAPIType() int8 { return apiconsumer.APITypeExternal }
+func (ReqGetRegistrationTokenValidity) GetMethod() []string {
+	return []string{http.MethodGet, http.MethodOptions}
+}
+func (ReqGetRegistrationTokenValidity) GetTopic(cfg *config.Dendrite) string {
+	return getProxyRpcTopic(cfg)
+}
+func (ReqGetRegistrationTokenValidity) NewRequest() core.Coder {
+	return new(external.GetRegistrationTokenValidityRequest)
+}
+func (ReqGetRegistrationTokenValidity) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetRegistrationTokenValidityRequest)
+	msg.Token = req.URL.Query().Get("token")
+	return nil
+}
+func (ReqGetRegistrationTokenValidity) NewResponse(code int) core.Coder {
+	return new(external.GetRegistrationTokenValidityResponse)
+}
+func (ReqGetRegistrationTokenValidity) GetPrefix() []string { return []string{"clientV1"} }
+func (ReqGetRegistrationTokenValidity) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetRegistrationTokenValidityRequest)
+	return routing.RegistrationTokenValidity(ctx, req, &c.Cfg, c.accountDB)
+}
+
 type ReqGetDirectoryRoomAlias struct{}
 
 func (ReqGetDirectoryRoomAlias) GetRoute() string       { return "/directory/room/{roomAlias}" }
@@ -883,6 +991,83 @@ func (ReqPostLogoutAll) Process(ctx context.Context, consumer interface{}, msg c
 	)
 }
 
+type ReqPostRefresh struct{}
+
+func (ReqPostRefresh) GetRoute() string                     { return "/refresh" }
+func (ReqPostRefresh) GetMetricsName() string               { return "refresh" }
+func (ReqPostRefresh) GetMsgType() int32                    { return internals.MSG_POST_REFRESH }
+func (ReqPostRefresh) GetAPIType() int8                     { return apiconsumer.APITypeExternal }
+func (ReqPostRefresh) GetMethod() []string                  { return []string{http.MethodPost, http.MethodOptions} }
+func (ReqPostRefresh) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostRefresh) NewRequest() core.Coder {
+	return new(external.PostRefreshRequest)
+}
+func (ReqPostRefresh) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostRefreshRequest)
+	return common.UnmarshalJSON(req, msg)
+}
+func (ReqPostRefresh) NewResponse(code int) core.Coder { return new(external.PostRefreshResponse) }
+func (ReqPostRefresh) GetPrefix() []string             { return []string{"r0", "v1"} }
+func (ReqPostRefresh) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostRefreshRequest)
+	return routing.RefreshAccessToken(ctx, req, c.deviceDB, &c.Cfg)
+}
+
+type ReqPostUserOpenID struct{}
+
+func (ReqPostUserOpenID) GetRoute() string                     { return "/user/{userId}/openid/request_token" }
+func (ReqPostUserOpenID) GetMetricsName() string               { return "user_openid" }
+func (ReqPostUserOpenID) GetMsgType() int32                    { return internals.MSG_POST_USER_OPENID }
+func (ReqPostUserOpenID) GetAPIType() int8                     { return apiconsumer.APITypeAuth }
+func (ReqPostUserOpenID) GetMethod() []string                  { return []string{http.MethodPost, http.MethodOptions} }
+func (ReqPostUserOpenID) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostUserOpenID) NewRequest() core.Coder {
+	return new(external.PostUserOpenIDRequest)
+}
+func (ReqPostUserOpenID) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostUserOpenIDRequest)
+	msg.UserID = vars["userId"]
+	return nil
+}
+func (ReqPostUserOpenID) NewResponse(code int) core.Coder {
+	return new(external.PostUserOpenIDResponse)
+}
+func (ReqPostUserOpenID) GetPrefix() []string { return []string{"r0"} }
+func (ReqPostUserOpenID) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostUserOpenIDRequest)
+	return routing.RequestOpenIDToken(ctx, req, device, c.cacheIn, &c.Cfg)
+}
+
+type ReqPostRoomReport struct{}
+
+func (ReqPostRoomReport) GetRoute() string                     { return "/rooms/{roomId}/report/{eventId}" }
+func (ReqPostRoomReport) GetMetricsName() string               { return "room_report" }
+func (ReqPostRoomReport) GetMsgType() int32                    { return internals.MSG_POST_ROOM_REPORT }
+func (ReqPostRoomReport) GetAPIType() int8                     { return apiconsumer.APITypeAuth }
+func (ReqPostRoomReport) GetMethod() []string                  { return []string{http.MethodPost, http.MethodOptions} }
+func (ReqPostRoomReport) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostRoomReport) NewRequest() core.Coder {
+	return new(external.PostRoomReportRequest)
+}
+func (ReqPostRoomReport) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostRoomReportRequest)
+	if err := common.UnmarshalJSON(req, msg); err != nil {
+		return err
+	}
+	msg.RoomID = vars["roomId"]
+	msg.EventID = vars["eventId"]
+	return nil
+}
+func (ReqPostRoomReport) NewResponse(code int) core.Coder { return nil }
+func (ReqPostRoomReport) GetPrefix() []string             { return []string{"r0"} }
+func (ReqPostRoomReport) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostRoomReportRequest)
+	return routing.ReportEvent(ctx, req, device.UserID, c.rsRpcCli, c.roomDB, c.idg)
+}
+
 type ReqGetLogin struct{}
 
 func (ReqGetLogin) GetRoute() string                     { return "/login" }
@@ -934,7 +1119,7 @@ func (ReqPostLogin) Process(ctx context.Context, consumer interface{}, msg core.
 	req := msg.(*external.PostLoginRequest)
 	return routing.LoginPost(
 		ctx, req, c.accountDB, c.deviceDB, c.encryptDB,
-		c.syncDB, c.Cfg, false, c.idg, c.tokenFilter, c.RpcCli,
+		c.syncDB, c.Cfg, false, c.idg, c.tokenFilter, c.RpcCli, c.cacheIn,
 	)
 }
 
@@ -989,7 +1174,7 @@ func (ReqPostLoginAdmin) Process(ctx context.Context, consumer interface{}, msg
 	req := msg.(*external.PostLoginRequest)
 	return routing.LoginPost(
 		ctx, req, c.accountDB, c.deviceDB, c.encryptDB,
-		c.syncDB, c.Cfg, true, c.idg, c.tokenFilter, c.RpcCli,
+		c.syncDB, c.Cfg, true, c.idg, c.tokenFilter, c.RpcCli, c.cacheIn,
 	)
 }
 
@@ -1260,7 +1445,8 @@ func (ReqGetAssociated3PIDs) NewResponse(code int) core.Coder {
 }
 func (ReqGetAssociated3PIDs) GetPrefix() []string { return []string{"r0"} }
 func (ReqGetAssociated3PIDs) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
-	return routing.GetAssociated3PIDs()
+	c := consumer.(*InternalMsgConsumer)
+	return routing.GetAssociated3PIDs(ctx, device, c.accountDB)
 }
 
 type ReqPostAssociated3PIDs struct{}
@@ -1287,7 +1473,9 @@ func (ReqPostAssociated3PIDs) FillRequest(coder core.Coder, req *http.Request, v
 func (ReqPostAssociated3PIDs) NewResponse(code int) core.Coder { return nil }
 func (ReqPostAssociated3PIDs) GetPrefix() []string             { return []string{"r0"} }
 func (ReqPostAssociated3PIDs) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
-	return routing.CheckAndSave3PIDAssociation()
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAccount3PIDRequest)
+	return routing.CheckAndSave3PIDAssociation(ctx, req, device, c.accountDB, &c.Cfg)
 }
 
 type ReqPostAssociated3PIDsDel struct{}
@@ -1314,7 +1502,9 @@ func (ReqPostAssociated3PIDsDel) FillRequest(coder core.Coder, req *http.Request
 func (ReqPostAssociated3PIDsDel) NewResponse(code int) core.Coder { return nil }
 func (ReqPostAssociated3PIDsDel) GetPrefix() []string             { return []string{"unstable"} }
 func (ReqPostAssociated3PIDsDel) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
-	return routing.Forget3PID()
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAccount3PIDDelRequest)
+	return routing.Forget3PID(ctx, req, device, c.accountDB)
 }
 
 type ReqPostAccount3PIDEmail struct{}
@@ -1348,7 +1538,217 @@ func (ReqPostAccount3PIDEmail) NewResponse(code int) core.Coder {
 }
 func (ReqPostAccount3PIDEmail) GetPrefix() []string { return []string{"r0"} }
 func (ReqPostAccount3PIDEmail) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
-	return routing.RequestEmailToken()
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAccount3PIDEmailRequest)
+	return routing.RequestEmailToken(ctx, req, c.accountDB, &c.Cfg)
+}
+
+type ReqGetAccount3PIDEmailSubmit struct{}
+
+func (ReqGetAccount3PIDEmailSubmit) GetRoute() string       { return "/3pid/email/submit_token" }
+func (ReqGetAccount3PIDEmailSubmit) GetMetricsName() string { return "account_3pid_email_submit_token" }
+func (ReqGetAccount3PIDEmailSubmit) GetMsgType() int32      { return internals.MSG_GET_ACCOUNT_3PID_SUBMIT }
+func (ReqGetAccount3PIDEmailSubmit) GetAPIType() int8       { return apiconsumer.APITypeExternal }
+func (ReqGetAccount3PIDEmailSubmit) GetMethod() []string {
+	return []string{http.MethodGet, http.MethodOptions}
+}
+func (ReqGetAccount3PIDEmailSubmit) GetTopic(cfg *config.Dendrite) string {
+	return getProxyRpcTopic(cfg)
+}
+func (ReqGetAccount3PIDEmailSubmit) NewRequest() core.Coder {
+	return new(external.GetSubmit3PIDEmailTokenRequest)
+}
+func (ReqGetAccount3PIDEmailSubmit) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetSubmit3PIDEmailTokenRequest)
+	query := req.URL.Query()
+	msg.Sid = query.Get("sid")
+	msg.ClientSecret = query.Get("client_secret")
+	msg.Token = query.Get("token")
+	return nil
+}
+func (ReqGetAccount3PIDEmailSubmit) NewResponse(code int) core.Coder { return nil }
+func (ReqGetAccount3PIDEmailSubmit) GetPrefix() []string             { return []string{"unstable"} }
+func (ReqGetAccount3PIDEmailSubmit) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetSubmit3PIDEmailTokenRequest)
+	return routing.SubmitEmailToken(ctx, req, c.accountDB, &c.Cfg)
+}
+
+type ReqPostAccountPassword struct{}
+
+func (ReqPostAccountPassword) GetRoute() string       { return "/account/password" }
+func (ReqPostAccountPassword) GetMetricsName() string { return "account_password" }
+func (ReqPostAccountPassword) GetMsgType() int32      { return internals.MSG_POST_ACCOUT_PASS }
+func (ReqPostAccountPassword) GetAPIType() int8       { return apiconsumer.APITypeOptionalAuth }
+func (ReqPostAccountPassword) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostAccountPassword) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostAccountPassword) NewRequest() core.Coder {
+	return new(external.PostAccountPasswordRequest)
+}
+func (ReqPostAccountPassword) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostAccountPasswordRequest)
+	err := common.UnmarshalJSON(req, msg)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+func (ReqPostAccountPassword) NewResponse(code int) core.Coder {
+	if code == http.StatusUnauthorized {
+		return new(external.UserInteractiveResponse)
+	}
+	return nil
+}
+func (ReqPostAccountPassword) GetPrefix() []string { return []string{"r0"} }
+func (ReqPostAccountPassword) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAccountPasswordRequest)
+	return routing.ChangePassword(
+		ctx, req, device, &c.Cfg, c.accountDB, c.deviceDB, c.pushDB,
+		c.encryptDB, c.syncDB, c.cacheIn, c.tokenFilter, c.RpcCli,
+	)
+}
+
+type ReqPostAccountPasswordEmail struct{}
+
+func (ReqPostAccountPasswordEmail) GetRoute() string {
+	return "/account/password/email/requestToken"
+}
+func (ReqPostAccountPasswordEmail) GetMetricsName() string {
+	return "account_password_email_request_token"
+}
+func (ReqPostAccountPasswordEmail) GetMsgType() int32 { return internals.MSG_POST_ACCOUT_PASS_EMAIL }
+func (ReqPostAccountPasswordEmail) GetAPIType() int8  { return apiconsumer.APITypeExternal }
+func (ReqPostAccountPasswordEmail) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostAccountPasswordEmail) GetTopic(cfg *config.Dendrite) string {
+	return getProxyRpcTopic(cfg)
+}
+func (ReqPostAccountPasswordEmail) NewRequest() core.Coder {
+	return new(external.PostAccountPasswordEmailRequest)
+}
+func (ReqPostAccountPasswordEmail) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostAccountPasswordEmailRequest)
+	err := common.UnmarshalJSON(req, msg)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+func (ReqPostAccountPasswordEmail) NewResponse(code int) core.Coder {
+	return new(external.PostAccountPasswordEmailResponse)
+}
+func (ReqPostAccountPasswordEmail) GetPrefix() []string { return []string{"r0"} }
+func (ReqPostAccountPasswordEmail) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAccountPasswordEmailRequest)
+	return routing.RequestPasswordEmailToken(ctx, req, c.accountDB, &c.Cfg)
+}
+
+type ReqPostAdminResetPassword struct{}
+
+func (ReqPostAdminResetPassword) GetRoute() string       { return "/users/{userID}/reset_password" }
+func (ReqPostAdminResetPassword) GetMetricsName() string { return "admin_reset_password" }
+func (ReqPostAdminResetPassword) GetMsgType() int32      { return internals.MSG_POST_ACCOUNT_PASS_ADMIN }
+func (ReqPostAdminResetPassword) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqPostAdminResetPassword) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostAdminResetPassword) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostAdminResetPassword) NewRequest() core.Coder {
+	return new(external.PostAdminResetPasswordRequest)
+}
+func (ReqPostAdminResetPassword) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostAdminResetPasswordRequest)
+	err := common.UnmarshalJSON(req, msg)
+	if err != nil {
+		return err
+	}
+	if vars != nil {
+		msg.UserID = vars["userID"]
+	}
+	return nil
+}
+func (ReqPostAdminResetPassword) NewResponse(code int) core.Coder { return nil }
+func (ReqPostAdminResetPassword) GetPrefix() []string             { return []string{"admin"} }
+func (ReqPostAdminResetPassword) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAdminResetPasswordRequest)
+	return routing.AdminResetPassword(
+		ctx, req, device, &c.Cfg, c.accountDB, c.deviceDB, c.pushDB,
+		c.encryptDB, c.syncDB, c.cacheIn, c.tokenFilter, c.RpcCli,
+	)
+}
+
+type ReqPostAccountDeactivate struct{}
+
+func (ReqPostAccountDeactivate) GetRoute() string       { return "/account/deactivate" }
+func (ReqPostAccountDeactivate) GetMetricsName() string { return "account_deactivate" }
+func (ReqPostAccountDeactivate) GetMsgType() int32      { return internals.MSG_POST_ACCOUNT_DEACTIVATE }
+func (ReqPostAccountDeactivate) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqPostAccountDeactivate) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostAccountDeactivate) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostAccountDeactivate) NewRequest() core.Coder {
+	return new(external.PostAccountDeactivateRequest)
+}
+func (ReqPostAccountDeactivate) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostAccountDeactivateRequest)
+	err := common.UnmarshalJSON(req, msg)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+func (ReqPostAccountDeactivate) NewResponse(code int) core.Coder {
+	if code == http.StatusUnauthorized {
+		return new(external.UserInteractiveResponse)
+	}
+	return nil
+}
+func (ReqPostAccountDeactivate) GetPrefix() []string { return []string{"r0"} }
+func (ReqPostAccountDeactivate) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAccountDeactivateRequest)
+	return routing.Deactivate(
+		ctx, req, device, c.Cfg, c.accountDB, c.deviceDB, c.pushDB, c.encryptDB, c.syncDB,
+		c.cacheIn, c.tokenFilter, c.RpcCli, c.rsRpcCli, c.federation, c.idg, c.complexCache,
+	)
+}
+
+type ReqPostAdminDeactivate struct{}
+
+func (ReqPostAdminDeactivate) GetRoute() string       { return "/users/{userID}/deactivate" }
+func (ReqPostAdminDeactivate) GetMetricsName() string { return "admin_deactivate" }
+func (ReqPostAdminDeactivate) GetMsgType() int32      { return internals.MSG_POST_ADMIN_DEACTIVATE }
+func (ReqPostAdminDeactivate) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqPostAdminDeactivate) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostAdminDeactivate) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostAdminDeactivate) NewRequest() core.Coder {
+	return new(external.PostAdminDeactivateRequest)
+}
+func (ReqPostAdminDeactivate) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostAdminDeactivateRequest)
+	if vars != nil {
+		msg.UserID = vars["userID"]
+	}
+	return nil
+}
+func (ReqPostAdminDeactivate) NewResponse(code int) core.Coder { return nil }
+func (ReqPostAdminDeactivate) GetPrefix() []string             { return []string{"admin"} }
+func (ReqPostAdminDeactivate) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostAdminDeactivateRequest)
+	return routing.AdminDeactivate(
+		ctx, req, device, c.Cfg, c.accountDB, c.deviceDB, c.pushDB, c.encryptDB, c.syncDB,
+		c.cacheIn, c.tokenFilter, c.RpcCli, c.rsRpcCli, c.federation, c.idg, c.complexCache,
+	)
 }
 
 type ReqGetVoipTurnServer struct{}
@@ -1624,7 +2024,9 @@ func (ReqGetPresenceListByID) NewResponse(code int) core.Coder {
 }
 func (ReqGetPresenceListByID) GetPrefix() []string { return []string{"r0"} }
 func (ReqGetPresenceListByID) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
-	return routing.GetPresenceListByID()
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetPresenceListRequest)
+	return routing.GetPresenceListByID(ctx, c.presenceDB, c.RpcCli, c.cacheIn, c.complexCache, device.UserID, req.UserID)
 }
 
 type ReqPostPresenceListByID struct{}
@@ -1651,10 +2053,12 @@ func (ReqPostPresenceListByID) FillRequest(coder core.Coder, req *http.Request,
 	}
 	return nil
 }
-func (ReqPostPresenceListByID) NewResponse(code int) core.Coder { return new(external.PresenceJSON) }
+func (ReqPostPresenceListByID) NewResponse(code int) core.Coder { return nil }
 func (ReqPostPresenceListByID) GetPrefix() []string             { return []string{"r0"} }
 func (ReqPostPresenceListByID) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
-	return routing.UpdatePresenceListByID()
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.PostPresenceListRequest)
+	return routing.UpdatePresenceListByID(ctx, req, c.presenceDB, c.rsRpcCli, c.RpcCli, device.UserID)
 }
 
 type ReqGetRoomsTagsByID struct{}
@@ -2007,12 +2411,14 @@ func (ReqGetUserNewToken) Process(ctx context.Context, consumer interface{}, msg
 	return routing.GenNewToken(ctx, c.deviceDB, device, c.tokenFilter, c.idg, c.Cfg, c.encryptDB, c.syncDB, c.RpcCli)
 }
 
+// ReqGetSuperAdminToken mints the token of the admin API, so it is only
+// served on the internal prefix.
 type ReqGetSuperAdminToken struct{}
 
 func (ReqGetSuperAdminToken) GetRoute() string       { return "/user/super_token" }
 func (ReqGetSuperAdminToken) GetMetricsName() string { return "super_token" }
 func (ReqGetSuperAdminToken) GetMsgType() int32      { return internals.MSG_GET_SUPER_ADMIN_TOKEN }
-func (ReqGetSuperAdminToken) GetAPIType() int8       { return apiconsumer.APITypeExternal }
+func (ReqGetSuperAdminToken) GetAPIType() int8       { return apiconsumer.APITypeInternal }
 func (ReqGetSuperAdminToken) GetMethod() []string {
 	return []string{http.MethodGet, http.MethodOptions}
 }
@@ -2026,7 +2432,7 @@ func (ReqGetSuperAdminToken) FillRequest(coder core.Coder, req *http.Request, va
 func (ReqGetSuperAdminToken) NewResponse(code int) core.Coder {
 	return new(external.PostLoginResponse)
 }
-func (ReqGetSuperAdminToken) GetPrefix() []string { return []string{"r0"} }
+func (ReqGetSuperAdminToken) GetPrefix() []string { return []string{"inr0"} }
 func (ReqGetSuperAdminToken) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
 	c := consumer.(*InternalMsgConsumer)
 	return routing.GetSuperAdminToken(ctx, c.deviceDB, device, c.tokenFilter, c.idg, c.Cfg, c.encryptDB, c.syncDB, c.RpcCli)
@@ -21,7 +21,7 @@ import (
 	"github.com/finogeeks/ligase/clientapi/api"
 	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
 
-	// "github.com/finogeeks/ligase/clientapi/routing"
+	"github.com/finogeeks/ligase/clientapi/routing"
 	"github.com/finogeeks/ligase/clientapi/rpc"
 	"github.com/finogeeks/ligase/common"
 	"github.com/finogeeks/ligase/common/basecomponent"
@@ -30,6 +30,7 @@ import (
 	fed "github.com/finogeeks/ligase/federation/fedreq"
 	"github.com/finogeeks/ligase/model/service"
 	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/skunkworks/log"
 	"github.com/finogeeks/ligase/storage/model"
 )
 
@@ -53,12 +54,18 @@ func SetupClientAPIComponent(
 	complexCache *common.ComplexCache,
 	serverConfDB model.ConfigDatabase,
 ) {
+	if err := routing.SetupJWTLogin(base.Cfg); err != nil {
+		log.Panicf("failed to set up jwt login err:%v", err)
+	}
+
 	profileRpcConsumer := rpc.NewProfileRpcConsumer(rpcCli, base.Cfg, rsRpcCli, idg, accountsDB, presenceDB, cache, complexCache)
 	profileRpcConsumer.Start()
 
+	pushDB := base.CreatePushApiDB()
+
 	apiConsumer := api.NewInternalMsgConsumer(
 		base.APIMux, *base.Cfg,
-		rsRpcCli, accountsDB, deviceDB,
+		rsRpcCli, accountsDB, deviceDB, pushDB,
 		federation, *keyRing,
 		cache, encryptDB, syncDB, presenceDB,
 		roomDB, rpcCli, tokenFilter, complexCache, serverConfDB,
@@ -0,0 +1,623 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/finogeeks/ligase/clientapi/httputil"
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/filter"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/common/uid"
+	"github.com/finogeeks/ligase/core"
+	fed "github.com/finogeeks/ligase/federation/fedreq"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/model/types"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+const (
+	adminDefaultLimit = 100
+	adminMaxLimit     = 1000
+)
+
+func isSuperAdmin(device *authtypes.Device) bool {
+	return device != nil && device.UserID == superAdminUserID
+}
+
+func adminPage(from, limit int64) (int64, int64) {
+	if from < 0 {
+		from = 0
+	}
+	if limit <= 0 {
+		limit = adminDefaultLimit
+	} else if limit > adminMaxLimit {
+		limit = adminMaxLimit
+	}
+	return from, limit
+}
+
+// checkLocalAccount returns an error response if userID isn't an account of
+// this server.
+func checkLocalAccount(
+	ctx context.Context, userID string, cfg *config.Dendrite, accountDB model.AccountsDatabase,
+) (int, core.Coder) {
+	domain, err := common.DomainFromID(userID)
+	if err != nil {
+		return http.StatusBadRequest, jsonerror.InvalidUsername("User ID must be @localpart:domain")
+	}
+	if !common.CheckValidDomain(domain, cfg.Matrix.ServerName) {
+		return http.StatusBadRequest, jsonerror.InvalidUsername("Not a local user")
+	}
+	account, err := accountDB.GetAccount(ctx, userID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if account == nil || account.UserID == "" {
+		return http.StatusNotFound, jsonerror.NotFound("Unknown user")
+	}
+	return http.StatusOK, nil
+}
+
+// AdminListUsers implements GET /_ligase/admin/v1/users
+func AdminListUsers(
+	ctx context.Context,
+	req *external.GetAdminUsersRequest,
+	device *authtypes.Device,
+	accountDB model.AccountsDatabase,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can list users")
+	}
+
+	from, limit := adminPage(req.From, req.Limit)
+	accounts, total, err := accountDB.GetAccountsPage(ctx, req.Search, limit, from)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+
+	resp := &external.GetAdminUsersResponse{Users: []external.AdminUser{}, Total: total}
+	for _, account := range accounts {
+		resp.Users = append(resp.Users, external.AdminUser(account))
+	}
+	if next := from + int64(len(accounts)); next < total {
+		resp.NextFrom = next
+	}
+	return http.StatusOK, resp
+}
+
+// AdminGetUserDevices implements GET /_ligase/admin/v1/users/{userID}/devices
+func AdminGetUserDevices(
+	req *external.GetAdminUserDevicesRequest,
+	device *authtypes.Device,
+	cache service.Cache,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can list devices")
+	}
+
+	resp := &external.GetAdminUserDevicesResponse{Devices: []external.AdminDevice{}}
+	if devices := cache.GetDevicesByUserID(req.UserID); devices != nil {
+		for _, dev := range *devices {
+			resp.Devices = append(resp.Devices, external.AdminDevice{
+				DeviceID:    dev.ID,
+				DisplayName: dev.DisplayName,
+				DeviceType:  dev.DeviceType,
+				CreatedTs:   dev.CreateTs,
+				LastSeenTs:  dev.LastActiveTs,
+			})
+		}
+	}
+	resp.Total = len(resp.Devices)
+	return http.StatusOK, resp
+}
+
+// AdminLogoutUser implements POST /_ligase/admin/v1/users/{userID}/logout
+func AdminLogoutUser(
+	ctx context.Context,
+	req *external.PostAdminUserLogoutRequest,
+	device *authtypes.Device,
+	deviceDB model.DeviceDatabase,
+	cache service.Cache,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	tokenFilter *filter.Filter,
+	rpcClient *common.RpcClient,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can log out users")
+	}
+
+	if req.DeviceID != "" {
+		if cache.GetDeviceByDeviceID(req.DeviceID, req.UserID) == nil {
+			return http.StatusNotFound, jsonerror.NotFound("Unknown device")
+		}
+		LogoutDevice(ctx, req.UserID, req.DeviceID, deviceDB, cache, encryptDB, syncDB, tokenFilter, rpcClient)
+		log.Infof("device %s of user %s logged out by super admin", req.DeviceID, req.UserID)
+		return http.StatusOK, &external.PostAdminUserLogoutResponse{Devices: []string{req.DeviceID}}
+	}
+
+	return http.StatusOK, &external.PostAdminUserLogoutResponse{
+		Devices: logoutAllDevices(ctx, req.UserID, deviceDB, cache, encryptDB, syncDB, tokenFilter, rpcClient),
+	}
+}
+
+// AdminLockUser implements PUT /_ligase/admin/v1/users/{userID}/lock
+// A locked account can't log in, and locking it logs out all its devices.
+func AdminLockUser(
+	ctx context.Context,
+	req *external.PutAdminUserLockRequest,
+	device *authtypes.Device,
+	cfg *config.Dendrite,
+	accountDB model.AccountsDatabase,
+	deviceDB model.DeviceDatabase,
+	cache service.Cache,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	tokenFilter *filter.Filter,
+	rpcClient *common.RpcClient,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can lock users")
+	}
+	if code, resErr := checkLocalAccount(ctx, req.UserID, cfg, accountDB); resErr != nil {
+		return code, resErr
+	}
+
+	if !req.Locked {
+		if err := accountDB.UnlockAccount(ctx, req.UserID); err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		log.Infof("user %s unlocked by super admin", req.UserID)
+		return http.StatusOK, &external.PutAdminUserLockResponse{Locked: false}
+	}
+
+	if err := accountDB.LockAccount(ctx, req.UserID); err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	logoutAllDevices(ctx, req.UserID, deviceDB, cache, encryptDB, syncDB, tokenFilter, rpcClient)
+	log.Infof("user %s locked by super admin", req.UserID)
+	return http.StatusOK, &external.PutAdminUserLockResponse{Locked: true}
+}
+
+func logoutAllDevices(
+	ctx context.Context,
+	userID string,
+	deviceDB model.DeviceDatabase,
+	cache service.Cache,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	tokenFilter *filter.Filter,
+	rpcClient *common.RpcClient,
+) []string {
+	deviceIDs := []string{}
+	devices := cache.GetDevicesByUserID(userID)
+	if devices == nil {
+		return deviceIDs
+	}
+	for _, dev := range *devices {
+		LogoutDevice(ctx, userID, dev.ID, deviceDB, cache, encryptDB, syncDB, tokenFilter, rpcClient)
+		deviceIDs = append(deviceIDs, dev.ID)
+	}
+	log.Infof("%d devices of user %s logged out by super admin", len(deviceIDs), userID)
+	return deviceIDs
+}
+
+// AdminListRooms implements GET /_ligase/admin/v1/rooms
+func AdminListRooms(
+	ctx context.Context,
+	req *external.GetAdminRoomsRequest,
+	device *authtypes.Device,
+	roomDB model.RoomServerDatabase,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can list rooms")
+	}
+
+	from, limit := adminPage(req.From, req.Limit)
+	rooms, total, err := roomDB.GetRoomsJoinedCount(ctx, int(limit), int(from))
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+
+	resp := &external.GetAdminRoomsResponse{Rooms: []external.AdminRoom{}, Total: total}
+	for _, room := range rooms {
+		resp.Rooms = append(resp.Rooms, external.AdminRoom{
+			RoomID:        room.RoomID,
+			JoinedMembers: room.JoinedMembers,
+		})
+	}
+	if next := from + int64(len(rooms)); next < total {
+		resp.NextFrom = next
+	}
+	return http.StatusOK, resp
+}
+
+// AdminGetRoomState implements GET /_ligase/admin/v1/rooms/{roomID}/state
+func AdminGetRoomState(
+	ctx context.Context,
+	req *external.GetAdminRoomStateRequest,
+	device *authtypes.Device,
+	roomDB model.RoomServerDatabase,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can read room state")
+	}
+
+	exists, err := roomDB.RoomExists(ctx, req.RoomID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if !exists {
+		return http.StatusNotFound, jsonerror.NotFound("Unknown room")
+	}
+
+	events, _, err := roomDB.GetRoomStates(ctx, req.RoomID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	resp := &external.GetAdminRoomStateResponse{State: []gomatrixserverlib.ClientEvent{}}
+	for _, ev := range events {
+		resp.State = append(resp.State, gomatrixserverlib.ToClientEvent(*ev, gomatrixserverlib.FormatAll))
+	}
+	return http.StatusOK, resp
+}
+
+// AdminUpdateRoomMembership implements POST /_ligase/admin/v1/rooms/{roomID}/join
+// and /leave, the membership is sent on behalf of the local user. Joins are
+// still subject to the join rules of the room.
+func AdminUpdateRoomMembership(
+	ctx context.Context,
+	req *external.PostAdminRoomMembershipRequest,
+	device *authtypes.Device,
+	cfg *config.Dendrite,
+	accountDB model.AccountsDatabase,
+	federation *fed.Federation,
+	rpcCli roomserverapi.RoomserverRPCAPI,
+	keyRing gomatrixserverlib.KeyRing,
+	cache service.Cache,
+	idg *uid.UidGenerator,
+	complexCache *common.ComplexCache,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can change memberships")
+	}
+	if req.UserID == "" {
+		return http.StatusBadRequest, jsonerror.MissingArgument("'user_id' must be supplied")
+	}
+	if code, resErr := checkLocalAccount(ctx, req.UserID, cfg, accountDB); resErr != nil {
+		return code, resErr
+	}
+	log.Infof("super admin makes user %s %s room %s", req.UserID, req.Membership, req.RoomID)
+
+	switch req.Membership {
+	case "join":
+		return JoinRoomByIDOrAlias(
+			ctx, &external.PostRoomsJoinByAliasRequest{RoomID: req.RoomID, Content: []byte("{}")},
+			req.UserID, req.RoomID, *cfg, federation, rpcCli, keyRing, cache, idg, complexCache,
+		)
+	case "leave":
+		return SendMembership(
+			ctx, &external.PostRoomsMembershipRequest{RoomID: req.RoomID, Membership: "leave"},
+			accountDB, req.UserID, "", req.RoomID, "leave", *cfg, rpcCli, federation, cache, idg, complexCache,
+		)
+	}
+	return http.StatusBadRequest, jsonerror.InvalidArgumentValue("membership must be join or leave")
+}
+
+// AdminPurgeRoomHistory implements POST /_ligase/admin/v1/rooms/{roomID}/purge_history,
+// the events sent before purge_up_to_event_id or purge_up_to_ts are deleted.
+// State events and the latest event of the room are kept, so the current
+// state is intact.
+func AdminPurgeRoomHistory(
+	ctx context.Context,
+	req *external.PostAdminPurgeHistoryRequest,
+	device *authtypes.Device,
+	roomDB model.RoomServerDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	rpcCli *common.RpcClient,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can purge room history")
+	}
+
+	exists, err := roomDB.RoomExists(ctx, req.RoomID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if !exists {
+		return http.StatusNotFound, jsonerror.NotFound("Unknown room")
+	}
+
+	ts := req.PurgeUpToTs
+	if req.PurgeUpToEventID != "" {
+		events, _, err := syncDB.StreamEvents(ctx, []string{req.PurgeUpToEventID})
+		if err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		if len(events) == 0 || events[0].RoomID != req.RoomID {
+			return http.StatusNotFound, jsonerror.NotFound("Unknown event")
+		}
+		ts = int64(events[0].OriginServerTS)
+	}
+	if ts <= 0 {
+		return http.StatusBadRequest, jsonerror.MissingArgument("'purge_up_to_ts' or 'purge_up_to_event_id' must be supplied")
+	}
+	log.Infof("super admin purges room %s history before %d", req.RoomID, ts)
+
+	purged, err := common.PurgeRoomHistory(ctx, req.RoomID, ts, roomDB, syncDB, cache, rpcCli)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	return http.StatusOK, &external.PostAdminPurgeHistoryResponse{Purged: purged}
+}
+
+// AdminShutdownRoom implements DELETE /_ligase/admin/v1/rooms/{roomID}.
+// The local aliases of the room are removed, the room leaves the public
+// directory and the local members are made to leave it. With
+// new_room_user_id they are moved to a new room where only that user can
+// talk. Blocked rooms refuse any further join or invite, a purge
+// deletes the history but keeps the room state.
+func AdminShutdownRoom(
+	ctx context.Context,
+	req *external.DelAdminRoomRequest,
+	device *authtypes.Device,
+	cfg *config.Dendrite,
+	accountDB model.AccountsDatabase,
+	roomDB model.RoomServerDatabase,
+	syncDB model.SyncAPIDatabase,
+	federation *fed.Federation,
+	rsRpcCli roomserverapi.RoomserverRPCAPI,
+	keyRing gomatrixserverlib.KeyRing,
+	cache service.Cache,
+	idg *uid.UidGenerator,
+	complexCache *common.ComplexCache,
+	rpcCli *common.RpcClient,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can shut down rooms")
+	}
+
+	var queryRes roomserverapi.QueryRoomStateResponse
+	queryReq := roomserverapi.QueryRoomStateRequest{RoomID: req.RoomID}
+	if err := rsRpcCli.QueryRoomState(ctx, &queryReq, &queryRes); err != nil || !queryRes.RoomExists {
+		return http.StatusNotFound, jsonerror.NotFound("Unknown room")
+	}
+	if req.NewRoomUserID != "" {
+		if code, resErr := checkLocalAccount(ctx, req.NewRoomUserID, cfg, accountDB); resErr != nil {
+			return code, resErr
+		}
+	}
+	log.Infof("super admin shuts down room %s, new room user:%s block:%t purge:%t", req.RoomID, req.NewRoomUserID, req.Block, req.Purge)
+
+	resp := &external.DelAdminRoomResponse{
+		KickedUsers:       []string{},
+		FailedToKickUsers: []string{},
+		LocalAliases:      []string{},
+	}
+	if req.NewRoomUserID != "" {
+		code, res := createShutdownNoticeRoom(ctx, req, cfg, accountDB, rsRpcCli, cache, idg, complexCache)
+		if code != http.StatusOK {
+			return code, res
+		}
+		resp.NewRoomID = res.(*external.PostCreateRoomResponse).RoomID
+	}
+
+	if req.Block {
+		if err := roomDB.BlockRoom(ctx, req.RoomID, device.UserID, time.Now().UnixNano()/1000000); err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		// the roomservers keep the blocked rooms in memory
+		bytes, _ := json.Marshal(types.RoomBlocked{RoomID: req.RoomID})
+		rpcCli.Pub(types.RoomBlockedTopicDef, bytes)
+	}
+
+	aliases, err := roomDB.GetAliasesFromRoomID(ctx, req.RoomID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	for _, alias := range aliases {
+		if err := removeShutdownRoomAlias(ctx, alias, &queryRes, roomDB, rsRpcCli, cache); err != nil {
+			log.Errorf("AdminShutdownRoom room:%s remove alias %s err:%v", req.RoomID, alias, err)
+			continue
+		}
+		resp.LocalAliases = append(resp.LocalAliases, alias)
+	}
+
+	// the state is sent by a local member, so before they leave
+	members := localJoinedMembers(&queryRes, cfg)
+	if queryRes.Visibility != nil {
+		var content common.VisibilityContent
+		if err := json.Unmarshal(queryRes.Visibility.Content(), &content); err == nil && content.Visibility == "public" {
+			unpublishShutdownRoom(ctx, req.RoomID, roomModerator(&queryRes, members), cfg, rsRpcCli, cache, idg)
+		}
+	}
+
+	for _, userID := range members {
+		if resp.NewRoomID != "" && userID != req.NewRoomUserID {
+			code, _ := JoinRoomByIDOrAlias(
+				ctx, &external.PostRoomsJoinByAliasRequest{RoomID: resp.NewRoomID, Content: []byte("{}")},
+				userID, resp.NewRoomID, *cfg, federation, rsRpcCli, keyRing, cache, idg, complexCache,
+			)
+			if code != http.StatusOK {
+				log.Warnf("AdminShutdownRoom user:%s can't join new room:%s code:%d", userID, resp.NewRoomID, code)
+			}
+		}
+		code, _ := SendMembership(
+			ctx, &external.PostRoomsMembershipRequest{RoomID: req.RoomID, Membership: "leave"},
+			accountDB, userID, "", req.RoomID, "leave", *cfg, rsRpcCli, federation, cache, idg, complexCache,
+		)
+		if code != http.StatusOK {
+			resp.FailedToKickUsers = append(resp.FailedToKickUsers, userID)
+			continue
+		}
+		resp.KickedUsers = append(resp.KickedUsers, userID)
+	}
+
+	if req.Purge {
+		purged, err := common.PurgeRoomHistory(ctx, req.RoomID, time.Now().UnixNano()/1000000, roomDB, syncDB, cache, rpcCli)
+		if err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		resp.Purged = purged
+	}
+	log.Infof("room %s shut down, kicked:%d failed:%d aliases:%d new room:%s", req.RoomID, len(resp.KickedUsers), len(resp.FailedToKickUsers), len(resp.LocalAliases), resp.NewRoomID)
+	return http.StatusOK, resp
+}
+
+// createShutdownNoticeRoom creates the public room the members of a shut down
+// room are moved to, the users default level keeps them from talking.
+func createShutdownNoticeRoom(
+	ctx context.Context,
+	req *external.DelAdminRoomRequest,
+	cfg *config.Dendrite,
+	accountDB model.AccountsDatabase,
+	rsRpcCli roomserverapi.RoomserverRPCAPI,
+	cache service.Cache,
+	idg *uid.UidGenerator,
+	complexCache *common.ComplexCache,
+) (int, core.Coder) {
+	name := req.RoomName
+	if name == "" {
+		name = "Content Violation Notification"
+	}
+	powerContent := common.InitialPowerLevelsContent(req.NewRoomUserID)
+	powerContent.UsersDefault = -10
+	code, res := CreateRoom(ctx, &external.PostCreateRoomRequest{
+		Preset:       presetPublicChat,
+		Name:         name,
+		InitialState: []external.StateEvent{{Type: "m.room.power_levels", Content: powerContent}},
+	}, req.NewRoomUserID, *cfg, accountDB, rsRpcCli, cache, idg, complexCache)
+	if code != http.StatusOK || req.Message == "" {
+		return code, res
+	}
+
+	roomID := res.(*external.PostCreateRoomResponse).RoomID
+	content, _ := json.Marshal(map[string]string{"msgtype": "m.text", "body": req.Message})
+	if code, _ := PostEvent(
+		ctx, &external.PutRoomStateByTypeWithTxnID{RoomID: roomID, EventType: "m.room.message", Content: content},
+		req.NewRoomUserID, "", "", roomID, "m.room.message", nil, nil, *cfg, rsRpcCli, cache, idg,
+	); code != http.StatusOK {
+		log.Warnf("AdminShutdownRoom can't send the notice to new room:%s code:%d", roomID, code)
+	}
+	return code, res
+}
+
+// removeShutdownRoomAlias removes the alias through the roomserver, which
+// updates m.room.aliases, and falls back to the database if the sender of
+// m.room.aliases can't do it anymore.
+func removeShutdownRoomAlias(
+	ctx context.Context, alias string,
+	queryRes *roomserverapi.QueryRoomStateResponse,
+	roomDB model.RoomServerDatabase,
+	rsRpcCli roomserverapi.RoomserverRPCAPI,
+	cache service.Cache,
+) error {
+	if queryRes.Alias != nil {
+		removeReq := roomserverapi.RemoveRoomAliasRequest{Alias: alias, UserID: queryRes.Alias.Sender()}
+		var removeRes roomserverapi.RemoveRoomAliasResponse
+		err := rsRpcCli.RemoveRoomAlias(ctx, &removeReq, &removeRes)
+		if err == nil {
+			return nil
+		}
+		log.Warnf("AdminShutdownRoom remove alias %s by %s err:%v", alias, removeReq.UserID, err)
+	}
+	if err := cache.DelAlias(alias); err != nil {
+		return err
+	}
+	return roomDB.RemoveRoomAlias(ctx, alias)
+}
+
+// unpublishShutdownRoom takes the room out of the public directory
+func unpublishShutdownRoom(
+	ctx context.Context, roomID, moderator string,
+	cfg *config.Dendrite,
+	rsRpcCli roomserverapi.RoomserverRPCAPI,
+	cache service.Cache,
+	idg *uid.UidGenerator,
+) {
+	if moderator == "" {
+		log.Warnf("AdminShutdownRoom no local member can unpublish room:%s", roomID)
+		return
+	}
+	stateKey := ""
+	content, _ := json.Marshal(common.VisibilityContent{Visibility: "private"})
+	if code, _ := PostEvent(
+		ctx, &external.PutRoomStateByTypeWithTxnID{RoomID: roomID, EventType: "m.room.visibility", Content: content},
+		moderator, "", "", roomID, "m.room.visibility", nil, &stateKey, *cfg, rsRpcCli, cache, idg,
+	); code != http.StatusOK {
+		log.Warnf("AdminShutdownRoom user:%s can't unpublish room:%s code:%d", moderator, roomID, code)
+	}
+}
+
+func localJoinedMembers(queryRes *roomserverapi.QueryRoomStateResponse, cfg *config.Dendrite) []string {
+	members := []string{}
+	for userID := range queryRes.Join {
+		domain, err := common.DomainFromID(userID)
+		if err == nil && common.CheckValidDomain(domain, cfg.Matrix.ServerName) {
+			members = append(members, userID)
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// roomModerator returns the member with the highest power level, the creator
+// if it is one of them
+func roomModerator(queryRes *roomserverapi.QueryRoomStateResponse, members []string) string {
+	if len(members) == 0 {
+		return ""
+	}
+	creator := ""
+	if queryRes.Creator != nil {
+		creator = queryRes.Creator.Sender()
+	}
+	if queryRes.Power == nil {
+		for _, userID := range members {
+			if userID == creator {
+				return creator
+			}
+		}
+		return ""
+	}
+	var power common.PowerLevelContent
+	if err := json.Unmarshal(queryRes.Power.Content(), &power); err != nil {
+		return ""
+	}
+	level := func(userID string) int {
+		if l, ok := power.Users[userID]; ok {
+			return l
+		}
+		return power.UsersDefault
+	}
+	moderator := members[0]
+	for _, userID := range members[1:] {
+		if level(userID) > level(moderator) || (level(userID) == level(moderator) && userID == creator) {
+			moderator = userID
+		}
+	}
+	return moderator
+}
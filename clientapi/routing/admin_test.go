@@ -0,0 +1,381 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// adminAccountsDB pages through a fixed list of accounts and keeps the locks
+// set by the admin API.
+type adminAccountsDB struct {
+	*fakeAccountsDB
+	accounts []authtypes.AccountSummary
+	locked   map[string]bool
+}
+
+func newAdminAccountsDB(userIDs ...string) *adminAccountsDB {
+	d := &adminAccountsDB{
+		fakeAccountsDB: &fakeAccountsDB{deactivated: map[string]bool{}},
+		locked:         map[string]bool{},
+	}
+	for _, userID := range userIDs {
+		d.accounts = append(d.accounts, authtypes.AccountSummary{UserID: userID})
+	}
+	return d
+}
+
+func (d *adminAccountsDB) GetAccount(ctx context.Context, userID string) (*authtypes.Account, error) {
+	for _, account := range d.accounts {
+		if account.UserID == userID {
+			return &authtypes.Account{UserID: userID}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (d *adminAccountsDB) GetAccountsPage(ctx context.Context, search string, limit, offset int64) ([]authtypes.AccountSummary, int64, error) {
+	total := int64(len(d.accounts))
+	if offset >= total {
+		return nil, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return d.accounts[offset:end], total, nil
+}
+
+func (d *adminAccountsDB) LockAccount(ctx context.Context, userID string) error {
+	d.locked[userID] = true
+	return nil
+}
+
+func (d *adminAccountsDB) UnlockAccount(ctx context.Context, userID string) error {
+	delete(d.locked, userID)
+	return nil
+}
+
+func (d *adminAccountsDB) IsAccountLocked(ctx context.Context, userID string) (bool, error) {
+	return d.locked[userID], nil
+}
+
+func TestAdminRequiresSuperAdmin(t *testing.T) {
+	cfg := testLoginConfig("password")
+	accountDB := newAdminAccountsDB("@alice:example.com")
+	for _, device := range []*authtypes.Device{nil, {UserID: "@alice:example.com"}} {
+		if code, resp := AdminListUsers(context.Background(), &external.GetAdminUsersRequest{}, device, accountDB); code != http.StatusForbidden {
+			t.Errorf("AdminListUsers by %v returned %d %v", device, code, resp)
+		}
+		req := &external.PutAdminUserLockRequest{UserID: "@alice:example.com", Locked: true}
+		code, resp := AdminLockUser(context.Background(), req, device, &cfg, accountDB, nil, nil, nil, nil, nil, nil)
+		if code != http.StatusForbidden {
+			t.Errorf("AdminLockUser by %v returned %d %v", device, code, resp)
+		}
+	}
+	if accountDB.locked["@alice:example.com"] {
+		t.Error("the account was locked by a regular user")
+	}
+}
+
+func TestAdminListUsersPaging(t *testing.T) {
+	accountDB := newAdminAccountsDB("@a:example.com", "@b:example.com", "@c:example.com")
+	admin := &authtypes.Device{UserID: superAdminUserID}
+
+	code, resp := AdminListUsers(context.Background(), &external.GetAdminUsersRequest{Limit: 2}, admin, accountDB)
+	if code != http.StatusOK {
+		t.Fatalf("AdminListUsers returned %d %v", code, resp)
+	}
+	page := resp.(*external.GetAdminUsersResponse)
+	if len(page.Users) != 2 || page.Total != 3 || page.NextFrom != 2 {
+		t.Fatalf("first page = %+v", page)
+	}
+
+	code, resp = AdminListUsers(context.Background(), &external.GetAdminUsersRequest{From: page.NextFrom, Limit: 2}, admin, accountDB)
+	if code != http.StatusOK {
+		t.Fatalf("AdminListUsers returned %d %v", code, resp)
+	}
+	page = resp.(*external.GetAdminUsersResponse)
+	if len(page.Users) != 1 || page.Users[0].UserID != "@c:example.com" || page.NextFrom != 0 {
+		t.Fatalf("last page = %+v", page)
+	}
+}
+
+func TestAdminLockUser(t *testing.T) {
+	userID := "@alice:example.com"
+	cfg := testLoginConfig("password")
+	accountDB := newAdminAccountsDB(userID)
+	cache := &deactivateCache{
+		fakeCache: newFakeCache(),
+		devices:   []authtypes.Device{{ID: "DEV1", UserID: userID}, {ID: "DEV2", UserID: userID}},
+	}
+	deviceDB := &fakeDeviceDB{}
+	admin := &authtypes.Device{UserID: superAdminUserID}
+	lock := func(userID string, locked bool) (int, interface{}) {
+		req := &external.PutAdminUserLockRequest{UserID: userID, Locked: locked}
+		return AdminLockUser(
+			context.Background(), req, admin, &cfg, accountDB, deviceDB, cache,
+			&fakeEncryptDB{}, &fakeSyncDB{}, nil, &common.RpcClient{},
+		)
+	}
+
+	if code, resp := lock("@bob:example.com", true); code != http.StatusNotFound {
+		t.Errorf("locking an unknown user returned %d %v", code, resp)
+	}
+	if code, resp := lock("@alice:other.com", true); code != http.StatusBadRequest {
+		t.Errorf("locking a remote user returned %d %v", code, resp)
+	}
+
+	if code, resp := lock(userID, true); code != http.StatusOK {
+		t.Fatalf("lock returned %d %v", code, resp)
+	}
+	if !accountDB.locked[userID] {
+		t.Error("the account is not locked")
+	}
+	if want := []string{"DEV1", "DEV2"}; !reflect.DeepEqual(deviceDB.removed, want) {
+		t.Errorf("devices logged out = %v, want %v", deviceDB.removed, want)
+	}
+
+	code, resp := completeLogin(userID, context.Background(), external.PostLoginRequest{}, cfg, nil, accountDB, nil, nil, nil, nil)
+	if code != http.StatusForbidden || errCode(t, resp) != "M_USER_LOCKED" {
+		t.Errorf("login of a locked account returned %d %v", code, resp)
+	}
+
+	if code, resp := lock(userID, false); code != http.StatusOK {
+		t.Fatalf("unlock returned %d %v", code, resp)
+	}
+	if accountDB.locked[userID] {
+		t.Error("the account is still locked")
+	}
+}
+
+// purgeRoomDB knows one room and records the chunks of event IDs purged
+type purgeRoomDB struct {
+	model.RoomServerDatabase
+	roomID string
+	chunks [][]string
+}
+
+func (d *purgeRoomDB) RoomExists(ctx context.Context, roomID string) (bool, error) {
+	return roomID == d.roomID, nil
+}
+
+func (d *purgeRoomDB) PurgeRoomEvents(ctx context.Context, roomID string, eventIDs []string) (int64, error) {
+	d.chunks = append(d.chunks, eventIDs)
+	return int64(len(eventIDs)), nil
+}
+
+// purgeSyncDB purges the events before the timestamp, err makes it stop after
+// them as if the purge failed half way
+type purgeSyncDB struct {
+	model.SyncAPIDatabase
+	events []gomatrixserverlib.ClientEvent
+	before int64
+	err    error
+}
+
+func (d *purgeSyncDB) StreamEvents(ctx context.Context, eventIDs []string) ([]gomatrixserverlib.ClientEvent, []int64, error) {
+	for i, ev := range d.events {
+		if ev.EventID == eventIDs[0] {
+			return d.events[i : i+1], []int64{int64(i)}, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+func (d *purgeSyncDB) PurgeRoomEvents(ctx context.Context, roomID string, ts int64) ([]string, error) {
+	d.before = ts
+	eventIDs := []string{}
+	for _, ev := range d.events {
+		if ev.RoomID == roomID && int64(ev.OriginServerTS) < ts {
+			eventIDs = append(eventIDs, ev.EventID)
+		}
+	}
+	return eventIDs, d.err
+}
+
+type purgeCache struct {
+	service.Cache
+	txnRooms []string
+}
+
+func (c *purgeCache) DelTxnID(roomID string) error {
+	c.txnRooms = append(c.txnRooms, roomID)
+	return nil
+}
+
+func newPurgeSyncDB(roomID string, n int) *purgeSyncDB {
+	d := &purgeSyncDB{}
+	for i := 1; i <= n; i++ {
+		d.events = append(d.events, gomatrixserverlib.ClientEvent{
+			EventID:        fmt.Sprintf("$%d:example.com", i),
+			RoomID:         roomID,
+			OriginServerTS: gomatrixserverlib.Timestamp(i),
+		})
+	}
+	d.events = append(d.events, gomatrixserverlib.ClientEvent{EventID: "$other:example.com", RoomID: "!other:example.com", OriginServerTS: 1})
+	return d
+}
+
+func TestAdminPurgeRoomHistoryErrors(t *testing.T) {
+	roomID := "!room:example.com"
+	admin := &authtypes.Device{UserID: superAdminUserID}
+	tests := []struct {
+		name    string
+		device  *authtypes.Device
+		req     external.PostAdminPurgeHistoryRequest
+		code    int
+		errcode string
+	}{
+		{"no device", nil, external.PostAdminPurgeHistoryRequest{RoomID: roomID, PurgeUpToTs: 5}, http.StatusForbidden, "M_FORBIDDEN"},
+		{"not admin", &authtypes.Device{UserID: "@alice:example.com"}, external.PostAdminPurgeHistoryRequest{RoomID: roomID, PurgeUpToTs: 5}, http.StatusForbidden, "M_FORBIDDEN"},
+		{"unknown room", admin, external.PostAdminPurgeHistoryRequest{RoomID: "!unknown:example.com", PurgeUpToTs: 5}, http.StatusNotFound, "M_NOT_FOUND"},
+		{"no bound", admin, external.PostAdminPurgeHistoryRequest{RoomID: roomID}, http.StatusBadRequest, "M_MISSING_ARGUMENT"},
+		{"unknown event", admin, external.PostAdminPurgeHistoryRequest{RoomID: roomID, PurgeUpToEventID: "$unknown:example.com"}, http.StatusNotFound, "M_NOT_FOUND"},
+		{"event of another room", admin, external.PostAdminPurgeHistoryRequest{RoomID: roomID, PurgeUpToEventID: "$other:example.com"}, http.StatusNotFound, "M_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		roomDB := &purgeRoomDB{roomID: roomID}
+		code, resp := AdminPurgeRoomHistory(context.Background(), &tt.req, tt.device, roomDB, newPurgeSyncDB(roomID, 10), &purgeCache{}, &common.RpcClient{})
+		if code != tt.code {
+			t.Fatalf("%s: code = %d, want %d", tt.name, code, tt.code)
+		}
+		if got := errCode(t, resp); got != tt.errcode {
+			t.Fatalf("%s: errcode = %s, want %s", tt.name, got, tt.errcode)
+		}
+		if len(roomDB.chunks) != 0 {
+			t.Fatalf("%s: roomserver events were purged", tt.name)
+		}
+	}
+}
+
+func TestAdminPurgeRoomHistory(t *testing.T) {
+	roomID := "!room:example.com"
+	admin := &authtypes.Device{UserID: superAdminUserID}
+
+	// purge_up_to_event_id purges before the timestamp of the event
+	roomDB := &purgeRoomDB{roomID: roomID}
+	syncDB := newPurgeSyncDB(roomID, 10)
+	cache := &purgeCache{}
+	req := &external.PostAdminPurgeHistoryRequest{RoomID: roomID, PurgeUpToEventID: "$4:example.com"}
+	code, resp := AdminPurgeRoomHistory(context.Background(), req, admin, roomDB, syncDB, cache, &common.RpcClient{})
+	if code != http.StatusOK {
+		t.Fatalf("code = %d %v, want 200", code, resp)
+	}
+	if purged := resp.(*external.PostAdminPurgeHistoryResponse).Purged; purged != 3 || syncDB.before != 4 {
+		t.Fatalf("purged %d events before %d, want 3 before 4", purged, syncDB.before)
+	}
+	if want := [][]string{{"$1:example.com", "$2:example.com", "$3:example.com"}}; !reflect.DeepEqual(roomDB.chunks, want) {
+		t.Fatalf("roomserver purged %v, want %v", roomDB.chunks, want)
+	}
+	if want := []string{roomID}; !reflect.DeepEqual(cache.txnRooms, want) {
+		t.Fatalf("txn ids dropped for %v, want %v", cache.txnRooms, want)
+	}
+
+	// the roomserver is purged in chunks
+	roomDB = &purgeRoomDB{roomID: roomID}
+	req = &external.PostAdminPurgeHistoryRequest{RoomID: roomID, PurgeUpToTs: 1501}
+	code, resp = AdminPurgeRoomHistory(context.Background(), req, admin, roomDB, newPurgeSyncDB(roomID, 2000), &purgeCache{}, &common.RpcClient{})
+	if code != http.StatusOK || resp.(*external.PostAdminPurgeHistoryResponse).Purged != 1500 {
+		t.Fatalf("code = %d %v, want 1500 events purged", code, resp)
+	}
+	if len(roomDB.chunks) != 2 || len(roomDB.chunks[0]) != 1000 || len(roomDB.chunks[1]) != 500 {
+		t.Fatalf("roomserver purged %d chunks, want 1000 and 500 events", len(roomDB.chunks))
+	}
+
+	// the roomserver follows a sync api purge that stopped half way
+	roomDB = &purgeRoomDB{roomID: roomID}
+	syncDB = newPurgeSyncDB(roomID, 10)
+	syncDB.err = errors.New("connection lost")
+	cache = &purgeCache{}
+	req = &external.PostAdminPurgeHistoryRequest{RoomID: roomID, PurgeUpToTs: 3}
+	code, _ = AdminPurgeRoomHistory(context.Background(), req, admin, roomDB, syncDB, cache, &common.RpcClient{})
+	if code != http.StatusInternalServerError {
+		t.Fatalf("code = %d, want 500", code)
+	}
+	if want := [][]string{{"$1:example.com", "$2:example.com"}}; !reflect.DeepEqual(roomDB.chunks, want) || len(cache.txnRooms) != 1 {
+		t.Fatalf("roomserver purged %v and txn ids dropped for %v after a failed sync purge", roomDB.chunks, cache.txnRooms)
+	}
+}
+
+// shutdownRoomDB records the blocks of a room without aliases
+type shutdownRoomDB struct {
+	model.RoomServerDatabase
+	blocked map[string]string
+}
+
+func (d *shutdownRoomDB) BlockRoom(ctx context.Context, roomID, blockedBy string, blockedTs int64) error {
+	d.blocked[roomID] = blockedBy
+	return nil
+}
+
+func (d *shutdownRoomDB) GetAliasesFromRoomID(ctx context.Context, roomID string) ([]string, error) {
+	return nil, nil
+}
+
+type shutdownRoomserver struct {
+	roomserverapi.RoomserverRPCAPI
+	roomID string
+}
+
+func (r *shutdownRoomserver) QueryRoomState(ctx context.Context, req *roomserverapi.QueryRoomStateRequest, res *roomserverapi.QueryRoomStateResponse) error {
+	res.RoomExists = req.RoomID == r.roomID
+	return nil
+}
+
+func TestAdminShutdownRoomBlock(t *testing.T) {
+	roomID := "!room:example.com"
+	cfg := testLoginConfig("password")
+	admin := &authtypes.Device{UserID: superAdminUserID}
+	tests := []struct {
+		name    string
+		device  *authtypes.Device
+		req     external.DelAdminRoomRequest
+		code    int
+		blocked bool
+	}{
+		{"no device", nil, external.DelAdminRoomRequest{RoomID: roomID, Block: true}, http.StatusForbidden, false},
+		{"not admin", &authtypes.Device{UserID: "@alice:example.com"}, external.DelAdminRoomRequest{RoomID: roomID, Block: true}, http.StatusForbidden, false},
+		{"unknown room", admin, external.DelAdminRoomRequest{RoomID: "!unknown:example.com", Block: true}, http.StatusNotFound, false},
+		{"no block", admin, external.DelAdminRoomRequest{RoomID: roomID}, http.StatusOK, false},
+		{"block", admin, external.DelAdminRoomRequest{RoomID: roomID, Block: true}, http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		roomDB := &shutdownRoomDB{blocked: map[string]string{}}
+		code, resp := AdminShutdownRoom(
+			context.Background(), &tt.req, tt.device, &cfg, nil, roomDB, nil, nil,
+			&shutdownRoomserver{roomID: roomID}, gomatrixserverlib.KeyRing{}, nil, nil, nil, &common.RpcClient{},
+		)
+		if code != tt.code {
+			t.Fatalf("%s: code = %d %v, want %d", tt.name, code, resp, tt.code)
+		}
+		if blockedBy, ok := roomDB.blocked[roomID]; ok != tt.blocked || (ok && blockedBy != superAdminUserID) {
+			t.Fatalf("%s: blocked = %v, want %t", tt.name, roomDB.blocked, tt.blocked)
+		}
+	}
+}
@@ -49,8 +49,11 @@ const (
 )
 
 const (
-	joinRulePublic = "public"
-	joinRuleInvite = "invite"
+	joinRulePublic          = "public"
+	joinRuleInvite          = "invite"
+	joinRuleKnock           = "knock"
+	joinRuleRestricted      = "restricted"
+	joinRuleKnockRestricted = "knock_restricted"
 )
 const (
 	historyVisibilityShared        = "shared"         //only after join can read
@@ -82,10 +85,24 @@ func validate(r *external.PostCreateRoomRequest) (int, *jsonerror.MatrixError) {
 	default:
 		return http.StatusBadRequest, jsonerror.BadJSON("preset must be any of 'private_chat', 'trusted_private_chat', 'public_chat'")
 	}
+	if r.RoomVersion != "" {
+		if _, ok := gomatrixserverlib.SupportedRoomVersions()[gomatrixserverlib.RoomVersion(r.RoomVersion)]; !ok {
+			return http.StatusBadRequest, jsonerror.UnsupportedRoomVersion(fmt.Sprintf("Room version %q is not supported", r.RoomVersion))
+		}
+	}
 
 	return http.StatusOK, nil
 }
 
+// supportedRoomVersions lists the room versions we can join remote rooms of
+func supportedRoomVersions() []string {
+	versions := []string{}
+	for _, v := range gomatrixserverlib.SortedRoomVersions() {
+		versions = append(versions, string(v))
+	}
+	return versions
+}
+
 // CreateRoom implements /createRoom
 func CreateRoom(ctx context.Context, r *external.PostCreateRoomRequest, userID string,
 	cfg config.Dendrite,
@@ -221,7 +238,11 @@ func createRoom(ctx context.Context, r *external.PostCreateRoomRequest,
 	//r.CreationContent["creator"] = userID
 	//r.CreationContent["is_direct"] = r.IsDrirect
 
-	createContent := common.CreateContent{Creator: userID, Federate: &federate, IsDirect: &r.IsDirect}
+	roomVersion := cfg.Matrix.DefaultRoomVersion
+	if r.RoomVersion != "" {
+		roomVersion = gomatrixserverlib.RoomVersion(r.RoomVersion)
+	}
+	createContent := common.CreateContent{Creator: userID, Federate: &federate, IsDirect: &r.IsDirect, RoomVersion: roomVersion}
 
 	mapVal, ok := r.CreationContent["enable_watermark"]
 	if ok {
@@ -264,6 +285,11 @@ func createRoom(ctx context.Context, r *external.PostCreateRoomRequest,
 		val := int(mapVal.(float64))
 		createContent.RoomType = &val
 	}
+	mapVal, ok = r.CreationContent["type"]
+	if ok {
+		val, _ := mapVal.(string)
+		createContent.Type = val
+	}
 
 	eventsToMake := []external.StateEvent{
 		{Type: "m.room.create", Content: createContent},
@@ -343,7 +369,7 @@ func createRoom(ctx context.Context, r *external.PostCreateRoomRequest,
 		if err != nil {
 			return httputil.LogThenErrorCtx(ctx, err)
 		}
-		ev, err := common.BuildEvent(&builder, domainID, cfg, idg)
+		ev, err := common.BuildEvent(&builder, domainID, roomVersion, idg)
 		if err != nil {
 			return httputil.LogThenErrorCtx(ctx, err)
 		}
@@ -0,0 +1,190 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/finogeeks/ligase/clientapi/httputil"
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/filter"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/common/uid"
+	"github.com/finogeeks/ligase/core"
+	fed "github.com/finogeeks/ligase/federation/fedreq"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	util "github.com/finogeeks/ligase/skunkworks/gomatrixutil"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// Deactivate implements POST /account/deactivate
+func Deactivate(
+	ctx context.Context,
+	req *external.PostAccountDeactivateRequest,
+	device *authtypes.Device,
+	cfg config.Dendrite,
+	accountDB model.AccountsDatabase,
+	deviceDB model.DeviceDatabase,
+	pushDB model.PushAPIDatabase,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	tokenFilter *filter.Filter,
+	rpcClient *common.RpcClient,
+	rsRpcCli roomserverapi.RoomserverRPCAPI,
+	federation *fed.Federation,
+	idg *uid.UidGenerator,
+	complexCache *common.ComplexCache,
+) (int, core.Coder) {
+	sessionID := req.Auth.Session
+	if sessionID == "" {
+		sessionID = util.RandomString(sessionIDLength)
+	}
+
+	// If no auth type is specified by the client, send back the list of available flows
+	if req.Auth.Type == "" {
+		flows := []external.AuthFlow{{Stages: []string{authtypes.LoginTypePassword}}}
+		return http.StatusUnauthorized, newUserInteractiveResponse(sessionID, flows, nil)
+	}
+
+	userID, code, resErr := checkPasswordAuth(ctx, &req.Auth, device, accountDB, cache, &cfg)
+	if resErr != nil {
+		return code, resErr
+	}
+
+	return deactivateAccount(
+		ctx, userID, cfg, accountDB, deviceDB, pushDB, encryptDB, syncDB, cache,
+		tokenFilter, rpcClient, rsRpcCli, federation, idg, complexCache,
+	)
+}
+
+// AdminDeactivate implements POST /_ligase/admin/v1/users/{userID}/deactivate
+// It lets the super admin offboard any local user, the super admin token is
+// only handed out on the internal API.
+func AdminDeactivate(
+	ctx context.Context,
+	req *external.PostAdminDeactivateRequest,
+	device *authtypes.Device,
+	cfg config.Dendrite,
+	accountDB model.AccountsDatabase,
+	deviceDB model.DeviceDatabase,
+	pushDB model.PushAPIDatabase,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	tokenFilter *filter.Filter,
+	rpcClient *common.RpcClient,
+	rsRpcCli roomserverapi.RoomserverRPCAPI,
+	federation *fed.Federation,
+	idg *uid.UidGenerator,
+	complexCache *common.ComplexCache,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can deactivate accounts")
+	}
+
+	domain, err := common.DomainFromID(req.UserID)
+	if err != nil {
+		return http.StatusBadRequest, jsonerror.InvalidUsername("User ID must be @localpart:domain")
+	}
+	if !common.CheckValidDomain(domain, cfg.Matrix.ServerName) {
+		return http.StatusBadRequest, jsonerror.InvalidUsername("Can only deactivate local users")
+	}
+
+	account, err := accountDB.GetAccount(ctx, req.UserID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if account == nil || account.UserID == "" {
+		return http.StatusNotFound, jsonerror.NotFound("Unknown user")
+	}
+
+	return deactivateAccount(
+		ctx, req.UserID, cfg, accountDB, deviceDB, pushDB, encryptDB, syncDB, cache,
+		tokenFilter, rpcClient, rsRpcCli, federation, idg, complexCache,
+	)
+}
+
+// deactivateAccount marks the account first so that it can't log in again
+// while the cleanup is running, then makes the user leave every joined room,
+// clears the profile, and logs out all devices along with their keys, to-device
+// messages and pushers.
+func deactivateAccount(
+	ctx context.Context,
+	userID string,
+	cfg config.Dendrite,
+	accountDB model.AccountsDatabase,
+	deviceDB model.DeviceDatabase,
+	pushDB model.PushAPIDatabase,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	tokenFilter *filter.Filter,
+	rpcClient *common.RpcClient,
+	rsRpcCli roomserverapi.RoomserverRPCAPI,
+	federation *fed.Federation,
+	idg *uid.UidGenerator,
+	complexCache *common.ComplexCache,
+) (int, core.Coder) {
+	log.Infof("deactivate account user %s", userID)
+	if err := accountDB.DeactivateAccount(ctx, userID); err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+
+	var request roomserverapi.QueryJoinRoomsRequest
+	request.UserID = userID
+	var response roomserverapi.QueryJoinRoomsResponse
+	err := rsRpcCli.QueryJoinRooms(ctx, &request, &response)
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("deactivate account query join rooms error, user: %s, error: %v", userID, err)
+	}
+	for _, roomID := range response.Rooms {
+		r := &external.PostRoomsMembershipRequest{RoomID: roomID, Membership: "leave"}
+		code, resp := SendMembership(
+			ctx, r, accountDB, userID, "", roomID, "leave", cfg,
+			rsRpcCli, federation, cache, idg, complexCache,
+		)
+		if code != http.StatusOK {
+			log.Errorf("deactivate account leave room error, user: %s, room: %s, code: %d, resp: %v", userID, roomID, code, resp)
+		}
+	}
+
+	if err := complexCache.SetProfile(ctx, userID, "", ""); err != nil {
+		log.Errorf("deactivate account clear profile error, user: %s, error: %v", userID, err)
+	}
+	if err := accountDB.DeleteUserInfo(ctx, userID); err != nil {
+		log.Errorf("deactivate account delete user info error, user: %s, error: %v", userID, err)
+	}
+	if err := accountDB.RemoveThreePIDsForUser(ctx, userID); err != nil {
+		log.Errorf("deactivate account remove 3pids error, user: %s, error: %v", userID, err)
+	}
+
+	deviceList := cache.GetDevicesByUserID(userID)
+	if deviceList != nil {
+		for _, dev := range *deviceList {
+			LogoutDevice(ctx, userID, dev.ID, deviceDB, cache, encryptDB, syncDB, tokenFilter, rpcClient)
+		}
+	}
+	deleteDevicePushers(ctx, userID, "", pushDB, cache)
+
+	return http.StatusOK, nil
+}
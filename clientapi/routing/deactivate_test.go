@@ -0,0 +1,255 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/pushapitypes"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// deactivateCache serves the devices and pushers of a user and records what
+// the cleanup deletes from the cache.
+type deactivateCache struct {
+	*fakeCache
+	devices        []authtypes.Device
+	pushers        map[string]*pushapitypes.PusherCacheData
+	deletedKeys    []string
+	deletedProfile []string
+}
+
+func (c *deactivateCache) GetDevicesByUserID(userID string) *[]authtypes.Device {
+	return &c.devices
+}
+
+func (c *deactivateCache) GetUserPusherIds(userID string) ([]string, bool) {
+	ids := []string{}
+	for id := range c.pushers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, len(ids) > 0
+}
+
+func (c *deactivateCache) GetPusherCacheData(pusherKey string) (*pushapitypes.PusherCacheData, bool) {
+	data, ok := c.pushers[pusherKey]
+	return data, ok
+}
+
+func (c *deactivateCache) DeleteDeviceOneTimeKey(userID, deviceID string) error {
+	return nil
+}
+
+func (c *deactivateCache) DeleteDeviceKey(userID, deviceID string) error {
+	c.deletedKeys = append(c.deletedKeys, deviceID)
+	return nil
+}
+
+func (c *deactivateCache) DelProfile(userID string) error {
+	c.deletedProfile = append(c.deletedProfile, userID)
+	return nil
+}
+
+// deactivateAccountsDB records the account changes made by the cleanup.
+type deactivateAccountsDB struct {
+	*fakeAccountsDB
+	profiles        map[string][2]string
+	userInfoDeleted bool
+	threePIDRemoved bool
+}
+
+func (d *deactivateAccountsDB) DeactivateAccount(ctx context.Context, userID string) error {
+	d.deactivated[userID] = true
+	return nil
+}
+
+func (d *deactivateAccountsDB) UpsertProfileSync(ctx context.Context, userID, displayName, avatarURL string) error {
+	d.profiles[userID] = [2]string{displayName, avatarURL}
+	return nil
+}
+
+func (d *deactivateAccountsDB) DeleteUserInfo(ctx context.Context, userID string) error {
+	d.userInfoDeleted = true
+	return nil
+}
+
+func (d *deactivateAccountsDB) RemoveThreePIDsForUser(ctx context.Context, userID string) error {
+	d.threePIDRemoved = true
+	return nil
+}
+
+type fakeDeviceDB struct {
+	model.DeviceDatabase
+	removed       []string
+	refreshTokens []string
+}
+
+func (d *fakeDeviceDB) RemoveDevice(ctx context.Context, deviceID, userID string, createTs int64) error {
+	d.removed = append(d.removed, deviceID)
+	return nil
+}
+
+func (d *fakeDeviceDB) RemoveDeviceRefreshTokens(ctx context.Context, userID, deviceID string) error {
+	d.refreshTokens = append(d.refreshTokens, deviceID)
+	return nil
+}
+
+type fakeEncryptDB struct {
+	model.EncryptorAPIDatabase
+	deleted []string
+}
+
+func (d *fakeEncryptDB) DeleteDeviceKeys(ctx context.Context, deviceID, userID string) error {
+	d.deleted = append(d.deleted, deviceID)
+	return nil
+}
+
+type fakeSyncDB struct {
+	model.SyncAPIDatabase
+	deleted []string
+}
+
+func (d *fakeSyncDB) DeleteDeviceStdMessage(ctx context.Context, targetUID, targetDevice string) error {
+	d.deleted = append(d.deleted, targetDevice)
+	return nil
+}
+
+type fakePushDB struct {
+	model.PushAPIDatabase
+	deleted []string
+}
+
+func (d *fakePushDB) DeleteUserPushers(ctx context.Context, userID, appID, pushKey string) error {
+	d.deleted = append(d.deleted, appID+"/"+pushKey)
+	return nil
+}
+
+// fakeRoomserver reports that the user hasn't joined any room, so that the
+// cleanup doesn't send leave events.
+type fakeRoomserver struct {
+	roomserverapi.RoomserverRPCAPI
+}
+
+func (r *fakeRoomserver) QueryJoinRooms(ctx context.Context, request *roomserverapi.QueryJoinRoomsRequest, response *roomserverapi.QueryJoinRoomsResponse) error {
+	response.UserID = request.UserID
+	return nil
+}
+
+func TestDeactivateAccountCleanup(t *testing.T) {
+	userID := "@alice:example.com"
+	cfg := testLoginConfig("password")
+	accountDB := &deactivateAccountsDB{
+		fakeAccountsDB: &fakeAccountsDB{deactivated: map[string]bool{}},
+		profiles:       map[string][2]string{userID: {"Alice", "mxc://example.com/a"}},
+	}
+	cache := &deactivateCache{
+		fakeCache: newFakeCache(),
+		devices:   []authtypes.Device{{ID: "DEV1", UserID: userID}, {ID: "DEV2", UserID: userID}},
+		pushers: map[string]*pushapitypes.PusherCacheData{
+			"p1": {AppId: "app", PushKey: "key1", DeviceID: "DEV1"},
+			"p2": {AppId: "app", PushKey: "key2", DeviceID: "DEV2"},
+		},
+	}
+	deviceDB := &fakeDeviceDB{}
+	encryptDB := &fakeEncryptDB{}
+	syncDB := &fakeSyncDB{}
+	pushDB := &fakePushDB{}
+
+	code, resp := deactivateAccount(
+		context.Background(), userID, cfg, accountDB, deviceDB, pushDB, encryptDB, syncDB,
+		cache, nil, &common.RpcClient{}, &fakeRoomserver{}, nil, nil,
+		common.NewComplexCache(accountDB, cache),
+	)
+	if code != http.StatusOK {
+		t.Fatalf("deactivateAccount returned %d %v", code, resp)
+	}
+
+	if !accountDB.deactivated[userID] {
+		t.Error("the account is not marked deactivated")
+	}
+	if p := accountDB.profiles[userID]; p != [2]string{"", ""} {
+		t.Errorf("profile = %v, want it cleared", p)
+	}
+	if !reflect.DeepEqual(cache.deletedProfile, []string{userID}) {
+		t.Errorf("profile cache deleted for %v", cache.deletedProfile)
+	}
+	if !accountDB.userInfoDeleted || !accountDB.threePIDRemoved {
+		t.Error("the user info and 3pids are kept")
+	}
+
+	devices := []string{"DEV1", "DEV2"}
+	for name, got := range map[string][]string{
+		"devices":          deviceDB.removed,
+		"refresh tokens":   deviceDB.refreshTokens,
+		"device keys":      encryptDB.deleted,
+		"cached keys":      cache.deletedKeys,
+		"to-device events": syncDB.deleted,
+	} {
+		if !reflect.DeepEqual(got, devices) {
+			t.Errorf("%s deleted for %v, want %v", name, got, devices)
+		}
+	}
+	if want := []string{"app/key1", "app/key2"}; !reflect.DeepEqual(pushDB.deleted, want) {
+		t.Errorf("pushers deleted = %v, want %v", pushDB.deleted, want)
+	}
+}
+
+func TestLoginDeactivatedAccount(t *testing.T) {
+	userID := "@alice:example.com"
+	accountDB := &fakeAccountsDB{deactivated: map[string]bool{userID: true}}
+	code, resp := completeLogin(userID, context.Background(), external.PostLoginRequest{}, testLoginConfig("password"), nil, accountDB, nil, nil, nil, nil)
+	if code != http.StatusForbidden || errCode(t, resp) != "M_USER_DEACTIVATED" {
+		t.Errorf("login of a deactivated account returned %d %v", code, resp)
+	}
+}
+
+func TestRegisterDeactivatedAccount(t *testing.T) {
+	userID := "@alice:example.com"
+	cfg := testLoginConfig("password")
+	accountDB := &fakeAccountsDB{deactivated: map[string]bool{userID: true}}
+	code, resp := completeRegistration(context.Background(), &cfg, accountDB, nil, userID, "secret", "", "", false, nil)
+	if code != http.StatusBadRequest || errCode(t, resp) != "M_USER_IN_USE" {
+		t.Errorf("registering a deactivated user ID returned %d %v", code, resp)
+	}
+}
+
+func TestAdminDeactivateRequiresSuperAdmin(t *testing.T) {
+	userID := "@bob:example.com"
+	cfg := testLoginConfig("password")
+	req := &external.PostAdminDeactivateRequest{UserID: userID}
+	for _, device := range []*authtypes.Device{
+		nil,
+		{UserID: "@alice:example.com"},
+		{UserID: "@super_admin:example.com"},
+	} {
+		accountDB := &fakeAccountsDB{deactivated: map[string]bool{}}
+		code, resp := AdminDeactivate(context.Background(), req, device, cfg, accountDB, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+		if code != http.StatusForbidden || errCode(t, resp) != "M_FORBIDDEN" {
+			t.Fatalf("device %v: code = %d, want 403", device, code)
+		}
+		if accountDB.deactivated[userID] {
+			t.Fatalf("device %v deactivated %s", device, userID)
+		}
+	}
+}
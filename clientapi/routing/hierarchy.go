@@ -0,0 +1,314 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/skunkworks/log"
+)
+
+const (
+	hierarchyDefaultLimit = 50
+	hierarchyMaxLimit     = 100
+	// longest order of m.space.child that is taken into account
+	spaceChildMaxOrderLen = 50
+)
+
+// hierarchyRoom is a room waiting to be walked, state is only set for the root
+type hierarchyRoom struct {
+	roomID string
+	depth  int
+	state  *roomserverapi.QueryRoomStateResponse
+}
+
+// spaceChild is a m.space.child event along with its parsed content
+type spaceChild struct {
+	ev      *gomatrixserverlib.Event
+	content common.SpaceChildContent
+}
+
+// GetRoomHierarchy implements GET /rooms/{roomId}/hierarchy
+// The space is walked breadth first through the m.space.child events of the
+// local rooms. Rooms the user can neither see nor join are skipped along with
+// their children. The next_batch token is the number of rooms already returned.
+func GetRoomHierarchy(
+	ctx context.Context,
+	req *external.GetRoomHierarchyRequest,
+	userID string,
+	rpcCli roomserverapi.RoomserverRPCAPI,
+) (int, core.Coder) {
+	limit := hierarchyDefaultLimit
+	if req.Limit != "" {
+		l, err := strconv.Atoi(req.Limit)
+		if err != nil || l <= 0 {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("limit must be a positive integer")
+		}
+		if l < hierarchyMaxLimit {
+			limit = l
+		} else {
+			limit = hierarchyMaxLimit
+		}
+	}
+	maxDepth := -1
+	if req.MaxDepth != "" {
+		d, err := strconv.Atoi(req.MaxDepth)
+		if err != nil || d < 0 {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("max_depth must be a non-negative integer")
+		}
+		maxDepth = d
+	}
+	from := 0
+	if req.From != "" {
+		f, err := strconv.Atoi(req.From)
+		if err != nil || f < 0 {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Invalid from token")
+		}
+		from = f
+	}
+
+	var rootRes roomserverapi.QueryRoomStateResponse
+	rootReq := roomserverapi.QueryRoomStateRequest{RoomID: req.RoomID}
+	if err := rpcCli.QueryRoomState(ctx, &rootReq, &rootRes); err != nil || !rootRes.RoomExists {
+		return http.StatusNotFound, jsonerror.NotFound("Room does not exist")
+	}
+	if !canPeekSpaceRoom(ctx, rpcCli, userID, &rootRes) {
+		return http.StatusForbidden, jsonerror.Forbidden("You are not allowed to view this room")
+	}
+
+	rooms := []external.HierarchyRoomsChunk{}
+	queue := []hierarchyRoom{{roomID: req.RoomID, state: &rootRes}}
+	seen := map[string]bool{req.RoomID: true}
+	walked := 0
+	more := false
+	for len(queue) > 0 {
+		room := queue[0]
+		queue = queue[1:]
+
+		state := room.state
+		if state == nil {
+			state = &roomserverapi.QueryRoomStateResponse{}
+			queryReq := roomserverapi.QueryRoomStateRequest{RoomID: room.roomID}
+			// Rooms of other servers aren't walked
+			if err := rpcCli.QueryRoomState(ctx, &queryReq, state); err != nil || !state.RoomExists {
+				continue
+			}
+			if !canPeekSpaceRoom(ctx, rpcCli, userID, state) {
+				continue
+			}
+		}
+		if walked >= from+limit {
+			more = true
+			break
+		}
+
+		children := spaceChildren(state, req.SuggestedOnly)
+		if walked >= from {
+			rooms = append(rooms, hierarchyChunk(state, children))
+		}
+		walked++
+
+		if maxDepth >= 0 && room.depth >= maxDepth {
+			continue
+		}
+		for _, child := range children {
+			childID := *child.ev.StateKey()
+			if !seen[childID] {
+				seen[childID] = true
+				queue = append(queue, hierarchyRoom{roomID: childID, depth: room.depth + 1})
+			}
+		}
+	}
+
+	resp := &external.GetRoomHierarchyResponse{Rooms: rooms}
+	if more {
+		resp.NextBatch = strconv.Itoa(from + limit)
+	}
+	return http.StatusOK, resp
+}
+
+// canPeekSpaceRoom returns true if the user is in the room, may join or knock
+// on it, or if anyone can read it.
+func canPeekSpaceRoom(
+	ctx context.Context, rpcCli roomserverapi.RoomserverRPCAPI,
+	userID string, state *roomserverapi.QueryRoomStateResponse,
+) bool {
+	if _, ok := state.Join[userID]; ok {
+		return true
+	}
+	if _, ok := state.Invite[userID]; ok {
+		return true
+	}
+	if state.HistoryVisibility != nil {
+		visibility := common.HistoryVisibilityContent{}
+		json.Unmarshal(state.HistoryVisibility.Content(), &visibility)
+		if visibility.HistoryVisibility == historyVisibilityWorldReadable {
+			return true
+		}
+	}
+
+	rule := common.JoinRulesContent{}
+	if state.JoinRule != nil {
+		json.Unmarshal(state.JoinRule.Content(), &rule)
+	}
+	switch rule.JoinRule {
+	case joinRulePublic, joinRuleKnock, joinRuleKnockRestricted:
+		return true
+	case joinRuleRestricted:
+		return inAllowedRooms(ctx, rpcCli, userID, rule)
+	}
+	return false
+}
+
+// inAllowedRooms returns true if the user is joined to one of the local rooms
+// in the allow list of the join rules.
+func inAllowedRooms(
+	ctx context.Context, rpcCli roomserverapi.RoomserverRPCAPI,
+	userID string, rule common.JoinRulesContent,
+) bool {
+	for _, allow := range rule.Allow {
+		if allow.Type != "m.room_membership" || allow.RoomID == "" {
+			continue
+		}
+		var queryRes roomserverapi.QueryRoomStateResponse
+		queryReq := roomserverapi.QueryRoomStateRequest{RoomID: allow.RoomID}
+		if err := rpcCli.QueryRoomState(ctx, &queryReq, &queryRes); err != nil {
+			log.Warnf("inAllowedRooms query room:%s state err:%v", allow.RoomID, err)
+			continue
+		}
+		if _, ok := queryRes.Join[userID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// spaceChildren returns the m.space.child events of the room which still have
+// a via, in the order of the spec: by order, then by timestamp, then by room ID.
+func spaceChildren(state *roomserverapi.QueryRoomStateResponse, suggestedOnly bool) []spaceChild {
+	children := []spaceChild{}
+	for _, ev := range state.SpaceChild {
+		content := common.SpaceChildContent{}
+		if err := json.Unmarshal(ev.Content(), &content); err != nil || len(content.Via) == 0 {
+			continue
+		}
+		if suggestedOnly && !content.Suggested {
+			continue
+		}
+		if !validSpaceChildOrder(content.Order) {
+			content.Order = ""
+		}
+		children = append(children, spaceChild{ev: ev, content: content})
+	}
+
+	sort.Slice(children, func(i, j int) bool {
+		oi, oj := children[i].content.Order, children[j].content.Order
+		if oi != oj {
+			if oi == "" || oj == "" {
+				return oj == ""
+			}
+			return oi < oj
+		}
+		ti, tj := children[i].ev.OriginServerTS(), children[j].ev.OriginServerTS()
+		if ti != tj {
+			return ti < tj
+		}
+		return *children[i].ev.StateKey() < *children[j].ev.StateKey()
+	})
+	return children
+}
+
+// validSpaceChildOrder returns true if order is made of at most 50 printable
+// ASCII characters.
+func validSpaceChildOrder(order string) bool {
+	if len(order) > spaceChildMaxOrderLen {
+		return false
+	}
+	for i := 0; i < len(order); i++ {
+		if order[i] < 0x20 || order[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// hierarchyChunk summarises the room and its children for the hierarchy
+func hierarchyChunk(state *roomserverapi.QueryRoomStateResponse, children []spaceChild) external.HierarchyRoomsChunk {
+	chunk := external.HierarchyRoomsChunk{
+		RoomID:           state.RoomID,
+		NumJoinedMembers: int64(len(state.Join)),
+		ChildrenState:    []external.HierarchyChildState{},
+	}
+	if state.Name != nil {
+		content := common.NameContent{}
+		json.Unmarshal(state.Name.Content(), &content)
+		chunk.Name = content.Name
+	}
+	if state.Topic != nil {
+		content := common.TopicContent{}
+		json.Unmarshal(state.Topic.Content(), &content)
+		chunk.Topic = content.Topic
+	}
+	if state.CanonicalAlias != nil {
+		content := common.CanonicalAliasContent{}
+		json.Unmarshal(state.CanonicalAlias.Content(), &content)
+		chunk.CanonicalAlias = content.Alias
+	}
+	if state.Avatar != nil {
+		content := common.AvatarContent{}
+		json.Unmarshal(state.Avatar.Content(), &content)
+		chunk.AvatarURL = content.URL
+	}
+	if state.GuestAccess != nil {
+		content := common.GuestAccessContent{}
+		json.Unmarshal(state.GuestAccess.Content(), &content)
+		chunk.GuestCanJoin = content.GuestAccess == "can_join"
+	}
+	if state.HistoryVisibility != nil {
+		content := common.HistoryVisibilityContent{}
+		json.Unmarshal(state.HistoryVisibility.Content(), &content)
+		chunk.WorldReadable = content.HistoryVisibility == historyVisibilityWorldReadable
+	}
+	if state.JoinRule != nil {
+		content := common.JoinRulesContent{}
+		json.Unmarshal(state.JoinRule.Content(), &content)
+		chunk.JoinRule = content.JoinRule
+	}
+	if state.Creator != nil {
+		content := common.CreateContent{}
+		json.Unmarshal(state.Creator.Content(), &content)
+		chunk.RoomType = content.Type
+	}
+
+	for _, child := range children {
+		chunk.ChildrenState = append(chunk.ChildrenState, external.HierarchyChildState{
+			Type:           child.ev.Type(),
+			StateKey:       *child.ev.StateKey(),
+			Content:        child.ev.Content(),
+			Sender:         child.ev.Sender(),
+			OriginServerTS: int64(child.ev.OriginServerTS()),
+		})
+	}
+	return chunk
+}
@@ -0,0 +1,198 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/uid"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+)
+
+type spaceRoomserver struct {
+	roomserverapi.RoomserverRPCAPI
+	rooms map[string]roomserverapi.QueryRoomStateResponse
+}
+
+func (r *spaceRoomserver) QueryRoomState(ctx context.Context, req *roomserverapi.QueryRoomStateRequest, res *roomserverapi.QueryRoomStateResponse) error {
+	state, ok := r.rooms[req.RoomID]
+	if !ok {
+		return errors.New("room not found")
+	}
+	*res = state
+	return nil
+}
+
+// addRoom creates the room with alice as its creator, the join rule and the
+// extra state events.
+func (r *spaceRoomserver) addRoom(t *testing.T, idg *uid.UidGenerator, roomID, joinRule string, allow []string, extra ...external.StateEvent) {
+	creator := "@alice:example.com"
+	rule := common.JoinRulesContent{JoinRule: joinRule}
+	for _, room := range allow {
+		rule.Allow = append(rule.Allow, common.JoinRuleAllow{Type: "m.room_membership", RoomID: room})
+	}
+	stateEvents := append([]external.StateEvent{
+		{Type: "m.room.create", Content: common.CreateContent{Creator: creator}},
+		{Type: "m.room.member", StateKey: creator, Content: external.MemberContent{Membership: "join"}},
+		{Type: "m.room.power_levels", Content: common.InitialPowerLevelsContent(creator)},
+		{Type: "m.room.join_rules", Content: rule},
+	}, extra...)
+	var events []gomatrixserverlib.Event
+	for _, e := range stateEvents {
+		sender := creator
+		if e.Type == "m.room.member" {
+			sender = e.StateKey
+		}
+		ev, err := buildUpgradeEvent(sender, roomID, e.Type, e.StateKey, e.Content, "example.com", gomatrixserverlib.RoomVersionV1, idg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, *ev)
+	}
+	var state roomserverapi.QueryRoomStateResponse
+	state.InitFromEvents(events)
+	r.rooms[roomID] = state
+}
+
+func spaceChildEvent(roomID, order string) external.StateEvent {
+	return external.StateEvent{
+		Type:     "m.space.child",
+		StateKey: roomID,
+		Content:  common.SpaceChildContent{Via: []string{"example.com"}, Order: order},
+	}
+}
+
+func joinedEvent(userID string) external.StateEvent {
+	return external.StateEvent{Type: "m.room.member", StateKey: userID, Content: external.MemberContent{Membership: "join"}}
+}
+
+// newSpaceRoomserver builds a space bob is joined to, with a public child
+// that has a child of its own, an invite only child, a child restricted to
+// the members of the space and a child on another server.
+func newSpaceRoomserver(t *testing.T) *spaceRoomserver {
+	idg, _ := uid.NewDefaultIdGenerator(0)
+	rs := &spaceRoomserver{rooms: map[string]roomserverapi.QueryRoomStateResponse{}}
+	rs.addRoom(t, idg, "!space:example.com", joinRulePublic, nil,
+		joinedEvent("@bob:example.com"),
+		spaceChildEvent("!public:example.com", "b"),
+		spaceChildEvent("!private:example.com", "c"),
+		spaceChildEvent("!restricted:example.com", "a"),
+		spaceChildEvent("!remote:other.org", "d"),
+	)
+	rs.addRoom(t, idg, "!public:example.com", joinRulePublic, nil, spaceChildEvent("!sub:example.com", ""))
+	rs.addRoom(t, idg, "!private:example.com", joinRuleInvite, nil)
+	rs.addRoom(t, idg, "!restricted:example.com", joinRuleRestricted, []string{"!space:example.com"})
+	rs.addRoom(t, idg, "!sub:example.com", joinRulePublic, nil)
+	return rs
+}
+
+func hierarchyRoomIDs(resp interface{}) []string {
+	ids := []string{}
+	for _, room := range resp.(*external.GetRoomHierarchyResponse).Rooms {
+		ids = append(ids, room.RoomID)
+	}
+	return ids
+}
+
+func TestGetRoomHierarchy(t *testing.T) {
+	rs := newSpaceRoomserver(t)
+	tests := []struct {
+		name      string
+		userID    string
+		req       external.GetRoomHierarchyRequest
+		want      []string
+		nextBatch string
+	}{
+		{
+			name:   "whole space",
+			userID: "@bob:example.com",
+			want:   []string{"!space:example.com", "!restricted:example.com", "!public:example.com", "!sub:example.com"},
+		},
+		{
+			name:   "outside the allowed rooms",
+			userID: "@carol:example.com",
+			want:   []string{"!space:example.com", "!public:example.com", "!sub:example.com"},
+		},
+		{
+			name:   "max depth",
+			userID: "@bob:example.com",
+			req:    external.GetRoomHierarchyRequest{MaxDepth: "1"},
+			want:   []string{"!space:example.com", "!restricted:example.com", "!public:example.com"},
+		},
+		{
+			name:      "first page",
+			userID:    "@bob:example.com",
+			req:       external.GetRoomHierarchyRequest{Limit: "2"},
+			want:      []string{"!space:example.com", "!restricted:example.com"},
+			nextBatch: "2",
+		},
+		{
+			name:   "second page",
+			userID: "@bob:example.com",
+			req:    external.GetRoomHierarchyRequest{Limit: "2", From: "2"},
+			want:   []string{"!public:example.com", "!sub:example.com"},
+		},
+	}
+	for _, tt := range tests {
+		req := tt.req
+		req.RoomID = "!space:example.com"
+		code, resp := GetRoomHierarchy(context.Background(), &req, tt.userID, rs)
+		if code != http.StatusOK {
+			t.Fatalf("%s: code = %d, want 200", tt.name, code)
+		}
+		got := hierarchyRoomIDs(resp)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: rooms = %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%s: rooms = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+		if next := resp.(*external.GetRoomHierarchyResponse).NextBatch; next != tt.nextBatch {
+			t.Fatalf("%s: next_batch = %q, want %q", tt.name, next, tt.nextBatch)
+		}
+	}
+}
+
+func TestGetRoomHierarchyErrors(t *testing.T) {
+	rs := newSpaceRoomserver(t)
+	tests := []struct {
+		name    string
+		req     external.GetRoomHierarchyRequest
+		code    int
+		errcode string
+	}{
+		{"unknown room", external.GetRoomHierarchyRequest{RoomID: "!unknown:example.com"}, http.StatusNotFound, "M_NOT_FOUND"},
+		{"invite only root", external.GetRoomHierarchyRequest{RoomID: "!private:example.com"}, http.StatusForbidden, "M_FORBIDDEN"},
+		{"bad limit", external.GetRoomHierarchyRequest{RoomID: "!space:example.com", Limit: "0"}, http.StatusBadRequest, "M_INVALID_ARGUMENT_VALUE"},
+		{"bad from", external.GetRoomHierarchyRequest{RoomID: "!space:example.com", From: "x"}, http.StatusBadRequest, "M_INVALID_ARGUMENT_VALUE"},
+	}
+	for _, tt := range tests {
+		code, resp := GetRoomHierarchy(context.Background(), &tt.req, "@bob:example.com", rs)
+		if code != tt.code {
+			t.Fatalf("%s: code = %d, want %d", tt.name, code, tt.code)
+		}
+		if got := errCode(t, resp); got != tt.errcode {
+			t.Fatalf("%s: errcode = %s, want %s", tt.name, got, tt.errcode)
+		}
+	}
+}
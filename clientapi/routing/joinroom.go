@@ -186,13 +186,13 @@ func (r joinRoomReq) joinRoom(
 		domainID, _ := common.DomainFromID(roomID)
 		if common.CheckValidDomain(domainID, r.cfg.Matrix.ServerName) == false {
 			// resp, err := r.federation.LookupState(domainID, roomID)
-			resp, err := r.federation.MakeJoin(domainID, roomID, r.userID, []string{"1"})
+			resp, err := r.federation.MakeJoin(domainID, roomID, r.userID, supportedRoomVersions())
 			if err != nil {
 				return httputil.LogThenErrorCtx(r.ctx, err)
 			}
 			builder := resp.JoinEvent
 			sendDomain, _ := common.DomainFromID(r.userID)
-			ev, err := common.BuildEvent(&builder, sendDomain, r.cfg, idg)
+			ev, err := common.BuildEvent(&builder, sendDomain, resp.GetRoomVersion(), idg)
 			if err != nil {
 				log.Errorf("make join build event err %v", err)
 				return httputil.LogThenErrorCtx(r.ctx, err)
@@ -234,7 +234,7 @@ func (r joinRoomReq) joinRoom(
 		return httputil.LogThenErrorCtx(r.ctx, err)
 	}
 
-	event, err := common.BuildEvent(&eb, domainID, r.cfg, idg)
+	event, err := common.BuildEvent(&eb, domainID, gomatrixserverlib.AuthEventsRoomVersion(&queryRes), idg)
 	if err != nil {
 		return httputil.LogThenErrorCtx(r.ctx, err)
 	}
@@ -19,32 +19,84 @@ package routing
 
 import (
 	"context"
+	"crypto/subtle"
+	"database/sql"
 	"fmt"
 	"github.com/finogeeks/ligase/model/authtypes"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/finogeeks/ligase/clientapi/httputil"
+	"github.com/finogeeks/ligase/clientapi/threepid"
 	"github.com/finogeeks/ligase/common"
 	"github.com/finogeeks/ligase/common/config"
 	"github.com/finogeeks/ligase/common/filter"
 	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/common/jwt"
+	"github.com/finogeeks/ligase/common/ldap"
 	"github.com/finogeeks/ligase/common/uid"
 	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/service"
 	"github.com/finogeeks/ligase/model/types"
 	"github.com/finogeeks/ligase/plugins/message/external"
 	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
 	"github.com/finogeeks/ligase/skunkworks/log"
 	"github.com/finogeeks/ligase/storage/model"
+	"golang.org/x/crypto/bcrypt"
 )
 
-func passwordLogin() *external.GetLoginResponse {
-	f := &external.GetLoginResponse{}
-	s := external.Flow{"m.login.password", []string{"m.login.password"}}
-	f.Flows = append(f.Flows, s)
+// loginFlows returns the login flows of the enabled authorize modes
+func loginFlows(cfg config.Dendrite) *external.GetLoginResponse {
+	f := &external.GetLoginResponse{Flows: []external.Flow{}}
+	if cfg.LoginModeEnabled("provider") || cfg.LoginModeEnabled("password") || cfg.LoginModeEnabled("ldap") {
+		s := external.Flow{Type: authtypes.LoginTypePassword, Stages: []string{authtypes.LoginTypePassword}}
+		f.Flows = append(f.Flows, s)
+	}
+	if cfg.LoginModeEnabled("jwt") {
+		s := external.Flow{Type: authtypes.LoginTypeJWT, Stages: []string{authtypes.LoginTypeJWT}}
+		f.Flows = append(f.Flows, s)
+	}
+	if cfg.LoginModeEnabled("sso") {
+		s := external.Flow{Type: authtypes.LoginTypeSSO, Stages: []string{authtypes.LoginTypeSSO}}
+		f.Flows = append(f.Flows, s)
+		s = external.Flow{Type: authtypes.LoginTypeToken, Stages: []string{authtypes.LoginTypeToken}}
+		f.Flows = append(f.Flows, s)
+	}
 	return f
 }
 
+// loginMode returns the authorize mode handling a login of the given type, or
+// "" when none of the enabled modes handles it. Provider mode trusts the
+// caller, so it only takes password logins when neither ldap nor password
+// mode is enabled.
+func loginMode(cfg config.Dendrite, loginType string) string {
+	switch loginType {
+	case authtypes.LoginTypeJWT:
+		if cfg.LoginModeEnabled("jwt") {
+			return "jwt"
+		}
+	case authtypes.LoginTypeToken:
+		if cfg.LoginModeEnabled("sso") {
+			return "sso"
+		}
+	case authtypes.LoginTypePassword, "":
+		for _, mode := range []string{"ldap", "password", "provider"} {
+			if cfg.LoginModeEnabled(mode) {
+				return mode
+			}
+		}
+	}
+	return ""
+}
+
+// checkAuthorizeCode reports whether code is the configured admin login code.
+// Admin login is refused when no code is configured.
+func checkAuthorizeCode(cfg config.Dendrite, code string) bool {
+	want := cfg.Authorization.AuthorizeCode
+	return want != "" && subtle.ConstantTimeCompare([]byte(code), []byte(want)) == 1
+}
+
 func providerLogin(
 	userID string,
 	ctx context.Context,
@@ -54,16 +106,292 @@ func providerLogin(
 	accountDB model.AccountsDatabase,
 	encryptDB model.EncryptorAPIDatabase,
 	syncDB model.SyncAPIDatabase,
-	admin bool,
 	idg *uid.UidGenerator,
 	tokenFilter *filter.Filter,
 	rpcClient *common.RpcClient,
 ) (int, core.Coder) {
-	if admin == true {
-		if r.Password != cfg.Authorization.AuthorizeCode {
-			return http.StatusUnauthorized, jsonerror.Unknown("password incorrect")
+	return completeLogin(userID, ctx, r, cfg, deviceDB, accountDB, encryptDB, syncDB, idg, rpcClient)
+}
+
+// passwordLogin checks the password against the bcrypt hash stored for the
+// account, and locks the account for a while after too many failures.
+func passwordLogin(
+	userID string,
+	ctx context.Context,
+	r external.PostLoginRequest,
+	cfg config.Dendrite,
+	deviceDB model.DeviceDatabase,
+	accountDB model.AccountsDatabase,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	idg *uid.UidGenerator,
+	rpcClient *common.RpcClient,
+) (int, core.Coder) {
+	if r.Password == "" {
+		return http.StatusBadRequest, jsonerror.BadJSON("'password' must be supplied.")
+	}
+
+	failures, err := cache.GetLoginFailCount(userID)
+	if err != nil {
+		log.Errorf("Login get fail count error, user: %s, error: %v", userID, err)
+	}
+	if failures >= cfg.Authorization.MaxLoginFailures {
+		return http.StatusForbidden, jsonerror.UserLocked("too many failed login attempts, try again later")
+	}
+
+	_, err = accountDB.GetAccountByPassword(ctx, userID, r.Password)
+	if err == nil {
+		if err = cache.DelLoginFailCount(userID); err != nil {
+			log.Errorf("Login reset fail count error, user: %s, error: %v", userID, err)
+		}
+		return completeLogin(userID, ctx, r, cfg, deviceDB, accountDB, encryptDB, syncDB, idg, rpcClient)
+	}
+
+	if err != sql.ErrNoRows && !isPasswordMismatch(err) {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	failures, err = cache.IncrLoginFailCount(userID, cfg.Authorization.LoginLockDuration)
+	if err != nil {
+		log.Errorf("Login incr fail count error, user: %s, error: %v", userID, err)
+	}
+	log.Warnf("password login failed user %s failures %d", userID, failures)
+	if failures >= cfg.Authorization.MaxLoginFailures {
+		return http.StatusForbidden, jsonerror.UserLocked("too many failed login attempts, try again later")
+	}
+	return http.StatusForbidden, jsonerror.Forbidden("username or password does not match")
+}
+
+// jwtValidator checks the org.matrix.login.jwt tokens, SetupJWTLogin builds
+// it once at startup.
+var jwtValidator *jwt.Validator
+
+// SetupJWTLogin builds the jwt login validator from the configuration. It
+// fails if jwt login is enabled with a bad algorithm or key, so that the
+// component doesn't start misconfigured.
+func SetupJWTLogin(cfg *config.Dendrite) error {
+	if !cfg.LoginModeEnabled("jwt") {
+		jwtValidator = nil
+		return nil
+	}
+	jwtCfg := cfg.Authorization.JWT
+	validator, err := jwt.NewValidator(
+		jwtCfg.Algorithm, []byte(jwtCfg.Secret), jwtCfg.PublicKey,
+		jwtCfg.Issuer, jwtCfg.Audiences, time.Duration(jwtCfg.Leeway)*time.Second,
+	)
+	if err != nil {
+		return err
+	}
+	// login tokens must expire
+	validator.RequireExpiry()
+	jwtValidator = validator
+	return nil
+}
+
+// jwtLogin validates the token against the configured key and claims, and
+// logs in the local user named by the subject claim.
+func jwtLogin(
+	ctx context.Context,
+	r external.PostLoginRequest,
+	cfg config.Dendrite,
+	deviceDB model.DeviceDatabase,
+	accountDB model.AccountsDatabase,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	idg *uid.UidGenerator,
+	rpcClient *common.RpcClient,
+) (int, core.Coder) {
+	if r.Token == "" {
+		return http.StatusBadRequest, jsonerror.BadJSON("'token' must be supplied.")
+	}
+
+	if jwtValidator == nil {
+		log.Errorf("jwt login is enabled but SetupJWTLogin didn't build a validator")
+		return http.StatusInternalServerError, jsonerror.Unknown("jwt login is misconfigured")
+	}
+	jwtCfg := cfg.Authorization.JWT
+	claims, err := jwtValidator.Validate(r.Token, time.Now())
+	if err != nil {
+		log.Warnf("jwt login invalid token: %v", err)
+		return http.StatusForbidden, jsonerror.Forbidden("Invalid JWT: " + err.Error())
+	}
+
+	localPart := strings.ToLower(claims.String(jwtCfg.SubjectClaim))
+	if localPart == "" {
+		return http.StatusForbidden, jsonerror.Forbidden("Invalid JWT: missing claim " + jwtCfg.SubjectClaim)
+	}
+	if len(cfg.Matrix.ServerName) == 0 {
+		return http.StatusServiceUnavailable, jsonerror.Unknown("Internal Server Error")
+	}
+	userID := fmt.Sprintf("@%s:%s", localPart, cfg.Matrix.ServerName[0])
+	if _, _, err = gomatrixserverlib.SplitID('@', userID); err != nil {
+		return http.StatusForbidden, jsonerror.InvalidUsername("Invalid JWT: bad localpart")
+	}
+	return completeLogin(userID, ctx, r, cfg, deviceDB, accountDB, encryptDB, syncDB, idg, rpcClient)
+}
+
+// tokenLogin redeems an m.login.token minted by the SSO callback. Tokens are
+// single use and short-lived.
+func tokenLogin(
+	ctx context.Context,
+	r external.PostLoginRequest,
+	cfg config.Dendrite,
+	deviceDB model.DeviceDatabase,
+	accountDB model.AccountsDatabase,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	idg *uid.UidGenerator,
+	rpcClient *common.RpcClient,
+) (int, core.Coder) {
+	if r.Token == "" {
+		return http.StatusBadRequest, jsonerror.BadJSON("'token' must be supplied.")
+	}
+	userID, err := cache.TakeLoginToken(r.Token)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if userID == "" {
+		return http.StatusForbidden, jsonerror.Forbidden("Invalid login token")
+	}
+	return completeLogin(userID, ctx, r, cfg, deviceDB, accountDB, encryptDB, syncDB, idg, rpcClient)
+}
+
+// ldapLogin authenticates the localpart against the directory, and copies the
+// configured attributes into user_info when sync_user_info is set.
+func ldapLogin(
+	userID string,
+	ctx context.Context,
+	r external.PostLoginRequest,
+	cfg config.Dendrite,
+	deviceDB model.DeviceDatabase,
+	accountDB model.AccountsDatabase,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	idg *uid.UidGenerator,
+	rpcClient *common.RpcClient,
+) (int, core.Coder) {
+	if r.Password == "" {
+		return http.StatusBadRequest, jsonerror.BadJSON("'password' must be supplied.")
+	}
+
+	failures, err := cache.GetLoginFailCount(userID)
+	if err != nil {
+		log.Errorf("Login get fail count error, user: %s, error: %v", userID, err)
+	}
+	if failures >= cfg.Authorization.MaxLoginFailures {
+		return http.StatusForbidden, jsonerror.UserLocked("too many failed login attempts, try again later")
+	}
+
+	ldapCfg := cfg.Authorization.LDAP
+	localPart, _, _ := gomatrixserverlib.SplitID('@', userID)
+	entry, err := ldap.Authenticate(ldap.Config{
+		URL:           ldapCfg.URL,
+		TLSSkipVerify: ldapCfg.TLSSkipVerify,
+		Plaintext:     ldapCfg.InsecurePlaintext,
+		BindDN:        ldapCfg.BindDN,
+		BindPassword:  ldapCfg.BindPassword,
+		BaseDN:        ldapCfg.BaseDN,
+		Filter:        ldapCfg.Filter,
+		Attributes:    []string{ldapCfg.Attributes.DisplayName, ldapCfg.Attributes.Email, ldapCfg.Attributes.Mobile},
+		Timeout:       time.Duration(ldapCfg.Timeout) * time.Second,
+	}, localPart, r.Password)
+	if err == nil {
+		if err = cache.DelLoginFailCount(userID); err != nil {
+			log.Errorf("Login reset fail count error, user: %s, error: %v", userID, err)
+		}
+		if ldapCfg.SyncUserInfo {
+			syncLDAPUserInfo(ctx, userID, entry, cfg, accountDB, cache)
+		}
+		return completeLogin(userID, ctx, r, cfg, deviceDB, accountDB, encryptDB, syncDB, idg, rpcClient)
+	}
+
+	if err != ldap.ErrInvalidCredentials && err != ldap.ErrUserNotFound {
+		log.Errorf("ldap login error, user: %s, error: %v", userID, err)
+		return http.StatusServiceUnavailable, jsonerror.Unknown("LDAP server unavailable")
+	}
+	failures, err = cache.IncrLoginFailCount(userID, cfg.Authorization.LoginLockDuration)
+	if err != nil {
+		log.Errorf("Login incr fail count error, user: %s, error: %v", userID, err)
+	}
+	log.Warnf("ldap login failed user %s failures %d", userID, failures)
+	if failures >= cfg.Authorization.MaxLoginFailures {
+		return http.StatusForbidden, jsonerror.UserLocked("too many failed login attempts, try again later")
+	}
+	return http.StatusForbidden, jsonerror.Forbidden("username or password does not match")
+}
+
+// syncLDAPUserInfo stores the directory attributes of the user, keeping the
+// fields LDAP doesn't provide.
+func syncLDAPUserInfo(
+	ctx context.Context,
+	userID string,
+	entry *ldap.Entry,
+	cfg config.Dendrite,
+	accountDB model.AccountsDatabase,
+	cache service.Cache,
+) {
+	attrs := cfg.Authorization.LDAP.Attributes
+	userName := entry.Get(attrs.DisplayName)
+	email := entry.Get(attrs.Email)
+	mobile := entry.Get(attrs.Mobile)
+
+	var jobNumber, landline string
+	if info := cache.GetUserInfoByUserID(userID); info != nil {
+		if info.UserName == userName && info.Email == email && info.Mobile == mobile {
+			return
 		}
+		jobNumber, landline = info.JobNumber, info.Landline
+	}
+
+	if err := cache.SetUserInfo(userID, userName, jobNumber, mobile, landline, email); err != nil {
+		log.Errorf("ldap login set user info cache error, user: %s, error: %v", userID, err)
+		return
 	}
+	if err := accountDB.UpsertUserInfo(ctx, userID, userName, jobNumber, mobile, landline, email); err != nil {
+		log.Errorf("ldap login upsert user info error, user: %s, error: %v", userID, err)
+	}
+}
+
+// isPasswordMismatch reports whether err is a bcrypt error returned by
+// GetAccountByPassword, which covers wrong passwords and passwordless accounts.
+func isPasswordMismatch(err error) bool {
+	switch err {
+	case bcrypt.ErrMismatchedHashAndPassword, bcrypt.ErrHashTooShort:
+		return true
+	}
+	return false
+}
+
+// completeLogin creates the account if needed and a device with its access token.
+func completeLogin(
+	userID string,
+	ctx context.Context,
+	r external.PostLoginRequest,
+	cfg config.Dendrite,
+	deviceDB model.DeviceDatabase,
+	accountDB model.AccountsDatabase,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	idg *uid.UidGenerator,
+	rpcClient *common.RpcClient,
+) (int, core.Coder) {
+	deactivated, err := accountDB.IsAccountDeactivated(ctx, userID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if deactivated {
+		return http.StatusForbidden, jsonerror.UserDeactivated("This account has been deactivated")
+	}
+	locked, err := accountDB.IsAccountLocked(ctx, userID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if locked {
+		return http.StatusForbidden, jsonerror.UserLocked("This account has been locked")
+	}
+
 	devID := &r.DeviceID
 	account, allow, e := checkCreateAccount(cfg, accountDB, userID, *devID)
 	if e != nil {
@@ -77,7 +405,7 @@ func providerLogin(
 		appServiceID = "actual"
 	}
 
-	_, err := accountDB.CreateAccountWithCheck(ctx, account, userID, "", appServiceID, "")
+	_, err = accountDB.CreateAccountWithCheck(ctx, account, userID, "", appServiceID, "")
 	if err != nil {
 		return http.StatusInternalServerError, jsonerror.Unknown("failed to create account: " + err.Error())
 	}
@@ -109,10 +437,17 @@ func providerLogin(
 		log.Errorf("Login remove std message error, device: %s ,  user: %s , error: %v", deviceID, userID, err)
 	}
 
-	token, err := common.BuildToken(cfg.Macaroon.Key, r.User, domain, r.User, r.DeviceID, false, deviceID, deviceType, human)
+	tokens, err := issueTokens(ctx, &cfg, deviceDB, domain, &authtypes.RefreshToken{
+		UserID:     userID,
+		DeviceID:   deviceID,
+		DeviceType: deviceType,
+		Identifier: r.DeviceID,
+		IsHuman:    human,
+	}, r.RefreshToken)
 	if err != nil {
-		httputil.LogThenErrorCtx(ctx, err)
+		return httputil.LogThenErrorCtx(ctx, err)
 	}
+	token := tokens.accessToken
 
 	dev, err := deviceDB.CreateDevice(
 		ctx, userID, deviceID, deviceType, r.InitialDisplayName, human, devID, -1,
@@ -143,10 +478,12 @@ func providerLogin(
 	}
 	pubLoginToken(userID, deviceID, rpcClient)
 	return http.StatusOK, &external.PostLoginResponse{
-		UserID:      dev.UserID,
-		AccessToken: token,
-		HomeServer:  domain,
-		DeviceID:    dev.ID,
+		UserID:       dev.UserID,
+		AccessToken:  token,
+		HomeServer:   domain,
+		DeviceID:     dev.ID,
+		RefreshToken: tokens.refreshToken,
+		ExpiresInMs:  tokens.expiresInMs,
 	}
 }
 
@@ -201,8 +538,62 @@ func LoginPost(
 	idg *uid.UidGenerator,
 	tokenFilter *filter.Filter,
 	rpcClient *common.RpcClient,
+	cache service.Cache,
 ) (int, core.Coder) {
+	mode := loginMode(cfg, req.RequestType)
+	if mode == "" {
+		return http.StatusBadRequest, jsonerror.Unknown("Unsupported login type " + req.RequestType)
+	}
+	// Admin login needs the authorize code on top of the credentials of the
+	// mode, provider mode takes it as the password.
+	if admin {
+		code := req.AuthorizeCode
+		if mode == "provider" {
+			code = req.Password
+		}
+		if !checkAuthorizeCode(cfg, code) {
+			return http.StatusUnauthorized, jsonerror.Unknown("authorize code incorrect")
+		}
+	}
+
+	// The jwt and sso tokens carry the user, so they are checked before r.User
+	switch mode {
+	case "jwt":
+		return jwtLogin(ctx, *req, cfg, deviceDB, accountDB, encryptDB, syncDB, idg, rpcClient)
+	case "sso":
+		return tokenLogin(ctx, *req, cfg, deviceDB, accountDB, encryptDB, syncDB, cache, idg, rpcClient)
+	}
+
 	// r.User can either be a user ID or just the userID... or other things maybe.
+	if req.User == "" && req.Identifier.Type == "m.id.user" {
+		req.User = req.Identifier.User
+	}
+	// A 3PID identifies the user it is bound to, the legacy medium and
+	// address fields are accepted too.
+	medium, address := req.Medium, req.Address
+	if req.Identifier.Type == "m.id.thirdparty" {
+		medium, address = req.Identifier.Medium, req.Identifier.Address
+	}
+	if req.User == "" && medium != "" {
+		if medium != "email" {
+			return http.StatusBadRequest, jsonerror.ThreePIDMediumNotSupported("Only email 3PIDs can be used to log in")
+		}
+		email, err := threepid.NormalizeEmail(address)
+		if err != nil {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Invalid email address")
+		}
+		userID, err := lookupUserByEmail(ctx, accountDB, email)
+		if err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		if userID == "" {
+			return http.StatusForbidden, jsonerror.Forbidden("username or password does not match")
+		}
+		req.User = userID
+	}
+	if req.User != "" && !strings.HasPrefix(req.User, "@") && len(cfg.Matrix.ServerName) > 0 {
+		req.User = fmt.Sprintf("@%s:%s", strings.ToLower(req.User), cfg.Matrix.ServerName[0])
+	}
 	localPart, domain, err := gomatrixserverlib.SplitID('@', req.User)
 	if err != nil {
 		return http.StatusBadRequest, jsonerror.InvalidUsername("User ID must be @localpart:domain")
@@ -216,11 +607,15 @@ func LoginPost(
 		return http.StatusBadRequest, jsonerror.InvalidUsername("User ID not ours")
 	}
 
-	if strings.EqualFold(cfg.Authorization.AuthorizeMode, "provider") {
-		return providerLogin(req.User, ctx, *req, cfg, deviceDB, accountDB, encryptDB, syncDB, admin, idg, tokenFilter, rpcClient)
+	switch mode {
+	case "ldap":
+		// The directory is the source of truth when ldap is enabled
+		return ldapLogin(req.User, ctx, *req, cfg, deviceDB, accountDB, encryptDB, syncDB, cache, idg, rpcClient)
+	case "password":
+		return passwordLogin(req.User, ctx, *req, cfg, deviceDB, accountDB, encryptDB, syncDB, cache, idg, rpcClient)
+	default:
+		return providerLogin(req.User, ctx, *req, cfg, deviceDB, accountDB, encryptDB, syncDB, idg, tokenFilter, rpcClient)
 	}
-
-	return http.StatusServiceUnavailable, jsonerror.Unknown("Internal Server Error")
 }
 
 func LoginGet(
@@ -236,6 +631,5 @@ func LoginGet(
 	tokenFilter *filter.Filter,
 	rpcClient *common.RpcClient,
 ) (int, core.Coder) {
-	// TODO: support other forms of login other than password, depending on config options
-	return http.StatusOK, passwordLogin()
+	return http.StatusOK, loginFlows(cfg)
 }
@@ -0,0 +1,298 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/plugins/message/internals"
+	"github.com/finogeeks/ligase/storage/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeCache keeps the login failure counters in memory, the other methods
+// of service.Cache are not used by the tests and panic.
+type fakeCache struct {
+	service.Cache
+	failures map[string]int64
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{failures: map[string]int64{}}
+}
+
+func (c *fakeCache) GetLoginFailCount(userID string) (int64, error) {
+	return c.failures[userID], nil
+}
+
+func (c *fakeCache) IncrLoginFailCount(userID string, expire int64) (int64, error) {
+	c.failures[userID]++
+	return c.failures[userID], nil
+}
+
+func (c *fakeCache) DelLoginFailCount(userID string) error {
+	delete(c.failures, userID)
+	return nil
+}
+
+// fakeAccountsDB checks plaintext passwords, the other methods of
+// model.AccountsDatabase are not used by the tests and panic.
+type fakeAccountsDB struct {
+	model.AccountsDatabase
+	passwords   map[string]string
+	deactivated map[string]bool
+}
+
+func (d *fakeAccountsDB) GetAccountByPassword(ctx context.Context, userID, password string) (*authtypes.Account, error) {
+	want, ok := d.passwords[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if want != password {
+		return nil, bcrypt.ErrMismatchedHashAndPassword
+	}
+	return &authtypes.Account{UserID: userID}, nil
+}
+
+func (d *fakeAccountsDB) IsAccountDeactivated(ctx context.Context, userID string) (bool, error) {
+	return d.deactivated[userID], nil
+}
+
+func testLoginConfig(mode string) config.Dendrite {
+	var cfg config.Dendrite
+	cfg.Matrix.ServerName = []string{"example.com"}
+	cfg.Authorization.AuthorizeMode = mode
+	cfg.Authorization.AuthorizeCode = "letmein"
+	cfg.Authorization.MaxLoginFailures = 3
+	cfg.Authorization.LoginLockDuration = 900
+	return cfg
+}
+
+func errCode(t *testing.T, resp interface{}) string {
+	t.Helper()
+	e, ok := resp.(*internals.MatrixError)
+	if !ok {
+		t.Fatalf("response %#v is not a matrix error", resp)
+	}
+	return e.ErrCode
+}
+
+func testLoginPost(cfg config.Dendrite, req *external.PostLoginRequest, admin bool, accountDB model.AccountsDatabase, cache service.Cache) (int, interface{}) {
+	return LoginPost(context.Background(), req, accountDB, nil, nil, nil, cfg, admin, nil, nil, nil, cache)
+}
+
+func TestLoginMode(t *testing.T) {
+	tests := []struct {
+		modes     string
+		loginType string
+		want      string
+	}{
+		{"provider", authtypes.LoginTypePassword, "provider"},
+		{"provider", "", "provider"},
+		{"provider,password", authtypes.LoginTypePassword, "password"},
+		{"password, provider", authtypes.LoginTypePassword, "password"},
+		{"provider,ldap,password", authtypes.LoginTypePassword, "ldap"},
+		{"provider", authtypes.LoginTypeJWT, ""},
+		{"provider,jwt", authtypes.LoginTypeJWT, "jwt"},
+		{"password", authtypes.LoginTypeToken, ""},
+		{"password,sso", authtypes.LoginTypeToken, "sso"},
+		{"provider", authtypes.LoginTypeDummy, ""},
+	}
+	for _, tt := range tests {
+		if got := loginMode(testLoginConfig(tt.modes), tt.loginType); got != tt.want {
+			t.Errorf("loginMode(%q, %q) = %q, want %q", tt.modes, tt.loginType, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAuthorizeCode(t *testing.T) {
+	cfg := testLoginConfig("password")
+	if !checkAuthorizeCode(cfg, "letmein") {
+		t.Error("the configured code is refused")
+	}
+	if checkAuthorizeCode(cfg, "letme") {
+		t.Error("a wrong code is accepted")
+	}
+	cfg.Authorization.AuthorizeCode = ""
+	if checkAuthorizeCode(cfg, "") {
+		t.Error("an empty code is accepted")
+	}
+}
+
+func TestLoginPostAdminNeedsAuthorizeCode(t *testing.T) {
+	accountDB := &fakeAccountsDB{passwords: map[string]string{"@alice:example.com": "secret"}}
+	for _, mode := range []string{"password", "provider,password", "ldap", "jwt"} {
+		cfg := testLoginConfig(mode)
+		req := &external.PostLoginRequest{
+			RequestType:   authtypes.LoginTypePassword,
+			User:          "@alice:example.com",
+			Password:      "secret",
+			AuthorizeCode: "wrong",
+		}
+		if mode == "jwt" {
+			req.RequestType = authtypes.LoginTypeJWT
+		}
+		code, _ := testLoginPost(cfg, req, true, accountDB, newFakeCache())
+		if code != http.StatusUnauthorized {
+			t.Errorf("mode %s: admin login without the authorize code returned %d", mode, code)
+		}
+	}
+
+	// provider mode takes the code as the password
+	cfg := testLoginConfig("provider")
+	req := &external.PostLoginRequest{RequestType: authtypes.LoginTypePassword, User: "@alice:example.com", Password: "secret"}
+	if code, _ := testLoginPost(cfg, req, true, accountDB, newFakeCache()); code != http.StatusUnauthorized {
+		t.Errorf("provider admin login with a wrong code returned %d", code)
+	}
+}
+
+func TestLoginPostProviderDoesNotShadowPassword(t *testing.T) {
+	cfg := testLoginConfig("provider,password")
+	accountDB := &fakeAccountsDB{passwords: map[string]string{"@alice:example.com": "secret"}}
+	cache := newFakeCache()
+	req := &external.PostLoginRequest{RequestType: authtypes.LoginTypePassword, User: "alice", Password: "wrong"}
+	code, resp := testLoginPost(cfg, req, false, accountDB, cache)
+	if code != http.StatusForbidden || errCode(t, resp) != "M_FORBIDDEN" {
+		t.Fatalf("wrong password returned %d %v", code, resp)
+	}
+	if cache.failures["@alice:example.com"] != 1 {
+		t.Errorf("failures = %d, want 1", cache.failures["@alice:example.com"])
+	}
+}
+
+func TestPasswordLoginLockout(t *testing.T) {
+	cfg := testLoginConfig("password")
+	userID := "@alice:example.com"
+	accountDB := &fakeAccountsDB{passwords: map[string]string{userID: "secret"}}
+	cache := newFakeCache()
+	login := func(password string) (int, string) {
+		req := external.PostLoginRequest{RequestType: authtypes.LoginTypePassword, User: userID, Password: password}
+		code, resp := passwordLogin(userID, context.Background(), req, cfg, nil, accountDB, nil, nil, cache, nil, nil)
+		return code, errCode(t, resp)
+	}
+
+	if code, errcode := login(""); code != http.StatusBadRequest || errcode != "M_BAD_JSON" {
+		t.Errorf("empty password returned %d %s", code, errcode)
+	}
+	for i := 1; i < 3; i++ {
+		if code, errcode := login("wrong"); code != http.StatusForbidden || errcode != "M_FORBIDDEN" {
+			t.Errorf("failure %d returned %d %s", i, code, errcode)
+		}
+	}
+	if code, errcode := login("wrong"); code != http.StatusForbidden || errcode != "M_USER_LOCKED" {
+		t.Errorf("last failure returned %d %s", code, errcode)
+	}
+	// the right password doesn't unlock the account before the lock expires
+	if code, errcode := login("secret"); code != http.StatusForbidden || errcode != "M_USER_LOCKED" {
+		t.Errorf("locked login returned %d %s", code, errcode)
+	}
+	if cache.failures[userID] != 3 {
+		t.Errorf("failures = %d, want 3", cache.failures[userID])
+	}
+}
+
+func TestPasswordLoginResetsFailures(t *testing.T) {
+	cfg := testLoginConfig("password")
+	userID := "@alice:example.com"
+	// completeLogin stops at the deactivated check, after the password
+	accountDB := &fakeAccountsDB{
+		passwords:   map[string]string{userID: "secret"},
+		deactivated: map[string]bool{userID: true},
+	}
+	cache := newFakeCache()
+	cache.failures[userID] = 2
+	req := external.PostLoginRequest{RequestType: authtypes.LoginTypePassword, User: userID, Password: "secret"}
+	code, resp := passwordLogin(userID, context.Background(), req, cfg, nil, accountDB, nil, nil, cache, nil, nil)
+	if code != http.StatusForbidden || errCode(t, resp) != "M_USER_DEACTIVATED" {
+		t.Errorf("login returned %d %v", code, resp)
+	}
+	if _, ok := cache.failures[userID]; ok {
+		t.Error("the failure count is kept after a good password")
+	}
+}
+
+func TestPasswordLoginUnknownUser(t *testing.T) {
+	cfg := testLoginConfig("password")
+	cache := newFakeCache()
+	req := external.PostLoginRequest{RequestType: authtypes.LoginTypePassword, User: "@bob:example.com", Password: "secret"}
+	code, resp := passwordLogin("@bob:example.com", context.Background(), req, cfg, nil, &fakeAccountsDB{}, nil, nil, cache, nil, nil)
+	if code != http.StatusForbidden || errCode(t, resp) != "M_FORBIDDEN" {
+		t.Errorf("unknown user returned %d %v", code, resp)
+	}
+	if cache.failures["@bob:example.com"] != 1 {
+		t.Error("unknown users are not counted")
+	}
+}
+
+func hs256LoginToken(secret, payload string) string {
+	input := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestJWTLoginRequiresExpiry(t *testing.T) {
+	cfg := testLoginConfig("jwt")
+	cfg.Authorization.JWT.Algorithm = "HS256"
+	cfg.Authorization.JWT.Secret = "secret"
+	cfg.Authorization.JWT.SubjectClaim = "sub"
+	if err := SetupJWTLogin(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { jwtValidator = nil }()
+
+	userID := "@alice:example.com"
+	// completeLogin stops at the deactivated check, after the token
+	accountDB := &fakeAccountsDB{deactivated: map[string]bool{userID: true}}
+	login := func(payload string) (int, string) {
+		req := &external.PostLoginRequest{RequestType: authtypes.LoginTypeJWT, Token: hs256LoginToken("secret", payload)}
+		code, resp := testLoginPost(cfg, req, false, accountDB, newFakeCache())
+		return code, errCode(t, resp)
+	}
+
+	if code, errcode := login(`{"sub":"alice"}`); code != http.StatusForbidden || errcode != "M_FORBIDDEN" {
+		t.Errorf("token without exp returned %d %s", code, errcode)
+	}
+	exp := time.Now().Add(time.Minute).Unix()
+	if code, errcode := login(`{"sub":"alice","exp":` + strconv.FormatInt(exp, 10) + `}`); code != http.StatusForbidden || errcode != "M_USER_DEACTIVATED" {
+		t.Errorf("token with exp returned %d %s", code, errcode)
+	}
+}
+
+func TestSetupJWTLoginMisconfigured(t *testing.T) {
+	cfg := testLoginConfig("jwt")
+	cfg.Authorization.JWT.Algorithm = "RS256"
+	if err := SetupJWTLogin(&cfg); err == nil {
+		jwtValidator = nil
+		t.Error("RS256 without a public key is accepted")
+	}
+	cfg = testLoginConfig("password")
+	if err := SetupJWTLogin(&cfg); err != nil || jwtValidator != nil {
+		t.Errorf("jwt login disabled: err %v, validator %v", err, jwtValidator)
+	}
+}
@@ -63,6 +63,9 @@ func LogoutDevice(
 	if err := deviceDB.RemoveDevice(ctx, deviceID, userID, createdTimeMS); err != nil {
 		log.Errorf("Log out remove device error, device: %s ,  user: %s , error: %v", deviceID, userID, err)
 	}
+	if err := deviceDB.RemoveDeviceRefreshTokens(ctx, userID, deviceID); err != nil {
+		log.Errorf("Log out remove refresh tokens error, device: %s ,  user: %s , error: %v", deviceID, userID, err)
+	}
 
 	err := encryptDB.DeleteDeviceKeys(ctx, deviceID, userID)
 	if err != nil {
@@ -97,14 +97,14 @@ func SendMembership(
 	if err != nil {
 		domainID, _ := common.DomainFromID(roomID)
 		if membership == "join" && common.CheckValidDomain(domainID, cfg.Matrix.ServerName) == false {
-			resp, err := federation.MakeJoin(domainID, roomID, userID, []string{"1"})
+			resp, err := federation.MakeJoin(domainID, roomID, userID, supportedRoomVersions())
 			if err != nil {
 				log.Errorf("traceId:%s handle SendMembership user:%s roomID:%s make join error: %v", traceId, userID, roomID, err)
 				return httputil.LogThenErrorCtx(ctx, err)
 			}
 			builder := resp.JoinEvent
 			sendDomain, _ := common.DomainFromID(userID)
-			ev, err := common.BuildEvent(&builder, sendDomain, cfg, idg)
+			ev, err := common.BuildEvent(&builder, sendDomain, resp.GetRoomVersion(), idg)
 			if err != nil {
 				log.Errorf("traceId:%s handle SendMembership user:%s roomID:%s make join build event err %v", traceId, userID, roomID, err)
 				return httputil.LogThenErrorCtx(ctx, err)
@@ -194,7 +194,7 @@ func SendMembership(
 			}
 			builder := resp.Event
 			sendDomain, _ := common.DomainFromID(userID)
-			ev, err := common.BuildEvent(&builder, sendDomain, cfg, idg)
+			ev, err := common.BuildEvent(&builder, sendDomain, resp.GetRoomVersion(), idg)
 			if err != nil {
 				log.Errorf("traceId:%s handle SendMembership user:%s roomID:%s make leave build event err %v", traceId, userID, roomID, err)
 				return httputil.LogThenErrorCtx(ctx, err)
@@ -375,7 +375,7 @@ func buildMembershipEvent(
 		return nil, err
 	}
 
-	e, err := common.BuildEvent(&builder, domainID, cfg, idg)
+	e, err := common.BuildEvent(&builder, domainID, gomatrixserverlib.AuthEventsRoomVersion(queryRes), idg)
 	if err == nil {
 		if membership == "join" && body.AutoJoin {
 			log.Infof("buildevent join and autojoin handle SendMembership traceId:%s membership:%s buildMembershipEvent builder.SetContent for user %s roomID:%s error %v", traceId, membership, userID, roomID, err)
@@ -98,7 +98,7 @@ func GetSuperAdminToken(
 	syncDB model.SyncAPIDatabase,
 	rpcClient *common.RpcClient,
 ) (int, core.Coder) {
-	userID := "super_admin"
+	userID := superAdminUserID
 	domain := "super_domain"
 	mac := "super-gen"
 	displayName := "super_displayname"
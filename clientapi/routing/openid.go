@@ -0,0 +1,64 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/finogeeks/ligase/clientapi/httputil"
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/plugins/message/external"
+)
+
+// RequestOpenIDToken implements POST /_matrix/client/r0/user/{userId}/openid/request_token
+// The token proves the identity of the user to a third party, which checks it
+// with /_matrix/federation/v1/openid/userinfo, without giving it the access token.
+func RequestOpenIDToken(
+	ctx context.Context,
+	req *external.PostUserOpenIDRequest,
+	device *authtypes.Device,
+	cache service.Cache,
+	cfg *config.Dendrite,
+) (int, core.Coder) {
+	if req.UserID != device.UserID {
+		return http.StatusForbidden, jsonerror.Forbidden("Cannot request tokens for other users")
+	}
+	serverName, err := common.DomainFromID(device.UserID)
+	if err != nil {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue(err.Error())
+	}
+
+	token, err := common.BuildRandomURLEncString()
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	lifetime := cfg.Authorization.OpenIDTokenLifetime
+	if err = cache.SetOpenIDToken(token, device.UserID, lifetime); err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+
+	return http.StatusOK, &external.PostUserOpenIDResponse{
+		AccessToken:      token,
+		TokenType:        "Bearer",
+		MatrixServerName: serverName,
+		ExpiresIn:        int(lifetime),
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/finogeeks/ligase/clientapi/httputil"
+	"github.com/finogeeks/ligase/clientapi/threepid"
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/filter"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	util "github.com/finogeeks/ligase/skunkworks/gomatrixutil"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// superAdminUserID is the user carried by the token from GetSuperAdminToken
+const superAdminUserID = "super_admin"
+
+// ChangePassword implements POST /account/password
+// An authenticated user proves the current password with m.login.password,
+// an unauthenticated one resets it with an email validated by m.login.email.identity.
+func ChangePassword(
+	ctx context.Context,
+	req *external.PostAccountPasswordRequest,
+	device *authtypes.Device,
+	cfg *config.Dendrite,
+	accountDB model.AccountsDatabase,
+	deviceDB model.DeviceDatabase,
+	pushDB model.PushAPIDatabase,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	tokenFilter *filter.Filter,
+	rpcClient *common.RpcClient,
+) (int, core.Coder) {
+	sessionID := req.Auth.Session
+	if sessionID == "" {
+		sessionID = util.RandomString(sessionIDLength)
+	}
+
+	flows := []external.AuthFlow{{Stages: []string{authtypes.LoginTypeEmail}}}
+	if device != nil {
+		flows = []external.AuthFlow{{Stages: []string{authtypes.LoginTypePassword}}}
+	}
+
+	// If no auth type is specified by the client, send back the list of available flows
+	if req.Auth.Type == "" {
+		return http.StatusUnauthorized, newUserInteractiveResponse(sessionID, flows, nil)
+	}
+
+	if req.NewPassword == "" {
+		return http.StatusBadRequest, jsonerror.MissingArgument("'new_password' must be supplied")
+	}
+	if code, err := validatePassword(req.NewPassword); err != nil {
+		return code, err
+	}
+
+	var userID string
+	var code int
+	var resErr core.Coder
+	if device != nil {
+		userID, code, resErr = checkPasswordAuth(ctx, &req.Auth, device, accountDB, cache, cfg)
+	} else {
+		userID, code, resErr = checkEmailAuth(ctx, &req.Auth, accountDB, cfg)
+	}
+	if resErr != nil {
+		return code, resErr
+	}
+
+	if err := accountDB.UpdatePassword(ctx, userID, req.NewPassword); err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	log.Infof("password changed for user %s by %s", userID, req.Auth.Type)
+
+	if req.LogoutDevices == nil || *req.LogoutDevices {
+		keepDeviceID := ""
+		if device != nil {
+			keepDeviceID = device.ID
+		}
+		LogoutDevicesForPasswordChange(ctx, userID, keepDeviceID, deviceDB, pushDB, encryptDB, syncDB, cache, tokenFilter, rpcClient)
+	}
+
+	return http.StatusOK, nil
+}
+
+// RequestPasswordEmailToken implements POST /account/password/email/requestToken
+func RequestPasswordEmailToken(
+	ctx context.Context,
+	req *external.PostAccountPasswordEmailRequest,
+	accountDB model.AccountsDatabase,
+	cfg *config.Dendrite,
+) (int, core.Coder) {
+	if req.Email == "" || req.ClientSecret == "" {
+		return http.StatusBadRequest, jsonerror.MissingArgument("'email' and 'client_secret' must be supplied")
+	}
+
+	email, err := threepid.NormalizeEmail(req.Email)
+	if err != nil {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Invalid email address")
+	}
+	userID, err := lookupUserByEmail(ctx, accountDB, email)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if userID == "" {
+		return http.StatusBadRequest, jsonerror.ThreePIDNotFound("Email not found")
+	}
+
+	sid, err := threepid.RequestEmailValidation(ctx, accountDB, cfg, req.ClientSecret, email, req.SendAttempt, req.NextLink)
+	if err != nil {
+		return threePIDErrorResponse(ctx, err)
+	}
+
+	return http.StatusOK, &external.PostAccountPasswordEmailResponse{SID: sid}
+}
+
+// AdminResetPassword implements POST /_ligase/admin/v1/users/{userID}/reset_password
+// It lets the super admin force a password rotation on any local user, the
+// super admin token is only handed out on the internal API.
+func AdminResetPassword(
+	ctx context.Context,
+	req *external.PostAdminResetPasswordRequest,
+	device *authtypes.Device,
+	cfg *config.Dendrite,
+	accountDB model.AccountsDatabase,
+	deviceDB model.DeviceDatabase,
+	pushDB model.PushAPIDatabase,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	tokenFilter *filter.Filter,
+	rpcClient *common.RpcClient,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can reset passwords")
+	}
+
+	domain, err := common.DomainFromID(req.UserID)
+	if err != nil {
+		return http.StatusBadRequest, jsonerror.InvalidUsername("User ID must be @localpart:domain")
+	}
+	if !common.CheckValidDomain(domain, cfg.Matrix.ServerName) {
+		return http.StatusBadRequest, jsonerror.InvalidUsername("Can only reset the password of local users")
+	}
+
+	if req.NewPassword == "" {
+		return http.StatusBadRequest, jsonerror.MissingArgument("'new_password' must be supplied")
+	}
+	if code, err := validatePassword(req.NewPassword); err != nil {
+		return code, err
+	}
+
+	if err := accountDB.UpdatePassword(ctx, req.UserID, req.NewPassword); err == sql.ErrNoRows {
+		return http.StatusNotFound, jsonerror.NotFound("Unknown user")
+	} else if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	log.Infof("password of user %s reset by super admin", req.UserID)
+
+	if req.LogoutDevices == nil || *req.LogoutDevices {
+		LogoutDevicesForPasswordChange(ctx, req.UserID, "", deviceDB, pushDB, encryptDB, syncDB, cache, tokenFilter, rpcClient)
+	}
+
+	return http.StatusOK, nil
+}
+
+// LogoutDevicesForPasswordChange logs out every device of the user except
+// keepDeviceID and deletes their pushers. The devices are remembered in the
+// cache so that their next request is answered with M_PWD_CHANGE_KICK.
+func LogoutDevicesForPasswordChange(
+	ctx context.Context,
+	userID, keepDeviceID string,
+	deviceDB model.DeviceDatabase,
+	pushDB model.PushAPIDatabase,
+	encryptDB model.EncryptorAPIDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	tokenFilter *filter.Filter,
+	rpcClient *common.RpcClient,
+) {
+	deviceList := cache.GetDevicesByUserID(userID)
+	if deviceList == nil {
+		return
+	}
+
+	hasPwdDevice := false
+	for _, dev := range *deviceList {
+		if dev.ID == keepDeviceID {
+			continue
+		}
+		log.Infof("cache pwd change user %s device %s ", userID, dev.ID)
+		cache.SetPwdChangeDevcie(dev.ID, userID)
+		hasPwdDevice = true
+		deleteDevicePushers(ctx, userID, dev.ID, pushDB, cache)
+		LogoutDevice(ctx, userID, dev.ID, deviceDB, cache, encryptDB, syncDB, tokenFilter, rpcClient)
+	}
+	if hasPwdDevice {
+		cache.ExpirePwdChangeDevice(userID)
+	}
+}
+
+// deleteDevicePushers deletes the pushers registered by the device, or all the
+// pushers of the user when deviceID is empty. Pushers are keyed by the mac part
+// of the device id.
+func deleteDevicePushers(
+	ctx context.Context, userID, deviceID string, pushDB model.PushAPIDatabase, cache service.Cache,
+) {
+	pusherIDs, ok := cache.GetUserPusherIds(userID)
+	if !ok {
+		return
+	}
+	mac := common.GetDeviceMac(deviceID)
+	for _, pusherID := range pusherIDs {
+		data, _ := cache.GetPusherCacheData(pusherID)
+		if data == nil || (deviceID != "" && data.DeviceID != mac) {
+			continue
+		}
+		if err := pushDB.DeleteUserPushers(ctx, userID, data.AppId, data.PushKey); err != nil {
+			log.Errorf("delete pushers error, device: %s ,  user: %s , error: %v", deviceID, userID, err)
+		}
+	}
+}
+
+// checkPasswordAuth checks the m.login.password stage for an authenticated
+// request, the user in the auth dict must be the owner of the device. Failures
+// count towards the same lockout as password login.
+func checkPasswordAuth(
+	ctx context.Context,
+	auth *external.AuthData,
+	device *authtypes.Device,
+	accountDB model.AccountsDatabase,
+	cache service.Cache,
+	cfg *config.Dendrite,
+) (string, int, core.Coder) {
+	if auth.Type != authtypes.LoginTypePassword {
+		return "", http.StatusBadRequest, jsonerror.Unknown("Unknown auth.type: " + auth.Type)
+	}
+
+	user := auth.User
+	if auth.Identifier.User != "" {
+		user = auth.Identifier.User
+	}
+	if user != "" && user != device.UserID {
+		localpart, _, _ := gomatrixserverlib.SplitID('@', device.UserID)
+		if user != localpart {
+			return "", http.StatusForbidden, jsonerror.Forbidden("The auth user doesn't own the access token")
+		}
+	}
+
+	userID := device.UserID
+	failures, err := cache.GetLoginFailCount(userID)
+	if err != nil {
+		log.Errorf("Password auth get fail count error, user: %s, error: %v", userID, err)
+	}
+	if failures >= cfg.Authorization.MaxLoginFailures {
+		return "", http.StatusForbidden, jsonerror.UserLocked("too many failed login attempts, try again later")
+	}
+
+	_, err = accountDB.GetAccountByPassword(ctx, userID, auth.Password)
+	if err == nil {
+		if err = cache.DelLoginFailCount(userID); err != nil {
+			log.Errorf("Password auth reset fail count error, user: %s, error: %v", userID, err)
+		}
+		return userID, http.StatusOK, nil
+	}
+	if err != sql.ErrNoRows && !isPasswordMismatch(err) {
+		code, resErr := httputil.LogThenErrorCtx(ctx, err)
+		return "", code, resErr
+	}
+
+	if _, err = cache.IncrLoginFailCount(userID, cfg.Authorization.LoginLockDuration); err != nil {
+		log.Errorf("Password auth incr fail count error, user: %s, error: %v", userID, err)
+	}
+	return "", http.StatusUnauthorized, jsonerror.Forbidden("Invalid password")
+}
+
+// checkEmailAuth checks the m.login.email.identity stage against the local
+// validation sessions, and resolves the user the validated email belongs to.
+func checkEmailAuth(
+	ctx context.Context,
+	auth *external.AuthData,
+	accountDB model.AccountsDatabase,
+	cfg *config.Dendrite,
+) (string, int, core.Coder) {
+	if auth.Type != authtypes.LoginTypeEmail {
+		return "", http.StatusUnauthorized, jsonerror.MissingToken("Missing access token")
+	}
+
+	session, err := threepid.CheckValidatedSession(ctx, accountDB, cfg, auth.ThreePIDCreds.Sid, auth.ThreePIDCreds.ClientSecret)
+	if err != nil {
+		code, resErr := threePIDErrorResponse(ctx, err)
+		return "", code, resErr
+	}
+	if session.Medium != "email" {
+		return "", http.StatusUnauthorized, jsonerror.ThreePIDAuthFailed("Email has not been validated")
+	}
+
+	userID, err := lookupUserByEmail(ctx, accountDB, session.Address)
+	if err != nil {
+		code, resErr := httputil.LogThenErrorCtx(ctx, err)
+		return "", code, resErr
+	}
+	if userID == "" {
+		return "", http.StatusBadRequest, jsonerror.ThreePIDNotFound("Email not found")
+	}
+
+	// The validation can't be replayed to reset the password again
+	if err = accountDB.DeleteThreePIDSession(ctx, session.SID); err != nil {
+		log.Errorf("delete 3pid session %s error: %v", session.SID, err)
+	}
+	return userID, http.StatusOK, nil
+}
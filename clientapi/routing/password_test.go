@@ -0,0 +1,39 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/plugins/message/external"
+)
+
+func TestAdminResetPasswordRequiresSuperAdmin(t *testing.T) {
+	cfg := testLoginConfig("password")
+	req := &external.PostAdminResetPasswordRequest{UserID: "@bob:example.com", NewPassword: "n3w-Passw0rd!"}
+	for _, device := range []*authtypes.Device{
+		nil,
+		{UserID: "@alice:example.com"},
+		{UserID: "@super_admin:example.com"},
+	} {
+		code, resp := AdminResetPassword(context.Background(), req, device, &cfg, nil, nil, nil, nil, nil, nil, nil, nil)
+		if code != http.StatusForbidden || errCode(t, resp) != "M_FORBIDDEN" {
+			t.Fatalf("device %v: code = %d, want 403", device, code)
+		}
+	}
+}
@@ -95,7 +95,7 @@ func PostEvent(
 	}
 
 	domainID, _ := common.DomainFromID(userID)
-	e, err := common.BuildEvent(&builder, domainID, cfg, idg)
+	e, err := common.BuildEvent(&builder, domainID, gomatrixserverlib.AuthEventsRoomVersion(&queryRes), idg)
 	log.Debugf("------------------------PostEvent build-event %v", (time.Now().UnixNano()-last)/1000)
 	last = time.Now().UnixNano()
 	if err != nil {
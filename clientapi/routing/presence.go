@@ -25,8 +25,10 @@ import (
 	"github.com/finogeeks/ligase/core"
 	fed "github.com/finogeeks/ligase/federation/fedreq"
 	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
 	"github.com/finogeeks/ligase/model/types"
 	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
 	"github.com/finogeeks/ligase/skunkworks/log"
 	"github.com/finogeeks/ligase/storage/model"
 )
@@ -208,9 +210,121 @@ func GetPresenceByID(
 		ExtStatusMsg:    extStatusMsg,
 	}
 }
-func UpdatePresenceListByID() (int, core.Coder) {
-	return http.StatusOK, &external.PresenceJSON{}
+
+// UpdatePresenceListByID implements POST /presence/list/{userId}
+// The sync aggregates deliver the presence of the users of the list in /sync
+// even after they stop sharing a room with the user. Only users sharing a
+// joined room with the user can be invited, so that a presence list can't be
+// used to watch arbitrary users. Remote users only send their presence to the
+// servers they share a room with.
+func UpdatePresenceListByID(
+	ctx context.Context,
+	req *external.PostPresenceListRequest,
+	presenceDB model.PresenceDatabase,
+	rsRpcCli roomserverapi.RoomserverRPCAPI,
+	rpcCli *common.RpcClient,
+	userID string,
+) (int, core.Coder) {
+	if req.UserID != userID {
+		return http.StatusForbidden, jsonerror.Forbidden("Cannot modify the presence list of other users")
+	}
+	for _, ids := range [][]string{req.Invite, req.Drop} {
+		for _, id := range ids {
+			if _, _, err := gomatrixserverlib.SplitID('@', id); err != nil {
+				return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Invalid user ID " + id)
+			}
+		}
+	}
+	if len(req.Invite) > 0 {
+		rooms := joinedRooms(ctx, rsRpcCli, userID)
+		for _, id := range req.Invite {
+			if id == userID {
+				continue
+			}
+			if !shareRoom(rooms, joinedRooms(ctx, rsRpcCli, id)) {
+				return http.StatusForbidden, jsonerror.Forbidden("Cannot add " + id + " to the presence list, it shares no room with you")
+			}
+		}
+	}
+
+	if len(req.Drop) > 0 {
+		if err := presenceDB.RemovePresenceList(ctx, userID, req.Drop); err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+	}
+	if len(req.Invite) > 0 {
+		if err := presenceDB.AddPresenceList(ctx, userID, req.Invite); err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+	}
+
+	update := types.PresenceListUpdate{UserID: userID, Invite: req.Invite, Drop: req.Drop}
+	bytes, err := json.Marshal(update)
+	if err == nil {
+		rpcCli.Pub(types.PresenceListUpdateTopicDef, bytes)
+	} else {
+		log.Errorf("UpdatePresenceListByID user:%s marshal presence list update err:%v", userID, err)
+	}
+	return http.StatusOK, nil
 }
-func GetPresenceListByID() (int, core.Coder) {
-	return http.StatusOK, &external.GetPresenceListResponse{}
+
+// joinedRooms returns the rooms userID has joined, an unknown user has none.
+func joinedRooms(ctx context.Context, rsRpcCli roomserverapi.RoomserverRPCAPI, userID string) map[string]bool {
+	var resp roomserverapi.QueryJoinRoomsResponse
+	if err := rsRpcCli.QueryJoinRooms(ctx, &roomserverapi.QueryJoinRoomsRequest{UserID: userID}, &resp); err != nil {
+		log.Infof("presence list query join rooms of user:%s err:%v", userID, err)
+		return nil
+	}
+	rooms := make(map[string]bool, len(resp.Rooms))
+	for _, roomID := range resp.Rooms {
+		rooms[roomID] = true
+	}
+	return rooms
+}
+
+func shareRoom(rooms, other map[string]bool) bool {
+	for roomID := range other {
+		if rooms[roomID] {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPresenceListByID implements GET /presence/list/{userId}
+func GetPresenceListByID(
+	ctx context.Context,
+	presenceDB model.PresenceDatabase,
+	rpcCli *common.RpcClient,
+	cache service.Cache,
+	complexCache *common.ComplexCache,
+	userID, listUserID string,
+) (int, core.Coder) {
+	if listUserID != userID {
+		return http.StatusForbidden, jsonerror.Forbidden("Cannot see the presence list of other users")
+	}
+
+	targets, err := presenceDB.GetPresenceList(ctx, userID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+
+	resp := external.GetPresenceListResponse{}
+	for _, target := range targets {
+		presence, statusMsg, extStatusMsg := getPresence(target, cache, rpcCli)
+		displayName, avatarURL, _ := complexCache.GetProfileByUserID(ctx, target)
+		resp = append(resp, external.PresenceListJSON{
+			Type: "m.presence",
+			Content: external.PresenceJSON{
+				AvatarURL:       avatarURL,
+				DisplayName:     displayName,
+				Presence:        presence,
+				CurrentlyActive: presence == "online",
+				UserID:          target,
+				StatusMsg:       statusMsg,
+				ExtStatusMsg:    extStatusMsg,
+			},
+		})
+	}
+	return http.StatusOK, &resp
 }
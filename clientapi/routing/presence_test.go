@@ -0,0 +1,101 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+type presenceListDB struct {
+	model.PresenceDatabase
+	added   []string
+	removed []string
+}
+
+func (d *presenceListDB) AddPresenceList(ctx context.Context, userID string, targets []string) error {
+	d.added = append(d.added, targets...)
+	return nil
+}
+
+func (d *presenceListDB) RemovePresenceList(ctx context.Context, userID string, targets []string) error {
+	d.removed = append(d.removed, targets...)
+	return nil
+}
+
+// joinedRoomsRoomserver answers QueryJoinRooms from a fixed membership.
+type joinedRoomsRoomserver struct {
+	roomserverapi.RoomserverRPCAPI
+	rooms map[string][]string
+}
+
+func (r *joinedRoomsRoomserver) QueryJoinRooms(ctx context.Context, request *roomserverapi.QueryJoinRoomsRequest, response *roomserverapi.QueryJoinRoomsResponse) error {
+	response.UserID = request.UserID
+	if len(r.rooms[request.UserID]) == 0 {
+		return errors.New("no joined room")
+	}
+	response.Rooms = r.rooms[request.UserID]
+	return nil
+}
+
+func TestUpdatePresenceListRequiresSharedRoom(t *testing.T) {
+	rsRpcCli := &joinedRoomsRoomserver{rooms: map[string][]string{
+		"@alice:example.com": {"!a:example.com", "!b:example.com"},
+		"@bob:example.com":   {"!b:example.com"},
+		"@carol:remote.org":  {"!c:remote.org"},
+	}}
+
+	tests := []struct {
+		name   string
+		invite []string
+		code   int
+	}{
+		{"shared room", []string{"@bob:example.com"}, http.StatusOK},
+		{"no shared room", []string{"@bob:example.com", "@carol:remote.org"}, http.StatusForbidden},
+		{"unknown user", []string{"@dave:example.com"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &presenceListDB{}
+			req := &external.PostPresenceListRequest{UserID: "@alice:example.com", Invite: tt.invite}
+			code, resp := UpdatePresenceListByID(context.Background(), req, db, rsRpcCli, &common.RpcClient{}, "@alice:example.com")
+			if code != tt.code {
+				t.Fatalf("code = %d, want %d (%v)", code, tt.code, resp)
+			}
+			if code == http.StatusOK && len(db.added) != len(tt.invite) {
+				t.Fatalf("added = %v, want %v", db.added, tt.invite)
+			}
+			if code != http.StatusOK && len(db.added) != 0 {
+				t.Fatalf("added = %v on a rejected request", db.added)
+			}
+		})
+	}
+}
+
+func TestUpdatePresenceListDropWithoutSharedRoom(t *testing.T) {
+	db := &presenceListDB{}
+	req := &external.PostPresenceListRequest{UserID: "@alice:example.com", Drop: []string{"@carol:remote.org"}}
+	code, _ := UpdatePresenceListByID(context.Background(), req, db, &joinedRoomsRoomserver{}, &common.RpcClient{}, "@alice:example.com")
+	if code != http.StatusOK || len(db.removed) != 1 {
+		t.Fatalf("code = %d removed = %v, want 200 and the dropped user", code, db.removed)
+	}
+}
@@ -123,7 +123,7 @@ func RedactEvent(
 	}
 
 	domainID, _ := common.DomainFromID(userID)
-	e, err := common.BuildEvent(&builder, domainID, cfg, idg)
+	e, err := common.BuildEvent(&builder, domainID, gomatrixserverlib.AuthEventsRoomVersion(&queryRes), idg)
 	log.Debugf("------------------------RedactEvent build-event %v", (time.Now().UnixNano()-last)/1000)
 
 	e.SetRedactEventSender(queryRoomEventByIDResponse.Event.Sender())
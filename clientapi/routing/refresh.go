@@ -0,0 +1,139 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/finogeeks/ligase/clientapi/httputil"
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// issuedTokens is what a login, a registration or a refresh hands out
+type issuedTokens struct {
+	accessToken  string
+	refreshToken string
+	expiresInMs  int64
+}
+
+// issueTokens builds the access token of the device. When the client asked
+// for a refresh token and access_token_lifetime is set, the access token
+// expires and comes with a refresh token. Otherwise the access token doesn't
+// expire and stays valid until the device is removed.
+func issueTokens(
+	ctx context.Context,
+	cfg *config.Dendrite,
+	deviceDB model.DeviceDatabase,
+	domain string,
+	device *authtypes.RefreshToken,
+	withRefresh bool,
+) (*issuedTokens, error) {
+	lifetime := cfg.Authorization.AccessTokenLifetime
+	if !withRefresh || lifetime <= 0 {
+		token, err := common.BuildToken(
+			cfg.Macaroon.Key, device.UserID, domain, device.UserID, device.Identifier,
+			false, device.DeviceID, device.DeviceType, device.IsHuman,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &issuedTokens{accessToken: token}, nil
+	}
+
+	now := time.Now().UnixNano() / 1000000
+	token, err := common.BuildExpiringToken(
+		cfg.Macaroon.Key, device.UserID, domain, device.UserID, device.Identifier,
+		false, device.DeviceID, device.DeviceType, device.IsHuman, now+lifetime*1000,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	stored := *device
+	stored.ExpiresTs = 0
+	if cfg.Authorization.RefreshTokenLifetime > 0 {
+		stored.ExpiresTs = now + cfg.Authorization.RefreshTokenLifetime*1000
+	}
+	if err = deviceDB.SaveRefreshToken(ctx, hashRefreshToken(refreshToken), &stored); err != nil {
+		return nil, err
+	}
+	return &issuedTokens{accessToken: token, refreshToken: refreshToken, expiresInMs: lifetime * 1000}, nil
+}
+
+// RefreshAccessToken implements POST /refresh
+// Refresh tokens are single use, the response carries the next one.
+func RefreshAccessToken(
+	ctx context.Context,
+	req *external.PostRefreshRequest,
+	deviceDB model.DeviceDatabase,
+	cfg *config.Dendrite,
+) (int, core.Coder) {
+	if req.RefreshToken == "" {
+		return http.StatusBadRequest, jsonerror.MissingArgument("'refresh_token' must be supplied")
+	}
+
+	device, err := deviceDB.TakeRefreshToken(ctx, hashRefreshToken(req.RefreshToken))
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if device == nil {
+		return http.StatusUnauthorized, jsonerror.UnknownToken("Unknown refresh token")
+	}
+	if device.ExpiresTs > 0 && time.Now().UnixNano()/1000000 >= device.ExpiresTs {
+		return http.StatusUnauthorized, jsonerror.UnknownToken("Refresh token has expired")
+	}
+
+	domain, _ := common.DomainFromID(device.UserID)
+	tokens, err := issueTokens(ctx, cfg, deviceDB, domain, device, true)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	log.Infof("refresh token used by user %s device %s", device.UserID, device.DeviceID)
+
+	return http.StatusOK, &external.PostRefreshResponse{
+		AccessToken:  tokens.accessToken,
+		RefreshToken: tokens.refreshToken,
+		ExpiresInMs:  tokens.expiresInMs,
+	}
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
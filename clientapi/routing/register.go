@@ -31,6 +31,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/finogeeks/ligase/common"
@@ -54,35 +55,94 @@ const (
 	maxPasswordLength = 512 // https://github.com/matrix-org/synapse/blob/v0.20.0/synapse/rest/client/v2_alpha/register.py#L161
 	maxUsernameLength = 254 // http://matrix.org/speculator/spec/HEAD/intro.html#user-identifiers TODO account for domain
 	sessionIDLength   = 24
+	sessionLifetime   = 30 * 60 * 1000 // ms an unfinished registration session is kept
 )
 
-// sessionsDict keeps track of completed auth stages for each session.
+// sessionsDict keeps track of completed auth stages for each session, and of
+// the registration token the session has been validated with. Sessions are
+// dropped when the registration completes, or sessionLifetime after their
+// last stage.
 type sessionsDict struct {
-	sessions map[string][]string
+	mu        sync.RWMutex
+	sessions  map[string][]string
+	tokens    map[string]string
+	updated   map[string]int64
+	lastSweep int64
 }
 
 // GetCompletedStages returns the completed stages for a session.
-func (d sessionsDict) GetCompletedStages(sessionID string) []string {
-	if completedStages, ok := d.sessions[sessionID]; ok {
-		return completedStages
-	}
+func (d *sessionsDict) GetCompletedStages(sessionID string) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.completedStages(sessionID)
+}
+
+func (d *sessionsDict) completedStages(sessionID string) []string {
 	// Ensure that a empty slice is returned and not nil. See #399.
-	return make([]string, 0)
+	completedStages := make([]string, 0, len(d.sessions[sessionID]))
+	return append(completedStages, d.sessions[sessionID]...)
 }
 
 // AAddCompletedStage records that a session has completed an auth stage.
 func (d *sessionsDict) AddCompletedStage(sessionID string, stage string) {
-	d.sessions[sessionID] = append(d.GetCompletedStages(sessionID), stage)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sessions[sessionID] = append(d.completedStages(sessionID), stage)
+	d.touch(sessionID)
+}
+
+// GetRegistrationToken returns the registration token of a session, or "".
+func (d *sessionsDict) GetRegistrationToken(sessionID string) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.tokens[sessionID]
+}
+
+func (d *sessionsDict) SetRegistrationToken(sessionID string, token string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tokens[sessionID] = token
+	d.touch(sessionID)
+}
+
+// DeleteSession forgets a session once its registration has completed.
+func (d *sessionsDict) DeleteSession(sessionID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.delete(sessionID)
+}
+
+func (d *sessionsDict) delete(sessionID string) {
+	delete(d.sessions, sessionID)
+	delete(d.tokens, sessionID)
+	delete(d.updated, sessionID)
+}
+
+// touch refreshes the session and drops the expired ones, at most once per
+// sessionLifetime. It must be called with the lock held.
+func (d *sessionsDict) touch(sessionID string) {
+	now := time.Now().UnixNano() / 1000000
+	d.updated[sessionID] = now
+	if now-d.lastSweep < sessionLifetime {
+		return
+	}
+	d.lastSweep = now
+	for id, ts := range d.updated {
+		if now-ts >= sessionLifetime {
+			d.delete(id)
+		}
+	}
 }
 
 func newSessionsDict() *sessionsDict {
 	return &sessionsDict{
 		sessions: make(map[string][]string),
+		tokens:   make(map[string]string),
+		updated:  make(map[string]int64),
 	}
 }
 
 var (
-	// TODO: Remove old sessions. Need to do so on a session-specific timeout.
 	// sessions stores the completed flow stages for all sessions. Referenced using their sessionID.
 	sessions           = newSessionsDict()
 	validUsernameRegex = regexp.MustCompile(`^[0-9a-z_\-./]+$`)
@@ -441,13 +501,26 @@ func handleRegistrationFlow(
 		// Don't need to worry about appending to registration stages as
 		// application service registration is entirely separate.
 		return completeRegistration(ctx, cfg, accountDB, deviceDB,
-			req.Username, "", appServiceID, req.InitialDisplayName, idg)
+			req.Username, "", appServiceID, req.InitialDisplayName, req.RefreshToken, idg)
 
 	case authtypes.LoginTypeDummy:
 		// there is nothing to do
 		// Add Dummy to the list of completed registration stages
 		sessions.AddCompletedStage(sessionID, authtypes.LoginTypeDummy)
 
+	case authtypes.LoginTypeRegistrationToken:
+		// The use of the token is only counted once the registration completes
+		token, err := accountDB.GetRegistrationToken(ctx, req.Auth.Token)
+		if err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		if token == nil || !token.IsValid(time.Now().UnixNano()/1000000) {
+			return http.StatusUnauthorized, jsonerror.Unauthorized("Invalid registration token")
+		}
+
+		sessions.SetRegistrationToken(sessionID, token.Token)
+		sessions.AddCompletedStage(sessionID, authtypes.LoginTypeRegistrationToken)
+
 	default:
 		return http.StatusNotImplemented, jsonerror.Unknown("unknown/unimplemented auth type")
 	}
@@ -474,8 +547,34 @@ func checkAndCompleteFlow(
 ) (int, core.Coder) {
 	if checkFlowCompleted(flow, cfg.Derived.Registration.Flows) {
 		// This flow was completed, registration can continue
-		return completeRegistration(ctx, cfg, accountDB, deviceDB,
-			r.Username, r.Password, "", r.InitialDisplayName, idg)
+		token := sessions.GetRegistrationToken(sessionID)
+		if token == "" {
+			code, resp := completeRegistration(ctx, cfg, accountDB, deviceDB,
+				r.Username, r.Password, "", r.InitialDisplayName, r.RefreshToken, idg)
+			if code == http.StatusOK {
+				sessions.DeleteSession(sessionID)
+			}
+			return code, resp
+		}
+
+		// The token may have been used up since the stage was completed
+		ok, err := accountDB.UseRegistrationToken(ctx, token, time.Now().UnixNano()/1000000)
+		if err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		if !ok {
+			return http.StatusUnauthorized, jsonerror.Unauthorized("Invalid registration token")
+		}
+		code, resp := completeRegistration(ctx, cfg, accountDB, deviceDB,
+			r.Username, r.Password, "", r.InitialDisplayName, r.RefreshToken, idg)
+		if code != http.StatusOK {
+			if err := accountDB.ReleaseRegistrationToken(ctx, token); err != nil {
+				log.Errorf("failed to release registration token: %v", err)
+			}
+			return code, resp
+		}
+		sessions.DeleteSession(sessionID)
+		return code, resp
 	}
 
 	// There are still more stages to complete.
@@ -522,10 +621,10 @@ func LegacyRegister(
 			return http.StatusForbidden, &internals.RespMessage{Message: "HMAC incorrect"}
 		}
 
-		return completeRegistration(ctx, cfg, accountDB, deviceDB, req.Username, req.Password, "", "", idg)
+		return completeRegistration(ctx, cfg, accountDB, deviceDB, req.Username, req.Password, "", "", false, idg)
 	case authtypes.LoginTypeDummy:
 		// there is nothing to do
-		return completeRegistration(ctx, cfg, accountDB, deviceDB, req.Username, req.Password, "", "", idg)
+		return completeRegistration(ctx, cfg, accountDB, deviceDB, req.Username, req.Password, "", "", false, idg)
 	default:
 		return http.StatusNotImplemented, jsonerror.Unknown("unknown/unimplemented auth type")
 	}
@@ -564,6 +663,7 @@ func completeRegistration(
 	deviceDB model.DeviceDatabase,
 	username, password, appServiceID string,
 	displayName string,
+	refreshToken bool,
 	idg *uid.UidGenerator,
 ) (int, core.Coder) {
 	if username == "" {
@@ -574,6 +674,14 @@ func completeRegistration(
 		return http.StatusBadRequest, jsonerror.BadJSON("missing password")
 	}
 
+	deactivated, err := accountDB.IsAccountDeactivated(ctx, username)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if deactivated {
+		return http.StatusBadRequest, jsonerror.UserInUse("Desired user ID is already taken.")
+	}
+
 	acc, err := accountDB.CreateAccount(ctx, username, password, appServiceID, displayName)
 	if err != nil {
 		return http.StatusInternalServerError, jsonerror.Unknown("failed to create account: " + err.Error())
@@ -588,7 +696,12 @@ func completeRegistration(
 	}
 
 	domain, _ := common.DomainFromID(username)
-	token, err := common.BuildToken(cfg.Macaroon.Key, username, domain, username, "", false, deviceID, deviceType, true)
+	tokens, err := issueTokens(ctx, cfg, deviceDB, domain, &authtypes.RefreshToken{
+		UserID:     username,
+		DeviceID:   deviceID,
+		DeviceType: deviceType,
+		IsHuman:    true,
+	}, refreshToken)
 	if err != nil {
 		return http.StatusInternalServerError, jsonerror.Unknown("Failed to generate access token")
 	}
@@ -599,10 +712,12 @@ func completeRegistration(
 	}
 
 	return http.StatusOK, &external.RegisterResponse{
-		UserID:      dev.UserID,
-		AccessToken: token,
-		HomeServer:  domain,
-		DeviceID:    dev.ID,
+		UserID:       dev.UserID,
+		AccessToken:  tokens.accessToken,
+		HomeServer:   domain,
+		DeviceID:     dev.ID,
+		RefreshToken: tokens.refreshToken,
+		ExpiresInMs:  tokens.expiresInMs,
 	}
 }
 
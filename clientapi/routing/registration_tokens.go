@@ -0,0 +1,208 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/finogeeks/ligase/clientapi/httputil"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+const (
+	registrationTokenChars         = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789._~-"
+	registrationTokenDefaultLength = 16
+	registrationTokenMaxLength     = 64
+)
+
+// Registration tokens are restricted to unreserved URI characters by the spec
+var validRegistrationTokenRegex = regexp.MustCompile(`^[A-Za-z0-9._~\-]{1,64}$`)
+
+// AdminCreateRegistrationToken implements POST /_ligase/admin/v1/registration_tokens/new
+func AdminCreateRegistrationToken(
+	ctx context.Context,
+	req *external.PostAdminRegistrationTokenRequest,
+	device *authtypes.Device,
+	accountDB model.AccountsDatabase,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can create registration tokens")
+	}
+
+	now := time.Now().UnixNano() / 1000000
+	token := authtypes.RegistrationToken{Token: req.Token, CreatedTs: now}
+	if req.UsesAllowed != nil {
+		if *req.UsesAllowed <= 0 {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("uses_allowed must be a positive integer or null")
+		}
+		token.UsesAllowed = *req.UsesAllowed
+	}
+	if req.ExpiryTime != nil {
+		if *req.ExpiryTime <= now {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("expiry_time must not be in the past")
+		}
+		token.ExpiryTs = *req.ExpiryTime
+	}
+
+	if token.Token != "" {
+		if !validRegistrationTokenRegex.MatchString(token.Token) {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("token must consist of at most 64 characters in [A-Za-z0-9._~-]")
+		}
+	} else {
+		length := req.Length
+		if length == 0 {
+			length = registrationTokenDefaultLength
+		}
+		if length < 0 || length > registrationTokenMaxLength {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("length must be between 1 and 64")
+		}
+		generated, err := randomRegistrationToken(length)
+		if err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		token.Token = generated
+	}
+
+	created, err := accountDB.CreateRegistrationToken(ctx, &token)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if !created {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Token already exists")
+	}
+	log.Infof("registration token created by super admin, uses allowed %d, expiry %d", token.UsesAllowed, token.ExpiryTs)
+
+	resp := toExternalRegistrationToken(&token)
+	return http.StatusOK, &resp
+}
+
+// RegistrationTokenValidity implements
+// GET /_matrix/client/v1/register/m.login.registration_token/validity
+// A valid token is not reserved, it may be used up before the registration.
+func RegistrationTokenValidity(
+	ctx context.Context,
+	req *external.GetRegistrationTokenValidityRequest,
+	cfg *config.Dendrite,
+	accountDB model.AccountsDatabase,
+) (int, core.Coder) {
+	if cfg.Matrix.RegistrationDisabled {
+		return http.StatusForbidden, jsonerror.Forbidden("Registration has been disabled")
+	}
+	if req.Token == "" {
+		return http.StatusBadRequest, jsonerror.MissingArgument("'token' must be supplied")
+	}
+	if !validRegistrationTokenRegex.MatchString(req.Token) {
+		return http.StatusOK, &external.GetRegistrationTokenValidityResponse{Valid: false}
+	}
+
+	token, err := accountDB.GetRegistrationToken(ctx, req.Token)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	valid := token != nil && token.IsValid(time.Now().UnixNano()/1000000)
+	return http.StatusOK, &external.GetRegistrationTokenValidityResponse{Valid: valid}
+}
+
+// AdminListRegistrationTokens implements GET /_ligase/admin/v1/registration_tokens
+// valid=true only lists the tokens which can still be used, valid=false the
+// others.
+func AdminListRegistrationTokens(
+	ctx context.Context,
+	req *external.GetAdminRegistrationTokensRequest,
+	device *authtypes.Device,
+	accountDB model.AccountsDatabase,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can list registration tokens")
+	}
+	if req.Valid != "" && req.Valid != "true" && req.Valid != "false" {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("valid must be true or false")
+	}
+
+	tokens, err := accountDB.GetRegistrationTokens(ctx)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+
+	now := time.Now().UnixNano() / 1000000
+	resp := &external.GetAdminRegistrationTokensResponse{RegistrationTokens: []external.RegistrationToken{}}
+	for i := range tokens {
+		if req.Valid != "" && tokens[i].IsValid(now) != (req.Valid == "true") {
+			continue
+		}
+		resp.RegistrationTokens = append(resp.RegistrationTokens, toExternalRegistrationToken(&tokens[i]))
+	}
+	return http.StatusOK, resp
+}
+
+// AdminDeleteRegistrationToken implements DELETE /_ligase/admin/v1/registration_tokens/{token}
+// Registrations in progress with the token fail once it is deleted.
+func AdminDeleteRegistrationToken(
+	ctx context.Context,
+	req *external.DelAdminRegistrationTokenRequest,
+	device *authtypes.Device,
+	accountDB model.AccountsDatabase,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can revoke registration tokens")
+	}
+
+	deleted, err := accountDB.DeleteRegistrationToken(ctx, req.Token)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if !deleted {
+		return http.StatusNotFound, jsonerror.NotFound("No such registration token")
+	}
+	log.Infof("registration token revoked by super admin")
+	return http.StatusOK, nil
+}
+
+func toExternalRegistrationToken(token *authtypes.RegistrationToken) external.RegistrationToken {
+	res := external.RegistrationToken{Token: token.Token, Completed: token.Completed}
+	if token.UsesAllowed > 0 {
+		usesAllowed := token.UsesAllowed
+		res.UsesAllowed = &usesAllowed
+	}
+	if token.ExpiryTs > 0 {
+		expiry := token.ExpiryTs
+		res.ExpiryTime = &expiry
+	}
+	return res
+}
+
+func randomRegistrationToken(length int) (string, error) {
+	max := big.NewInt(int64(len(registrationTokenChars)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = registrationTokenChars[n.Int64()]
+	}
+	return string(b), nil
+}
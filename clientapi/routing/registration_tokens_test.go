@@ -0,0 +1,190 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/plugins/message/external"
+)
+
+// tokenAccountsDB serves registration tokens and counts their uses.
+type tokenAccountsDB struct {
+	*fakeAccountsDB
+	tokens map[string]*authtypes.RegistrationToken
+}
+
+func (d *tokenAccountsDB) GetRegistrationToken(ctx context.Context, token string) (*authtypes.RegistrationToken, error) {
+	return d.tokens[token], nil
+}
+
+func (d *tokenAccountsDB) UseRegistrationToken(ctx context.Context, token string, now int64) (bool, error) {
+	t, ok := d.tokens[token]
+	if !ok || !t.IsValid(now) {
+		return false, nil
+	}
+	t.Completed++
+	return true, nil
+}
+
+func TestRegistrationTokenValidity(t *testing.T) {
+	cfg := testLoginConfig("password")
+	accountDB := &tokenAccountsDB{tokens: map[string]*authtypes.RegistrationToken{
+		"fresh":   {Token: "fresh", UsesAllowed: 1},
+		"usedup":  {Token: "usedup", UsesAllowed: 1, Completed: 1},
+		"expired": {Token: "expired", ExpiryTs: 1},
+	}}
+	for token, want := range map[string]bool{
+		"fresh":   true,
+		"usedup":  false,
+		"expired": false,
+		"unknown": false,
+		"bad/one": false,
+	} {
+		req := &external.GetRegistrationTokenValidityRequest{Token: token}
+		code, resp := RegistrationTokenValidity(context.Background(), req, &cfg, accountDB)
+		if code != http.StatusOK {
+			t.Errorf("token %q returned %d %v", token, code, resp)
+			continue
+		}
+		if got := resp.(*external.GetRegistrationTokenValidityResponse).Valid; got != want {
+			t.Errorf("token %q valid = %t, want %t", token, got, want)
+		}
+	}
+
+	req := &external.GetRegistrationTokenValidityRequest{}
+	if code, resp := RegistrationTokenValidity(context.Background(), req, &cfg, accountDB); code != http.StatusBadRequest {
+		t.Errorf("missing token returned %d %v", code, resp)
+	}
+	cfg.Matrix.RegistrationDisabled = true
+	req.Token = "fresh"
+	if code, resp := RegistrationTokenValidity(context.Background(), req, &cfg, accountDB); code != http.StatusForbidden {
+		t.Errorf("disabled registration returned %d %v", code, resp)
+	}
+}
+
+func TestRegistrationTokenStage(t *testing.T) {
+	cfg := testLoginConfig("password")
+	cfg.Derived.Registration.Flows = []external.AuthFlow{
+		{Stages: []string{authtypes.LoginTypeRegistrationToken, authtypes.LoginTypeDummy}},
+	}
+	accountDB := &tokenAccountsDB{tokens: map[string]*authtypes.RegistrationToken{
+		"usedup": {Token: "usedup", UsesAllowed: 1, Completed: 1},
+		"once":   {Token: "once", UsesAllowed: 1},
+	}}
+	register := func(session, authType, token string) (int, interface{}) {
+		req := &external.PostRegisterRequest{
+			Auth:     external.AuthDict{Type: authType, Session: session, Token: token},
+			Username: "alice",
+			Password: "password",
+		}
+		return handleRegistrationFlow(context.Background(), req, session, &cfg, accountDB, nil, nil)
+	}
+
+	code, resp := register("s1", authtypes.LoginTypeRegistrationToken, "usedup")
+	if code != http.StatusUnauthorized || errCode(t, resp) != "M_UNAUTHORIZED" {
+		t.Errorf("used up token returned %d %v", code, resp)
+	}
+	if stages := sessions.GetCompletedStages("s1"); len(stages) != 0 {
+		t.Errorf("used up token completed %v", stages)
+	}
+
+	code, resp = register("s2", authtypes.LoginTypeRegistrationToken, "once")
+	if code != http.StatusUnauthorized {
+		t.Fatalf("token stage returned %d %v", code, resp)
+	}
+	uiResp, ok := resp.(*external.UserInteractiveResponse)
+	if !ok || !reflect.DeepEqual(uiResp.Completed, []string{authtypes.LoginTypeRegistrationToken}) {
+		t.Fatalf("token stage returned %#v", resp)
+	}
+	if token := sessions.GetRegistrationToken("s2"); token != "once" {
+		t.Errorf("session token = %q", token)
+	}
+
+	// the token is used up by another registration before s2 completes
+	accountDB.tokens["once"].Completed = 1
+	code, resp = register("s2", authtypes.LoginTypeDummy, "")
+	if code != http.StatusUnauthorized || errCode(t, resp) != "M_UNAUTHORIZED" {
+		t.Errorf("completing with a used up token returned %d %v", code, resp)
+	}
+	sessions.DeleteSession("s2")
+}
+
+func TestSessionsDictExpiry(t *testing.T) {
+	d := newSessionsDict()
+	d.AddCompletedStage("old", authtypes.LoginTypeDummy)
+	d.SetRegistrationToken("old", "token")
+	d.updated["old"] -= sessionLifetime
+	d.lastSweep -= sessionLifetime
+
+	d.AddCompletedStage("new", authtypes.LoginTypeDummy)
+	if stages := d.GetCompletedStages("old"); len(stages) != 0 {
+		t.Errorf("expired session kept stages %v", stages)
+	}
+	if token := d.GetRegistrationToken("old"); token != "" {
+		t.Errorf("expired session kept token %q", token)
+	}
+	if stages := d.GetCompletedStages("new"); len(stages) != 1 {
+		t.Errorf("new session stages = %v", stages)
+	}
+
+	d.DeleteSession("new")
+	if len(d.sessions) != 0 || len(d.tokens) != 0 || len(d.updated) != 0 {
+		t.Errorf("sessions left after delete: %v %v %v", d.sessions, d.tokens, d.updated)
+	}
+}
+
+func TestSessionsDictConcurrent(t *testing.T) {
+	d := newSessionsDict()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				d.AddCompletedStage("session", authtypes.LoginTypeDummy)
+				d.SetRegistrationToken("session", "token")
+				d.GetCompletedStages("session")
+				d.GetRegistrationToken("session")
+			}
+		}()
+	}
+	wg.Wait()
+	if stages := d.GetCompletedStages("session"); len(stages) != 800 {
+		t.Errorf("%d stages recorded, want 800", len(stages))
+	}
+}
+
+func TestAdminRegistrationTokensRequireSuperAdmin(t *testing.T) {
+	for _, device := range []*authtypes.Device{nil, {UserID: "@alice:example.com"}} {
+		code, _ := AdminCreateRegistrationToken(context.Background(), &external.PostAdminRegistrationTokenRequest{}, device, nil)
+		if code != http.StatusForbidden {
+			t.Fatalf("device %v created a token: %d", device, code)
+		}
+		code, _ = AdminListRegistrationTokens(context.Background(), &external.GetAdminRegistrationTokensRequest{}, device, nil)
+		if code != http.StatusForbidden {
+			t.Fatalf("device %v listed the tokens: %d", device, code)
+		}
+		code, _ = AdminDeleteRegistrationToken(context.Background(), &external.DelAdminRegistrationTokenRequest{Token: "abc"}, device, nil)
+		if code != http.StatusForbidden {
+			t.Fatalf("device %v revoked a token: %d", device, code)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/finogeeks/ligase/clientapi/httputil"
+	"github.com/finogeeks/ligase/clientapi/threepid"
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/common/uid"
+	"github.com/finogeeks/ligase/core"
+	fed "github.com/finogeeks/ligase/federation/fedreq"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/roomservertypes"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+const (
+	eventReportOpen     = "open"
+	eventReportResolved = "resolved"
+)
+
+// ReportEvent implements POST /rooms/{roomId}/report/{eventId}
+// The event is stored with the report, so moderators still see what was
+// reported once it has been redacted.
+func ReportEvent(
+	ctx context.Context,
+	req *external.PostRoomReportRequest,
+	userID string,
+	rsRpcCli roomserverapi.RoomserverRPCAPI,
+	roomDB model.RoomServerDatabase,
+	idg *uid.UidGenerator,
+) (int, core.Coder) {
+	if req.Score < -100 || req.Score > 0 {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("score must be between -100 and 0")
+	}
+
+	var queryRes roomserverapi.QueryRoomStateResponse
+	queryReq := roomserverapi.QueryRoomStateRequest{RoomID: req.RoomID}
+	if err := rsRpcCli.QueryRoomState(ctx, &queryReq, &queryRes); err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	// Same answer for unknown rooms and rooms the user can't see
+	if !queryRes.RoomExists || queryRes.Join[userID] == nil {
+		return http.StatusNotFound, jsonerror.NotFound("Unable to report event: it does not exist or you aren't able to see it.")
+	}
+
+	eventReq := roomserverapi.QueryRoomEventByIDRequest{EventID: req.EventID, RoomID: req.RoomID}
+	var eventRes roomserverapi.QueryRoomEventByIDResponse
+	if err := rsRpcCli.QueryRoomEventByID(ctx, &eventReq, &eventRes); err != nil || eventRes.Event == nil {
+		return http.StatusNotFound, jsonerror.NotFound("Unable to report event: it does not exist or you aren't able to see it.")
+	}
+
+	id, err := idg.Next()
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	report := &roomservertypes.EventReport{
+		ID:         id,
+		RoomID:     req.RoomID,
+		EventID:    req.EventID,
+		Sender:     eventRes.Event.Sender(),
+		Reporter:   userID,
+		Reason:     req.Reason,
+		Score:      req.Score,
+		EventJSON:  eventRes.Event.JSON(),
+		ReceivedTs: time.Now().UnixNano() / 1000000,
+	}
+	if err = roomDB.InsertEventReport(ctx, report); err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	log.Infof("user %s reported event %s of room %s, report %d", userID, req.EventID, req.RoomID, id)
+	return http.StatusOK, nil
+}
+
+// AdminListEventReports implements GET /_ligase/admin/v1/event_reports
+// state=open lists the moderation queue, state=resolved the handled reports.
+func AdminListEventReports(
+	ctx context.Context,
+	req *external.GetAdminEventReportsRequest,
+	device *authtypes.Device,
+	roomDB model.RoomServerDatabase,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can list event reports")
+	}
+	if req.State != "" && req.State != eventReportOpen && req.State != eventReportResolved {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("state must be open or resolved")
+	}
+
+	from, limit := adminPage(req.From, req.Limit)
+	reports, total, err := roomDB.GetEventReports(ctx, req.RoomID, req.State, limit, from)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+
+	resp := &external.GetAdminEventReportsResponse{EventReports: []external.AdminEventReport{}, Total: total}
+	for i := range reports {
+		resp.EventReports = append(resp.EventReports, toExternalEventReport(&reports[i], false))
+	}
+	if next := from + int64(len(reports)); next < total {
+		resp.NextFrom = next
+	}
+	return http.StatusOK, resp
+}
+
+// AdminGetEventReport implements GET /_ligase/admin/v1/event_reports/{reportID}
+func AdminGetEventReport(
+	ctx context.Context,
+	req *external.GetAdminEventReportRequest,
+	device *authtypes.Device,
+	roomDB model.RoomServerDatabase,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can read event reports")
+	}
+
+	report, code, resErr := loadEventReport(ctx, req.ReportID, roomDB)
+	if resErr != nil {
+		return code, resErr
+	}
+	res := toExternalEventReport(report, true)
+	return http.StatusOK, &res
+}
+
+// AdminAssignEventReport implements PUT /_ligase/admin/v1/event_reports/{reportID}/assign
+func AdminAssignEventReport(
+	ctx context.Context,
+	req *external.PutAdminEventReportAssignRequest,
+	device *authtypes.Device,
+	roomDB model.RoomServerDatabase,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can assign event reports")
+	}
+
+	report, code, resErr := loadEventReport(ctx, req.ReportID, roomDB)
+	if resErr != nil {
+		return code, resErr
+	}
+	assigned, err := roomDB.AssignEventReport(ctx, report.ID, req.Assignee)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if !assigned {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("The report is already resolved")
+	}
+	log.Infof("event report %d assigned to %q by super admin", report.ID, req.Assignee)
+	return http.StatusOK, nil
+}
+
+// AdminResolveEventReport implements POST /_ligase/admin/v1/event_reports/{reportID}/resolve
+// The redaction and the kick are sent by the given moderator. The report
+// stays open if one of them fails, so that it can be resolved again, and the
+// actions which succeeded are recorded so that they aren't repeated.
+func AdminResolveEventReport(
+	ctx context.Context,
+	req *external.PostAdminEventReportResolveRequest,
+	device *authtypes.Device,
+	cfg *config.Dendrite,
+	accountDB model.AccountsDatabase,
+	roomDB model.RoomServerDatabase,
+	federation *fed.Federation,
+	rpcCli roomserverapi.RoomserverRPCAPI,
+	cache service.Cache,
+	idg *uid.UidGenerator,
+	complexCache *common.ComplexCache,
+) (int, core.Coder) {
+	if !isSuperAdmin(device) {
+		return http.StatusForbidden, jsonerror.Forbidden("Only the super admin can resolve event reports")
+	}
+
+	report, code, resErr := loadEventReport(ctx, req.ReportID, roomDB)
+	if resErr != nil {
+		return code, resErr
+	}
+	if report.ResolvedTs > 0 {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("The report is already resolved")
+	}
+
+	resp := &external.PostAdminEventReportResolveResponse{
+		RedactionEventID: report.RedactionEventID,
+		Kicked:           report.Kicked,
+	}
+	redact := req.Redact && report.RedactionEventID == ""
+	kick := req.Kick && !report.Kicked
+	if redact || kick {
+		if req.Moderator == "" {
+			return http.StatusBadRequest, jsonerror.MissingArgument("'moderator' must be supplied to redact or kick")
+		}
+		if code, resErr := checkLocalAccount(ctx, req.Moderator, cfg, accountDB); resErr != nil {
+			return code, resErr
+		}
+	}
+
+	if redact {
+		content, _ := json.Marshal(struct {
+			Reason string `json:"reason"`
+		}{req.Resolution})
+		code, res := RedactEvent(
+			ctx, content, req.Moderator, "", report.RoomID, nil, nil, *cfg, cache, rpcCli,
+			report.EventID, "m.room.redaction", idg,
+		)
+		if code != http.StatusOK {
+			return code, res
+		}
+		resp.RedactionEventID = res.(*external.PutRedactEventResponse).EventID
+		if err := roomDB.SetEventReportActions(ctx, report.ID, resp.RedactionEventID, resp.Kicked); err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+	}
+	if kick {
+		content, _ := json.Marshal(threepid.MembershipRequest{UserID: report.Sender, Reason: req.Resolution})
+		code, res := SendMembership(
+			ctx, &external.PostRoomsMembershipRequest{RoomID: report.RoomID, Membership: "kick", Content: content},
+			accountDB, req.Moderator, "", report.RoomID, "kick", *cfg, rpcCli, federation, cache, idg, complexCache,
+		)
+		if code != http.StatusOK {
+			return code, res
+		}
+		resp.Kicked = true
+		if err := roomDB.SetEventReportActions(ctx, report.ID, resp.RedactionEventID, resp.Kicked); err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+	}
+
+	resolved, err := roomDB.ResolveEventReport(ctx, report.ID, device.UserID, time.Now().UnixNano()/1000000, req.Resolution)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if !resolved {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("The report is already resolved")
+	}
+	log.Infof("event report %d resolved by super admin, redaction: %s, kicked: %t", report.ID, resp.RedactionEventID, resp.Kicked)
+	return http.StatusOK, resp
+}
+
+func loadEventReport(
+	ctx context.Context, reportID string, roomDB model.RoomServerDatabase,
+) (*roomservertypes.EventReport, int, core.Coder) {
+	id, err := strconv.ParseInt(reportID, 10, 64)
+	if err != nil {
+		return nil, http.StatusNotFound, jsonerror.NotFound("Unknown event report")
+	}
+	report, err := roomDB.GetEventReport(ctx, id)
+	if err != nil {
+		code, resErr := httputil.LogThenErrorCtx(ctx, err)
+		return nil, code, resErr
+	}
+	if report == nil {
+		return nil, http.StatusNotFound, jsonerror.NotFound("Unknown event report")
+	}
+	return report, http.StatusOK, nil
+}
+
+func toExternalEventReport(report *roomservertypes.EventReport, withEvent bool) external.AdminEventReport {
+	res := external.AdminEventReport{
+		ID:         report.ID,
+		RoomID:     report.RoomID,
+		EventID:    report.EventID,
+		Sender:     report.Sender,
+		UserID:     report.Reporter,
+		Reason:     report.Reason,
+		Score:      report.Score,
+		ReceivedTs: report.ReceivedTs,
+		State:      eventReportOpen,
+		Assignee:   report.Assignee,
+		ResolvedBy: report.ResolvedBy,
+		ResolvedTs: report.ResolvedTs,
+		Resolution: report.Resolution,
+
+		RedactionEventID: report.RedactionEventID,
+		Kicked:           report.Kicked,
+	}
+	if report.ResolvedTs > 0 {
+		res.State = eventReportResolved
+	}
+	if withEvent {
+		res.Event = report.EventJSON
+	}
+	return res
+}
@@ -0,0 +1,167 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/finogeeks/ligase/common/uid"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/roomservertypes"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// reportRoomDB holds a single report and records the changes made to it.
+type reportRoomDB struct {
+	model.RoomServerDatabase
+	report *roomservertypes.EventReport
+}
+
+func (d *reportRoomDB) GetEventReport(ctx context.Context, id int64) (*roomservertypes.EventReport, error) {
+	if id != d.report.ID {
+		return nil, nil
+	}
+	report := *d.report
+	return &report, nil
+}
+
+func (d *reportRoomDB) SetEventReportActions(ctx context.Context, id int64, redactionEventID string, kicked bool) error {
+	d.report.RedactionEventID = redactionEventID
+	d.report.Kicked = kicked
+	return nil
+}
+
+func (d *reportRoomDB) ResolveEventReport(ctx context.Context, id int64, resolvedBy string, resolvedTs int64, resolution string) (bool, error) {
+	if d.report.ResolvedTs > 0 {
+		return false, nil
+	}
+	d.report.ResolvedBy, d.report.ResolvedTs, d.report.Resolution = resolvedBy, resolvedTs, resolution
+	return true, nil
+}
+
+// reportCache answers the redaction from the transaction cache, so that
+// RedactEvent doesn't build an event.
+type reportCache struct {
+	*fakeCache
+	redactionEventID string
+}
+
+func (c *reportCache) GetTxnID(roomID, msgID string) (string, bool) {
+	return c.redactionEventID, c.redactionEventID != ""
+}
+
+// unreachableRoomserver fails every room state query, which fails the kick.
+type unreachableRoomserver struct {
+	roomserverapi.RoomserverRPCAPI
+}
+
+func (r *unreachableRoomserver) QueryRoomState(ctx context.Context, req *roomserverapi.QueryRoomStateRequest, res *roomserverapi.QueryRoomStateResponse) error {
+	return errors.New("roomserver unreachable")
+}
+
+func resolveTestReport(
+	t *testing.T, req *external.PostAdminEventReportResolveRequest, roomDB *reportRoomDB, cache *reportCache,
+) (int, interface{}) {
+	cfg := testLoginConfig("password")
+	idg, err := uid.NewDefaultIdGenerator(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return AdminResolveEventReport(
+		context.Background(), req, &authtypes.Device{UserID: superAdminUserID}, &cfg, newAdminAccountsDB("@mod:example.com"),
+		roomDB, nil, &unreachableRoomserver{}, cache, idg, nil,
+	)
+}
+
+func newTestReport() *roomservertypes.EventReport {
+	return &roomservertypes.EventReport{ID: 1, RoomID: "!room:example.com", EventID: "$spam", Sender: "@spammer:example.com"}
+}
+
+func TestResolveEventReportRequiresModerator(t *testing.T) {
+	roomDB := &reportRoomDB{report: newTestReport()}
+	req := &external.PostAdminEventReportResolveRequest{ReportID: "1", Redact: true}
+	code, resp := resolveTestReport(t, req, roomDB, &reportCache{fakeCache: newFakeCache()})
+	if code != http.StatusBadRequest || errCode(t, resp) != "M_MISSING_ARGUMENT" {
+		t.Errorf("resolving without a moderator returned %d %v", code, resp)
+	}
+	if roomDB.report.ResolvedTs > 0 {
+		t.Error("the report was resolved")
+	}
+}
+
+func TestResolveEventReportRecordsActions(t *testing.T) {
+	roomDB := &reportRoomDB{report: newTestReport()}
+	cache := &reportCache{fakeCache: newFakeCache(), redactionEventID: "$redaction"}
+	req := &external.PostAdminEventReportResolveRequest{ReportID: "1", Redact: true, Kick: true, Moderator: "@mod:example.com"}
+
+	// the redaction succeeds, the kick fails
+	if code, resp := resolveTestReport(t, req, roomDB, cache); code == http.StatusOK {
+		t.Fatalf("resolving with a failed kick returned %d %v", code, resp)
+	}
+	if roomDB.report.RedactionEventID != "$redaction" || roomDB.report.Kicked {
+		t.Fatalf("recorded actions = %q %t", roomDB.report.RedactionEventID, roomDB.report.Kicked)
+	}
+	if roomDB.report.ResolvedTs > 0 {
+		t.Fatal("the report was resolved after a failed kick")
+	}
+
+	// once the sender is kicked by hand, resolving again doesn't redact
+	roomDB.report.Kicked = true
+	cache.redactionEventID = ""
+	req.Moderator = ""
+	code, resp := resolveTestReport(t, req, roomDB, cache)
+	if code != http.StatusOK {
+		t.Fatalf("resolving again returned %d %v", code, resp)
+	}
+	res := resp.(*external.PostAdminEventReportResolveResponse)
+	if res.RedactionEventID != "$redaction" || !res.Kicked {
+		t.Errorf("response = %+v", res)
+	}
+	if roomDB.report.ResolvedTs == 0 {
+		t.Error("the report is still open")
+	}
+}
+
+func TestAdminEventReportsRequireSuperAdmin(t *testing.T) {
+	cfg := testLoginConfig("password")
+	for _, device := range []*authtypes.Device{nil, {UserID: "@alice:example.com"}} {
+		roomDB := &reportRoomDB{report: newTestReport()}
+		codes := []int{}
+		code, _ := AdminListEventReports(context.Background(), &external.GetAdminEventReportsRequest{}, device, roomDB)
+		codes = append(codes, code)
+		code, _ = AdminGetEventReport(context.Background(), &external.GetAdminEventReportRequest{ReportID: "1"}, device, roomDB)
+		codes = append(codes, code)
+		code, _ = AdminAssignEventReport(context.Background(), &external.PutAdminEventReportAssignRequest{ReportID: "1", Assignee: "@mod:example.com"}, device, roomDB)
+		codes = append(codes, code)
+		code, _ = AdminResolveEventReport(
+			context.Background(), &external.PostAdminEventReportResolveRequest{ReportID: "1", Redact: true}, device, &cfg,
+			newAdminAccountsDB("@mod:example.com"), roomDB, nil, &unreachableRoomserver{}, &reportCache{fakeCache: newFakeCache()}, nil, nil,
+		)
+		codes = append(codes, code)
+		for i, code := range codes {
+			if code != http.StatusForbidden {
+				t.Fatalf("device %v: call %d returned %d, want 403", device, i, code)
+			}
+		}
+		if roomDB.report.ResolvedTs > 0 {
+			t.Fatalf("device %v resolved the report", device)
+		}
+	}
+}
@@ -18,30 +18,172 @@
 package routing
 
 import (
+	"context"
 	"net/http"
 
+	"github.com/finogeeks/ligase/clientapi/httputil"
+	"github.com/finogeeks/ligase/clientapi/threepid"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/common/mail"
 	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/authtypes"
 	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
 )
 
-// RequestEmailToken implements:
-//     POST /account/3pid/email/requestToken
-//     POST /register/email/requestToken
-func RequestEmailToken() (int, core.Coder) {
-	return http.StatusOK, &external.PostAccount3PIDEmailResponse{}
+// RequestEmailToken implements POST /account/3pid/email/requestToken and
+// POST /register/email/requestToken, both refuse emails which are already
+// bound to a user.
+func RequestEmailToken(
+	ctx context.Context,
+	req *external.PostAccount3PIDEmailRequest,
+	accountDB model.AccountsDatabase,
+	cfg *config.Dendrite,
+) (int, core.Coder) {
+	if req.Email == "" || req.ClientSecret == "" {
+		return http.StatusBadRequest, jsonerror.MissingArgument("'email' and 'client_secret' must be supplied")
+	}
+	email, err := threepid.NormalizeEmail(req.Email)
+	if err != nil {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Invalid email address")
+	}
+
+	userID, err := accountDB.GetUserIDByThreePID(ctx, "email", email)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if userID != "" {
+		return http.StatusBadRequest, jsonerror.ThreePIDInUse("Email is already in use")
+	}
+
+	sid, err := threepid.RequestEmailValidation(ctx, accountDB, cfg, req.ClientSecret, email, req.SendAttempt, req.NextLink)
+	if err != nil {
+		return threePIDErrorResponse(ctx, err)
+	}
+	return http.StatusOK, &external.PostAccount3PIDEmailResponse{Sid: sid}
+}
+
+// SubmitEmailToken implements GET /3pid/email/submit_token, the link sent
+// in validation emails
+func SubmitEmailToken(
+	ctx context.Context,
+	req *external.GetSubmit3PIDEmailTokenRequest,
+	accountDB model.AccountsDatabase,
+	cfg *config.Dendrite,
+) (int, core.Coder) {
+	if req.Sid == "" || req.ClientSecret == "" || req.Token == "" {
+		return http.StatusBadRequest, jsonerror.MissingArgument("'sid', 'client_secret' and 'token' must be supplied")
+	}
+	if _, err := threepid.SubmitEmailToken(ctx, accountDB, cfg, req.Sid, req.ClientSecret, req.Token); err != nil {
+		return threePIDErrorResponse(ctx, err)
+	}
+	return http.StatusOK, nil
 }
 
 // CheckAndSave3PIDAssociation implements POST /account/3pid
-func CheckAndSave3PIDAssociation() (int, core.Coder) {
+func CheckAndSave3PIDAssociation(
+	ctx context.Context,
+	req *external.PostAccount3PIDRequest,
+	device *authtypes.Device,
+	accountDB model.AccountsDatabase,
+	cfg *config.Dendrite,
+) (int, core.Coder) {
+	creds := req.ThreePIDCreds
+	session, err := threepid.CheckValidatedSession(ctx, accountDB, cfg, creds.Sid, creds.ClientSecret)
+	if err != nil {
+		return threePIDErrorResponse(ctx, err)
+	}
+
+	inserted, err := accountDB.SaveThreePIDAssociation(ctx, session.Medium, session.Address, device.UserID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if !inserted {
+		owner, err := accountDB.GetUserIDByThreePID(ctx, session.Medium, session.Address)
+		if err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		if owner != device.UserID {
+			return http.StatusBadRequest, jsonerror.ThreePIDInUse("Email is already in use")
+		}
+	}
+
+	// A session binds a single 3PID
+	if err = accountDB.DeleteThreePIDSession(ctx, session.SID); err != nil {
+		log.Errorf("delete 3pid session %s error: %v", session.SID, err)
+	}
 	return http.StatusOK, nil
 }
 
 // GetAssociated3PIDs implements GET /account/3pid
-func GetAssociated3PIDs() (int, core.Coder) {
-	return http.StatusOK, &external.GetThreePIDsResponse{[]external.ThreePID{}}
+func GetAssociated3PIDs(
+	ctx context.Context,
+	device *authtypes.Device,
+	accountDB model.AccountsDatabase,
+) (int, core.Coder) {
+	threePIDs, err := accountDB.GetThreePIDsForUser(ctx, device.UserID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+
+	resp := &external.GetThreePIDsResponse{ThreePIDs: []external.ThreePID{}}
+	for _, t := range threePIDs {
+		resp.ThreePIDs = append(resp.ThreePIDs, external.ThreePID{
+			Address:     t.Address,
+			Medium:      t.Medium,
+			ValidatedAt: t.AddedAt,
+			AddedAt:     t.AddedAt,
+		})
+	}
+	return http.StatusOK, resp
 }
 
 // Forget3PID implements POST /account/3pid/delete
-func Forget3PID() (int, core.Coder) {
+func Forget3PID(
+	ctx context.Context,
+	req *external.PostAccount3PIDDelRequest,
+	device *authtypes.Device,
+	accountDB model.AccountsDatabase,
+) (int, core.Coder) {
+	if req.Medium == "" || req.Address == "" {
+		return http.StatusBadRequest, jsonerror.MissingArgument("'medium' and 'address' must be supplied")
+	}
+	address := req.Address
+	if req.Medium == "email" {
+		if email, err := threepid.NormalizeEmail(address); err == nil {
+			address = email
+		}
+	}
+
+	if err := accountDB.RemoveThreePIDAssociation(ctx, req.Medium, address, device.UserID); err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
 	return http.StatusOK, nil
 }
+
+// lookupUserByEmail returns the user bound to the email, or "" if none. Only
+// validated 3PID bindings count, the email of the user info is set without
+// any validation.
+func lookupUserByEmail(ctx context.Context, accountDB model.AccountsDatabase, email string) (string, error) {
+	return accountDB.GetUserIDByThreePID(ctx, "email", email)
+}
+
+func threePIDErrorResponse(ctx context.Context, err error) (int, core.Coder) {
+	switch err {
+	case mail.ErrNotConfigured:
+		return http.StatusBadRequest, jsonerror.ThreePIDMediumNotSupported("Email validation is not configured")
+	case threepid.ErrInvalidEmail:
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Invalid email address")
+	case threepid.ErrSessionNotFound:
+		return http.StatusBadRequest, jsonerror.ThreePIDAuthFailed("No such validation session")
+	case threepid.ErrInvalidToken:
+		return http.StatusBadRequest, jsonerror.ThreePIDAuthFailed("Invalid token")
+	case threepid.ErrSessionExpired:
+		return http.StatusBadRequest, jsonerror.ThreePIDAuthFailed("Validation session expired")
+	case threepid.ErrNotValidated:
+		return http.StatusBadRequest, jsonerror.SessionNotValidated("Email has not been validated")
+	}
+	return httputil.LogThenErrorCtx(ctx, err)
+}
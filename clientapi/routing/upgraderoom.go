@@ -0,0 +1,318 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	jsonRaw "encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/finogeeks/ligase/clientapi/httputil"
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/common/uid"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/roomservertypes"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// UpgradeRoom implements POST /rooms/{roomId}/upgrade
+// The replacement room gets a copy of the old room state and takes over its
+// local aliases and public directory entry. The old room is then tombstoned
+// and its power levels raised so that regular members can't talk there anymore.
+// Nothing is created when the user can't restrict the old room.
+func UpgradeRoom(
+	ctx context.Context,
+	req *external.PostRoomUpgradeRequest,
+	userID string,
+	cfg config.Dendrite,
+	roomDB model.RoomServerDatabase,
+	rpcCli roomserverapi.RoomserverRPCAPI,
+	cache service.Cache,
+	idg *uid.UidGenerator,
+	complexCache *common.ComplexCache,
+) (int, core.Coder) {
+	newVersion := gomatrixserverlib.RoomVersion(req.NewVersion)
+	if _, ok := gomatrixserverlib.SupportedRoomVersions()[newVersion]; !ok {
+		return http.StatusBadRequest, jsonerror.UnsupportedRoomVersion(fmt.Sprintf("Room version %q is not supported", req.NewVersion))
+	}
+
+	var queryRes roomserverapi.QueryRoomStateResponse
+	queryReq := roomserverapi.QueryRoomStateRequest{RoomID: req.RoomID}
+	if err := rpcCli.QueryRoomState(ctx, &queryReq, &queryRes); err != nil || !queryRes.RoomExists {
+		return http.StatusNotFound, jsonerror.NotFound("Room does not exist")
+	}
+	if queryRes.Join[userID] == nil {
+		return http.StatusForbidden, jsonerror.Forbidden("You are not in the room")
+	}
+	if queryRes.Tombstone != nil {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("The room has already been upgraded")
+	}
+
+	domainID, _ := common.DomainFromID(userID)
+	oldVersion := gomatrixserverlib.AuthEventsRoomVersion(&queryRes)
+	nid, _ := idg.Next()
+	newRoomID := fmt.Sprintf("!%d:%s", nid, domainID)
+
+	// The tombstone is built first, the new room refers to it as predecessor.
+	// It also tells whether the user is allowed to upgrade the room at all.
+	tombstone, err := buildUpgradeEvent(
+		userID, req.RoomID, "m.room.tombstone", "",
+		common.TombstoneContent{Body: "This room has been replaced", ReplacementRoom: newRoomID},
+		domainID, oldVersion, idg,
+	)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	if err = gomatrixserverlib.Allowed(*tombstone, &queryRes); err != nil {
+		return http.StatusForbidden, jsonerror.Forbidden(err.Error())
+	}
+
+	aliases, err := roomDB.GetAliasesFromRoomID(ctx, req.RoomID)
+	if err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+
+	var createContent common.CreateContent
+	if err = json.Unmarshal(queryRes.Creator.Content(), &createContent); err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+	createContent.Creator = userID
+	createContent.RoomVersion = newVersion
+	createContent.Predecessor = &common.PreviousRoom{RoomID: req.RoomID, EventID: tombstone.EventID()}
+
+	powerContent := common.InitialPowerLevelsContent(queryRes.Creator.Sender())
+	if queryRes.Power != nil {
+		powerContent = common.PowerLevelContent{}
+		if err = json.Unmarshal(queryRes.Power.Content(), &powerContent); err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+	}
+
+	// The old room events are built before the new room is created so that an
+	// upgrade the user can't finish fails without leaving a room behind. The
+	// old room must be restricted with its power levels, the other events
+	// are only sent when the user is allowed to.
+	oldRoomEvents := []gomatrixserverlib.Event{*tombstone}
+	for _, e := range oldRoomUpgradeState(&queryRes, powerContent, aliases, domainID) {
+		ev, err := buildUpgradeEvent(userID, req.RoomID, e.Type, e.StateKey, e.Content, domainID, oldVersion, idg)
+		if err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		if err = gomatrixserverlib.Allowed(*ev, &queryRes); err != nil {
+			if e.Type == "m.room.power_levels" {
+				return http.StatusForbidden, jsonerror.Forbidden("You can't restrict the power levels of the old room: " + err.Error())
+			}
+			log.Warnf("UpgradeRoom user %s can't send %s in old room %s: %v", userID, e.Type, req.RoomID, err)
+			continue
+		}
+		oldRoomEvents = append(oldRoomEvents, *ev)
+	}
+
+	// The user may be allowed to send the tombstone but not all of the state
+	// copied below, so the new room starts with the user raised to the level
+	// needed and the original power levels are restored at the end.
+	userLevel := powerContent.UsersDefault
+	if level, ok := powerContent.Users[userID]; ok {
+		userLevel = level
+	}
+	neededLevel := powerContent.StateDefault
+	for _, level := range powerContent.Events {
+		if level > neededLevel {
+			neededLevel = level
+		}
+	}
+	initialPower := powerContent
+	if userLevel < neededLevel {
+		initialPower.Users = map[string]int{}
+		for user, level := range powerContent.Users {
+			initialPower.Users[user] = level
+		}
+		initialPower.Users[userID] = neededLevel
+	}
+
+	displayName, avatarURL, _ := complexCache.GetProfileByUserID(ctx, userID)
+	eventsToMake := []external.StateEvent{
+		{Type: "m.room.create", Content: createContent},
+		{Type: "m.room.member", StateKey: userID, Content: external.MemberContent{
+			Membership:  "join",
+			DisplayName: displayName,
+			AvatarURL:   avatarURL,
+		}},
+		{Type: "m.room.power_levels", Content: initialPower},
+	}
+	for _, ev := range []*gomatrixserverlib.Event{
+		queryRes.JoinRule, queryRes.HistoryVisibility, queryRes.Visibility, queryRes.GuestAccess,
+		queryRes.Name, queryRes.Topic, queryRes.Desc, queryRes.Avatar, queryRes.Encryption,
+		queryRes.CanonicalAlias,
+	} {
+		if ev != nil {
+			eventsToMake = append(eventsToMake, external.StateEvent{
+				Type: ev.Type(), StateKey: *ev.StateKey(), Content: jsonRaw.RawMessage(ev.Content()),
+			})
+		}
+	}
+	if len(aliases) > 0 {
+		eventsToMake = append(eventsToMake, external.StateEvent{Type: "m.room.aliases", StateKey: domainID, Content: common.AliasesContent{Aliases: aliases}})
+	}
+	if userLevel < neededLevel {
+		eventsToMake = append(eventsToMake, external.StateEvent{Type: "m.room.power_levels", Content: powerContent})
+	}
+
+	var builtEvents []gomatrixserverlib.Event
+	for i, e := range eventsToMake {
+		builder := gomatrixserverlib.EventBuilder{
+			Sender:   userID,
+			RoomID:   newRoomID,
+			Type:     e.Type,
+			StateKey: &eventsToMake[i].StateKey,
+			Depth:    int64(i + 1),
+		}
+		if err = builder.SetContent(e.Content); err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		ev, err := common.BuildEvent(&builder, domainID, newVersion, idg)
+		if err != nil {
+			return httputil.LogThenErrorCtx(ctx, err)
+		}
+		builtEvents = append(builtEvents, *ev)
+	}
+
+	rawEvent := roomserverapi.RawEvent{
+		RoomID: newRoomID,
+		Kind:   roomserverapi.KindNew,
+		TxnID:  &roomservertypes.TransactionID{DeviceID: userID},
+		Trust:  false,
+		BulkEvents: roomserverapi.BulkEvent{
+			Events:  builtEvents,
+			SvrName: domainID,
+		},
+		Query: []string{"room_upgrade", ""},
+	}
+	if _, err = rpcCli.InputRoomEvents(ctx, &rawEvent); err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+
+	for _, alias := range aliases {
+		if err = moveRoomAlias(ctx, alias, newRoomID, roomDB, cache); err != nil {
+			log.Errorf("UpgradeRoom move alias %s from %s to %s err: %v", alias, req.RoomID, newRoomID, err)
+		}
+	}
+
+	rawEvent = roomserverapi.RawEvent{
+		RoomID: req.RoomID,
+		Kind:   roomserverapi.KindNew,
+		TxnID:  &roomservertypes.TransactionID{DeviceID: userID},
+		Trust:  true,
+		BulkEvents: roomserverapi.BulkEvent{
+			Events:  oldRoomEvents,
+			SvrName: domainID,
+		},
+		Query: []string{"room_upgrade", ""},
+	}
+	if _, err = rpcCli.InputRoomEvents(ctx, &rawEvent); err != nil {
+		return httputil.LogThenErrorCtx(ctx, err)
+	}
+
+	log.Infof("room %s upgraded to %s by %s, replacement room %s", req.RoomID, newVersion, userID, newRoomID)
+	return http.StatusOK, &external.PostRoomUpgradeResponse{ReplacementRoom: newRoomID}
+}
+
+// oldRoomUpgradeState returns the state sent in the old room after the
+// tombstone: the moved aliases are removed, the room leaves the public
+// directory and only moderators can still send events or invite.
+func oldRoomUpgradeState(
+	queryRes *roomserverapi.QueryRoomStateResponse,
+	powerContent common.PowerLevelContent,
+	aliases []string, domainID string,
+) []external.StateEvent {
+	var res []external.StateEvent
+	if queryRes.CanonicalAlias != nil {
+		var content common.CanonicalAliasContent
+		if err := json.Unmarshal(queryRes.CanonicalAlias.Content(), &content); err == nil {
+			for _, alias := range aliases {
+				if alias == content.Alias {
+					res = append(res, external.StateEvent{Type: "m.room.canonical_alias", Content: common.CanonicalAliasContent{}})
+					break
+				}
+			}
+		}
+	}
+	if len(aliases) > 0 {
+		res = append(res, external.StateEvent{Type: "m.room.aliases", StateKey: domainID, Content: common.AliasesContent{Aliases: []string{}}})
+	}
+	if queryRes.Visibility != nil {
+		var content common.VisibilityContent
+		if err := json.Unmarshal(queryRes.Visibility.Content(), &content); err == nil && content.Visibility == "public" {
+			res = append(res, external.StateEvent{Type: "m.room.visibility", Content: common.VisibilityContent{Visibility: "private"}})
+		}
+	}
+
+	restrictedLevel := 50
+	if powerContent.UsersDefault+1 > restrictedLevel {
+		restrictedLevel = powerContent.UsersDefault + 1
+	}
+	if powerContent.EventsDefault < restrictedLevel || powerContent.Invite < restrictedLevel {
+		if powerContent.EventsDefault < restrictedLevel {
+			powerContent.EventsDefault = restrictedLevel
+		}
+		if powerContent.Invite < restrictedLevel {
+			powerContent.Invite = restrictedLevel
+		}
+		res = append(res, external.StateEvent{Type: "m.room.power_levels", Content: powerContent})
+	}
+	return res
+}
+
+func buildUpgradeEvent(
+	userID, roomID, eventType, stateKey string, content interface{},
+	domainID string, roomVersion gomatrixserverlib.RoomVersion, idg *uid.UidGenerator,
+) (*gomatrixserverlib.Event, error) {
+	builder := gomatrixserverlib.EventBuilder{
+		Sender:   userID,
+		RoomID:   roomID,
+		Type:     eventType,
+		StateKey: &stateKey,
+	}
+	if err := builder.SetContent(content); err != nil {
+		return nil, err
+	}
+	return common.BuildEvent(&builder, domainID, roomVersion, idg)
+}
+
+// moveRoomAlias points a local alias to another room, in the database and in
+// the alias cache the roomserver reads first.
+func moveRoomAlias(
+	ctx context.Context, alias, roomID string,
+	roomDB model.RoomServerDatabase, cache service.Cache,
+) error {
+	if err := cache.DelAlias(alias); err != nil {
+		return err
+	}
+	if err := roomDB.RemoveRoomAlias(ctx, alias); err != nil {
+		return err
+	}
+	if err := roomDB.SetRoomAlias(ctx, alias, roomID); err != nil {
+		return err
+	}
+	return cache.SetAlias(alias, roomID, 0)
+}
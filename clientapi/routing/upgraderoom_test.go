@@ -0,0 +1,134 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/uid"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+type upgradeRoomDB struct {
+	model.RoomServerDatabase
+}
+
+func (d *upgradeRoomDB) GetAliasesFromRoomID(ctx context.Context, roomID string) ([]string, error) {
+	return nil, nil
+}
+
+type upgradeCache struct {
+	*fakeCache
+}
+
+func (c *upgradeCache) GetProfileLessByUserID(userID string) (string, string, bool) {
+	return userID, "", true
+}
+
+// upgradeRoomserver serves the state of the old room and records the events
+// sent to each room.
+type upgradeRoomserver struct {
+	roomserverapi.RoomserverRPCAPI
+	state roomserverapi.QueryRoomStateResponse
+	input map[string][]gomatrixserverlib.Event
+}
+
+func (r *upgradeRoomserver) QueryRoomState(ctx context.Context, req *roomserverapi.QueryRoomStateRequest, res *roomserverapi.QueryRoomStateResponse) error {
+	*res = r.state
+	return nil
+}
+
+func (r *upgradeRoomserver) InputRoomEvents(ctx context.Context, rawEvent *roomserverapi.RawEvent) (int, error) {
+	r.input[rawEvent.RoomID] = append(r.input[rawEvent.RoomID], rawEvent.BulkEvents.Events...)
+	return 0, nil
+}
+
+func newUpgradeRoomserver(t *testing.T, idg *uid.UidGenerator, roomID string, power common.PowerLevelContent) *upgradeRoomserver {
+	creator := "@alice:example.com"
+	var events []gomatrixserverlib.Event
+	for _, e := range []external.StateEvent{
+		{Type: "m.room.create", Content: common.CreateContent{Creator: creator}},
+		{Type: "m.room.member", StateKey: creator, Content: external.MemberContent{Membership: "join"}},
+		{Type: "m.room.member", StateKey: "@bob:example.com", Content: external.MemberContent{Membership: "join"}},
+		{Type: "m.room.power_levels", Content: power},
+	} {
+		sender := creator
+		if e.StateKey != "" {
+			sender = e.StateKey
+		}
+		ev, err := buildUpgradeEvent(sender, roomID, e.Type, e.StateKey, e.Content, "example.com", gomatrixserverlib.RoomVersionV1, idg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, *ev)
+	}
+	rs := &upgradeRoomserver{input: map[string][]gomatrixserverlib.Event{}}
+	rs.state.InitFromEvents(events)
+	rs.state.RoomID = roomID
+	rs.state.RoomExists = true
+	return rs
+}
+
+func upgradeTestRoom(t *testing.T, userID string, power common.PowerLevelContent) (int, *upgradeRoomserver) {
+	idg, _ := uid.NewDefaultIdGenerator(0)
+	roomID := "!old:example.com"
+	rs := newUpgradeRoomserver(t, idg, roomID, power)
+	cache := &upgradeCache{newFakeCache()}
+	req := &external.PostRoomUpgradeRequest{RoomID: roomID, NewVersion: "1"}
+	code, _ := UpgradeRoom(
+		context.Background(), req, userID, testLoginConfig("password"), &upgradeRoomDB{}, rs,
+		cache, idg, common.NewComplexCache(nil, cache),
+	)
+	return code, rs
+}
+
+func TestUpgradeRoom(t *testing.T) {
+	code, rs := upgradeTestRoom(t, "@alice:example.com", common.InitialPowerLevelsContent("@alice:example.com"))
+	if code != http.StatusOK {
+		t.Fatalf("code = %d, want 200", code)
+	}
+	if len(rs.input) != 2 {
+		t.Fatalf("events sent to %d rooms, want the new and the old room", len(rs.input))
+	}
+	var tombstone, power bool
+	for _, ev := range rs.input["!old:example.com"] {
+		tombstone = tombstone || ev.Type() == "m.room.tombstone"
+		power = power || ev.Type() == "m.room.power_levels"
+	}
+	if !tombstone || !power {
+		t.Fatalf("old room got tombstone:%v power levels:%v, want both", tombstone, power)
+	}
+}
+
+func TestUpgradeRoomFailsEarlyWithoutPowerLevels(t *testing.T) {
+	// Bob may send the tombstone, but can't raise the levels of the old room
+	// above his own.
+	power := common.InitialPowerLevelsContent("@alice:example.com")
+	power.StateDefault = 0
+	power.Events = map[string]int{"m.room.tombstone": 0}
+	code, rs := upgradeTestRoom(t, "@bob:example.com", power)
+	if code != http.StatusForbidden {
+		t.Fatalf("code = %d, want 403", code)
+	}
+	if len(rs.input) != 0 {
+		t.Fatalf("events sent to %d rooms before failing", len(rs.input))
+	}
+}
@@ -378,7 +378,7 @@ func BuildMembershipAndFireEvents(
 			continue
 		}
 
-		event, err := common.BuildEvent(&builder, domain, *cfg, idg)
+		event, err := common.BuildEvent(&builder, domain, gomatrixserverlib.AuthEventsRoomVersion(&queryRes), idg)
 		if err != nil {
 			log.Errorf("BuildMembershipAndFireEvents fail on BuildEvent for roomid:%s user:%s with err:%v", roomID, userID, err)
 			continue
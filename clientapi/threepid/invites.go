@@ -368,7 +368,7 @@ func emit3PIDInviteEvent(
 	}
 
 	domainID, _ := common.DomainFromID(userID)
-	event, err := common.BuildEvent(builder, domainID, cfg, idg)
+	event, err := common.BuildEvent(builder, domainID, gomatrixserverlib.AuthEventsRoomVersion(&queryRes), idg)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,230 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package threepid
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/mail"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// SubmitTokenPath is the path, relative to email.public_base_url, of the
+// link sent in validation emails.
+const SubmitTokenPath = "/_matrix/client/unstable/3pid/email/submit_token"
+
+var (
+	// ErrInvalidEmail is returned when the address can't be parsed
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrSessionNotFound is returned when the sid and client secret don't match a session
+	ErrSessionNotFound = errors.New("no such validation session")
+	// ErrInvalidToken is returned when the submitted token doesn't match the session
+	ErrInvalidToken = errors.New("invalid validation token")
+	// ErrSessionExpired is returned when the token or the validation is too old
+	ErrSessionExpired = errors.New("validation session expired")
+	// ErrNotValidated is returned when the token of the session hasn't been submitted
+	ErrNotValidated = errors.New("validation session not validated")
+)
+
+var (
+	sweepMutex sync.Mutex
+	lastSweep  int64
+)
+
+// NormalizeEmail lowercases the address, so that a 3PID is bound once
+// whatever the case used by the client.
+func NormalizeEmail(email string) (string, error) {
+	addr, err := netmail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || addr.Name != "" {
+		return "", ErrInvalidEmail
+	}
+	return strings.ToLower(addr.Address), nil
+}
+
+// RequestEmailValidation sends a validation token to the email and returns
+// the session ID. As the spec requires, the email isn't sent again if the
+// send attempt isn't greater than the one of the existing session.
+func RequestEmailValidation(
+	ctx context.Context,
+	accountDB model.AccountsDatabase,
+	cfg *config.Dendrite,
+	clientSecret, email string,
+	sendAttempt int,
+	nextLink string,
+) (string, error) {
+	sweepExpiredSessions(ctx, accountDB, cfg)
+
+	session, err := accountDB.GetThreePIDSessionBySecret(ctx, clientSecret, "email", email)
+	if err != nil {
+		return "", err
+	}
+	if session != nil && int64(sendAttempt) <= session.SendAttempt {
+		return session.SID, nil
+	}
+
+	sender, err := mail.NewSender(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	sid := randomToken(16)
+	if session != nil {
+		sid = session.SID
+	}
+	token := randomToken(24)
+	sid, err = accountDB.UpsertThreePIDSession(ctx, &authtypes.ThreePIDSession{
+		SID:          sid,
+		ClientSecret: clientSecret,
+		Medium:       "email",
+		Address:      email,
+		Token:        token,
+		SendAttempt:  int64(sendAttempt),
+		NextLink:     nextLink,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("sid", sid)
+	query.Set("client_secret", clientSecret)
+	query.Set("token", token)
+	link := strings.TrimRight(cfg.Email.PublicBaseURL, "/") + SubmitTokenPath + "?" + query.Encode()
+
+	err = sender.Send(&mail.Message{
+		To:      email,
+		Subject: "Validate your email",
+		Body: fmt.Sprintf("A request was made to use this email address on %s.\n\n"+
+			"To confirm it, open the link below:\n\n%s\n\n"+
+			"If you didn't make this request, you can ignore this email.\n", serverName(cfg), link),
+	})
+	if err != nil {
+		return "", err
+	}
+	return sid, nil
+}
+
+// SubmitEmailToken validates the session if the token matches, and returns
+// the next_link given when the token was requested.
+func SubmitEmailToken(
+	ctx context.Context,
+	accountDB model.AccountsDatabase,
+	cfg *config.Dendrite,
+	sid, clientSecret, token string,
+) (string, error) {
+	session, err := getSession(ctx, accountDB, sid, clientSecret)
+	if err != nil {
+		return "", err
+	}
+	if subtle.ConstantTimeCompare([]byte(session.Token), []byte(token)) != 1 {
+		return "", ErrInvalidToken
+	}
+	if expired(session.CreatedAt, cfg) {
+		return "", ErrSessionExpired
+	}
+	if session.ValidatedAt == 0 {
+		if err = accountDB.ValidateThreePIDSession(ctx, sid); err != nil {
+			return "", err
+		}
+	}
+	return session.NextLink, nil
+}
+
+// CheckValidatedSession returns the session if its token has been submitted
+// recently enough.
+func CheckValidatedSession(
+	ctx context.Context,
+	accountDB model.AccountsDatabase,
+	cfg *config.Dendrite,
+	sid, clientSecret string,
+) (*authtypes.ThreePIDSession, error) {
+	session, err := getSession(ctx, accountDB, sid, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if session.ValidatedAt == 0 {
+		return nil, ErrNotValidated
+	}
+	if expired(session.ValidatedAt, cfg) {
+		return nil, ErrSessionExpired
+	}
+	return session, nil
+}
+
+func getSession(
+	ctx context.Context, accountDB model.AccountsDatabase, sid, clientSecret string,
+) (*authtypes.ThreePIDSession, error) {
+	if sid == "" || clientSecret == "" {
+		return nil, ErrSessionNotFound
+	}
+	session, err := accountDB.GetThreePIDSession(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || subtle.ConstantTimeCompare([]byte(session.ClientSecret), []byte(clientSecret)) != 1 {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// sweepExpiredSessions deletes the sessions which can't be used any more, at
+// most once per token lifetime.
+func sweepExpiredSessions(ctx context.Context, accountDB model.AccountsDatabase, cfg *config.Dendrite) {
+	now := time.Now().UnixNano() / 1000000
+	lifetime := cfg.Email.TokenLifetime * 1000
+	sweepMutex.Lock()
+	if now-lastSweep < lifetime {
+		sweepMutex.Unlock()
+		return
+	}
+	lastSweep = now
+	sweepMutex.Unlock()
+	if err := accountDB.DeleteExpiredThreePIDSessions(ctx, now-lifetime); err != nil {
+		log.Errorf("delete expired 3pid sessions error: %v", err)
+	}
+}
+
+func expired(ts int64, cfg *config.Dendrite) bool {
+	lifetime := cfg.Email.TokenLifetime * 1000
+	return time.Now().UnixNano()/1000000-ts > lifetime
+}
+
+func serverName(cfg *config.Dendrite) string {
+	if len(cfg.Matrix.ServerName) > 0 {
+		return cfg.Matrix.ServerName[0]
+	}
+	return "the server"
+}
+
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
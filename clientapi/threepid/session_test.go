@@ -0,0 +1,254 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package threepid
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/mail"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// sessionDB keeps the validation sessions in memory
+type sessionDB struct {
+	model.AccountsDatabase
+	sessions    map[string]*authtypes.ThreePIDSession
+	sweptBefore []int64
+}
+
+func newSessionDB() *sessionDB {
+	return &sessionDB{sessions: map[string]*authtypes.ThreePIDSession{}}
+}
+
+func (d *sessionDB) UpsertThreePIDSession(ctx context.Context, session *authtypes.ThreePIDSession) (string, error) {
+	s := *session
+	s.CreatedAt = time.Now().UnixNano() / 1000000
+	d.sessions[s.SID] = &s
+	return s.SID, nil
+}
+
+func (d *sessionDB) GetThreePIDSession(ctx context.Context, sid string) (*authtypes.ThreePIDSession, error) {
+	if s, ok := d.sessions[sid]; ok {
+		copied := *s
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (d *sessionDB) GetThreePIDSessionBySecret(ctx context.Context, clientSecret, medium, address string) (*authtypes.ThreePIDSession, error) {
+	for _, s := range d.sessions {
+		if s.ClientSecret == clientSecret && s.Medium == medium && s.Address == address {
+			copied := *s
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (d *sessionDB) ValidateThreePIDSession(ctx context.Context, sid string) error {
+	d.sessions[sid].ValidatedAt = time.Now().UnixNano() / 1000000
+	return nil
+}
+
+func (d *sessionDB) DeleteExpiredThreePIDSessions(ctx context.Context, before int64) error {
+	d.sweptBefore = append(d.sweptBefore, before)
+	return nil
+}
+
+// sentMails collects the messages of the "test" mail provider
+var sentMails []*mail.Message
+
+type testSender struct{}
+
+func (testSender) Send(msg *mail.Message) error {
+	sentMails = append(sentMails, msg)
+	return nil
+}
+
+func init() {
+	mail.Register("test", func(cfg *config.Dendrite) (mail.Sender, error) {
+		return testSender{}, nil
+	})
+}
+
+func testConfig() *config.Dendrite {
+	cfg := &config.Dendrite{}
+	cfg.Matrix.ServerName = []string{"example.com"}
+	cfg.Email.Provider = "test"
+	cfg.Email.PublicBaseURL = "https://matrix.example.com/"
+	cfg.Email.TokenLifetime = 3600
+	return cfg
+}
+
+// sentToken returns the token of the link in the last sent mail
+func sentToken(t *testing.T) string {
+	if len(sentMails) == 0 {
+		t.Fatal("no mail was sent")
+	}
+	body := sentMails[len(sentMails)-1].Body
+	start := strings.Index(body, "https://")
+	if start < 0 {
+		t.Fatalf("no link in %q", body)
+	}
+	link, err := url.Parse(strings.Fields(body[start:])[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link.Query().Get("token")
+}
+
+func TestRequestEmailValidationSendAttempt(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	db := newSessionDB()
+	sentMails = nil
+
+	sid, err := RequestEmailValidation(ctx, db, cfg, "secret", "alice@example.com", 1, "https://app.example.com/next")
+	if err != nil || len(sentMails) != 1 {
+		t.Fatalf("first request: sid %q, %d mails, %v", sid, len(sentMails), err)
+	}
+	if sentMails[0].To != "alice@example.com" {
+		t.Fatalf("mail sent to %s", sentMails[0].To)
+	}
+	token := sentToken(t)
+	if token != db.sessions[sid].Token {
+		t.Fatalf("mailed token %q, stored %q", token, db.sessions[sid].Token)
+	}
+
+	// the same send attempt doesn't send the mail again
+	again, err := RequestEmailValidation(ctx, db, cfg, "secret", "alice@example.com", 1, "")
+	if err != nil || again != sid || len(sentMails) != 1 {
+		t.Fatalf("retried request: sid %q, %d mails, %v", again, len(sentMails), err)
+	}
+
+	// a greater one sends a new token in the same session
+	again, err = RequestEmailValidation(ctx, db, cfg, "secret", "alice@example.com", 2, "")
+	if err != nil || again != sid || len(sentMails) != 2 {
+		t.Fatalf("next attempt: sid %q, %d mails, %v", again, len(sentMails), err)
+	}
+	if sentToken(t) == token {
+		t.Fatal("the token wasn't renewed")
+	}
+
+	// another client secret gets its own session
+	other, err := RequestEmailValidation(ctx, db, cfg, "other", "alice@example.com", 1, "")
+	if err != nil || other == sid || len(sentMails) != 3 {
+		t.Fatalf("other secret: sid %q, %d mails, %v", other, len(sentMails), err)
+	}
+}
+
+func TestSubmitEmailToken(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	db := newSessionDB()
+	sentMails = nil
+
+	sid, err := RequestEmailValidation(ctx, db, cfg, "secret", "alice@example.com", 1, "https://app.example.com/next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sentToken(t)
+
+	if _, err = SubmitEmailToken(ctx, db, cfg, sid, "wrong", token); err != ErrSessionNotFound {
+		t.Fatalf("wrong secret: %v", err)
+	}
+	if _, err = SubmitEmailToken(ctx, db, cfg, "", "secret", token); err != ErrSessionNotFound {
+		t.Fatalf("no sid: %v", err)
+	}
+	if _, err = SubmitEmailToken(ctx, db, cfg, sid, "secret", "wrong"); err != ErrInvalidToken {
+		t.Fatalf("wrong token: %v", err)
+	}
+	if db.sessions[sid].ValidatedAt != 0 {
+		t.Fatal("the session was validated by a wrong token")
+	}
+
+	nextLink, err := SubmitEmailToken(ctx, db, cfg, sid, "secret", token)
+	if err != nil || nextLink != "https://app.example.com/next" {
+		t.Fatalf("valid token: %q, %v", nextLink, err)
+	}
+	if db.sessions[sid].ValidatedAt == 0 {
+		t.Fatal("the session isn't validated")
+	}
+
+	// the token expires token_lifetime after it was sent
+	db.sessions[sid].CreatedAt -= cfg.Email.TokenLifetime*1000 + 1
+	if _, err = SubmitEmailToken(ctx, db, cfg, sid, "secret", token); err != ErrSessionExpired {
+		t.Fatalf("expired token: %v", err)
+	}
+}
+
+func TestCheckValidatedSession(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	db := newSessionDB()
+	sentMails = nil
+
+	sid, err := RequestEmailValidation(ctx, db, cfg, "secret", "alice@example.com", 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = CheckValidatedSession(ctx, db, cfg, sid, "secret"); err != ErrNotValidated {
+		t.Fatalf("not validated: %v", err)
+	}
+	if _, err = SubmitEmailToken(ctx, db, cfg, sid, "secret", sentToken(t)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = CheckValidatedSession(ctx, db, cfg, sid, "wrong"); err != ErrSessionNotFound {
+		t.Fatalf("wrong secret: %v", err)
+	}
+	session, err := CheckValidatedSession(ctx, db, cfg, sid, "secret")
+	if err != nil || session.Address != "alice@example.com" {
+		t.Fatalf("validated: %v, %v", session, err)
+	}
+
+	// the validation expires token_lifetime after the token was submitted
+	db.sessions[sid].ValidatedAt -= cfg.Email.TokenLifetime*1000 + 1
+	if _, err = CheckValidatedSession(ctx, db, cfg, sid, "secret"); err != ErrSessionExpired {
+		t.Fatalf("expired validation: %v", err)
+	}
+}
+
+func TestSweepExpiredSessions(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	db := newSessionDB()
+	lastSweep = 0
+
+	now := time.Now().UnixNano() / 1000000
+	sweepExpiredSessions(ctx, db, cfg)
+	if len(db.sweptBefore) != 1 {
+		t.Fatalf("swept %d times", len(db.sweptBefore))
+	}
+	if before := db.sweptBefore[0]; before < now-cfg.Email.TokenLifetime*1000 || before > now {
+		t.Fatalf("swept the sessions before %d, now is %d", before, now)
+	}
+
+	// the next sweep waits for a token lifetime
+	sweepExpiredSessions(ctx, db, cfg)
+	if len(db.sweptBefore) != 1 {
+		t.Fatalf("swept again within the token lifetime")
+	}
+	lastSweep -= cfg.Email.TokenLifetime * 1000
+	sweepExpiredSessions(ctx, db, cfg)
+	if len(db.sweptBefore) != 2 {
+		t.Fatalf("didn't sweep after the token lifetime")
+	}
+}
@@ -187,11 +187,12 @@ func StartBgMgr(base *basecomponent.BaseDendrite, cmd *serverCmdPar) {
 	deviceDB := base.CreateDeviceDB()
 	syncDB := base.CreateSyncDB()
 	encryptDB := base.CreateEncryptApiDB()
+	roomDB := base.CreateRoomDB()
 	cache := base.PrepareCache()
 	idg, _ := uid.NewDefaultIdGenerator(base.Cfg.Matrix.InstanceId)
 	rpcClient := common.NewRpcClient(base.Cfg.Nats.Uri, idg)
 	rpcClient.Start(true)
 	tokenFilter := filter.GetFilterMng().Register("device", deviceDB)
 	tokenFilter.Load()
-	bgmgr.SetupBgMgrComponent(deviceDB, cache, encryptDB, syncDB, rpcClient, tokenFilter, base.Cfg.DeviceMng.ScanUnActive, base.Cfg.DeviceMng.KickUnActive)
+	bgmgr.SetupBgMgrComponent(base.Cfg, deviceDB, cache, encryptDB, syncDB, roomDB, rpcClient, tokenFilter, base.Cfg.DeviceMng.ScanUnActive, base.Cfg.DeviceMng.KickUnActive)
 }
@@ -193,6 +193,6 @@ func StartMonolithServer(base *basecomponent.BaseDendrite, cmd *serverCmdPar) {
 	syncwriter.SetupSyncWriterComponent(base)
 	syncaggregate.SetupSyncAggregateComponent(base, cache, rpcClient, idg, complexCache)
 	proxy.SetupProxy(base, cache, rpcClient, rsRpcCli, newTokenFilter)
-	bgmgr.SetupBgMgrComponent(deviceDB, cache, encryptDB, syncDB, rpcClient, tokenFilter, base.Cfg.DeviceMng.ScanUnActive, base.Cfg.DeviceMng.KickUnActive)
+	bgmgr.SetupBgMgrComponent(base.Cfg, deviceDB, cache, encryptDB, syncDB, roomDB, rpcClient, tokenFilter, base.Cfg.DeviceMng.ScanUnActive, base.Cfg.DeviceMng.KickUnActive)
 	rcsserver.SetupRCSServerComponent(base, rpcClient)
 }
@@ -44,6 +44,7 @@ const (
 	APITypeDownload
 	APITypeUpload
 	APITypeFed
+	APITypeOptionalAuth
 
 	APITypeMax
 )
@@ -60,7 +61,7 @@ func SetAPIProcessor(p APIProcessor) {
 	}
 	prefix := p.GetPrefix()
 	for _, v := range prefix {
-		if v != "r0" && v != "v1" && v != "inr0" && v != "sys" && v != "unstable" && v != "mediaR0" && v != "mediaV1" && v != "fedV1" {
+		if v != "r0" && v != "v1" && v != "inr0" && v != "sys" && v != "unstable" && v != "mediaR0" && v != "mediaV1" && v != "fedV1" && v != "admin" && v != "clientV1" {
 			log.Panicf("invalid prefix type %s for [%s]", v, p.GetRoute())
 			return
 		}
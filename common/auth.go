@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	util "github.com/finogeeks/ligase/skunkworks/gomatrixutil"
 	"github.com/finogeeks/ligase/skunkworks/log"
@@ -77,6 +78,14 @@ func VerifyToken(token string, requestURI string, cache service.Cache, cfg confi
 		}
 		return nil, resErr
 	}
+	if device.ExpiresTs > 0 && time.Now().UnixNano()/1000000 >= device.ExpiresTs {
+		log.Infof("expired token user %s device %s, req: %s", device.UserID, device.ID, requestURI)
+		resErr := &util.JSONResponse{
+			Code: 401,
+			JSON: jsonerror.UnknownTokenSoftLogout("Access token has expired"),
+		}
+		return nil, resErr
+	}
 	if guest == false && devFilter != nil && !filterTokenCheck(device.UserID) {
 		key := device.UserID + ":" + device.ID
 		if !devFilter.Lookup(device.UserID, device.ID) {
@@ -116,7 +116,13 @@ type Dendrite struct {
 		// If set disables new users from registering (except via shared
 		// secrets)
 		RegistrationDisabled bool `yaml:"registration_disabled"`
-		ServerFromDB         bool `yaml:"server_from_db"`
+		// If set, users must complete the m.login.registration_token stage
+		// with a token created through the admin API to register
+		RegistrationRequiresToken bool `yaml:"registration_requires_token"`
+		ServerFromDB              bool `yaml:"server_from_db"`
+		// The room version of the rooms created without asking for one.
+		// Defaults to gomatrixserverlib.RoomVersionDefault.
+		DefaultRoomVersion gomatrixserverlib.RoomVersion `yaml:"default_room_version"`
 	} `yaml:"matrix"`
 
 	// The configuration specific to the media repostitory.
@@ -203,6 +209,7 @@ type Dendrite struct {
 		FedSendJoinTopic           string `yaml:"fed_send_join_topic"`
 		FedMakeLeaveTopic          string `yaml:"fed_make_leave_topic"`
 		FedSendLeaveTopic          string `yaml:"fed_send_leave_topic"`
+		FedTimestampToEventTopic   string `yaml:"fed_timestamp_to_event_topic"`
 		ProxyClientApiTopic        string `yaml:"proxy_client_api_topic"`
 		ProxyEncryptoApiTopic      string `yaml:"proxy_encrypto_api_topic"`
 		ProxyPublicRoomApiTopic    string `yaml:"proxy_publicroom_api_topic"`
@@ -308,11 +315,135 @@ type Dendrite struct {
 	} `yaml:"application_services"`
 
 	Authorization struct {
-		// Configuration for login authorize mode
+		// Configuration for login authorize mode, a comma separated list of
+		// "provider", "password", "jwt", "ldap" or "sso"
 		AuthorizeMode string `yaml:"login_authorize_mode"`
 		AuthorizeCode string `yaml:"login_authorize_code"`
+		// Number of failed password logins before the account is locked
+		MaxLoginFailures int64 `yaml:"max_login_failures"`
+		// How long a locked account stays locked, in seconds
+		LoginLockDuration int64 `yaml:"login_lock_duration"`
+		// Lifetime of the access tokens of clients which asked for a refresh
+		// token, in seconds. Refresh tokens aren't issued if it's 0. The
+		// access tokens of the other clients never expire
+		AccessTokenLifetime int64 `yaml:"access_token_lifetime"`
+		// Lifetime of refresh tokens, in seconds, 0 means they don't expire
+		RefreshTokenLifetime int64 `yaml:"refresh_token_lifetime"`
+		// Lifetime of the tokens from /user/{userId}/openid/request_token, in seconds
+		OpenIDTokenLifetime int64 `yaml:"openid_token_lifetime"`
+		// Configuration for org.matrix.login.jwt tokens
+		JWT struct {
+			// Signing algorithm, one of HS256/384/512, RS256/384/512, ES256/384/512
+			Algorithm string `yaml:"algorithm"`
+			// Shared secret for the HS algorithms
+			Secret string `yaml:"secret"`
+			// PEM encoded public key for the RS and ES algorithms
+			PublicKeyPath Path `yaml:"public_key_path"`
+			// Expected "iss" claim, not checked if empty
+			Issuer string `yaml:"issuer"`
+			// Accepted "aud" claims, not checked if empty
+			Audiences []string `yaml:"audiences"`
+			// Claim holding the localpart of the user
+			SubjectClaim string `yaml:"subject_claim"`
+			// Allowed clock skew for "exp" and "nbf", in seconds
+			Leeway int64 `yaml:"leeway"`
+			// The public key read from PublicKeyPath
+			PublicKey []byte `yaml:"-"`
+		} `yaml:"jwt"`
+		// Configuration for the ldap authorize mode, used by /login and /adminlogin
+		LDAP struct {
+			// ldap://host:389 or ldaps://host:636, ldap:// connections are
+			// upgraded with StartTLS unless InsecurePlaintext is set
+			URL               string `yaml:"url"`
+			TLSSkipVerify     bool   `yaml:"tls_skip_verify"`
+			InsecurePlaintext bool   `yaml:"insecure_plaintext"`
+			// Service account used to search for users
+			BindDN       string `yaml:"bind_dn"`
+			BindPassword string `yaml:"bind_password"`
+			BaseDN       string `yaml:"base_dn"`
+			// Search filter, %s is replaced by the localpart of the user
+			Filter string `yaml:"filter"`
+			// Connect and request timeout, in seconds
+			Timeout int64 `yaml:"timeout"`
+			// Copy the attributes below into user_info on each login
+			SyncUserInfo bool `yaml:"sync_user_info"`
+			Attributes   struct {
+				DisplayName string `yaml:"displayname"`
+				Email       string `yaml:"email"`
+				Mobile      string `yaml:"mobile"`
+			} `yaml:"attributes"`
+		} `yaml:"ldap"`
+		// Configuration for the sso authorize mode, an OpenID Connect provider
+		// used with the authorization code flow and PKCE
+		OIDC struct {
+			// Issuer URL, the provider is discovered from
+			// <issuer>/.well-known/openid-configuration
+			Issuer       string   `yaml:"issuer"`
+			ClientID     string   `yaml:"client_id"`
+			ClientSecret string   `yaml:"client_secret"`
+			Scopes       []string `yaml:"scopes"`
+			// Public URL of /_matrix/client/r0/login/sso/callback
+			CallbackURL string `yaml:"callback_url"`
+			// Claim holding the localpart of the user, "sub" by default. Other
+			// claims must not be editable by the users of the provider
+			SubjectClaim string `yaml:"subject_claim"`
+			// URLs the client redirectUrl must start with, SSO login is
+			// refused if empty
+			ClientWhitelist []string `yaml:"client_whitelist"`
+			// Lifetime of the m.login.token handed to the client, in seconds
+			LoginTokenLifetime int64 `yaml:"login_token_lifetime"`
+		} `yaml:"oidc"`
 	} `yaml:"authorization"`
 
+	// Email used to validate 3PIDs without an identity server
+	Email struct {
+		// Mail provider, "smtp". Email validation is disabled if empty
+		Provider string `yaml:"provider"`
+		From     string `yaml:"from"`
+		// Public URL of the client API, used in the validation links
+		PublicBaseURL string `yaml:"public_base_url"`
+		// How long a validation token stays valid, in seconds
+		TokenLifetime int64 `yaml:"token_lifetime"`
+		SMTP          struct {
+			Host     string `yaml:"host"`
+			Port     int    `yaml:"port"`
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+			// Connect with TLS, usually on port 465, instead of STARTTLS
+			ImplicitTLS   bool `yaml:"implicit_tls"`
+			RequireTLS    bool `yaml:"require_tls"`
+			TLSSkipVerify bool `yaml:"tls_skip_verify"`
+		} `yaml:"smtp"`
+	} `yaml:"email"`
+
+	UserDirectory struct {
+		// Search every user instead of only the users sharing a room with
+		// the searcher or joined to a public room
+		SearchAllUsers bool `yaml:"search_all_users"`
+	} `yaml:"user_directory"`
+
+	Search struct {
+		// Tokenizer of the message search index, "simple" or "cjk" which
+		// splits chinese, japanese and korean text into characters
+		Tokenizer string `yaml:"tokenizer"`
+		// Index the stored events again when the background manager starts,
+		// for the events written before /search or a tokenizer change
+		Reindex bool `yaml:"reindex"`
+	} `yaml:"search"`
+
+	Retention struct {
+		// Enforce the m.room.retention state of the rooms. Lifetimes are in
+		// ms, 0 keeps the events forever
+		Enable             bool  `yaml:"enable"`
+		DefaultMinLifetime int64 `yaml:"default_min_lifetime"`
+		DefaultMaxLifetime int64 `yaml:"default_max_lifetime"`
+		// Bounds of the max_lifetime a room may set
+		AllowedLifetimeMin int64 `yaml:"allowed_lifetime_min"`
+		AllowedLifetimeMax int64 `yaml:"allowed_lifetime_max"`
+		// How often the expired events are purged, in ms
+		PurgeInterval int64 `yaml:"purge_interval"`
+	} `yaml:"retention"`
+
 	PushService struct {
 		// Configuration for push service
 		RemoveFailTimes      int    `yaml:"remove_fail_times"`
@@ -676,6 +807,13 @@ func loadConfig(
 		return err
 	}
 
+	if config.Authorization.JWT.PublicKeyPath != "" {
+		keyPath := absPath(basePath, config.Authorization.JWT.PublicKeyPath)
+		if config.Authorization.JWT.PublicKey, err = readFile(keyPath); err != nil {
+			return err
+		}
+	}
+
 	for _, val := range config.EventSkip.Items {
 		gomatrixserverlib.AddSkipItem(val.Patten, val.IsReg)
 	}
@@ -756,6 +894,13 @@ func (config *Dendrite) derive() error {
 			external.AuthFlow{Stages: []string{authtypes.LoginTypeDummy}})
 	}
 
+	if config.Matrix.RegistrationRequiresToken {
+		for i := range config.Derived.Registration.Flows {
+			flow := &config.Derived.Registration.Flows[i]
+			flow.Stages = append([]string{authtypes.LoginTypeRegistrationToken}, flow.Stages...)
+		}
+	}
+
 	// Load application service configuration files
 	if err := loadAppservices(config); err != nil {
 		return err
@@ -770,6 +915,10 @@ func (config *Dendrite) setDefaults() {
 		config.Matrix.KeyValidityPeriod = 24 * time.Hour
 	}
 
+	if config.Matrix.DefaultRoomVersion == "" {
+		config.Matrix.DefaultRoomVersion = gomatrixserverlib.RoomVersionDefault
+	}
+
 	if config.Matrix.TrustedIDServers == nil {
 		config.Matrix.TrustedIDServers = []string{}
 	}
@@ -781,6 +930,77 @@ func (config *Dendrite) setDefaults() {
 	if config.DeviceMng.KickUnActive == 0 {
 		config.DeviceMng.KickUnActive = 2592000000 //30 day
 	}
+
+	if config.Retention.PurgeInterval == 0 {
+		config.Retention.PurgeInterval = 3600000 //1 hour
+	}
+
+	if config.Authorization.MaxLoginFailures == 0 {
+		config.Authorization.MaxLoginFailures = 5
+	}
+
+	if config.Authorization.LoginLockDuration == 0 {
+		config.Authorization.LoginLockDuration = 900 //15 min
+	}
+
+	if config.Authorization.OpenIDTokenLifetime == 0 {
+		config.Authorization.OpenIDTokenLifetime = 3600
+	}
+
+	if config.Authorization.JWT.SubjectClaim == "" {
+		config.Authorization.JWT.SubjectClaim = "sub"
+	}
+
+	if config.Email.TokenLifetime == 0 {
+		config.Email.TokenLifetime = 86400 //1 day
+	}
+
+	if config.Email.SMTP.Port == 0 {
+		config.Email.SMTP.Port = 25
+	}
+
+	if len(config.Authorization.OIDC.Scopes) == 0 {
+		config.Authorization.OIDC.Scopes = []string{"openid", "profile"}
+	}
+
+	if config.Authorization.OIDC.SubjectClaim == "" {
+		config.Authorization.OIDC.SubjectClaim = "sub"
+	}
+
+	if config.Authorization.OIDC.LoginTokenLifetime == 0 {
+		config.Authorization.OIDC.LoginTokenLifetime = 120
+	}
+
+	if config.Authorization.LDAP.Filter == "" {
+		config.Authorization.LDAP.Filter = "(uid=%s)"
+	}
+
+	if config.Authorization.LDAP.Timeout == 0 {
+		config.Authorization.LDAP.Timeout = 10
+	}
+
+	if config.Authorization.LDAP.Attributes.DisplayName == "" {
+		config.Authorization.LDAP.Attributes.DisplayName = "displayName"
+	}
+
+	if config.Authorization.LDAP.Attributes.Email == "" {
+		config.Authorization.LDAP.Attributes.Email = "mail"
+	}
+
+	if config.Authorization.LDAP.Attributes.Mobile == "" {
+		config.Authorization.LDAP.Attributes.Mobile = "mobile"
+	}
+}
+
+// LoginModeEnabled reports whether the given mode is one of the configured
+// login authorize modes.
+func (config *Dendrite) LoginModeEnabled(mode string) bool {
+	for _, v := range strings.Split(config.Authorization.AuthorizeMode, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), mode) {
+			return true
+		}
+	}
+	return false
 }
 
 // Error returns a string detailing how many errors were contained within an
@@ -830,9 +1050,22 @@ func (config *Dendrite) check(monolithic bool) error {
 	}
 
 	checkNotZero("matrix.server_name", int64(len(config.Matrix.ServerName)))
+
+	if config.LoginModeEnabled("sso") {
+		if claim := config.Authorization.OIDC.SubjectClaim; claim != "sub" {
+			log.Warnf("!!! authorization.oidc.subject_claim is %q instead of \"sub\": if users can change this claim at the provider, they can log in as any matrix user !!!", claim)
+		}
+		if len(config.Authorization.OIDC.ClientWhitelist) == 0 {
+			log.Warnf("authorization.oidc.client_whitelist is empty, every SSO login will be refused")
+		}
+	}
 	//checkNotEmpty("matrix.private_key", string(config.Matrix.PrivateKeyPath))
 	//checkNotZero("matrix.federation_certificates", int64(len(config.Matrix.FederationCertificatePaths)))
 
+	if _, ok := gomatrixserverlib.SupportedRoomVersions()[config.Matrix.DefaultRoomVersion]; !ok {
+		problems = append(problems, fmt.Sprintf("unsupported room version %q for config key %q", config.Matrix.DefaultRoomVersion, "matrix.default_room_version"))
+	}
+
 	if config.TURN.UserLifetime != "" {
 		checkValidDuration("turn.turn_user_lifetime", config.TURN.UserLifetime)
 	}
@@ -17,11 +17,19 @@
 
 package common
 
+import "github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+
 // CreateContent is the event content for http://matrix.org/docs/spec/client_server/r0.2.0.html#m-room-create
 type CreateContent struct {
 	Creator  string `json:"creator"`
 	Federate *bool  `json:"m.federate,omitempty"`
 	IsDirect *bool  `json:"is_direct,omitempty"`
+	// Absent for the rooms created before room versions, which are version 1
+	RoomVersion gomatrixserverlib.RoomVersion `json:"room_version,omitempty"`
+	// Set when the room replaces an upgraded room
+	Predecessor *PreviousRoom `json:"predecessor,omitempty"`
+	// m.space for spaces
+	Type string `json:"type,omitempty"`
 
 	//used by secrect group
 	EnableWatermark *bool `json:"enable_watermark,omitempty"`
@@ -36,6 +44,19 @@ type CreateContent struct {
 
 //type CreateContent map[string]interface{}
 
+// PreviousRoom is the predecessor field of m.room.create, it points to the
+// tombstone of the upgraded room.
+type PreviousRoom struct {
+	RoomID  string `json:"room_id"`
+	EventID string `json:"event_id"`
+}
+
+// TombstoneContent is the event content for https://matrix.org/docs/spec/client_server/r0.6.0#m-room-tombstone
+type TombstoneContent struct {
+	Body            string `json:"body"`
+	ReplacementRoom string `json:"replacement_room"`
+}
+
 // ThirdPartyInviteContent is the content event for https://matrix.org/docs/spec/client_server/r0.2.0.html#m-room-third-party-invite
 type ThirdPartyInviteContent struct {
 	DisplayName    string      `json:"display_name"`
@@ -72,6 +93,21 @@ type GuestAccessContent struct {
 // JoinRulesContent is the event content for http://matrix.org/docs/spec/client_server/r0.2.0.html#m-room-join-rules
 type JoinRulesContent struct {
 	JoinRule string `json:"join_rule"`
+	// Rooms whose members may join a restricted room
+	Allow []JoinRuleAllow `json:"allow,omitempty"`
+}
+
+// JoinRuleAllow is an entry of the allow list of https://spec.matrix.org/v1.2/client-server-api/#mroomjoin_rules
+type JoinRuleAllow struct {
+	Type   string `json:"type"`
+	RoomID string `json:"room_id,omitempty"`
+}
+
+// SpaceChildContent is the event content for https://spec.matrix.org/v1.2/client-server-api/#mspacechild
+type SpaceChildContent struct {
+	Via       []string `json:"via,omitempty"`
+	Order     string   `json:"order,omitempty"`
+	Suggested bool     `json:"suggested,omitempty"`
 }
 
 // HistoryVisibilityContent is the event content for http://matrix.org/docs/spec/client_server/r0.2.0.html#m-room-history-visibility
@@ -94,6 +130,7 @@ type PowerLevelContent struct {
 	Events        map[string]int `json:"events"`
 	Kick          int            `json:"kick"`
 	Users         map[string]int `json:"users"`
+	Notifications map[string]int `json:"notifications,omitempty"`
 }
 
 // InitialPowerLevelsContent returns the initial values for m.room.power_levels on room creation
@@ -115,6 +152,7 @@ func InitialPowerLevelsContent(roomCreator string) PowerLevelContent {
 			"m.room.canonical_alias":    50,
 			"m.room.avatar":             50,
 			"m.room.archive":            0,
+			"m.room.tombstone":          100,
 		},
 		Kick:  50,
 		Users: map[string]int{roomCreator: 100},
@@ -21,7 +21,6 @@ import (
 	"errors"
 	"time"
 
-	"github.com/finogeeks/ligase/common/config"
 	"github.com/finogeeks/ligase/common/uid"
 
 	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
@@ -38,13 +37,17 @@ var ErrRoomNoExists = errors.New("Room does not exist")
 // Returns ErrRoomNoExists if the state of the room could not be retrieved because
 // the room doesn't exist
 // Returns an error if something else went wrong
+// The room version decides the format of the event ID.
 func BuildEvent(
-	builder *gomatrixserverlib.EventBuilder, domain string, cfg config.Dendrite,
+	builder *gomatrixserverlib.EventBuilder, domain string, roomVersion gomatrixserverlib.RoomVersion,
 	idg *uid.UidGenerator,
 ) (*gomatrixserverlib.Event, error) {
+	if roomVersion == "" {
+		roomVersion = gomatrixserverlib.RoomVersionDefault
+	}
 	now := time.Now()
 	nid, _ := idg.Next()
-	event, err := builder.Build(nid, now, gomatrixserverlib.ServerName(domain))
+	event, err := builder.BuildWithRoomVersion(nid, now, gomatrixserverlib.ServerName(domain), roomVersion)
 	if err != nil {
 		return nil, err
 	}
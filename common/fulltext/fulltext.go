@@ -0,0 +1,123 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package fulltext splits message text into the lexemes of the postgres full
+// text index. Chinese, japanese and korean text has no spaces between words,
+// the cjk tokenizer indexes such runs as single characters and overlapping
+// pairs of characters so that any part of a sentence can be searched.
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	TokenizerSimple = "simple"
+	TokenizerCJK    = "cjk"
+)
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Words returns the lowercased words of text, a run of cjk characters is
+// one word.
+func Words(text string) []string {
+	words := []string{}
+	var word []rune
+	cjk := false
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if isCJK(r) != cjk {
+			flush()
+			cjk = !cjk
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+func isCJKWord(word string) bool {
+	for _, r := range word {
+		return isCJK(r)
+	}
+	return false
+}
+
+// bigrams returns the overlapping pairs of characters of word, the word
+// itself if it has a single character.
+func bigrams(word string) []string {
+	runes := []rune(word)
+	if len(runes) < 2 {
+		return []string{word}
+	}
+	pairs := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		pairs = append(pairs, string(runes[i:i+2]))
+	}
+	return pairs
+}
+
+// Lexemes returns the distinct lexemes to index text under
+func Lexemes(text, tokenizer string) []string {
+	seen := map[string]bool{}
+	lexemes := []string{}
+	add := func(lexeme string) {
+		if !seen[lexeme] {
+			seen[lexeme] = true
+			lexemes = append(lexemes, lexeme)
+		}
+	}
+	for _, word := range Words(text) {
+		if tokenizer != TokenizerCJK || !isCJKWord(word) {
+			add(word)
+			continue
+		}
+		for _, r := range word {
+			add(string(r))
+		}
+		for _, pair := range bigrams(word) {
+			add(pair)
+		}
+	}
+	return lexemes
+}
+
+// Query returns the tsquery matching the text containing every word of term,
+// it is empty if term has no word.
+func Query(term, tokenizer string) string {
+	lexemes := []string{}
+	for _, word := range Words(term) {
+		if tokenizer == TokenizerCJK && isCJKWord(word) {
+			lexemes = append(lexemes, bigrams(word)...)
+		} else {
+			lexemes = append(lexemes, word)
+		}
+	}
+	for i, lexeme := range lexemes {
+		lexemes[i] = "'" + strings.Replace(lexeme, "'", "''", -1) + "'"
+	}
+	return strings.Join(lexemes, " & ")
+}
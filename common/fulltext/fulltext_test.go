@@ -0,0 +1,54 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package fulltext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexemes(t *testing.T) {
+	cases := []struct {
+		text, tokenizer string
+		want            []string
+	}{
+		{"Hello, World! hello", TokenizerSimple, []string{"hello", "world"}},
+		{"明天开会", TokenizerSimple, []string{"明天开会"}},
+		{"明天开会", TokenizerCJK, []string{"明", "天", "开", "会", "明天", "天开", "开会"}},
+		{"Q3季度", TokenizerCJK, []string{"q3", "季", "度", "季度"}},
+	}
+	for _, c := range cases {
+		if got := Lexemes(c.text, c.tokenizer); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Lexemes(%q, %s) = %q, want %q", c.text, c.tokenizer, got, c.want)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	cases := []struct {
+		term, tokenizer, want string
+	}{
+		{"开会 Plan", TokenizerCJK, "'开会' & 'plan'"},
+		{"明天开会", TokenizerCJK, "'明天' & '天开' & '开会'"},
+		{"会", TokenizerCJK, "'会'"},
+		{"明天开会", TokenizerSimple, "'明天开会'"},
+		{" ?! ", TokenizerCJK, ""},
+	}
+	for _, c := range cases {
+		if got := Query(c.term, c.tokenizer); got != c.want {
+			t.Errorf("Query(%q, %s) = %q, want %q", c.term, c.tokenizer, got, c.want)
+		}
+	}
+}
@@ -93,6 +93,34 @@ func MakeAuthAPI(
 	return MakeExternalAPI(metricsName, h)
 }
 
+// MakeOptionalAuthAPI behaves like MakeAuthAPI when the request carries an
+// access token, and passes a nil device to f when it doesn't.
+func MakeOptionalAuthAPI(
+	metricsName string, cache service.Cache, cfg config.Dendrite, devFilter *filter.SimpleFilter,
+	histogram mon.LabeledHistogram,
+	f func(*http.Request, *authtypes.Device) util.JSONResponse,
+) http.Handler {
+	authHandler := MakeAuthAPI(metricsName, cache, cfg, devFilter, histogram, f)
+	externalHandler := MakeExternalAPI(metricsName, func(req *http.Request) util.JSONResponse {
+		start := time.Now()
+		res := f(req, nil)
+
+		duration := float64(time.Since(start)) / float64(time.Millisecond)
+		code := strconv.Itoa(res.Code)
+		if req.Method != "OPTION" {
+			histogram.WithLabelValues(req.Method, metricsName, code).Observe(duration)
+		}
+		return res
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") == "" && req.URL.Query().Get("access_token") == "" {
+			externalHandler.ServeHTTP(w, req)
+			return
+		}
+		authHandler.ServeHTTP(w, req)
+	})
+}
+
 // MakeExternalAPI turns a util.JSONRequestHandler function into an http.Handler.
 // This is used for APIs that are called from the internet.
 func MakeExternalAPI(metricsName string, f func(*http.Request) util.JSONResponse) http.Handler {
@@ -75,6 +75,12 @@ func NotFound(msg string) *MatrixError {
 	return &MatrixError{ErrCode: "M_NOT_FOUND", Err: msg}
 }
 
+// Unauthorized is an error when the client supplies credentials which can't
+// be used, e.g. an invalid registration token.
+func Unauthorized(msg string) *MatrixError {
+	return &MatrixError{ErrCode: "M_UNAUTHORIZED", Err: msg}
+}
+
 // MissingArgument is an error when the client tries to access a resource
 // without providing an argument that is required.
 func MissingArgument(msg string) *MatrixError {
@@ -103,16 +109,79 @@ func UnknownToken(msg string) *MatrixError {
 	return &MatrixError{ErrCode: "M_UNKNOWN_TOKEN", Err: msg}
 }
 
+// SoftLogoutError is an M_UNKNOWN_TOKEN error telling whether the client
+// can log in again without losing its device, e.g. with a refresh token.
+type SoftLogoutError struct {
+	MatrixError
+	SoftLogout bool `json:"soft_logout"`
+}
+
+// UnknownTokenSoftLogout is an error when the access token has expired
+func UnknownTokenSoftLogout(msg string) *SoftLogoutError {
+	return &SoftLogoutError{
+		MatrixError: MatrixError{ErrCode: "M_UNKNOWN_TOKEN", Err: msg},
+		SoftLogout:  true,
+	}
+}
+
 func PwdChangeKick(msg string) *MatrixError {
 	return &MatrixError{ErrCode: "M_PWD_CHANGE_KICK", Err: msg}
 }
 
+// UserLocked is an error returned when the client tries to login to an
+// account which has been locked, e.g. after too many failed logins
+func UserLocked(msg string) *MatrixError {
+	return &MatrixError{ErrCode: "M_USER_LOCKED", Err: msg}
+}
+
+// UserDeactivated is an error returned when the client tries to login to an
+// account which has been deactivated
+func UserDeactivated(msg string) *MatrixError {
+	return &MatrixError{ErrCode: "M_USER_DEACTIVATED", Err: msg}
+}
+
 // WeakPassword is an error which is returned when the client tries to register
 // using a weak password. http://matrix.org/docs/spec/client_server/r0.2.0.html#password-based
 func WeakPassword(msg string) *MatrixError {
 	return &MatrixError{ErrCode: "M_WEAK_PASSWORD", Err: msg}
 }
 
+// UnsupportedRoomVersion is an error returned when the client asks for a room
+// version the server doesn't implement
+func UnsupportedRoomVersion(msg string) *MatrixError {
+	return &MatrixError{ErrCode: "M_UNSUPPORTED_ROOM_VERSION", Err: msg}
+}
+
+// ThreePIDNotFound is an error returned when the client supplies a third-party
+// identifier which isn't associated with any user
+func ThreePIDNotFound(msg string) *MatrixError {
+	return &MatrixError{ErrCode: "M_THREEPID_NOT_FOUND", Err: msg}
+}
+
+// ThreePIDAuthFailed is an error returned when the validation of a third-party
+// identifier could not be confirmed by the identity server
+func ThreePIDAuthFailed(msg string) *MatrixError {
+	return &MatrixError{ErrCode: "M_THREEPID_AUTH_FAILED", Err: msg}
+}
+
+// ThreePIDInUse is an error returned when the third-party identifier is
+// already bound to another user
+func ThreePIDInUse(msg string) *MatrixError {
+	return &MatrixError{ErrCode: "M_THREEPID_IN_USE", Err: msg}
+}
+
+// ThreePIDMediumNotSupported is an error returned when the server can't
+// validate third-party identifiers of the given medium
+func ThreePIDMediumNotSupported(msg string) *MatrixError {
+	return &MatrixError{ErrCode: "M_THREEPID_MEDIUM_NOT_SUPPORTED", Err: msg}
+}
+
+// SessionNotValidated is an error returned when the token of a 3PID
+// validation session hasn't been submitted yet
+func SessionNotValidated(msg string) *MatrixError {
+	return &MatrixError{ErrCode: "M_SESSION_NOT_VALIDATED", Err: msg}
+}
+
 // InvalidUsername is an error returned when the client tries to register an
 // invalid username
 func InvalidUsername(msg string) *MatrixError {
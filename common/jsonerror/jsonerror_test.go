@@ -45,3 +45,15 @@ func TestForbidden(t *testing.T) {
 		t.Errorf("TestForbidden: want %s, got %s", want, string(jsonBytes))
 	}
 }
+
+func TestUnknownTokenSoftLogout(t *testing.T) {
+	e := UnknownTokenSoftLogout("expired")
+	jsonBytes, err := json.Marshal(&e)
+	if err != nil {
+		t.Fatalf("TestUnknownTokenSoftLogout: Failed to marshal SoftLogout error. %s", err.Error())
+	}
+	want := `{"errcode":"M_UNKNOWN_TOKEN","error":"expired","soft_logout":true}`
+	if string(jsonBytes) != want {
+		t.Errorf("TestUnknownTokenSoftLogout: want %s, got %s", want, string(jsonBytes))
+	}
+}
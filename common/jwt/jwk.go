@@ -0,0 +1,139 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// ErrUnknownKey is returned when no key of the set matches the kid and alg of
+// a token, the set may need to be fetched again after a key rotation.
+var ErrUnknownKey = errors.New("jwt: no matching key")
+
+// KeySet is a JSON Web Key Set (RFC 7517) of RSA and EC public keys, as
+// published at the jwks_uri of an OpenID Connect provider.
+type KeySet struct {
+	keys []keySetEntry
+}
+
+type keySetEntry struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// ParseKeySet decodes a JWK Set. Encryption keys and key types other than RSA
+// and EC are skipped.
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Alg string `json:"alg"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	ks := &KeySet{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var key crypto.PublicKey
+		switch k.Kty {
+		case "RSA":
+			n, err1 := decodeBigInt(k.N)
+			e, err2 := decodeBigInt(k.E)
+			if err1 != nil || err2 != nil || !e.IsInt64() {
+				return nil, errors.New("jwt: malformed RSA key " + k.Kid)
+			}
+			key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, err1 := decodeBigInt(k.X)
+			y, err2 := decodeBigInt(k.Y)
+			if err1 != nil || err2 != nil || !curve.IsOnCurve(x, y) {
+				return nil, errors.New("jwt: malformed EC key " + k.Kid)
+			}
+			key = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		default:
+			continue
+		}
+		ks.keys = append(ks.keys, keySetEntry{kid: k.Kid, alg: k.Alg, key: key})
+	}
+	if len(ks.keys) == 0 {
+		return nil, errors.New("jwt: no signing keys in key set")
+	}
+	return ks, nil
+}
+
+// find returns the key for a token header. A kid must match when the token
+// has one, and the key must fit the algorithm.
+func (ks *KeySet) find(kid, alg string) (crypto.PublicKey, error) {
+	for _, k := range ks.keys {
+		if kid != "" && k.kid != kid {
+			continue
+		}
+		if k.alg != "" && k.alg != alg {
+			continue
+		}
+		switch k.key.(type) {
+		case *rsa.PublicKey:
+			if alg[:2] == "RS" {
+				return k.key, nil
+			}
+		case *ecdsa.PublicKey:
+			if alg[:2] == "ES" {
+				return k.key, nil
+			}
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("jwt: empty key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
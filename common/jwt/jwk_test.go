@@ -0,0 +1,89 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func rs256Token(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	input := encodeSegment(t, map[string]string{"alg": "RS256", "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) string {
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	return `{"kty":"RSA","kid":"` + kid + `","use":"sig","alg":"RS256","n":"` + n + `","e":"` + e + `"}`
+}
+
+func TestKeySetValidator(t *testing.T) {
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ParseKeySet([]byte(`{"keys":[` +
+		`{"kty":"oct","kid":"sym","k":"c2VjcmV0"},` +
+		`{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"},` +
+		rsaJWK("k1", &key1.PublicKey) + `,` + rsaJWK("k2", &key2.PublicKey) + `]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys.keys) != 2 {
+		t.Fatalf("got %d keys, want the 2 RSA signing keys", len(keys.keys))
+	}
+
+	now := time.Unix(1600000000, 0)
+	v := NewKeySetValidator(keys, "https://idp.example.com", []string{"ligase"}, 0)
+	v.RequireExpiry()
+	claims := map[string]interface{}{"sub": "alice", "iss": "https://idp.example.com", "aud": "ligase", "exp": 1600000100}
+
+	if got, err := v.Validate(rs256Token(t, key2, "k2", claims), now); err != nil || got.String("sub") != "alice" {
+		t.Errorf("valid token: got %v, %v", got, err)
+	}
+	if _, err = v.Validate(rs256Token(t, key1, "k2", claims), now); err != ErrSignature {
+		t.Errorf("wrong key: got error %v, want %v", err, ErrSignature)
+	}
+	if _, err = v.Validate(rs256Token(t, key1, "k3", claims), now); err != ErrUnknownKey {
+		t.Errorf("unknown kid: got error %v, want %v", err, ErrUnknownKey)
+	}
+	if _, err = v.Validate(hs256Token(t, []byte("secret"), claims), now); err != ErrAlgorithm {
+		t.Errorf("HS256 token: got error %v, want %v", err, ErrAlgorithm)
+	}
+	noExp := map[string]interface{}{"sub": "alice", "iss": "https://idp.example.com", "aud": "ligase"}
+	if _, err = v.Validate(rs256Token(t, key1, "k1", noExp), now); err != ErrMissingExpiry {
+		t.Errorf("no exp: got error %v, want %v", err, ErrMissingExpiry)
+	}
+
+	if _, err = ParseKeySet([]byte(`{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`)); err == nil {
+		t.Error("a key set without signing keys is accepted")
+	}
+}
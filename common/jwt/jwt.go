@@ -0,0 +1,351 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package jwt verifies compact JSON Web Tokens (RFC 7519) signed with HMAC,
+// RSA or ECDSA keys. It only covers what token login needs.
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed        = errors.New("jwt: malformed token")
+	ErrAlgorithm        = errors.New("jwt: unexpected signing algorithm")
+	ErrSignature        = errors.New("jwt: invalid signature")
+	ErrExpired          = errors.New("jwt: token is expired")
+	ErrMissingExpiry    = errors.New("jwt: token has no expiry")
+	ErrNotValidYet      = errors.New("jwt: token is not valid yet")
+	ErrInvalidIssuer    = errors.New("jwt: invalid issuer")
+	ErrInvalidAudience  = errors.New("jwt: invalid audience")
+	ErrUnsupportedKey   = errors.New("jwt: unsupported key type")
+	ErrMissingAlgorithm = errors.New("jwt: algorithm must be set")
+)
+
+// Claims holds the decoded payload of a token.
+type Claims map[string]interface{}
+
+// String returns the claim as a string, or "" if it is missing or not a string.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Validator checks the signature and the registered claims of tokens.
+type Validator struct {
+	alg       string
+	hash      crypto.Hash
+	secret    []byte
+	publicKey crypto.PublicKey
+	// keySet replaces alg and the key when the key is picked per token
+	keySet    *KeySet
+	issuer    string
+	audiences []string
+	leeway    time.Duration
+	// requireExp refuses tokens without an "exp" claim
+	requireExp bool
+}
+
+// NewValidator builds a validator for alg, e.g. "HS256", "RS256" or "ES256".
+// HMAC algorithms use secret, the others use the PEM encoded public key.
+// An empty issuer or audiences list disables the matching check.
+func NewValidator(
+	alg string, secret, publicKeyPEM []byte,
+	issuer string, audiences []string, leeway time.Duration,
+) (*Validator, error) {
+	if alg == "" {
+		return nil, ErrMissingAlgorithm
+	}
+	hash, err := hashForAlg(alg)
+	if err != nil {
+		return nil, err
+	}
+	v := &Validator{
+		alg:       alg,
+		hash:      hash,
+		issuer:    issuer,
+		audiences: audiences,
+		leeway:    leeway,
+	}
+
+	switch alg[:2] {
+	case "HS":
+		if len(secret) == 0 {
+			return nil, errors.New("jwt: secret must be set for " + alg)
+		}
+		v.secret = secret
+	case "RS", "ES":
+		key, err := parsePublicKey(publicKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if alg[:2] != "RS" {
+				return nil, ErrUnsupportedKey
+			}
+		case *ecdsa.PublicKey:
+			if alg[:2] != "ES" {
+				return nil, ErrUnsupportedKey
+			}
+		default:
+			return nil, ErrUnsupportedKey
+		}
+		v.publicKey = key
+	default:
+		return nil, ErrAlgorithm
+	}
+	return v, nil
+}
+
+// NewKeySetValidator builds a validator which verifies RS and ES signed
+// tokens with the key of the set named by their kid header.
+func NewKeySetValidator(keys *KeySet, issuer string, audiences []string, leeway time.Duration) *Validator {
+	return &Validator{keySet: keys, issuer: issuer, audiences: audiences, leeway: leeway}
+}
+
+func hashForAlg(alg string) (crypto.Hash, error) {
+	if len(alg) != 5 {
+		return 0, ErrAlgorithm
+	}
+	switch alg[2:] {
+	case "256":
+		return crypto.SHA256, nil
+	case "384":
+		return crypto.SHA384, nil
+	case "512":
+		return crypto.SHA512, nil
+	}
+	return 0, ErrAlgorithm
+}
+
+// RequireExpiry makes tokens without an "exp" claim invalid, tokens used to
+// log in must not be valid forever.
+func (v *Validator) RequireExpiry() {
+	v.requireExp = true
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: no PEM data in public key")
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: parse public key: %v", err)
+	}
+	return cert.PublicKey, nil
+}
+
+// Validate verifies the token at the given time and returns its claims.
+func (v *Validator) Validate(token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signer := v
+	if v.keySet != nil {
+		var err error
+		if signer, err = v.keySetSigner(header.Alg, header.Kid); err != nil {
+			return nil, err
+		}
+	} else if header.Alg != v.alg {
+		return nil, ErrAlgorithm
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if err = signer.verify(parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	claims := Claims{}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err = v.checkClaims(claims, now); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// keySetSigner returns a validator holding the key of the set for a token
+// header. Only public key algorithms are accepted.
+func (v *Validator) keySetSigner(alg, kid string) (*Validator, error) {
+	hash, err := hashForAlg(alg)
+	if err != nil {
+		return nil, err
+	}
+	if alg[:2] != "RS" && alg[:2] != "ES" {
+		return nil, ErrAlgorithm
+	}
+	key, err := v.keySet.find(kid, alg)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{alg: alg, hash: hash, publicKey: key}, nil
+}
+
+// CheckClaims verifies exp, nbf, iss and aud of the claims at the given time.
+func (v *Validator) CheckClaims(claims Claims, now time.Time) error {
+	return v.checkClaims(claims, now)
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrMalformed
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	if err = dec.Decode(v); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
+
+func (v *Validator) verify(signingInput string, sig []byte) error {
+	if v.alg == "" {
+		return ErrAlgorithm
+	}
+	if v.secret != nil {
+		mac := hmac.New(v.hash.New, v.secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrSignature
+		}
+		return nil
+	}
+
+	h := v.hash.New()
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+	switch key := v.publicKey.(type) {
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(key, v.hash, digest, sig) != nil {
+			return ErrSignature
+		}
+	case *ecdsa.PublicKey:
+		// ES signatures are the fixed size concatenation of r and s
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return ErrSignature
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return ErrSignature
+		}
+	default:
+		return ErrUnsupportedKey
+	}
+	return nil
+}
+
+func (v *Validator) checkClaims(claims Claims, now time.Time) error {
+	if _, ok := claims["exp"]; ok {
+		exp, ok := numericDate(claims["exp"])
+		if !ok {
+			return ErrMalformed
+		}
+		if !now.Before(exp.Add(v.leeway)) {
+			return ErrExpired
+		}
+	} else if v.requireExp {
+		return ErrMissingExpiry
+	}
+	if _, ok := claims["nbf"]; ok {
+		nbf, ok := numericDate(claims["nbf"])
+		if !ok {
+			return ErrMalformed
+		}
+		if now.Add(v.leeway).Before(nbf) {
+			return ErrNotValidYet
+		}
+	}
+	if v.issuer != "" && claims.String("iss") != v.issuer {
+		return ErrInvalidIssuer
+	}
+	if len(v.audiences) > 0 && !v.hasAudience(claims["aud"]) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+// hasAudience reports whether aud, a string or a list of strings, contains
+// one of the accepted audiences.
+func (v *Validator) hasAudience(aud interface{}) bool {
+	var auds []string
+	switch a := aud.(type) {
+	case string:
+		auds = []string{a}
+	case []interface{}:
+		for _, item := range a {
+			if s, ok := item.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+	}
+	for _, want := range v.audiences {
+		for _, got := range auds {
+			if got == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func numericDate(v interface{}) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hs256Token(t *testing.T, secret []byte, claims map[string]interface{}) string {
+	input := encodeSegment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidateHMAC(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1600000000, 0)
+	v, err := NewValidator("HS256", secret, nil, "issuer", []string{"ligase"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		claims map[string]interface{}
+		want   error
+	}{
+		{"valid", map[string]interface{}{"sub": "alice", "iss": "issuer", "aud": "ligase", "exp": 1600000100}, nil},
+		{"audience list", map[string]interface{}{"sub": "alice", "iss": "issuer", "aud": []string{"other", "ligase"}}, nil},
+		{"expired", map[string]interface{}{"sub": "alice", "iss": "issuer", "aud": "ligase", "exp": 1600000000}, ErrExpired},
+		{"not before", map[string]interface{}{"sub": "alice", "iss": "issuer", "aud": "ligase", "nbf": 1600000100}, ErrNotValidYet},
+		{"issuer", map[string]interface{}{"sub": "alice", "iss": "other", "aud": "ligase"}, ErrInvalidIssuer},
+		{"audience", map[string]interface{}{"sub": "alice", "iss": "issuer", "aud": "other"}, ErrInvalidAudience},
+	}
+	for _, c := range cases {
+		claims, err := v.Validate(hs256Token(t, secret, c.claims), now)
+		if err != c.want {
+			t.Errorf("%s: got error %v, want %v", c.name, err, c.want)
+		}
+		if err == nil && claims.String("sub") != "alice" {
+			t.Errorf("%s: got sub %q", c.name, claims.String("sub"))
+		}
+	}
+
+	token := hs256Token(t, []byte("wrong"), map[string]interface{}{"sub": "alice", "iss": "issuer", "aud": "ligase"})
+	if _, err := v.Validate(token, now); err != ErrSignature {
+		t.Errorf("wrong secret: got error %v, want %v", err, ErrSignature)
+	}
+}
+
+func TestRequireExpiry(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1600000000, 0)
+	v, err := NewValidator("HS256", secret, nil, "", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.RequireExpiry()
+
+	if _, err = v.Validate(hs256Token(t, secret, map[string]interface{}{"sub": "alice"}), now); err != ErrMissingExpiry {
+		t.Errorf("no exp: got error %v, want %v", err, ErrMissingExpiry)
+	}
+	if _, err = v.Validate(hs256Token(t, secret, map[string]interface{}{"sub": "alice", "exp": 1600000100}), now); err != nil {
+		t.Errorf("valid exp: got error %v", err)
+	}
+	if err = v.CheckClaims(Claims{}, now); err != ErrMissingExpiry {
+		t.Errorf("CheckClaims without exp: got error %v, want %v", err, ErrMissingExpiry)
+	}
+}
+
+func TestValidateECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	v, err := NewValidator("ES256", nil, pemData, "", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := encodeSegment(t, map[string]string{"alg": "ES256"}) + "." + encodeSegment(t, map[string]string{"sub": "bob"})
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	rb, sb := r.Bytes(), s.Bytes()
+	copy(sig[32-len(rb):32], rb)
+	copy(sig[64-len(sb):], sb)
+	token := input + "." + base64.RawURLEncoding.EncodeToString(sig)
+
+	claims, err := v.Validate(token, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.String("sub") != "bob" {
+		t.Errorf("got sub %q", claims.String("sub"))
+	}
+
+	if _, err = NewValidator("RS256", nil, pemData, "", nil, 0); err != ErrUnsupportedKey {
+		t.Errorf("RS256 with EC key: got error %v, want %v", err, ErrUnsupportedKey)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// BER identifier classes
+const (
+	classUniversal   = 0x00
+	classApplication = 0x40
+	classContext     = 0x80
+)
+
+// Universal tags used by LDAP
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x10
+	tagSet         = 0x11
+)
+
+// maxPacketSize bounds the size of a single response we are willing to read.
+const maxPacketSize = 16 << 20
+
+var errBadPacket = errors.New("ldap: malformed BER packet")
+
+// packet is a decoded BER element. Primitive elements carry value, constructed
+// ones carry children.
+type packet struct {
+	class       byte
+	constructed bool
+	tag         int
+	value       []byte
+	children    []*packet
+}
+
+func newConstructed(class byte, tag int, children ...*packet) *packet {
+	return &packet{class: class, constructed: true, tag: tag, children: children}
+}
+
+func newPrimitive(class byte, tag int, value []byte) *packet {
+	return &packet{class: class, tag: tag, value: value}
+}
+
+func newString(class byte, tag int, s string) *packet {
+	return newPrimitive(class, tag, []byte(s))
+}
+
+func newInteger(tag int, n int64) *packet {
+	var b []byte
+	for {
+		b = append([]byte{byte(n)}, b...)
+		n >>= 8
+		if (n == 0 && b[0]&0x80 == 0) || (n == -1 && b[0]&0x80 != 0) {
+			break
+		}
+	}
+	return newPrimitive(classUniversal, tag, b)
+}
+
+func newBoolean(v bool) *packet {
+	if v {
+		return newPrimitive(classUniversal, tagBoolean, []byte{0xff})
+	}
+	return newPrimitive(classUniversal, tagBoolean, []byte{0x00})
+}
+
+func (p *packet) add(children ...*packet) *packet {
+	p.children = append(p.children, children...)
+	return p
+}
+
+func (p *packet) is(class byte, tag int) bool {
+	return p.class == class && p.tag == tag
+}
+
+func (p *packet) int() int64 {
+	var n int64
+	for i, b := range p.value {
+		if i == 0 && b&0x80 != 0 {
+			n = -1
+		}
+		n = n<<8 | int64(b)
+	}
+	return n
+}
+
+func (p *packet) str() string {
+	return string(p.value)
+}
+
+func (p *packet) bytes() []byte {
+	var content []byte
+	if p.constructed {
+		for _, c := range p.children {
+			content = append(content, c.bytes()...)
+		}
+	} else {
+		content = p.value
+	}
+
+	id := p.class | byte(p.tag)
+	if p.constructed {
+		id |= 0x20
+	}
+	out := []byte{id}
+	out = append(out, encodeLength(len(content))...)
+	return append(out, content...)
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// readPacket reads one complete element from r.
+func readPacket(r *bufio.Reader) (*packet, error) {
+	id, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	l, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length := int(l)
+	if l&0x80 != 0 {
+		n := int(l & 0x7f)
+		if n == 0 || n > 4 {
+			return nil, errBadPacket
+		}
+		length = 0
+		for i := 0; i < n; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxPacketSize {
+		return nil, errBadPacket
+	}
+	content := make([]byte, length)
+	if _, err = io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return decodeElement(id, content)
+}
+
+func decodeElement(id byte, content []byte) (*packet, error) {
+	if id&0x1f == 0x1f {
+		// high tag numbers are never used by LDAP
+		return nil, errBadPacket
+	}
+	p := &packet{class: id & 0xc0, constructed: id&0x20 != 0, tag: int(id & 0x1f)}
+	if !p.constructed {
+		p.value = content
+		return p, nil
+	}
+	for len(content) > 0 {
+		child, rest, err := decodeNext(content)
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		content = rest
+	}
+	return p, nil
+}
+
+func decodeNext(data []byte) (*packet, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, errBadPacket
+	}
+	id, l := data[0], data[1]
+	data = data[2:]
+	length := int(l)
+	if l&0x80 != 0 {
+		n := int(l & 0x7f)
+		if n == 0 || n > 4 || len(data) < n {
+			return nil, nil, errBadPacket
+		}
+		length = 0
+		for _, b := range data[:n] {
+			length = length<<8 | int(b)
+		}
+		data = data[n:]
+	}
+	if length > len(data) {
+		return nil, nil, errBadPacket
+	}
+	p, err := decodeElement(id, data[:length])
+	return p, data[length:], err
+}
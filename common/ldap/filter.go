@@ -0,0 +1,203 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Filter choices, RFC 4511 section 4.5.1
+const (
+	filterAnd            = 0
+	filterOr             = 1
+	filterNot            = 2
+	filterEqualityMatch  = 3
+	filterSubstrings     = 4
+	filterGreaterOrEqual = 5
+	filterLessOrEqual    = 6
+	filterPresent        = 7
+	filterApproxMatch    = 8
+)
+
+// EscapeFilter escapes the characters with a special meaning in a filter
+// string, so that user input can be put into a filter template.
+func EscapeFilter(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compileFilter parses a filter string as described in RFC 4515, e.g.
+// "(&(objectClass=person)(uid=alice))". Extensible matches are not supported.
+func compileFilter(filter string) (*packet, error) {
+	if filter == "" {
+		return nil, fmt.Errorf("ldap: empty filter")
+	}
+	p, pos, err := parseFilter(filter, 0)
+	if err != nil {
+		return nil, err
+	}
+	if pos != len(filter) {
+		return nil, fmt.Errorf("ldap: unexpected data at %d in filter %q", pos, filter)
+	}
+	return p, nil
+}
+
+func parseFilter(f string, pos int) (*packet, int, error) {
+	if pos >= len(f) || f[pos] != '(' {
+		return nil, pos, fmt.Errorf("ldap: expected '(' at %d in filter %q", pos, f)
+	}
+	pos++
+	if pos >= len(f) {
+		return nil, pos, fmt.Errorf("ldap: unexpected end of filter %q", f)
+	}
+
+	var p *packet
+	var err error
+	switch f[pos] {
+	case '&', '|':
+		tag := filterAnd
+		if f[pos] == '|' {
+			tag = filterOr
+		}
+		p = newConstructed(classContext, tag)
+		pos++
+		for pos < len(f) && f[pos] == '(' {
+			var child *packet
+			if child, pos, err = parseFilter(f, pos); err != nil {
+				return nil, pos, err
+			}
+			p.add(child)
+		}
+	case '!':
+		var child *packet
+		if child, pos, err = parseFilter(f, pos+1); err != nil {
+			return nil, pos, err
+		}
+		p = newConstructed(classContext, filterNot, child)
+	default:
+		end := strings.IndexByte(f[pos:], ')')
+		if end < 0 {
+			return nil, pos, fmt.Errorf("ldap: unterminated item in filter %q", f)
+		}
+		if p, err = parseItem(f[pos : pos+end]); err != nil {
+			return nil, pos, err
+		}
+		pos += end
+	}
+
+	if pos >= len(f) || f[pos] != ')' {
+		return nil, pos, fmt.Errorf("ldap: expected ')' at %d in filter %q", pos, f)
+	}
+	return p, pos + 1, nil
+}
+
+func parseItem(item string) (*packet, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("ldap: invalid filter item %q", item)
+	}
+	attr, value := item[:eq], item[eq+1:]
+	tag := filterEqualityMatch
+	switch attr[len(attr)-1] {
+	case '>':
+		tag = filterGreaterOrEqual
+	case '<':
+		tag = filterLessOrEqual
+	case '~':
+		tag = filterApproxMatch
+	case ':':
+		return nil, fmt.Errorf("ldap: extensible match is not supported in %q", item)
+	}
+	if tag != filterEqualityMatch {
+		attr = attr[:len(attr)-1]
+	}
+	if attr == "" {
+		return nil, fmt.Errorf("ldap: invalid filter item %q", item)
+	}
+
+	if tag == filterEqualityMatch && value == "*" {
+		return newString(classContext, filterPresent, attr), nil
+	}
+	if tag == filterEqualityMatch && strings.Contains(value, "*") {
+		return parseSubstrings(attr, value)
+	}
+
+	v, err := unescapeValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return newConstructed(classContext, tag,
+		newString(classUniversal, tagOctetString, attr),
+		newString(classUniversal, tagOctetString, v),
+	), nil
+}
+
+func parseSubstrings(attr, value string) (*packet, error) {
+	parts := strings.Split(value, "*")
+	subs := newConstructed(classUniversal, tagSequence)
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		v, err := unescapeValue(part)
+		if err != nil {
+			return nil, err
+		}
+		tag := 1 // any
+		if i == 0 {
+			tag = 0 // initial
+		} else if i == len(parts)-1 {
+			tag = 2 // final
+		}
+		subs.add(newString(classContext, tag, v))
+	}
+	return newConstructed(classContext, filterSubstrings,
+		newString(classUniversal, tagOctetString, attr), subs,
+	), nil
+}
+
+func unescapeValue(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return "", fmt.Errorf("ldap: invalid escape in filter value %q", s)
+		}
+		c, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("ldap: invalid escape in filter value %q", s)
+		}
+		b.Write(c)
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,389 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package ldap is a small LDAPv3 client covering the simple bind and search
+// operations needed to authenticate users against LDAP or Active Directory.
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Protocol operations, RFC 4511 section 4.2
+const (
+	opBindRequest       = 0
+	opBindResponse      = 1
+	opUnbindRequest     = 2
+	opSearchRequest     = 3
+	opSearchResultEntry = 4
+	opSearchResultDone  = 5
+	opSearchResultRef   = 19
+	opExtendedRequest   = 23
+	opExtendedResponse  = 24
+)
+
+// oidStartTLS names the StartTLS extended operation, RFC 4511 section 4.14
+const oidStartTLS = "1.3.6.1.4.1.1466.20037"
+
+// Search scopes
+const (
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+)
+
+// Result codes
+const (
+	ResultSuccess            = 0
+	ResultInvalidCredentials = 49
+)
+
+var (
+	ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+	ErrUserNotFound       = errors.New("ldap: user not found")
+	ErrMultipleUsers      = errors.New("ldap: filter matched more than one user")
+)
+
+// Error is a non successful LDAP result.
+type Error struct {
+	Code    int64
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// Entry is an object returned by a search.
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Get returns the first value of the attribute, matched case insensitively.
+func (e *Entry) Get(name string) string {
+	for k, v := range e.Attributes {
+		if strings.EqualFold(k, name) && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// Conn is a connection to an LDAP server. It is not safe for concurrent use.
+type Conn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	msgID   int64
+	timeout time.Duration
+	host    string
+	isTLS   bool
+}
+
+// Dial connects to an ldap:// or ldaps:// URL.
+func Dial(rawURL string, timeout time.Duration, tlsConfig *tls.Config) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(host, "389")
+		}
+		conn, err = dialer.Dial("tcp", host)
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(host, "636")
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ServerName = u.Hostname()
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, tlsConfig)
+	default:
+		return nil, fmt.Errorf("ldap: unsupported url scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+		timeout: timeout,
+		host:    u.Hostname(),
+		isTLS:   u.Scheme == "ldaps",
+	}, nil
+}
+
+// StartTLS upgrades an ldap:// connection to TLS, it must be called before
+// anything is sent in the clear.
+func (c *Conn) StartTLS(tlsConfig *tls.Config) error {
+	if c.isTLS {
+		return errors.New("ldap: connection already uses TLS")
+	}
+	op := newConstructed(classApplication, opExtendedRequest,
+		newString(classContext, 0, oidStartTLS),
+	)
+	resp, err := c.roundTrip(op)
+	if err != nil {
+		return err
+	}
+	if !resp.is(classApplication, opExtendedResponse) {
+		return errBadPacket
+	}
+	if err = resultError(resp); err != nil {
+		return fmt.Errorf("ldap: start tls: %v", err)
+	}
+
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = c.host
+	}
+	tlsConn := tls.Client(c.conn, tlsConfig)
+	tlsConn.SetDeadline(time.Now().Add(c.timeout))
+	if err = tlsConn.Handshake(); err != nil {
+		return err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	c.conn = tlsConn
+	c.r = bufio.NewReader(tlsConn)
+	c.isTLS = true
+	return nil
+}
+
+// Close sends an unbind request and closes the connection.
+func (c *Conn) Close() error {
+	c.msgID++
+	msg := newConstructed(classUniversal, tagSequence,
+		newInteger(tagInteger, c.msgID),
+		newPrimitive(classApplication, opUnbindRequest, nil),
+	)
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	c.conn.Write(msg.bytes())
+	return c.conn.Close()
+}
+
+// Bind performs a simple bind. An empty password is rejected, servers treat
+// it as an unauthenticated bind which always succeeds.
+func (c *Conn) Bind(dn, password string) error {
+	if password == "" {
+		return ErrInvalidCredentials
+	}
+	op := newConstructed(classApplication, opBindRequest,
+		newInteger(tagInteger, 3),
+		newString(classUniversal, tagOctetString, dn),
+		newString(classContext, 0, password),
+	)
+	resp, err := c.roundTrip(op)
+	if err != nil {
+		return err
+	}
+	if !resp.is(classApplication, opBindResponse) {
+		return errBadPacket
+	}
+	err = resultError(resp)
+	if e, ok := err.(*Error); ok && e.Code == ResultInvalidCredentials {
+		return ErrInvalidCredentials
+	}
+	return err
+}
+
+// Search returns the entries below baseDN matching filter, with the given
+// attributes.
+func (c *Conn) Search(baseDN string, scope int, filter string, sizeLimit int, attributes []string) ([]*Entry, error) {
+	f, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	attrs := newConstructed(classUniversal, tagSequence)
+	for _, a := range attributes {
+		attrs.add(newString(classUniversal, tagOctetString, a))
+	}
+	op := newConstructed(classApplication, opSearchRequest,
+		newString(classUniversal, tagOctetString, baseDN),
+		newInteger(tagEnumerated, int64(scope)),
+		newInteger(tagEnumerated, 0), // neverDerefAliases
+		newInteger(tagInteger, int64(sizeLimit)),
+		newInteger(tagInteger, int64(c.timeout/time.Second)),
+		newBoolean(false),
+		f,
+		attrs,
+	)
+	if err = c.send(op); err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for {
+		resp, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.is(classApplication, opSearchResultEntry):
+			entry, err := parseEntry(resp)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case resp.is(classApplication, opSearchResultRef):
+			// referrals are not followed
+		case resp.is(classApplication, opSearchResultDone):
+			return entries, resultError(resp)
+		default:
+			return nil, errBadPacket
+		}
+	}
+}
+
+func (c *Conn) roundTrip(op *packet) (*packet, error) {
+	if err := c.send(op); err != nil {
+		return nil, err
+	}
+	return c.receive()
+}
+
+func (c *Conn) send(op *packet) error {
+	c.msgID++
+	msg := newConstructed(classUniversal, tagSequence, newInteger(tagInteger, c.msgID), op)
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err := c.conn.Write(msg.bytes())
+	return err
+}
+
+// receive reads the next response to the last request, and returns its
+// protocol operation.
+func (c *Conn) receive() (*packet, error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	msg, err := readPacket(c.r)
+	if err != nil {
+		return nil, err
+	}
+	if !msg.is(classUniversal, tagSequence) || len(msg.children) < 2 {
+		return nil, errBadPacket
+	}
+	if msg.children[0].int() != c.msgID {
+		return nil, fmt.Errorf("ldap: unexpected message id %d", msg.children[0].int())
+	}
+	return msg.children[1], nil
+}
+
+func resultError(resp *packet) error {
+	if len(resp.children) < 3 {
+		return errBadPacket
+	}
+	code := resp.children[0].int()
+	if code == ResultSuccess {
+		return nil
+	}
+	return &Error{Code: code, Message: resp.children[2].str()}
+}
+
+func parseEntry(resp *packet) (*Entry, error) {
+	if len(resp.children) < 2 {
+		return nil, errBadPacket
+	}
+	entry := &Entry{DN: resp.children[0].str(), Attributes: make(map[string][]string)}
+	for _, attr := range resp.children[1].children {
+		if len(attr.children) < 2 {
+			return nil, errBadPacket
+		}
+		name := attr.children[0].str()
+		for _, v := range attr.children[1].children {
+			entry.Attributes[name] = append(entry.Attributes[name], v.str())
+		}
+	}
+	return entry, nil
+}
+
+// Config describes how users are looked up and authenticated.
+type Config struct {
+	URL           string
+	TLSSkipVerify bool
+	// Plaintext keeps ldap:// connections in the clear instead of upgrading
+	// them with StartTLS, passwords are then sent unencrypted
+	Plaintext    bool
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// Filter is a filter template, "%s" is replaced by the escaped username
+	Filter     string
+	Attributes []string
+	Timeout    time.Duration
+}
+
+// Authenticate binds as the service account, looks up the user and re-binds
+// with the user's password. It returns the user's entry on success. ldap://
+// connections are upgraded with StartTLS first unless cfg.Plaintext is set.
+func Authenticate(cfg Config, username, password string) (*Entry, error) {
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	var tlsConfig *tls.Config
+	if cfg.TLSSkipVerify {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	conn, err := Dial(cfg.URL, cfg.Timeout, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if !conn.isTLS && !cfg.Plaintext {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.BindDN != "" {
+		if err = conn.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap: service account bind: %v", err)
+		}
+	}
+
+	filter := strings.Replace(cfg.Filter, "%s", EscapeFilter(username), -1)
+	entries, err := conn.Search(cfg.BaseDN, ScopeWholeSubtree, filter, 2, cfg.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	switch len(entries) {
+	case 0:
+		return nil, ErrUserNotFound
+	case 1:
+	default:
+		return nil, ErrMultipleUsers
+	}
+
+	if err = conn.Bind(entries[0].DN, password); err != nil {
+		return nil, err
+	}
+	return entries[0], nil
+}
@@ -0,0 +1,280 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeServer is a stand-in directory holding DN -> password, which answers
+// equality searches on uid. It refuses StartTLS when tlsConfig is nil.
+type fakeServer struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	passwords map[string]string
+	entries   map[string]*Entry
+	// binds records whether each bind request came over TLS
+	binds chan bool
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{
+		ln:        ln,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{selfSignedCert(t)}},
+		binds:     make(chan bool, 10),
+		passwords: map[string]string{
+			"cn=admin,dc=example,dc=com":           "adminpw",
+			"uid=alice,ou=users,dc=example,dc=com": "alicepw",
+		},
+		entries: map[string]*Entry{
+			"alice": {
+				DN: "uid=alice,ou=users,dc=example,dc=com",
+				Attributes: map[string][]string{
+					"displayName": {"Alice"},
+					"mail":        {"alice@example.com"},
+				},
+			},
+		},
+	}
+	go s.serve()
+	return s
+}
+
+func selfSignedCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (s *fakeServer) url() string {
+	return "ldap://" + s.ln.Addr().String()
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	isTLS := false
+	for {
+		msg, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		id := msg.children[0]
+		op := msg.children[1]
+		reply := func(resp *packet) {
+			conn.Write(newConstructed(classUniversal, tagSequence, id, resp).bytes())
+		}
+		result := func(tag int, code int64) *packet {
+			return newConstructed(classApplication, tag,
+				newInteger(tagEnumerated, code),
+				newString(classUniversal, tagOctetString, ""),
+				newString(classUniversal, tagOctetString, ""),
+			)
+		}
+
+		switch op.tag {
+		case opExtendedRequest:
+			if op.children[0].str() != oidStartTLS || s.tlsConfig == nil {
+				reply(result(opExtendedResponse, 2)) // protocolError
+				continue
+			}
+			reply(result(opExtendedResponse, ResultSuccess))
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			defer tlsConn.Close()
+			conn, r, isTLS = tlsConn, bufio.NewReader(tlsConn), true
+		case opBindRequest:
+			s.binds <- isTLS
+			dn, pw := op.children[1].str(), op.children[2].str()
+			code := int64(ResultSuccess)
+			if want, ok := s.passwords[dn]; !ok || want != pw {
+				code = ResultInvalidCredentials
+			}
+			reply(result(opBindResponse, code))
+		case opSearchRequest:
+			// the filter is (&(objectClass=person)(uid=<name>))
+			f := op.children[6]
+			uid := f.children[1].children[1].str()
+			if e, ok := s.entries[uid]; ok {
+				attrs := newConstructed(classUniversal, tagSequence)
+				for k, vs := range e.Attributes {
+					set := newConstructed(classUniversal, tagSet)
+					for _, v := range vs {
+						set.add(newString(classUniversal, tagOctetString, v))
+					}
+					attrs.add(newConstructed(classUniversal, tagSequence,
+						newString(classUniversal, tagOctetString, k), set))
+				}
+				reply(newConstructed(classApplication, opSearchResultEntry,
+					newString(classUniversal, tagOctetString, e.DN), attrs))
+			}
+			reply(result(opSearchResultDone, ResultSuccess))
+		case opUnbindRequest:
+			return
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.ln.Close()
+
+	cfg := Config{
+		URL:           s.url(),
+		TLSSkipVerify: true,
+		BindDN:        "cn=admin,dc=example,dc=com",
+		BindPassword:  "adminpw",
+		BaseDN:        "ou=users,dc=example,dc=com",
+		Filter:        "(&(objectClass=person)(uid=%s))",
+		Attributes:    []string{"displayName", "mail"},
+		Timeout:       5 * time.Second,
+	}
+
+	entry, err := Authenticate(cfg, "alice", "alicepw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if !<-s.binds {
+			t.Error("the password was sent before StartTLS")
+		}
+	}
+	if entry.Get("displayname") != "Alice" || entry.Get("mail") != "alice@example.com" {
+		t.Errorf("unexpected attributes %v", entry.Attributes)
+	}
+
+	if _, err = Authenticate(cfg, "alice", "wrong"); err != ErrInvalidCredentials {
+		t.Errorf("wrong password: got error %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err = Authenticate(cfg, "alice", ""); err != ErrInvalidCredentials {
+		t.Errorf("empty password: got error %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err = Authenticate(cfg, "bob", "bobpw"); err != ErrUserNotFound {
+		t.Errorf("unknown user: got error %v, want %v", err, ErrUserNotFound)
+	}
+
+	cfg.BindPassword = "wrong"
+	if _, err = Authenticate(cfg, "alice", "alicepw"); err == nil {
+		t.Errorf("wrong service account password: expected an error")
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	cases := []struct {
+		filter string
+		want   *packet
+	}{
+		{"(uid=a\\2ab)", newConstructed(classContext, filterEqualityMatch,
+			newString(classUniversal, tagOctetString, "uid"),
+			newString(classUniversal, tagOctetString, "a*b"))},
+		{"(mail=*)", newString(classContext, filterPresent, "mail")},
+		{"(!(cn=x*y*z))", newConstructed(classContext, filterNot,
+			newConstructed(classContext, filterSubstrings,
+				newString(classUniversal, tagOctetString, "cn"),
+				newConstructed(classUniversal, tagSequence,
+					newString(classContext, 0, "x"),
+					newString(classContext, 1, "y"),
+					newString(classContext, 2, "z"))))},
+	}
+	for _, c := range cases {
+		got, err := compileFilter(c.filter)
+		if err != nil {
+			t.Errorf("%s: %v", c.filter, err)
+			continue
+		}
+		if !bytes.Equal(got.bytes(), c.want.bytes()) {
+			t.Errorf("%s: got %x, want %x", c.filter, got.bytes(), c.want.bytes())
+		}
+	}
+
+	for _, bad := range []string{"", "uid=a", "(uid=a", "(&(uid=a)", "(uid=a\\2)"} {
+		if _, err := compileFilter(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+
+	if got := EscapeFilter("a*(b)\\"); got != "a\\2a\\28b\\29\\5c" {
+		t.Errorf("EscapeFilter: got %q", got)
+	}
+}
+
+func TestAuthenticateStartTLS(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.ln.Close()
+
+	cfg := Config{
+		URL:          s.url(),
+		BindDN:       "cn=admin,dc=example,dc=com",
+		BindPassword: "adminpw",
+		BaseDN:       "ou=users,dc=example,dc=com",
+		Filter:       "(&(objectClass=person)(uid=%s))",
+		Timeout:      5 * time.Second,
+	}
+	// the self-signed certificate of the fake server is not trusted
+	if _, err := Authenticate(cfg, "alice", "alicepw"); err == nil {
+		t.Error("an untrusted certificate is accepted")
+	}
+
+	// a server without StartTLS is refused unless plaintext is allowed
+	s.tlsConfig = nil
+	cfg.TLSSkipVerify = true
+	if _, err := Authenticate(cfg, "alice", "alicepw"); err == nil {
+		t.Error("a server without StartTLS is accepted")
+	}
+	if len(s.binds) != 0 {
+		t.Error("a password was sent without TLS")
+	}
+	cfg.Plaintext = true
+	if _, err := Authenticate(cfg, "alice", "alicepw"); err != nil {
+		t.Errorf("plaintext: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package mail sends the emails of the server, e.g. 3PID validation tokens.
+// Senders are registered by name, so that providers other than SMTP can be
+// plugged in.
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/finogeeks/ligase/common/config"
+)
+
+// ErrNotConfigured is returned when no mail provider is configured
+var ErrNotConfigured = errors.New("mail: no provider configured")
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages
+type Sender interface {
+	Send(msg *Message) error
+}
+
+// NewSenderFunc creates a sender from the email configuration
+type NewSenderFunc func(cfg *config.Dendrite) (Sender, error)
+
+var (
+	regMutex  sync.Mutex
+	providers = map[string]NewSenderFunc{}
+)
+
+// Register makes a provider available under the given name
+func Register(name string, f NewSenderFunc) {
+	regMutex.Lock()
+	defer regMutex.Unlock()
+	providers[name] = f
+}
+
+// NewSender creates the sender of the configured provider
+func NewSender(cfg *config.Dendrite) (Sender, error) {
+	name := cfg.Email.Provider
+	if name == "" {
+		return nil, ErrNotConfigured
+	}
+	regMutex.Lock()
+	f, ok := providers[name]
+	regMutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("mail: unknown provider %q", name)
+	}
+	return f(cfg)
+}
@@ -0,0 +1,147 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/finogeeks/ligase/common/config"
+)
+
+func init() {
+	Register("smtp", NewSMTPSender)
+}
+
+// SMTPSender sends mails through an SMTP relay. STARTTLS is used when the
+// server offers it, and can be made mandatory.
+type SMTPSender struct {
+	addr          string
+	host          string
+	from          string
+	fromAddr      string
+	auth          smtp.Auth
+	implicitTLS   bool
+	requireTLS    bool
+	tlsSkipVerify bool
+	timeout       time.Duration
+}
+
+// NewSMTPSender creates a sender from the email.smtp configuration
+func NewSMTPSender(cfg *config.Dendrite) (Sender, error) {
+	c := cfg.Email.SMTP
+	if c.Host == "" || cfg.Email.From == "" {
+		return nil, fmt.Errorf("mail: email.smtp.host and email.from must be set")
+	}
+	from, err := netmail.ParseAddress(cfg.Email.From)
+	if err != nil {
+		return nil, fmt.Errorf("mail: invalid email.from: %v", err)
+	}
+	s := &SMTPSender{
+		addr:          net.JoinHostPort(c.Host, fmt.Sprint(c.Port)),
+		host:          c.Host,
+		from:          from.String(),
+		fromAddr:      from.Address,
+		implicitTLS:   c.ImplicitTLS,
+		requireTLS:    c.RequireTLS,
+		tlsSkipVerify: c.TLSSkipVerify,
+		timeout:       10 * time.Second,
+	}
+	if c.Username != "" {
+		s.auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+	return s, nil
+}
+
+// Send implements Sender
+func (s *SMTPSender) Send(msg *Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") {
+		return fmt.Errorf("mail: invalid recipient %q", msg.To)
+	}
+	tlsConfig := &tls.Config{ServerName: s.host, InsecureSkipVerify: s.tlsSkipVerify}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: s.timeout}
+	if s.implicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", s.addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if !s.implicitTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err = c.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		} else if s.requireTLS {
+			return fmt.Errorf("mail: %s does not support STARTTLS", s.addr)
+		}
+	}
+	if s.auth != nil {
+		if err = c.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(s.fromAddr); err != nil {
+		return err
+	}
+	if err = c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(s.format(msg)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *SMTPSender) format(msg *Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.Replace(msg.Body, "\n", "\r\n", -1))
+	return b.Bytes()
+}
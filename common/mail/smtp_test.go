@@ -0,0 +1,133 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mail
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/finogeeks/ligase/common/config"
+)
+
+// smtpSink is a local SMTP server which records the last message it got
+type smtpSink struct {
+	ln   net.Listener
+	rcpt chan string
+	data chan string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpSink{ln: ln, rcpt: make(chan string, 1), data: make(chan string, 1)}
+	go s.serve()
+	return s
+}
+
+func (s *smtpSink) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 sink ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 sink")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			s.rcpt <- strings.TrimSpace(line[len("RCPT TO:"):])
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var body strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				body.WriteString(l)
+			}
+			s.data <- body.String()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPSender(t *testing.T) {
+	sink := newSMTPSink(t)
+	defer sink.ln.Close()
+
+	host, port, _ := net.SplitHostPort(sink.ln.Addr().String())
+	cfg := &config.Dendrite{}
+	cfg.Email.Provider = "smtp"
+	cfg.Email.From = "noreply@example.com"
+	cfg.Email.SMTP.Host = host
+	cfg.Email.SMTP.Port, _ = strconv.Atoi(port)
+
+	sender, err := NewSender(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sender.Send(&Message{To: "alice@example.com", Subject: "Validate", Body: "token: 123\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rcpt := <-sink.rcpt; rcpt != "<alice@example.com>" {
+		t.Errorf("got recipient %q", rcpt)
+	}
+	data := <-sink.data
+	if !strings.Contains(data, "To: alice@example.com\r\n") || !strings.Contains(data, "\r\n\r\ntoken: 123\r\n") {
+		t.Errorf("unexpected message %q", data)
+	}
+
+	cfg.Email.SMTP.RequireTLS = true
+	sink2 := newSMTPSink(t)
+	defer sink2.ln.Close()
+	_, port, _ = net.SplitHostPort(sink2.ln.Addr().String())
+	cfg.Email.SMTP.Port, _ = strconv.Atoi(port)
+	sender, _ = NewSender(cfg)
+	if err = sender.Send(&Message{To: "alice@example.com", Subject: "x", Body: "y"}); err == nil {
+		t.Errorf("expected an error without STARTTLS when TLS is required")
+	}
+
+	if _, err = NewSender(&config.Dendrite{}); err != ErrNotConfigured {
+		t.Errorf("got error %v, want %v", err, ErrNotConfigured)
+	}
+}
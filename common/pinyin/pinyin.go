@@ -0,0 +1,94 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package pinyin converts hanzi to toneless pinyin so that chinese names
+// can be searched with latin letters.
+package pinyin
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var readings map[rune]string
+
+func init() {
+	readings = make(map[rune]string, 6800)
+	for _, s := range syllables {
+		for _, r := range s.hanzi {
+			readings[r] = s.syllable
+		}
+	}
+}
+
+// Reading returns the pinyin of a hanzi, ok is false if r is not a known hanzi
+func Reading(r rune) (syllable string, ok bool) {
+	syllable, ok = readings[r]
+	return
+}
+
+// Full replaces every hanzi of s with its pinyin, e.g. 张三 becomes zhangsan.
+// The other runes are kept lowercased.
+func Full(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if syllable, ok := readings[r]; ok {
+			b.WriteString(syllable)
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// Initials replaces every hanzi of s with the first letter of its pinyin,
+// e.g. 张三 becomes zs. The other runes are kept lowercased.
+func Initials(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if syllable, ok := readings[r]; ok {
+			b.WriteByte(syllable[0])
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// Name returns the full pinyin and the pinyin initials of a name as Full and
+// Initials do, and again with the surname reading of its first hanzi when it
+// has one, e.g. 单田芳 gives dantianfang and shantianfang.
+func Name(s string) (full, initials []string) {
+	s = strings.TrimSpace(s)
+	full, initials = []string{Full(s)}, []string{Initials(s)}
+	r, size := utf8.DecodeRuneInString(s)
+	if syllable, ok := surnames[r]; ok {
+		full = append(full, syllable+Full(s[size:]))
+		if initial := syllable[:1] + Initials(s[size:]); initial != initials[0] {
+			initials = append(initials, initial)
+		}
+	}
+	return
+}
+
+// HasHanzi reports whether s contains any known hanzi
+func HasHanzi(s string) bool {
+	for _, r := range s {
+		if _, ok := readings[r]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pinyin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFull(t *testing.T) {
+	cases := map[string]string{
+		"张三":       "zhangsan",
+		"欧阳娜娜":     "ouyangnana",
+		"吕布":       "lvbu",
+		"闫婧":       "yanjing",
+		"Alice 王":  "alice wang",
+		"@bob:a.b": "@bob:a.b",
+	}
+	for in, want := range cases {
+		if got := Full(in); got != want {
+			t.Errorf("Full(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestInitials(t *testing.T) {
+	cases := map[string]string{
+		"张三":   "zs",
+		"欧阳娜娜": "oynn",
+		"Bob李": "bobl",
+	}
+	for in, want := range cases {
+		if got := Initials(in); got != want {
+			t.Errorf("Initials(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	cases := []struct {
+		name     string
+		full     []string
+		initials []string
+	}{
+		{"张三", []string{"zhangsan"}, []string{"zs"}},
+		{"单田芳", []string{"dantianfang", "shantianfang"}, []string{"dtf", "stf"}},
+		{" 朴树", []string{"pushu", "piaoshu"}, []string{"ps"}},
+		{"区丽", []string{"quli", "ouli"}, []string{"ql", "ol"}},
+		{"翟", []string{"di", "zhai"}, []string{"d", "z"}},
+		{"简单", []string{"jiandan"}, []string{"jd"}},
+	}
+	for _, c := range cases {
+		full, initials := Name(c.name)
+		if !reflect.DeepEqual(full, c.full) || !reflect.DeepEqual(initials, c.initials) {
+			t.Errorf("Name(%q) = %v %v, want %v %v", c.name, full, initials, c.full, c.initials)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	seen := make(map[rune]string)
+	for _, s := range syllables {
+		for _, r := range s.hanzi {
+			if prev, ok := seen[r]; ok {
+				t.Errorf("%c has two readings %s and %s", r, prev, s.syllable)
+			}
+			seen[r] = s.syllable
+		}
+	}
+	for r, syllable := range surnames {
+		if reading, ok := seen[r]; !ok || reading == syllable {
+			t.Errorf("surname %c reading %s is not a second reading", r, syllable)
+		}
+	}
+	if len(seen) < 6700 {
+		t.Errorf("only %d hanzi have a reading", len(seen))
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"context"
+
+	"github.com/finogeeks/ligase/model/types"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// UpdateEventRelations returns the unsigned of parent once ev, which relates
+// to it by rel, is added to its m.relations, or taken out of them when
+// removed is true. The edit or thread reply replacing a redacted latest one
+// is looked up in the relations of syncDB.
+func UpdateEventRelations(
+	ctx context.Context, syncDB model.SyncAPIDatabase,
+	parent, ev *gomatrixserverlib.ClientEvent, rel *types.RelatesTo, removed bool,
+) ([]byte, error) {
+	var latest *gomatrixserverlib.ClientEvent
+	if removed {
+		latest = latestRelationEvent(ctx, syncDB, parent, ev, rel)
+	}
+	return types.UpdateRelations(parent.Unsigned, func(relations *types.EventRelations) {
+		if removed {
+			relations.Remove(ev, rel, latest)
+		} else {
+			relations.Add(parent, ev, rel)
+		}
+	})
+}
+
+// latestRelationEvent returns the latest edit or thread reply of parent other
+// than ev, nil if there is none. Only the edits of the sender of parent count.
+func latestRelationEvent(
+	ctx context.Context, syncDB model.SyncAPIDatabase,
+	parent, ev *gomatrixserverlib.ClientEvent, rel *types.RelatesTo,
+) *gomatrixserverlib.ClientEvent {
+	sender := ""
+	switch rel.RelType {
+	case types.RelTypeReplace:
+		sender = parent.Sender
+	case types.RelTypeThread:
+	default:
+		return nil
+	}
+	eventID, err := syncDB.SelectLatestEventRelation(ctx, rel.EventID, rel.RelType, sender, ev.EventID)
+	if err != nil {
+		log.Errorf("select latest %s of ev:%s err:%v", rel.RelType, rel.EventID, err)
+		return nil
+	}
+	if eventID == "" {
+		return nil
+	}
+	evs, err := syncDB.Events(ctx, []string{eventID})
+	if err != nil || len(evs) == 0 {
+		log.Errorf("load latest %s:%s of ev:%s err:%v", rel.RelType, eventID, rel.EventID, err)
+		return nil
+	}
+	return &evs[0]
+}
@@ -0,0 +1,145 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/finogeeks/ligase/model/types"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// relationsDB keeps the events and the syncapi_event_relations rows in
+// stream order
+type relationsDB struct {
+	model.SyncAPIDatabase
+	events map[string]gomatrixserverlib.ClientEvent
+	rows   []gomatrixserverlib.ClientEvent
+}
+
+func (d *relationsDB) Events(ctx context.Context, eventIDs []string) ([]gomatrixserverlib.ClientEvent, error) {
+	evs := []gomatrixserverlib.ClientEvent{}
+	for _, eventID := range eventIDs {
+		if ev, ok := d.events[eventID]; ok {
+			evs = append(evs, ev)
+		}
+	}
+	return evs, nil
+}
+
+func (d *relationsDB) SelectLatestEventRelation(ctx context.Context, relatesToID, relType, sender, excludeID string) (string, error) {
+	for i := len(d.rows) - 1; i >= 0; i-- {
+		ev := d.rows[i]
+		rel := types.GetRelatesTo(ev.Content)
+		if rel.EventID == relatesToID && rel.RelType == relType && (sender == "" || ev.Sender == sender) && ev.EventID != excludeID {
+			return ev.EventID, nil
+		}
+	}
+	return "", nil
+}
+
+func (d *relationsDB) deleteRow(eventID string) {
+	for i, ev := range d.rows {
+		if ev.EventID == eventID {
+			d.rows = append(d.rows[:i], d.rows[i+1:]...)
+			return
+		}
+	}
+}
+
+// relationsWriter updates the parent like the syncwriter and the feed do,
+// with the relation row deleted before or after the redaction is applied
+type relationsWriter struct {
+	t      *testing.T
+	db     *relationsDB
+	parent gomatrixserverlib.ClientEvent
+}
+
+func (w *relationsWriter) send(eventID, sender, relType string, ts int64) {
+	ev := gomatrixserverlib.ClientEvent{
+		EventID: eventID, Sender: sender, Type: "m.room.message", OriginServerTS: gomatrixserverlib.Timestamp(ts),
+		Content: []byte(`{"m.relates_to":{"rel_type":"` + relType + `","event_id":"` + w.parent.EventID + `"}}`),
+	}
+	w.db.events[eventID] = ev
+	w.db.rows = append(w.db.rows, ev)
+	w.update(&ev, false)
+}
+
+func (w *relationsWriter) redact(eventID string, deleteFirst bool) {
+	ev := w.db.events[eventID]
+	if deleteFirst {
+		w.db.deleteRow(eventID)
+	}
+	w.update(&ev, true)
+	w.db.deleteRow(eventID)
+}
+
+func (w *relationsWriter) update(ev *gomatrixserverlib.ClientEvent, removed bool) {
+	unsigned, err := UpdateEventRelations(context.Background(), w.db, &w.parent, ev, types.GetRelatesTo(ev.Content), removed)
+	if err != nil {
+		w.t.Fatal(err)
+	}
+	w.parent.Unsigned = unsigned
+}
+
+func (w *relationsWriter) relations() *types.EventRelations {
+	relations := types.GetRelations(w.parent.Unsigned)
+	if relations == nil {
+		return &types.EventRelations{}
+	}
+	return relations
+}
+
+func TestUpdateEventRelations(t *testing.T) {
+	for _, deleteFirst := range []bool{true, false} {
+		db := &relationsDB{events: map[string]gomatrixserverlib.ClientEvent{}}
+		w := &relationsWriter{t: t, db: db, parent: gomatrixserverlib.ClientEvent{EventID: "$p", Sender: "@a:h"}}
+
+		w.send("$e1", "@a:h", types.RelTypeReplace, 10)
+		w.send("$e2", "@a:h", types.RelTypeReplace, 20)
+		w.send("$forged", "@b:h", types.RelTypeReplace, 30)
+		w.send("$t1", "@b:h", types.RelTypeThread, 40)
+		w.send("$t2", "@c:h", types.RelTypeThread, 50)
+		if r := w.relations(); r.Replace == nil || r.Replace.EventID != "$e2" || r.Thread == nil || r.Thread.LatestEvent.EventID != "$t2" {
+			t.Fatalf("unexpected relations: %s", w.parent.Unsigned)
+		}
+
+		// the latest edit of the sender replaces the redacted one, not the
+		// edit of another user
+		w.redact("$e2", deleteFirst)
+		if r := w.relations(); r.Replace == nil || r.Replace.EventID != "$e1" {
+			t.Fatalf("deleteFirst %t: replace after redacting $e2: %+v", deleteFirst, r.Replace)
+		}
+		w.redact("$e1", deleteFirst)
+		if r := w.relations(); r.Replace != nil {
+			t.Fatalf("deleteFirst %t: replace after redacting every edit: %+v", deleteFirst, r.Replace)
+		}
+
+		w.redact("$t2", deleteFirst)
+		r := w.relations()
+		if r.Thread == nil || r.Thread.Count != 1 || r.Thread.LatestEvent == nil || r.Thread.LatestEvent.EventID != "$t1" {
+			t.Fatalf("deleteFirst %t: thread after redacting $t2: %s", deleteFirst, w.parent.Unsigned)
+		}
+		if _, ok := r.Thread.Participants["@c:h"]; ok {
+			t.Fatalf("deleteFirst %t: @c:h still takes part: %v", deleteFirst, r.Thread.Participants)
+		}
+		w.redact("$t1", deleteFirst)
+		if r := w.relations(); r.Thread != nil {
+			t.Fatalf("deleteFirst %t: thread after redacting every reply: %s", deleteFirst, w.parent.Unsigned)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"context"
+
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/model/types"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// the roomserver events are purged in chunks of this size
+const purgeChunkSize = 1000
+
+// RetentionContent is the content of m.room.retention, lifetimes are in ms
+type RetentionContent struct {
+	MaxLifetime *int64 `json:"max_lifetime,omitempty"`
+	MinLifetime *int64 `json:"min_lifetime,omitempty"`
+}
+
+// RetentionMaxLifetime is how long in ms the events of a room with policy are
+// kept, 0 keeps them forever. policy may be nil if the room has none. Only a
+// max_lifetime the room set is held to the allowed bounds, a room without one
+// gets the default.
+func RetentionMaxLifetime(cfg *config.Dendrite, policy *RetentionContent) int64 {
+	if cfg == nil || !cfg.Retention.Enable {
+		return 0
+	}
+	maxLifetime := cfg.Retention.DefaultMaxLifetime
+	minLifetime := cfg.Retention.DefaultMinLifetime
+	if policy != nil {
+		if policy.MaxLifetime != nil && *policy.MaxLifetime > 0 {
+			maxLifetime = *policy.MaxLifetime
+			if cfg.Retention.AllowedLifetimeMax > 0 && maxLifetime > cfg.Retention.AllowedLifetimeMax {
+				maxLifetime = cfg.Retention.AllowedLifetimeMax
+			}
+			if maxLifetime < cfg.Retention.AllowedLifetimeMin {
+				maxLifetime = cfg.Retention.AllowedLifetimeMin
+			}
+		}
+		if policy.MinLifetime != nil {
+			minLifetime = *policy.MinLifetime
+		}
+	}
+	if maxLifetime <= 0 {
+		return 0
+	}
+	if maxLifetime < minLifetime {
+		maxLifetime = minLifetime
+	}
+	return maxLifetime
+}
+
+// MessageVisibilityTime is how long in seconds the messages of a room stay
+// visible, the stricter of the messageVisibilityTime setting and the room
+// retention. 0 means forever.
+func MessageVisibilityTime(settings *Settings, cfg *config.Dendrite, policy *RetentionContent) int64 {
+	visibilityTime := settings.GetMessageVisilibityTime()
+	// round up so a lifetime below a second still expires
+	lifetime := (RetentionMaxLifetime(cfg, policy) + 999) / 1000
+	if lifetime > 0 && (visibilityTime <= 0 || lifetime < visibilityTime) {
+		visibilityTime = lifetime
+	}
+	return visibilityTime
+}
+
+// PurgeRoomHistory deletes the non state events of roomID sent before ts,
+// except the latest one, and tells the sync servers to drop them. It returns
+// how many events the sync api purged.
+func PurgeRoomHistory(
+	ctx context.Context,
+	roomID string,
+	ts int64,
+	roomDB model.RoomServerDatabase,
+	syncDB model.SyncAPIDatabase,
+	cache service.Cache,
+	rpcCli *RpcClient,
+) (int64, error) {
+	// the roomserver follows what the sync api purged, even if it stopped
+	// half way
+	eventIDs, purgeErr := syncDB.PurgeRoomEvents(ctx, roomID, ts)
+	var rsPurged int64
+	for start := 0; start < len(eventIDs); start += purgeChunkSize {
+		end := start + purgeChunkSize
+		if end > len(eventIDs) {
+			end = len(eventIDs)
+		}
+		n, err := roomDB.PurgeRoomEvents(ctx, roomID, eventIDs[start:end])
+		if err != nil {
+			return int64(len(eventIDs)), err
+		}
+		rsPurged += n
+	}
+	log.Infof("room %s history before %d purged, sync events:%d roomserver events:%d", roomID, ts, len(eventIDs), rsPurged)
+
+	if len(eventIDs) > 0 {
+		if err := cache.DelTxnID(roomID); err != nil {
+			log.Errorf("PurgeRoomHistory room:%s delete txn ids err:%v", roomID, err)
+		}
+		bytes, err := json.Marshal(types.RoomHistoryPurge{RoomID: roomID, BeforeTS: ts})
+		if err == nil {
+			rpcCli.Pub(types.RoomHistoryPurgeTopicDef, bytes)
+		} else {
+			log.Errorf("PurgeRoomHistory room:%s marshal purge err:%v", roomID, err)
+		}
+	}
+	return int64(len(eventIDs)), purgeErr
+}
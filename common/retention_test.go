@@ -0,0 +1,60 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/finogeeks/ligase/common/config"
+)
+
+func TestRetentionMaxLifetime(t *testing.T) {
+	lifetime := func(ms int64) *int64 { return &ms }
+	retention := func(enable bool, defaultMin, defaultMax, allowedMin, allowedMax int64) *config.Dendrite {
+		cfg := &config.Dendrite{}
+		cfg.Retention.Enable = enable
+		cfg.Retention.DefaultMinLifetime = defaultMin
+		cfg.Retention.DefaultMaxLifetime = defaultMax
+		cfg.Retention.AllowedLifetimeMin = allowedMin
+		cfg.Retention.AllowedLifetimeMax = allowedMax
+		return cfg
+	}
+	tests := []struct {
+		name   string
+		cfg    *config.Dendrite
+		policy *RetentionContent
+		want   int64
+	}{
+		{"no config", nil, &RetentionContent{MaxLifetime: lifetime(1000)}, 0},
+		{"disabled", retention(false, 0, 0, 0, 0), &RetentionContent{MaxLifetime: lifetime(1000)}, 0},
+		{"no policy no default", retention(true, 0, 0, 100, 5000), nil, 0},
+		{"policy without max no default", retention(true, 0, 0, 100, 5000), &RetentionContent{MinLifetime: lifetime(200)}, 0},
+		{"zero max no default", retention(true, 0, 0, 100, 5000), &RetentionContent{MaxLifetime: lifetime(0)}, 0},
+		{"no policy default", retention(true, 0, 3000, 100, 5000), nil, 3000},
+		{"default above the allowed max", retention(true, 0, 9000, 100, 5000), nil, 9000},
+		{"policy max", retention(true, 0, 3000, 100, 5000), &RetentionContent{MaxLifetime: lifetime(1000)}, 1000},
+		{"policy max above the allowed max", retention(true, 0, 0, 100, 5000), &RetentionContent{MaxLifetime: lifetime(9000)}, 5000},
+		{"policy max below the allowed min", retention(true, 0, 0, 100, 5000), &RetentionContent{MaxLifetime: lifetime(10)}, 100},
+		{"policy max without bounds", retention(true, 0, 0, 0, 0), &RetentionContent{MaxLifetime: lifetime(9000)}, 9000},
+		{"default min", retention(true, 2000, 0, 0, 0), &RetentionContent{MaxLifetime: lifetime(1000)}, 2000},
+		{"policy min", retention(true, 0, 0, 0, 0), &RetentionContent{MaxLifetime: lifetime(1000), MinLifetime: lifetime(1500)}, 1500},
+		{"policy min below the default min", retention(true, 2000, 0, 0, 0), &RetentionContent{MaxLifetime: lifetime(1000), MinLifetime: lifetime(500)}, 1000},
+	}
+	for _, tt := range tests {
+		if got := RetentionMaxLifetime(tt.cfg, tt.policy); got != tt.want {
+			t.Errorf("%s: lifetime = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
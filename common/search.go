@@ -0,0 +1,63 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"context"
+
+	"github.com/finogeeks/ligase/common/encryption"
+	"github.com/finogeeks/ligase/common/fulltext"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// searchKeys are the content fields indexed for /search by event type
+var searchKeys = map[string]string{
+	"m.room.message": "body",
+	"m.room.name":    "name",
+	"m.room.topic":   "topic",
+}
+
+// SearchEventTypes returns the event types indexed for /search
+func SearchEventTypes() []string {
+	types := make([]string, 0, len(searchKeys))
+	for typ := range searchKeys {
+		types = append(types, typ)
+	}
+	return types
+}
+
+// IndexSearchEvent indexes the text of ev, written at offset, for /search.
+// The index is plain text, so event types stored encrypted are left out.
+// Indexing an event again replaces its lexemes.
+func IndexSearchEvent(
+	ctx context.Context, syncDB model.SyncAPIDatabase,
+	ev *gomatrixserverlib.ClientEvent, offset int64, tokenizer string,
+) error {
+	field, ok := searchKeys[ev.Type]
+	if !ok || encryption.CheckCrypto(ev.Type) {
+		return nil
+	}
+	content := map[string]interface{}{}
+	if err := json.Unmarshal(ev.Content, &content); err != nil {
+		return nil
+	}
+	text, _ := content[field].(string)
+	lexemes := fulltext.Lexemes(text, tokenizer)
+	if len(lexemes) == 0 {
+		return nil
+	}
+	return syncDB.UpsertEventSearch(ctx, ev.EventID, ev.RoomID, ev.Sender, ev.Type, "content."+field, int64(ev.OriginServerTS), offset, lexemes)
+}
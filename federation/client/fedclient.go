@@ -199,6 +199,15 @@ func (fed *FedClientWrap) Backfill(
 	return fed.Client.Backfill(ctx, s, domain, roomID, limit, eventIDs, dir)
 }
 
+func (fed *FedClientWrap) TimestampToEvent(
+	ctx context.Context, destination, roomID string, ts int64, dir string,
+) (res gomatrixserverlib.RespTimestampToEvent, err error) {
+	if ok, err := checkCert(); !ok {
+		return gomatrixserverlib.RespTimestampToEvent{}, err
+	}
+	return fed.Client.TimestampToEvent(ctx, gomatrixserverlib.ServerName(destination), roomID, ts, dir)
+}
+
 func (fed *FedClientWrap) SendTransaction(
 	ctx context.Context, t gomatrixserverlib.Transaction,
 ) (res gomatrixserverlib.RespSend, err error) {
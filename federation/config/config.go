@@ -71,23 +71,24 @@ type Fed struct {
 		Uri string `yaml:"uri"`
 	} `yaml:"nats"`
 	Rpc struct {
-		RsQryTopic          string `yaml:"rs_qry_topic"`
-		PrQryTopic          string `yaml:"pr_qry_topic"`
-		AliasTopic          string `yaml:"alias_topic"`
-		RoomInputTopic      string `yaml:"room_input_topic"`
-		FedTopic            string `yaml:"fed_topic"`
-		FedAliasTopic       string `yaml:"fed_alias_topic"`
-		FedProfileTopic     string `yaml:"fed_profile_topic"`
-		FedAvatarTopic      string `yaml:"fed_avatar_topic"`
-		FedDisplayNameTopic string `yaml:"fed_displayname_topic"`
-		FedRsQryTopic       string `yaml:"fed_rs_qry_topic"`
-		FedRsDownloadTopic  string `yaml:"fed_download_topic"`
-		FedRsInviteTopic    string `yaml:"fed_invite_topic"`
-		FedUserInfoTopic    string `yaml:"fed_user_info_topic"`
-		FedRsMakeJoinTopic  string `yaml:"fed_makejoin_topic"`
-		FedRsSendJoinTopic  string `yaml:"fed_sendjoin_topic"`
-		FedRsMakeLeaveTopic string `yaml:"fed_makeleave_topic"`
-		FedRsSendLeaveTopic string `yaml:"fed_sendleave_topic"`
+		RsQryTopic               string `yaml:"rs_qry_topic"`
+		PrQryTopic               string `yaml:"pr_qry_topic"`
+		AliasTopic               string `yaml:"alias_topic"`
+		RoomInputTopic           string `yaml:"room_input_topic"`
+		FedTopic                 string `yaml:"fed_topic"`
+		FedAliasTopic            string `yaml:"fed_alias_topic"`
+		FedProfileTopic          string `yaml:"fed_profile_topic"`
+		FedAvatarTopic           string `yaml:"fed_avatar_topic"`
+		FedDisplayNameTopic      string `yaml:"fed_displayname_topic"`
+		FedRsQryTopic            string `yaml:"fed_rs_qry_topic"`
+		FedRsDownloadTopic       string `yaml:"fed_download_topic"`
+		FedRsInviteTopic         string `yaml:"fed_invite_topic"`
+		FedUserInfoTopic         string `yaml:"fed_user_info_topic"`
+		FedRsMakeJoinTopic       string `yaml:"fed_makejoin_topic"`
+		FedRsSendJoinTopic       string `yaml:"fed_sendjoin_topic"`
+		FedRsMakeLeaveTopic      string `yaml:"fed_makeleave_topic"`
+		FedRsSendLeaveTopic      string `yaml:"fed_sendleave_topic"`
+		FedTimestampToEventTopic string `yaml:"fed_timestamp_to_event_topic"`
 	} `yaml:"rpc"`
 	Media struct {
 		UploadUrl string `yaml:"upload_url"`
@@ -100,6 +101,7 @@ type Fed struct {
 		ServerKey  DataBaseConf `yaml:"server_key"`
 		Encryption DataBaseConf `yaml:"encryption"`
 		Account    DataBaseConf `yaml:"account"`
+		SyncAPI    DataBaseConf `yaml:"sync_api"`
 		UseSync    bool         `yaml:"use_sync"`
 	} `yaml:"database"`
 	Redis struct {
@@ -230,6 +232,8 @@ func (f Fed) GetDBConfig(name string) (driver string, createAddr string, addr st
 		return f.Database.Encryption.Driver, f.Database.CreateDB.Addresses, f.Database.Encryption.Addresses, f.Kafka.Producer.DBUpdates.Underlying, f.Kafka.Producer.DBUpdates.Name, !f.Database.UseSync
 	case "accounts":
 		return f.Database.Account.Driver, f.Database.CreateDB.Addresses, f.Database.Account.Addresses, f.Kafka.Producer.DBUpdates.Underlying, f.Kafka.Producer.DBUpdates.Name, !f.Database.UseSync
+	case "syncapi":
+		return f.Database.SyncAPI.Driver, f.Database.CreateDB.Addresses, f.Database.SyncAPI.Addresses, f.Kafka.Producer.DBUpdates.Underlying, f.Kafka.Producer.DBUpdates.Name, !f.Database.UseSync
 	default:
 		return "", "", "", "", "", false
 	}
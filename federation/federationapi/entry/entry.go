@@ -52,6 +52,7 @@ var (
 	publicroomsAPI publicroomsapi.PublicRoomsQueryAPI
 	rpcClient      *common.RpcClient
 	encryptionDB   dbmodel.EncryptorAPIDatabase
+	syncDB         dbmodel.SyncAPIDatabase
 	complexCache   *common.ComplexCache
 )
 
@@ -118,6 +119,10 @@ func SetEncryptionDB(db dbmodel.EncryptorAPIDatabase) {
 	encryptionDB = db
 }
 
+func SetSyncDB(db dbmodel.SyncAPIDatabase) {
+	syncDB = db
+}
+
 func SetComplexCache(cache *common.ComplexCache) {
 	complexCache = cache
 }
@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/finogeeks/ligase/common"
 	"github.com/finogeeks/ligase/federation/client"
 	fedmodel "github.com/finogeeks/ligase/federation/storage/model"
 	"github.com/finogeeks/ligase/model"
@@ -56,6 +57,10 @@ func Invite(ctx context.Context, msg *model.GobMessage, cache service.Cache, rpc
 	}
 
 	// TODO: check if can invite user
+	inviterDomain, _ := common.DomainFromID(event.Sender())
+	if err = checkServerACL(ctx, rpcCli, event.RoomID(), inviterDomain); err != nil {
+		return retMsg, err
+	}
 
 	log.Infof("invite event: %v", event)
 	log.Infof("invite states: %s", event.Unsigned())
@@ -60,6 +60,25 @@ func MakeJoin(ctx context.Context, msg *model.GobMessage, cache service.Cache, r
 	if err != nil {
 		return retMsg, errors.New("MakeJoin query room state error: " + err.Error())
 	}
+	if err = checkServerACLFromState(&queryRes, domain); err != nil {
+		return retMsg, errors.New("MakeJoin " + err.Error())
+	}
+
+	// Servers that don't list their room versions are let through, older
+	// ligase servers sent them under the wrong query parameter
+	roomVersion := gomatrixserverlib.AuthEventsRoomVersion(&queryRes)
+	if len(reqParam.Ver) > 0 {
+		compatible := false
+		for _, v := range reqParam.Ver {
+			if v == string(roomVersion) {
+				compatible = true
+				break
+			}
+		}
+		if !compatible {
+			return retMsg, errors.New("MakeJoin incompatible room version " + string(roomVersion))
+		}
+	}
 
 	builder := gomatrixserverlib.EventBuilder{
 		Sender:   reqParam.UserID,
@@ -75,7 +94,7 @@ func MakeJoin(ctx context.Context, msg *model.GobMessage, cache service.Cache, r
 
 	now := time.Now()
 	nid, _ := idg.Next()
-	ev, err := builder.Build(nid, now, gomatrixserverlib.ServerName(domain))
+	ev, err := builder.BuildWithRoomVersion(nid, now, gomatrixserverlib.ServerName(domain), roomVersion)
 	if err != nil {
 		return retMsg, errors.New("MakeJoin buildEvent error: " + err.Error())
 	}
@@ -85,7 +104,8 @@ func MakeJoin(ctx context.Context, msg *model.GobMessage, cache service.Cache, r
 	}
 
 	retMsg.Body, err = json.Marshal(gomatrixserverlib.RespMakeJoin{
-		JoinEvent: builder,
+		JoinEvent:   builder,
+		RoomVersion: roomVersion,
 	})
 	if err != nil {
 		return retMsg, errors.New("MakeJoin marshal ret body error: " + err.Error())
@@ -118,6 +138,10 @@ func SendJoin(ctx context.Context, msg *model.GobMessage, cache service.Cache, r
 	if err != nil {
 		return retMsg, errors.New("SendJoin query room state error: " + err.Error())
 	}
+	domain, _ := common.DomainFromID(reqParam.Event.Sender())
+	if err = checkServerACLFromState(&queryRes, domain); err != nil {
+		return retMsg, errors.New("SendJoin " + err.Error())
+	}
 
 	if err = gomatrixserverlib.Allowed(reqParam.Event, &queryRes); err != nil {
 		return retMsg, errors.New("SendJoin not allowed, error: " + err.Error())
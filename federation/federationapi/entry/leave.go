@@ -73,9 +73,10 @@ func MakeLeave(ctx context.Context, msg *model.GobMessage, cache service.Cache,
 		return retMsg, errors.New("MakeLeave builder.SetContent error: " + err.Error())
 	}
 
+	roomVersion := gomatrixserverlib.AuthEventsRoomVersion(&queryRes)
 	now := time.Now()
 	nid, _ := idg.Next()
-	ev, err := builder.Build(nid, now, gomatrixserverlib.ServerName(domain))
+	ev, err := builder.BuildWithRoomVersion(nid, now, gomatrixserverlib.ServerName(domain), roomVersion)
 	if err != nil {
 		return retMsg, errors.New("MakeLeave buildEvent error: " + err.Error())
 	}
@@ -85,7 +86,8 @@ func MakeLeave(ctx context.Context, msg *model.GobMessage, cache service.Cache,
 	}
 
 	retMsg.Body, err = json.Marshal(gomatrixserverlib.RespMakeLeave{
-		Event: builder,
+		Event:       builder,
+		RoomVersion: roomVersion,
 	})
 	if err != nil {
 		return retMsg, errors.New("MakeLeave marshal ret body error: " + err.Error())
@@ -0,0 +1,54 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package entry
+
+import (
+	"context"
+	"errors"
+
+	"github.com/finogeeks/ligase/federation/client"
+	fedmodel "github.com/finogeeks/ligase/federation/storage/model"
+	"github.com/finogeeks/ligase/model"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/log"
+)
+
+func init() {
+	Register(model.CMD_FED_OPENID_USERINFO, GetOpenIDUserInfo)
+}
+
+// GetOpenIDUserInfo returns the user of an OpenID token issued by
+// /user/{userId}/openid/request_token, the body is empty if the token is
+// unknown or expired.
+func GetOpenIDUserInfo(ctx context.Context, msg *model.GobMessage, cache service.Cache, rpcCli roomserverapi.RoomserverRPCAPI, fedClient *client.FedClientWrap, db fedmodel.FederationDatabase) (*model.GobMessage, error) {
+	if msg == nil {
+		return &model.GobMessage{}, errors.New("msg from connector is nil")
+	}
+
+	userID, err := cache.GetOpenIDToken(string(msg.Body))
+	if err != nil {
+		log.Errorf("GetOpenIDUserInfo get token err:%v", err)
+		return &model.GobMessage{}, err
+	}
+	if userID == "" {
+		log.Infof("GetOpenIDUserInfo unknown or expired token")
+		return &model.GobMessage{}, nil
+	}
+
+	body, _ := (&external.GetFedOpenIDUserInfoResponse{Sub: userID}).Encode()
+	return &model.GobMessage{Body: body}, nil
+}
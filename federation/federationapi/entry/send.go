@@ -69,7 +69,8 @@ func Send(ctx context.Context, msg *model.GobMessage, cache service.Cache, rpcCl
 	}
 	log.Infof("api send recv trans: %s", msg.Body)
 
-	pdus := trans.PDUs
+	origin := string(trans.Origin)
+	pdus := filterServerACL(ctx, rpcCli, trans.PDUs, origin)
 	if len(pdus) > 0 {
 		ev := pdus[0]
 		roomID := ev.RoomID()
@@ -178,6 +179,12 @@ func Send(ctx context.Context, msg *model.GobMessage, cache service.Cache, rpcCl
 		}
 	}
 	for _, edu := range trans.EDUs {
+		if roomID := eduRoomID(&edu); roomID != "" {
+			if err := checkServerACL(ctx, rpcCli, roomID, origin); err != nil {
+				log.Warnf("api send drop %s edu: %v", edu.Type, err)
+				continue
+			}
+		}
 		switch edu.Type {
 		case "profile":
 			rpcCli.ProcessProfile(&edu)
@@ -0,0 +1,94 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package entry
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/model/types"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/pkg/errors"
+)
+
+// checkServerACL returns an error if the m.room.server_acl of the room denies
+// the server. Rooms we don't know of have no ACL to apply.
+func checkServerACL(ctx context.Context, rpcCli roomserverapi.RoomserverRPCAPI, roomID, serverName string) error {
+	var queryRes roomserverapi.QueryRoomStateResponse
+	queryReq := roomserverapi.QueryRoomStateRequest{RoomID: roomID}
+	if err := rpcCli.QueryRoomState(ctx, &queryReq, &queryRes); err != nil || !queryRes.RoomExists {
+		return nil
+	}
+	return checkServerACLFromState(&queryRes, serverName)
+}
+
+func checkServerACLFromState(queryRes *roomserverapi.QueryRoomStateResponse, serverName string) error {
+	acl, err := gomatrixserverlib.NewServerACLFromEvent(queryRes.ServerACL)
+	if err != nil {
+		// Can't happen for events that passed auth, don't lock everyone out
+		log.Warnf("room %s has an invalid server ACL: %v", queryRes.RoomID, err)
+		return nil
+	}
+	if !acl.IsServerAllowed(serverName) {
+		return errors.Errorf("server %s is denied by the server ACL of room %s", serverName, queryRes.RoomID)
+	}
+	return nil
+}
+
+// filterServerACL drops the PDUs whose room denies the origin server, each PDU
+// is checked against the server ACL of its own room.
+func filterServerACL(
+	ctx context.Context, rpcCli roomserverapi.RoomserverRPCAPI,
+	pdus []gomatrixserverlib.Event, origin string,
+) []gomatrixserverlib.Event {
+	denied := map[string]bool{}
+	var allowed []gomatrixserverlib.Event
+	for _, pdu := range pdus {
+		roomID := pdu.RoomID()
+		isDenied, checked := denied[roomID]
+		if !checked {
+			err := checkServerACL(ctx, rpcCli, roomID, origin)
+			if err != nil {
+				log.Warnf("api send drop pdus of room %s: %v", roomID, err)
+			}
+			isDenied = err != nil
+			denied[roomID] = isDenied
+		}
+		if !isDenied {
+			allowed = append(allowed, pdu)
+		}
+	}
+	return allowed
+}
+
+// eduRoomID returns the room of a receipt or typing EDU, other EDUs aren't
+// bound to a room.
+func eduRoomID(edu *gomatrixserverlib.EDU) string {
+	switch edu.Type {
+	case "receipt":
+		var content types.ReceiptContent
+		if err := json.Unmarshal(edu.Content, &content); err == nil {
+			return content.RoomID
+		}
+	case "typing":
+		var content types.TypingContent
+		if err := json.Unmarshal(edu.Content, &content); err == nil {
+			return content.RoomID
+		}
+	}
+	return ""
+}
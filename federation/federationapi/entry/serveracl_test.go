@@ -0,0 +1,78 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package entry
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+)
+
+// aclRoomserver serves the server ACL of each room.
+type aclRoomserver struct {
+	roomserverapi.RoomserverRPCAPI
+	acls map[string]*gomatrixserverlib.Event
+}
+
+func (r *aclRoomserver) QueryRoomState(ctx context.Context, req *roomserverapi.QueryRoomStateRequest, res *roomserverapi.QueryRoomStateResponse) error {
+	res.RoomID = req.RoomID
+	res.RoomExists = true
+	res.ServerACL = r.acls[req.RoomID]
+	return nil
+}
+
+func testACLEvent(t *testing.T, roomID, eventType, stateKey, content string) gomatrixserverlib.Event {
+	b := gomatrixserverlib.EventBuilder{
+		Sender:   "@u1:a.com",
+		RoomID:   roomID,
+		Type:     eventType,
+		StateKey: &stateKey,
+	}
+	if err := b.SetContent(json.RawMessage(content)); err != nil {
+		t.Fatal(err)
+	}
+	ev, err := b.Build(time.Now().UnixNano(), time.Now(), "a.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+func TestFilterServerACL(t *testing.T) {
+	acl := testACLEvent(t, "!deny:a.com", gomatrixserverlib.MRoomServerACL, "", `{"allow":["*"],"deny":["evil.com"]}`)
+	rpcCli := &aclRoomserver{acls: map[string]*gomatrixserverlib.Event{"!deny:a.com": &acl}}
+	pdus := []gomatrixserverlib.Event{
+		testACLEvent(t, "!open:a.com", "m.room.topic", "", `{"topic":"a"}`),
+		testACLEvent(t, "!deny:a.com", "m.room.topic", "", `{"topic":"b"}`),
+		testACLEvent(t, "!open:a.com", "m.room.topic", "", `{"topic":"c"}`),
+	}
+
+	allowed := filterServerACL(context.Background(), rpcCli, pdus, "evil.com:8448")
+	if len(allowed) != 2 {
+		t.Fatalf("allowed %d pdus, want 2", len(allowed))
+	}
+	for _, pdu := range allowed {
+		if pdu.RoomID() != "!open:a.com" {
+			t.Fatalf("pdu of room %s not dropped", pdu.RoomID())
+		}
+	}
+	if allowed = filterServerACL(context.Background(), rpcCli, pdus, "good.com"); len(allowed) != 3 {
+		t.Fatalf("allowed %d pdus of good.com, want 3", len(allowed))
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package entry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/finogeeks/ligase/federation/client"
+	fedmodel "github.com/finogeeks/ligase/federation/storage/model"
+	"github.com/finogeeks/ligase/model"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/model/service/roomserverapi"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	dbmodel "github.com/finogeeks/ligase/storage/model"
+)
+
+func init() {
+	Register(model.CMD_FED_TIMESTAMP_TO_EVENT, TimestampToEvent)
+}
+
+func TimestampToEvent(ctx context.Context, msg *model.GobMessage, cache service.Cache, rpcCli roomserverapi.RoomserverRPCAPI, fedClient *client.FedClientWrap, db fedmodel.FederationDatabase) (*model.GobMessage, error) {
+	retMsg := &model.GobMessage{Body: []byte{}}
+	if msg == nil {
+		return retMsg, errors.New("msg from connector is nil")
+	}
+
+	var req external.GetFedTimestampToEventRequest
+	if err := req.Decode(msg.Body); err != nil {
+		return retMsg, err
+	}
+	resp, err := timestampToEvent(ctx, rpcCli, syncDB, &req)
+	if err != nil {
+		log.Errorf("TimestampToEvent room:%s ts:%d dir:%s origin:%s err:%v", req.RoomID, req.TS, req.Dir, req.Origin, err)
+		return retMsg, err
+	}
+	retMsg.Body, _ = json.Marshal(resp)
+	return retMsg, nil
+}
+
+// timestampToEvent returns the local event of the room closest to req.TS in
+// the direction req.Dir, the event ID is empty if there is none or the room
+// is unknown.
+func timestampToEvent(
+	ctx context.Context, rpcCli roomserverapi.RoomserverRPCAPI, db dbmodel.SyncAPIDatabase,
+	req *external.GetFedTimestampToEventRequest,
+) (*gomatrixserverlib.RespTimestampToEvent, error) {
+	resp := &gomatrixserverlib.RespTimestampToEvent{}
+	var queryRes roomserverapi.QueryRoomStateResponse
+	queryReq := roomserverapi.QueryRoomStateRequest{RoomID: req.RoomID}
+	if err := rpcCli.QueryRoomState(ctx, &queryReq, &queryRes); err != nil || !queryRes.RoomExists {
+		return resp, nil
+	}
+	if err := checkServerACLFromState(&queryRes, req.Origin); err != nil {
+		return nil, err
+	}
+
+	eventID, originServerTS, err := db.SelectEventByTimestamp(ctx, req.RoomID, req.TS, req.Dir)
+	if err != nil {
+		return nil, err
+	}
+	resp.EventID = eventID
+	resp.OriginServerTS = gomatrixserverlib.Timestamp(originServerTS)
+	return resp, nil
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package entry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// timestampSyncDB has a single event at ts 100 in every room
+type timestampSyncDB struct {
+	model.SyncAPIDatabase
+}
+
+func (d *timestampSyncDB) SelectEventByTimestamp(ctx context.Context, roomID string, ts int64, dir string) (string, int64, error) {
+	if (dir == "f" && ts <= 100) || (dir == "b" && ts >= 100) {
+		return "$event:a.com", 100, nil
+	}
+	return "", 0, nil
+}
+
+func TestTimestampToEvent(t *testing.T) {
+	acl := testACLEvent(t, "!deny:a.com", gomatrixserverlib.MRoomServerACL, "", `{"allow":["*"],"deny":["evil.com"]}`)
+	rpcCli := &aclRoomserver{acls: map[string]*gomatrixserverlib.Event{"!deny:a.com": &acl}}
+	db := &timestampSyncDB{}
+
+	tests := []struct {
+		name    string
+		req     external.GetFedTimestampToEventRequest
+		eventID string
+		err     bool
+	}{
+		{"forward", external.GetFedTimestampToEventRequest{RoomID: "!open:a.com", TS: 50, Dir: "f", Origin: "b.com"}, "$event:a.com", false},
+		{"backward", external.GetFedTimestampToEventRequest{RoomID: "!open:a.com", TS: 150, Dir: "b", Origin: "b.com"}, "$event:a.com", false},
+		{"no event", external.GetFedTimestampToEventRequest{RoomID: "!open:a.com", TS: 150, Dir: "f", Origin: "b.com"}, "", false},
+		{"denied by the server ACL", external.GetFedTimestampToEventRequest{RoomID: "!deny:a.com", TS: 50, Dir: "f", Origin: "evil.com:8448"}, "", true},
+		{"allowed by the server ACL", external.GetFedTimestampToEventRequest{RoomID: "!deny:a.com", TS: 50, Dir: "f", Origin: "b.com"}, "$event:a.com", false},
+	}
+	for _, tt := range tests {
+		resp, err := timestampToEvent(context.Background(), rpcCli, db, &tt.req)
+		if (err != nil) != tt.err {
+			t.Fatalf("%s: err = %v, want error %t", tt.name, err, tt.err)
+		}
+		if err == nil && resp.EventID != tt.eventID {
+			t.Fatalf("%s: event = %q, want %q", tt.name, resp.EventID, tt.eventID)
+		}
+	}
+}
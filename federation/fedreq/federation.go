@@ -95,6 +95,16 @@ func (fed *Federation) LookupState(
 	return resp, err
 }
 
+func (fed *Federation) TimestampToEvent(
+	destination string,
+	roomID string, ts int64, dir string,
+) (gomatrixserverlib.RespTimestampToEvent, error) {
+	var resp gomatrixserverlib.RespTimestampToEvent
+	queryReq := external.GetFedTimestampToEventRequest{RoomID: roomID, TS: ts, Dir: dir}
+	err := rpc.TimestampToEvent(fed.cfg, fed.rpcClient, destination, &queryReq, &resp)
+	return resp, err
+}
+
 func (fed *Federation) LookupUserInfo(
 	destination string,
 	userID string) (external.GetUserInfoResponse, error) {
@@ -62,6 +62,22 @@ func GetRoomState(
 	return err
 }
 
+func TimestampToEvent(
+	cfg *config.Dendrite,
+	rpcClient *common.RpcClient,
+	destination string,
+	req *external.GetFedTimestampToEventRequest,
+	response *gomatrixserverlib.RespTimestampToEvent,
+) error {
+	data, err := RpcRequest(rpcClient, destination, cfg.Rpc.FedTimestampToEventTopic, req)
+	if err != nil {
+		log.Errorf("federation timestamp to event error: %v", err)
+		return err
+	}
+	json.Unmarshal(data, response)
+	return nil
+}
+
 type DownloadCB struct {
 	id string
 	ch chan []byte
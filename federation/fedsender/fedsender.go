@@ -225,6 +225,10 @@ func (c *FederationSender) OnMessage(ctx context.Context, topic string, partitio
 }
 
 func (c *FederationSender) processEvent(ctx context.Context, partition int32, targetDomain, roomID string, ev *gomatrixserverlib.Event) {
+	if !c.serverAllowed(ctx, roomID, targetDomain) {
+		log.Warnf("fed-sender skip event %s of room %s, %s is denied by the server ACL", ev.EventID(), roomID, targetDomain)
+		return
+	}
 	c.recRepo.IncrPendingSize(ctx, roomID, targetDomain, 1, ev.DomainOffset())
 
 	senderDomain, _ := utils.DomainFromID(ev.Sender())
@@ -236,10 +240,32 @@ func (c *FederationSender) processEvent(ctx context.Context, partition int32, ta
 }
 
 func (c *FederationSender) processEdu(ctx context.Context, partition int32, targetDomain, roomID string, edu *gomatrixserverlib.EDU) {
+	if !c.serverAllowed(ctx, roomID, targetDomain) {
+		log.Warnf("fed-sender skip %s edu of room %s, %s is denied by the server ACL", edu.Type, roomID, targetDomain)
+		return
+	}
 	oqs := c.getQueue(edu.Origin)
 	oqs.SendEDU(ctx, partition, edu, targetDomain, roomID)
 }
 
+// serverAllowed returns false if the m.room.server_acl of the room denies the
+// domain. EDUs that aren't bound to a room have an empty room ID.
+func (c *FederationSender) serverAllowed(ctx context.Context, roomID, domain string) bool {
+	if roomID == "" || c.rsRepo == nil {
+		return true
+	}
+	rs := c.rsRepo.GetRoomState(ctx, roomID)
+	if rs == nil {
+		return true
+	}
+	acl, err := gomatrixserverlib.NewServerACLFromEvent(rs.ServerACL)
+	if err != nil {
+		log.Warnf("fed-sender room %s has an invalid server ACL: %v", roomID, err)
+		return true
+	}
+	return acl.IsServerAllowed(domain)
+}
+
 func (c *FederationSender) sendEdu(ctx context.Context, edu *gomatrixserverlib.EDU) {
 	var idx uint32
 	roomID := ""
@@ -135,6 +135,8 @@ func (s *SyncConsumer) processRequest(ctx context.Context, request *FedEventExtr
 		response = SendLeave(ctx, s.fedClient, &request.FedEvent, destination, s.backfill)
 	} else if request.Subject == s.cfg.Rpc.FedRsInviteTopic {
 		response = SendInvite(ctx, s.fedClient, &request.FedEvent, destination)
+	} else if request.Subject == s.cfg.Rpc.FedTimestampToEventTopic {
+		response = TimestampToEvent(ctx, s.fedClient, &request.FedEvent, destination)
 	}
 
 	s.rpcClient.PubObj(request.FedEvent.Reply, response)
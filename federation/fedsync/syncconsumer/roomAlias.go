@@ -81,3 +81,23 @@ func GetRoomState(
 	return fedResp
 	// s.rpcClient.PubObj(reply, response)
 }
+
+func TimestampToEvent(
+	ctx context.Context,
+	fedClient *client.FedClientWrap,
+	request *roomserverapi.FederationEvent,
+	destination string,
+) gomatrixserverlib.RespTimestampToEvent {
+	var tsReq external.GetFedTimestampToEventRequest
+	if err := json.Unmarshal(request.Extra, &tsReq); err != nil {
+		log.Errorf("federation TimestampToEvent unmarshal error: %v", err)
+		return gomatrixserverlib.RespTimestampToEvent{}
+	}
+
+	fedResp, err := fedClient.TimestampToEvent(ctx, destination, tsReq.RoomID, tsReq.TS, tsReq.Dir)
+	if err != nil {
+		log.Errorf("federation TimestampToEvent error %v", err)
+		return gomatrixserverlib.RespTimestampToEvent{}
+	}
+	return fedResp
+}
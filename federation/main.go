@@ -234,11 +234,17 @@ func startFedMonolith() {
 	}
 	encrytionDB := edb.(model.EncryptorAPIDatabase)
 
+	sdb, err := common.GetDBInstance("syncapi", &cfg)
+	if err != nil {
+		log.Panicw("failed to connect to sync db", log.KeysAndValues{"error", err})
+	}
+	syncDB := sdb.(model.SyncAPIDatabase)
+
 	publicroomsAPI := rpc.NewFedPublicRoomsRpcClient(&cfg, rpcClient)
 
 	fedAPIEntry := federationapi.NewFederationAPIComponent(&cfg, cache, fedClient, fedDB, keyDB,
 		feddomains, fedRpcCli, backfillRepo, joinRoomsRepo, backfill, publicroomsAPI,
-		rpcClient, encrytionDB, certInfo, idg, complexCache)
+		rpcClient, encrytionDB, syncDB, certInfo, idg, complexCache)
 
 	//subject := fmt.Sprintf("%s.%s", fed.cfg.GetMsgBusReqTopic(), ">")
 	//fed.NatsBus.SubRegister(subject, "federation-msgbus")
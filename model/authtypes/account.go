@@ -32,6 +32,16 @@ type Account struct {
 	// TODO: Associations (e.g. with application services)
 }
 
+// AccountSummary is an account as listed by the admin API
+type AccountSummary struct {
+	UserID      string `json:"user_id"`
+	DisplayName string `json:"displayname,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	CreatedTs   int64  `json:"created_ts"`
+	Deactivated bool   `json:"deactivated"`
+	Locked      bool   `json:"locked"`
+}
+
 type RoomTagCacheData struct {
 	UserID  string
 	RoomID  string
@@ -27,4 +27,16 @@ type Device struct {
 	Identifier   string `json:"identifier,omitempty"`
 	CreateTs     int64  `json:"create_ts,omitempty"`
 	LastActiveTs int64  `json:"last_active_ts,omitempty"`
+	ExpiresTs    int64  `json:"expires_ts,omitempty"`
+}
+
+// RefreshToken is a refresh token issued to a device, it describes the
+// access tokens to mint when it is used.
+type RefreshToken struct {
+	UserID     string
+	DeviceID   string
+	DeviceType string
+	Identifier string
+	IsHuman    bool
+	ExpiresTs  int64
 }
@@ -23,6 +23,12 @@ const (
 	LoginTypeSharedSecret = "org.matrix.login.shared_secret"
 	LoginTypeRecaptcha    = "m.login.recaptcha"
 	LoginTypePassword     = "m.login.password"
+	LoginTypeEmail        = "m.login.email.identity"
+	LoginTypeJWT          = "org.matrix.login.jwt"
+	LoginTypeSSO          = "m.login.sso"
+	LoginTypeToken        = "m.login.token"
+
+	LoginTypeRegistrationToken = "m.login.registration_token"
 
 	LoginTypeApplicationService = "m.login.application_service"
 )
@@ -0,0 +1,33 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package authtypes
+
+// RegistrationToken lets a user complete the m.login.registration_token
+// registration stage. UsesAllowed and ExpiryTs are 0 when unlimited.
+type RegistrationToken struct {
+	Token       string
+	UsesAllowed int64
+	Completed   int64
+	ExpiryTs    int64
+	CreatedTs   int64
+}
+
+// IsValid reports whether the token can still be used at now (ms).
+func (t *RegistrationToken) IsValid(now int64) bool {
+	if t.ExpiryTs > 0 && now >= t.ExpiryTs {
+		return false
+	}
+	return t.UsesAllowed == 0 || t.Completed < t.UsesAllowed
+}
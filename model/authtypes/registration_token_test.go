@@ -0,0 +1,36 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package authtypes
+
+import "testing"
+
+func TestRegistrationTokenIsValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		token RegistrationToken
+		valid bool
+	}{
+		{"unlimited", RegistrationToken{}, true},
+		{"uses left", RegistrationToken{UsesAllowed: 2, Completed: 1}, true},
+		{"used up", RegistrationToken{UsesAllowed: 2, Completed: 2}, false},
+		{"not expired", RegistrationToken{ExpiryTs: 2000}, true},
+		{"expired", RegistrationToken{ExpiryTs: 1000}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.token.IsValid(1000); got != tt.valid {
+			t.Errorf("%s: IsValid() = %v, want %v", tt.name, got, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package authtypes
+
+// ThreePID is a third party identifier bound to an account
+type ThreePID struct {
+	Medium  string
+	Address string
+	AddedAt int64
+}
+
+// ThreePIDSession is a pending or finished validation of a third party identifier
+type ThreePIDSession struct {
+	SID          string
+	ClientSecret string
+	Medium       string
+	Address      string
+	Token        string
+	SendAttempt  int64
+	NextLink     string
+	CreatedAt    int64
+	ValidatedAt  int64
+}
@@ -69,6 +69,8 @@ const (
 	CMD_FED_CLIENT_KEYS
 	CMD_FED_CLIENT_KEYS_CLAIM
 	CMD_FED_EXCHANGE_THIRD_PARTY_INVITE
+	CMD_FED_OPENID_USERINFO
+	CMD_FED_TIMESTAMP_TO_EVENT
 )
 
 const (
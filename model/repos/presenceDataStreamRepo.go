@@ -38,6 +38,9 @@ type PresenceDataStreamRepo struct {
 	loading      sync.Map
 	cfg          *config.Dendrite
 	onlineRepo   *OnlineUserRepo
+	// presence lists, user -> target -> refresh position and target -> users
+	listTargets  sync.Map
+	listWatchers sync.Map
 
 	queryHitCounter mon.LabeledCounter
 }
@@ -99,6 +102,12 @@ func (tl *PresenceDataStreamRepo) AddPresenceDataStream(ctx context.Context,
 				return true
 			})
 		}
+		if watchers, ok := tl.listWatchers.Load(dataStream.UserID); ok {
+			watchers.(*sync.Map).Range(func(key, _ interface{}) bool {
+				tl.UpdateUserMaxPos(key.(string), offset)
+				return true
+			})
+		}
 	}
 }
 
@@ -169,52 +178,147 @@ func (tl *PresenceDataStreamRepo) CheckLoadReady(ctx context.Context, userID str
 
 func (tl *PresenceDataStreamRepo) loadHistory(ctx context.Context, userID string) {
 	defer tl.loading.Delete(userID)
-	userMap := tl.userTimeLine.GetFriendShip(ctx, userID, true)
-	if userMap != nil {
-		var users []string
-		maxPos := int64(0)
-		userMap.Range(func(key, _ interface{}) bool {
-			if presence, ok := tl.repo.Load(key.(string)); !ok {
-				users = append(users, key.(string))
-			} else {
-				if maxPos < presence.(*feedstypes.PresenceDataStream).Offset {
-					maxPos = presence.(*feedstypes.PresenceDataStream).Offset
-				}
-			}
-			return true
-		})
-		if len(users) > 0 {
-			bs := time.Now().UnixNano() / 1000000
-			streams, offsets, err := tl.persist.GetUserPresenceDataStream(ctx, users)
-			spend := time.Now().UnixNano()/1000000 - bs
-			if err != nil {
-				log.Errorf("load db failed PresenceDataStreamRepo history user:%s spend:%d ms err:%v", userID, spend, err)
-				return
-			}
-			if spend > types.DB_EXCEED_TIME {
-				log.Warnf("load db exceed %d ms PresenceDataStreamRepo history user:%s spend:%d ms", types.DB_EXCEED_TIME, userID, spend)
-			} else {
-				log.Infof("load db succ PresenceDataStreamRepo history user:%s spend:%d ms", userID, spend)
+	var users []string
+	maxPos := int64(0)
+	tl.RangePresenceUsers(ctx, userID, func(user string) bool {
+		if presence, ok := tl.repo.Load(user); !ok {
+			users = append(users, user)
+		} else {
+			if maxPos < presence.(*feedstypes.PresenceDataStream).Offset {
+				maxPos = presence.(*feedstypes.PresenceDataStream).Offset
 			}
+		}
+		return true
+	})
+	if len(users) > 0 {
+		bs := time.Now().UnixNano() / 1000000
+		streams, offsets, err := tl.persist.GetUserPresenceDataStream(ctx, users)
+		spend := time.Now().UnixNano()/1000000 - bs
+		if err != nil {
+			log.Errorf("load db failed PresenceDataStreamRepo history user:%s spend:%d ms err:%v", userID, spend, err)
+			return
+		}
+		if spend > types.DB_EXCEED_TIME {
+			log.Warnf("load db exceed %d ms PresenceDataStreamRepo history user:%s spend:%d ms", types.DB_EXCEED_TIME, userID, spend)
+		} else {
+			log.Infof("load db succ PresenceDataStreamRepo history user:%s spend:%d ms", userID, spend)
+		}
 
-			for idx := range streams {
-				tl.AddPresenceDataStream(ctx, &streams[idx], offsets[idx], false)
-				if offsets[idx] > maxPos {
-					maxPos = offsets[idx]
-				}
+		for idx := range streams {
+			tl.AddPresenceDataStream(ctx, &streams[idx], offsets[idx], false)
+			if offsets[idx] > maxPos {
+				maxPos = offsets[idx]
 			}
 		}
-		if val, ok := tl.maxPosition.Load(userID); ok {
-			if val.(int64) < maxPos {
-				tl.maxPosition.Store(userID, maxPos)
-			}
-		} else {
+	}
+	if val, ok := tl.maxPosition.Load(userID); ok {
+		if val.(int64) < maxPos {
 			tl.maxPosition.Store(userID, maxPos)
 		}
+	} else {
+		tl.maxPosition.Store(userID, maxPos)
 	}
 	tl.ready.Store(userID, true)
 }
 
+// RangePresenceUsers calls f once for every user whose presence userID
+// receives: the users sharing a room with it and the users of its presence list.
+func (tl *PresenceDataStreamRepo) RangePresenceUsers(ctx context.Context, userID string, f func(user string) bool) {
+	friends := tl.userTimeLine.GetFriendShip(ctx, userID, true)
+	next := true
+	if friends != nil {
+		friends.Range(func(key, _ interface{}) bool {
+			next = f(key.(string))
+			return next
+		})
+	}
+	if !next {
+		return
+	}
+	if targets, ok := tl.listTargets.Load(userID); ok {
+		targets.(*sync.Map).Range(func(key, _ interface{}) bool {
+			if friends != nil {
+				if _, ok := friends.Load(key); ok {
+					return true
+				}
+			}
+			return f(key.(string))
+		})
+	}
+}
+
+// AddPresenceList subscribes userID to the presence of targets.
+func (tl *PresenceDataStreamRepo) AddPresenceList(userID string, targets []string) {
+	val, _ := tl.listTargets.LoadOrStore(userID, new(sync.Map))
+	for _, target := range targets {
+		val.(*sync.Map).LoadOrStore(target, int64(0))
+		watchers, _ := tl.listWatchers.LoadOrStore(target, new(sync.Map))
+		watchers.(*sync.Map).Store(userID, true)
+	}
+}
+
+func (tl *PresenceDataStreamRepo) RemovePresenceList(userID string, targets []string) {
+	val, ok := tl.listTargets.Load(userID)
+	for _, target := range targets {
+		if ok {
+			val.(*sync.Map).Delete(target)
+		}
+		if watchers, ok := tl.listWatchers.Load(target); ok {
+			watchers.(*sync.Map).Delete(userID)
+		}
+	}
+}
+
+// RefreshPresenceList makes the next incremental sync of userID return the
+// current presence of targets even when it is older than the sync token. The
+// presence keeps its offset, only userID is given the new position pos.
+func (tl *PresenceDataStreamRepo) RefreshPresenceList(ctx context.Context, userID string, targets []string, pos int64) {
+	var missing []string
+	for _, target := range targets {
+		if _, ok := tl.repo.Load(target); !ok {
+			missing = append(missing, target)
+		}
+	}
+	if len(missing) > 0 {
+		streams, offsets, err := tl.persist.GetUserPresenceDataStream(ctx, missing)
+		if err != nil {
+			log.Errorf("PresenceDataStreamRepo load presence list of user:%s err:%v", userID, err)
+		}
+		for idx := range streams {
+			tl.AddPresenceDataStream(ctx, &streams[idx], offsets[idx], false)
+		}
+	}
+
+	val, ok := tl.listTargets.Load(userID)
+	if !ok {
+		return
+	}
+	refreshed := false
+	for _, target := range targets {
+		if tl.GetHistoryByUserID(target) == nil {
+			continue
+		}
+		if _, ok := val.(*sync.Map).Load(target); ok {
+			val.(*sync.Map).Store(target, pos)
+			refreshed = true
+		}
+	}
+	if refreshed {
+		tl.UpdateUserMaxPos(userID, pos)
+	}
+}
+
+// GetPresenceListPos returns the position at which the presence of target
+// was last refreshed for userID, 0 when it never was.
+func (tl *PresenceDataStreamRepo) GetPresenceListPos(userID, target string) int64 {
+	if val, ok := tl.listTargets.Load(userID); ok {
+		if pos, ok := val.(*sync.Map).Load(target); ok {
+			return pos.(int64)
+		}
+	}
+	return 0
+}
+
 func (tl *PresenceDataStreamRepo) GetHistoryByUserID(userID string) *feedstypes.PresenceDataStream {
 	if tl.repo == nil {
 		return nil
@@ -177,6 +177,14 @@ func (tl *RoomHistoryTimeLineRepo) GetHistory(ctx context.Context, roomID string
 	return tl.repo.getTimeLine(roomID)
 }
 
+// RemoveHistory drops the loaded history of roomID, it is loaded again on the
+// next access
+func (tl *RoomHistoryTimeLineRepo) RemoveHistory(roomID string) {
+	tl.repo.remove(roomID)
+	tl.ready.Delete(roomID)
+	tl.roomMinStream.Delete(roomID)
+}
+
 func (tl *RoomHistoryTimeLineRepo) GetStreamEv(ctx context.Context, roomID, eventId string) *feedstypes.StreamEvent {
 	history := tl.GetHistory(ctx, roomID)
 	if history == nil {
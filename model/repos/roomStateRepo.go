@@ -48,6 +48,7 @@ type RoomState struct {
 	canonicalAlias       string
 	power                *common.PowerLevelContent
 	isEncrypted          bool
+	retention            *common.RetentionContent
 
 	join   sync.Map
 	leave  sync.Map
@@ -170,6 +171,10 @@ func (rs *RoomState) onEvent(ev *gomatrixserverlib.ClientEvent, offset int64, ba
 			rs.canonicalAlias = alias.Alias
 		case "m.room.encryption":
 			rs.isEncrypted = true
+		case "m.room.retention":
+			retention := common.RetentionContent{}
+			json.Unmarshal(ev.Content, &retention)
+			rs.retention = &retention
 		}
 
 		if common.IsStateClientEv(ev) {
@@ -527,6 +532,11 @@ func (rs *RoomState) GetPowerLevels() *common.PowerLevelContent {
 	return rs.power
 }
 
+// GetRetention is nil if the room has no m.room.retention
+func (rs *RoomState) GetRetention() *common.RetentionContent {
+	return rs.retention
+}
+
 func (rs *RoomState) GetJoinMap() *sync.Map {
 	return &rs.join
 }
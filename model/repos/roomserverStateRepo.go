@@ -71,18 +71,23 @@ type RoomServerState struct {
 	Power             *gomatrixserverlib.Event `json:"power_ev"`
 	GuestAccess       *gomatrixserverlib.Event `json:"guest_access"`
 
-	Avatar *gomatrixserverlib.Event `json:"avatar_ev"`
-	Pin    *gomatrixserverlib.Event `json:"pin_ev"`
+	Avatar     *gomatrixserverlib.Event `json:"avatar_ev"`
+	Pin        *gomatrixserverlib.Event `json:"pin_ev"`
+	Encryption *gomatrixserverlib.Event `json:"encryption_ev"`
+	Tombstone  *gomatrixserverlib.Event `json:"tombstone_ev"`
+	ServerACL  *gomatrixserverlib.Event `json:"server_acl_ev"`
 
 	join        sync.Map
 	leave       sync.Map
 	invite      sync.Map
 	thirdInvite sync.Map
+	spaceChild  sync.Map
 
 	JoinExport        map[string]*gomatrixserverlib.Event `json:"join_map"`
 	LeaveExport       map[string]*gomatrixserverlib.Event `json:"leave_map"`
 	InviteExport      map[string]*gomatrixserverlib.Event `json:"invite_map"`
 	ThirdInviteExport map[string]*gomatrixserverlib.Event `json:"third_invite_map"`
+	SpaceChildExport  map[string]*gomatrixserverlib.Event `json:"space_child_map"`
 	DomainsExport     map[string]*DomainTLItem            `json:"domains"`
 
 	domainTl sync.Map
@@ -128,6 +133,15 @@ func (rs *RoomServerState) GetAllState() []gomatrixserverlib.Event {
 	if rs.GuestAccess != nil {
 		res = append(res, *rs.GuestAccess)
 	}
+	if rs.Encryption != nil {
+		res = append(res, *rs.Encryption)
+	}
+	if rs.Tombstone != nil {
+		res = append(res, *rs.Tombstone)
+	}
+	if rs.ServerACL != nil {
+		res = append(res, *rs.ServerACL)
+	}
 	rs.join.Range(func(key, value interface{}) bool {
 		res = append(res, *value.(*gomatrixserverlib.Event))
 		return true
@@ -144,6 +158,10 @@ func (rs *RoomServerState) GetAllState() []gomatrixserverlib.Event {
 		res = append(res, *value.(*gomatrixserverlib.Event))
 		return true
 	})
+	rs.spaceChild.Range(func(key, value interface{}) bool {
+		res = append(res, *value.(*gomatrixserverlib.Event))
+		return true
+	})
 
 	sort.Sort(res)
 	return res
@@ -255,6 +273,15 @@ func (rs *RoomServerState) GetPreEvent(ev *gomatrixserverlib.Event) (*gomatrixse
 	case "m.room.pinned_events":
 		return rs.Pin, true
 	case "m.room.encryption":
+		return rs.Encryption, true
+	case "m.room.tombstone":
+		return rs.Tombstone, true
+	case "m.room.server_acl":
+		return rs.ServerACL, true
+	case "m.space.child":
+		if val, ok := rs.spaceChild.Load(*ev.StateKey()); ok {
+			return val.(*gomatrixserverlib.Event), true
+		}
 		return nil, true
 	}
 
@@ -369,6 +396,7 @@ func (rs *RoomServerState) serialize() ([]byte, error) {
 	rs.LeaveExport = make(map[string]*gomatrixserverlib.Event)
 	rs.InviteExport = make(map[string]*gomatrixserverlib.Event)
 	rs.ThirdInviteExport = make(map[string]*gomatrixserverlib.Event)
+	rs.SpaceChildExport = make(map[string]*gomatrixserverlib.Event)
 	rs.DomainsExport = make(map[string]*DomainTLItem)
 
 	rs.join.Range(func(key, value interface{}) bool {
@@ -387,6 +415,10 @@ func (rs *RoomServerState) serialize() ([]byte, error) {
 		rs.ThirdInviteExport[key.(string)] = value.(*gomatrixserverlib.Event)
 		return true
 	})
+	rs.spaceChild.Range(func(key, value interface{}) bool {
+		rs.SpaceChildExport[key.(string)] = value.(*gomatrixserverlib.Event)
+		return true
+	})
 
 	var item *DomainTLItem
 	rs.domainTl.Range(func(key, value interface{}) bool {
@@ -512,6 +544,13 @@ func (rs *RoomServerState) onEventUpdate(ev *gomatrixserverlib.Event, offset int
 		rs.Pin = ev
 	case "m.room.encryption":
 		rs.IsEncrypted = true
+		rs.Encryption = ev
+	case "m.room.tombstone":
+		rs.Tombstone = ev
+	case "m.room.server_acl":
+		rs.ServerACL = ev
+	case "m.space.child":
+		rs.spaceChild.Store(*ev.StateKey(), ev)
 	case "m.room.guest_access":
 		rs.GuestAccess = ev
 	}
@@ -578,6 +617,10 @@ func (rs *RoomServerState) GetThirdInviteMap() *sync.Map {
 	return &rs.thirdInvite
 }
 
+func (rs *RoomServerState) GetSpaceChildMap() *sync.Map {
+	return &rs.spaceChild
+}
+
 func (rs *RoomServerState) GetDomainTlMap() *sync.Map {
 	return &rs.domainTl
 }
@@ -716,6 +759,18 @@ func (repo *RoomServerCurStateRepo) parseCacheRoomState(rs *RoomServerState) {
 		states = append(states, rs.GuestAccess)
 		rs.GuestAccess = nil
 	}
+	if rs.Encryption != nil {
+		states = append(states, rs.Encryption)
+		rs.Encryption = nil
+	}
+	if rs.Tombstone != nil {
+		states = append(states, rs.Tombstone)
+		rs.Tombstone = nil
+	}
+	if rs.ServerACL != nil {
+		states = append(states, rs.ServerACL)
+		rs.ServerACL = nil
+	}
 	if rs.JoinExport != nil {
 		for _, v := range rs.JoinExport {
 			states = append(states, v)
@@ -736,6 +791,11 @@ func (repo *RoomServerCurStateRepo) parseCacheRoomState(rs *RoomServerState) {
 			states = append(states, v)
 		}
 	}
+	if rs.SpaceChildExport != nil {
+		for _, v := range rs.SpaceChildExport {
+			states = append(states, v)
+		}
+	}
 	sort.Slice(states, func(i, j int) bool { return states[i].EventNID() < states[j].EventNID() })
 	for _, v := range states {
 		rs.onEvent(v, v.EventNID(), true)
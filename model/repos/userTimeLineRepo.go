@@ -600,6 +600,33 @@ func (tl *UserTimeLineRepo) GetHistory(ctx context.Context, user string) *feedst
 	return tl.repo.getTimeLine(user)
 }
 
+// RemoveRoomHistory drops the loaded timelines having events of roomID, they
+// are loaded again on the next access
+func (tl *UserTimeLineRepo) RemoveRoomHistory(roomID string) {
+	tl.ready.Range(func(key, value interface{}) bool {
+		user := key.(string)
+		timeLine := tl.repo.getTimeLine(user)
+		if timeLine == nil {
+			return true
+		}
+		found := false
+		timeLine.ForRange(func(offset int, feed feedstypes.Feed) bool {
+			if feed != nil && feed.(*feedstypes.TimeLineEvent).Ev.RoomID == roomID {
+				found = true
+				return false
+			}
+			return true
+		})
+		if found {
+			tl.repo.remove(user)
+			tl.ready.Delete(user)
+			tl.userMinPos.Delete(user)
+			tl.userReady.Delete(user)
+		}
+		return true
+	})
+}
+
 func (tl *UserTimeLineRepo) GetUserRange(ctx context.Context, user string) (int64, int64) {
 	timeLine := tl.GetHistory(ctx, user)
 	if timeLine != nil {
@@ -50,3 +50,30 @@ type RoomNIDs struct {
 	RoomID  string
 	RoomNID int64
 }
+
+type RoomJoinedCount struct {
+	RoomID        string
+	RoomNID       int64
+	JoinedMembers int64
+}
+
+// EventReport is an event reported by a user, EventJSON is the event as it
+// was when reported. ResolvedTs is 0 while the report is open.
+type EventReport struct {
+	ID         int64
+	RoomID     string
+	EventID    string
+	Sender     string
+	Reporter   string
+	Reason     string
+	Score      int
+	EventJSON  []byte
+	ReceivedTs int64
+	Assignee   string
+	ResolvedBy string
+	ResolvedTs int64
+	Resolution string
+	// The redaction sent and whether the sender was kicked by the moderator
+	RedactionEventID string
+	Kicked           bool
+}
@@ -113,6 +113,17 @@ type Cache interface {
 	DelPwdChangeDevice(deviceID, userID string) error
 	ExpirePwdChangeDevice(userID string) error
 
+	GetLoginFailCount(userID string) (int64, error)
+	IncrLoginFailCount(userID string, expire int64) (int64, error)
+	DelLoginFailCount(userID string) error
+
+	SetSSOSession(state, session string, expire int64) error
+	TakeSSOSession(state string) (string, error)
+	SetLoginToken(token, userID string, expire int64) error
+	TakeLoginToken(token string) (string, error)
+	SetOpenIDToken(token, userID string, expire int64) error
+	GetOpenIDToken(token string) (string, error)
+
 	GetSetting(settingKey string) (int64, error)
 	GetSettingRaw(settingKey string) (string, error)
 	SetSetting(settingKey string, val string) error
@@ -137,6 +148,7 @@ type Cache interface {
 	//txn
 	GetTxnID(roomID, msgID string) (string, bool)
 	PutTxnID(roomID, txnID, eventID string) error
+	DelTxnID(roomID string) error
 	ScanTxnID(cursor uint64, count int) ([]string, uint64, error)
 
 	//roomusermembership
@@ -121,6 +121,10 @@ type QueryRoomStateResponse struct {
 	ThirdInvite       map[string]*gomatrixserverlib.Event `json:"third_invite_map"`
 	Avatar            *gomatrixserverlib.Event            `json:"avatar_ev"`
 	GuestAccess       *gomatrixserverlib.Event            `json:"guest_access"`
+	Encryption        *gomatrixserverlib.Event            `json:"encryption_ev"`
+	Tombstone         *gomatrixserverlib.Event            `json:"tombstone_ev"`
+	ServerACL         *gomatrixserverlib.Event            `json:"server_acl_ev"`
+	SpaceChild        map[string]*gomatrixserverlib.Event `json:"space_child_map"`
 }
 
 type RoomserverRpcRequest struct {
@@ -147,6 +151,9 @@ func (rs *QueryRoomStateResponse) InitFromEvents(events []gomatrixserverlib.Even
 	if rs.ThirdInvite == nil {
 		rs.ThirdInvite = make(map[string]*gomatrixserverlib.Event)
 	}
+	if rs.SpaceChild == nil {
+		rs.SpaceChild = make(map[string]*gomatrixserverlib.Event)
+	}
 
 	for idx, ev := range events {
 		if ev.Type() == "m.room.create" {
@@ -175,8 +182,16 @@ func (rs *QueryRoomStateResponse) InitFromEvents(events []gomatrixserverlib.Even
 			rs.Avatar = &events[idx]
 		} else if ev.Type() == "m.room.guest_access" {
 			rs.GuestAccess = &events[idx]
+		} else if ev.Type() == "m.room.encryption" {
+			rs.Encryption = &events[idx]
+		} else if ev.Type() == "m.room.tombstone" {
+			rs.Tombstone = &events[idx]
+		} else if ev.Type() == "m.room.server_acl" {
+			rs.ServerACL = &events[idx]
 		} else if ev.Type() == "m.room.third_party_invite" {
 			rs.ThirdInvite[*ev.StateKey()] = &events[idx]
+		} else if ev.Type() == "m.space.child" {
+			rs.SpaceChild[*ev.StateKey()] = &events[idx]
 		} else if ev.Type() == "m.room.member" {
 			member := external.MemberContent{}
 			json.Unmarshal(ev.Content(), &member)
@@ -225,6 +240,15 @@ func (rs *QueryRoomStateResponse) GetAllState() []gomatrixserverlib.Event {
 	if rs.GuestAccess != nil {
 		res = append(res, *rs.GuestAccess)
 	}
+	if rs.Encryption != nil {
+		res = append(res, *rs.Encryption)
+	}
+	if rs.Tombstone != nil {
+		res = append(res, *rs.Tombstone)
+	}
+	if rs.ServerACL != nil {
+		res = append(res, *rs.ServerACL)
+	}
 	for _, value := range rs.Join {
 		res = append(res, *value)
 	}
@@ -237,6 +261,9 @@ func (rs *QueryRoomStateResponse) GetAllState() []gomatrixserverlib.Event {
 	for _, value := range rs.ThirdInvite {
 		res = append(res, *value)
 	}
+	for _, value := range rs.SpaceChild {
+		res = append(res, *value)
+	}
 
 	sort.Sort(res)
 	return res
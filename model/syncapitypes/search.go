@@ -0,0 +1,52 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package syncapitypes
+
+// EventSearchResult is an event matching a /search term
+type EventSearchResult struct {
+	EventID        string
+	RoomID         string
+	Sender         string
+	Type           string
+	OriginServerTS int64
+	Offset         int64
+	Rank           float64
+}
+
+// EventSearchFilter narrows the count of /search results to the senders and
+// types of the room event filter, empty lists don't restrict
+type EventSearchFilter struct {
+	Senders    []string
+	NotSenders []string
+	Types      []string
+	NotTypes   []string
+}
+
+// VisibilityRange is a span of origin_server_ts in which a user may see the
+// events of a room, End is -1 while the span is open.
+type VisibilityRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// IsVisible checks ts against the ranges the way RoomState.CheckEventVisibility does
+func IsVisible(ranges []VisibilityRange, ts int64) bool {
+	for _, r := range ranges {
+		if ts >= r.Start && (r.End == -1 || ts <= r.End) {
+			return true
+		}
+	}
+	return false
+}
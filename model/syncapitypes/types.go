@@ -88,6 +88,8 @@ type SyncServerResponse struct {
 	NewUsers         []string         `json:"new_users,omitempty"`
 	Ready            bool             `json:"ready,omitempty"`
 	MaxRoomOffset    map[string]int64 `json:"max_room_offset,omitempty"`
+	// The visibility ranges of the user in the rooms of a "visibility" request
+	Visibility map[string][]VisibilityRange `json:"visibility,omitempty"`
 }
 
 type ToDevice struct {
@@ -298,6 +300,46 @@ func (r *MessageEventResp) Decode(input []byte) error {
 	return json.Unmarshal(input, r)
 }
 
+type RelationsResp struct {
+	Chunk     []gomatrixserverlib.ClientEvent `json:"chunk"`
+	NextBatch string                          `json:"next_batch,omitempty"`
+	PrevBatch string                          `json:"prev_batch,omitempty"`
+}
+
+func (r *RelationsResp) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *RelationsResp) Decode(input []byte) error {
+	return json.Unmarshal(input, r)
+}
+
+type ThreadsResp struct {
+	Chunk     []gomatrixserverlib.ClientEvent `json:"chunk"`
+	NextBatch string                          `json:"next_batch,omitempty"`
+}
+
+func (r *ThreadsResp) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *ThreadsResp) Decode(input []byte) error {
+	return json.Unmarshal(input, r)
+}
+
+type TimestampToEventResp struct {
+	EventID        string `json:"event_id"`
+	OriginServerTS int64  `json:"origin_server_ts"`
+}
+
+func (r *TimestampToEventResp) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *TimestampToEventResp) Decode(input []byte) error {
+	return json.Unmarshal(input, r)
+}
+
 type ContextEventResp struct {
 	Start     string                           `json:"start"`
 	End       string                           `json:"end"`
@@ -0,0 +1,46 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package syncapitypes
+
+import (
+	"strings"
+
+	"github.com/finogeeks/ligase/common/pinyin"
+)
+
+// UserDirectoryEntry is what /user_directory/search matches against
+type UserDirectoryEntry struct {
+	UserID      string
+	DisplayName string
+	AvatarURL   string
+	UserName    string
+	JobNumber   string
+	Email       string
+}
+
+// SearchText returns the lowercased fields of the entry, one per line,
+// with the full pinyin and pinyin initials of the names which have hanzi,
+// for every reading of their surname.
+func (e *UserDirectoryEntry) SearchText() string {
+	fields := []string{e.UserID, e.DisplayName, e.UserName, e.JobNumber, e.Email}
+	for _, name := range []string{e.DisplayName, e.UserName} {
+		if pinyin.HasHanzi(name) {
+			full, initials := pinyin.Name(name)
+			fields = append(fields, full...)
+			fields = append(fields, initials...)
+		}
+	}
+	return strings.ToLower(strings.Join(fields, "\n"))
+}
@@ -43,6 +43,9 @@ var FilterTokenTopicDef = "filter-token-topic"
 var DeviceStateUpdateDef = "sync-device-state-update-topic"
 var VerifyTokenTopicDef = "proxy-verify-token-topic"
 var PresenceTopicDef = "sync-presence-topic"
+var PresenceListUpdateTopicDef = "sync-presence-list-update-topic"
+var RoomHistoryPurgeTopicDef = "sync-room-history-purge-topic"
+var RoomBlockedTopicDef = "roomserver-room-blocked-topic"
 var RCSEventTopicDef = "rcs-event-topic"
 
 const (
@@ -334,6 +337,7 @@ type RedactUnsigned struct {
 	TransactionID   string                         `json:"transaction_id,omitempty"`
 	RedactedBecause *gomatrixserverlib.ClientEvent `json:"redacted_because,omitempty"`
 	UpdatedBecause  *gomatrixserverlib.ClientEvent `json:"updated_because,omitempty"`
+	Relations       *EventRelations                `json:"m.relations,omitempty"`
 }
 
 type Unsigned struct {
@@ -373,6 +377,25 @@ type OnlinePresence struct {
 	ExtStatusMsg string `json:"ext_status_msg,omitempty"`
 }
 
+// PresenceListUpdate tells the sync aggregates that a presence list changed
+type PresenceListUpdate struct {
+	UserID string   `json:"user_id"`
+	Invite []string `json:"invite,omitempty"`
+	Drop   []string `json:"drop,omitempty"`
+}
+
+// RoomHistoryPurge tells the sync components that the history of a room
+// before BeforeTS was purged
+type RoomHistoryPurge struct {
+	RoomID   string `json:"room_id"`
+	BeforeTS int64  `json:"before_ts"`
+}
+
+// RoomBlocked tells the roomservers that a room was shut down and blocked
+type RoomBlocked struct {
+	RoomID string `json:"room_id"`
+}
+
 type RoomStateExt struct {
 	PreStateId  string `json:"pre_state_id"`
 	LastStateId string `json:"last_state_id"`
@@ -0,0 +1,253 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	jsonRaw "encoding/json"
+
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+)
+
+const (
+	RelTypeAnnotation = "m.annotation"
+	RelTypeReplace    = "m.replace"
+	RelTypeThread     = "m.thread"
+)
+
+// RelatesTo is the m.relates_to of an event content
+type RelatesTo struct {
+	RelType string `json:"rel_type,omitempty"`
+	EventID string `json:"event_id,omitempty"`
+	Key     string `json:"key,omitempty"`
+}
+
+type relatesToContent struct {
+	RelatesTo *RelatesTo `json:"m.relates_to,omitempty"`
+}
+
+// GetRelatesTo returns the relation of the event content, nil if it relates
+// to no event. Replies without a rel_type aren't relations.
+func GetRelatesTo(content []byte) *RelatesTo {
+	con := relatesToContent{}
+	if err := json.Unmarshal(content, &con); err != nil || con.RelatesTo == nil {
+		return nil
+	}
+	if con.RelatesTo.RelType == "" || con.RelatesTo.EventID == "" {
+		return nil
+	}
+	return con.RelatesTo
+}
+
+// EventRelations is the unsigned m.relations of an event, the server side
+// aggregation of the events relating to it.
+type EventRelations struct {
+	Annotation *AnnotationAggregation `json:"m.annotation,omitempty"`
+	Replace    *ReplaceAggregation    `json:"m.replace,omitempty"`
+	Thread     *ThreadAggregation     `json:"m.thread,omitempty"`
+}
+
+type AnnotationAggregation struct {
+	Chunk []AnnotationChunk `json:"chunk"`
+}
+
+type AnnotationChunk struct {
+	Type  string `json:"type"`
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// ReplaceAggregation is the latest edit of the event
+type ReplaceAggregation struct {
+	EventID        string `json:"event_id"`
+	Sender         string `json:"sender"`
+	OriginServerTS int64  `json:"origin_server_ts"`
+}
+
+type ThreadAggregation struct {
+	LatestEvent             *gomatrixserverlib.ClientEvent `json:"latest_event,omitempty"`
+	Count                   int64                          `json:"count"`
+	CurrentUserParticipated bool                           `json:"current_user_participated"`
+	// Participants counts the replies of each sender in the stored event,
+	// RelationsForUser turns it into CurrentUserParticipated.
+	Participants map[string]int64 `json:"io.ligase.participants,omitempty"`
+}
+
+// Add counts ev, which relates to parent by rel, in the aggregation. Only
+// the sender of parent may edit it.
+func (r *EventRelations) Add(parent, ev *gomatrixserverlib.ClientEvent, rel *RelatesTo) {
+	switch rel.RelType {
+	case RelTypeAnnotation:
+		if r.Annotation == nil {
+			r.Annotation = &AnnotationAggregation{}
+		}
+		for i := range r.Annotation.Chunk {
+			chunk := &r.Annotation.Chunk[i]
+			if chunk.Type == ev.Type && chunk.Key == rel.Key {
+				chunk.Count++
+				return
+			}
+		}
+		r.Annotation.Chunk = append(r.Annotation.Chunk, AnnotationChunk{Type: ev.Type, Key: rel.Key, Count: 1})
+	case RelTypeReplace:
+		if ev.Sender != parent.Sender {
+			return
+		}
+		if r.Replace != nil && r.Replace.OriginServerTS > int64(ev.OriginServerTS) {
+			return
+		}
+		r.Replace = newReplaceAggregation(ev)
+	case RelTypeThread:
+		if r.Thread == nil {
+			r.Thread = &ThreadAggregation{}
+		}
+		if r.Thread.Participants == nil {
+			r.Thread.Participants = map[string]int64{}
+		}
+		r.Thread.Count++
+		r.Thread.Participants[ev.Sender]++
+		if r.Thread.LatestEvent == nil || r.Thread.LatestEvent.OriginServerTS <= ev.OriginServerTS {
+			r.Thread.LatestEvent = threadLatestEvent(ev)
+		}
+	}
+}
+
+// Remove takes the redacted ev out of the aggregation. latest is the edit or
+// the thread reply which is the latest one once ev is gone, nil if there is
+// none, it replaces ev if ev was the latest.
+func (r *EventRelations) Remove(ev *gomatrixserverlib.ClientEvent, rel *RelatesTo, latest *gomatrixserverlib.ClientEvent) {
+	switch rel.RelType {
+	case RelTypeAnnotation:
+		if r.Annotation == nil {
+			return
+		}
+		chunks := r.Annotation.Chunk[:0]
+		for _, chunk := range r.Annotation.Chunk {
+			if chunk.Type == ev.Type && chunk.Key == rel.Key {
+				chunk.Count--
+			}
+			if chunk.Count > 0 {
+				chunks = append(chunks, chunk)
+			}
+		}
+		r.Annotation.Chunk = chunks
+		if len(chunks) == 0 {
+			r.Annotation = nil
+		}
+	case RelTypeReplace:
+		if r.Replace == nil || r.Replace.EventID != ev.EventID {
+			return
+		}
+		r.Replace = nil
+		if latest != nil {
+			r.Replace = newReplaceAggregation(latest)
+		}
+	case RelTypeThread:
+		if r.Thread == nil {
+			return
+		}
+		r.Thread.Count--
+		if n := r.Thread.Participants[ev.Sender]; n > 1 {
+			r.Thread.Participants[ev.Sender] = n - 1
+		} else {
+			delete(r.Thread.Participants, ev.Sender)
+		}
+		if r.Thread.Count <= 0 {
+			r.Thread = nil
+		} else if r.Thread.LatestEvent != nil && r.Thread.LatestEvent.EventID == ev.EventID {
+			r.Thread.LatestEvent = nil
+			if latest != nil {
+				r.Thread.LatestEvent = threadLatestEvent(latest)
+			}
+		}
+	}
+}
+
+func newReplaceAggregation(ev *gomatrixserverlib.ClientEvent) *ReplaceAggregation {
+	return &ReplaceAggregation{
+		EventID:        ev.EventID,
+		Sender:         ev.Sender,
+		OriginServerTS: int64(ev.OriginServerTS),
+	}
+}
+
+func threadLatestEvent(ev *gomatrixserverlib.ClientEvent) *gomatrixserverlib.ClientEvent {
+	// the unsigned holds the transaction_id of the sender
+	latest := *ev
+	latest.Unsigned = nil
+	return &latest
+}
+
+func (r *EventRelations) IsEmpty() bool {
+	return r.Annotation == nil && r.Replace == nil && r.Thread == nil
+}
+
+// GetRelations returns the m.relations in the unsigned of an event
+func GetRelations(unsigned []byte) *EventRelations {
+	con := struct {
+		Relations *EventRelations `json:"m.relations,omitempty"`
+	}{}
+	if len(unsigned) == 0 || json.Unmarshal(unsigned, &con) != nil {
+		return nil
+	}
+	return con.Relations
+}
+
+// RelationsForUser returns the unsigned of an event sent by sender as it is
+// served to userID: current_user_participated of its thread is set, the
+// participants of the thread aren't served.
+func RelationsForUser(unsigned []byte, userID, sender string) []byte {
+	relations := GetRelations(unsigned)
+	if relations == nil || relations.Thread == nil {
+		return unsigned
+	}
+	updated, err := UpdateRelations(unsigned, func(r *EventRelations) {
+		r.Thread.CurrentUserParticipated = r.Thread.CurrentUserParticipated ||
+			sender == userID || r.Thread.Participants[userID] > 0
+		r.Thread.Participants = nil
+	})
+	if err != nil {
+		return unsigned
+	}
+	return updated
+}
+
+// UpdateRelations applies update to the m.relations in the unsigned of an
+// event, keeping the other unsigned fields.
+func UpdateRelations(unsigned []byte, update func(*EventRelations)) ([]byte, error) {
+	fields := map[string]jsonRaw.RawMessage{}
+	if len(unsigned) > 0 {
+		if err := json.Unmarshal(unsigned, &fields); err != nil {
+			return nil, err
+		}
+		if fields == nil {
+			fields = map[string]jsonRaw.RawMessage{}
+		}
+	}
+	relations := GetRelations(unsigned)
+	if relations == nil {
+		relations = &EventRelations{}
+	}
+	update(relations)
+	if relations.IsEmpty() {
+		delete(fields, "m.relations")
+	} else {
+		data, err := json.Marshal(relations)
+		if err != nil {
+			return nil, err
+		}
+		fields["m.relations"] = data
+	}
+	return jsonRaw.Marshal(fields)
+}
@@ -0,0 +1,159 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+)
+
+func TestUpdateRelations(t *testing.T) {
+	parent := gomatrixserverlib.ClientEvent{EventID: "$p", Type: "m.room.message", Sender: "@a:h"}
+	reaction := gomatrixserverlib.ClientEvent{EventID: "$r1", Type: "m.reaction"}
+	reactionContent := []byte(`{"m.relates_to":{"rel_type":"m.annotation","event_id":"$p","key":"👍"}}`)
+	edit := gomatrixserverlib.ClientEvent{EventID: "$e1", Type: "m.room.message", Sender: "@a:h", OriginServerTS: 10}
+	editContent := []byte(`{"m.relates_to":{"rel_type":"m.replace","event_id":"$p"}}`)
+
+	unsigned := []byte(`{"transaction_id":"t1"}`)
+	for _, c := range []struct {
+		ev      *gomatrixserverlib.ClientEvent
+		content []byte
+	}{{&reaction, reactionContent}, {&reaction, reactionContent}, {&edit, editContent}} {
+		rel := GetRelatesTo(c.content)
+		if rel == nil || rel.EventID != "$p" {
+			t.Fatalf("GetRelatesTo(%s) = %v", c.content, rel)
+		}
+		var err error
+		unsigned, err = UpdateRelations(unsigned, func(r *EventRelations) { r.Add(&parent, c.ev, rel) })
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fields := map[string]interface{}{}
+	json.Unmarshal(unsigned, &fields)
+	if fields["transaction_id"] != "t1" {
+		t.Errorf("transaction_id lost: %s", unsigned)
+	}
+	relations := GetRelations(unsigned)
+	if relations == nil || relations.Annotation == nil || len(relations.Annotation.Chunk) != 1 ||
+		relations.Annotation.Chunk[0].Count != 2 || relations.Annotation.Chunk[0].Key != "👍" {
+		t.Fatalf("unexpected annotations: %s", unsigned)
+	}
+	if relations.Replace == nil || relations.Replace.EventID != "$e1" {
+		t.Fatalf("unexpected replace: %s", unsigned)
+	}
+
+	unsigned, _ = UpdateRelations(unsigned, func(r *EventRelations) {
+		r.Remove(&reaction, GetRelatesTo(reactionContent), nil)
+		r.Remove(&reaction, GetRelatesTo(reactionContent), nil)
+		r.Remove(&edit, GetRelatesTo(editContent), nil)
+	})
+	if GetRelations(unsigned) != nil {
+		t.Errorf("relations left after redactions: %s", unsigned)
+	}
+}
+
+func TestReplaceRelations(t *testing.T) {
+	parent := gomatrixserverlib.ClientEvent{EventID: "$p", Sender: "@a:h"}
+	rel := &RelatesTo{RelType: RelTypeReplace, EventID: "$p"}
+	edit1 := gomatrixserverlib.ClientEvent{EventID: "$e1", Sender: "@a:h", OriginServerTS: 10}
+	edit2 := gomatrixserverlib.ClientEvent{EventID: "$e2", Sender: "@a:h", OriginServerTS: 20}
+	forged := gomatrixserverlib.ClientEvent{EventID: "$e3", Sender: "@b:h", OriginServerTS: 30}
+
+	r := &EventRelations{}
+	r.Add(&parent, &edit1, rel)
+	r.Add(&parent, &edit2, rel)
+	r.Add(&parent, &forged, rel)
+	if r.Replace == nil || r.Replace.EventID != "$e2" {
+		t.Fatalf("latest edit = %+v, want $e2", r.Replace)
+	}
+
+	// redacting an older edit keeps the latest one
+	r.Remove(&edit1, rel, &edit2)
+	if r.Replace == nil || r.Replace.EventID != "$e2" {
+		t.Fatalf("after redacting $e1: %+v", r.Replace)
+	}
+	// redacting the latest one falls back to the one before it
+	r.Add(&parent, &edit1, rel)
+	r.Remove(&edit2, rel, &edit1)
+	if r.Replace == nil || r.Replace.EventID != "$e1" || r.Replace.OriginServerTS != 10 {
+		t.Fatalf("after redacting $e2: %+v", r.Replace)
+	}
+	r.Remove(&edit1, rel, nil)
+	if r.Replace != nil {
+		t.Fatalf("after redacting every edit: %+v", r.Replace)
+	}
+}
+
+func TestThreadRelations(t *testing.T) {
+	root := gomatrixserverlib.ClientEvent{EventID: "$root", Sender: "@a:h"}
+	rel := &RelatesTo{RelType: RelTypeThread, EventID: "$root"}
+	reply1 := gomatrixserverlib.ClientEvent{EventID: "$t1", Sender: "@b:h", OriginServerTS: 10, Unsigned: []byte(`{"transaction_id":"t"}`)}
+	reply2 := gomatrixserverlib.ClientEvent{EventID: "$t2", Sender: "@c:h", OriginServerTS: 20}
+	reply3 := gomatrixserverlib.ClientEvent{EventID: "$t3", Sender: "@b:h", OriginServerTS: 30}
+
+	unsigned, _ := UpdateRelations(nil, func(r *EventRelations) {
+		r.Add(&root, &reply1, rel)
+		r.Add(&root, &reply2, rel)
+		r.Add(&root, &reply3, rel)
+	})
+	thread := GetRelations(unsigned).Thread
+	if thread.Count != 3 || thread.LatestEvent.EventID != "$t3" ||
+		thread.Participants["@b:h"] != 2 || thread.Participants["@c:h"] != 1 {
+		t.Fatalf("unexpected thread: %s", unsigned)
+	}
+
+	// the participants are served as current_user_participated
+	for _, c := range []struct {
+		userID string
+		want   bool
+	}{{"@a:h", true}, {"@b:h", true}, {"@c:h", true}, {"@d:h", false}} {
+		served := RelationsForUser(unsigned, c.userID, root.Sender)
+		thread := GetRelations(served).Thread
+		if thread.CurrentUserParticipated != c.want || thread.Participants != nil {
+			t.Errorf("served to %s: %s", c.userID, served)
+		}
+	}
+	if served := RelationsForUser([]byte(`{"age":1}`), "@a:h", "@a:h"); string(served) != `{"age":1}` {
+		t.Errorf("unsigned without thread changed: %s", served)
+	}
+
+	// the redacted latest reply is replaced by the one before it, c no
+	// longer takes part once its only reply is redacted
+	unsigned, _ = UpdateRelations(unsigned, func(r *EventRelations) {
+		r.Remove(&reply3, rel, &reply2)
+		r.Remove(&reply2, rel, &reply1)
+	})
+	thread = GetRelations(unsigned).Thread
+	if thread.Count != 1 || thread.LatestEvent.EventID != "$t1" || thread.LatestEvent.Unsigned != nil {
+		t.Fatalf("unexpected thread after redactions: %s", unsigned)
+	}
+	if thread.Participants["@b:h"] != 1 || thread.Participants["@c:h"] != 0 {
+		t.Fatalf("unexpected participants after redactions: %v", thread.Participants)
+	}
+	unsigned, _ = UpdateRelations(unsigned, func(r *EventRelations) { r.Remove(&reply1, rel, nil) })
+	if GetRelations(unsigned) != nil {
+		t.Errorf("relations left after redactions: %s", unsigned)
+	}
+}
+
+func TestGetRelatesToReply(t *testing.T) {
+	content := []byte(`{"m.relates_to":{"m.in_reply_to":{"event_id":"$p"}}}`)
+	if rel := GetRelatesTo(content); rel != nil {
+		t.Errorf("reply taken as relation: %v", rel)
+	}
+}
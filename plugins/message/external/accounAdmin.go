@@ -28,8 +28,10 @@ type ThirdPartyIdentifier struct {
 
 // ThreePID represents a third-party identifier
 type ThreePID struct {
-	Address string `json:"address"`
-	Medium  string `json:"medium"`
+	Address     string `json:"address"`
+	Medium      string `json:"medium"`
+	ValidatedAt int64  `json:"validated_at"`
+	AddedAt     int64  `json:"added_at"`
 }
 
 type GetThreePIDsResponse struct {
@@ -60,7 +62,7 @@ type PostAccount3PIDEmailRequest struct {
 	Path         string `json:"path"`
 	ClientSecret string `json:"client_secret"`
 	Email        string `json:"email"`
-	SendAttempt  string `json:"send_attempt"`
+	SendAttempt  int    `json:"send_attempt"`
 	NextLink     string `json:"next_link,omitempty"`
 	IdServer     string `json:"id_server"`
 }
@@ -69,6 +71,13 @@ type PostAccount3PIDEmailResponse struct {
 	Sid string `json:"sid"`
 }
 
+// GET /_matrix/client/unstable/3pid/email/submit_token
+type GetSubmit3PIDEmailTokenRequest struct {
+	Sid          string `json:"sid"`
+	ClientSecret string `json:"client_secret"`
+	Token        string `json:"token"`
+}
+
 // POST /_matrix/client/r0/account/3pid/msisdn/requestToken
 // same as /register/msisdn/requestToken
 type PostAccount3PIDMsisdnRequest struct {
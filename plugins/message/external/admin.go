@@ -0,0 +1,244 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	jsonRaw "encoding/json"
+
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+)
+
+// GET /_ligase/admin/v1/users
+type GetAdminUsersRequest struct {
+	From   int64  `json:"from"`
+	Limit  int64  `json:"limit"`
+	Search string `json:"search"`
+}
+
+type AdminUser struct {
+	UserID      string `json:"user_id"`
+	DisplayName string `json:"displayname,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	CreatedTs   int64  `json:"created_ts"`
+	Deactivated bool   `json:"deactivated"`
+	Locked      bool   `json:"locked"`
+}
+
+type GetAdminUsersResponse struct {
+	Users    []AdminUser `json:"users"`
+	Total    int64       `json:"total"`
+	NextFrom int64       `json:"next_from,omitempty"`
+}
+
+// GET /_ligase/admin/v1/users/{userID}/devices
+type GetAdminUserDevicesRequest struct {
+	UserID string `json:"user_id"`
+}
+
+type AdminDevice struct {
+	DeviceID    string `json:"device_id"`
+	DisplayName string `json:"display_name,omitempty"`
+	DeviceType  string `json:"device_type,omitempty"`
+	CreatedTs   int64  `json:"created_ts"`
+	LastSeenTs  int64  `json:"last_seen_ts"`
+}
+
+type GetAdminUserDevicesResponse struct {
+	Devices []AdminDevice `json:"devices"`
+	Total   int           `json:"total"`
+}
+
+// POST /_ligase/admin/v1/users/{userID}/logout
+// Logs out every device of the user when no device_id is given
+type PostAdminUserLogoutRequest struct {
+	UserID   string `json:"user_id"`
+	DeviceID string `json:"device_id,omitempty"`
+}
+
+type PostAdminUserLogoutResponse struct {
+	Devices []string `json:"devices"`
+}
+
+// PUT /_ligase/admin/v1/users/{userID}/lock
+type PutAdminUserLockRequest struct {
+	UserID string `json:"user_id"`
+	Locked bool   `json:"locked"`
+}
+
+type PutAdminUserLockResponse struct {
+	Locked bool `json:"locked"`
+}
+
+// GET /_ligase/admin/v1/rooms
+type GetAdminRoomsRequest struct {
+	From  int64 `json:"from"`
+	Limit int64 `json:"limit"`
+}
+
+type AdminRoom struct {
+	RoomID        string `json:"room_id"`
+	JoinedMembers int64  `json:"joined_members"`
+}
+
+type GetAdminRoomsResponse struct {
+	Rooms    []AdminRoom `json:"rooms"`
+	Total    int64       `json:"total"`
+	NextFrom int64       `json:"next_from,omitempty"`
+}
+
+// GET /_ligase/admin/v1/rooms/{roomID}/state
+type GetAdminRoomStateRequest struct {
+	RoomID string `json:"room_id"`
+}
+
+type GetAdminRoomStateResponse struct {
+	State []gomatrixserverlib.ClientEvent `json:"state"`
+}
+
+// POST /_ligase/admin/v1/rooms/{roomID}/join
+// POST /_ligase/admin/v1/rooms/{roomID}/leave
+type PostAdminRoomMembershipRequest struct {
+	RoomID     string `json:"room_id"`
+	Membership string `json:"membership"`
+	UserID     string `json:"user_id"`
+}
+
+// POST /_ligase/admin/v1/rooms/{roomID}/purge_history
+// purge_up_to_event_id wins over purge_up_to_ts
+type PostAdminPurgeHistoryRequest struct {
+	RoomID           string `json:"room_id"`
+	PurgeUpToEventID string `json:"purge_up_to_event_id,omitempty"`
+	PurgeUpToTs      int64  `json:"purge_up_to_ts,omitempty"`
+}
+
+type PostAdminPurgeHistoryResponse struct {
+	Purged int64 `json:"purged"`
+}
+
+// DELETE /_ligase/admin/v1/rooms/{roomID}
+// The local members are moved to a new room created by new_room_user_id if
+// it is given
+type DelAdminRoomRequest struct {
+	RoomID        string `json:"room_id"`
+	NewRoomUserID string `json:"new_room_user_id,omitempty"`
+	RoomName      string `json:"room_name,omitempty"`
+	Message       string `json:"message,omitempty"`
+	Block         bool   `json:"block"`
+	Purge         bool   `json:"purge"`
+}
+
+type DelAdminRoomResponse struct {
+	KickedUsers       []string `json:"kicked_users"`
+	FailedToKickUsers []string `json:"failed_to_kick_users"`
+	LocalAliases      []string `json:"local_aliases"`
+	NewRoomID         string   `json:"new_room_id,omitempty"`
+	Purged            int64    `json:"purged"`
+}
+
+// A registration token as shown by the admin API, uses_allowed and
+// expiry_time are null when unlimited
+type RegistrationToken struct {
+	Token       string `json:"token"`
+	UsesAllowed *int64 `json:"uses_allowed"`
+	Completed   int64  `json:"completed"`
+	ExpiryTime  *int64 `json:"expiry_time"`
+}
+
+// POST /_ligase/admin/v1/registration_tokens/new
+// A random token of the given length is generated when token is empty
+type PostAdminRegistrationTokenRequest struct {
+	Token       string `json:"token,omitempty"`
+	UsesAllowed *int64 `json:"uses_allowed"`
+	ExpiryTime  *int64 `json:"expiry_time"`
+	Length      int    `json:"length,omitempty"`
+}
+
+// GET /_ligase/admin/v1/registration_tokens
+type GetAdminRegistrationTokensRequest struct {
+	Valid string `json:"valid"`
+}
+
+type GetAdminRegistrationTokensResponse struct {
+	RegistrationTokens []RegistrationToken `json:"registration_tokens"`
+}
+
+// DELETE /_ligase/admin/v1/registration_tokens/{token}
+type DelAdminRegistrationTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// An event report as shown by the admin API, state is open or resolved.
+// The event is the snapshot taken when it was reported, it is only returned
+// for a single report.
+type AdminEventReport struct {
+	ID         int64              `json:"id,string"`
+	RoomID     string             `json:"room_id"`
+	EventID    string             `json:"event_id"`
+	Sender     string             `json:"sender"`
+	UserID     string             `json:"user_id"`
+	Reason     string             `json:"reason"`
+	Score      int                `json:"score"`
+	ReceivedTs int64              `json:"received_ts"`
+	State      string             `json:"state"`
+	Assignee   string             `json:"assignee,omitempty"`
+	ResolvedBy string             `json:"resolved_by,omitempty"`
+	ResolvedTs int64              `json:"resolved_ts,omitempty"`
+	Resolution string             `json:"resolution,omitempty"`
+	Event      jsonRaw.RawMessage `json:"event_json,omitempty"`
+
+	RedactionEventID string `json:"redaction_event_id,omitempty"`
+	Kicked           bool   `json:"kicked,omitempty"`
+}
+
+// GET /_ligase/admin/v1/event_reports
+type GetAdminEventReportsRequest struct {
+	From   int64  `json:"from"`
+	Limit  int64  `json:"limit"`
+	RoomID string `json:"room_id"`
+	State  string `json:"state"`
+}
+
+type GetAdminEventReportsResponse struct {
+	EventReports []AdminEventReport `json:"event_reports"`
+	Total        int64              `json:"total"`
+	NextFrom     int64              `json:"next_from,omitempty"`
+}
+
+// GET /_ligase/admin/v1/event_reports/{reportID}
+type GetAdminEventReportRequest struct {
+	ReportID string `json:"report_id"`
+}
+
+// PUT /_ligase/admin/v1/event_reports/{reportID}/assign
+// An empty assignee puts the report back in the queue
+type PutAdminEventReportAssignRequest struct {
+	ReportID string `json:"report_id"`
+	Assignee string `json:"assignee"`
+}
+
+// POST /_ligase/admin/v1/event_reports/{reportID}/resolve
+// redact and kick are sent on behalf of moderator, which is required for them
+type PostAdminEventReportResolveRequest struct {
+	ReportID   string `json:"report_id"`
+	Resolution string `json:"resolution"`
+	Redact     bool   `json:"redact"`
+	Kick       bool   `json:"kick"`
+	Moderator  string `json:"moderator,omitempty"`
+}
+
+type PostAdminEventReportResolveResponse struct {
+	RedactionEventID string `json:"redaction_event_id,omitempty"`
+	Kicked           bool   `json:"kicked"`
+}
@@ -25,6 +25,14 @@ type GetFedRoomStateRequest struct {
 	EventID string `json:"event_id,omitempty"`
 }
 
+// GET /_matrix/federation/v1/timestamp_to_event/{roomId}
+type GetFedTimestampToEventRequest struct {
+	RoomID string `json:"room_id"`
+	TS     int64  `json:"ts"`
+	Dir    string `json:"dir"`
+	Origin string `json:"origin,omitempty"`
+}
+
 //GET /_matrix/federation/v1/backfill/{roomId}
 type GetFedBackFillRequest struct {
 	RoomID      string `json:"roomId"`
@@ -44,19 +44,38 @@ type PostLoginRequest struct {
 	InitialDisplayName *string        `json:"initial_device_display_name"`
 	IsHuman            *bool          `json:"is_human"`
 	IsAdmin            bool           `json:"is_admin"`
+	RefreshToken       bool           `json:"refresh_token"`
+	// AuthorizeCode is the admin login code, provider mode takes the password
+	AuthorizeCode string `json:"authorize_code,omitempty"`
 }
 type PostLoginAdminRequest PostLoginRequest
 
 type UserIdentifier struct {
-	userType string `json:"type"`
+	Type    string `json:"type"`
+	User    string `json:"user"`
+	Medium  string `json:"medium,omitempty"`
+	Address string `json:"address,omitempty"`
 }
 
 //response
 type PostLoginResponse struct {
-	UserID      string `json:"user_id"`
-	AccessToken string `json:"access_token"`
-	HomeServer  string `json:"home_server"`
-	DeviceID    string `json:"device_id"`
+	UserID       string `json:"user_id"`
+	AccessToken  string `json:"access_token"`
+	HomeServer   string `json:"home_server"`
+	DeviceID     string `json:"device_id"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresInMs  int64  `json:"expires_in_ms,omitempty"`
+}
+
+//POST /_matrix/client/r0/refresh
+type PostRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type PostRefreshResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresInMs  int64  `json:"expires_in_ms,omitempty"`
 }
 
 //POST /_matrix/client/r0/logout
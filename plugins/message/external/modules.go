@@ -610,6 +610,11 @@ type PostUserOpenIDResponse struct {
 	ExpiresIn        int    `json:"expires_in"`
 }
 
+// GET /_matrix/federation/v1/openid/userinfo
+type GetFedOpenIDUserInfoResponse struct {
+	Sub string `json:"sub"`
+}
+
 //POST /system/manager//{type}
 type PostSystemManagerRequest struct {
 	Type string `json:"type"`
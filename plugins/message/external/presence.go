@@ -40,7 +40,7 @@ type GetPresenceListRequest struct {
 	UserID string `json:"user_id"`
 }
 
-type GetPresenceListResponse []PresenceJSON
+type GetPresenceListResponse []PresenceListJSON
 
 type PostPresenceListRequest struct {
 	UserID string   `json:"user_id"`
@@ -67,7 +67,8 @@ type PresenceJSON struct {
 	*/
 }
 
+// PresenceListJSON is a m.presence event of GetPresenceListResponse
 type PresenceListJSON struct {
-	content PresenceJSON `json:"content"`
-	typ     string       `json:"type"`
+	Content PresenceJSON `json:"content"`
+	Type    string       `json:"type"`
 }
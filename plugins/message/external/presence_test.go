@@ -0,0 +1,40 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostPresenceListRequestEncodeDecode(t *testing.T) {
+	req := PostPresenceListRequest{
+		UserID: "@alice:example.com",
+		Invite: []string{"@bob:example.com", "@carol:example.org"},
+		Drop:   []string{"@dave:example.com"},
+	}
+	data, err := req.Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	var res PostPresenceListRequest
+	if err = res.Decode(data); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if !reflect.DeepEqual(req, res) {
+		t.Fatalf("got %+v, want %+v", res, req)
+	}
+}
@@ -117,12 +117,12 @@ type AuthDictCapn struct{ capnp.Struct }
 const AuthDictCapn_TypeID = 0xeee54a14f2c8c4f3
 
 func NewAuthDictCapn(s *capnp.Segment) (AuthDictCapn, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 4})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 5})
 	return AuthDictCapn{st}, err
 }
 
 func NewRootAuthDictCapn(s *capnp.Segment) (AuthDictCapn, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 4})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 5})
 	return AuthDictCapn{st}, err
 }
 
@@ -207,12 +207,31 @@ func (s AuthDictCapn) SetResponse(v string) error {
 	return s.Struct.SetText(3, v)
 }
 
+func (s AuthDictCapn) Token() (string, error) {
+	p, err := s.Struct.Ptr(4)
+	return p.Text(), err
+}
+
+func (s AuthDictCapn) HasToken() bool {
+	p, err := s.Struct.Ptr(4)
+	return p.IsValid() || err != nil
+}
+
+func (s AuthDictCapn) TokenBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(4)
+	return p.TextBytes(), err
+}
+
+func (s AuthDictCapn) SetToken(v string) error {
+	return s.Struct.SetText(4, v)
+}
+
 // AuthDictCapn_List is a list of AuthDictCapn.
 type AuthDictCapn_List struct{ capnp.List }
 
 // NewAuthDictCapn creates a new list of AuthDictCapn.
 func NewAuthDictCapn_List(s *capnp.Segment, sz int32) (AuthDictCapn_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 4}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 5}, sz)
 	return AuthDictCapn_List{l}, err
 }
 
@@ -1883,6 +1902,14 @@ func (s PostRegisterRequestCapn) SetAdmin(v bool) {
 	s.Struct.SetBit(2, v)
 }
 
+func (s PostRegisterRequestCapn) RefreshToken() bool {
+	return s.Struct.Bit(3)
+}
+
+func (s PostRegisterRequestCapn) SetRefreshToken(v bool) {
+	s.Struct.SetBit(3, v)
+}
+
 // PostRegisterRequestCapn_List is a list of PostRegisterRequestCapn.
 type PostRegisterRequestCapn_List struct{ capnp.List }
 
@@ -1923,12 +1950,12 @@ type RegisterResponseCapn struct{ capnp.Struct }
 const RegisterResponseCapn_TypeID = 0xc235bc5c20ec749c
 
 func NewRegisterResponseCapn(s *capnp.Segment) (RegisterResponseCapn, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 4})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 5})
 	return RegisterResponseCapn{st}, err
 }
 
 func NewRootRegisterResponseCapn(s *capnp.Segment) (RegisterResponseCapn, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 4})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 5})
 	return RegisterResponseCapn{st}, err
 }
 
@@ -2018,12 +2045,39 @@ func (s RegisterResponseCapn) SetDeviceID(v string) error {
 	return s.Struct.SetText(3, v)
 }
 
+func (s RegisterResponseCapn) RefreshToken() (string, error) {
+	p, err := s.Struct.Ptr(4)
+	return p.Text(), err
+}
+
+func (s RegisterResponseCapn) HasRefreshToken() bool {
+	p, err := s.Struct.Ptr(4)
+	return p.IsValid() || err != nil
+}
+
+func (s RegisterResponseCapn) RefreshTokenBytes() ([]byte, error) {
+	p, err := s.Struct.Ptr(4)
+	return p.TextBytes(), err
+}
+
+func (s RegisterResponseCapn) SetRefreshToken(v string) error {
+	return s.Struct.SetText(4, v)
+}
+
+func (s RegisterResponseCapn) ExpiresInMs() int64 {
+	return int64(s.Struct.Uint64(0))
+}
+
+func (s RegisterResponseCapn) SetExpiresInMs(v int64) {
+	s.Struct.SetUint64(0, uint64(v))
+}
+
 // RegisterResponseCapn_List is a list of RegisterResponseCapn.
 type RegisterResponseCapn_List struct{ capnp.List }
 
 // NewRegisterResponseCapn creates a new list of RegisterResponseCapn.
 func NewRegisterResponseCapn_List(s *capnp.Segment, sz int32) (RegisterResponseCapn_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 4}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 8, PointerCount: 5}, sz)
 	return RegisterResponseCapn_List{l}, err
 }
 
@@ -2048,104 +2102,106 @@ func (p RegisterResponseCapn_Promise) Struct() (RegisterResponseCapn, error) {
 	return RegisterResponseCapn{s}, err
 }
 
-const schema_e5137205f57b43a9 = "x\xda\xecW\x7fhTW\x16>\xe7\xdd\x99L\xa2\xf9" +
-	"\xe1pgw\xd5\xc55\x13\x93\x98\x04\x15\x8d.\xbb\x8a" +
-	"K\x123\xee\x9a\xec$\xce\xcd\xe8\xfe\x08.\xf8f\xe6" +
-	"\x92y\xc9\xcc\x9b\xc9\xbc\x97\xc4\xb8\x06\xb3\xa0\xbb\x06\xb2" +
-	"\xbb*V4\x0d-\x16S+m\xa1\x85V\x9a6\xa1" +
-	"\xb6T\xeb?V,TjZh)\xd8bk\x8bm" +
-	")m)2\xe5\xbe\xcc\xbcy\x19\x9f?\xb0\xfd\xab\x94" +
-	"\xfc3|\xf98\xf3}\xdf9\xf7\xdc;\xab\x1b\x8a\x1b" +
-	"\xa55\xceS\x8d\x00\xc1\x9b\x92\xb3 M6v\xbe\xff" +
-	"\xcb\x97\x1e\xfd\x0f\xb8K1}\xba\xf9\x9f_9S\xf4" +
-	"\x1a8%\x17\xc0\xda\xf5\xe5\xddH\xdb\xca]\x00\xb4\xa5" +
-	"|\x000=\xb9\xd5?\xef\xef\x1fT<\x9cGFA" +
-	">]\x1eB:m\x90'\xcb\x1b\x00\xd3G\x7fs>" +
-	"6\xd5\xfb\xab\xc7\xec\xc8W\xcbw#\xfd\xd4 _7" +
-	"\xc8#e\xfem\xebF\x03\x13v\xe4\"o\x08\xe9\x12" +
-	"\xaf /\xf2\x0a\xf2\xc5\xbf|x\xa2\xf0\xf1!;2" +
-	"]\xef\x9d\xa1\x9b\x0dn\x93\xc1m\x8c\xbc\xb35|\xf4" +
-	"\xcc\xb3\xc0J\xd1Bv\x88\xc2\xbd\xdeN\xa4\xfb\x0d\xf2" +
-	"\xbf\x0c\xf2\x15\x12Z\xf5\xd6\xf9\xc63v*&\xbcc" +
-	"H\xa7\x0d\xf2\xa4An\xf9$\xb1b\xf4\x7f[\xa6\xf2" +
-	"*;\x8d\xd2W\xbd\x9b\x90^\x17\xec\xb5\xd7\xbcK\x11" +
-	"0\xfd\xee\xbf\x1b&\xc7\xdf<|\xd6\x96~\xab\xa2\x13" +
-	"\xa9{\x99\xf8X\xb2\xcc\xa0\x8f\xeb7\xcawL\xfd\xf6" +
-	"\xd5<)\x06{e\xe5b\xa4\x7f\xa84\xecV~\x04" +
-	"\x98>\xb7~\xe4w~\xdf\xf9\xd7\xedt/\xaa\x1aC" +
-	"\xba\xa6J\x90WV\x09\xdd\xf4\xc5}\x83\xed'\xfd\x17" +
-	"\xf3\x858\x05\xbbM\xb0\xb9`\xaf\x95\xab\xfe*\x84\xdc" +
-	"\xd8\xdd\xff\xc8\xc7W\xe4Kv\xb5_\xa9^\x8c\xf4r" +
-	"\xb5\xa8}\xb1Z\xd4.\x9c\x08\xe8\xef}\xfd\xf2%\x9b" +
-	"i\xa2\x9fW\xcf\xd0[\x06\xf7\xdbj1L#\xa7\x97" +
-	"\xfc\xe2\x8d\xee'glul_\x1eB\xaa,\x17\x1f" +
-	"\xf9rC\xc7\x97\xaf]\xf8\xc2\xd3z\xed3\x9b@\xe8" +
-	"\xb9\x9a\x19z\xb9\xc6\x90Q#\xf2\xf8\x7f`\xa6\xe7\xcf" +
-	"\xf5c7m\xb3\x9e\xa8=\x8ct\xbaV|\x9c\xac5" +
-	"\xb2\xf6\xfez\xf7wC\xfc\xedo\xf2\xe9EF'\xeb" +
-	"\xea\x91^\xaf3:YwJ\x82\xf4\x9c\xbf\xad\xe9\x14" +
-	"\xefR4\x9d\xa7\x1c\xab\xc2rR\xdd\x10HhzS" +
-	"8\x9c\xe8S\xf5\x80\xaci\x03\x89T\xa4\x83\xf7\xf6q" +
-	"M/k\x96\x93j\x00\x91\x15\x12\x07\x80\x03\x01\xdc\xb5" +
-	"!\x00VC\x90\xad\x93\xd0\x8d\xe8A\x01\xae\xa9\x03`" +
-	"+\x08\xb2\xdfK\x98V\xf9\x80Q\x06\\\x89T\x04\x8b" +
-	"A\xc2b\xc02\xb9O\x8f\xe2\x82\\\xda\x80\xb8\x00\xd0" +
-	"NJG\x06j\xd3\x14-\xd2\xc1\xb5dB\xd5x\xb3" +
-	"+\xa3\xc4a*)\xa9\x00`\x85\x04\x99GB\x97\xd6" +
-	"\xe2\xcb~\xd5]\xec\xf9\xb8\x1c\xd6\x95~Y\xe7\x86\xc1" +
-	"\x06Mo\xbe\xbdl]\xae\xec\x03\xa8\xde\x1c\x97\x95\xd8" +
-	"\x0fQ-\xcd\xd6l\xea\xd3\xa3\x7f\x8c%\x06\x9aer" +
-	"[\x89\x0d\x99\x12\x95\x126h\xba\xdc\xc55,\x05\x0c" +
-	"\x104\x8a\x95\xde.\xf0O\xdc\xd4\xd7\xd4o\xd5gS" +
-	"\xbc\x03\x80\x15\x13d\x0b%L\xcb\x82,\x87b\x80\x1c" +
-	"\x11$DKi\xe7\x1d\x87gn\xe3l\xbe\xe2>\x1b" +
-	"\xe7\xe7]rx0+<3\x92\xa2a \xeay\xcc" +
-	"zC\xad\x00l\x0fAv\xc02\x92\xfb\x05\xb8\x8f " +
-	";(!J\x1e\x94\x00\xdc\xff\xad\x07`\x07\x08\xb2#" +
-	"\x12\xba\x89\xe4A\x02\xe0>$\xfa=J\x90\x1d\x93\xd0" +
-	"\xed \x1et\x00\xb8\x1f\x12\x12\x0f\x12d\xe3\x12\xa6\x93" +
-	"\x19_\x00`J\xed\xd3xJ\x95\xe3\xdc\x82-\x95#" +
-	"qE\xcd\xe6T\xa6\x0f&y\xf6_\xae\xb8\x1c\xc6\x12" +
-	"\x90\xb0\xe4\xbe\x86'c4\x13\x9c\xc5h\xb7\x9dQa" +
-	"j\x98 \x1b\xcd\x19\x1d\x09\xd9\x19m\xcdy2\x8d\x1e" +
-	"\x17\xe01\x82\xec\xa4\x84\xe9pL\xe1\xaa\x1e\xe4P\x16" +
-	"Nq\xdd\xf4\xc5\x85*\xd3\xb9\xc6\xd5H\x93\xaesp" +
-	"\xc5\x93::AB'\x88C\xbfK\xf7+j\x8f5" +
-	"#\xc5\x17\xe4\xa9~\x9e\xb2bY\xf3d\xd6|\xae\xb9" +
-	"y\xd3\xb2\xc04-\x8bi\xdfA\x90E-\xa6\xb90" +
-	"\x18!\xc8\x92\x12\xba\xa5\x8c\xebx'\x00\x8b\x11d\xbb" +
-	"\x84k2\xeb\xbaO\x18\xd4\x09\xb2a\x09\x1bD\xd7," +
-	"\xf3&\x87\xc3\\\xd3\xb6%\xc0\xd5\xc3U\x13\x8d&\xe2" +
-	"\\\x08\x07\xc2S&\x18\xe1\xfdJ\x98\xb7\xf8\xec\xbc\xdc" +
-	"\xf9$\xcc]\x06\x0fp\x12\xeeq\xc8\xd4\xfcYYh" +
-	"\x96>\xde\x9dk\xac\x19\xdb\x89M\x00l\x9c {\xc2" +
-	"\x12\xdb\x84\xc8\xf2$A\xf6\x8c\x84\x98I\xedi\x81=" +
-	"E\x90\xbd`\x99\x95\xe7E\x94\xcf\x11dg%t;" +
-	"\x1d\x1et\x02\xb8\xa7\x058E\x90]\xb8\xe3\x00\xed5" +
-	"\x84\xa7\x06Mw\xc9hB\xe5\xed}qp\x85,\x11" +
-	"\xff\xa8\x83\x95\xbf\xf1\xec\xc2o\xb5l:1\x19\xeds" +
-	"\xcf\xb3\xddF\xf6\xc9\xbal\x96\xb2\\\x8ab\x87T\x12" +
-	"d\xab-a\xaf\xdc\x94\xbb)\xe7l\x83\xbd\x1a\xd74" +
-	"%\xa1\xde\xed\xc2\x9as\x09\x9am\xce^'?\xb7\xd9" +
-	"\xda\x13%\xac\xdb\xed\x8d:\xbb\xbd!\x92\xd9I\x90\xc5" +
-	",\xc9(\x15\x96e\x92\xdd\x1b\xf1\xd6\xdc2\xb9k\xf7" +
-	"\xf2v\xfb\xecY\x7f\xa05\x91\xbd\xdf\xf0'\xbf\xf6\xad" +
-	"\x13>\xeb{\xa9\xf9\x12[\x9d\xf5M\x9b\xb0\x0e \xb8" +
-	"\x11\x09\x06\xb7\xa0\x84\x19\xe7t3v\x00\x04}\x02\x0e" +
-	"\xa0h#\x1a\xeei\x1b\xb6\x02\x04\xfd\x02\xff\x1b\xe6\x02" +
-	"\xa0\xdb\x0d|\x9b\xc0wb.\x03\xfa\x0f\x03\xdf!\xf0" +
-	"(\xe6F\x9dr\x1c\x03\x08F\x05\xae\x0b\xbc\x00=X" +
-	"\x00@{\xb1\x1b \x98\x14\xf8\x1e\x81\xbb\x9c\x1e\xe3\xe7" +
-	"\xdb\xa0!S\x17\xf8\xb0\xc0\x0b\x0b<X\x08@\x87p" +
-	"\x03@p\x97\xc0\xf7\x09\xbc\xc8\xe5\xc1\"\xf1\xbb\x0dC" +
-	"\x00\xc1a\x81\x8f\x0a|^\xa1\x07\xe7\x01\xd0\x11\xec\x04" +
-	"\x08\x1e\x10\xf8\x11\x81\xcf\x97<8\x1f\x80\x1e\xc2z\x80" +
-	"\xe0\xa8\xc0\x8fa\xeeMj\xfe\xb8\xc8\xbcIC\x8aj" +
-	"\x0c\x12`\xcc|\xab\xd9\xbcRl_3v\xb7\x9b\xa2" +
-	"*\xba\"\xc7|\xa8h\xc9\x98<\xd8.\x938\xb7\xfc" +
-	"3\xaa\x84\x14\xdd\x0fe\x89.\xcb\x9b\xa7GQ\xcd\x17" +
-	"\x7fC$\x11\x97\x15\xf5\x1e\x97m\x8a\xc7\x13:o\x8a" +
-	"\x00\x89\xa4\xec\x1fR\xdf\x07\x00\x00\xff\xff\xf9\x0f\xc8\x7f"
+const schema_e5137205f57b43a9 = "x\xda\xecW]l\x14_\x15?g\xeeNw\xbb\xff" +
+	"\xdd\x96\xcd\xdd*`\x14%<\x08A\x02\x05\x83\x10M" +
+	"\xe9\x07J\xd7R:]\xe2G#\x89\xb3\xbb\xd7v\xda" +
+	"\xdd\xd9\xed\xcc\xf4\x0b!\xd8\xa4U\x9a\x14-\x04\x08\xd4" +
+	"\xa2\xd4\xb4\x06\xa2Mh\x02D\xb4Fb\x04yA\xa2" +
+	"\x09D\xab\x09j\x82\x0fJ\x8c\xd1\x10M|Xs\xa6" +
+	"\xbb3\xd3\xed\xf0!\xc6\x17\xe3\xdb\xcdo\x7f=\xfd\xfd" +
+	"\xce9\xf7\x9c;;w\xca\x07\xa4]\xf2\xde*\x00\xe5" +
+	"\x90\\Ud\x1f\xed\xfa\xed\xbb~\xf0\xcd\xaf@\xac\x06" +
+	"\x8b\xd7\x9b\xbf\xf8B6\xf83\x90\xa5 \xc0nd\xbd" +
+	"\xc8\xebX\x10\x80\xc7\xd8\x10`\xf1\xce\x91\xb6\xf0g\x7f" +
+	"\xbf\xf9\xeb\x15d$r?K!\x1f\xb7\xc9\xa3\xac\x01" +
+	"\xb0x\xf1}\xf7\xb3K\xfd\xef\xfe\x96\x1fy\x96\x1dG" +
+	"~\xcb&/\xda\xe4\x89\xda\xb6\xa3{&;\xe6\xfd\xc8" +
+	"?\xa7\xc8\xcfl\xf2\xefl\xf2\xc3O\xfda6\xf4\xed" +
+	"\x93~d\x8e\x81e\x1e\x0d\xd0\xa9:@\xdc\x03\x99_" +
+	"\x1fI_\xbc\xbd\x08J\x0dz\xc8\xc4\xd8\xbd/\xd0\x85" +
+	"\xfc\xb0Mn\xb5\xc9OXj\xc7\xe3\xfb\x07n\xfb\xa9" +
+	"\xc8\x05\xa6\x91\x8f\xdb\xe4Q\x9b\xdc\xfa\xa7\xfc\xf6\xc9\xaf" +
+	"\x1eZ\xaa\x88,\xdb\xa1g\x03M\xc8\x17\xed\xe3B`" +
+	"\x13\x02\x16\x7f\xf3\xe5\x86;3\xbf8w\xd7\x97~O" +
+	"\xeeB\xfe+\x99\x8e\x8fe\x9b>c=\x7f\xff\xe7\x96" +
+	">\xfc\xe3J\xba\xcdyQ\xb5\x11\xb9\x1c\xa4#\x06?" +
+	"M\xf4{\xfb&\xf6\xb6\xb5\xdc\xff\xa9\x9fr54\x8d" +
+	"|$D\xca\x07B\xa4\x9c\x7f\x7fl\xa4}\xae\xed\xa1" +
+	"o\xec\x0b\xc4^ \xf6\xee\xeb!;\xf6\xf3\xe3\x83\xdf" +
+	"\xf8\xe3\x13\xf5\x91_\xec\xea\xf0F\xe4\x1b\xc2\x14\xbb." +
+	"L\xb1C\xf3\x1d\xd6\xd3\xbf\xff\xe8\x91O?\xf1}\xe1" +
+	"e~\xd0\xe66\x86\xa9\x9d&\xae\xbf\xb7\xeeg\xbd\xdf" +
+	"Y\xf6\xd5q%\x9cB\xbe\x18\xb63\x18\xb6u\xfc\xed" +
+	"'\x0f\xfe\x1aO<\xfbsEhb\xf3hd\x99o" +
+	"\x88\x10\xb9.b\xe7\xefk\x1d\xcb}\x9f\xac\x9f\xfe\x8b" +
+	"o\xba\xb7F\xcf!o\x8c\xd2\xf1cQ\x9b\xfe\x81\xf7" +
+	"\x1c\xff\xe7I\xf1\xcb\x7fT\xd2\xab\x89s\xac\xa6\x1ey" +
+	"\xae\x86\x8eZ\xcdS\x09\xb0h\x88n\xcd\xb4\x84\x11\xd8" +
+	"\x91V\x0b\xfa\xfe\x8e\xbci5\xa6\xd3\xf9\x01\xdd\xeaP" +
+	"Ms(od:E\xff\x800\xad\xdaf\xb5\xa0w" +
+	" *!\x16\x00\x08 @lk\x0a@\xf9 Ce" +
+	"\x8f\x841\xc48\x12\xb8k\x1b\x80\xb2\x9d\xa1\xf2\x11\x09" +
+	"\x8b\xba\x18\xb2\xc3@0od0\x02\x12F\x00k\xd5" +
+	"\x01\xab\x07\xd7\xb9\x09\x06\xc4u\xfeR:K\xd0aS" +
+	"33\x9d\xc2,\xe4uS4\x07KJ\x02\x8e\x92\xe8" +
+	"f\x00%\xc4P\x89K\x184[[\xca\xff\xea\x15\xf6" +
+	"Z\x84\x9a\xb6\xb4A\xd5\x12\xb6\xc1\x06\xd3j^\x1bv" +
+	"\x9b\x1b\xf6-T\x1f\xcc\xa9Z\xf6?Q-\xad\xc4l" +
+	"\x1c\xb0z>\x9e\xcd\x0f5\xablM\x88\xfd\xa5\x10[" +
+	"$l0-\xb5[\x98X\x03\xd8\xc1\xd0\x0eV\xb3V" +
+	"\xe0'\x84\xa3\xafq\xd0\xab\xcf'x'\x80\x12a\xa8" +
+	"\xac\x97\xb0\xa8\x12YMe\x01\x05\"H\x88\x9e\xd0\xf2" +
+	"K\x9bgu\xe1T\xf6o\xa7\xa0\xa4\xbaMt\xab\xe9" +
+	"\x91\xb2\xf0RKR\xc1\x80\xe2\xc5\x9dx'\x13\x00\xca" +
+	"\x09\x86\xcaiOK\x8e\x138\xc6P\x99\x92\x10\xa58" +
+	"J\x00\xb13\xf5\x00\xcai\x86\xcay\x09cL\x8a#" +
+	"\x03\x88\x9d\xa5zO2T.I\x18\x0b\xb08\x06\x00" +
+	"b\x17H\xe2\x14CeF\xc2b\xa1\xe4\x0b\x00\x1c\xa9" +
+	"\x03\xa60t5'<\xd8&5\x93\xd3\xf4r\x9ej" +
+	"\xad\x91\x82(\xff\x14\xcc\xa9i\x8c\x82\x84\xd17j\x9e" +
+	"\x92\xd1R\xe2<F{\xfd\x8c\x92\xa9/1T&]" +
+	"\xa3\x13)?\xa3\x09\xd7\x93c\xf42\x81\x97\x18*s" +
+	"\x12\x16\xd3YM\xe8VR@m\xda\x10\x96\xe3K\x90" +
+	"*\xc7\xb9)\xf4L\xa3e\x09\x08\xe6\x0a\x16\xca \xa1" +
+	"\x0ct\xe9\x87\xad6M\xef\xf3\xe6HkI\x0acP" +
+	"\x18^\xacl\x9e\xad\x98w\x8b[\xd1-\xeb\x1d\xd3\x97" +
+	"\xa9\xdb\xcf3T\xaezL_!\x833\x0c\x95k\x12" +
+	"\xc6\xa4\x92\xeb\xf9.\x00e\x8e\xa1r\x83\\\xb3\x15\xd7" +
+	"\x0bd\xf0\xbb\x0c\x95\xef\x91\xeb\xc0\x8a\xeb[\x94\xc8\x9b" +
+	"\x0c\x95\xbb\x12\xa2\x1cG\x19 \xf6C\x0a\xb9\xc4Py" +
+	" a\x03\x95\xd7\xd3\x98j:-L\xf3h\x1e\x82}" +
+	"Bw\xd0\x9e|N\x90C`\xc2p\xc0\x8c\x18\xd4\xd2" +
+	"\xa2\xb5e\xb5\xe9/\x18\xc2\xec9\x0a\xb5y\xef\xdf\x8b" +
+	"\xe1\x82f\x08\xb3\x15\x82\xfaa\xd3\xc9\xe4\xeb\xef\xd7\xea" +
+	"\x11\xf3\x16\xf7\xeb5WW\xaf\xec@O1z\xddv" +
+	"q\x8a1\xdb\xe4W\x8c\x94[\x0c,\xd7\"\xe5\xadE" +
+	"\xa9\x03o%\xdcZ\xc4\xe4@\xa9\x18\x09\xb7\x18/i" +
+	"\xcbS\xb6pc\xc4qW\xe8\xc9\xeb\xa2} \x07\xc1" +
+	"\x940\xfe;\xedZ9G\xfd\x92\x9f\xf0\xccOj\xa3" +
+	"\xf6\xd5S\xc2o\xce\xb7\xa8\x96\xea\x84\xf2\xacZ\x9aL" +
+	"[\x18*;=\xc9\xfeP\x93\xbb\x7fW\xcd\x98S\xa6" +
+	"0M-\xaf\xbfj\x0d\xaeZ\xadN\x99\x83\xea\xff\xcb" +
+	"\xbc\xb6&Z\xdaw\x04SM\x86\x19*c\x9e\xcc\x8c" +
+	"6y\xe6r93\xe3\x9b\xdd\xb9\xecL\xa3\x89\x84g" +
+	"0\x97\xa7\xd1\xd9zw\x03\xbd\xb2\xa4\x15kde\x00" +
+	"x7\x90\xb5j\xbe\xbc\xf1$)/V\xfc\x9f\xdf7" +
+	"\xdeK\xb0\xe2{\x93\xf3\x04\xdcS\xf6\xcd\x8f\xe16\x80" +
+	"\xe4g\x90a2\x83\x12\x96\x9cs\x15;\x01\x92\x9f'" +
+	"8\x8bTi\xb4\xdds\x0d\x13\x00\xc9\x1e\xc2-t\x13" +
+	"\xc0\xfbm\xbc@\xf8\x09ts\xc0Gl|\x98\xf01" +
+	"to\x03\x1f\xc5i\x80\xe4\x18\xe1S\x84Wa\x1c\xab" +
+	"\x00\xf8\x19\xec\x05HN\x12~\x89\xf0\xa0\x1c\xb7?\x16" +
+	"/\xd82\xa7\x08\x9f!<T\x15\xc7\x10\x00\xbf\x8c\xfb" +
+	"\x01\x92\xe7\x09\xbfJxu0\x8e\xd5\x00\xfc\x0a\xa6\x00" +
+	"\x923\x84_#<\x1c\x8ac\x18\x80\xcfc\x17@r" +
+	"\x8e\xf0\x1b\x84\xbf#\xc5\xf1\x1d\x00\xbe\x80\xf5\x00\xc9k" +
+	"\x84\xdf$<\xc2\xe2\x18\xa1o^[\xcf\x0d\xc2\x97\xd0" +
+	"}$;\x1f8\xa5GrJ\xd3\xed\x06\x03\xcc:\x8f" +
+	"G\x9fg\x93\xef\xf3\xcao\x8bj\xbafij\xb6\x05" +
+	"5\xb3\x90UG\xdaU\x96\x13\x9e\x1f{\xb4\x94f\xb5" +
+	"Am\xbe\xdb\xf3\x08\xeb\xd3t\xe7\x13\xa4!\x93\xcf\xa9" +
+	"\x9a\xfe\x9a\xa5n\x88\\\xde\x12\x8d\x19`\x19\xc3\xffe" +
+	"W\xb9\xceK\xf0\xbf\x06\x00\xf70\xdb\x07"
 
 func init() {
 	schemas.Register(schema_e5137205f57b43a9,
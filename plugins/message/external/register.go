@@ -21,6 +21,7 @@ type AuthDict struct {
 	Session  string `json:"session"`
 	Mac      []byte `json:"mac"`
 	Response string `json:"response"`
+	Token    string `json:"token,omitempty"`
 }
 
 type PostRegisterRequest struct {
@@ -31,6 +32,7 @@ type PostRegisterRequest struct {
 	DeviceID           string   `json:"device_id"`
 	InitialDisplayName string   `json:"initial_device_display_name"`
 	InhibitLogin       bool     `json:"inhibit_login"`
+	RefreshToken       bool     `json:"refresh_token"`
 
 	Kind        string `json:"kind"`
 	Domain      string `json:"domain"`
@@ -52,10 +54,12 @@ type LegacyRegisterRequest struct {
 
 //response
 type RegisterResponse struct {
-	UserID      string `json:"user_id"`
-	AccessToken string `json:"access_token"`
-	HomeServer  string `json:"home_server"`
-	DeviceID    string `json:"device_id"`
+	UserID       string `json:"user_id"`
+	AccessToken  string `json:"access_token"`
+	HomeServer   string `json:"home_server"`
+	DeviceID     string `json:"device_id"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresInMs  int64  `json:"expires_in_ms,omitempty"`
 }
 
 // Flow represents one possible way that the client can authenticate a request.
@@ -106,13 +110,26 @@ type PostRegisterMsisdResponse struct {
 //POST /_matrix/client/r0/account/password
 //request
 type PostAccountPasswordRequest struct {
-	NewPassword string   `json:"new_password"`
-	Auth        AuthData `json:"auth"`
+	NewPassword   string   `json:"new_password"`
+	LogoutDevices *bool    `json:"logout_devices,omitempty"`
+	Auth          AuthData `json:"auth"`
 }
 
 type AuthData struct {
-	Type    string `json:"type"`
-	Session string `json:"session"`
+	Type          string              `json:"type"`
+	Session       string              `json:"session"`
+	Identifier    UserIdentifier      `json:"identifier"`
+	User          string              `json:"user"`
+	Password      string              `json:"password"`
+	ThreePIDCreds ThreePidCredentials `json:"threepid_creds"`
+}
+
+//POST /_ligase/admin/v1/users/{userID}/reset_password
+//request
+type PostAdminResetPasswordRequest struct {
+	UserID        string `json:"user_id"`
+	NewPassword   string `json:"new_password"`
+	LogoutDevices *bool  `json:"logout_devices,omitempty"`
 }
 
 //POST /_matrix/client/r0/account/password/email/requestToken
@@ -151,6 +168,11 @@ type PostAccountDeactivateRequest struct {
 	Auth AuthData `json:"auth"`
 }
 
+//POST /_ligase/admin/v1/users/{userID}/deactivate
+type PostAdminDeactivateRequest struct {
+	UserID string `json:"user_id"`
+}
+
 // GET /_matrix/client/r0/register/available
 type GetRegisterAvail struct {
 	UserName string `json:"username"`
@@ -159,3 +181,12 @@ type GetRegisterAvail struct {
 type GetRegisterAvailResponse struct {
 	Available bool `json:"available"`
 }
+
+// GET /_matrix/client/v1/register/m.login.registration_token/validity
+type GetRegistrationTokenValidityRequest struct {
+	Token string `json:"token"`
+}
+
+type GetRegistrationTokenValidityResponse struct {
+	Valid bool `json:"valid"`
+}
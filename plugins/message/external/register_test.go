@@ -0,0 +1,98 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostRegisterRequestEncodeDecode(t *testing.T) {
+	req := PostRegisterRequest{
+		Auth: AuthDict{
+			Type:     "m.login.registration_token",
+			Session:  "session",
+			Mac:      []byte{1, 2, 3},
+			Response: "response",
+			Token:    "token",
+		},
+		Username:     "alice",
+		Password:     "secret",
+		DeviceID:     "DEVICE",
+		InhibitLogin: true,
+		RefreshToken: true,
+		Kind:         "user",
+		Domain:       "example.com",
+		RemoteAddr:   "127.0.0.1",
+	}
+	data, err := req.Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	var res PostRegisterRequest
+	if err = res.Decode(data); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if !reflect.DeepEqual(req, res) {
+		t.Fatalf("got %+v, want %+v", res, req)
+	}
+}
+
+func TestRegisterResponseEncodeDecode(t *testing.T) {
+	resp := RegisterResponse{
+		UserID:       "@alice:example.com",
+		AccessToken:  "access",
+		HomeServer:   "example.com",
+		DeviceID:     "DEVICE",
+		RefreshToken: "refresh",
+		ExpiresInMs:  300000,
+	}
+	data, err := resp.Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	var res RegisterResponse
+	if err = res.Decode(data); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if !reflect.DeepEqual(resp, res) {
+		t.Fatalf("got %+v, want %+v", res, resp)
+	}
+}
+
+func TestPostLoginResponseEncodeDecode(t *testing.T) {
+	resp := PostLoginResponse{
+		UserID:       "@alice:example.com",
+		AccessToken:  "access",
+		HomeServer:   "example.com",
+		DeviceID:     "DEVICE",
+		RefreshToken: "refresh",
+		ExpiresInMs:  300000,
+	}
+	data, err := resp.Encode()
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	var res PostLoginResponse
+	if err = res.Decode(data); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if !reflect.DeepEqual(resp, res) {
+		t.Fatalf("got %+v, want %+v", res, resp)
+	}
+}
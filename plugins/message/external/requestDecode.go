@@ -34,12 +34,36 @@ func (externalReq *PostLoginRequest) Decode(input []byte) error {
 	return json.Unmarshal(input, externalReq)
 }
 
+func (externalReq *PostRefreshRequest) Decode(input []byte) error {
+	return json.Unmarshal(input, externalReq)
+}
+
 func (externalReq *PostLoginAdminRequest) Decode(input []byte) error {
 	t := (*PostLoginRequest)(externalReq)
 	return t.Decode(input)
 	//return json.Unmarshal(input, externalReq)
 }
 
+func (externalReq *PostAccountPasswordRequest) Decode(input []byte) error {
+	return json.Unmarshal(input, externalReq)
+}
+
+func (externalReq *PostAccountPasswordEmailRequest) Decode(input []byte) error {
+	return json.Unmarshal(input, externalReq)
+}
+
+func (externalReq *PostAdminResetPasswordRequest) Decode(input []byte) error {
+	return json.Unmarshal(input, externalReq)
+}
+
+func (externalReq *PostAccountDeactivateRequest) Decode(input []byte) error {
+	return json.Unmarshal(input, externalReq)
+}
+
+func (externalReq *PostAdminDeactivateRequest) Decode(input []byte) error {
+	return json.Unmarshal(input, externalReq)
+}
+
 func (externalReq *PostUserFilterRequest) Decode(input []byte) error {
 	return json.Unmarshal(input, externalReq)
 }
@@ -439,6 +463,7 @@ func (externalReq *PostRegisterRequest) Decode(input []byte) error {
 		return err
 	}
 	externalReq.InhibitLogin = reqCapn.InhibitLogin()
+	externalReq.RefreshToken = reqCapn.RefreshToken()
 	externalReq.Kind, err = reqCapn.Kind()
 	if err != nil {
 		return err
@@ -461,6 +486,7 @@ func (externalReq *PostRegisterRequest) Decode(input []byte) error {
 	externalReq.Auth.Session, _ = authCapn.Session()
 	externalReq.Auth.Mac, _ = authCapn.Mac()
 	externalReq.Auth.Response, _ = authCapn.Response()
+	externalReq.Auth.Token, _ = authCapn.Token()
 	if err != nil {
 		return err
 	}
@@ -766,41 +792,11 @@ func (externalReq *PostAccount3PIDDelRequest) Decode(input []byte) error {
 }
 
 func (externalReq *PostAccount3PIDEmailRequest) Decode(input []byte) error {
-	msg, err := capn.Unmarshal(input)
-	if err != nil {
-		return err
-	}
-
-	reqCapn, err := ReadRootPostAccount3PIDEmailRequestCapn(msg)
-	if err != nil {
-		return err
-	}
+	return json.Unmarshal(input, externalReq)
+}
 
-	externalReq.Path, err = reqCapn.Path()
-	if err != nil {
-		return err
-	}
-	externalReq.ClientSecret, err = reqCapn.ClientSecret()
-	if err != nil {
-		return err
-	}
-	externalReq.Email, err = reqCapn.Email()
-	if err != nil {
-		return err
-	}
-	externalReq.SendAttempt, err = reqCapn.SendAttempt()
-	if err != nil {
-		return err
-	}
-	externalReq.NextLink, err = reqCapn.NextLink()
-	if err != nil {
-		return err
-	}
-	externalReq.IdServer, err = reqCapn.IdServer()
-	if err != nil {
-		return err
-	}
-	return nil
+func (externalReq *GetSubmit3PIDEmailTokenRequest) Decode(input []byte) error {
+	return json.Unmarshal(input, externalReq)
 }
 
 func (externalReq *PostAccount3PIDMsisdnRequest) Decode(input []byte) error {
@@ -1120,6 +1116,24 @@ func (externalReq *PostPresenceListRequest) Decode(input []byte) error {
 	if err != nil {
 		return err
 	}
+
+	inviteCapn, err := reqCapn.Invite()
+	if err != nil {
+		return err
+	}
+	externalReq.Invite = make([]string, inviteCapn.Len())
+	for i := range externalReq.Invite {
+		externalReq.Invite[i], _ = inviteCapn.At(i)
+	}
+
+	dropCapn, err := reqCapn.Drop()
+	if err != nil {
+		return err
+	}
+	externalReq.Drop = make([]string, dropCapn.Len())
+	for i := range externalReq.Drop {
+		externalReq.Drop[i], _ = dropCapn.At(i)
+	}
 	return nil
 }
 
@@ -1862,6 +1876,10 @@ func (externalReq *GetFedBackFillRequest) Decode(input []byte) error {
 	return json.Unmarshal(input, externalReq)
 }
 
+func (externalReq *GetFedTimestampToEventRequest) Decode(input []byte) error {
+	return json.Unmarshal(input, externalReq)
+}
+
 func (externalReq *PostReportRoomRequest) Decode(input []byte) error {
 	return json.Unmarshal(input, externalReq)
 }
@@ -1996,3 +2014,99 @@ func (externalReq *PostQueryClientKeysRequest) Decode(data []byte) error {
 func (externalReq *PostClaimClientKeysRequest) Decode(data []byte) error {
 	return json.Unmarshal(data, externalReq)
 }
+
+func (externalReq *GetAdminUsersRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *GetAdminUserDevicesRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *PostAdminUserLogoutRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *PutAdminUserLockRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *GetAdminRoomsRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *GetAdminRoomStateRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *PostAdminRoomMembershipRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *PostAdminRegistrationTokenRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *GetAdminRegistrationTokensRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *DelAdminRegistrationTokenRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *PostUserSearchRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *GetAdminEventReportsRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *GetAdminEventReportRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *PutAdminEventReportAssignRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *PostAdminEventReportResolveRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *PostRoomUpgradeRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *GetRoomHierarchyRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *GetRoomRelationsRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *GetRoomThreadsRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *PostSearchRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *PostAdminPurgeHistoryRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *DelAdminRoomRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *GetRoomTimestampToEventRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
+
+func (externalReq *GetRegistrationTokenValidityRequest) Decode(data []byte) error {
+	return json.Unmarshal(data, externalReq)
+}
@@ -37,11 +37,35 @@ func (externalReq *PostLoginRequest) Encode() ([]byte, error) {
 	return json.Marshal(externalReq)
 }
 
+func (externalReq *PostRefreshRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
 func (externalReq *PostLoginAdminRequest) Encode() ([]byte, error) {
 	t := (*PostLoginRequest)(externalReq)
 	return t.Encode()
 }
 
+func (externalReq *PostAccountPasswordRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostAccountPasswordEmailRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostAdminResetPasswordRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostAccountDeactivateRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostAdminDeactivateRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
 func (externalReq *PostUserFilterRequest) Encode() ([]byte, error) {
 	return json.Marshal(externalReq)
 }
@@ -383,6 +407,7 @@ func (externalReq *PostRegisterRequest) Encode() ([]byte, error) {
 	reqCapn.SetDeviceID(externalReq.DeviceID)
 	reqCapn.SetInitialDisplayName(externalReq.InitialDisplayName)
 	reqCapn.SetInhibitLogin(externalReq.InhibitLogin)
+	reqCapn.SetRefreshToken(externalReq.RefreshToken)
 	reqCapn.SetKind(externalReq.Kind)
 	reqCapn.SetDomain(externalReq.Domain)
 	reqCapn.SetAccessToken(externalReq.AccessToken)
@@ -398,6 +423,7 @@ func (externalReq *PostRegisterRequest) Encode() ([]byte, error) {
 	auth.SetSession(externalReq.Auth.Session)
 	auth.SetMac(externalReq.Auth.Mac)
 	auth.SetResponse(externalReq.Auth.Response)
+	auth.SetToken(externalReq.Auth.Token)
 
 	data, err := msg.Marshal()
 	if err != nil {
@@ -711,28 +737,11 @@ func (externalReq *PostAccount3PIDDelRequest) Encode() ([]byte, error) {
 }
 
 func (externalReq *PostAccount3PIDEmailRequest) Encode() ([]byte, error) {
-	msg, seg, err := capn.NewMessage(capn.SingleSegment(nil))
-	if err != nil {
-		return nil, err
-	}
-
-	reqCapn, err := NewRootPostAccount3PIDEmailRequestCapn(seg)
-	if err != nil {
-		return nil, err
-	}
-
-	reqCapn.SetPath(externalReq.Path)
-	reqCapn.SetClientSecret(externalReq.ClientSecret)
-	reqCapn.SetEmail(externalReq.Email)
-	reqCapn.SetSendAttempt(externalReq.SendAttempt)
-	reqCapn.SetNextLink(externalReq.NextLink)
-	reqCapn.SetIdServer(externalReq.IdServer)
-	data, err := msg.Marshal()
-	if err != nil {
-		return nil, err
-	}
+	return json.Marshal(externalReq)
+}
 
-	return data, nil
+func (externalReq *GetSubmit3PIDEmailTokenRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
 }
 
 func (externalReq *PostAccount3PIDMsisdnRequest) Encode() ([]byte, error) {
@@ -1708,6 +1717,10 @@ func (externalReq *GetFedBackFillRequest) Encode() ([]byte, error) {
 	return json.Marshal(externalReq)
 }
 
+func (externalReq *GetFedTimestampToEventRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
 func (externalReq *PostReportRoomRequest) Encode() ([]byte, error) {
 	return json.Marshal(externalReq)
 }
@@ -1843,3 +1856,99 @@ func (externalReq *PostQueryClientKeysRequest) Encode() ([]byte, error) {
 func (externalReq *PostClaimClientKeysRequest) Encode() ([]byte, error) {
 	return json.Marshal(externalReq)
 }
+
+func (externalReq *GetAdminUsersRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *GetAdminUserDevicesRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostAdminUserLogoutRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PutAdminUserLockRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *GetAdminRoomsRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *GetAdminRoomStateRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostAdminRoomMembershipRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostAdminRegistrationTokenRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *GetAdminRegistrationTokensRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *DelAdminRegistrationTokenRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostUserSearchRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *GetAdminEventReportsRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *GetAdminEventReportRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PutAdminEventReportAssignRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostAdminEventReportResolveRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostRoomUpgradeRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *GetRoomHierarchyRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *GetRoomRelationsRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *GetRoomThreadsRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostSearchRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *PostAdminPurgeHistoryRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *DelAdminRoomRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *GetRoomTimestampToEventRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
+
+func (externalReq *GetRegistrationTokenValidityRequest) Encode() ([]byte, error) {
+	return json.Marshal(externalReq)
+}
@@ -81,9 +81,18 @@ func (res *PostLoginResponse) Decode(input []byte) error {
 	if err != nil {
 		return err
 	}
+	res.RefreshToken, err = resCapn.RefreshToken()
+	if err != nil {
+		return err
+	}
+	res.ExpiresInMs = resCapn.ExpiresInMs()
 	return nil
 }
 
+func (res *PostRefreshResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
 func (res *RegisterResponse) Decode(input []byte) error {
 	msg, err := capn.Unmarshal(input)
 	if err != nil {
@@ -111,6 +120,11 @@ func (res *RegisterResponse) Decode(input []byte) error {
 	if err != nil {
 		return err
 	}
+	res.RefreshToken, err = resCapn.RefreshToken()
+	if err != nil {
+		return err
+	}
+	res.ExpiresInMs = resCapn.ExpiresInMs()
 	return nil
 }
 
@@ -1146,3 +1160,79 @@ func (res *PostQueryClientKeysResponse) Decode(input []byte) error {
 func (res *PostClaimClientKeysResponse) Decode(input []byte) error {
 	return json.Unmarshal(input, res)
 }
+
+func (res *GetAdminUsersResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *GetAdminUserDevicesResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *PostAdminUserLogoutResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *PutAdminUserLockResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *GetAdminRoomsResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *GetAdminRoomStateResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *RegistrationToken) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *GetAdminRegistrationTokensResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *PostUserOpenIDResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *GetFedOpenIDUserInfoResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *AdminEventReport) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *GetAdminEventReportsResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *PostAdminEventReportResolveResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *PostRoomUpgradeResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *GetRoomHierarchyResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *PostSearchResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *PostAdminPurgeHistoryResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *DelAdminRoomResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
+
+func (res *GetRegistrationTokenValidityResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, res)
+}
@@ -70,6 +70,8 @@ func (res *PostLoginResponse) Encode() ([]byte, error) {
 	resCapn.SetAccessToken(res.AccessToken)
 	resCapn.SetHomeServer(res.HomeServer)
 	resCapn.SetDeviceID(res.DeviceID)
+	resCapn.SetRefreshToken(res.RefreshToken)
+	resCapn.SetExpiresInMs(res.ExpiresInMs)
 
 	data, err := msg.Marshal()
 	if err != nil {
@@ -79,6 +81,10 @@ func (res *PostLoginResponse) Encode() ([]byte, error) {
 	return data, nil
 }
 
+func (res *PostRefreshResponse) Encode() ([]byte, error) {
+	return json.Marshal(res)
+}
+
 func (res *RegisterResponse) Encode() ([]byte, error) {
 	msg, seg, err := capn.NewMessage(capn.SingleSegment(nil))
 	if err != nil {
@@ -93,6 +99,8 @@ func (res *RegisterResponse) Encode() ([]byte, error) {
 	resCapn.SetAccessToken(res.AccessToken)
 	resCapn.SetHomeServer(res.HomeServer)
 	resCapn.SetDeviceID(res.DeviceID)
+	resCapn.SetRefreshToken(res.RefreshToken)
+	resCapn.SetExpiresInMs(res.ExpiresInMs)
 
 	data, err := msg.Marshal()
 	if err != nil {
@@ -1133,3 +1141,79 @@ func (r *PostQueryClientKeysResponse) Encode() ([]byte, error) {
 func (r *PostClaimClientKeysResponse) Encode() ([]byte, error) {
 	return json.Marshal(r)
 }
+
+func (r *GetAdminUsersResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *GetAdminUserDevicesResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *PostAdminUserLogoutResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *PutAdminUserLockResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *GetAdminRoomsResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *GetAdminRoomStateResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *RegistrationToken) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *GetAdminRegistrationTokensResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *PostUserOpenIDResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *GetFedOpenIDUserInfoResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *AdminEventReport) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *GetAdminEventReportsResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *PostAdminEventReportResolveResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *PostRoomUpgradeResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *GetRoomHierarchyResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *PostSearchResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *PostAdminPurgeHistoryResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *DelAdminRoomResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *GetRegistrationTokenValidityResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
@@ -14,6 +14,8 @@
 
 package external
 
+import jsonRaw "encoding/json"
+
 //POST /_matrix/client/r0/createRoom
 type PostCreateRoomRequest struct {
 	Visibility      string                 `json:"visibility"`
@@ -45,6 +47,80 @@ type PostCreateRoomResponse struct {
 	RoomAlias string `json:"room_alias,omitempty"` // in synapse not spec
 }
 
+//POST /_matrix/client/r0/rooms/{roomId}/upgrade
+type PostRoomUpgradeRequest struct {
+	RoomID     string `json:"room_id"`
+	NewVersion string `json:"new_version"`
+}
+
+type PostRoomUpgradeResponse struct {
+	ReplacementRoom string `json:"replacement_room"`
+}
+
+// GET /_matrix/client/v1/rooms/{roomId}/hierarchy
+type GetRoomHierarchyRequest struct {
+	RoomID        string `json:"room_id"`
+	From          string `json:"from,omitempty"`
+	Limit         string `json:"limit,omitempty"`
+	MaxDepth      string `json:"max_depth,omitempty"`
+	SuggestedOnly bool   `json:"suggested_only,omitempty"`
+}
+
+type GetRoomHierarchyResponse struct {
+	Rooms     []HierarchyRoomsChunk `json:"rooms"`
+	NextBatch string                `json:"next_batch,omitempty"`
+}
+
+type HierarchyRoomsChunk struct {
+	RoomID           string                `json:"room_id"`
+	CanonicalAlias   string                `json:"canonical_alias,omitempty"`
+	Name             string                `json:"name,omitempty"`
+	Topic            string                `json:"topic,omitempty"`
+	AvatarURL        string                `json:"avatar_url,omitempty"`
+	NumJoinedMembers int64                 `json:"num_joined_members"`
+	WorldReadable    bool                  `json:"world_readable"`
+	GuestCanJoin     bool                  `json:"guest_can_join"`
+	JoinRule         string                `json:"join_rule,omitempty"`
+	RoomType         string                `json:"room_type,omitempty"`
+	ChildrenState    []HierarchyChildState `json:"children_state"`
+}
+
+// HierarchyChildState is a stripped m.space.child event
+type HierarchyChildState struct {
+	Type           string             `json:"type"`
+	StateKey       string             `json:"state_key"`
+	Content        jsonRaw.RawMessage `json:"content"`
+	Sender         string             `json:"sender"`
+	OriginServerTS int64              `json:"origin_server_ts"`
+}
+
+// GET /_matrix/client/v1/rooms/{roomId}/relations/{eventId}[/{relType}[/{eventType}]]
+type GetRoomRelationsRequest struct {
+	RoomID    string `json:"room_id"`
+	EventID   string `json:"event_id"`
+	RelType   string `json:"rel_type,omitempty"`
+	EventType string `json:"event_type,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Limit     string `json:"limit,omitempty"`
+	Dir       string `json:"dir,omitempty"`
+}
+
+// GET /_matrix/client/v1/rooms/{roomId}/threads
+type GetRoomThreadsRequest struct {
+	RoomID  string `json:"room_id"`
+	Include string `json:"include,omitempty"`
+	From    string `json:"from,omitempty"`
+	Limit   string `json:"limit,omitempty"`
+}
+
+// GET /_matrix/client/v1/rooms/{roomId}/timestamp_to_event
+type GetRoomTimestampToEventRequest struct {
+	RoomID string `json:"room_id"`
+	TS     string `json:"ts,omitempty"`
+	Dir    string `json:"dir,omitempty"`
+}
+
 //PUT /_matrix/client/r0/directory/room/{roomAlias}
 type PutDirectoryRoomAliasRequest struct {
 	RoomAlias string `json:"roomAlias"`
@@ -0,0 +1,95 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"github.com/finogeeks/ligase/skunkworks/gomatrix"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+)
+
+// POST /_matrix/client/r0/search
+type PostSearchRequest struct {
+	// NextBatch is the next_batch query parameter
+	NextBatch        string           `json:"next_batch,omitempty"`
+	SearchCategories SearchCategories `json:"search_categories"`
+}
+
+type SearchCategories struct {
+	RoomEvents *RoomEventsCriteria `json:"room_events,omitempty"`
+}
+
+type RoomEventsCriteria struct {
+	SearchTerm   string               `json:"search_term"`
+	Keys         []string             `json:"keys,omitempty"`
+	Filter       *gomatrix.FilterPart `json:"filter,omitempty"`
+	OrderBy      string               `json:"order_by,omitempty"`
+	EventContext *SearchEventContext  `json:"event_context,omitempty"`
+	Groupings    *SearchGroupings     `json:"groupings,omitempty"`
+}
+
+type SearchEventContext struct {
+	BeforeLimit    *int `json:"before_limit,omitempty"`
+	AfterLimit     *int `json:"after_limit,omitempty"`
+	IncludeProfile bool `json:"include_profile,omitempty"`
+}
+
+type SearchGroupings struct {
+	GroupBy []SearchGroupBy `json:"group_by,omitempty"`
+}
+
+type SearchGroupBy struct {
+	Key string `json:"key"`
+}
+
+type PostSearchResponse struct {
+	SearchCategories SearchCategoriesResults `json:"search_categories"`
+}
+
+type SearchCategoriesResults struct {
+	RoomEvents *RoomEventsResults `json:"room_events,omitempty"`
+}
+
+type RoomEventsResults struct {
+	Count      int64                                  `json:"count"`
+	Highlights []string                               `json:"highlights"`
+	Results    []SearchResult                         `json:"results"`
+	Groups     map[string]map[string]SearchGroupValue `json:"groups,omitempty"`
+	NextBatch  string                                 `json:"next_batch,omitempty"`
+}
+
+type SearchResult struct {
+	Rank    float64                       `json:"rank"`
+	Result  gomatrixserverlib.ClientEvent `json:"result"`
+	Context *SearchResultContext          `json:"context,omitempty"`
+}
+
+type SearchResultContext struct {
+	Start        string                          `json:"start,omitempty"`
+	End          string                          `json:"end,omitempty"`
+	EventsBefore []gomatrixserverlib.ClientEvent `json:"events_before"`
+	EventsAfter  []gomatrixserverlib.ClientEvent `json:"events_after"`
+	ProfileInfo  map[string]SearchUserProfile    `json:"profile_info,omitempty"`
+}
+
+type SearchUserProfile struct {
+	DisplayName string `json:"displayname,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+type SearchGroupValue struct {
+	Results   []string `json:"results"`
+	Order     int      `json:"order"`
+	NextBatch string   `json:"next_batch,omitempty"`
+}
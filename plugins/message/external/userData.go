@@ -21,7 +21,7 @@ type PostUserSearchRequest struct {
 }
 
 type PostUserSearchResponse struct {
-	Results []User `json:"result"`
+	Results []User `json:"results"`
 	Limited bool   `json:"limited"`
 }
 
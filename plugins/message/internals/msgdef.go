@@ -45,6 +45,7 @@ const (
 	MSG_POST_LOGIN_ADMIN int32 = 0x00010103
 	MSG_POST_LOGOUT      int32 = 0x00010202
 	MSG_POST_LOGOUT_ALL  int32 = 0x00010302
+	MSG_POST_REFRESH     int32 = 0x00010402
 
 	MSG_POST_REGISTER           int32 = 0x00020002
 	MSG_POST_REGISTER_LEGACY    int32 = 0x00020003
@@ -55,12 +56,16 @@ const (
 	MSG_POST_ACCOUT_PASS_MSISDN int32 = 0x00020502
 	MSG_POST_ACCOUNT_DEACTIVATE int32 = 0x00020602
 	MSG_GET_REGISTER_AVAILABLE  int32 = 0x00020700
+	MSG_POST_ACCOUNT_PASS_ADMIN int32 = 0x00020802
+	MSG_POST_ADMIN_DEACTIVATE   int32 = 0x00020902
+	MSG_GET_REG_TOKEN_VALIDITY  int32 = 0x00020a00
 
 	MSG_GET_ACCOUNT_3PID         int32 = 0x00030000
 	MSG_POST_ACCOUNT_3PID        int32 = 0x00030102
 	MSG_POST_ACCOUNT_3PID_DEL    int32 = 0x00030202
 	MSG_POST_ACCOUNT_3PID_EMAIL  int32 = 0x00030302
 	MSG_POST_ACCOUNT_3PID_MSISDN int32 = 0x00030402
+	MSG_GET_ACCOUNT_3PID_SUBMIT  int32 = 0x00030500
 
 	MSG_GET_ACCOUNT_WHOAMI int32 = 0x00040000
 
@@ -80,6 +85,11 @@ const (
 	MSG_GET_ROOM_JOIN_MEMBERS            int32 = 0x00070500
 	MSG_GET_ROOM_MESSAGES                int32 = 0x00070600
 	MSG_GET_ROOM_INITIAL_SYNC            int32 = 0x00070700
+	MSG_GET_ROOM_RELATIONS               int32 = 0x00070800
+	MSG_GET_ROOM_RELATIONS_BY_REL_TYPE   int32 = 0x00070900
+	MSG_GET_ROOM_RELATIONS_BY_EVENT_TYPE int32 = 0x00070a00
+	MSG_GET_ROOM_THREADS                 int32 = 0x00070b00
+	MSG_GET_ROOM_TIMESTAMP_TO_EVENT      int32 = 0x00070c00
 	MSG_POST_ROOM_INFO                   int32 = 0x000706
 
 	MSG_PUT_ROOM_STATE_WITH_TYPE_AND_KEY  int32 = 0x00080001
@@ -91,7 +101,9 @@ const (
 	MSG_PUT_ROOM_UPDATE            int32 = 0x00090003
 	MSG_PUT_ROOM_UPDATE_WITH_TXNID int32 = 0x00090004
 
-	MSG_POST_CREATEROOM int32 = 0x000a0002
+	MSG_POST_CREATEROOM    int32 = 0x000a0002
+	MSG_POST_ROOM_UPGRADE  int32 = 0x000a0102
+	MSG_GET_ROOM_HIERARCHY int32 = 0x000a0200
 
 	MSG_PUT_DIRECTORY_ROOM_ALIAS int32 = 0x000b0001
 	MSG_GET_DIRECTORY_ROOM_ALIAS int32 = 0x000b0100
@@ -215,30 +227,50 @@ const (
 
 	MSG_POST_SYSTEM_MANAGER int32 = 0x00280001
 
-	MSG_GET_FED_VER               int32 = 0x00290001
-	MSG_GET_FED_DIRECTOR          int32 = 0x00290101
-	MSG_GET_FED_PROFILE           int32 = 0x00290201
-	MSG_PUT_FED_SEND              int32 = 0x00290301
-	MSG_PUT_FED_INVITE            int32 = 0x00290401
-	MSG_GET_FED_ROOM_STATE        int32 = 0x00290501
-	MSG_GET_FED_BACKFILL          int32 = 0x00290601
-	MSG_GET_FED_MISSING_EVENTS    int32 = 0x00290611
-	MSG_GET_FED_MEDIA_DOWNLOAD    int32 = 0x00290701
-	MSG_GET_FED_MEDIA_INFO        int32 = 0x00290801
-	MSG_GET_FED_USER_INFO         int32 = 0x00290901
-	MSG_GET_FED_MAKE_JOIN         int32 = 0x00291901
-	MSG_PUT_FED_SEND_JOIN         int32 = 0x00292001
-	MSG_GET_FED_MAKE_LEAVE        int32 = 0x00293901
-	MSG_PUT_FED_SEND_LEAVE        int32 = 0x00294001
-	MSG_GET_FED_EVENT_AUTH        int32 = 0x00294101
-	MSG_POST_FED_QUERY_AUTH       int32 = 0x00294201
-	MSG_GET_FED_EVENT             int32 = 0x00294301
-	MSG_GET_FED_STATE_IDS         int32 = 0x00294401
-	MSG_GET_FED_PUBLIC_ROOMS      int32 = 0x00294501
-	MSG_POST_FED_PUBLIC_ROOMS     int32 = 0x00294601
-	MSG_GET_FED_USER_DEVICES      int32 = 0x00294701
-	MSG_GET_FED_CLIENT_KEYS       int32 = 0x00294801
-	MSG_GET_FED_CLIENT_KEYS_CLAIM int32 = 0x00294901
+	MSG_GET_ADMIN_USERS        int32 = 0x002a0000
+	MSG_GET_ADMIN_USER_DEVICES int32 = 0x002a0100
+	MSG_POST_ADMIN_USER_LOGOUT int32 = 0x002a0202
+	MSG_PUT_ADMIN_USER_LOCK    int32 = 0x002a0301
+	MSG_GET_ADMIN_ROOMS        int32 = 0x002a0400
+	MSG_GET_ADMIN_ROOM_STATE   int32 = 0x002a0500
+	MSG_POST_ADMIN_ROOM_MEMBER int32 = 0x002a0602
+	MSG_POST_ADMIN_REG_TOKEN   int32 = 0x002a0702
+	MSG_GET_ADMIN_REG_TOKENS   int32 = 0x002a0800
+	MSG_DEL_ADMIN_REG_TOKEN    int32 = 0x002a0903
+
+	MSG_GET_ADMIN_EVENT_REPORTS         int32 = 0x002a0a00
+	MSG_GET_ADMIN_EVENT_REPORT          int32 = 0x002a0b00
+	MSG_PUT_ADMIN_EVENT_REPORT_ASSIGN   int32 = 0x002a0c01
+	MSG_POST_ADMIN_EVENT_REPORT_RESOLVE int32 = 0x002a0d02
+
+	MSG_POST_ADMIN_PURGE_HISTORY int32 = 0x002a0e02
+	MSG_DEL_ADMIN_ROOM           int32 = 0x002a0f03
+
+	MSG_GET_FED_VER                int32 = 0x00290001
+	MSG_GET_FED_DIRECTOR           int32 = 0x00290101
+	MSG_GET_FED_PROFILE            int32 = 0x00290201
+	MSG_PUT_FED_SEND               int32 = 0x00290301
+	MSG_PUT_FED_INVITE             int32 = 0x00290401
+	MSG_GET_FED_ROOM_STATE         int32 = 0x00290501
+	MSG_GET_FED_BACKFILL           int32 = 0x00290601
+	MSG_GET_FED_MISSING_EVENTS     int32 = 0x00290611
+	MSG_GET_FED_MEDIA_DOWNLOAD     int32 = 0x00290701
+	MSG_GET_FED_MEDIA_INFO         int32 = 0x00290801
+	MSG_GET_FED_USER_INFO          int32 = 0x00290901
+	MSG_GET_FED_MAKE_JOIN          int32 = 0x00291901
+	MSG_PUT_FED_SEND_JOIN          int32 = 0x00292001
+	MSG_GET_FED_MAKE_LEAVE         int32 = 0x00293901
+	MSG_PUT_FED_SEND_LEAVE         int32 = 0x00294001
+	MSG_GET_FED_EVENT_AUTH         int32 = 0x00294101
+	MSG_POST_FED_QUERY_AUTH        int32 = 0x00294201
+	MSG_GET_FED_EVENT              int32 = 0x00294301
+	MSG_GET_FED_STATE_IDS          int32 = 0x00294401
+	MSG_GET_FED_PUBLIC_ROOMS       int32 = 0x00294501
+	MSG_POST_FED_PUBLIC_ROOMS      int32 = 0x00294601
+	MSG_GET_FED_USER_DEVICES       int32 = 0x00294701
+	MSG_GET_FED_CLIENT_KEYS        int32 = 0x00294801
+	MSG_GET_FED_CLIENT_KEYS_CLAIM  int32 = 0x00294901
+	MSG_GET_FED_TIMESTAMP_TO_EVENT int32 = 0x00295001
 
 	MSG_PUT_FED_EXCHANGE_THIRD_PARTY_INVITE int32 = 0x00294901
 
@@ -379,6 +411,7 @@ type Device struct {
 	Identifier   string `json:"identifier,omitempty"`
 	CreateTs     int64  `json:"create_ts,omitempty"`
 	LastActiveTs int64  `json:"last_active_ts,omitempty"`
+	ExpiresTs    int64  `json:"expires_ts,omitempty"`
 }
 
 type InputMsg struct {
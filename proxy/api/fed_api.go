@@ -74,6 +74,7 @@ func init() {
 	apiconsumer.SetAPIProcessor(ReqPutFedInvite{})
 	apiconsumer.SetAPIProcessor(ReqGetFedRoomState{})
 	apiconsumer.SetAPIProcessor(ReqGetFedBackfill{})
+	apiconsumer.SetAPIProcessor(ReqGetFedTimestampToEvent{})
 	apiconsumer.SetAPIProcessor(ReqGetFedMissingEvents{})
 	apiconsumer.SetAPIProcessor(ReqGetFedMediaInfo{})
 	apiconsumer.SetAPIProcessor(ReqGetFedMediaDownload{})
@@ -636,6 +637,100 @@ func (ReqGetFedBackfill) Process(ctx context.Context, ud interface{}, msg core.C
 	return http.StatusOK, (*ReqGetFedBackfillResponse)(&evs)
 }
 
+type ReqGetFedTimestampToEventRequest struct {
+	RoomID string `json:"roomID"`
+	TS     string `json:"ts"`
+	Dir    string `json:"dir"`
+}
+
+func (r *ReqGetFedTimestampToEventRequest) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *ReqGetFedTimestampToEventRequest) Decode(input []byte) error {
+	return json.Unmarshal(input, r)
+}
+
+type ReqGetFedTimestampToEventResponse gomatrixserverlib.RespTimestampToEvent
+
+func (r *ReqGetFedTimestampToEventResponse) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *ReqGetFedTimestampToEventResponse) Decode(input []byte) error {
+	return json.Unmarshal(input, r)
+}
+
+type ReqGetFedTimestampToEvent struct{}
+
+func (ReqGetFedTimestampToEvent) GetRoute() string       { return "/timestamp_to_event/{roomID}" }
+func (ReqGetFedTimestampToEvent) GetMetricsName() string { return "federation_timestamp_to_event" }
+func (ReqGetFedTimestampToEvent) GetMsgType() int32 {
+	return internals.MSG_GET_FED_TIMESTAMP_TO_EVENT
+}
+func (ReqGetFedTimestampToEvent) GetAPIType() int8 { return apiconsumer.APITypeFed }
+func (ReqGetFedTimestampToEvent) GetMethod() []string {
+	return []string{http.MethodGet, http.MethodOptions}
+}
+func (ReqGetFedTimestampToEvent) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqGetFedTimestampToEvent) GetPrefix() []string                  { return []string{"fedV1"} }
+func (ReqGetFedTimestampToEvent) NewRequest() core.Coder {
+	return new(ReqGetFedTimestampToEventRequest)
+}
+func (ReqGetFedTimestampToEvent) NewResponse(code int) core.Coder {
+	return new(ReqGetFedTimestampToEventResponse)
+}
+func (ReqGetFedTimestampToEvent) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*ReqGetFedTimestampToEventRequest)
+	msg.RoomID = vars["roomID"]
+	msg.TS = req.FormValue("ts")
+	msg.Dir = req.FormValue("dir")
+	return nil
+}
+func (ReqGetFedTimestampToEvent) Process(ctx context.Context, ud interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	req := msg.(*ReqGetFedTimestampToEventRequest)
+	request := ud.(*FedApiUserData).Request
+	idg := ud.(*FedApiUserData).Idg
+
+	ts, err := strconv.ParseInt(req.TS, 10, 64)
+	if err != nil || ts < 0 {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("ts must be a timestamp in milliseconds")
+	}
+	if req.Dir != "f" && req.Dir != "b" {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("dir must be f or b")
+	}
+	rid, err := url.PathUnescape(req.RoomID)
+	if err != nil {
+		rid = req.RoomID
+	}
+
+	gobMsg := model.GobMessage{}
+	gobMsg.MsgSeq = genMsgSeq(idg)
+	gobMsg.Cmd = model.CMD_FED_TIMESTAMP_TO_EVENT
+	tsReq := external.GetFedTimestampToEventRequest{
+		RoomID: rid,
+		TS:     ts,
+		Dir:    req.Dir,
+		Origin: string(request.Origin()),
+	}
+	gobMsg.Body, _ = tsReq.Encode()
+
+	resp, err := bridge.SendAndRecv(gobMsg, 30000)
+	if err != nil {
+		log.Errorf("TimestampToEvent recv request: rid:%s ts:%d dir:%s, err:%v", rid, ts, req.Dir, err)
+		return http.StatusRequestTimeout, jsonerror.Unknown(err.Error())
+	} else if resp.Head.ErrStr != "" {
+		log.Errorf("TimestampToEvent recv request: rid:%s ts:%d dir:%s, err:%s", rid, ts, req.Dir, resp.Head.ErrStr)
+		return http.StatusInternalServerError, jsonerror.Unknown(resp.Head.ErrStr)
+	}
+
+	result := ReqGetFedTimestampToEventResponse{}
+	if err = json.Unmarshal(resp.Body, &result); err != nil || result.EventID == "" {
+		return http.StatusNotFound, jsonerror.NotFound(fmt.Sprintf("Unable to find event from %d in direction %s", ts, req.Dir))
+	}
+	return http.StatusOK, &result
+}
+
 type ReqGetFedMissingEvents struct{}
 
 func (ReqGetFedMissingEvents) GetRoute() string       { return "/get_missing_events/{roomId}" }
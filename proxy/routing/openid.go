@@ -0,0 +1,84 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/common/uid"
+	"github.com/finogeeks/ligase/model"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/proxy/bridge"
+	util "github.com/finogeeks/ligase/skunkworks/gomatrixutil"
+	"github.com/finogeeks/ligase/skunkworks/log"
+)
+
+// OpenIDUserInfo implements GET /_matrix/federation/v1/openid/userinfo
+// The caller is a third party rather than a homeserver, so the request isn't
+// signed and the OpenID token is the only credential.
+func OpenIDUserInfo(req *http.Request, idg *uid.UidGenerator) util.JSONResponse {
+	token := req.URL.Query().Get("access_token")
+	if token == "" {
+		return util.JSONResponse{
+			Code: http.StatusUnauthorized,
+			JSON: jsonerror.UnknownToken("Access Token unknown or expired"),
+		}
+	}
+
+	seq, _ := idg.Next()
+	gobMsg := model.GobMessage{}
+	gobMsg.MsgSeq = strconv.FormatInt(seq, 16)
+	gobMsg.Body = []byte(token)
+	gobMsg.Cmd = model.CMD_FED_OPENID_USERINFO
+
+	resp, err := bridge.SendAndRecv(gobMsg, 30000)
+	return openIDUserInfoResponse(resp, err)
+}
+
+// openIDUserInfoResponse maps the reply of the federation server. A token
+// which can't be checked is reported as unknown, the third party can't do
+// anything about our errors anyway.
+func openIDUserInfoResponse(resp *model.GobMessage, err error) util.JSONResponse {
+	if err != nil {
+		return util.JSONResponse{
+			Code: http.StatusRequestTimeout,
+			JSON: jsonerror.Unknown(err.Error()),
+		}
+	}
+	if resp.Head.ErrStr != "" {
+		log.Errorf("OpenIDUserInfo federation err:%s", resp.Head.ErrStr)
+		return util.JSONResponse{
+			Code: http.StatusUnauthorized,
+			JSON: jsonerror.UnknownToken("Access Token unknown or expired"),
+		}
+	}
+	if len(resp.Body) == 0 {
+		return util.JSONResponse{
+			Code: http.StatusUnauthorized,
+			JSON: jsonerror.UnknownToken("Access Token unknown or expired"),
+		}
+	}
+
+	info := &external.GetFedOpenIDUserInfoResponse{}
+	if err := info.Decode(resp.Body); err != nil {
+		return util.JSONResponse{
+			Code: http.StatusInternalServerError,
+			JSON: jsonerror.Unknown(err.Error()),
+		}
+	}
+	return util.JSONResponse{Code: http.StatusOK, JSON: info}
+}
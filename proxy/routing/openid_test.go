@@ -0,0 +1,58 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/finogeeks/ligase/model"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/plugins/message/internals"
+)
+
+func TestOpenIDUserInfoResponse(t *testing.T) {
+	body, _ := (&external.GetFedOpenIDUserInfoResponse{Sub: "@alice:example.com"}).Encode()
+	failed := &model.GobMessage{}
+	failed.Head.ErrStr = "redis: connection refused"
+
+	cases := []struct {
+		name    string
+		resp    *model.GobMessage
+		err     error
+		code    int
+		errCode string
+	}{
+		{"known token", &model.GobMessage{Body: body}, nil, http.StatusOK, ""},
+		{"unknown token", &model.GobMessage{}, nil, http.StatusUnauthorized, "M_UNKNOWN_TOKEN"},
+		{"federation error", failed, nil, http.StatusUnauthorized, "M_UNKNOWN_TOKEN"},
+		{"timeout", nil, errors.New("timeout"), http.StatusRequestTimeout, "M_UNKNOWN"},
+	}
+	for _, c := range cases {
+		res := openIDUserInfoResponse(c.resp, c.err)
+		if res.Code != c.code {
+			t.Errorf("%s: code = %d, want %d", c.name, res.Code, c.code)
+			continue
+		}
+		if c.errCode == "" {
+			if info, ok := res.JSON.(*external.GetFedOpenIDUserInfoResponse); !ok || info.Sub != "@alice:example.com" {
+				t.Errorf("%s: returned %#v", c.name, res.JSON)
+			}
+		} else if e, ok := res.JSON.(*internals.MatrixError); !ok || e.ErrCode != c.errCode {
+			t.Errorf("%s: returned %#v, want %s", c.name, res.JSON, c.errCode)
+		}
+	}
+}
@@ -150,6 +150,10 @@ func (w *HttpProcessor) Route(path, metricsName, topic string, msgType int32, ap
 				return response
 			}),
 		).Methods(methods...)
+	} else if apiType == apiconsumer.APITypeOptionalAuth {
+		w.router.Handle(path,
+			common.MakeOptionalAuthAPI(metricsName, w.cacheIn, w.cfg, w.tokenFilter, w.histogram, handler),
+		).Methods(methods...)
 	} else if apiType == apiconsumer.APITypeInternal {
 		w.router.Handle(path,
 			common.MakeInternalAPI(metricsName, func(req *http.Request) util.JSONResponse {
@@ -79,9 +79,11 @@ func Setup(
 	prefixMap := map[string]string{
 		"v1":       "/_matrix/client/api/v1",
 		"r0":       "/_matrix/client/r0",
+		"clientV1": "/_matrix/client/v1",
 		"unstable": "/_matrix/client/unstable",
 		"inr0":     "/internal/_matrix/client/r0",
 		"sys":      "/system/manager",
+		"admin":    "/_ligase/admin/v1",
 		"mediaR0":  "/_matrix/media/r0",
 		"mediaV1":  "/_matrix/media/v1",
 		"fedV1":    "/_matrix/federation/v1",
@@ -98,7 +100,7 @@ func Setup(
 
 	apiconsumer.ForeachAPIProcessor(func(p apiconsumer.APIProcessor) bool {
 		for _, prefix := range p.GetPrefix() {
-			if prefix == "inr0" {
+			if prefix == "inr0" && p.GetAPIType() != apiconsumer.APITypeInternal {
 				procs[prefix].Route(
 					p.GetRoute(),
 					p.GetMetricsName(),
@@ -121,6 +123,25 @@ func Setup(
 		return true
 	})
 
+	// SSO answers with redirects, so it is served by the proxy itself
+	muxs["r0"].Handle("/login/sso/redirect",
+		common.MakeExternalAPI("sso_redirect", func(req *http.Request) util.JSONResponse {
+			return SSORedirect(req, &cfg, cacheIn)
+		}),
+	).Methods(http.MethodGet, http.MethodOptions)
+	muxs["r0"].Handle("/login/sso/callback",
+		common.MakeExternalAPI("sso_callback", func(req *http.Request) util.JSONResponse {
+			return SSOCallback(req, &cfg, cacheIn)
+		}),
+	).Methods(http.MethodGet, http.MethodOptions)
+
+	// OpenID userinfo is called by third parties, not signed by a homeserver
+	muxs["fedV1"].Handle("/openid/userinfo",
+		common.MakeExternalAPI("federation_openid_userinfo", func(req *http.Request) util.JSONResponse {
+			return OpenIDUserInfo(req, idg)
+		}),
+	).Methods(http.MethodGet)
+
 	// // r0?
 	// r0Processor.route("/admin/whois/{userId}", "whois", internals.MSG_GET_WHO_IS, http.MethodGet, http.MethodOptions)
 
@@ -128,8 +149,6 @@ func Setup(
 
 	// r0Processor.route("/login/cas/ticket", "cas_ticket", internals.MSG_GET_CAS_LOGIN_TICKET, http.MethodGet, http.MethodOptions)
 
-	// r0Processor.route("/rooms/{roomId}/report/{eventId}", "room_report", internals.MSG_POST_ROOM_REPORT, http.MethodPost, http.MethodOptions)
-
 	// r0Processor.route("/thirdparty/protocols", "thirdparty_protocols", internals.MSG_GET_THIRDPARTY_PROTOS, http.MethodGet, http.MethodOptions)
 
 	// r0Processor.route("/thirdparty/protocol/{protocol}", "thirdparty_by_name", internals.MSG_GET_THIRDPARTY_PROTO_BY_NAME, http.MethodGet, http.MethodOptions)
@@ -141,6 +160,4 @@ func Setup(
 	// r0Processor.route("/thirdparty/location", "thirdparty_location", internals.MSG_GET_THIRDPARTY_LOCATION, http.MethodGet, http.MethodOptions)
 
 	// r0Processor.route("/thirdparty/user", "thirdparty_user", internals.MSG_GET_THIRDPARTY_USER, http.MethodGet, http.MethodOptions)
-
-	// r0Processor.route("/user/{userId}/openid/request_token", "user_openid", internals.MSG_POST_USER_OPENID, http.MethodPost, http.MethodOptions)
 }
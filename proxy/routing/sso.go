@@ -0,0 +1,369 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/common/jwt"
+	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	util "github.com/finogeeks/ligase/skunkworks/gomatrixutil"
+	"github.com/finogeeks/ligase/skunkworks/log"
+)
+
+// ssoSessionLifetime is how long the user has to log in at the provider, in seconds
+const ssoSessionLifetime = 600
+
+var ssoHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// oidcProvider is the part of the provider metadata used by the code flow
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+var (
+	oidcProviderMutex sync.Mutex
+	oidcProviderCache *oidcProvider
+	oidcKeysCache     *jwt.KeySet
+)
+
+// ssoSession is kept in the cache between the redirect and the callback,
+// keyed by the state parameter
+type ssoSession struct {
+	RedirectURL  string `json:"redirect_url"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+// SSORedirect implements GET /login/sso/redirect
+// It sends the client to the provider with a PKCE challenge.
+func SSORedirect(req *http.Request, cfg *config.Dendrite, cache service.Cache) util.JSONResponse {
+	if !cfg.LoginModeEnabled("sso") {
+		return util.JSONResponse{Code: http.StatusNotFound, JSON: jsonerror.NotFound("SSO login is not enabled")}
+	}
+	redirectURL := req.URL.Query().Get("redirectUrl")
+	if redirectURL == "" {
+		return util.JSONResponse{Code: http.StatusBadRequest, JSON: jsonerror.MissingArgument("redirectUrl parameter missing")}
+	}
+	if !ssoClientAllowed(cfg, redirectURL) {
+		return util.JSONResponse{Code: http.StatusBadRequest, JSON: jsonerror.InvalidArgumentValue("redirectUrl is not allowed")}
+	}
+
+	provider, err := getOIDCProvider(cfg)
+	if err != nil {
+		log.Errorf("sso redirect discover provider error: %v", err)
+		return util.JSONResponse{Code: http.StatusBadGateway, JSON: jsonerror.Unknown("SSO provider unavailable")}
+	}
+
+	session := ssoSession{
+		RedirectURL:  redirectURL,
+		CodeVerifier: randomURLSafe(32),
+		Nonce:        randomURLSafe(16),
+	}
+	state := randomURLSafe(16)
+	data, _ := json.Marshal(session)
+	if err = cache.SetSSOSession(state, string(data), ssoSessionLifetime); err != nil {
+		log.Errorf("sso redirect save session error: %v", err)
+		return util.JSONResponse{Code: http.StatusInternalServerError, JSON: jsonerror.Unknown("Internal Server Error")}
+	}
+
+	challenge := sha256.Sum256([]byte(session.CodeVerifier))
+	oidc := cfg.Authorization.OIDC
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", oidc.ClientID)
+	q.Set("redirect_uri", oidc.CallbackURL)
+	q.Set("scope", strings.Join(oidc.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", session.Nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	return util.RedirectResponse(appendQuery(provider.AuthorizationEndpoint, q))
+}
+
+// SSOCallback implements GET /login/sso/callback
+// It redeems the authorization code, maps the user and hands a one-time
+// m.login.token to the client's redirectUrl.
+func SSOCallback(req *http.Request, cfg *config.Dendrite, cache service.Cache) util.JSONResponse {
+	if !cfg.LoginModeEnabled("sso") {
+		return util.JSONResponse{Code: http.StatusNotFound, JSON: jsonerror.NotFound("SSO login is not enabled")}
+	}
+	query := req.URL.Query()
+	if e := query.Get("error"); e != "" {
+		log.Warnf("sso callback provider error: %s %s", e, query.Get("error_description"))
+		return util.JSONResponse{Code: http.StatusForbidden, JSON: jsonerror.Forbidden("SSO login failed: " + e)}
+	}
+
+	data, err := cache.TakeSSOSession(query.Get("state"))
+	if err != nil {
+		log.Errorf("sso callback load session error: %v", err)
+		return util.JSONResponse{Code: http.StatusInternalServerError, JSON: jsonerror.Unknown("Internal Server Error")}
+	}
+	var session ssoSession
+	if data == "" || json.Unmarshal([]byte(data), &session) != nil {
+		return util.JSONResponse{Code: http.StatusBadRequest, JSON: jsonerror.Unknown("Unknown or expired SSO session")}
+	}
+
+	provider, err := getOIDCProvider(cfg)
+	if err != nil {
+		log.Errorf("sso callback discover provider error: %v", err)
+		return util.JSONResponse{Code: http.StatusBadGateway, JSON: jsonerror.Unknown("SSO provider unavailable")}
+	}
+	claims, err := redeemOIDCCode(cfg, provider, query.Get("code"), &session)
+	if err != nil {
+		log.Warnf("sso callback redeem code error: %v", err)
+		return util.JSONResponse{Code: http.StatusForbidden, JSON: jsonerror.Forbidden("SSO login failed")}
+	}
+
+	oidc := cfg.Authorization.OIDC
+	localPart := strings.ToLower(claims.String(oidc.SubjectClaim))
+	if localPart == "" || len(cfg.Matrix.ServerName) == 0 {
+		log.Warnf("sso callback missing claim %s", oidc.SubjectClaim)
+		return util.JSONResponse{Code: http.StatusForbidden, JSON: jsonerror.Forbidden("SSO login failed: missing claim " + oidc.SubjectClaim)}
+	}
+	userID := fmt.Sprintf("@%s:%s", localPart, cfg.Matrix.ServerName[0])
+	if _, _, err = gomatrixserverlib.SplitID('@', userID); err != nil {
+		return util.JSONResponse{Code: http.StatusForbidden, JSON: jsonerror.InvalidUsername("SSO login failed: bad localpart")}
+	}
+
+	token := randomURLSafe(32)
+	if err = cache.SetLoginToken(token, userID, oidc.LoginTokenLifetime); err != nil {
+		log.Errorf("sso callback save login token error: %v", err)
+		return util.JSONResponse{Code: http.StatusInternalServerError, JSON: jsonerror.Unknown("Internal Server Error")}
+	}
+	log.Infof("sso login user %s", userID)
+	return util.RedirectResponse(appendQuery(session.RedirectURL, url.Values{"loginToken": {token}}))
+}
+
+// redeemOIDCCode exchanges the code at the token endpoint, checks the id_token
+// against the provider's keys and returns its claims merged with the userinfo
+// claims.
+func redeemOIDCCode(cfg *config.Dendrite, provider *oidcProvider, code string, session *ssoSession) (jwt.Claims, error) {
+	oidc := cfg.Authorization.OIDC
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", oidc.CallbackURL)
+	form.Set("client_id", oidc.ClientID)
+	form.Set("code_verifier", session.CodeVerifier)
+	tokenReq, err := http.NewRequest(http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if oidc.ClientSecret != "" {
+		tokenReq.SetBasicAuth(url.QueryEscape(oidc.ClientID), url.QueryEscape(oidc.ClientSecret))
+	}
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+	}
+	if err = doJSON(tokenReq, &tokenResp); err != nil {
+		return nil, err
+	}
+	if tokenResp.IDToken == "" {
+		return nil, fmt.Errorf("token response without id_token")
+	}
+
+	claims, err := validateIDToken(cfg, provider, tokenResp.IDToken, false)
+	if err == jwt.ErrUnknownKey {
+		// the provider may have rotated its keys since they were fetched
+		claims, err = validateIDToken(cfg, provider, tokenResp.IDToken, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if claims.String("nonce") != session.Nonce {
+		return nil, fmt.Errorf("id_token nonce mismatch")
+	}
+
+	if provider.UserinfoEndpoint != "" && tokenResp.AccessToken != "" {
+		infoReq, err := http.NewRequest(http.MethodGet, provider.UserinfoEndpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		infoReq.Header.Set("Authorization", "Bearer "+tokenResp.AccessToken)
+		info := jwt.Claims{}
+		if err = doJSON(infoReq, &info); err != nil {
+			return nil, err
+		}
+		// the userinfo response must be about the same subject
+		if info.String("sub") != claims.String("sub") {
+			return nil, fmt.Errorf("userinfo subject mismatch")
+		}
+		for k, v := range info {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+	return claims, nil
+}
+
+// validateIDToken checks the signature of the id_token with the provider's
+// JWKS, then its issuer, audience and expiry.
+func validateIDToken(cfg *config.Dendrite, provider *oidcProvider, idToken string, refreshKeys bool) (jwt.Claims, error) {
+	keys, err := getOIDCKeys(provider, refreshKeys)
+	if err != nil {
+		return nil, err
+	}
+	validator := jwt.NewKeySetValidator(keys, provider.Issuer, []string{cfg.Authorization.OIDC.ClientID}, time.Minute)
+	validator.RequireExpiry()
+	return validator.Validate(idToken, time.Now())
+}
+
+// getOIDCKeys fetches the provider's signing keys once, refresh fetches them
+// again.
+func getOIDCKeys(provider *oidcProvider, refresh bool) (*jwt.KeySet, error) {
+	oidcProviderMutex.Lock()
+	defer oidcProviderMutex.Unlock()
+	if oidcKeysCache != nil && !refresh {
+		return oidcKeysCache, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, provider.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := jwt.ParseKeySet(data)
+	if err != nil {
+		return nil, err
+	}
+	oidcKeysCache = keys
+	return keys, nil
+}
+
+// getOIDCProvider discovers the provider metadata once, and retries on the
+// next login if the discovery failed.
+func getOIDCProvider(cfg *config.Dendrite) (*oidcProvider, error) {
+	oidcProviderMutex.Lock()
+	defer oidcProviderMutex.Unlock()
+	if oidcProviderCache != nil {
+		return oidcProviderCache, nil
+	}
+
+	issuer := strings.TrimSuffix(cfg.Authorization.OIDC.Issuer, "/")
+	req, err := http.NewRequest(http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	provider := &oidcProvider{}
+	if err = doJSON(req, provider); err != nil {
+		return nil, err
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, fmt.Errorf("incomplete provider metadata from %s", issuer)
+	}
+	// the id_tokens are checked against the discovered issuer, it must be
+	// the configured one or another issuer could be trusted through it
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("provider metadata from %s has issuer %s", issuer, provider.Issuer)
+	}
+	oidcProviderCache = provider
+	return provider, nil
+}
+
+func doJSON(req *http.Request, v interface{}) error {
+	body, err := doRequest(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func doRequest(req *http.Request) ([]byte, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, err := ssoHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s returned %d: %s", req.Method, req.URL.Path, resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// ssoClientAllowed reports whether the loginToken may be handed to
+// redirectURL. The URL must have the scheme and host of a whitelist entry and
+// its path must be the entry's path or lie below it, nothing is allowed when
+// the whitelist is empty since the token logs in as the user.
+func ssoClientAllowed(cfg *config.Dendrite, redirectURL string) bool {
+	u, err := url.Parse(redirectURL)
+	if err != nil || u.User != nil {
+		return false
+	}
+	for _, entry := range cfg.Authorization.OIDC.ClientWhitelist {
+		w, err := url.Parse(entry)
+		if err != nil || w.Scheme == "" {
+			continue
+		}
+		if strings.EqualFold(u.Scheme, w.Scheme) && strings.EqualFold(u.Host, w.Host) &&
+			pathWithin(u.Opaque, w.Opaque) && pathWithin(u.Path, w.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathWithin reports whether path is base or a path below it, so that /cb
+// allows /cb/done but not /cbevil.
+func pathWithin(path, base string) bool {
+	if base == "" || path == base {
+		return true
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	return strings.HasPrefix(path, base)
+}
+
+func appendQuery(rawURL string, q url.Values) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + q.Encode()
+}
+
+func randomURLSafe(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,185 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package routing
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/finogeeks/ligase/common/config"
+)
+
+func TestSSOClientAllowed(t *testing.T) {
+	cfg := &config.Dendrite{}
+	if ssoClientAllowed(cfg, "https://app.example.com/") {
+		t.Error("an empty whitelist allows redirects")
+	}
+
+	cfg.Authorization.OIDC.ClientWhitelist = []string{"https://app.example.com/", "com.example.app:/sso", "https://cb.example.com/cb"}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://app.example.com/", true},
+		{"https://app.example.com/#/login?x=1", true},
+		{"https://APP.example.com/login", true},
+		{"https://app.example.com.evil.com/", false},
+		{"https://app.example.com@evil.com/", false},
+		{"https://user@app.example.com/", false},
+		{"http://app.example.com/", false},
+		{"https://evil.com/https://app.example.com/", false},
+		{"com.example.app:/sso/callback", true},
+		{"com.example.app:/other", false},
+		{"com.example.app:/ssoevil", false},
+		{"com.example.app:/sso", true},
+		{"https://cb.example.com/cb", true},
+		{"https://cb.example.com/cb/done?x=1", true},
+		{"https://cb.example.com/cbevil", false},
+		{"https://cb.example.com/", false},
+		{"javascript:alert(1)", false},
+		{"%zz", false},
+	}
+	for _, tt := range tests {
+		if got := ssoClientAllowed(cfg, tt.url); got != tt.want {
+			t.Errorf("ssoClientAllowed(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+// fakeOIDCProvider serves its metadata with issuer, a token endpoint
+// returning idToken and the JWKS of keys.
+type fakeOIDCProvider struct {
+	*httptest.Server
+	keys    map[string]*rsa.PublicKey
+	idToken string
+	issuer  string
+}
+
+func newFakeOIDCProvider() *fakeOIDCProvider {
+	p := &fakeOIDCProvider{keys: map[string]*rsa.PublicKey{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"issuer":%q,"authorization_endpoint":"%[2]s/auth","token_endpoint":"%[2]s/token","jwks_uri":"%[2]s/jwks"}`, p.issuer, p.URL)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token":"at","id_token":%q}`, p.idToken)
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		sep := ""
+		fmt.Fprint(w, `{"keys":[`)
+		for kid, key := range p.keys {
+			n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+			e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+			fmt.Fprintf(w, `%s{"kty":"RSA","kid":%q,"n":%q,"e":%q}`, sep, kid, n, e)
+			sep = ","
+		}
+		fmt.Fprint(w, `]}`)
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *fakeOIDCProvider) provider() *oidcProvider {
+	return &oidcProvider{Issuer: p.URL, TokenEndpoint: p.URL + "/token", JWKSURI: p.URL + "/jwks"}
+}
+
+func signIDToken(t *testing.T, key *rsa.PrivateKey, kid, payload string) string {
+	input := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"`+kid+`"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestRedeemOIDCCodeVerifiesIDToken(t *testing.T) {
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newFakeOIDCProvider()
+	defer p.Close()
+	p.keys["k1"] = &key1.PublicKey
+	oidcKeysCache = nil
+	defer func() { oidcKeysCache = nil }()
+
+	cfg := &config.Dendrite{}
+	cfg.Authorization.OIDC.ClientID = "ligase"
+	session := &ssoSession{CodeVerifier: "verifier", Nonce: "n0nce"}
+	payload := fmt.Sprintf(`{"iss":%q,"aud":"ligase","sub":"alice","nonce":"n0nce","exp":%d}`, p.URL, time.Now().Add(time.Minute).Unix())
+
+	p.idToken = signIDToken(t, key1, "k1", payload)
+	claims, err := redeemOIDCCode(cfg, p.provider(), "code", session)
+	if err != nil || claims.String("sub") != "alice" {
+		t.Fatalf("valid id_token: got %v, %v", claims, err)
+	}
+
+	// a token signed by another key is refused, even with a known kid
+	p.idToken = signIDToken(t, key2, "k1", payload)
+	if _, err = redeemOIDCCode(cfg, p.provider(), "code", session); err == nil {
+		t.Error("a forged id_token is accepted")
+	}
+
+	// the keys are fetched again for an unknown kid
+	p.keys["k2"] = &key2.PublicKey
+	p.idToken = signIDToken(t, key2, "k2", payload)
+	if _, err = redeemOIDCCode(cfg, p.provider(), "code", session); err != nil {
+		t.Errorf("rotated key: %v", err)
+	}
+
+	noExp := fmt.Sprintf(`{"iss":%q,"aud":"ligase","sub":"alice","nonce":"n0nce"}`, p.URL)
+	p.idToken = signIDToken(t, key1, "k1", noExp)
+	if _, err = redeemOIDCCode(cfg, p.provider(), "code", session); err == nil {
+		t.Error("an id_token without exp is accepted")
+	}
+}
+
+func TestGetOIDCProviderChecksIssuer(t *testing.T) {
+	p := newFakeOIDCProvider()
+	defer p.Close()
+	oidcProviderCache = nil
+	defer func() { oidcProviderCache = nil }()
+
+	cfg := &config.Dendrite{}
+	cfg.Authorization.OIDC.Issuer = p.URL + "/"
+	p.issuer = "https://evil.example.com"
+	if _, err := getOIDCProvider(cfg); err == nil {
+		t.Fatal("metadata with another issuer is accepted")
+	}
+	if oidcProviderCache != nil {
+		t.Fatal("metadata with another issuer is cached")
+	}
+
+	p.issuer = p.URL
+	provider, err := getOIDCProvider(cfg)
+	if err != nil || provider.Issuer != p.URL {
+		t.Fatalf("got %v, %v", provider, err)
+	}
+}
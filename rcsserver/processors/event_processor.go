@@ -353,7 +353,9 @@ func (p *EventProcessor) buildEvent(
 		return nil, err
 	}
 	domainID, _ := common.DomainFromID(sender)
-	e, err := common.BuildEvent(&builder, domainID, *p.cfg, p.idg)
+	// The friendship rooms are created by the clients without asking for a
+	// room version, so they have the default one
+	e, err := common.BuildEvent(&builder, domainID, p.cfg.Matrix.DefaultRoomVersion, p.idg)
 	if err != nil {
 		log.Errorf("Failed to build event: %v\n", err)
 		return nil, err
@@ -175,7 +175,7 @@ func buildEvent(
 		return nil, err
 	}
 	domainID, _ := common.DomainFromID(sender)
-	e, err := common.BuildEvent(&builder, domainID, *cfg, idg)
+	e, err := common.BuildEvent(&builder, domainID, cfg.Matrix.DefaultRoomVersion, idg)
 	if err != nil {
 		log.Errorf("Failed to build event: %v\n", err)
 		return nil, err
@@ -215,7 +215,7 @@ func (r *AliasProcessor) sendUpdatedAliasesEvent(
 	}
 
 	// Build the event
-	event, err := common.BuildEvent(&builder, domainID, *r.Cfg, r.Idg)
+	event, err := common.BuildEvent(&builder, domainID, gomatrixserverlib.AuthEventsRoomVersion(rs), r.Idg)
 	if err != nil {
 		return err
 	}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/finogeeks/ligase/adapter"
@@ -63,6 +64,7 @@ type EventsProcessor struct {
 	slot         uint32
 	chanSize     int
 	inputChan    []chan *InputContext
+	blockedRooms sync.Map
 }
 
 func (r *EventsProcessor) SetFed(fed *FedProcessor) {
@@ -74,6 +76,24 @@ func (r *EventsProcessor) NewMonitor() {
 	r.evtProcGauge = monitor.NewLabeledGauge("room_event_process_duration_millisecond", []string{"query", "addition", "room_id"})
 }
 
+// LoadBlockedRooms fills the blocked room set from the database, rooms blocked
+// later are added by BlockRoom
+func (r *EventsProcessor) LoadBlockedRooms(ctx context.Context) error {
+	roomIDs, err := r.DB.SelectBlockedRooms(ctx)
+	if err != nil {
+		return err
+	}
+	for _, roomID := range roomIDs {
+		r.blockedRooms.Store(roomID, true)
+	}
+	log.Infof("load %d blocked rooms", len(roomIDs))
+	return nil
+}
+
+func (r *EventsProcessor) BlockRoom(roomID string) {
+	r.blockedRooms.Store(roomID, true)
+}
+
 func (r *EventsProcessor) Start() {
 	r.chanSize = 1024
 	r.slot = 128
@@ -254,6 +274,11 @@ func (r *EventsProcessor) processRoomNewEvent(
 			return errors.New("Room not found")
 		}
 		isDirect = rs.IsDirect()
+		if event.Type() == gomatrixserverlib.MRoomMember {
+			if err = r.checkBlockedRoom(ctx, &event); err != nil {
+				return err
+			}
+		}
 	}
 
 	if isDirect &&
@@ -304,6 +329,19 @@ func (r *EventsProcessor) processRoomNewEvent(
 	return nil
 }
 
+// checkBlockedRoom refuses the joins and invites of the rooms shut down by
+// the super admin
+func (r *EventsProcessor) checkBlockedRoom(ctx context.Context, event *gomatrixserverlib.Event) error {
+	membership, err := event.Membership()
+	if err != nil || (membership != "join" && membership != "invite") {
+		return nil
+	}
+	if _, ok := r.blockedRooms.Load(event.RoomID()); ok {
+		return errors.New("Room is blocked")
+	}
+	return nil
+}
+
 func (r *EventsProcessor) processDirectRoomCreateOrMemberEvent(
 	ctx context.Context,
 	event gomatrixserverlib.Event,
@@ -410,6 +448,13 @@ func (r *EventsProcessor) processNew(
 		if rs != nil { // 防止重复create
 			return nil
 		}
+		var roomVersion gomatrixserverlib.RoomVersion
+		if roomVersion, err = gomatrixserverlib.CreateEventRoomVersion(&event); err != nil {
+			return err
+		}
+		if _, ok := gomatrixserverlib.SupportedRoomVersions()[roomVersion]; !ok {
+			return gomatrixserverlib.UnsupportedRoomVersionError{Version: roomVersion}
+		}
 		bs := time.Now().UnixNano() / 1000000
 		roomNID, err = r.DB.AssignRoomNID(ctx, event.RoomID())
 		spend := time.Now().UnixNano()/1000000 - bs
@@ -417,6 +462,9 @@ func (r *EventsProcessor) processNew(
 		if err != nil {
 			return err
 		}
+		if err = r.DB.SetRoomVersion(ctx, event.RoomID(), string(roomVersion)); err != nil {
+			return err
+		}
 	} else {
 		if rs == nil {
 			return errors.New("can't find room")
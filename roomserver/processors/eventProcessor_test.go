@@ -0,0 +1,78 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package processors
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+type blockedRoomsDB struct {
+	model.RoomServerDatabase
+	blocked []string
+}
+
+func (d *blockedRoomsDB) SelectBlockedRooms(ctx context.Context) ([]string, error) {
+	return d.blocked, nil
+}
+
+func (d *blockedRoomsDB) IsRoomBlocked(ctx context.Context, roomID string) (bool, error) {
+	panic("the blocked rooms are checked in memory")
+}
+
+func memberEvent(t *testing.T, roomID, membership string) *gomatrixserverlib.Event {
+	ev, err := gomatrixserverlib.NewEventFromTrustedJSON([]byte(fmt.Sprintf(
+		`{"event_id":"$1:example.com","room_id":%q,"type":"m.room.member","state_key":"@bob:example.com","sender":"@bob:example.com","content":{"membership":%q}}`,
+		roomID, membership,
+	)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ev
+}
+
+func TestCheckBlockedRoom(t *testing.T) {
+	r := &EventsProcessor{DB: &blockedRoomsDB{blocked: []string{"!blocked:example.com"}}}
+	if err := r.LoadBlockedRooms(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		roomID     string
+		membership string
+		blocked    bool
+	}{
+		{"!blocked:example.com", "join", true},
+		{"!blocked:example.com", "invite", true},
+		{"!blocked:example.com", "leave", false},
+		{"!blocked:example.com", "ban", false},
+		{"!other:example.com", "join", false},
+	}
+	for _, tt := range tests {
+		err := r.checkBlockedRoom(context.Background(), memberEvent(t, tt.roomID, tt.membership))
+		if (err != nil) != tt.blocked {
+			t.Errorf("%s %s: got err %v, want blocked %t", tt.roomID, tt.membership, err, tt.blocked)
+		}
+	}
+
+	r.BlockRoom("!other:example.com")
+	if err := r.checkBlockedRoom(context.Background(), memberEvent(t, "!other:example.com", "join")); err == nil {
+		t.Error("join of a room blocked after the load was allowed")
+	}
+}
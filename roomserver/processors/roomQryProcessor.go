@@ -68,11 +68,15 @@ func (s *RoomQryProcessor) QueryRoomState(
 	response.Alias = rs.Alias
 	response.Avatar = rs.Avatar
 	response.GuestAccess = rs.GuestAccess
+	response.Encryption = rs.Encryption
+	response.Tombstone = rs.Tombstone
+	response.ServerACL = rs.ServerACL
 
 	response.Join = make(map[string]*gomatrixserverlib.Event)
 	response.Leave = make(map[string]*gomatrixserverlib.Event)
 	response.Invite = make(map[string]*gomatrixserverlib.Event)
 	response.ThirdInvite = make(map[string]*gomatrixserverlib.Event)
+	response.SpaceChild = make(map[string]*gomatrixserverlib.Event)
 
 	rs.GetJoinMap().Range(func(key, value interface{}) bool {
 		response.Join[key.(string)] = value.(*gomatrixserverlib.Event)
@@ -90,6 +94,10 @@ func (s *RoomQryProcessor) QueryRoomState(
 		response.ThirdInvite[key.(string)] = value.(*gomatrixserverlib.Event)
 		return true
 	})
+	rs.GetSpaceChildMap().Range(func(key, value interface{}) bool {
+		response.SpaceChild[key.(string)] = value.(*gomatrixserverlib.Event)
+		return true
+	})
 
 	log.Debugf("RoomQryProcessor recv QueryRoomState set room:%s resp:%v", request.RoomID, response)
 	return nil
@@ -18,6 +18,8 @@
 package roomserver
 
 import (
+	"context"
+
 	"github.com/finogeeks/ligase/common"
 	"github.com/finogeeks/ligase/common/basecomponent"
 	"github.com/finogeeks/ligase/common/filter"
@@ -98,6 +100,9 @@ func SetupRoomServerComponent(
 
 	inputAPI.SetFed(&fedProcessor)
 	inputAPI.NewMonitor()
+	if err := inputAPI.LoadBlockedRooms(context.Background()); err != nil {
+		log.Panicw("failed to load blocked rooms", log.KeysAndValues{"error", err})
+	}
 	inputAPI.Start()
 	if processEvent {
 		consumer := consumers.NewInputRoomEventConsumer(
@@ -113,6 +118,9 @@ func SetupRoomServerComponent(
 	rpcConsumer := consumers.NewQueryConsumer(base.Cfg, roomserverDB, repo, umsRepo, rpcClient, &aliasAPI, &queryAPI)
 	rpcConsumer.Start()
 
+	blockedConsumer := rpc.NewRoomBlockedRpcConsumer(rpcClient, &inputAPI)
+	blockedConsumer.Start()
+
 	return &inputAPI, rsRpcCli, roomserverDB
 }
 
@@ -0,0 +1,62 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/model/types"
+	"github.com/finogeeks/ligase/roomserver/processors"
+	"github.com/nats-io/go-nats"
+
+	log "github.com/finogeeks/ligase/skunkworks/log"
+)
+
+type RoomBlockedRpcConsumer struct {
+	rpcClient *common.RpcClient
+	Proc      *processors.EventsProcessor
+}
+
+func NewRoomBlockedRpcConsumer(
+	rpcClient *common.RpcClient,
+	proc *processors.EventsProcessor,
+) *RoomBlockedRpcConsumer {
+	return &RoomBlockedRpcConsumer{
+		rpcClient: rpcClient,
+		Proc:      proc,
+	}
+}
+
+func (s *RoomBlockedRpcConsumer) GetTopic() string {
+	return types.RoomBlockedTopicDef
+}
+
+// Every roomserver keeps its own blocked room set, so the block is not
+// filtered by group
+func (s *RoomBlockedRpcConsumer) cb(ctx context.Context, msg *nats.Msg) {
+	var result types.RoomBlocked
+	if err := json.Unmarshal(msg.Data, &result); err != nil {
+		log.Errorf("rpc room blocked cb error %v", err)
+		return
+	}
+	log.Infof("room:%s blocked", result.RoomID)
+	s.Proc.BlockRoom(result.RoomID)
+}
+
+func (s *RoomBlockedRpcConsumer) Start() error {
+	s.rpcClient.ReplyWithContext(s.GetTopic(), s.cb)
+	return nil
+}
@@ -112,12 +112,24 @@ type eventFields struct {
 
 var emptyEventReferenceList = []EventReference{}
 
-// Build a new Event.
+// Build a new Event for a room version 1 room.
 // This is used when a local event is created on this server.
 // Call this after filling out the necessary fields.
 // This can be called multiple times on the same builder.
 // A different event ID must be supplied each time this is called.
 func (eb *EventBuilder) Build(eventNID int64, now time.Time, origin ServerName) (result Event, err error) {
+	return eb.BuildWithRoomVersion(eventNID, now, origin, RoomVersionV1)
+}
+
+// BuildWithRoomVersion builds a new Event for a room of the given version.
+// It fails if the room version is not supported by this server.
+func (eb *EventBuilder) BuildWithRoomVersion(
+	eventNID int64, now time.Time, origin ServerName, roomVersion RoomVersion,
+) (result Event, err error) {
+	if _, ok := SupportedRoomVersions()[roomVersion]; !ok {
+		err = UnsupportedRoomVersionError{roomVersion}
+		return
+	}
 	result.fields.RoomID = eb.RoomID
 	result.fields.EventID = fmt.Sprintf("$%d:%s", eventNID, origin)
 	result.fields.EventNID = eventNID
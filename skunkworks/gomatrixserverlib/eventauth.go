@@ -51,6 +51,8 @@ const (
 	MRoomHistoryVisibility = "m.room.history_visibility"
 	// MRoomRedaction https://matrix.org/docs/spec/client_server/r0.2.0.html#id21
 	MRoomRedaction = "m.room.redaction"
+	// MRoomServerACL https://matrix.org/docs/spec/client_server/r0.6.0#m-room-server-acl
+	MRoomServerACL = "m.room.server_acl"
 	// MTyping https://matrix.org/docs/spec/client_server/r0.3.0.html#m-typing
 	MTyping = "m.typing"
 	// customized for room meeting
@@ -350,6 +352,8 @@ func Allowed(event Event, authEvents AuthEventProvider) error {
 		return powerLevelsEventAllowed(event, authEvents)
 	case MRoomRedaction, MRoomUpdate:
 		return redactEventAllowed(event, authEvents)
+	case MRoomServerACL:
+		return serverACLEventAllowed(event, authEvents)
 	default:
 		return defaultEventAllowed(event, authEvents)
 	}
@@ -377,6 +381,13 @@ func createEventAllowed(event Event, authEvents AuthEventProvider) error {
 	if senderDomain != roomIDDomain {
 		return errorf("create event room ID domain does not match sender: %q != %q", roomIDDomain, senderDomain)
 	}
+	roomVersion, err := CreateEventRoomVersion(&event)
+	if err != nil {
+		return errorf("unparsable create event content: %s", err.Error())
+	}
+	if _, ok := SupportedRoomVersions()[roomVersion]; !ok {
+		return errorf("create event has unsupported room version %q", roomVersion)
+	}
 	return nil
 }
 
@@ -422,7 +433,11 @@ func aliasEventAllowed(event Event, authEvents AuthEventProvider) error {
 	// Check that the state key matches the server sending this event.
 	// https://github.com/matrix-org/synapse/blob/v0.18.5/synapse/api/auth.py#L158
 	if !event.StateKeyEquals(senderDomain) {
-		return errorf("alias state_key does not match sender domain, %q != %q", senderDomain, event.StateKey())
+		var stateKey string
+		if event.StateKey() != nil {
+			stateKey = *event.StateKey()
+		}
+		return errorf("alias state_key does not match sender domain, %q != %q", senderDomain, stateKey)
 	}
 
 	return nil
@@ -681,7 +696,7 @@ func redactEventAllowed(event Event, authEvents AuthEventProvider) error {
 		} else {
 			return errorf(
 				"%q is not allowed to redact message from %q send by %s create by %s",
-				event.Sender(), redactDomain, event.RedactEventSender, allower.create.Creator,
+				event.Sender(), redactDomain, event.RedactEventSender(), allower.create.Creator,
 			)
 		}
 	}
@@ -713,6 +728,18 @@ func defaultEventAllowed(event Event, authEvents AuthEventProvider) error {
 	return allower.commonChecks(event)
 }
 
+// serverACLEventAllowed checks whether the m.room.server_acl event is allowed.
+// The content has to be well formed, otherwise the default checks apply.
+func serverACLEventAllowed(event Event, authEvents AuthEventProvider) error {
+	if !event.StateKeyEquals("") {
+		return errorf("server ACL event state key is not empty: %v", event.StateKey())
+	}
+	if _, err := NewServerACLFromEvent(&event); err != nil {
+		return err
+	}
+	return defaultEventAllowed(event, authEvents)
+}
+
 // An eventAllower has the information needed to authorise all events types
 // other than m.room.create, m.room.member and m.room.aliases which are special.
 type eventAllower struct {
@@ -865,7 +892,7 @@ func newMembershipAllower(authEvents AuthEventProvider, event Event) (m membersh
 		return
 	}
 	// We only need to check the join rules if the proposed membership is "join".
-	if m.newMember.Membership == "join" {
+	if m.newMember.Membership == join {
 		if m.joinRule, err = newJoinRuleContentFromAuthEvents(authEvents); err != nil {
 			return
 		}
@@ -16,7 +16,7 @@
 package gomatrixserverlib
 
 import (
-	"encoding/json"
+	encjson "encoding/json"
 	"testing"
 )
 
@@ -128,7 +128,7 @@ func TestStateNeededForJoin(t *testing.T) {
 		StateKey: &skey,
 		Sender:   "@u1:a",
 	}
-	if err := b.SetContent(memberContent{"join", nil}); err != nil {
+	if err := b.SetContent(memberContent{Membership: "join"}); err != nil {
 		t.Fatal(err)
 	}
 	testStateNeededForAuth(t, `[{
@@ -151,7 +151,7 @@ func TestStateNeededForInvite(t *testing.T) {
 		StateKey: &skey,
 		Sender:   "@u1:a",
 	}
-	if err := b.SetContent(memberContent{"invite", nil}); err != nil {
+	if err := b.SetContent(memberContent{Membership: "invite"}); err != nil {
 		t.Fatal(err)
 	}
 	testStateNeededForAuth(t, `[{
@@ -174,7 +174,7 @@ func TestStateNeededForInvite3PID(t *testing.T) {
 		Sender:   "@u1:a",
 	}
 
-	if err := b.SetContent(memberContent{"invite", &memberThirdPartyInvite{
+	if err := b.SetContent(memberContent{Membership: "invite", ThirdPartyInvite: &memberThirdPartyInvite{
 		Signed: memberThirdPartyInviteSigned{
 			Token: "my_token",
 		},
@@ -202,11 +202,11 @@ func TestStateNeededForInvite3PID(t *testing.T) {
 }
 
 type testAuthEvents struct {
-	CreateJSON           json.RawMessage            `json:"create"`
-	JoinRulesJSON        json.RawMessage            `json:"join_rules"`
-	PowerLevelsJSON      json.RawMessage            `json:"power_levels"`
-	MemberJSON           map[string]json.RawMessage `json:"member"`
-	ThirdPartyInviteJSON map[string]json.RawMessage `json:"third_party_invite"`
+	CreateJSON           encjson.RawMessage            `json:"create"`
+	JoinRulesJSON        encjson.RawMessage            `json:"join_rules"`
+	PowerLevelsJSON      encjson.RawMessage            `json:"power_levels"`
+	MemberJSON           map[string]encjson.RawMessage `json:"member"`
+	ThirdPartyInviteJSON map[string]encjson.RawMessage `json:"third_party_invite"`
 }
 
 func (tae *testAuthEvents) Create() (*Event, error) {
@@ -266,9 +266,9 @@ func (tae *testAuthEvents) ThirdPartyInvite(stateKey string) (*Event, error) {
 }
 
 type testCase struct {
-	AuthEvents testAuthEvents    `json:"auth_events"`
-	Allowed    []json.RawMessage `json:"allowed"`
-	NotAllowed []json.RawMessage `json:"not_allowed"`
+	AuthEvents testAuthEvents       `json:"auth_events"`
+	Allowed    []encjson.RawMessage `json:"allowed"`
+	NotAllowed []encjson.RawMessage `json:"not_allowed"`
 }
 
 func testEventAllowed(t *testing.T, testCaseJSON string) {
@@ -18,6 +18,7 @@ package gomatrixserverlib
 import (
 	"github.com/finogeeks/ligase/adapter"
 	//"encoding/json"
+	"fmt"
 	"runtime/debug"
 	"strconv"
 	"strings"
@@ -37,6 +38,8 @@ type createContent struct {
 	Federate *bool `json:"m.federate"`
 	// The creator of the room tells us what the default power levels are.
 	Creator string `json:"creator"`
+	// The version of the room, absent means "1".
+	RoomVersion *RoomVersion `json:"room_version,omitempty"`
 }
 
 // newCreateContentFromAuthEvents loads the create event content from the create event in the
@@ -62,6 +65,42 @@ func newCreateContentFromAuthEvents(authEvents AuthEventProvider) (c createConte
 	return
 }
 
+// roomVersion returns the version of the room, rooms created before room
+// versions existed are version 1.
+func (c *createContent) roomVersion() RoomVersion {
+	if c.RoomVersion == nil {
+		return RoomVersionV1
+	}
+	return *c.RoomVersion
+}
+
+// CreateEventRoomVersion returns the version of the room created by the
+// m.room.create event.
+func CreateEventRoomVersion(createEvent *Event) (RoomVersion, error) {
+	if createEvent == nil || createEvent.Type() != MRoomCreate {
+		return "", fmt.Errorf("gomatrixserverlib: not an m.room.create event")
+	}
+	var c createContent
+	if err := json.Unmarshal(createEvent.Content(), &c); err != nil {
+		return "", err
+	}
+	return c.roomVersion(), nil
+}
+
+// AuthEventsRoomVersion returns the version of the room from the create event
+// in the auth events, version 1 if it can't be found.
+func AuthEventsRoomVersion(authEvents AuthEventProvider) RoomVersion {
+	createEvent, err := authEvents.Create()
+	if err != nil || createEvent == nil {
+		return RoomVersionV1
+	}
+	roomVersion, err := CreateEventRoomVersion(createEvent)
+	if err != nil {
+		return RoomVersionV1
+	}
+	return roomVersion
+}
+
 // domainAllowed checks whether the domain is allowed in the room by the
 // "m.federate" flag.
 func (c *createContent) domainAllowed(domain string) error {
@@ -106,9 +106,9 @@ func (ac *FederationClient) MakeJoin(
 		url.PathEscape(userID)
 	for i, v := range ver {
 		if i == 0 {
-			path += "?v=" + url.PathEscape(v)
+			path += "?ver=" + url.QueryEscape(v)
 		} else {
-			path += "&v=" + url.PathEscape(v)
+			path += "&ver=" + url.QueryEscape(v)
 		}
 	}
 	req := NewFederationRequest("GET", s, path)
@@ -297,6 +297,22 @@ func (ac *FederationClient) Backfill(
 	return
 }
 
+// TimestampToEvent asks a homeserver for the event of a room closest to ts in
+// the direction dir, "f" or "b".
+// See https://spec.matrix.org/v1.6/server-server-api/#get_matrixfederationv1timestamp_to_eventroomid
+func (ac *FederationClient) TimestampToEvent(
+	ctx context.Context, s ServerName, roomID string, ts int64, dir string,
+) (res RespTimestampToEvent, err error) {
+	query := url.Values{}
+	query.Set("ts", strconv.FormatInt(ts, 10))
+	query.Set("dir", dir)
+	path := federationPathPrefix + "/timestamp_to_event/" +
+		url.PathEscape(roomID) + "?" + query.Encode()
+	req := NewFederationRequest("GET", s, path)
+	err = ac.doRequest(ctx, req, &res)
+	return
+}
+
 func (ac *FederationClient) LookupDisplayname(
 	ctx context.Context, s ServerName, userID string,
 ) (res RespDisplayname, err error) {
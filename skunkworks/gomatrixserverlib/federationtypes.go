@@ -179,6 +179,17 @@ type RespMakeJoin struct {
 	// generated by the responding server.
 	// See https://matrix.org/docs/spec/server_server/unstable.html#joining-rooms
 	JoinEvent EventBuilder `json:"event"`
+	// The version of the room, absent means "1".
+	RoomVersion RoomVersion `json:"room_version,omitempty"`
+}
+
+// GetRoomVersion returns the version of the room, servers that don't send it
+// only know version 1.
+func (r *RespMakeJoin) GetRoomVersion() RoomVersion {
+	if r.RoomVersion == "" {
+		return RoomVersionV1
+	}
+	return r.RoomVersion
 }
 
 func (r *RespMakeJoin) Encode() ([]byte, error) {
@@ -291,6 +302,17 @@ type RespMakeLeave struct {
 	// generated by the responding server.
 	// See https://matrix.org/docs/spec/server_server/unstable#leaving-rooms-rejecting-invites
 	Event EventBuilder `json:"event"`
+	// The version of the room, absent means "1".
+	RoomVersion RoomVersion `json:"room_version,omitempty"`
+}
+
+// GetRoomVersion returns the version of the room, servers that don't send it
+// only know version 1.
+func (r *RespMakeLeave) GetRoomVersion() RoomVersion {
+	if r.RoomVersion == "" {
+		return RoomVersionV1
+	}
+	return r.RoomVersion
 }
 
 func (r *RespMakeLeave) Encode() ([]byte, error) {
@@ -447,6 +469,12 @@ type RespUserInfo struct {
 	Email     string `json:"email"`
 }
 
+// RespTimestampToEvent is the event of a room closest to a given timestamp
+type RespTimestampToEvent struct {
+	EventID        string    `json:"event_id"`
+	OriginServerTS Timestamp `json:"origin_server_ts"`
+}
+
 type ReqGetMissingEventContent struct {
 	EarliestEvents []string `json:"earliest_events"`
 	LatestEvents   []string `json:"latest_events"`
@@ -1,7 +1,6 @@
 package gomatrixserverlib
 
 import (
-	"encoding/json"
 	"testing"
 )
 
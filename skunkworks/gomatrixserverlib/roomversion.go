@@ -0,0 +1,124 @@
+/* Copyright 2020 The Matrix.org Foundation C.I.C.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gomatrixserverlib
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// A RoomVersion refers to the room version for a specific room.
+// https://matrix.org/docs/spec/#room-versions
+type RoomVersion string
+
+// Room version constants. These are strings because the version grammar
+// allows for future expansion.
+// https://matrix.org/docs/spec/#room-version-grammar
+const (
+	RoomVersionV1 RoomVersion = "1"
+)
+
+// RoomVersionDefault is the room version used when a room is created without
+// asking for one, unless the config says otherwise.
+var RoomVersionDefault = RoomVersionV1
+
+// Only room version 1 is known. The later versions need state resolution v2,
+// signed events with auth_events and prev_events, the key validity checks of
+// version 5 and the canonical JSON of version 6. Events of this server carry
+// none of these, so rooms of other versions are refused rather than handled
+// with the wrong rules.
+var roomVersionMeta = map[RoomVersion]RoomVersionDescription{
+	RoomVersionV1: {
+		Supported: true,
+		Stable:    true,
+	},
+}
+
+// RoomVersions returns information about room versions currently
+// implemented by this commit of gomatrixserverlib.
+func RoomVersions() map[RoomVersion]RoomVersionDescription {
+	return roomVersionMeta
+}
+
+// SupportedRoomVersions returns a map of descriptions for room
+// versions that are supported by this homeserver.
+func SupportedRoomVersions() map[RoomVersion]RoomVersionDescription {
+	versions := make(map[RoomVersion]RoomVersionDescription)
+	for id, version := range RoomVersions() {
+		if version.Supported {
+			versions[id] = version
+		}
+	}
+	return versions
+}
+
+// StableRoomVersions returns a map of descriptions for room
+// versions that are marked as stable.
+func StableRoomVersions() map[RoomVersion]RoomVersionDescription {
+	versions := make(map[RoomVersion]RoomVersionDescription)
+	for id, version := range RoomVersions() {
+		if version.Supported && version.Stable {
+			versions[id] = version
+		}
+	}
+	return versions
+}
+
+// SortedRoomVersions returns the supported room versions in ascending order,
+// numeric versions first.
+func SortedRoomVersions() []RoomVersion {
+	versions := make([]RoomVersion, 0, len(roomVersionMeta))
+	for id := range SupportedRoomVersions() {
+		versions = append(versions, id)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		a, errA := strconv.Atoi(string(versions[i]))
+		b, errB := strconv.Atoi(string(versions[j]))
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		if errA == nil || errB == nil {
+			return errA == nil
+		}
+		return versions[i] < versions[j]
+	})
+	return versions
+}
+
+// RoomVersionDescription contains information about a room version,
+// namely whether it is marked as supported or stable in this server
+// version.
+//
+// A version is supported if the server has some support for rooms
+// that are this version. A version is marked as stable or unstable
+// in order to hint whether the version should be used to clients
+// calling the /capabilities endpoint.
+// https://matrix.org/docs/spec/client_server/r0.6.0#get-matrix-client-r0-capabilities
+type RoomVersionDescription struct {
+	Supported bool
+	Stable    bool
+}
+
+// UnsupportedRoomVersionError occurs when a call has been made with a room
+// version that is not supported by this version of gomatrixserverlib.
+type UnsupportedRoomVersionError struct {
+	Version RoomVersion
+}
+
+func (e UnsupportedRoomVersionError) Error() string {
+	return fmt.Sprintf("gomatrixserverlib: unsupported room version '%s'", e.Version)
+}
@@ -0,0 +1,89 @@
+/* Copyright 2020 The Matrix.org Foundation C.I.C.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gomatrixserverlib
+
+import (
+	"testing"
+	"time"
+)
+
+func testRoomVersionBuilder(content string) EventBuilder {
+	skey := ""
+	return EventBuilder{
+		Sender:   "@u1:a",
+		RoomID:   "!r1:a",
+		Type:     "m.room.name",
+		StateKey: &skey,
+		Depth:    3,
+		Content:  rawJSON(content),
+	}
+}
+
+func TestBuildWithRoomVersionV1EventID(t *testing.T) {
+	b := testRoomVersionBuilder(`{"name":"n"}`)
+	ev, err := b.BuildWithRoomVersion(42, time.Now(), "a", RoomVersionV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.EventID() != "$42:a" {
+		t.Fatalf("event ID = %q, want $42:a", ev.EventID())
+	}
+	if _, err = b.BuildWithRoomVersion(42, time.Now(), "a", "unknown"); err == nil {
+		t.Fatal("expected an error for an unknown room version")
+	}
+}
+
+func TestSupportedRoomVersions(t *testing.T) {
+	versions := SortedRoomVersions()
+	if len(versions) != 1 || versions[0] != RoomVersionV1 {
+		t.Fatalf("supported room versions = %v, want [1]", versions)
+	}
+	if _, ok := SupportedRoomVersions()[RoomVersionDefault]; !ok {
+		t.Fatalf("the default room version %s is not supported", RoomVersionDefault)
+	}
+}
+
+func TestAllowedCreateRoomVersion(t *testing.T) {
+	testEventAllowed(t, `{
+		"auth_events": {},
+		"allowed": [{
+			"type": "m.room.create",
+			"state_key": "",
+			"sender": "@u1:a",
+			"room_id": "!r1:a",
+			"event_id": "$e1:a",
+			"content": {"creator": "@u1:a"}
+		}, {
+			"type": "m.room.create",
+			"state_key": "",
+			"sender": "@u1:a",
+			"room_id": "!r1:a",
+			"event_id": "$e2:a",
+			"content": {"creator": "@u1:a", "room_version": "1"}
+		}],
+		"not_allowed": [{
+			"type": "m.room.create",
+			"state_key": "",
+			"sender": "@u1:a",
+			"room_id": "!r1:a",
+			"event_id": "$e3:a",
+			"content": {"creator": "@u1:a", "room_version": "6"},
+			"unsigned": {
+				"not_allowed": "Room version 6 is not supported"
+			}
+		}]
+	}`)
+}
@@ -0,0 +1,131 @@
+/* Copyright 2020 The Matrix.org Foundation C.I.C.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gomatrixserverlib
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// serverACLContent is the JSON content of a m.room.server_acl event.
+// https://matrix.org/docs/spec/client_server/r0.6.0#m-room-server-acl
+type serverACLContent struct {
+	Allow           []string `json:"allow"`
+	Deny            []string `json:"deny"`
+	AllowIPLiterals *bool    `json:"allow_ip_literals"`
+}
+
+// ServerACL is a m.room.server_acl event compiled for matching server names.
+// A nil ServerACL allows every server.
+type ServerACL struct {
+	allowIPLiterals bool
+	allow           []*regexp.Regexp
+	deny            []*regexp.Regexp
+}
+
+// NewServerACLFromEvent compiles the server ACL of a room. It returns nil if
+// the room has no m.room.server_acl event.
+func NewServerACLFromEvent(event *Event) (*ServerACL, error) {
+	if event == nil {
+		return nil, nil
+	}
+	if event.Type() != MRoomServerACL {
+		return nil, errorf("event %s is not a m.room.server_acl event", event.EventID())
+	}
+	var content serverACLContent
+	if err := json.Unmarshal(event.Content(), &content); err != nil {
+		return nil, errorf("unparsable m.room.server_acl content: %s", err.Error())
+	}
+
+	acl := &ServerACL{allowIPLiterals: true}
+	if content.AllowIPLiterals != nil {
+		acl.allowIPLiterals = *content.AllowIPLiterals
+	}
+	for _, glob := range content.Allow {
+		acl.allow = append(acl.allow, compileServerGlob(glob))
+	}
+	for _, glob := range content.Deny {
+		acl.deny = append(acl.deny, compileServerGlob(glob))
+	}
+	return acl, nil
+}
+
+// IsServerAllowed returns true if the server is allowed to take part in the
+// room. The rules match the server name without its port. Deny rules take
+// precedence over allow rules, and a server must match at least one allow rule.
+func (acl *ServerACL) IsServerAllowed(serverName string) bool {
+	if acl == nil {
+		return true
+	}
+	host := serverHost(serverName)
+	if !acl.allowIPLiterals && isIPLiteral(host) {
+		return false
+	}
+	for _, re := range acl.deny {
+		if re.MatchString(host) {
+			return false
+		}
+	}
+	for _, re := range acl.allow {
+		if re.MatchString(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileServerGlob turns a server name glob, where * matches any number of
+// characters and ? matches exactly one, into an anchored regexp.
+func compileServerGlob(glob string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// serverHost returns the server name without its port.
+func serverHost(serverName string) string {
+	if strings.HasPrefix(serverName, "[") {
+		if i := strings.Index(serverName, "]"); i >= 0 {
+			return serverName[:i+1]
+		}
+		return serverName
+	}
+	if i := strings.LastIndex(serverName, ":"); i >= 0 && strings.Count(serverName, ":") == 1 {
+		return serverName[:i]
+	}
+	return serverName
+}
+
+// isIPLiteral returns true if the host is an IPv4 address or a bracketed IPv6
+// address.
+func isIPLiteral(host string) bool {
+	if strings.HasPrefix(host, "[") {
+		return true
+	}
+	return net.ParseIP(host) != nil
+}
@@ -0,0 +1,94 @@
+/* Copyright 2020 The Matrix.org Foundation C.I.C.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gomatrixserverlib
+
+import (
+	"testing"
+	"time"
+)
+
+func testServerACL(t *testing.T, content string) *ServerACL {
+	skey := ""
+	b := EventBuilder{
+		Sender:   "@u1:a",
+		RoomID:   "!r1:a",
+		Type:     MRoomServerACL,
+		StateKey: &skey,
+		Content:  rawJSON(content),
+	}
+	ev, err := b.Build(1, time.Now(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl, err := NewServerACLFromEvent(&ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acl
+}
+
+func TestServerACLGlobs(t *testing.T) {
+	acl := testServerACL(t, `{"allow":["*.example.com","matrix.org","b?.net"],"deny":["evil.example.com"]}`)
+	tests := []struct {
+		server  string
+		allowed bool
+	}{
+		{"a.example.com", true},
+		{"a.b.example.com", true},
+		{"example.com", false},
+		{"evil.example.com", false},
+		{"matrix.org", true},
+		{"matrix.org.evil", false},
+		{"bc.net", true},
+		{"b.net", false},
+		{"bcd.net", false},
+	}
+	for _, tt := range tests {
+		if got := acl.IsServerAllowed(tt.server); got != tt.allowed {
+			t.Errorf("IsServerAllowed(%q) = %v, want %v", tt.server, got, tt.allowed)
+		}
+	}
+}
+
+func TestServerACLPorts(t *testing.T) {
+	acl := testServerACL(t, `{"allow":["*"],"deny":["evil.com","*.bad.org"],"allow_ip_literals":false}`)
+	tests := []struct {
+		server  string
+		allowed bool
+	}{
+		{"good.com:8448", true},
+		{"evil.com", false},
+		{"evil.com:8448", false},
+		{"a.bad.org:443", false},
+		{"1.2.3.4", false},
+		{"1.2.3.4:8448", false},
+		{"[::1]", false},
+		{"[::1]:8448", false},
+	}
+	for _, tt := range tests {
+		if got := acl.IsServerAllowed(tt.server); got != tt.allowed {
+			t.Errorf("IsServerAllowed(%q) = %v, want %v", tt.server, got, tt.allowed)
+		}
+	}
+
+	acl = testServerACL(t, `{"allow":["1.2.3.4"]}`)
+	if !acl.IsServerAllowed("1.2.3.4:8448") {
+		t.Error("IP literals are allowed by default and matched without the port")
+	}
+	if (*ServerACL)(nil).IsServerAllowed("any.com:1") != true {
+		t.Error("a room without server ACL allows every server")
+	}
+}
@@ -18,7 +18,7 @@ package gomatrixserverlib
 import (
 	"bytes"
 	"encoding/base64"
-	"encoding/json"
+	encjson "encoding/json"
 	"testing"
 
 	"golang.org/x/crypto/ed25519"
@@ -177,17 +177,17 @@ func TestSignJSONTestVectors(t *testing.T) {
 }
 
 type MyMessage struct {
-	Unsigned   *json.RawMessage `json:"unsigned"`
-	Content    *json.RawMessage `json:"content"`
-	Signatures *json.RawMessage `json:"signatures,omitempty"`
+	Unsigned   *encjson.RawMessage `json:"unsigned"`
+	Content    *encjson.RawMessage `json:"content"`
+	Signatures *encjson.RawMessage `json:"signatures,omitempty"`
 }
 
 func TestSignJSONWithUnsigned(t *testing.T) {
 	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
 	entityName := "example.com"
 	keyID := KeyID("ed25519:my_key_id")
-	content := json.RawMessage(`{"signed":"data"}`)
-	unsigned := json.RawMessage(`{"unsigned":"data"}`)
+	content := encjson.RawMessage(`{"signed":"data"}`)
+	unsigned := encjson.RawMessage(`{"unsigned":"data"}`)
 	message := MyMessage{&unsigned, &content, nil}
 
 	input, err := json.Marshal(&message)
@@ -208,7 +208,7 @@ func TestSignJSONWithUnsigned(t *testing.T) {
 	if err2 := json.Unmarshal(signed, &message); err2 != nil {
 		t.Fatal(err2)
 	}
-	newUnsigned := json.RawMessage(`{"different":"data"}`)
+	newUnsigned := encjson.RawMessage(`{"different":"data"}`)
 	message.Unsigned = &newUnsigned
 	input, err = json.Marshal(&message)
 	if err != nil {
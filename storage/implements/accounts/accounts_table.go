@@ -62,6 +62,29 @@ const selectActualCountSQL = "" +
 const updateAccountSQL = "" +
 	"UPDATE account_accounts SET app_service_id = $1 WHERE user_id = $2"
 
+const selectPasswordHashSQL = "" +
+	"SELECT password_hash FROM account_accounts WHERE user_id = $1"
+
+const updatePasswordHashSQL = "" +
+	"UPDATE account_accounts SET password_hash = $1 WHERE user_id = $2"
+
+// The admin listing of the accounts. $1 is a LIKE pattern matched against
+// the user ID and the display name, or an empty string to list every account.
+const selectAccountsPageSQL = "" +
+	"SELECT a.user_id, a.created_ts, COALESCE(p.display_name, ''), COALESCE(p.avatar_url, '')," +
+	" d.user_id IS NOT NULL, l.user_id IS NOT NULL" +
+	" FROM account_accounts a" +
+	" LEFT JOIN account_profiles p ON p.user_id = a.user_id" +
+	" LEFT JOIN account_deactivated d ON d.user_id = a.user_id" +
+	" LEFT JOIN account_locked l ON l.user_id = a.user_id" +
+	" WHERE $1 = '' OR a.user_id ILIKE $1 OR p.display_name ILIKE $1" +
+	" ORDER BY a.user_id LIMIT $2 OFFSET $3"
+
+const selectAccountsPageCountSQL = "" +
+	"SELECT count(1) FROM account_accounts a" +
+	" LEFT JOIN account_profiles p ON p.user_id = a.user_id" +
+	" WHERE $1 = '' OR a.user_id ILIKE $1 OR p.display_name ILIKE $1"
+
 type accountsStatements struct {
 	db                      *Database
 	insertAccountStmt       *sql.Stmt
@@ -69,6 +92,10 @@ type accountsStatements struct {
 	selectAccountStmt       *sql.Stmt
 	selectActualCountStmt   *sql.Stmt
 	updateAccountStmt       *sql.Stmt
+	selectPasswordHashStmt  *sql.Stmt
+	updatePasswordHashStmt  *sql.Stmt
+	selectAccountsPageStmt  *sql.Stmt
+	selectPageCountStmt     *sql.Stmt
 }
 
 func (s *accountsStatements) getSchema() string {
@@ -92,6 +119,18 @@ func (s *accountsStatements) prepare(d *Database) (err error) {
 	if s.updateAccountStmt, err = d.db.Prepare(updateAccountSQL); err != nil {
 		return
 	}
+	if s.selectPasswordHashStmt, err = d.db.Prepare(selectPasswordHashSQL); err != nil {
+		return
+	}
+	if s.updatePasswordHashStmt, err = d.db.Prepare(updatePasswordHashSQL); err != nil {
+		return
+	}
+	if s.selectAccountsPageStmt, err = d.db.Prepare(selectAccountsPageSQL); err != nil {
+		return
+	}
+	if s.selectPageCountStmt, err = d.db.Prepare(selectAccountsPageCountSQL); err != nil {
+		return
+	}
 	return
 }
 
@@ -112,6 +151,31 @@ func (s *accountsStatements) selectAccount(ctx context.Context, userID string) (
 	return &account, nil
 }
 
+// selectPasswordHash returns the password hash of the account, which is empty
+// for a passwordless account. Returns sql.ErrNoRows if the account doesn't exist.
+func (s *accountsStatements) selectPasswordHash(
+	ctx context.Context, userID string,
+) (string, error) {
+	var hash sql.NullString
+	err := s.selectPasswordHashStmt.QueryRowContext(ctx, userID).Scan(&hash)
+	return hash.String, err
+}
+
+// updatePasswordHash replaces the password hash of the account. It always
+// writes through to the db, a stale hash must never be accepted after a change.
+func (s *accountsStatements) updatePasswordHash(
+	ctx context.Context, userID, hash string,
+) error {
+	res, err := s.updatePasswordHashStmt.ExecContext(ctx, hash, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 // insertAccount creates a new account. 'hash' should be the password hash for this account. If it is missing,
 // this account will be passwordless. Returns an error if this account already exists. Returns the account
 // on success.
@@ -181,3 +245,32 @@ func (s *accountsStatements) selectActualTotal(
 	err = s.selectActualCountStmt.QueryRowContext(ctx).Scan(&count)
 	return
 }
+
+// selectAccountsPage returns a page of the accounts matching the pattern,
+// and how many accounts match it.
+func (s *accountsStatements) selectAccountsPage(
+	ctx context.Context, pattern string, limit, offset int64,
+) ([]authtypes.AccountSummary, int64, error) {
+	var total int64
+	if err := s.selectPageCountStmt.QueryRowContext(ctx, pattern).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.selectAccountsPageStmt.QueryContext(ctx, pattern, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close() // nolint: errcheck
+	accounts := []authtypes.AccountSummary{}
+	for rows.Next() {
+		var account authtypes.AccountSummary
+		if err = rows.Scan(
+			&account.UserID, &account.CreatedTs, &account.DisplayName, &account.AvatarURL,
+			&account.Deactivated, &account.Locked,
+		); err != nil {
+			return nil, 0, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, total, rows.Err()
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const deactivatedSchema = `
+-- Stores the accounts which have been deactivated. They can never log in or
+-- be registered again.
+CREATE TABLE IF NOT EXISTS account_deactivated (
+    user_id TEXT NOT NULL PRIMARY KEY,
+    -- When this account was deactivated, as a unix timestamp (ms resolution).
+    deactivated_ts BIGINT NOT NULL
+);
+`
+
+const insertDeactivatedSQL = "" +
+	"INSERT INTO account_deactivated(user_id, deactivated_ts) VALUES ($1, $2)" +
+	" ON CONFLICT (user_id) DO NOTHING"
+
+const selectDeactivatedSQL = "" +
+	"SELECT count(1) FROM account_deactivated WHERE user_id = $1"
+
+type deactivatedStatements struct {
+	db                    *Database
+	insertDeactivatedStmt *sql.Stmt
+	selectDeactivatedStmt *sql.Stmt
+}
+
+func (s *deactivatedStatements) getSchema() string {
+	return deactivatedSchema
+}
+
+func (s *deactivatedStatements) prepare(d *Database) (err error) {
+	s.db = d
+	if s.insertDeactivatedStmt, err = d.db.Prepare(insertDeactivatedSQL); err != nil {
+		return
+	}
+	if s.selectDeactivatedStmt, err = d.db.Prepare(selectDeactivatedSQL); err != nil {
+		return
+	}
+	return
+}
+
+// insertDeactivated marks the account as deactivated. It always writes through
+// to the db, the mark is checked right away by login and registration.
+func (s *deactivatedStatements) insertDeactivated(
+	ctx context.Context, userID string,
+) error {
+	deactivatedTimeMS := time.Now().UnixNano() / 1000000
+	_, err := s.insertDeactivatedStmt.ExecContext(ctx, userID, deactivatedTimeMS)
+	return err
+}
+
+func (s *deactivatedStatements) selectDeactivated(
+	ctx context.Context, userID string,
+) (bool, error) {
+	var count int
+	err := s.selectDeactivatedStmt.QueryRowContext(ctx, userID).Scan(&count)
+	return count > 0, err
+}
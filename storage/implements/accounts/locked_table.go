@@ -0,0 +1,91 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const lockedSchema = `
+-- Stores the accounts locked by an admin. Unlike deactivated accounts they
+-- can be unlocked.
+CREATE TABLE IF NOT EXISTS account_locked (
+    user_id TEXT NOT NULL PRIMARY KEY,
+    -- When this account was locked, as a unix timestamp (ms resolution).
+    locked_ts BIGINT NOT NULL
+);
+`
+
+const insertLockedSQL = "" +
+	"INSERT INTO account_locked(user_id, locked_ts) VALUES ($1, $2)" +
+	" ON CONFLICT (user_id) DO NOTHING"
+
+const deleteLockedSQL = "" +
+	"DELETE FROM account_locked WHERE user_id = $1"
+
+const selectLockedSQL = "" +
+	"SELECT count(1) FROM account_locked WHERE user_id = $1"
+
+type lockedStatements struct {
+	db               *Database
+	insertLockedStmt *sql.Stmt
+	deleteLockedStmt *sql.Stmt
+	selectLockedStmt *sql.Stmt
+}
+
+func (s *lockedStatements) getSchema() string {
+	return lockedSchema
+}
+
+func (s *lockedStatements) prepare(d *Database) (err error) {
+	s.db = d
+	if s.insertLockedStmt, err = d.db.Prepare(insertLockedSQL); err != nil {
+		return
+	}
+	if s.deleteLockedStmt, err = d.db.Prepare(deleteLockedSQL); err != nil {
+		return
+	}
+	if s.selectLockedStmt, err = d.db.Prepare(selectLockedSQL); err != nil {
+		return
+	}
+	return
+}
+
+// insertLocked and deleteLocked always write through to the db, the lock is
+// checked right away by login.
+func (s *lockedStatements) insertLocked(
+	ctx context.Context, userID string,
+) error {
+	lockedTimeMS := time.Now().UnixNano() / 1000000
+	_, err := s.insertLockedStmt.ExecContext(ctx, userID, lockedTimeMS)
+	return err
+}
+
+func (s *lockedStatements) deleteLocked(
+	ctx context.Context, userID string,
+) error {
+	_, err := s.deleteLockedStmt.ExecContext(ctx, userID)
+	return err
+}
+
+func (s *lockedStatements) selectLocked(
+	ctx context.Context, userID string,
+) (bool, error) {
+	var count int
+	err := s.selectLockedStmt.QueryRowContext(ctx, userID).Scan(&count)
+	return count > 0, err
+}
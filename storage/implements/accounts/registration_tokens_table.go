@@ -0,0 +1,176 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/finogeeks/ligase/model/authtypes"
+)
+
+const registrationTokensSchema = `
+-- Stores the tokens which allow to register when registration requires one.
+CREATE TABLE IF NOT EXISTS account_registration_tokens (
+    token TEXT NOT NULL PRIMARY KEY,
+    -- How many accounts can be registered with the token, 0 if unlimited.
+    uses_allowed BIGINT NOT NULL DEFAULT 0,
+    -- How many accounts have been registered with the token.
+    completed BIGINT NOT NULL DEFAULT 0,
+    -- When the token expires, as a unix timestamp (ms resolution), 0 if it doesn't.
+    expiry_ts BIGINT NOT NULL DEFAULT 0,
+    created_ts BIGINT NOT NULL
+);
+`
+
+const insertRegistrationTokenSQL = "" +
+	"INSERT INTO account_registration_tokens(token, uses_allowed, completed, expiry_ts, created_ts)" +
+	" VALUES ($1, $2, 0, $3, $4) ON CONFLICT (token) DO NOTHING"
+
+const selectRegistrationTokenSQL = "" +
+	"SELECT token, uses_allowed, completed, expiry_ts, created_ts FROM account_registration_tokens WHERE token = $1"
+
+const selectRegistrationTokensSQL = "" +
+	"SELECT token, uses_allowed, completed, expiry_ts, created_ts FROM account_registration_tokens ORDER BY created_ts"
+
+const deleteRegistrationTokenSQL = "" +
+	"DELETE FROM account_registration_tokens WHERE token = $1"
+
+// Counting the use in the same statement that checks the limits keeps
+// concurrent registrations from exceeding uses_allowed.
+const useRegistrationTokenSQL = "" +
+	"UPDATE account_registration_tokens SET completed = completed + 1" +
+	" WHERE token = $1 AND (uses_allowed = 0 OR completed < uses_allowed) AND (expiry_ts = 0 OR expiry_ts > $2)"
+
+const releaseRegistrationTokenSQL = "" +
+	"UPDATE account_registration_tokens SET completed = completed - 1 WHERE token = $1 AND completed > 0"
+
+type registrationTokensStatements struct {
+	db                           *Database
+	insertRegistrationTokenStmt  *sql.Stmt
+	selectRegistrationTokenStmt  *sql.Stmt
+	selectRegistrationTokensStmt *sql.Stmt
+	deleteRegistrationTokenStmt  *sql.Stmt
+	useRegistrationTokenStmt     *sql.Stmt
+	releaseRegistrationTokenStmt *sql.Stmt
+}
+
+func (s *registrationTokensStatements) getSchema() string {
+	return registrationTokensSchema
+}
+
+func (s *registrationTokensStatements) prepare(d *Database) (err error) {
+	s.db = d
+	if s.insertRegistrationTokenStmt, err = d.db.Prepare(insertRegistrationTokenSQL); err != nil {
+		return
+	}
+	if s.selectRegistrationTokenStmt, err = d.db.Prepare(selectRegistrationTokenSQL); err != nil {
+		return
+	}
+	if s.selectRegistrationTokensStmt, err = d.db.Prepare(selectRegistrationTokensSQL); err != nil {
+		return
+	}
+	if s.deleteRegistrationTokenStmt, err = d.db.Prepare(deleteRegistrationTokenSQL); err != nil {
+		return
+	}
+	if s.useRegistrationTokenStmt, err = d.db.Prepare(useRegistrationTokenSQL); err != nil {
+		return
+	}
+	if s.releaseRegistrationTokenStmt, err = d.db.Prepare(releaseRegistrationTokenSQL); err != nil {
+		return
+	}
+	return
+}
+
+// insertRegistrationToken reports false if the token already exists.
+func (s *registrationTokensStatements) insertRegistrationToken(
+	ctx context.Context, token *authtypes.RegistrationToken,
+) (bool, error) {
+	res, err := s.insertRegistrationTokenStmt.ExecContext(
+		ctx, token.Token, token.UsesAllowed, token.ExpiryTs, token.CreatedTs,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *registrationTokensStatements) selectRegistrationToken(
+	ctx context.Context, token string,
+) (*authtypes.RegistrationToken, error) {
+	var t authtypes.RegistrationToken
+	err := s.selectRegistrationTokenStmt.QueryRowContext(ctx, token).Scan(
+		&t.Token, &t.UsesAllowed, &t.Completed, &t.ExpiryTs, &t.CreatedTs,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *registrationTokensStatements) selectRegistrationTokens(
+	ctx context.Context,
+) ([]authtypes.RegistrationToken, error) {
+	rows, err := s.selectRegistrationTokensStmt.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	tokens := []authtypes.RegistrationToken{}
+	for rows.Next() {
+		var t authtypes.RegistrationToken
+		if err = rows.Scan(&t.Token, &t.UsesAllowed, &t.Completed, &t.ExpiryTs, &t.CreatedTs); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// deleteRegistrationToken reports false if there is no such token.
+func (s *registrationTokensStatements) deleteRegistrationToken(
+	ctx context.Context, token string,
+) (bool, error) {
+	res, err := s.deleteRegistrationTokenStmt.ExecContext(ctx, token)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// useRegistrationToken counts a registration, it reports false if the token
+// doesn't exist, has expired or has no use left.
+func (s *registrationTokensStatements) useRegistrationToken(
+	ctx context.Context, token string, now int64,
+) (bool, error) {
+	res, err := s.useRegistrationTokenStmt.ExecContext(ctx, token, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *registrationTokensStatements) releaseRegistrationToken(
+	ctx context.Context, token string,
+) error {
+	_, err := s.releaseRegistrationTokenStmt.ExecContext(ctx, token)
+	return err
+}
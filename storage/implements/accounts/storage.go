@@ -20,6 +20,7 @@ package accounts
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/finogeeks/ligase/common"
@@ -48,6 +49,11 @@ type Database struct {
 	filter      filterStatements
 	tags        roomTagsStatements
 	userInfo    userInfoStatements
+	deactivated deactivatedStatements
+	threePIDs   threePIDStatements
+	sessions    threePIDSessionStatements
+	locked      lockedStatements
+	regTokens   registrationTokensStatements
 	AsyncSave   bool
 
 	qryDBGauge mon.LabeledGauge
@@ -69,7 +75,7 @@ func NewDatabase(driver, createAddr, address, underlying, topic string, useAsync
 	acc.db.SetMaxIdleConns(30)
 	acc.db.SetConnMaxLifetime(time.Minute * 3)
 
-	schemas := []string{acc.accounts.getSchema(), acc.profiles.getSchema(), acc.accountData.getSchema(), acc.filter.getSchema(), acc.tags.getSchema(), acc.userInfo.getSchema()}
+	schemas := []string{acc.accounts.getSchema(), acc.profiles.getSchema(), acc.accountData.getSchema(), acc.filter.getSchema(), acc.tags.getSchema(), acc.userInfo.getSchema(), acc.deactivated.getSchema(), acc.threePIDs.getSchema(), acc.sessions.getSchema(), acc.locked.getSchema(), acc.regTokens.getSchema()}
 	for _, sqlStr := range schemas {
 		_, err := acc.db.Exec(sqlStr)
 		if err != nil {
@@ -95,6 +101,21 @@ func NewDatabase(driver, createAddr, address, underlying, topic string, useAsync
 	if err = acc.userInfo.prepare(acc); err != nil {
 		return nil, err
 	}
+	if err = acc.deactivated.prepare(acc); err != nil {
+		return nil, err
+	}
+	if err = acc.threePIDs.prepare(acc); err != nil {
+		return nil, err
+	}
+	if err = acc.sessions.prepare(acc); err != nil {
+		return nil, err
+	}
+	if err = acc.locked.prepare(acc); err != nil {
+		return nil, err
+	}
+	if err = acc.regTokens.prepare(acc); err != nil {
+		return nil, err
+	}
 
 	acc.AsyncSave = useAsync
 	acc.underlying = underlying
@@ -217,6 +238,76 @@ func (d *Database) GetAccount(ctx context.Context, userID string) (*authtypes.Ac
 	return d.accounts.selectAccount(ctx, userID)
 }
 
+// GetAccountByPassword returns the account associated with the given user ID
+// and password. Returns sql.ErrNoRows if the account doesn't exist, or a bcrypt
+// error if the password doesn't match or the account is passwordless.
+func (d *Database) GetAccountByPassword(
+	ctx context.Context, userID, plaintextPassword string,
+) (*authtypes.Account, error) {
+	hash, err := d.accounts.selectPasswordHash(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(plaintextPassword)); err != nil {
+		return nil, err
+	}
+	return d.accounts.selectAccount(ctx, userID)
+}
+
+// UpdatePassword replaces the password of the account with the hash of the
+// given plaintext password. Returns sql.ErrNoRows if the account doesn't exist.
+func (d *Database) UpdatePassword(
+	ctx context.Context, userID, plaintextPassword string,
+) error {
+	hash, err := hashPassword(plaintextPassword)
+	if err != nil {
+		return err
+	}
+	return d.accounts.updatePasswordHash(ctx, userID, hash)
+}
+
+// DeactivateAccount clears the password of the account and marks it as
+// deactivated, so that it can never log in or be registered again.
+func (d *Database) DeactivateAccount(ctx context.Context, userID string) error {
+	if err := d.accounts.updatePasswordHash(ctx, userID, ""); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return d.deactivated.insertDeactivated(ctx, userID)
+}
+
+// IsAccountDeactivated reports whether the account has been deactivated.
+func (d *Database) IsAccountDeactivated(ctx context.Context, userID string) (bool, error) {
+	return d.deactivated.selectDeactivated(ctx, userID)
+}
+
+// LockAccount prevents the account from logging in until it is unlocked.
+func (d *Database) LockAccount(ctx context.Context, userID string) error {
+	return d.locked.insertLocked(ctx, userID)
+}
+
+func (d *Database) UnlockAccount(ctx context.Context, userID string) error {
+	return d.locked.deleteLocked(ctx, userID)
+}
+
+// IsAccountLocked reports whether the account has been locked by an admin.
+func (d *Database) IsAccountLocked(ctx context.Context, userID string) (bool, error) {
+	return d.locked.selectLocked(ctx, userID)
+}
+
+// GetAccountsPage lists the accounts whose user ID or display name contains
+// search, ordered by user ID, along with how many accounts match.
+func (d *Database) GetAccountsPage(
+	ctx context.Context, search string, limit, offset int64,
+) ([]authtypes.AccountSummary, int64, error) {
+	pattern := ""
+	if search != "" {
+		pattern = "%" + likeEscaper.Replace(search) + "%"
+	}
+	return d.accounts.selectAccountsPage(ctx, pattern, limit, offset)
+}
+
+var likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+
 func (d *Database) UpsertProfile(ctx context.Context, userID, displayName, avatarURL string,
 ) error {
 	return d.profiles.upsertProfile(ctx, userID, displayName, avatarURL)
@@ -411,6 +502,81 @@ func (d *Database) GetAllUserInfo() ([]authtypes.UserInfo, error) {
 	return d.userInfo.getAllUserInfo()
 }
 
+// SaveThreePIDAssociation binds a validated 3PID to the user. It reports
+// false if the 3PID is already bound. 3PIDs are always written through, they
+// are read right away by login and password reset.
+func (d *Database) SaveThreePIDAssociation(
+	ctx context.Context, medium, address, userID string,
+) (bool, error) {
+	return d.threePIDs.insertThreePID(ctx, medium, address, userID)
+}
+
+func (d *Database) RemoveThreePIDAssociation(
+	ctx context.Context, medium, address, userID string,
+) error {
+	return d.threePIDs.deleteThreePID(ctx, medium, address, userID)
+}
+
+func (d *Database) RemoveThreePIDsForUser(
+	ctx context.Context, userID string,
+) error {
+	return d.threePIDs.deleteThreePIDsByUser(ctx, userID)
+}
+
+func (d *Database) GetThreePIDsForUser(
+	ctx context.Context, userID string,
+) ([]authtypes.ThreePID, error) {
+	return d.threePIDs.selectThreePIDsByUser(ctx, userID)
+}
+
+// GetUserIDByThreePID returns the user the 3PID is bound to, or "".
+func (d *Database) GetUserIDByThreePID(
+	ctx context.Context, medium, address string,
+) (string, error) {
+	return d.threePIDs.selectUserIDByThreePID(ctx, medium, address)
+}
+
+// UpsertThreePIDSession stores a validation session, and returns its ID.
+func (d *Database) UpsertThreePIDSession(
+	ctx context.Context, session *authtypes.ThreePIDSession,
+) (string, error) {
+	return d.sessions.upsertThreePIDSession(ctx, session)
+}
+
+// GetThreePIDSession returns nil if there is no such session.
+func (d *Database) GetThreePIDSession(
+	ctx context.Context, sid string,
+) (*authtypes.ThreePIDSession, error) {
+	return d.sessions.selectThreePIDSession(ctx, sid)
+}
+
+// GetThreePIDSessionBySecret returns nil if there is no such session.
+func (d *Database) GetThreePIDSessionBySecret(
+	ctx context.Context, clientSecret, medium, address string,
+) (*authtypes.ThreePIDSession, error) {
+	return d.sessions.selectThreePIDSessionBySecret(ctx, clientSecret, medium, address)
+}
+
+func (d *Database) ValidateThreePIDSession(
+	ctx context.Context, sid string,
+) error {
+	return d.sessions.updateThreePIDSessionValidated(ctx, sid)
+}
+
+func (d *Database) DeleteThreePIDSession(
+	ctx context.Context, sid string,
+) error {
+	return d.sessions.deleteThreePIDSession(ctx, sid)
+}
+
+// DeleteExpiredThreePIDSessions deletes the sessions which are neither sent
+// nor validated since before, a unix timestamp in ms.
+func (d *Database) DeleteExpiredThreePIDSessions(
+	ctx context.Context, before int64,
+) error {
+	return d.sessions.deleteExpiredThreePIDSessions(ctx, before)
+}
+
 func (d *Database) DeleteUserInfo(
 	ctx context.Context, userID string,
 ) error {
@@ -422,3 +588,46 @@ func (d *Database) OnDeleteUserInfo(
 ) error {
 	return d.userInfo.onDeleteUserInfo(ctx, userID)
 }
+
+// CreateRegistrationToken reports false if the token already exists.
+func (d *Database) CreateRegistrationToken(
+	ctx context.Context, token *authtypes.RegistrationToken,
+) (bool, error) {
+	return d.regTokens.insertRegistrationToken(ctx, token)
+}
+
+// GetRegistrationToken returns nil if there is no such token.
+func (d *Database) GetRegistrationToken(
+	ctx context.Context, token string,
+) (*authtypes.RegistrationToken, error) {
+	return d.regTokens.selectRegistrationToken(ctx, token)
+}
+
+func (d *Database) GetRegistrationTokens(
+	ctx context.Context,
+) ([]authtypes.RegistrationToken, error) {
+	return d.regTokens.selectRegistrationTokens(ctx)
+}
+
+// DeleteRegistrationToken reports false if there is no such token.
+func (d *Database) DeleteRegistrationToken(
+	ctx context.Context, token string,
+) (bool, error) {
+	return d.regTokens.deleteRegistrationToken(ctx, token)
+}
+
+// UseRegistrationToken counts a registration made with the token. It reports
+// false if the token can't be used anymore.
+func (d *Database) UseRegistrationToken(
+	ctx context.Context, token string, now int64,
+) (bool, error) {
+	return d.regTokens.useRegistrationToken(ctx, token, now)
+}
+
+// ReleaseRegistrationToken gives back a use counted for a registration
+// which didn't complete.
+func (d *Database) ReleaseRegistrationToken(
+	ctx context.Context, token string,
+) error {
+	return d.regTokens.releaseRegistrationToken(ctx, token)
+}
@@ -0,0 +1,170 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/finogeeks/ligase/model/authtypes"
+)
+
+const threePIDSessionSchema = `
+-- Stores the sessions used to validate third party identifiers. The token
+-- is sent to the 3PID, and the session is validated once it is submitted.
+CREATE TABLE IF NOT EXISTS account_threepid_session (
+    sid TEXT NOT NULL PRIMARY KEY,
+    client_secret TEXT NOT NULL,
+    medium TEXT NOT NULL,
+    address TEXT NOT NULL,
+    token TEXT NOT NULL,
+    send_attempt BIGINT NOT NULL,
+    next_link TEXT NOT NULL DEFAULT '',
+    -- When the token was last sent, as a unix timestamp (ms resolution).
+    created_ts BIGINT NOT NULL,
+    -- When the token was submitted, 0 while the session isn't validated.
+    validated_ts BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT account_threepid_session_unique UNIQUE (client_secret, medium, address)
+);
+
+CREATE INDEX IF NOT EXISTS account_threepid_session_created_ts_idx ON account_threepid_session(created_ts);
+`
+
+const upsertThreePIDSessionSQL = "" +
+	"INSERT INTO account_threepid_session(sid, client_secret, medium, address, token, send_attempt, next_link, created_ts, validated_ts)" +
+	" VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 0)" +
+	" ON CONFLICT ON CONSTRAINT account_threepid_session_unique" +
+	" DO UPDATE SET token = EXCLUDED.token, send_attempt = EXCLUDED.send_attempt, next_link = EXCLUDED.next_link, created_ts = EXCLUDED.created_ts" +
+	" RETURNING sid"
+
+const threePIDSessionColumns = "sid, client_secret, medium, address, token, send_attempt, next_link, created_ts, validated_ts"
+
+const selectThreePIDSessionSQL = "" +
+	"SELECT " + threePIDSessionColumns + " FROM account_threepid_session WHERE sid = $1"
+
+const selectThreePIDSessionBySecretSQL = "" +
+	"SELECT " + threePIDSessionColumns + " FROM account_threepid_session WHERE client_secret = $1 AND medium = $2 AND address = $3"
+
+const updateThreePIDSessionValidatedSQL = "" +
+	"UPDATE account_threepid_session SET validated_ts = $2 WHERE sid = $1"
+
+const deleteThreePIDSessionSQL = "" +
+	"DELETE FROM account_threepid_session WHERE sid = $1"
+
+const deleteExpiredThreePIDSessionsSQL = "" +
+	"DELETE FROM account_threepid_session WHERE created_ts < $1 AND validated_ts < $1"
+
+type threePIDSessionStatements struct {
+	db                                 *Database
+	upsertThreePIDSessionStmt          *sql.Stmt
+	selectThreePIDSessionStmt          *sql.Stmt
+	selectThreePIDSessionBySecretStmt  *sql.Stmt
+	updateThreePIDSessionValidatedStmt *sql.Stmt
+	deleteThreePIDSessionStmt          *sql.Stmt
+	deleteExpiredThreePIDSessionsStmt  *sql.Stmt
+}
+
+func (s *threePIDSessionStatements) getSchema() string {
+	return threePIDSessionSchema
+}
+
+func (s *threePIDSessionStatements) prepare(d *Database) (err error) {
+	s.db = d
+	if s.upsertThreePIDSessionStmt, err = d.db.Prepare(upsertThreePIDSessionSQL); err != nil {
+		return
+	}
+	if s.selectThreePIDSessionStmt, err = d.db.Prepare(selectThreePIDSessionSQL); err != nil {
+		return
+	}
+	if s.selectThreePIDSessionBySecretStmt, err = d.db.Prepare(selectThreePIDSessionBySecretSQL); err != nil {
+		return
+	}
+	if s.updateThreePIDSessionValidatedStmt, err = d.db.Prepare(updateThreePIDSessionValidatedSQL); err != nil {
+		return
+	}
+	if s.deleteThreePIDSessionStmt, err = d.db.Prepare(deleteThreePIDSessionSQL); err != nil {
+		return
+	}
+	if s.deleteExpiredThreePIDSessionsStmt, err = d.db.Prepare(deleteExpiredThreePIDSessionsSQL); err != nil {
+		return
+	}
+	return
+}
+
+// upsertThreePIDSession stores the session, or refreshes the token of the
+// session with the same client secret and 3PID. It returns the session ID.
+func (s *threePIDSessionStatements) upsertThreePIDSession(
+	ctx context.Context, session *authtypes.ThreePIDSession,
+) (string, error) {
+	var sid string
+	err := s.upsertThreePIDSessionStmt.QueryRowContext(
+		ctx, session.SID, session.ClientSecret, session.Medium, session.Address,
+		session.Token, session.SendAttempt, session.NextLink, time.Now().UnixNano()/1000000,
+	).Scan(&sid)
+	return sid, err
+}
+
+func scanThreePIDSession(row *sql.Row) (*authtypes.ThreePIDSession, error) {
+	var session authtypes.ThreePIDSession
+	err := row.Scan(
+		&session.SID, &session.ClientSecret, &session.Medium, &session.Address, &session.Token,
+		&session.SendAttempt, &session.NextLink, &session.CreatedAt, &session.ValidatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// selectThreePIDSession returns nil if there is no such session.
+func (s *threePIDSessionStatements) selectThreePIDSession(
+	ctx context.Context, sid string,
+) (*authtypes.ThreePIDSession, error) {
+	return scanThreePIDSession(s.selectThreePIDSessionStmt.QueryRowContext(ctx, sid))
+}
+
+// selectThreePIDSessionBySecret returns nil if there is no such session.
+func (s *threePIDSessionStatements) selectThreePIDSessionBySecret(
+	ctx context.Context, clientSecret, medium, address string,
+) (*authtypes.ThreePIDSession, error) {
+	return scanThreePIDSession(s.selectThreePIDSessionBySecretStmt.QueryRowContext(ctx, clientSecret, medium, address))
+}
+
+func (s *threePIDSessionStatements) updateThreePIDSessionValidated(
+	ctx context.Context, sid string,
+) error {
+	_, err := s.updateThreePIDSessionValidatedStmt.ExecContext(ctx, sid, time.Now().UnixNano()/1000000)
+	return err
+}
+
+func (s *threePIDSessionStatements) deleteThreePIDSession(
+	ctx context.Context, sid string,
+) error {
+	_, err := s.deleteThreePIDSessionStmt.ExecContext(ctx, sid)
+	return err
+}
+
+// deleteExpiredThreePIDSessions deletes the sessions whose token was sent and,
+// if any, submitted before the timestamp.
+func (s *threePIDSessionStatements) deleteExpiredThreePIDSessions(
+	ctx context.Context, before int64,
+) error {
+	_, err := s.deleteExpiredThreePIDSessionsStmt.ExecContext(ctx, before)
+	return err
+}
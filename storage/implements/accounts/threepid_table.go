@@ -0,0 +1,150 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/finogeeks/ligase/model/authtypes"
+)
+
+const threePIDSchema = `
+-- Stores the third party identifiers which have been validated and bound to
+-- an account. A 3PID belongs to at most one account.
+CREATE TABLE IF NOT EXISTS account_threepid (
+    -- The medium of the 3PID, e.g. "email"
+    medium TEXT NOT NULL,
+    -- The address of the 3PID, lower case for emails
+    address TEXT NOT NULL,
+    -- The Matrix user ID the 3PID is bound to
+    user_id TEXT NOT NULL,
+    -- When the 3PID was bound, as a unix timestamp (ms resolution).
+    added_ts BIGINT NOT NULL,
+    CONSTRAINT account_threepid_unique UNIQUE (medium, address)
+);
+
+CREATE INDEX IF NOT EXISTS account_threepid_user_id ON account_threepid(user_id);
+`
+
+const insertThreePIDSQL = "" +
+	"INSERT INTO account_threepid(medium, address, user_id, added_ts) VALUES ($1, $2, $3, $4)" +
+	" ON CONFLICT ON CONSTRAINT account_threepid_unique DO NOTHING"
+
+const selectThreePIDsByUserSQL = "" +
+	"SELECT medium, address, added_ts FROM account_threepid WHERE user_id = $1 ORDER BY added_ts"
+
+const selectUserIDByThreePIDSQL = "" +
+	"SELECT user_id FROM account_threepid WHERE medium = $1 AND address = $2"
+
+const deleteThreePIDSQL = "" +
+	"DELETE FROM account_threepid WHERE medium = $1 AND address = $2 AND user_id = $3"
+
+const deleteThreePIDsByUserSQL = "" +
+	"DELETE FROM account_threepid WHERE user_id = $1"
+
+type threePIDStatements struct {
+	db                         *Database
+	insertThreePIDStmt         *sql.Stmt
+	selectThreePIDsByUserStmt  *sql.Stmt
+	selectUserIDByThreePIDStmt *sql.Stmt
+	deleteThreePIDStmt         *sql.Stmt
+	deleteThreePIDsByUserStmt  *sql.Stmt
+}
+
+func (s *threePIDStatements) getSchema() string {
+	return threePIDSchema
+}
+
+func (s *threePIDStatements) prepare(d *Database) (err error) {
+	s.db = d
+	if s.insertThreePIDStmt, err = d.db.Prepare(insertThreePIDSQL); err != nil {
+		return
+	}
+	if s.selectThreePIDsByUserStmt, err = d.db.Prepare(selectThreePIDsByUserSQL); err != nil {
+		return
+	}
+	if s.selectUserIDByThreePIDStmt, err = d.db.Prepare(selectUserIDByThreePIDSQL); err != nil {
+		return
+	}
+	if s.deleteThreePIDStmt, err = d.db.Prepare(deleteThreePIDSQL); err != nil {
+		return
+	}
+	if s.deleteThreePIDsByUserStmt, err = d.db.Prepare(deleteThreePIDsByUserSQL); err != nil {
+		return
+	}
+	return
+}
+
+// insertThreePID binds the 3PID to the user. It reports false if the 3PID was
+// already bound, to this or another user.
+func (s *threePIDStatements) insertThreePID(
+	ctx context.Context, medium, address, userID string,
+) (bool, error) {
+	addedTimeMS := time.Now().UnixNano() / 1000000
+	res, err := s.insertThreePIDStmt.ExecContext(ctx, medium, address, userID, addedTimeMS)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *threePIDStatements) selectThreePIDsByUser(
+	ctx context.Context, userID string,
+) ([]authtypes.ThreePID, error) {
+	rows, err := s.selectThreePIDsByUserStmt.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	threePIDs := []authtypes.ThreePID{}
+	for rows.Next() {
+		var threePID authtypes.ThreePID
+		if err = rows.Scan(&threePID.Medium, &threePID.Address, &threePID.AddedAt); err != nil {
+			return nil, err
+		}
+		threePIDs = append(threePIDs, threePID)
+	}
+	return threePIDs, rows.Err()
+}
+
+// selectUserIDByThreePID returns "" if the 3PID isn't bound.
+func (s *threePIDStatements) selectUserIDByThreePID(
+	ctx context.Context, medium, address string,
+) (string, error) {
+	var userID string
+	err := s.selectUserIDByThreePIDStmt.QueryRowContext(ctx, medium, address).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return userID, err
+}
+
+func (s *threePIDStatements) deleteThreePID(
+	ctx context.Context, medium, address, userID string,
+) error {
+	_, err := s.deleteThreePIDStmt.ExecContext(ctx, medium, address, userID)
+	return err
+}
+
+func (s *threePIDStatements) deleteThreePIDsByUser(
+	ctx context.Context, userID string,
+) error {
+	_, err := s.deleteThreePIDsByUserStmt.ExecContext(ctx, userID)
+	return err
+}
@@ -0,0 +1,112 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package devices
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/finogeeks/ligase/model/authtypes"
+)
+
+const refreshTokensSchema = `
+-- Stores the refresh tokens of devices. Only a hash of the token is kept.
+CREATE TABLE IF NOT EXISTS device_refresh_tokens (
+    token_hash TEXT NOT NULL PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    device_id TEXT NOT NULL,
+    device_type TEXT NOT NULL,
+    identifier TEXT NOT NULL DEFAULT '',
+    is_human BOOLEAN NOT NULL DEFAULT TRUE,
+    created_ts BIGINT NOT NULL,
+    -- When the token expires, as a unix timestamp (ms resolution), 0 if it doesn't.
+    expires_ts BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS device_refresh_tokens_device_idx ON device_refresh_tokens(user_id, device_id);
+`
+
+const insertRefreshTokenSQL = "" +
+	"INSERT INTO device_refresh_tokens(token_hash, user_id, device_id, device_type, identifier, is_human, created_ts, expires_ts)" +
+	" VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
+
+// Deleting and returning the row in one statement makes a token single use
+const takeRefreshTokenSQL = "" +
+	"DELETE FROM device_refresh_tokens WHERE token_hash = $1" +
+	" RETURNING user_id, device_id, device_type, identifier, is_human, expires_ts"
+
+const deleteDeviceRefreshTokensSQL = "" +
+	"DELETE FROM device_refresh_tokens WHERE user_id = $1 AND device_id = $2"
+
+type refreshTokensStatements struct {
+	db                            *Database
+	insertRefreshTokenStmt        *sql.Stmt
+	takeRefreshTokenStmt          *sql.Stmt
+	deleteDeviceRefreshTokensStmt *sql.Stmt
+}
+
+func (s *refreshTokensStatements) getSchema() string {
+	return refreshTokensSchema
+}
+
+func (s *refreshTokensStatements) prepare(d *Database) (err error) {
+	s.db = d
+	if s.insertRefreshTokenStmt, err = d.db.Prepare(insertRefreshTokenSQL); err != nil {
+		return
+	}
+	if s.takeRefreshTokenStmt, err = d.db.Prepare(takeRefreshTokenSQL); err != nil {
+		return
+	}
+	if s.deleteDeviceRefreshTokensStmt, err = d.db.Prepare(deleteDeviceRefreshTokensSQL); err != nil {
+		return
+	}
+	return
+}
+
+func (s *refreshTokensStatements) insertRefreshToken(
+	ctx context.Context, tokenHash string, token *authtypes.RefreshToken,
+) error {
+	_, err := s.insertRefreshTokenStmt.ExecContext(
+		ctx, tokenHash, token.UserID, token.DeviceID, token.DeviceType, token.Identifier,
+		token.IsHuman, time.Now().UnixNano()/1000000, token.ExpiresTs,
+	)
+	return err
+}
+
+// takeRefreshToken deletes the token and returns it, or nil if there is no
+// such token.
+func (s *refreshTokensStatements) takeRefreshToken(
+	ctx context.Context, tokenHash string,
+) (*authtypes.RefreshToken, error) {
+	var token authtypes.RefreshToken
+	err := s.takeRefreshTokenStmt.QueryRowContext(ctx, tokenHash).Scan(
+		&token.UserID, &token.DeviceID, &token.DeviceType, &token.Identifier, &token.IsHuman, &token.ExpiresTs,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *refreshTokensStatements) deleteDeviceRefreshTokens(
+	ctx context.Context, userID, deviceID string,
+) error {
+	_, err := s.deleteDeviceRefreshTokensStmt.ExecContext(ctx, userID, deviceID)
+	return err
+}
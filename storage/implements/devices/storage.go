@@ -42,6 +42,7 @@ type Database struct {
 	underlying string
 	devices    devicesStatements
 	migDevices migDevicesStatements
+	refreshes  refreshTokensStatements
 	AsyncSave  bool
 
 	qryDBGauge mon.LabeledGauge
@@ -62,7 +63,7 @@ func NewDatabase(driver, createAddr, address, underlying, topic string, useAsync
 	dataBase.db.SetMaxIdleConns(30)
 	dataBase.db.SetConnMaxLifetime(time.Minute * 3)
 
-	schemas := []string{dataBase.devices.getSchema(), dataBase.migDevices.getSchema()}
+	schemas := []string{dataBase.devices.getSchema(), dataBase.migDevices.getSchema(), dataBase.refreshes.getSchema()}
 	for _, sqlStr := range schemas {
 		_, err := dataBase.db.Exec(sqlStr)
 		if err != nil {
@@ -78,6 +79,10 @@ func NewDatabase(driver, createAddr, address, underlying, topic string, useAsync
 		return nil, err
 	}
 
+	if err = dataBase.refreshes.prepare(dataBase); err != nil {
+		return nil, err
+	}
+
 	dataBase.AsyncSave = useAsync
 	dataBase.topic = topic
 	dataBase.underlying = underlying
@@ -312,3 +317,25 @@ func (d *Database) LoadFilterData(ctx context.Context, key string, f *filter.Fil
 
 	return true
 }
+
+// SaveRefreshToken stores a refresh token of a device, under the hash of the token
+func (d *Database) SaveRefreshToken(
+	ctx context.Context, tokenHash string, token *authtypes.RefreshToken,
+) error {
+	return d.refreshes.insertRefreshToken(ctx, tokenHash, token)
+}
+
+// TakeRefreshToken removes the refresh token and returns it, or nil if it
+// doesn't exist or has already been used
+func (d *Database) TakeRefreshToken(
+	ctx context.Context, tokenHash string,
+) (*authtypes.RefreshToken, error) {
+	return d.refreshes.takeRefreshToken(ctx, tokenHash)
+}
+
+// RemoveDeviceRefreshTokens revokes the refresh tokens of a device
+func (d *Database) RemoveDeviceRefreshTokens(
+	ctx context.Context, userID, deviceID string,
+) error {
+	return d.refreshes.deleteDeviceRefreshTokens(ctx, userID, deviceID)
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package presence
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+const presenceListSchema = `
+-- Stores the users whose presence a user subscribed to with /presence/list
+CREATE TABLE IF NOT EXISTS presence_list (
+    user_id TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    CONSTRAINT presence_list_unique UNIQUE (user_id, target_id)
+);
+`
+
+const insertPresenceListSQL = "" +
+	"INSERT INTO presence_list(user_id, target_id) SELECT $1, unnest($2::TEXT[])" +
+	" ON CONFLICT ON CONSTRAINT presence_list_unique DO NOTHING"
+
+const deletePresenceListSQL = "" +
+	"DELETE FROM presence_list WHERE user_id = $1 AND target_id = ANY($2)"
+
+const selectPresenceListSQL = "" +
+	"SELECT target_id FROM presence_list WHERE user_id = $1 ORDER BY target_id"
+
+const selectAllPresenceListSQL = "" +
+	"SELECT user_id, target_id FROM presence_list"
+
+type presenceListStatements struct {
+	db                        *Database
+	insertPresenceListStmt    *sql.Stmt
+	deletePresenceListStmt    *sql.Stmt
+	selectPresenceListStmt    *sql.Stmt
+	selectAllPresenceListStmt *sql.Stmt
+}
+
+func (s *presenceListStatements) getSchema() string {
+	return presenceListSchema
+}
+
+func (s *presenceListStatements) prepare(d *Database) (err error) {
+	s.db = d
+	if s.insertPresenceListStmt, err = d.db.Prepare(insertPresenceListSQL); err != nil {
+		return
+	}
+	if s.deletePresenceListStmt, err = d.db.Prepare(deletePresenceListSQL); err != nil {
+		return
+	}
+	if s.selectPresenceListStmt, err = d.db.Prepare(selectPresenceListSQL); err != nil {
+		return
+	}
+	if s.selectAllPresenceListStmt, err = d.db.Prepare(selectAllPresenceListSQL); err != nil {
+		return
+	}
+	return
+}
+
+func (s *presenceListStatements) insertPresenceList(
+	ctx context.Context, userID string, targets []string,
+) error {
+	_, err := s.insertPresenceListStmt.ExecContext(ctx, userID, pq.StringArray(targets))
+	return err
+}
+
+func (s *presenceListStatements) deletePresenceList(
+	ctx context.Context, userID string, targets []string,
+) error {
+	_, err := s.deletePresenceListStmt.ExecContext(ctx, userID, pq.StringArray(targets))
+	return err
+}
+
+func (s *presenceListStatements) selectPresenceList(
+	ctx context.Context, userID string,
+) ([]string, error) {
+	rows, err := s.selectPresenceListStmt.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	targets := []string{}
+	for rows.Next() {
+		var target string
+		if err = rows.Scan(&target); err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
+
+// selectAllPresenceList returns the lists of every user, keyed by user
+func (s *presenceListStatements) selectAllPresenceList(
+	ctx context.Context,
+) (map[string][]string, error) {
+	rows, err := s.selectAllPresenceListStmt.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	lists := make(map[string][]string)
+	for rows.Next() {
+		var userID, target string
+		if err = rows.Scan(&userID, &target); err != nil {
+			return nil, err
+		}
+		lists[userID] = append(lists[userID], target)
+	}
+	return lists, rows.Err()
+}
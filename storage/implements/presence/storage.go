@@ -35,6 +35,7 @@ type Database struct {
 	topic      string
 	underlying string
 	presence   presencesStatements
+	list       presenceListStatements
 	AsyncSave  bool
 
 	qryDBGauge mon.LabeledGauge
@@ -54,7 +55,7 @@ func NewDatabase(driver, createAddr, address, underlying, topic string, useAsync
 	dataBase.db.SetMaxIdleConns(30)
 	dataBase.db.SetConnMaxLifetime(time.Minute * 3)
 
-	schemas := []string{dataBase.presence.getSchema()}
+	schemas := []string{dataBase.presence.getSchema(), dataBase.list.getSchema()}
 	for _, sqlStr := range schemas {
 		_, err := dataBase.db.Exec(sqlStr)
 		if err != nil {
@@ -65,6 +66,9 @@ func NewDatabase(driver, createAddr, address, underlying, topic string, useAsync
 	if err = dataBase.presence.prepare(dataBase); err != nil {
 		return nil, err
 	}
+	if err = dataBase.list.prepare(dataBase); err != nil {
+		return nil, err
+	}
 
 	dataBase.AsyncSave = useAsync
 	dataBase.topic = topic
@@ -128,3 +132,30 @@ func (d *Database) OnUpsertPresences(
 ) error {
 	return d.presence.onUpsertPresences(ctx, userID, status, statusMsg, extStatusMsg)
 }
+
+// AddPresenceList subscribes userID to the presence of targets, targets
+// already in the list are ignored.
+func (d *Database) AddPresenceList(
+	ctx context.Context, userID string, targets []string,
+) error {
+	return d.list.insertPresenceList(ctx, userID, targets)
+}
+
+func (d *Database) RemovePresenceList(
+	ctx context.Context, userID string, targets []string,
+) error {
+	return d.list.deletePresenceList(ctx, userID, targets)
+}
+
+func (d *Database) GetPresenceList(
+	ctx context.Context, userID string,
+) ([]string, error) {
+	return d.list.selectPresenceList(ctx, userID)
+}
+
+// GetAllPresenceList returns the presence list of every user, keyed by user.
+func (d *Database) GetAllPresenceList(
+	ctx context.Context,
+) (map[string][]string, error) {
+	return d.list.selectAllPresenceList(ctx)
+}
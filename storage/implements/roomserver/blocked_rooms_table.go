@@ -0,0 +1,90 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package roomserver
+
+import (
+	"context"
+	"database/sql"
+)
+
+const blockedRoomsSchema = `
+-- Stores the rooms shut down by the super admin, local users can't join them
+CREATE TABLE IF NOT EXISTS roomserver_blocked_rooms (
+    room_id TEXT NOT NULL PRIMARY KEY,
+    blocked_by TEXT NOT NULL,
+    blocked_ts BIGINT NOT NULL
+);
+`
+
+const insertBlockedRoomSQL = "" +
+	"INSERT INTO roomserver_blocked_rooms (room_id, blocked_by, blocked_ts) VALUES ($1, $2, $3)" +
+	" ON CONFLICT (room_id) DO NOTHING"
+
+const selectBlockedRoomSQL = "" +
+	"SELECT EXISTS(SELECT 1 FROM roomserver_blocked_rooms WHERE room_id = $1)"
+
+const selectBlockedRoomsSQL = "" +
+	"SELECT room_id FROM roomserver_blocked_rooms"
+
+type blockedRoomsStatements struct {
+	db                     *Database
+	insertBlockedRoomStmt  *sql.Stmt
+	selectBlockedRoomStmt  *sql.Stmt
+	selectBlockedRoomsStmt *sql.Stmt
+}
+
+func (s *blockedRoomsStatements) getSchema() string {
+	return blockedRoomsSchema
+}
+
+func (s *blockedRoomsStatements) prepare(db *sql.DB, d *Database) (err error) {
+	s.db = d
+	return statementList{
+		{&s.insertBlockedRoomStmt, insertBlockedRoomSQL},
+		{&s.selectBlockedRoomStmt, selectBlockedRoomSQL},
+		{&s.selectBlockedRoomsStmt, selectBlockedRoomsSQL},
+	}.prepare(db)
+}
+
+func (s *blockedRoomsStatements) insertBlockedRoom(
+	ctx context.Context, roomID, blockedBy string, blockedTs int64,
+) error {
+	_, err := s.insertBlockedRoomStmt.ExecContext(ctx, roomID, blockedBy, blockedTs)
+	return err
+}
+
+func (s *blockedRoomsStatements) selectBlockedRoom(
+	ctx context.Context, roomID string,
+) (blocked bool, err error) {
+	err = s.selectBlockedRoomStmt.QueryRowContext(ctx, roomID).Scan(&blocked)
+	return
+}
+
+func (s *blockedRoomsStatements) selectBlockedRooms(ctx context.Context) ([]string, error) {
+	rows, err := s.selectBlockedRoomsStmt.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	roomIDs := []string{}
+	for rows.Next() {
+		var roomID string
+		if err = rows.Scan(&roomID); err != nil {
+			return nil, err
+		}
+		roomIDs = append(roomIDs, roomID)
+	}
+	return roomIDs, rows.Err()
+}
@@ -0,0 +1,198 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package roomserver
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/finogeeks/ligase/model/roomservertypes"
+)
+
+const eventReportsSchema = `
+-- Stores the events reported by users for the moderation queue
+CREATE TABLE IF NOT EXISTS roomserver_event_reports (
+    id BIGINT NOT NULL PRIMARY KEY,
+    room_id TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    sender TEXT NOT NULL,
+    reporter TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    score INTEGER NOT NULL DEFAULT 0,
+    -- The event as it was when reported, it may be redacted since
+    event_json TEXT NOT NULL,
+    received_ts BIGINT NOT NULL,
+    assignee TEXT NOT NULL DEFAULT '',
+    resolved_by TEXT NOT NULL DEFAULT '',
+    -- 0 while the report is open
+    resolved_ts BIGINT NOT NULL DEFAULT 0,
+    resolution TEXT NOT NULL DEFAULT '',
+    -- The actions taken while resolving, kept so a retry doesn't repeat them
+    redaction_event_id TEXT NOT NULL DEFAULT '',
+    kicked BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS roomserver_event_reports_open_idx ON roomserver_event_reports(resolved_ts, id);
+`
+
+const eventReportColumns = "id, room_id, event_id, sender, reporter, reason, score, event_json," +
+	" received_ts, assignee, resolved_by, resolved_ts, resolution, redaction_event_id, kicked"
+
+const insertEventReportSQL = "" +
+	"INSERT INTO roomserver_event_reports (id, room_id, event_id, sender, reporter, reason, score, event_json, received_ts)" +
+	" VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+
+const selectEventReportSQL = "" +
+	"SELECT " + eventReportColumns + " FROM roomserver_event_reports WHERE id = $1"
+
+// $1 filters by room when not empty, $2 by state: open, resolved or empty for all
+const eventReportsFilter = "" +
+	" WHERE ($1 = '' OR room_id = $1)" +
+	" AND ($2 = '' OR ($2 = 'open' AND resolved_ts = 0) OR ($2 = 'resolved' AND resolved_ts > 0))"
+
+const selectEventReportsSQL = "" +
+	"SELECT " + eventReportColumns + " FROM roomserver_event_reports" + eventReportsFilter +
+	" ORDER BY id DESC LIMIT $3 OFFSET $4"
+
+const selectEventReportsCountSQL = "" +
+	"SELECT count(1) FROM roomserver_event_reports" + eventReportsFilter
+
+const updateEventReportAssigneeSQL = "" +
+	"UPDATE roomserver_event_reports SET assignee = $2 WHERE id = $1 AND resolved_ts = 0"
+
+const updateEventReportResolvedSQL = "" +
+	"UPDATE roomserver_event_reports SET resolved_by = $2, resolved_ts = $3, resolution = $4" +
+	" WHERE id = $1 AND resolved_ts = 0"
+
+const updateEventReportActionsSQL = "" +
+	"UPDATE roomserver_event_reports SET redaction_event_id = $2, kicked = $3 WHERE id = $1"
+
+type eventReportsStatements struct {
+	db                            *Database
+	insertEventReportStmt         *sql.Stmt
+	selectEventReportStmt         *sql.Stmt
+	selectEventReportsStmt        *sql.Stmt
+	selectEventReportsCountStmt   *sql.Stmt
+	updateEventReportAssigneeStmt *sql.Stmt
+	updateEventReportResolvedStmt *sql.Stmt
+	updateEventReportActionsStmt  *sql.Stmt
+}
+
+func (s *eventReportsStatements) getSchema() string {
+	return eventReportsSchema
+}
+
+func (s *eventReportsStatements) prepare(db *sql.DB, d *Database) (err error) {
+	s.db = d
+	return statementList{
+		{&s.insertEventReportStmt, insertEventReportSQL},
+		{&s.selectEventReportStmt, selectEventReportSQL},
+		{&s.selectEventReportsStmt, selectEventReportsSQL},
+		{&s.selectEventReportsCountStmt, selectEventReportsCountSQL},
+		{&s.updateEventReportAssigneeStmt, updateEventReportAssigneeSQL},
+		{&s.updateEventReportResolvedStmt, updateEventReportResolvedSQL},
+		{&s.updateEventReportActionsStmt, updateEventReportActionsSQL},
+	}.prepare(db)
+}
+
+func (s *eventReportsStatements) insertEventReport(
+	ctx context.Context, report *roomservertypes.EventReport,
+) error {
+	_, err := s.insertEventReportStmt.ExecContext(
+		ctx, report.ID, report.RoomID, report.EventID, report.Sender, report.Reporter,
+		report.Reason, report.Score, string(report.EventJSON), report.ReceivedTs,
+	)
+	return err
+}
+
+func scanEventReport(scan func(dest ...interface{}) error) (*roomservertypes.EventReport, error) {
+	var report roomservertypes.EventReport
+	var eventJSON string
+	if err := scan(
+		&report.ID, &report.RoomID, &report.EventID, &report.Sender, &report.Reporter,
+		&report.Reason, &report.Score, &eventJSON, &report.ReceivedTs, &report.Assignee,
+		&report.ResolvedBy, &report.ResolvedTs, &report.Resolution, &report.RedactionEventID, &report.Kicked,
+	); err != nil {
+		return nil, err
+	}
+	report.EventJSON = []byte(eventJSON)
+	return &report, nil
+}
+
+// selectEventReport returns nil if there is no such report
+func (s *eventReportsStatements) selectEventReport(
+	ctx context.Context, id int64,
+) (*roomservertypes.EventReport, error) {
+	report, err := scanEventReport(s.selectEventReportStmt.QueryRowContext(ctx, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return report, err
+}
+
+func (s *eventReportsStatements) selectEventReports(
+	ctx context.Context, roomID, state string, limit, offset int64,
+) ([]roomservertypes.EventReport, int64, error) {
+	var total int64
+	if err := s.selectEventReportsCountStmt.QueryRowContext(ctx, roomID, state).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.selectEventReportsStmt.QueryContext(ctx, roomID, state, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close() // nolint: errcheck
+	reports := []roomservertypes.EventReport{}
+	for rows.Next() {
+		report, err := scanEventReport(rows.Scan)
+		if err != nil {
+			return nil, 0, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, total, rows.Err()
+}
+
+// updateEventReportAssignee reports false if the report doesn't exist or is resolved
+func (s *eventReportsStatements) updateEventReportAssignee(
+	ctx context.Context, id int64, assignee string,
+) (bool, error) {
+	res, err := s.updateEventReportAssigneeStmt.ExecContext(ctx, id, assignee)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// updateEventReportResolved reports false if the report doesn't exist or is resolved
+func (s *eventReportsStatements) updateEventReportResolved(
+	ctx context.Context, id int64, resolvedBy string, resolvedTs int64, resolution string,
+) (bool, error) {
+	res, err := s.updateEventReportResolvedStmt.ExecContext(ctx, id, resolvedBy, resolvedTs, resolution)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *eventReportsStatements) updateEventReportActions(
+	ctx context.Context, id int64, redactionEventID string, kicked bool,
+) error {
+	_, err := s.updateEventReportActionsStmt.ExecContext(ctx, id, redactionEventID, kicked)
+	return err
+}
@@ -122,6 +122,15 @@ const selectRoomEventByDepthSQL = "SELECT event_nid, event_id FROM roomserver_ev
 
 const selectRoomMaxDomainOffsetSQL = "SELECT t.domain, t.m, m.event_id FROM(SELECT MAX(offsets) AS m, domain FROM roomserver_events WHERE room_nid=$1 GROUP BY domain) t LEFT JOIN roomserver_events m ON room_nid=$1 AND t.domain=m.domain AND t.m=m.offsets"
 
+// The latest events of the room are never purged
+const purgeRoomEventsSQL = "" +
+	"WITH r AS (SELECT room_nid, latest_event_nids FROM roomserver_rooms WHERE room_id = $1)," +
+	" purged AS (DELETE FROM roomserver_events e USING r WHERE e.room_nid = r.room_nid AND e.event_id = ANY($2)" +
+	" AND NOT e.event_nid = ANY(r.latest_event_nids) RETURNING e.event_nid)," +
+	" j AS (DELETE FROM roomserver_event_json WHERE event_nid IN (SELECT event_nid FROM purged) RETURNING 1)," +
+	" m AS (DELETE FROM roomserver_event_json_mirror WHERE event_nid IN (SELECT event_nid FROM purged) RETURNING 1)" +
+	" SELECT count(*) FROM purged"
+
 type eventStatements struct {
 	db                                         *Database
 	insertEventStmt                            *sql.Stmt
@@ -145,6 +154,7 @@ type eventStatements struct {
 	updateRoomEventStmt                        *sql.Stmt
 	selectRoomEventByDepthStmt                 *sql.Stmt
 	selectRoomMaxDomainOffsetStmt              *sql.Stmt
+	purgeRoomEventsStmt                        *sql.Stmt
 }
 
 func (s *eventStatements) getSchema() string {
@@ -176,6 +186,7 @@ func (s *eventStatements) prepare(db *sql.DB, d *Database) (err error) {
 		{&s.updateRoomEventStmt, updateRoomEventSQL},
 		{&s.selectRoomEventByDepthStmt, selectRoomEventByDepthSQL},
 		{&s.selectRoomMaxDomainOffsetStmt, selectRoomMaxDomainOffsetSQL},
+		{&s.purgeRoomEventsStmt, purgeRoomEventsSQL},
 	}.prepare(db)
 }
 
@@ -610,3 +621,8 @@ func (s *eventStatements) selectRoomStateNIDByStateBlockNID(ctx context.Context,
 	}
 	return eventNIDs, eventTypes, stateKeys, domains, nil
 }
+
+func (s *eventStatements) purgeRoomEvents(ctx context.Context, roomID string, eventIDs []string) (count int64, err error) {
+	err = s.purgeRoomEventsStmt.QueryRowContext(ctx, roomID, pq.StringArray(eventIDs)).Scan(&count)
+	return
+}
@@ -104,6 +104,15 @@ const selectMembershipsFromTargetSQL = "" +
 	"SELECT room_id, membership_nid FROM roomserver_membership" +
 	" WHERE target_id = $1"
 
+// Lists the rooms with how many users have joined them, for the admin API
+const selectRoomsJoinedCountSQL = "" +
+	"SELECT r.room_id, r.room_nid, (SELECT count(1) FROM roomserver_membership m" +
+	" WHERE m.room_nid = r.room_nid AND m.membership_nid = 3)" +
+	" FROM roomserver_rooms r ORDER BY r.room_nid LIMIT $1 OFFSET $2"
+
+const selectRoomsCountSQL = "" +
+	"SELECT count(1) FROM roomserver_rooms"
+
 type memshipItem struct {
 	room_nid          roomservertypes.RoomNID
 	target_nid        roomservertypes.EventStateKeyNID
@@ -124,6 +133,8 @@ type membershipStatements struct {
 	updateMembershipForgetNIDStmt              *sql.Stmt
 	selectMembershipFromRoomStmt               *sql.Stmt
 	selectMembershipsFromTargetStmt            *sql.Stmt
+	selectRoomsJoinedCountStmt                 *sql.Stmt
+	selectRoomsCountStmt                       *sql.Stmt
 }
 
 func (s *membershipStatements) getSchema() string {
@@ -143,6 +154,8 @@ func (s *membershipStatements) prepare(db *sql.DB, d *Database) (err error) {
 		{&s.updateMembershipForgetNIDStmt, updateMembershipForgetNIDSQL},
 		{&s.selectMembershipFromRoomStmt, selectMembershipFromRoomSQL},
 		{&s.selectMembershipsFromTargetStmt, selectMembershipsFromTargetSQL},
+		{&s.selectRoomsJoinedCountStmt, selectRoomsJoinedCountSQL},
+		{&s.selectRoomsCountStmt, selectRoomsCountSQL},
 	}.prepare(db)
 }
 
@@ -276,3 +289,27 @@ func (s *membershipStatements) selectMembershipsFromTarget(
 	}
 	return res, nil
 }
+
+func (s *membershipStatements) selectRoomsJoinedCount(
+	ctx context.Context, limit, offset int,
+) ([]roomservertypes.RoomJoinedCount, int64, error) {
+	var total int64
+	if err := s.selectRoomsCountStmt.QueryRowContext(ctx).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.selectRoomsJoinedCountStmt.QueryContext(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close() // nolint: errcheck
+	rooms := []roomservertypes.RoomJoinedCount{}
+	for rows.Next() {
+		var room roomservertypes.RoomJoinedCount
+		if err = rows.Scan(&room.RoomID, &room.RoomNID, &room.JoinedMembers); err != nil {
+			return nil, 0, err
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, total, rows.Err()
+}
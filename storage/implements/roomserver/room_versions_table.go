@@ -0,0 +1,72 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package roomserver
+
+import (
+	"context"
+	"database/sql"
+)
+
+const roomVersionsSchema = `
+-- Stores the room version of each room, rooms without a row are version 1
+CREATE TABLE IF NOT EXISTS roomserver_room_versions (
+    room_id TEXT NOT NULL PRIMARY KEY,
+    room_version TEXT NOT NULL
+);
+`
+
+// The room version is set by the create event and never changes
+const insertRoomVersionSQL = "" +
+	"INSERT INTO roomserver_room_versions (room_id, room_version) VALUES ($1, $2)" +
+	" ON CONFLICT (room_id) DO NOTHING"
+
+const selectRoomVersionSQL = "" +
+	"SELECT room_version FROM roomserver_room_versions WHERE room_id = $1"
+
+type roomVersionsStatements struct {
+	db                    *Database
+	insertRoomVersionStmt *sql.Stmt
+	selectRoomVersionStmt *sql.Stmt
+}
+
+func (s *roomVersionsStatements) getSchema() string {
+	return roomVersionsSchema
+}
+
+func (s *roomVersionsStatements) prepare(db *sql.DB, d *Database) (err error) {
+	s.db = d
+	return statementList{
+		{&s.insertRoomVersionStmt, insertRoomVersionSQL},
+		{&s.selectRoomVersionStmt, selectRoomVersionSQL},
+	}.prepare(db)
+}
+
+func (s *roomVersionsStatements) insertRoomVersion(
+	ctx context.Context, roomID, roomVersion string,
+) error {
+	_, err := s.insertRoomVersionStmt.ExecContext(ctx, roomID, roomVersion)
+	return err
+}
+
+// selectRoomVersion returns an empty version if the room has no row
+func (s *roomVersionsStatements) selectRoomVersion(
+	ctx context.Context, roomID string,
+) (roomVersion string, err error) {
+	err = s.selectRoomVersionStmt.QueryRowContext(ctx, roomID).Scan(&roomVersion)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return
+}
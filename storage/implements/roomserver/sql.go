@@ -31,6 +31,9 @@ type statements struct {
 	membershipStatements
 	roomDomainsStatements
 	settingsStatements
+	eventReportsStatements
+	roomVersionsStatements
+	blockedRoomsStatements
 }
 
 func (s *statements) prepare(db *sql.DB, d *Database) error {
@@ -46,6 +49,9 @@ func (s *statements) prepare(db *sql.DB, d *Database) error {
 		s.membershipStatements.prepare,
 		s.roomDomainsStatements.prepare,
 		s.settingsStatements.prepare,
+		s.eventReportsStatements.prepare,
+		s.roomVersionsStatements.prepare,
+		s.blockedRoomsStatements.prepare,
 	} {
 		if err = prepare(db, d); err != nil {
 			return err
@@ -82,7 +82,10 @@ func NewDatabase(driver, createAddr, address, underlying, topic string, useAsync
 		d.statements.inviteStatements.getSchema(),
 		d.statements.membershipStatements.getSchema(),
 		d.statements.roomDomainsStatements.getSchema(),
-		d.statements.settingsStatements.getSchema()}
+		d.statements.settingsStatements.getSchema(),
+		d.statements.eventReportsStatements.getSchema(),
+		d.statements.roomVersionsStatements.getSchema(),
+		d.statements.blockedRoomsStatements.getSchema()}
 	for _, sqlStr := range schemas {
 		_, err := d.db.Exec(sqlStr)
 		if err != nil {
@@ -470,6 +473,12 @@ func (d *Database) FixCorruptRooms() {
 	log.Errorf("FixCorruptRooms fix rooms end")
 }
 
+// PurgeRoomEvents deletes the events of roomID among eventIDs, the latest
+// events of the room are kept. It returns the number of purged events.
+func (d *Database) PurgeRoomEvents(ctx context.Context, roomID string, eventIDs []string) (int64, error) {
+	return d.statements.eventStatements.purgeRoomEvents(ctx, roomID, eventIDs)
+}
+
 type transaction struct {
 	ctx context.Context
 	txn *sql.Tx
@@ -679,6 +688,108 @@ func (d *Database) GetAllRooms(ctx context.Context, limit, offset int) ([]roomse
 	return roomNIDs, err
 }
 
+// GetRoomsJoinedCount returns a page of the rooms with their joined member
+// count, and how many rooms there are.
+func (d *Database) GetRoomsJoinedCount(ctx context.Context, limit, offset int) ([]roomservertypes.RoomJoinedCount, int64, error) {
+	start := time.Now()
+
+	rooms, total, err := d.statements.selectRoomsJoinedCount(ctx, limit, offset)
+
+	duration := float64(time.Since(start)) / float64(time.Millisecond)
+	d.qryDBGauge.WithLabelValues("GetRoomsJoinedCount").Set(duration)
+
+	return rooms, total, err
+}
+
+func (d *Database) InsertEventReport(ctx context.Context, report *roomservertypes.EventReport) error {
+	start := time.Now()
+
+	err := d.statements.insertEventReport(ctx, report)
+
+	duration := float64(time.Since(start)) / float64(time.Millisecond)
+	d.qryDBGauge.WithLabelValues("InsertEventReport").Set(duration)
+
+	return err
+}
+
+// GetEventReport returns nil if there is no such report.
+func (d *Database) GetEventReport(ctx context.Context, id int64) (*roomservertypes.EventReport, error) {
+	start := time.Now()
+
+	report, err := d.statements.selectEventReport(ctx, id)
+
+	duration := float64(time.Since(start)) / float64(time.Millisecond)
+	d.qryDBGauge.WithLabelValues("GetEventReport").Set(duration)
+
+	return report, err
+}
+
+// GetEventReports returns a page of the reports, newest first, and how many
+// reports match. roomID and state (open or resolved) are ignored when empty.
+func (d *Database) GetEventReports(ctx context.Context, roomID, state string, limit, offset int64) ([]roomservertypes.EventReport, int64, error) {
+	start := time.Now()
+
+	reports, total, err := d.statements.selectEventReports(ctx, roomID, state, limit, offset)
+
+	duration := float64(time.Since(start)) / float64(time.Millisecond)
+	d.qryDBGauge.WithLabelValues("GetEventReports").Set(duration)
+
+	return reports, total, err
+}
+
+// AssignEventReport reports false if the report doesn't exist or is resolved.
+func (d *Database) AssignEventReport(ctx context.Context, id int64, assignee string) (bool, error) {
+	return d.statements.updateEventReportAssignee(ctx, id, assignee)
+}
+
+// ResolveEventReport reports false if the report doesn't exist or is
+// already resolved.
+func (d *Database) ResolveEventReport(ctx context.Context, id int64, resolvedBy string, resolvedTs int64, resolution string) (bool, error) {
+	return d.statements.updateEventReportResolved(ctx, id, resolvedBy, resolvedTs, resolution)
+}
+
+// SetEventReportActions records the redaction sent and the kick done for
+// the report.
+func (d *Database) SetEventReportActions(ctx context.Context, id int64, redactionEventID string, kicked bool) error {
+	return d.statements.updateEventReportActions(ctx, id, redactionEventID, kicked)
+}
+
+func (d *Database) SetRoomVersion(ctx context.Context, roomID, roomVersion string) error {
+	return d.statements.insertRoomVersion(ctx, roomID, roomVersion)
+}
+
+// GetRoomVersion returns an empty version for rooms created before room
+// versions were stored, which are version 1.
+func (d *Database) GetRoomVersion(ctx context.Context, roomID string) (string, error) {
+	start := time.Now()
+
+	roomVersion, err := d.statements.selectRoomVersion(ctx, roomID)
+
+	duration := float64(time.Since(start)) / float64(time.Millisecond)
+	d.qryDBGauge.WithLabelValues("GetRoomVersion").Set(duration)
+
+	return roomVersion, err
+}
+
+func (d *Database) BlockRoom(ctx context.Context, roomID, blockedBy string, blockedTs int64) error {
+	return d.statements.insertBlockedRoom(ctx, roomID, blockedBy, blockedTs)
+}
+
+func (d *Database) IsRoomBlocked(ctx context.Context, roomID string) (bool, error) {
+	start := time.Now()
+
+	blocked, err := d.statements.selectBlockedRoom(ctx, roomID)
+
+	duration := float64(time.Since(start)) / float64(time.Millisecond)
+	d.qryDBGauge.WithLabelValues("IsRoomBlocked").Set(duration)
+
+	return blocked, err
+}
+
+func (d *Database) SelectBlockedRooms(ctx context.Context) ([]string, error) {
+	return d.statements.selectBlockedRooms(ctx)
+}
+
 func (d *Database) GetRoomEvents(ctx context.Context, roomNID int64) ([][]byte, error) {
 	start := time.Now()
 	res, err := d.statements.getRoomEvents(ctx, roomNID)
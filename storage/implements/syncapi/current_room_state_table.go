@@ -87,6 +87,9 @@ const updateRoomStateSQL = "" +
 const selectRoomStateByEventIDSQL = "" +
 	"SELECT event_json FROM syncapi_current_room_state WHERE event_id = $1"
 
+const selectStateByTypeSQL = "" +
+	"SELECT event_json FROM syncapi_current_room_state WHERE type = $1 AND state_key = ''"
+
 type currentRoomStateStatements struct {
 	db                              *Database
 	upsertRoomStateStmt             *sql.Stmt
@@ -98,6 +101,7 @@ type currentRoomStateStatements struct {
 	selectRoomStateCountStmt     *sql.Stmt
 	updateRoomStateStmt          *sql.Stmt
 	selectRoomStateByEventIDStmt *sql.Stmt
+	selectStateByTypeStmt        *sql.Stmt
 }
 
 func (s *currentRoomStateStatements) getSchema() string {
@@ -134,6 +138,9 @@ func (s *currentRoomStateStatements) prepare(db *sql.DB, d *Database) (err error
 	if s.selectRoomStateByEventIDStmt, err = db.Prepare(selectRoomStateByEventIDSQL); err != nil {
 		return
 	}
+	if s.selectStateByTypeStmt, err = db.Prepare(selectStateByTypeSQL); err != nil {
+		return
+	}
 	return
 }
 
@@ -221,6 +228,36 @@ func (s *currentRoomStateStatements) selectCurrentState(
 	return result, offsets, nil
 }
 
+func (s *currentRoomStateStatements) selectStateByType(
+	ctx context.Context, eventType string,
+) ([]gomatrixserverlib.ClientEvent, error) {
+	rows, err := s.selectStateByTypeStmt.QueryContext(ctx, eventType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+
+	result := []gomatrixserverlib.ClientEvent{}
+	for rows.Next() {
+		var eventBytes []byte
+		if err := rows.Scan(&eventBytes); err != nil {
+			return nil, err
+		}
+
+		var ev gomatrixserverlib.ClientEvent
+		if encryption.CheckCrypto(eventType) {
+			err = json.Unmarshal(encryption.Decrypt(eventBytes), &ev)
+		} else {
+			err = json.Unmarshal(eventBytes, &ev)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ev)
+	}
+	return result, rows.Err()
+}
+
 func (s *currentRoomStateStatements) upsertRoomState(
 	ctx context.Context,
 	event gomatrixserverlib.ClientEvent, membership *string, addedAt int64,
@@ -0,0 +1,196 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package syncapi
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+const eventRelationsSchema = `
+-- Stores the events which have a m.relates_to in their content
+CREATE TABLE IF NOT EXISTS syncapi_event_relations (
+    event_id TEXT NOT NULL PRIMARY KEY,
+    room_id TEXT NOT NULL,
+    -- The event this event relates to
+    relates_to_id TEXT NOT NULL,
+    rel_type TEXT NOT NULL,
+    type TEXT NOT NULL,
+    sender TEXT NOT NULL,
+    -- The key of a m.annotation
+    aggregation_key TEXT NOT NULL DEFAULT '',
+    -- The stream position of the event in syncapi_output_room_events
+    id BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS syncapi_event_relations_parent_idx ON syncapi_event_relations(relates_to_id, id);
+CREATE INDEX IF NOT EXISTS syncapi_event_relations_room_idx ON syncapi_event_relations(room_id, rel_type);
+`
+
+const insertEventRelationSQL = "" +
+	"INSERT INTO syncapi_event_relations (event_id, room_id, relates_to_id, rel_type, type, sender, aggregation_key, id)" +
+	" VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT DO NOTHING"
+
+const deleteEventRelationSQL = "" +
+	"DELETE FROM syncapi_event_relations WHERE event_id = $1"
+
+const deleteEventRelationsSQL = "" +
+	"DELETE FROM syncapi_event_relations WHERE event_id = ANY($1)"
+
+// An empty rel_type or type matches every relation
+const selectEventRelationsBackSQL = "" +
+	"SELECT event_id, id FROM syncapi_event_relations" +
+	" WHERE relates_to_id = $1 AND ($2 = '' OR rel_type = $2) AND ($3 = '' OR type = $3) AND id < $4" +
+	" ORDER BY id DESC LIMIT $5"
+
+const selectEventRelationsForwardSQL = "" +
+	"SELECT event_id, id FROM syncapi_event_relations" +
+	" WHERE relates_to_id = $1 AND ($2 = '' OR rel_type = $2) AND ($3 = '' OR type = $3) AND id > $4" +
+	" ORDER BY id ASC LIMIT $5"
+
+// An empty sender matches every sender
+const selectLatestEventRelationSQL = "" +
+	"SELECT event_id FROM syncapi_event_relations" +
+	" WHERE relates_to_id = $1 AND rel_type = $2 AND ($3 = '' OR sender = $3) AND event_id <> $4" +
+	" ORDER BY id DESC LIMIT 1"
+
+// Threads are ordered by their latest reply
+const selectRoomThreadsSQL = "" +
+	"SELECT relates_to_id, max(id), bool_or(sender = $2) FROM syncapi_event_relations" +
+	" WHERE room_id = $1 AND rel_type = 'm.thread'" +
+	" GROUP BY relates_to_id HAVING max(id) < $3 ORDER BY max(id) DESC LIMIT $4"
+
+type eventRelationsStatements struct {
+	db                              *Database
+	insertEventRelationStmt         *sql.Stmt
+	deleteEventRelationStmt         *sql.Stmt
+	deleteEventRelationsStmt        *sql.Stmt
+	selectEventRelationsBackStmt    *sql.Stmt
+	selectEventRelationsForwardStmt *sql.Stmt
+	selectLatestEventRelationStmt   *sql.Stmt
+	selectRoomThreadsStmt           *sql.Stmt
+}
+
+func (s *eventRelationsStatements) getSchema() string {
+	return eventRelationsSchema
+}
+
+func (s *eventRelationsStatements) prepare(db *sql.DB, d *Database) (err error) {
+	s.db = d
+	if s.insertEventRelationStmt, err = db.Prepare(insertEventRelationSQL); err != nil {
+		return
+	}
+	if s.deleteEventRelationStmt, err = db.Prepare(deleteEventRelationSQL); err != nil {
+		return
+	}
+	if s.deleteEventRelationsStmt, err = db.Prepare(deleteEventRelationsSQL); err != nil {
+		return
+	}
+	if s.selectEventRelationsBackStmt, err = db.Prepare(selectEventRelationsBackSQL); err != nil {
+		return
+	}
+	if s.selectEventRelationsForwardStmt, err = db.Prepare(selectEventRelationsForwardSQL); err != nil {
+		return
+	}
+	if s.selectLatestEventRelationStmt, err = db.Prepare(selectLatestEventRelationSQL); err != nil {
+		return
+	}
+	if s.selectRoomThreadsStmt, err = db.Prepare(selectRoomThreadsSQL); err != nil {
+		return
+	}
+	return
+}
+
+func (s *eventRelationsStatements) insertEventRelation(
+	ctx context.Context, eventID, roomID, relatesToID, relType, eventType, sender, key string, offset int64,
+) error {
+	_, err := s.insertEventRelationStmt.ExecContext(
+		ctx, eventID, roomID, relatesToID, relType, eventType, sender, key, offset,
+	)
+	return err
+}
+
+func (s *eventRelationsStatements) deleteEventRelation(ctx context.Context, eventID string) error {
+	_, err := s.deleteEventRelationStmt.ExecContext(ctx, eventID)
+	return err
+}
+
+func (s *eventRelationsStatements) deleteEventRelations(ctx context.Context, eventIDs []string) error {
+	_, err := s.deleteEventRelationsStmt.ExecContext(ctx, pq.StringArray(eventIDs))
+	return err
+}
+
+func (s *eventRelationsStatements) selectEventRelations(
+	ctx context.Context, relatesToID, relType, eventType, dir string, from int64, limit int,
+) ([]string, []int64, error) {
+	stmt := s.selectEventRelationsBackStmt
+	if dir == "f" {
+		stmt = s.selectEventRelationsForwardStmt
+	}
+	rows, err := stmt.QueryContext(ctx, relatesToID, relType, eventType, from, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	eventIDs := []string{}
+	offsets := []int64{}
+	for rows.Next() {
+		var eventID string
+		var offset int64
+		if err = rows.Scan(&eventID, &offset); err != nil {
+			return nil, nil, err
+		}
+		eventIDs = append(eventIDs, eventID)
+		offsets = append(offsets, offset)
+	}
+	return eventIDs, offsets, rows.Err()
+}
+
+func (s *eventRelationsStatements) selectLatestEventRelation(
+	ctx context.Context, relatesToID, relType, sender, excludeID string,
+) (string, error) {
+	var eventID string
+	err := s.selectLatestEventRelationStmt.QueryRowContext(ctx, relatesToID, relType, sender, excludeID).Scan(&eventID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return eventID, err
+}
+
+func (s *eventRelationsStatements) selectRoomThreads(
+	ctx context.Context, roomID, userID string, from int64, limit int,
+) ([]string, []int64, []bool, error) {
+	rows, err := s.selectRoomThreadsStmt.QueryContext(ctx, roomID, userID, from, limit)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	rootIDs := []string{}
+	offsets := []int64{}
+	participated := []bool{}
+	for rows.Next() {
+		var rootID string
+		var offset int64
+		var sent bool
+		if err = rows.Scan(&rootID, &offset, &sent); err != nil {
+			return nil, nil, nil, err
+		}
+		rootIDs = append(rootIDs, rootID)
+		offsets = append(offsets, offset)
+		participated = append(participated, sent)
+	}
+	return rootIDs, offsets, participated, rows.Err()
+}
@@ -0,0 +1,178 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package syncapi
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/finogeeks/ligase/model/syncapitypes"
+	"github.com/lib/pq"
+)
+
+// The event_json of syncapi_output_room_events may be encrypted, so the
+// searchable content is indexed apart.
+const eventSearchSchema = `
+-- Stores the full text index of the events in syncapi_output_room_events
+CREATE TABLE IF NOT EXISTS syncapi_event_search (
+    event_id TEXT NOT NULL PRIMARY KEY,
+    room_id TEXT NOT NULL,
+    sender TEXT NOT NULL,
+    type TEXT NOT NULL,
+    -- The indexed content field: content.body, content.name or content.topic
+    key TEXT NOT NULL,
+    origin_server_ts BIGINT NOT NULL,
+    -- The stream position of the event in syncapi_output_room_events
+    id BIGINT NOT NULL,
+    vector tsvector NOT NULL
+);
+CREATE INDEX IF NOT EXISTS syncapi_event_search_vector_idx ON syncapi_event_search USING GIN(vector);
+CREATE INDEX IF NOT EXISTS syncapi_event_search_room_idx ON syncapi_event_search(room_id, id);
+`
+
+// The lexemes are made by the configured tokenizer, array_to_tsvector keeps
+// them as they are instead of parsing them again.
+const upsertEventSearchSQL = "" +
+	"INSERT INTO syncapi_event_search (event_id, room_id, sender, type, key, origin_server_ts, id, vector)" +
+	" VALUES ($1, $2, $3, $4, $5, $6, $7, array_to_tsvector($8))" +
+	" ON CONFLICT (event_id) DO UPDATE SET vector = EXCLUDED.vector"
+
+const deleteEventSearchSQL = "" +
+	"DELETE FROM syncapi_event_search WHERE event_id = $1"
+
+const deleteEventSearchesSQL = "" +
+	"DELETE FROM syncapi_event_search WHERE event_id = ANY($1)"
+
+const searchEventsByRankSQL = "" +
+	"SELECT event_id, room_id, sender, type, origin_server_ts, id, ts_rank(vector, $1::tsquery) AS rank" +
+	" FROM syncapi_event_search WHERE vector @@ $1::tsquery AND room_id = ANY($2) AND key = ANY($3)" +
+	" ORDER BY rank DESC, id DESC LIMIT $4 OFFSET $5"
+
+const searchEventsByRecentSQL = "" +
+	"SELECT event_id, room_id, sender, type, origin_server_ts, id, ts_rank(vector, $1::tsquery) AS rank" +
+	" FROM syncapi_event_search WHERE vector @@ $1::tsquery AND room_id = ANY($2) AND key = ANY($3)" +
+	" ORDER BY id DESC LIMIT $4 OFFSET $5"
+
+// The rows are counted where the user may see them: the visibility ranges
+// are passed as parallel arrays of room, start and end, an end of -1 is open.
+const countSearchEventsSQL = "" +
+	"SELECT count(*) FROM syncapi_event_search s" +
+	" WHERE s.vector @@ $1::tsquery AND s.room_id = ANY($2) AND s.key = ANY($3)" +
+	" AND (cardinality($4::text[]) = 0 OR s.sender = ANY($4)) AND NOT s.sender = ANY($5)" +
+	" AND (cardinality($6::text[]) = 0 OR s.type = ANY($6)) AND NOT s.type = ANY($7)" +
+	" AND EXISTS (SELECT 1 FROM unnest($2::text[], $8::bigint[], $9::bigint[]) AS v(room_id, start_ts, end_ts)" +
+	" WHERE v.room_id = s.room_id AND s.origin_server_ts >= v.start_ts" +
+	" AND (v.end_ts = -1 OR s.origin_server_ts <= v.end_ts))"
+
+type eventSearchStatements struct {
+	db                       *Database
+	upsertEventSearchStmt    *sql.Stmt
+	deleteEventSearchStmt    *sql.Stmt
+	deleteEventSearchesStmt  *sql.Stmt
+	searchEventsByRankStmt   *sql.Stmt
+	searchEventsByRecentStmt *sql.Stmt
+	countSearchEventsStmt    *sql.Stmt
+}
+
+func (s *eventSearchStatements) getSchema() string {
+	return eventSearchSchema
+}
+
+func (s *eventSearchStatements) prepare(db *sql.DB, d *Database) (err error) {
+	s.db = d
+	if s.upsertEventSearchStmt, err = db.Prepare(upsertEventSearchSQL); err != nil {
+		return
+	}
+	if s.deleteEventSearchStmt, err = db.Prepare(deleteEventSearchSQL); err != nil {
+		return
+	}
+	if s.deleteEventSearchesStmt, err = db.Prepare(deleteEventSearchesSQL); err != nil {
+		return
+	}
+	if s.searchEventsByRankStmt, err = db.Prepare(searchEventsByRankSQL); err != nil {
+		return
+	}
+	if s.searchEventsByRecentStmt, err = db.Prepare(searchEventsByRecentSQL); err != nil {
+		return
+	}
+	if s.countSearchEventsStmt, err = db.Prepare(countSearchEventsSQL); err != nil {
+		return
+	}
+	return
+}
+
+func (s *eventSearchStatements) upsertEventSearch(
+	ctx context.Context, eventID, roomID, sender, eventType, key string, ts, offset int64, lexemes []string,
+) error {
+	_, err := s.upsertEventSearchStmt.ExecContext(
+		ctx, eventID, roomID, sender, eventType, key, ts, offset, pq.StringArray(lexemes),
+	)
+	return err
+}
+
+func (s *eventSearchStatements) deleteEventSearch(ctx context.Context, eventID string) error {
+	_, err := s.deleteEventSearchStmt.ExecContext(ctx, eventID)
+	return err
+}
+
+func (s *eventSearchStatements) deleteEventSearches(ctx context.Context, eventIDs []string) error {
+	_, err := s.deleteEventSearchesStmt.ExecContext(ctx, pq.StringArray(eventIDs))
+	return err
+}
+
+func (s *eventSearchStatements) searchEvents(
+	ctx context.Context, query string, roomIDs, keys []string, orderBy string, limit, skip int,
+) ([]syncapitypes.EventSearchResult, error) {
+	stmt := s.searchEventsByRankStmt
+	if orderBy == "recent" {
+		stmt = s.searchEventsByRecentStmt
+	}
+	rows, err := stmt.QueryContext(ctx, query, pq.StringArray(roomIDs), pq.StringArray(keys), limit, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	results := []syncapitypes.EventSearchResult{}
+	for rows.Next() {
+		var r syncapitypes.EventSearchResult
+		if err = rows.Scan(&r.EventID, &r.RoomID, &r.Sender, &r.Type, &r.OriginServerTS, &r.Offset, &r.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func (s *eventSearchStatements) countSearchEvents(
+	ctx context.Context, query string, visibility map[string][]syncapitypes.VisibilityRange,
+	keys []string, filter *syncapitypes.EventSearchFilter,
+) (count int64, err error) {
+	var roomIDs []string
+	var starts, ends []int64
+	for roomID, ranges := range visibility {
+		for _, r := range ranges {
+			roomIDs = append(roomIDs, roomID)
+			starts = append(starts, r.Start)
+			ends = append(ends, r.End)
+		}
+	}
+	err = s.countSearchEventsStmt.QueryRowContext(
+		ctx, query, pq.StringArray(roomIDs), pq.StringArray(keys),
+		pq.StringArray(filter.Senders), pq.StringArray(filter.NotSenders),
+		pq.StringArray(filter.Types), pq.StringArray(filter.NotTypes),
+		pq.Int64Array(starts), pq.Int64Array(ends),
+	).Scan(&count)
+	return
+}
@@ -70,6 +70,8 @@ CREATE INDEX  IF NOT EXISTS syncapi_user_history  ON syncapi_output_room_events
 CREATE INDEX IF NOT EXISTS syncapi_output_room_visibility  ON syncapi_output_room_events (type,room_id,device_id) WHERE device_id IS NOT NULL;
 CREATE INDEX  IF NOT EXISTS syncapi_user_recent  ON syncapi_output_room_events (room_id);
 CREATE INDEX  IF NOT EXISTS syncapi_load_room_history ON syncapi_output_room_events (id,room_id);
+-- for timestamp_to_event
+CREATE INDEX IF NOT EXISTS syncapi_output_room_ts_idx ON syncapi_output_room_events (room_id, origin_server_ts);
 
 -- mirror table for debug, plaintext storage
 CREATE TABLE IF NOT EXISTS syncapi_output_room_events_mirror (
@@ -153,7 +155,7 @@ const selectHistoryEvents = "" +
 	" ORDER BY id DESC LIMIT $2"
 
 const selectRoomStateStreamSQL = "" +
-	"SELECT event_json, id, type FROM syncapi_output_room_events WHERE room_id = $1 AND type=any('{\"m.room.create\", \"m.room.member\", \"m.room.power_levels\", \"m.room.join_rules\", \"m.room.history_visibility\", \"m.room.visibility\",\"m.room.name\", \"m.room.topic\", \"m.room.desc\", \"m.room.pinned_events\",\"m.room.aliases\", \"m.room.canonical_alias\", \"m.room.avatar\", \"m.room.encryption\"}') AND id >= $2 ORDER BY id ASC"
+	"SELECT event_json, id, type FROM syncapi_output_room_events WHERE room_id = $1 AND type=any('{\"m.room.create\", \"m.room.member\", \"m.room.power_levels\", \"m.room.join_rules\", \"m.room.history_visibility\", \"m.room.visibility\",\"m.room.name\", \"m.room.topic\", \"m.room.desc\", \"m.room.pinned_events\",\"m.room.aliases\", \"m.room.canonical_alias\", \"m.room.avatar\", \"m.room.encryption\", \"m.room.retention\"}') AND id >= $2 ORDER BY id ASC"
 
 const selectRoomLatestStreamsSQL = "" +
 	"SELECT max(id), room_id FROM syncapi_output_room_events WHERE room_id = ANY($1) group by room_id"
@@ -162,6 +164,11 @@ const selectTypeEventForwardSQL = "" +
 	"SELECT id, event_json, type FROM syncapi_output_room_events" +
 	" WHERE type = any($1) AND room_id = $2 AND id > 0 ORDER BY id ASC"
 
+// backfilled events have negative ids, so the walk starts below them
+const selectTypeEventsAfterSQL = "" +
+	"SELECT id, event_json, type FROM syncapi_output_room_events" +
+	" WHERE type = any($1) AND id > $2 ORDER BY id ASC LIMIT $3"
+
 const updateSyncMemberEventAvatarSQL = "" +
 	"UPDATE syncapi_output_room_events set event_json = replace(event_json, $1, $2) where type = 'm.room.member' AND event_json like $3"
 
@@ -208,6 +215,28 @@ const selectEventRawSQL = "" +
 const selectEventsByRoomIDSQL = "" +
 	"SELECT id, event_id, event_json FROM syncapi_output_room_events WHERE room_id = $1"
 
+// The latest event of the room is never purged
+const selectPurgeEventsSQL = "" +
+	"SELECT id, event_id, event_json, type FROM syncapi_output_room_events" +
+	" WHERE room_id = $1 AND origin_server_ts < $2 AND id > $3" +
+	" AND id < (SELECT max(id) FROM syncapi_output_room_events WHERE room_id = $1)" +
+	" ORDER BY id ASC LIMIT $4"
+
+const selectEventByTimestampForwardSQL = "" +
+	"SELECT event_id, origin_server_ts FROM syncapi_output_room_events" +
+	" WHERE room_id = $1 AND origin_server_ts >= $2 ORDER BY origin_server_ts ASC, id ASC LIMIT 1"
+
+// origin_server_ts is -1 for the events stored before it was recorded
+const selectEventByTimestampBackwardSQL = "" +
+	"SELECT event_id, origin_server_ts FROM syncapi_output_room_events" +
+	" WHERE room_id = $1 AND origin_server_ts >= 0 AND origin_server_ts <= $2 ORDER BY origin_server_ts DESC, id DESC LIMIT 1"
+
+const deleteRoomEventsSQL = "" +
+	"DELETE FROM syncapi_output_room_events WHERE room_id = $1 AND event_id = ANY($2)"
+
+const deleteRoomEventsMirrorSQL = "" +
+	"DELETE FROM syncapi_output_room_events_mirror WHERE room_id = $1 AND event_id = ANY($2)"
+
 type outputRoomEventsStatements struct {
 	db                          *Database
 	insertEventStmt             *sql.Stmt
@@ -223,6 +252,7 @@ type outputRoomEventsStatements struct {
 	selectRoomStateStreamStmt   *sql.Stmt
 	selectRoomLatestStreamsStmt *sql.Stmt
 	selectTypeEventForwardStmt  *sql.Stmt
+	selectTypeEventsAfterStmt   *sql.Stmt
 	updateMemberEventAvatarStmt *sql.Stmt
 	// selectMemberEventAvatarStmt   *sql.Stmt
 	selectAllSyncRoomsStmt        *sql.Stmt
@@ -238,6 +268,11 @@ type outputRoomEventsStatements struct {
 	updateSyncMsgEventStmt        *sql.Stmt
 	selectEventRawStmt            *sql.Stmt
 	selectEventsByRoomIDStmt      *sql.Stmt
+	selectPurgeEventsStmt         *sql.Stmt
+	selectEventByTsForwardStmt    *sql.Stmt
+	selectEventByTsBackwardStmt   *sql.Stmt
+	deleteRoomEventsStmt          *sql.Stmt
+	deleteRoomEventsMirrorStmt    *sql.Stmt
 }
 
 func (s *outputRoomEventsStatements) getSchema() string {
@@ -289,6 +324,9 @@ func (s *outputRoomEventsStatements) prepare(db *sql.DB, d *Database) (err error
 	if s.selectTypeEventForwardStmt, err = db.Prepare(selectTypeEventForwardSQL); err != nil {
 		return
 	}
+	if s.selectTypeEventsAfterStmt, err = db.Prepare(selectTypeEventsAfterSQL); err != nil {
+		return
+	}
 	if s.updateMemberEventAvatarStmt, err = db.Prepare(updateSyncMemberEventAvatarSQL); err != nil {
 		return
 	}
@@ -334,6 +372,21 @@ func (s *outputRoomEventsStatements) prepare(db *sql.DB, d *Database) (err error
 	if s.selectEventsByRoomIDStmt, err = db.Prepare(selectEventsByRoomIDSQL); err != nil {
 		return
 	}
+	if s.selectPurgeEventsStmt, err = db.Prepare(selectPurgeEventsSQL); err != nil {
+		return
+	}
+	if s.selectEventByTsForwardStmt, err = db.Prepare(selectEventByTimestampForwardSQL); err != nil {
+		return
+	}
+	if s.selectEventByTsBackwardStmt, err = db.Prepare(selectEventByTimestampBackwardSQL); err != nil {
+		return
+	}
+	if s.deleteRoomEventsStmt, err = db.Prepare(deleteRoomEventsSQL); err != nil {
+		return
+	}
+	if s.deleteRoomEventsMirrorStmt, err = db.Prepare(deleteRoomEventsMirrorSQL); err != nil {
+		return
+	}
 	return
 }
 
@@ -380,6 +433,42 @@ func (s *outputRoomEventsStatements) selectTypeEventForward(
 	return evs, ids, nil
 }
 
+func (s *outputRoomEventsStatements) selectTypeEventsAfter(
+	ctx context.Context,
+	typ []string, after int64, limit int,
+) (events []gomatrixserverlib.ClientEvent, offsets []int64, err error) {
+	rows, err := s.selectTypeEventsAfterStmt.QueryContext(ctx, pq.StringArray(typ), after, limit)
+	if err != nil {
+		log.Errorf("outputRoomEventsStatements.selectTypeEventsAfter err: %v", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			streamPos  int64
+			eventBytes []byte
+			eventType  string
+		)
+		if err = rows.Scan(&streamPos, &eventBytes, &eventType); err != nil {
+			return nil, nil, err
+		}
+
+		var ev gomatrixserverlib.ClientEvent
+		if encryption.CheckCrypto(eventType) {
+			err = json.Unmarshal(encryption.Decrypt(eventBytes), &ev)
+		} else {
+			err = json.Unmarshal(eventBytes, &ev)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		events = append(events, ev)
+		offsets = append(offsets, streamPos)
+	}
+	return events, offsets, rows.Err()
+}
+
 func (s *outputRoomEventsStatements) selectRoomLastOffsets(
 	ctx context.Context,
 	roomIDs []string,
@@ -1066,3 +1155,69 @@ func (s *outputRoomEventsStatements) selectEventsByRoomIDMigration(
 	}
 	return ids, eventIDs, result, nil
 }
+
+// selectEventByTimestamp returns the event of roomID closest to ts, at or
+// after it when dir is "f" and at or before it otherwise. The event ID is
+// empty if there is none.
+func (s *outputRoomEventsStatements) selectEventByTimestamp(
+	ctx context.Context, roomID string, ts int64, dir string,
+) (eventID string, originServerTS int64, err error) {
+	stmt := s.selectEventByTsBackwardStmt
+	if dir == "f" {
+		stmt = s.selectEventByTsForwardStmt
+	}
+	err = stmt.QueryRowContext(ctx, roomID, ts).Scan(&eventID, &originServerTS)
+	if err == sql.ErrNoRows {
+		return "", 0, nil
+	}
+	return
+}
+
+// selectPurgeEvents returns the events of roomID sent before ts, starting
+// after the stream position from. isState tells the state events apart, as
+// the event_json has to be decrypted for it.
+func (s *outputRoomEventsStatements) selectPurgeEvents(
+	ctx context.Context, roomID string, ts, from int64, limit int,
+) (offsets []int64, eventIDs []string, isState []bool, err error) {
+	rows, err := s.selectPurgeEventsStmt.QueryContext(ctx, roomID, ts, from, limit)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			id         int64
+			eventID    string
+			eventBytes []byte
+			eventType  string
+		)
+		if err = rows.Scan(&id, &eventID, &eventBytes, &eventType); err != nil {
+			return nil, nil, nil, err
+		}
+		if encryption.CheckCrypto(eventType) {
+			eventBytes = encryption.Decrypt(eventBytes)
+		}
+		var ev struct {
+			StateKey *string `json:"state_key"`
+		}
+		if err = json.Unmarshal(eventBytes, &ev); err != nil {
+			return nil, nil, nil, err
+		}
+
+		offsets = append(offsets, id)
+		eventIDs = append(eventIDs, eventID)
+		isState = append(isState, ev.StateKey != nil)
+	}
+	return offsets, eventIDs, isState, rows.Err()
+}
+
+func (s *outputRoomEventsStatements) deleteRoomEvents(
+	ctx context.Context, roomID string, eventIDs []string,
+) error {
+	if _, err := s.deleteRoomEventsStmt.ExecContext(ctx, roomID, pq.StringArray(eventIDs)); err != nil {
+		return err
+	}
+	_, err := s.deleteRoomEventsMirrorStmt.ExecContext(ctx, roomID, pq.StringArray(eventIDs))
+	return err
+}
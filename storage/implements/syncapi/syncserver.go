@@ -20,6 +20,8 @@ package syncapi
 import (
 	"context"
 	"database/sql"
+	"math"
+	"strings"
 	"time"
 
 	mon "github.com/finogeeks/ligase/skunkworks/monitor/go-client/monitor"
@@ -67,6 +69,9 @@ type Database struct {
 	presenceData    presenceDataStreamStatements
 	userTimeLine    userTimeLineStatements
 	outputMinStream outputMinStreamStatements
+	userDirectory   userDirectoryStatements
+	eventRelations  eventRelationsStatements
+	eventSearch     eventSearchStatements
 	AsyncSave       bool
 
 	qryDBGauge mon.LabeledGauge
@@ -136,7 +141,10 @@ func NewDatabase(driver, createAddr, address, underlying, topic string, useAsync
 		d.presenceData.getSchema(),
 		d.userReceiptData.getSchema(),
 		d.userTimeLine.getSchema(),
-		d.outputMinStream.getSchema()}
+		d.outputMinStream.getSchema(),
+		d.userDirectory.getSchema(),
+		d.eventRelations.getSchema(),
+		d.eventSearch.getSchema()}
 	for _, sqlStr := range schemas {
 		_, err := d.db.Exec(sqlStr)
 		if err != nil {
@@ -174,6 +182,15 @@ func NewDatabase(driver, createAddr, address, underlying, topic string, useAsync
 	if err := d.outputMinStream.prepare(d.db, d); err != nil {
 		return nil, err
 	}
+	if err := d.userDirectory.prepare(d.db, d); err != nil {
+		return nil, err
+	}
+	if err := d.eventRelations.prepare(d.db, d); err != nil {
+		return nil, err
+	}
+	if err := d.eventSearch.prepare(d.db, d); err != nil {
+		return nil, err
+	}
 	return d, nil
 }
 
@@ -582,6 +599,15 @@ func (d *Database) SelectTypeEventForward(
 	return d.events.selectTypeEventForward(ctx, typ, roomID)
 }
 
+// SelectTypeEventsAfter returns up to limit events of the types in all rooms
+// whose stream position is above after, in stream order.
+func (d *Database) SelectTypeEventsAfter(
+	ctx context.Context,
+	typ []string, after int64, limit int,
+) (events []gomatrixserverlib.ClientEvent, offsets []int64, err error) {
+	return d.events.selectTypeEventsAfter(ctx, typ, after, limit)
+}
+
 func (d *Database) UpdateSyncMemberEvent(
 	ctx context.Context, userID, oldAvatarUrl, newAvatarUrl string,
 ) error {
@@ -596,6 +622,14 @@ func (d *Database) GetAllSyncRooms() ([]string, error) {
 	return d.events.selectAllSyncRooms()
 }
 
+// SelectCurrentStateByType returns the current state event of type eventType
+// with an empty state key of every room having one
+func (d *Database) SelectCurrentStateByType(
+	ctx context.Context, eventType string,
+) ([]gomatrixserverlib.ClientEvent, error) {
+	return d.roomstate.selectStateByType(ctx, eventType)
+}
+
 func (d *Database) SelectUserTimeLineEvents(
 	ctx context.Context,
 	userID string,
@@ -725,3 +759,142 @@ func (d *Database) GetRoomStateWithLimit(ctx context.Context, limit, offset int6
 func (d *Database) GetRoomStateByEventID(ctx context.Context, eventID string) ([]byte, error) {
 	return d.roomstate.selectRoomStateByEventID(ctx, eventID)
 }
+
+func (d *Database) UpsertUserDirectory(ctx context.Context, entry *syncapitypes.UserDirectoryEntry) error {
+	return d.userDirectory.upsertUserDirectory(ctx, entry)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUserDirectory returns the users matching every word of term in any
+// order, at most limit of them. Only the users in userIDs are searched unless
+// it is nil.
+func (d *Database) SearchUserDirectory(
+	ctx context.Context, term string, userIDs []string, limit int,
+) ([]syncapitypes.UserDirectoryEntry, error) {
+	words := strings.Fields(strings.ToLower(term))
+	patterns := make([]string, 0, len(words))
+	for _, word := range words {
+		patterns = append(patterns, "%"+likeEscaper.Replace(word)+"%")
+	}
+	return d.userDirectory.searchUserDirectory(ctx, patterns, strings.Join(words, " "), userIDs, limit)
+}
+
+func (d *Database) GetUserDirectoryTotal(ctx context.Context) (int, error) {
+	return d.userDirectory.selectUserDirectoryCount(ctx)
+}
+
+func (d *Database) InsertEventRelation(
+	ctx context.Context, eventID, roomID, relatesToID, relType, eventType, sender, key string, offset int64,
+) error {
+	return d.eventRelations.insertEventRelation(ctx, eventID, roomID, relatesToID, relType, eventType, sender, key, offset)
+}
+
+func (d *Database) DeleteEventRelation(ctx context.Context, eventID string) error {
+	return d.eventRelations.deleteEventRelation(ctx, eventID)
+}
+
+// SelectEventRelations returns the events relating to relatesToID with their
+// stream positions, after from when dir is "f" and before it otherwise.
+func (d *Database) SelectEventRelations(
+	ctx context.Context, relatesToID, relType, eventType, dir string, from int64, limit int,
+) ([]string, []int64, error) {
+	return d.eventRelations.selectEventRelations(ctx, relatesToID, relType, eventType, dir, from, limit)
+}
+
+// SelectLatestEventRelation returns the latest event relating to relatesToID
+// by relType, sent by sender if it isn't empty, other than excludeID. It
+// returns "" if there is none.
+func (d *Database) SelectLatestEventRelation(
+	ctx context.Context, relatesToID, relType, sender, excludeID string,
+) (string, error) {
+	return d.eventRelations.selectLatestEventRelation(ctx, relatesToID, relType, sender, excludeID)
+}
+
+// SelectRoomThreads returns the thread roots of the room with the stream
+// position of their latest reply, and whether userID replied to them.
+func (d *Database) SelectRoomThreads(
+	ctx context.Context, roomID, userID string, from int64, limit int,
+) ([]string, []int64, []bool, error) {
+	return d.eventRelations.selectRoomThreads(ctx, roomID, userID, from, limit)
+}
+
+// UpsertEventSearch indexes the content field key of the event under lexemes
+func (d *Database) UpsertEventSearch(
+	ctx context.Context, eventID, roomID, sender, eventType, key string, ts, offset int64, lexemes []string,
+) error {
+	return d.eventSearch.upsertEventSearch(ctx, eventID, roomID, sender, eventType, key, ts, offset, lexemes)
+}
+
+func (d *Database) DeleteEventSearch(ctx context.Context, eventID string) error {
+	return d.eventSearch.deleteEventSearch(ctx, eventID)
+}
+
+// SearchEvents returns the events of the rooms matching the tsquery, skipping
+// the first skip ones. orderBy is "rank" or "recent".
+func (d *Database) SearchEvents(
+	ctx context.Context, query string, roomIDs, keys []string, orderBy string, limit, skip int,
+) ([]syncapitypes.EventSearchResult, error) {
+	return d.eventSearch.searchEvents(ctx, query, roomIDs, keys, orderBy, limit, skip)
+}
+
+// CountSearchEvents counts the events matching the tsquery which the user may
+// see through visibility and which pass the senders and types of filter.
+func (d *Database) CountSearchEvents(
+	ctx context.Context, query string, visibility map[string][]syncapitypes.VisibilityRange,
+	keys []string, filter *syncapitypes.EventSearchFilter,
+) (int64, error) {
+	return d.eventSearch.countSearchEvents(ctx, query, visibility, keys, filter)
+}
+
+// SelectEventByTimestamp returns the event of roomID closest to ts in the
+// direction dir, the event ID is empty if there is none.
+func (d *Database) SelectEventByTimestamp(
+	ctx context.Context, roomID string, ts int64, dir string,
+) (string, int64, error) {
+	return d.events.selectEventByTimestamp(ctx, roomID, ts, dir)
+}
+
+const purgeBatchSize = 500
+
+// PurgeRoomEvents deletes the events of roomID sent before ts, the state
+// events and the latest event of the room are kept. It returns the IDs of
+// the purged events.
+func (d *Database) PurgeRoomEvents(ctx context.Context, roomID string, ts int64) ([]string, error) {
+	purged := []string{}
+	from := int64(math.MinInt64)
+	for {
+		offsets, eventIDs, isState, err := d.events.selectPurgeEvents(ctx, roomID, ts, from, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+		var purgeIDs []string
+		var purgeOffsets []int64
+		for idx := range eventIDs {
+			if !isState[idx] {
+				purgeIDs = append(purgeIDs, eventIDs[idx])
+				purgeOffsets = append(purgeOffsets, offsets[idx])
+			}
+		}
+		if len(purgeIDs) > 0 {
+			// the events go last so a failed purge can be run again
+			if err = d.eventRelations.deleteEventRelations(ctx, purgeIDs); err != nil {
+				return purged, err
+			}
+			if err = d.eventSearch.deleteEventSearches(ctx, purgeIDs); err != nil {
+				return purged, err
+			}
+			if err = d.userTimeLine.deleteRoomUserTimeLine(ctx, roomID, purgeOffsets); err != nil {
+				return purged, err
+			}
+			if err = d.events.deleteRoomEvents(ctx, roomID, purgeIDs); err != nil {
+				return purged, err
+			}
+			purged = append(purged, purgeIDs...)
+		}
+		if len(offsets) < purgeBatchSize {
+			return purged, nil
+		}
+		from = offsets[len(offsets)-1]
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package syncapi
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/finogeeks/ligase/model/syncapitypes"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/lib/pq"
+)
+
+const userDirectorySchema = `
+-- Stores the profiles searched by /user_directory/search
+CREATE TABLE IF NOT EXISTS syncapi_user_directory (
+    user_id TEXT NOT NULL PRIMARY KEY,
+    display_name TEXT NOT NULL DEFAULT '',
+    avatar_url TEXT NOT NULL DEFAULT '',
+    -- The org fields of user_info
+    user_name TEXT NOT NULL DEFAULT '',
+    job_number TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL DEFAULT '',
+    -- The lowercased fields above and the pinyin of the names, one per line
+    search_text TEXT NOT NULL DEFAULT ''
+);
+`
+
+// The trigram index serves the LIKE '%word%' searches. The pg_trgm extension
+// may not be available to the database user, the searches then scan the table.
+const userDirectoryTrgmIndexSQL = `
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS syncapi_user_directory_search_text_idx
+    ON syncapi_user_directory USING gin (search_text gin_trgm_ops);
+`
+
+const upsertUserDirectorySQL = "" +
+	"INSERT INTO syncapi_user_directory (user_id, display_name, avatar_url, user_name, job_number, email, search_text)" +
+	" VALUES ($1, $2, $3, $4, $5, $6, $7)" +
+	" ON CONFLICT (user_id) DO UPDATE SET display_name = EXCLUDED.display_name, avatar_url = EXCLUDED.avatar_url," +
+	" user_name = EXCLUDED.user_name, job_number = EXCLUDED.job_number, email = EXCLUDED.email, search_text = EXCLUDED.search_text"
+
+// Every pattern of $4 must match. $1 is the longest of them, on its own so
+// that the trigram index can be used. Exact display name matches come first.
+const searchUserDirectorySQL = "" +
+	"SELECT user_id, display_name, avatar_url, user_name, job_number, email FROM syncapi_user_directory" +
+	" WHERE search_text LIKE $1 AND search_text LIKE ALL($4)" +
+	" ORDER BY lower(display_name) = $2 DESC, user_id LIMIT $3"
+
+const searchUserDirectoryInSQL = "" +
+	"SELECT user_id, display_name, avatar_url, user_name, job_number, email FROM syncapi_user_directory" +
+	" WHERE search_text LIKE $1 AND search_text LIKE ALL($4) AND user_id = ANY($5)" +
+	" ORDER BY lower(display_name) = $2 DESC, user_id LIMIT $3"
+
+const selectUserDirectoryCountSQL = "" +
+	"SELECT count(*) FROM syncapi_user_directory"
+
+type userDirectoryStatements struct {
+	db                           *Database
+	upsertUserDirectoryStmt      *sql.Stmt
+	searchUserDirectoryStmt      *sql.Stmt
+	searchUserDirectoryInStmt    *sql.Stmt
+	selectUserDirectoryCountStmt *sql.Stmt
+}
+
+func (s *userDirectoryStatements) getSchema() string {
+	return userDirectorySchema
+}
+
+func (s *userDirectoryStatements) prepare(db *sql.DB, d *Database) (err error) {
+	s.db = d
+	if _, e := db.Exec(userDirectoryTrgmIndexSQL); e != nil {
+		log.Warnf("user directory searches will scan the table, can't create the trigram index: %v", e)
+	}
+	if s.upsertUserDirectoryStmt, err = db.Prepare(upsertUserDirectorySQL); err != nil {
+		return
+	}
+	if s.searchUserDirectoryStmt, err = db.Prepare(searchUserDirectorySQL); err != nil {
+		return
+	}
+	if s.searchUserDirectoryInStmt, err = db.Prepare(searchUserDirectoryInSQL); err != nil {
+		return
+	}
+	if s.selectUserDirectoryCountStmt, err = db.Prepare(selectUserDirectoryCountSQL); err != nil {
+		return
+	}
+	return
+}
+
+func (s *userDirectoryStatements) upsertUserDirectory(
+	ctx context.Context, entry *syncapitypes.UserDirectoryEntry,
+) error {
+	_, err := s.upsertUserDirectoryStmt.ExecContext(
+		ctx, entry.UserID, entry.DisplayName, entry.AvatarURL,
+		entry.UserName, entry.JobNumber, entry.Email, entry.SearchText(),
+	)
+	return err
+}
+
+// searchUserDirectory returns the entries matching every pattern, it only
+// looks at userIDs unless it is nil
+func (s *userDirectoryStatements) searchUserDirectory(
+	ctx context.Context, patterns []string, term string, userIDs []string, limit int,
+) ([]syncapitypes.UserDirectoryEntry, error) {
+	longest := "%"
+	for _, pattern := range patterns {
+		if len(pattern) > len(longest) {
+			longest = pattern
+		}
+	}
+	var rows *sql.Rows
+	var err error
+	if userIDs == nil {
+		rows, err = s.searchUserDirectoryStmt.QueryContext(ctx, longest, term, limit, pq.StringArray(patterns))
+	} else {
+		rows, err = s.searchUserDirectoryInStmt.QueryContext(
+			ctx, longest, term, limit, pq.StringArray(patterns), pq.StringArray(userIDs),
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // nolint: errcheck
+	entries := []syncapitypes.UserDirectoryEntry{}
+	for rows.Next() {
+		var e syncapitypes.UserDirectoryEntry
+		if err = rows.Scan(&e.UserID, &e.DisplayName, &e.AvatarURL, &e.UserName, &e.JobNumber, &e.Email); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *userDirectoryStatements) selectUserDirectoryCount(ctx context.Context) (count int, err error) {
+	err = s.selectUserDirectoryCountStmt.QueryRowContext(ctx).Scan(&count)
+	return
+}
@@ -60,6 +60,9 @@ const selectUserTimeLineMinPosSQL = "" +
 const selectOffsetSQL = "" +
 	"SELECT id, user_id, room_id, event_offset, room_state FROM syncapi_user_time_line WHERE user_id = $1 AND event_offset = ANY($2)"
 
+const deleteRoomUserTimeLineSQL = "" +
+	"DELETE FROM syncapi_user_time_line WHERE room_id = $1 AND event_offset = ANY($2)"
+
 type userTimeLineStatements struct {
 	db                           *Database
 	insertUserTimeLineStmt       *sql.Stmt
@@ -67,6 +70,7 @@ type userTimeLineStatements struct {
 	selectUserTimeLineMinPosStmt *sql.Stmt
 	selectHistoryStmt            *sql.Stmt
 	selectOffsetStmt             *sql.Stmt
+	deleteRoomUserTimeLineStmt   *sql.Stmt
 }
 
 func (s *userTimeLineStatements) getSchema() string {
@@ -90,6 +94,9 @@ func (s *userTimeLineStatements) prepare(db *sql.DB, d *Database) (err error) {
 	if s.selectOffsetStmt, err = db.Prepare(selectOffsetSQL); err != nil {
 		return
 	}
+	if s.deleteRoomUserTimeLineStmt, err = db.Prepare(deleteRoomUserTimeLineSQL); err != nil {
+		return
+	}
 	return
 }
 
@@ -234,3 +241,10 @@ func (s *userTimeLineStatements) selectUserTimeLineOffset(
 	}
 	return evs, nil
 }
+
+func (s *userTimeLineStatements) deleteRoomUserTimeLine(
+	ctx context.Context, roomID string, offsets []int64,
+) error {
+	_, err := s.deleteRoomUserTimeLineStmt.ExecContext(ctx, roomID, pq.Int64Array(offsets))
+	return err
+}
@@ -39,6 +39,14 @@ type AccountsDatabase interface {
 	) (*authtypes.Account, error)
 
 	GetAccount(ctx context.Context, userID string) (*authtypes.Account, error)
+	GetAccountByPassword(ctx context.Context, userID, plaintextPassword string) (*authtypes.Account, error)
+	UpdatePassword(ctx context.Context, userID, plaintextPassword string) error
+	DeactivateAccount(ctx context.Context, userID string) error
+	IsAccountDeactivated(ctx context.Context, userID string) (bool, error)
+	LockAccount(ctx context.Context, userID string) error
+	UnlockAccount(ctx context.Context, userID string) error
+	IsAccountLocked(ctx context.Context, userID string) (bool, error)
+	GetAccountsPage(ctx context.Context, search string, limit, offset int64) ([]authtypes.AccountSummary, int64, error)
 
 	UpsertProfile(ctx context.Context, userID, displayName, avatarURL string) error
 	UpsertProfileSync(ctx context.Context, userID, displayName, avatarURL string) error
@@ -87,4 +95,23 @@ type AccountsDatabase interface {
 	GetAllUserInfo() ([]authtypes.UserInfo, error)
 	DeleteUserInfo(ctx context.Context, userID string) error
 	OnDeleteUserInfo(ctx context.Context, userID string) error
+
+	SaveThreePIDAssociation(ctx context.Context, medium, address, userID string) (bool, error)
+	RemoveThreePIDAssociation(ctx context.Context, medium, address, userID string) error
+	RemoveThreePIDsForUser(ctx context.Context, userID string) error
+	GetThreePIDsForUser(ctx context.Context, userID string) ([]authtypes.ThreePID, error)
+	GetUserIDByThreePID(ctx context.Context, medium, address string) (string, error)
+	UpsertThreePIDSession(ctx context.Context, session *authtypes.ThreePIDSession) (string, error)
+	GetThreePIDSession(ctx context.Context, sid string) (*authtypes.ThreePIDSession, error)
+	GetThreePIDSessionBySecret(ctx context.Context, clientSecret, medium, address string) (*authtypes.ThreePIDSession, error)
+	ValidateThreePIDSession(ctx context.Context, sid string) error
+	DeleteThreePIDSession(ctx context.Context, sid string) error
+	DeleteExpiredThreePIDSessions(ctx context.Context, before int64) error
+
+	CreateRegistrationToken(ctx context.Context, token *authtypes.RegistrationToken) (bool, error)
+	GetRegistrationToken(ctx context.Context, token string) (*authtypes.RegistrationToken, error)
+	GetRegistrationTokens(ctx context.Context) ([]authtypes.RegistrationToken, error)
+	DeleteRegistrationToken(ctx context.Context, token string) (bool, error)
+	UseRegistrationToken(ctx context.Context, token string, now int64) (bool, error)
+	ReleaseRegistrationToken(ctx context.Context, token string) error
 }
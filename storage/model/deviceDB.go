@@ -90,4 +90,16 @@ type DeviceDatabase interface {
 	LoadSimpleFilterData(ctx context.Context, f *filter.SimpleFilter) bool
 
 	LoadFilterData(ctx context.Context, key string, f *filter.Filter) bool
+
+	SaveRefreshToken(
+		ctx context.Context, tokenHash string, token *authtypes.RefreshToken,
+	) error
+
+	TakeRefreshToken(
+		ctx context.Context, tokenHash string,
+	) (*authtypes.RefreshToken, error)
+
+	RemoveDeviceRefreshTokens(
+		ctx context.Context, userID, deviceID string,
+	) error
 }
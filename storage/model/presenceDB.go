@@ -33,4 +33,9 @@ type PresenceDatabase interface {
 	OnUpsertPresences(
 		ctx context.Context, userID, status, statusMsg, extStatusMsg string,
 	) error
+
+	AddPresenceList(ctx context.Context, userID string, targets []string) error
+	RemovePresenceList(ctx context.Context, userID string, targets []string) error
+	GetPresenceList(ctx context.Context, userID string) ([]string, error)
+	GetAllPresenceList(ctx context.Context) (map[string][]string, error)
 }
@@ -90,6 +90,7 @@ type RoomServerDatabase interface {
 	) ([]*gomatrixserverlib.Event, []int64, error)
 	EventsCount(ctx context.Context) (count int, err error)
 	FixCorruptRooms()
+	PurgeRoomEvents(ctx context.Context, roomID string, eventIDs []string) (int64, error)
 	InsertEventJSON(ctx context.Context, eventNID int64, eventJSON []byte, eventType string) error
 	InsertEvent(ctx context.Context, eventNID int64,
 		roomNID int64, eventType string, eventStateKey string,
@@ -132,6 +133,18 @@ type RoomServerDatabase interface {
 	AliaseInsertRaw(ctx context.Context, aliase, roomID string) error
 	AliaseDeleteRaw(ctx context.Context, aliase string) error
 	GetAllRooms(ctx context.Context, limit, offset int) ([]roomservertypes.RoomNIDs, error)
+	GetRoomsJoinedCount(ctx context.Context, limit, offset int) ([]roomservertypes.RoomJoinedCount, int64, error)
+	InsertEventReport(ctx context.Context, report *roomservertypes.EventReport) error
+	GetEventReport(ctx context.Context, id int64) (*roomservertypes.EventReport, error)
+	GetEventReports(ctx context.Context, roomID, state string, limit, offset int64) ([]roomservertypes.EventReport, int64, error)
+	AssignEventReport(ctx context.Context, id int64, assignee string) (bool, error)
+	ResolveEventReport(ctx context.Context, id int64, resolvedBy string, resolvedTs int64, resolution string) (bool, error)
+	SetEventReportActions(ctx context.Context, id int64, redactionEventID string, kicked bool) error
+	SetRoomVersion(ctx context.Context, roomID, roomVersion string) error
+	GetRoomVersion(ctx context.Context, roomID string) (string, error)
+	BlockRoom(ctx context.Context, roomID, blockedBy string, blockedTs int64) error
+	IsRoomBlocked(ctx context.Context, roomID string) (bool, error)
+	SelectBlockedRooms(ctx context.Context) ([]string, error)
 	GetRoomEvents(ctx context.Context, roomNID int64) ([][]byte, error)
 	GetRoomEventsWithLimit(ctx context.Context, roomNID int64, limit, offset int) ([]int64, [][]byte, error)
 
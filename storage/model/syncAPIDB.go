@@ -200,10 +200,14 @@ type SyncAPIDatabase interface {
 	SelectTypeEventForward(
 		ctx context.Context, typ []string, roomID string,
 	) (events []gomatrixserverlib.ClientEvent, offsets []int64, err error)
+	SelectTypeEventsAfter(
+		ctx context.Context, typ []string, after int64, limit int,
+	) (events []gomatrixserverlib.ClientEvent, offsets []int64, err error)
 	UpdateSyncMemberEvent(
 		ctx context.Context, userID, oldAvatarUrl, newAvatarUrl string,
 	) error
 	GetAllSyncRooms() ([]string, error)
+	SelectCurrentStateByType(ctx context.Context, eventType string) ([]gomatrixserverlib.ClientEvent, error)
 	SelectUserTimeLineEvents(
 		ctx context.Context,
 		userID string,
@@ -275,4 +279,35 @@ type SyncAPIDatabase interface {
 	GetRoomStateTotal(ctx context.Context) (int, error)
 	UpdateRoomStateWithEventID(ctx context.Context, eventID string, eventBytes []byte) error
 	GetRoomStateByEventID(ctx context.Context, eventID string) ([]byte, error)
+
+	UpsertUserDirectory(ctx context.Context, entry *syncapitypes.UserDirectoryEntry) error
+	SearchUserDirectory(ctx context.Context, term string, userIDs []string, limit int) ([]syncapitypes.UserDirectoryEntry, error)
+	GetUserDirectoryTotal(ctx context.Context) (int, error)
+
+	InsertEventRelation(
+		ctx context.Context, eventID, roomID, relatesToID, relType, eventType, sender, key string, offset int64,
+	) error
+	DeleteEventRelation(ctx context.Context, eventID string) error
+	SelectEventRelations(
+		ctx context.Context, relatesToID, relType, eventType, dir string, from int64, limit int,
+	) ([]string, []int64, error)
+	SelectLatestEventRelation(ctx context.Context, relatesToID, relType, sender, excludeID string) (string, error)
+	SelectRoomThreads(
+		ctx context.Context, roomID, userID string, from int64, limit int,
+	) ([]string, []int64, []bool, error)
+
+	UpsertEventSearch(
+		ctx context.Context, eventID, roomID, sender, eventType, key string, ts, offset int64, lexemes []string,
+	) error
+	DeleteEventSearch(ctx context.Context, eventID string) error
+	SearchEvents(
+		ctx context.Context, query string, roomIDs, keys []string, orderBy string, limit, skip int,
+	) ([]syncapitypes.EventSearchResult, error)
+	CountSearchEvents(
+		ctx context.Context, query string, visibility map[string][]syncapitypes.VisibilityRange,
+		keys []string, filter *syncapitypes.EventSearchFilter,
+	) (int64, error)
+
+	SelectEventByTimestamp(ctx context.Context, roomID string, ts int64, dir string) (string, int64, error)
+	PurgeRoomEvents(ctx context.Context, roomID string, ts int64) ([]string, error)
 }
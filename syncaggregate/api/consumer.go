@@ -36,6 +36,7 @@ type InternalMsgConsumer struct {
 	keyChangeRepo    *repos.KeyChangeStreamRepo
 	stdEventTimeline *repos.STDEventStreamRepo
 	db               model.SyncAPIDatabase
+	publicRoomDB     model.PublicRoomAPIDatabase
 	cache            service.Cache
 }
 
@@ -48,6 +49,7 @@ func NewInternalMsgConsumer(
 	keyChangeRepo *repos.KeyChangeStreamRepo,
 	stdEventTimeline *repos.STDEventStreamRepo,
 	db model.SyncAPIDatabase,
+	publicRoomDB model.PublicRoomAPIDatabase,
 	cache service.Cache,
 ) *InternalMsgConsumer {
 	c := new(InternalMsgConsumer)
@@ -59,6 +61,7 @@ func NewInternalMsgConsumer(
 	c.keyChangeRepo = keyChangeRepo
 	c.stdEventTimeline = stdEventTimeline
 	c.db = db
+	c.publicRoomDB = publicRoomDB
 	c.cache = cache
 	return c
 }
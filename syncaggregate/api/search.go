@@ -0,0 +1,392 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/apiconsumer"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/fulltext"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/syncapitypes"
+	"github.com/finogeeks/ligase/model/types"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/plugins/message/internals"
+	"github.com/finogeeks/ligase/skunkworks/gomatrix"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/skunkworks/log"
+)
+
+const (
+	searchDefaultLimit   = 10
+	searchMaxLimit       = 100
+	searchDefaultContext = 5
+	searchMaxContext     = 50
+	// rows read from the index at a time, some are dropped by the filter
+	// and the history visibility
+	searchBatchSize = 100
+)
+
+var searchKeys = map[string]bool{
+	"content.body":  true,
+	"content.name":  true,
+	"content.topic": true,
+}
+
+func init() {
+	apiconsumer.SetAPIProcessor(ReqPostSearch{})
+}
+
+type ReqPostSearch struct{}
+
+func (ReqPostSearch) GetRoute() string       { return "/search" }
+func (ReqPostSearch) GetMetricsName() string { return "search" }
+func (ReqPostSearch) GetMsgType() int32      { return internals.MSG_POST_SEARCH }
+func (ReqPostSearch) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqPostSearch) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostSearch) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostSearch) GetPrefix() []string                  { return []string{"r0"} }
+func (ReqPostSearch) NewRequest() core.Coder {
+	return new(external.PostSearchRequest)
+}
+func (ReqPostSearch) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostSearchRequest)
+	if err := common.UnmarshalJSON(req, msg); err != nil {
+		return err
+	}
+	msg.NextBatch = req.URL.Query().Get("next_batch")
+	return nil
+}
+func (ReqPostSearch) NewResponse(code int) core.Coder {
+	return new(external.PostSearchResponse)
+}
+
+// Process searches the room_events category. The next_batch token is the
+// number of index rows read so far.
+func (ReqPostSearch) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	if !common.IsRelatedRequest(device.UserID, c.Cfg.MultiInstance.Instance, c.Cfg.MultiInstance.Total, c.Cfg.MultiInstance.MultiWrite) {
+		return internals.HTTP_RESP_DISCARD, jsonerror.MsgDiscard("msg discard")
+	}
+	req := msg.(*external.PostSearchRequest)
+	resp := &external.PostSearchResponse{}
+	criteria := req.SearchCategories.RoomEvents
+	if criteria == nil {
+		return http.StatusOK, resp
+	}
+
+	userID := device.UserID
+	query := fulltext.Query(criteria.SearchTerm, c.Cfg.Search.Tokenizer)
+	if query == "" {
+		return http.StatusBadRequest, jsonerror.BadJSON("search_term must contain a word")
+	}
+	keys := criteria.Keys
+	if len(keys) == 0 {
+		keys = []string{"content.body", "content.name", "content.topic"}
+	}
+	for _, key := range keys {
+		if !searchKeys[key] {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Unknown key " + key)
+		}
+	}
+	orderBy := criteria.OrderBy
+	if orderBy == "" {
+		orderBy = "rank"
+	}
+	if orderBy != "rank" && orderBy != "recent" {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("order_by must be rank or recent")
+	}
+	filter := criteria.Filter
+	if filter == nil {
+		filter = &gomatrix.FilterPart{}
+	}
+	limit := searchDefaultLimit
+	if filter.Limit != nil && *filter.Limit > 0 {
+		limit = *filter.Limit
+		if limit > searchMaxLimit {
+			limit = searchMaxLimit
+		}
+	}
+	skip := 0
+	if req.NextBatch != "" {
+		var err error
+		if skip, err = strconv.Atoi(req.NextBatch); err != nil || skip < 0 {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Invalid next_batch")
+		}
+	}
+
+	visibility, err := c.getSearchRooms(ctx, userID, filter)
+	if err != nil {
+		log.Errorf("search user:%s load rooms err:%v", userID, err)
+		return http.StatusInternalServerError, jsonerror.Unknown(err.Error())
+	}
+	roomIDs := make([]string, 0, len(visibility))
+	for roomID := range visibility {
+		roomIDs = append(roomIDs, roomID)
+	}
+
+	results := &external.RoomEventsResults{
+		Highlights: fulltext.Words(criteria.SearchTerm),
+		Results:    []external.SearchResult{},
+	}
+	resp.SearchCategories.RoomEvents = results
+	if len(roomIDs) == 0 {
+		return http.StatusOK, resp
+	}
+	// the count leaves out the events hidden by the history visibility and the
+	// senders and types of the filter, contains_url is only applied to results
+	countFilter := &syncapitypes.EventSearchFilter{
+		Senders:    filter.Senders,
+		NotSenders: filter.NotSenders,
+		Types:      filter.Types,
+		NotTypes:   filter.NotTypes,
+	}
+	if results.Count, err = c.db.CountSearchEvents(ctx, query, visibility, keys, countFilter); err != nil {
+		log.Errorf("search user:%s count err:%v", userID, err)
+		return http.StatusInternalServerError, jsonerror.Unknown(err.Error())
+	}
+
+	offset := skip
+	more := true
+	for more && len(results.Results) < limit {
+		rows, err := c.db.SearchEvents(ctx, query, roomIDs, keys, orderBy, searchBatchSize, offset)
+		if err != nil {
+			log.Errorf("search user:%s err:%v", userID, err)
+			return http.StatusInternalServerError, jsonerror.Unknown(err.Error())
+		}
+		more = len(rows) == searchBatchSize
+		events := c.loadSearchEvents(ctx, rows)
+		read := len(rows)
+		for i, row := range rows {
+			ev, ok := events[row.EventID]
+			if !ok || !matchSearchFilter(filter, &ev) || !syncapitypes.IsVisible(visibility[row.RoomID], row.OriginServerTS) {
+				continue
+			}
+			ev.Unsigned = types.RelationsForUser(ev.Unsigned, userID, ev.Sender)
+			results.Results = append(results.Results, external.SearchResult{Rank: row.Rank, Result: ev})
+			if len(results.Results) == limit {
+				read = i + 1
+				more = more || read < len(rows)
+				break
+			}
+		}
+		offset += read
+	}
+	if more {
+		results.NextBatch = strconv.Itoa(offset)
+	}
+
+	if criteria.EventContext != nil {
+		profiles := map[string]external.SearchUserProfile{}
+		for i := range results.Results {
+			results.Results[i].Context = c.getSearchContext(ctx, userID, &results.Results[i].Result, criteria.EventContext, visibility, profiles)
+		}
+	}
+	if criteria.Groupings != nil {
+		results.Groups = groupSearchResults(results.Results, criteria.Groupings, results.NextBatch)
+	}
+	return http.StatusOK, resp
+}
+
+// getSearchRooms returns the history visibility of userID in the rooms to
+// search, the joined rooms unless filter names the rooms.
+func (c *InternalMsgConsumer) getSearchRooms(
+	ctx context.Context, userID string, filter *gomatrix.FilterPart,
+) (map[string][]syncapitypes.VisibilityRange, error) {
+	roomIDs := filter.Rooms
+	if len(roomIDs) == 0 {
+		joined, err := c.userTimeLine.GetJoinRooms(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		joined.Range(func(key, _ interface{}) bool {
+			roomIDs = append(roomIDs, key.(string))
+			return true
+		})
+	}
+	notRooms := make(map[string]bool, len(filter.NotRooms))
+	for _, roomID := range filter.NotRooms {
+		notRooms[roomID] = true
+	}
+	rooms := make([]string, 0, len(roomIDs))
+	for _, roomID := range roomIDs {
+		if !notRooms[roomID] {
+			rooms = append(rooms, roomID)
+		}
+	}
+	if len(rooms) == 0 {
+		return map[string][]syncapitypes.VisibilityRange{}, nil
+	}
+	return c.sm.GetVisibilityRanges(ctx, userID, rooms)
+}
+
+func (c *InternalMsgConsumer) loadSearchEvents(
+	ctx context.Context, rows []syncapitypes.EventSearchResult,
+) map[string]gomatrixserverlib.ClientEvent {
+	events := make(map[string]gomatrixserverlib.ClientEvent, len(rows))
+	if len(rows) == 0 {
+		return events
+	}
+	eventIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		eventIDs = append(eventIDs, row.EventID)
+	}
+	evs, err := c.db.Events(ctx, eventIDs)
+	if err != nil {
+		log.Errorf("search load events err:%v", err)
+		return events
+	}
+	for _, ev := range evs {
+		events[ev.EventID] = ev
+	}
+	return events
+}
+
+func matchSearchFilter(filter *gomatrix.FilterPart, ev *gomatrixserverlib.ClientEvent) bool {
+	contains := func(list []string, s string) bool {
+		for _, item := range list {
+			if item == s {
+				return true
+			}
+		}
+		return false
+	}
+	if contains(filter.NotSenders, ev.Sender) || contains(filter.NotTypes, ev.Type) {
+		return false
+	}
+	if len(filter.Senders) > 0 && !contains(filter.Senders, ev.Sender) {
+		return false
+	}
+	if len(filter.Types) > 0 && !contains(filter.Types, ev.Type) {
+		return false
+	}
+	if filter.ContainsURL {
+		content := struct {
+			URL string `json:"url"`
+		}{}
+		if json.Unmarshal(ev.Content, &content) != nil || content.URL == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// getSearchContext returns the events around ev visible to userID. The
+// profiles are the current ones, cached in profiles across the results.
+func (c *InternalMsgConsumer) getSearchContext(
+	ctx context.Context, userID string, ev *gomatrixserverlib.ClientEvent, eventContext *external.SearchEventContext,
+	visibility map[string][]syncapitypes.VisibilityRange, profiles map[string]external.SearchUserProfile,
+) *external.SearchResultContext {
+	contextLimit := func(limit *int) int {
+		if limit == nil {
+			return searchDefaultContext
+		}
+		if *limit < 0 {
+			return 0
+		}
+		if *limit > searchMaxContext {
+			return searchMaxContext
+		}
+		return *limit
+	}
+	result := &external.SearchResultContext{
+		EventsBefore: []gomatrixserverlib.ClientEvent{},
+		EventsAfter:  []gomatrixserverlib.ClientEvent{},
+	}
+	ts := int64(ev.OriginServerTS)
+	ranges := visibility[ev.RoomID]
+	load := func(dir string, limit int) ([]gomatrixserverlib.ClientEvent, string) {
+		events := []gomatrixserverlib.ClientEvent{}
+		token := ""
+		if limit == 0 {
+			return events, token
+		}
+		// the event itself is among the ones sharing its origin_server_ts
+		evs, offsets, tss, err, _, _ := c.db.SelectEventsByDir(ctx, userID, ev.RoomID, dir, ts, limit+1)
+		if err != nil {
+			log.Errorf("search load context of ev:%s err:%v", ev.EventID, err)
+			return events, token
+		}
+		for i := range evs {
+			if evs[i].EventID == ev.EventID || len(events) == limit {
+				continue
+			}
+			token = common.BuildPreBatch(offsets[i], tss[i])
+			if (syncapitypes.IsVisible(ranges, tss[i]) && !common.IsExtEvent(&evs[i])) || common.IsStateClientEv(&evs[i]) {
+				evs[i].Unsigned = types.RelationsForUser(evs[i].Unsigned, userID, evs[i].Sender)
+				events = append(events, evs[i])
+			}
+		}
+		return events, token
+	}
+	result.EventsBefore, result.Start = load("b", contextLimit(eventContext.BeforeLimit))
+	result.EventsAfter, result.End = load("f", contextLimit(eventContext.AfterLimit))
+
+	if eventContext.IncludeProfile {
+		result.ProfileInfo = map[string]external.SearchUserProfile{}
+		senders := []string{ev.Sender}
+		for _, e := range result.EventsBefore {
+			senders = append(senders, e.Sender)
+		}
+		for _, e := range result.EventsAfter {
+			senders = append(senders, e.Sender)
+		}
+		for _, sender := range senders {
+			profile, ok := profiles[sender]
+			if !ok {
+				profile.DisplayName, profile.AvatarURL, _ = c.cache.GetProfileLessByUserID(sender)
+				profiles[sender] = profile
+			}
+			result.ProfileInfo[sender] = profile
+		}
+	}
+	return result
+}
+
+// groupSearchResults groups the results by room_id or sender, ordered by the
+// position of their first result.
+func groupSearchResults(
+	results []external.SearchResult, groupings *external.SearchGroupings, nextBatch string,
+) map[string]map[string]external.SearchGroupValue {
+	groups := map[string]map[string]external.SearchGroupValue{}
+	for _, groupBy := range groupings.GroupBy {
+		if groupBy.Key != "room_id" && groupBy.Key != "sender" {
+			continue
+		}
+		group := map[string]external.SearchGroupValue{}
+		for _, r := range results {
+			value := r.Result.RoomID
+			if groupBy.Key == "sender" {
+				value = r.Result.Sender
+			}
+			g, ok := group[value]
+			if !ok {
+				g = external.SearchGroupValue{Order: len(group) + 1, NextBatch: nextBatch}
+			}
+			g.Results = append(g.Results, r.Result.EventID)
+			group[value] = g
+		}
+		groups[groupBy.Key] = group
+	}
+	return groups
+}
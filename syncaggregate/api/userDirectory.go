@@ -0,0 +1,148 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/apiconsumer"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/plugins/message/internals"
+	"github.com/finogeeks/ligase/skunkworks/log"
+)
+
+const (
+	userDirectoryDefaultLimit = 10
+	userDirectoryMaxLimit     = 100
+)
+
+func init() {
+	apiconsumer.SetAPIProcessor(ReqPostUserDirectorySearch{})
+}
+
+type ReqPostUserDirectorySearch struct{}
+
+func (ReqPostUserDirectorySearch) GetRoute() string       { return "/user_directory/search" }
+func (ReqPostUserDirectorySearch) GetMetricsName() string { return "user_directory_search" }
+func (ReqPostUserDirectorySearch) GetMsgType() int32      { return internals.MSG_POST_USER_DIRECTORY_SEARCH }
+func (ReqPostUserDirectorySearch) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqPostUserDirectorySearch) GetMethod() []string {
+	return []string{http.MethodPost, http.MethodOptions}
+}
+func (ReqPostUserDirectorySearch) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqPostUserDirectorySearch) GetPrefix() []string                  { return []string{"r0"} }
+func (ReqPostUserDirectorySearch) NewRequest() core.Coder {
+	return new(external.PostUserSearchRequest)
+}
+func (ReqPostUserDirectorySearch) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.PostUserSearchRequest)
+	return common.UnmarshalJSON(req, msg)
+}
+func (ReqPostUserDirectorySearch) NewResponse(code int) core.Coder {
+	return new(external.PostUserSearchResponse)
+}
+func (ReqPostUserDirectorySearch) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	if !common.IsRelatedRequest(device.UserID, c.Cfg.MultiInstance.Instance, c.Cfg.MultiInstance.Total, c.Cfg.MultiInstance.MultiWrite) {
+		return internals.HTTP_RESP_DISCARD, jsonerror.MsgDiscard("msg discard")
+	}
+	req := msg.(*external.PostUserSearchRequest)
+
+	term := strings.TrimSpace(req.SearchTerm)
+	if term == "" {
+		return http.StatusBadRequest, jsonerror.BadJSON("search_term must not be empty")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = userDirectoryDefaultLimit
+	}
+	if limit > userDirectoryMaxLimit {
+		limit = userDirectoryMaxLimit
+	}
+
+	// nil searches the whole directory
+	var userIDs []string
+	if !c.Cfg.UserDirectory.SearchAllUsers {
+		var err error
+		if userIDs, err = c.getVisibleUsers(ctx, device.UserID); err != nil {
+			log.Errorf("user directory search user:%s load visible users err:%v", device.UserID, err)
+			return http.StatusInternalServerError, jsonerror.Unknown(err.Error())
+		}
+	}
+
+	entries, err := c.db.SearchUserDirectory(ctx, term, userIDs, limit+1)
+	if err != nil {
+		log.Errorf("user directory search user:%s err:%v", device.UserID, err)
+		return http.StatusInternalServerError, jsonerror.Unknown(err.Error())
+	}
+
+	resp := &external.PostUserSearchResponse{Results: []external.User{}}
+	if len(entries) > limit {
+		resp.Limited = true
+		entries = entries[:limit]
+	}
+	for _, e := range entries {
+		resp.Results = append(resp.Results, external.User{
+			UserID:      e.UserID,
+			DisplayName: e.DisplayName,
+			AvatarURL:   e.AvatarURL,
+		})
+	}
+	return http.StatusOK, resp
+}
+
+// getVisibleUsers returns the users sharing a room with userID and the users
+// joined to the rooms published in the public room directory
+func (c *InternalMsgConsumer) getVisibleUsers(ctx context.Context, userID string) ([]string, error) {
+	visible := map[string]bool{userID: true}
+	friends := c.userTimeLine.GetFriendShip(ctx, userID, true)
+	if friends != nil {
+		friends.Range(func(key, _ interface{}) bool {
+			visible[key.(string)] = true
+			return true
+		})
+	}
+
+	rooms, err := c.publicRoomDB.GetPublicRooms(ctx, 0, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(rooms) > 0 {
+		roomIDs := make([]string, 0, len(rooms))
+		for _, room := range rooms {
+			roomIDs = append(roomIDs, room.RoomID)
+		}
+		members, err := c.db.GetFriendShip(ctx, roomIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			visible[member] = true
+		}
+	}
+
+	userIDs := make([]string, 0, len(visible))
+	for id := range visible {
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/finogeeks/ligase/core"
 	"github.com/finogeeks/ligase/model/repos"
 	"github.com/finogeeks/ligase/model/service"
+	"github.com/finogeeks/ligase/model/syncapitypes"
 	"github.com/finogeeks/ligase/model/types"
 	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
 	"github.com/finogeeks/ligase/storage/model"
@@ -125,6 +126,10 @@ func (s *ProfileConsumer) onMessage(ctx context.Context, output *types.ProfileSt
 	}
 
 	log.Infof("Profile update, user:%s device:%s presence:%s %t", output.UserID, output.DeviceID, output.Presence.Presence, output.IsUpdateStauts)
+	// Presence changes of local users don't touch their profile
+	if isRelate && (!isSelfDomain || (output.IsMasterHndle && !output.IsUpdateStauts)) {
+		s.updateUserDirectory(ctx, output)
+	}
 	if isSelfDomain && isRelate && output.IsUpdateStauts {
 		if output.Presence.Presence == "offline" {
 			log.Infof("Profile offline user:%s device:%s", output.UserID, output.DeviceID)
@@ -285,3 +290,17 @@ func (s *ProfileConsumer) onMessage(ctx context.Context, output *types.ProfileSt
 		}
 	}
 }
+
+func (s *ProfileConsumer) updateUserDirectory(ctx context.Context, output *types.ProfileStreamUpdate) {
+	entry := &syncapitypes.UserDirectoryEntry{
+		UserID:      output.UserID,
+		DisplayName: output.Presence.DisplayName,
+		AvatarURL:   output.Presence.AvatarURL,
+		UserName:    output.Presence.UserName,
+		JobNumber:   output.Presence.JobNumber,
+		Email:       output.Presence.Email,
+	}
+	if err := s.syncDB.UpsertUserDirectory(ctx, entry); err != nil {
+		log.Errorf("Profile update user:%s user directory err:%v", output.UserID, err)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package syncaggregate
+
+import (
+	"context"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/model/repos"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// loadPresenceList restores the presence lists of the users served by this
+// instance, the presence list rpc consumer keeps them up to date afterwards.
+func loadPresenceList(presenceDB model.PresenceDatabase, presenceRepo *repos.PresenceDataStreamRepo, cfg *config.Dendrite) {
+	lists, err := presenceDB.GetAllPresenceList(context.Background())
+	if err != nil {
+		log.Panicf("load presence list err:%v", err)
+	}
+	for userID, targets := range lists {
+		if common.IsRelatedRequest(userID, cfg.MultiInstance.Instance, cfg.MultiInstance.Total, cfg.MultiInstance.MultiWrite) {
+			presenceRepo.AddPresenceList(userID, targets)
+		}
+	}
+	log.Infof("loadPresenceList restored %d presence lists", len(lists))
+}
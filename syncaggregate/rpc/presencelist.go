@@ -0,0 +1,100 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/uid"
+	"github.com/finogeeks/ligase/model/repos"
+	"github.com/finogeeks/ligase/model/types"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/nats-io/go-nats"
+)
+
+type PresenceListRpcConsumer struct {
+	rpcClient    *common.RpcClient
+	presenceRepo *repos.PresenceDataStreamRepo
+	idg          *uid.UidGenerator
+	chanSize     uint32
+	msgChan      []chan common.ContextMsg
+	cfg          *config.Dendrite
+}
+
+func NewPresenceListRpcConsumer(
+	presenceRepo *repos.PresenceDataStreamRepo,
+	rpcClient *common.RpcClient,
+	idg *uid.UidGenerator,
+	cfg *config.Dendrite,
+) *PresenceListRpcConsumer {
+	s := &PresenceListRpcConsumer{
+		presenceRepo: presenceRepo,
+		idg:          idg,
+		rpcClient:    rpcClient,
+		chanSize:     4,
+		cfg:          cfg,
+	}
+	return s
+}
+
+func (s *PresenceListRpcConsumer) GetTopic() string {
+	return types.PresenceListUpdateTopicDef
+}
+
+func (s *PresenceListRpcConsumer) cb(ctx context.Context, msg *nats.Msg) {
+	var result types.PresenceListUpdate
+	if err := json.Unmarshal(msg.Data, &result); err != nil {
+		log.Errorf("rpc presence list update cb error %v", err)
+		return
+	}
+	if common.IsRelatedRequest(result.UserID, s.cfg.MultiInstance.Instance, s.cfg.MultiInstance.Total, s.cfg.MultiInstance.MultiWrite) {
+		idx := common.CalcStringHashCode(result.UserID) % s.chanSize
+		s.msgChan[idx] <- common.ContextMsg{Ctx: ctx, Msg: &result}
+	}
+}
+
+func (s *PresenceListRpcConsumer) startWorker(msgChan chan common.ContextMsg) {
+	for msg := range msgChan {
+		data := msg.Msg.(*types.PresenceListUpdate)
+		s.processPresenceListUpdate(msg.Ctx, data)
+	}
+}
+
+func (s *PresenceListRpcConsumer) Start() error {
+	s.msgChan = make([]chan common.ContextMsg, s.chanSize)
+	for i := uint32(0); i < s.chanSize; i++ {
+		s.msgChan[i] = make(chan common.ContextMsg, 512)
+		go s.startWorker(s.msgChan[i])
+	}
+
+	s.rpcClient.ReplyWithContext(s.GetTopic(), s.cb)
+	return nil
+}
+
+func (s *PresenceListRpcConsumer) processPresenceListUpdate(ctx context.Context, data *types.PresenceListUpdate) {
+	log.Infof("presence list of user:%s invite:%v drop:%v", data.UserID, data.Invite, data.Drop)
+	s.presenceRepo.RemovePresenceList(data.UserID, data.Drop)
+	if len(data.Invite) > 0 {
+		s.presenceRepo.AddPresenceList(data.UserID, data.Invite)
+		pos, err := s.idg.Next()
+		if err != nil {
+			log.Errorf("presence list of user:%s gen position err:%v", data.UserID, err)
+			return
+		}
+		s.presenceRepo.RefreshPresenceList(ctx, data.UserID, data.Invite, pos)
+	}
+}
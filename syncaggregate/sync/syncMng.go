@@ -866,29 +866,33 @@ func (sm *SyncMng) addPresence(ctx context.Context, req *request, response *sync
 	maxPos := int64(-1)
 	if sm.presenceStreamRepo.ExistsPresence(req.device.UserID, req.marks.preRecv) {
 		log.Infof("add presence for %s", req.device.UserID)
-		friendShipMap := sm.userTimeLine.GetFriendShip(ctx, req.device.UserID, true)
-		if friendShipMap != nil {
-			var presenceEvent gomatrixserverlib.ClientEvent
-			friendShipMap.Range(func(key, _ interface{}) bool {
-				feed := sm.presenceStreamRepo.GetHistoryByUserID(key.(string))
-				if feed != nil && feed.GetOffset() > req.marks.preRecv {
-					err := json.Unmarshal(feed.DataStream.Content, &presenceEvent)
-					if err != nil {
-						log.Errorf("addReceipt: Unmarshal json error for presence traceid:%s userID:%s dev:%s  err:%v", req.traceId, key.(string), req.device.ID, err)
-						return true
-					}
+		var presenceEvent gomatrixserverlib.ClientEvent
+		sm.presenceStreamRepo.RangePresenceUsers(ctx, req.device.UserID, func(user string) bool {
+			feed := sm.presenceStreamRepo.GetHistoryByUserID(user)
+			if feed == nil {
+				return true
+			}
+			offset := feed.GetOffset()
+			if listPos := sm.presenceStreamRepo.GetPresenceListPos(req.device.UserID, user); listPos > offset {
+				offset = listPos
+			}
+			if offset > req.marks.preRecv {
+				err := json.Unmarshal(feed.DataStream.Content, &presenceEvent)
+				if err != nil {
+					log.Errorf("addReceipt: Unmarshal json error for presence traceid:%s userID:%s dev:%s  err:%v", req.traceId, user, req.device.ID, err)
+					return true
+				}
 
-					response.Presence.Events = append(response.Presence.Events, presenceEvent)
-					data, _ := json.Marshal(presenceEvent)
-					log.Infof("add presence for %s %d %d %s", req.device.UserID, feed.GetOffset(), req.marks.preRecv, data)
+				response.Presence.Events = append(response.Presence.Events, presenceEvent)
+				data, _ := json.Marshal(presenceEvent)
+				log.Infof("add presence for %s %d %d %s", req.device.UserID, offset, req.marks.preRecv, data)
 
-					if maxPos < feed.GetOffset() {
-						maxPos = feed.GetOffset()
-					}
+				if maxPos < offset {
+					maxPos = offset
 				}
-				return true
-			})
-		}
+			}
+			return true
+		})
 	}
 
 	if maxPos == -1 {
@@ -0,0 +1,98 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/model/syncapitypes"
+	"github.com/finogeeks/ligase/model/types"
+	"github.com/finogeeks/ligase/skunkworks/log"
+)
+
+// GetVisibilityRanges asks the sync servers owning the rooms for the history
+// visibility ranges of userID. Rooms userID has never been a member of are
+// missing from the result.
+func (sm *SyncMng) GetVisibilityRanges(
+	ctx context.Context, userID string, roomIDs []string,
+) (map[string][]syncapitypes.VisibilityRange, error) {
+	requestMap := make(map[uint32]*syncapitypes.SyncServerRequest)
+	for _, roomID := range roomIDs {
+		instance := common.GetSyncInstance(roomID, sm.cfg.MultiInstance.SyncServerTotal)
+		request, ok := requestMap[instance]
+		if !ok {
+			request = &syncapitypes.SyncServerRequest{
+				RequestType:  "visibility",
+				UserID:       userID,
+				SyncInstance: instance,
+			}
+			requestMap[instance] = request
+		}
+		request.JoinedRooms = append(request.JoinedRooms, roomID)
+	}
+
+	var mutex sync.Mutex
+	var lastErr error
+	ranges := make(map[string][]syncapitypes.VisibilityRange, len(roomIDs))
+	var wg sync.WaitGroup
+	for _, request := range requestMap {
+		wg.Add(1)
+		go func(request *syncapitypes.SyncServerRequest) {
+			defer wg.Done()
+			visibility, err := sm.callSyncVisibility(request)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				log.Errorf("SyncMng.GetVisibilityRanges user:%s instance:%d error %v", userID, request.SyncInstance, err)
+				lastErr = err
+				return
+			}
+			for roomID, r := range visibility {
+				ranges[roomID] = r
+			}
+		}(request)
+	}
+	wg.Wait()
+	return ranges, lastErr
+}
+
+func (sm *SyncMng) callSyncVisibility(request *syncapitypes.SyncServerRequest) (map[string][]syncapitypes.VisibilityRange, error) {
+	bytes, err := json.Marshal(*request)
+	if err != nil {
+		return nil, err
+	}
+	data, err := sm.rpcClient.Request(types.SyncServerTopicDef, bytes, 35000)
+	if err != nil {
+		return nil, err
+	}
+	var result types.CompressContent
+	if err = json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result.Compressed {
+		result.Content = common.DoUnCompress(result.Content)
+	}
+	var response syncapitypes.SyncServerResponse
+	if err = json.Unmarshal(result.Content, &response); err != nil {
+		return nil, err
+	}
+	if !response.AllLoaded {
+		return nil, fmt.Errorf("sync server instance %d not ready", request.SyncInstance)
+	}
+	return response.Visibility, nil
+}
@@ -66,6 +66,7 @@ func SetupSyncAggregateComponent(
 	presenceStreamRepo.SetCfg(base.Cfg)
 	presenceStreamRepo.SetOnlineRepo(onlineRepo)
 	presenceStreamRepo.LoadOnlinePresence()
+	loadPresenceList(base.CreatePresenceDB(), presenceStreamRepo, base.Cfg)
 
 	kcRepo := repos.NewKeyChangeStreamRepo(userTimeLine)
 	kcRepo.SetSyncDB(syncDB)
@@ -156,6 +157,16 @@ func SetupSyncAggregateComponent(
 		log.Panicf("failed to start sync presence rpc consumer err:%v", err)
 	}
 
+	presenceListRpcConsumer := rpc.NewPresenceListRpcConsumer(presenceStreamRepo, rpcClient, idg, base.Cfg)
+	if err := presenceListRpcConsumer.Start(); err != nil {
+		log.Panicf("failed to start sync presence list rpc consumer err:%v", err)
+	}
+
+	purgeRpcConsumer := rpc.NewRoomHistoryPurgeRpcConsumer(userTimeLine, rpcClient, base.Cfg)
+	if err := purgeRpcConsumer.Start(); err != nil {
+		log.Panicf("failed to start sync room history purge rpc consumer err:%v", err)
+	}
+
 	syncMng := sync.NewSyncMng(syncDB, syncMngChanNum, 1024, base.Cfg, rpcClient)
 	syncMng.SetCache(cacheIn)
 	syncMng.SetComplexCache(complexCache)
@@ -174,6 +185,11 @@ func SetupSyncAggregateComponent(
 		log.Panicf("failed to start sync rpc consumer err:%v", err)
 	}
 
-	apiConsumer := api.NewInternalMsgConsumer(*base.Cfg, rpcClient, idg, syncMng, userTimeLine, kcRepo, stdEventStreamRepo, syncDB, cacheIn)
+	if base.Cfg.MultiInstance.Instance == 0 {
+		go fillUserDirectory(syncDB, base.CreateAccountsDB())
+	}
+
+	publicRoomDB := base.CreatePublicRoomApiDB()
+	apiConsumer := api.NewInternalMsgConsumer(*base.Cfg, rpcClient, idg, syncMng, userTimeLine, kcRepo, stdEventStreamRepo, syncDB, publicRoomDB, cacheIn)
 	apiConsumer.Start()
 }
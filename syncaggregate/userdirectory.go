@@ -0,0 +1,74 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package syncaggregate
+
+import (
+	"context"
+
+	"github.com/finogeeks/ligase/model/syncapitypes"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+// fillUserDirectory indexes the profiles stored before the user directory
+// existed, the profile stream keeps it up to date afterwards.
+func fillUserDirectory(syncDB model.SyncAPIDatabase, accountDB model.AccountsDatabase) {
+	ctx := context.Background()
+	total, err := syncDB.GetUserDirectoryTotal(ctx)
+	if err != nil {
+		log.Errorf("fillUserDirectory count err:%v", err)
+		return
+	}
+	if total > 0 {
+		return
+	}
+
+	profiles, err := accountDB.GetAllProfile()
+	if err != nil {
+		log.Errorf("fillUserDirectory load profiles err:%v", err)
+		return
+	}
+	userInfos, err := accountDB.GetAllUserInfo()
+	if err != nil {
+		log.Errorf("fillUserDirectory load user info err:%v", err)
+		return
+	}
+
+	entries := make(map[string]*syncapitypes.UserDirectoryEntry, len(profiles))
+	for _, p := range profiles {
+		entries[p.UserID] = &syncapitypes.UserDirectoryEntry{
+			UserID:      p.UserID,
+			DisplayName: p.DisplayName,
+			AvatarURL:   p.AvatarURL,
+		}
+	}
+	for _, info := range userInfos {
+		entry, ok := entries[info.UserID]
+		if !ok {
+			entry = &syncapitypes.UserDirectoryEntry{UserID: info.UserID}
+			entries[info.UserID] = entry
+		}
+		entry.UserName = info.UserName
+		entry.JobNumber = info.JobNumber
+		entry.Email = info.Email
+	}
+
+	for _, entry := range entries {
+		if err := syncDB.UpsertUserDirectory(ctx, entry); err != nil {
+			log.Errorf("fillUserDirectory user:%s err:%v", entry.UserID, err)
+		}
+	}
+	log.Infof("fillUserDirectory indexed %d users", len(entries))
+}
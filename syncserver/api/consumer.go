@@ -19,6 +19,7 @@ import (
 	"github.com/finogeeks/ligase/common/apiconsumer"
 	"github.com/finogeeks/ligase/common/config"
 	"github.com/finogeeks/ligase/common/uid"
+	fed "github.com/finogeeks/ligase/federation/fedreq"
 	"github.com/finogeeks/ligase/model/repos"
 	"github.com/finogeeks/ligase/model/service"
 	"github.com/finogeeks/ligase/storage/model"
@@ -39,6 +40,7 @@ type InternalMsgConsumer struct {
 	receiptConsumer *consumers.ReceiptConsumer
 	settings        *common.Settings
 	cache           service.Cache
+	federation      *fed.Federation
 }
 
 func NewInternalMsgConsumer(
@@ -66,6 +68,7 @@ func NewInternalMsgConsumer(
 	c.receiptConsumer = receiptConsumer
 	c.settings = settings
 	c.cache = cache
+	c.federation = fed.NewFederation(&c.Cfg, rpcCli)
 	return c
 }
 
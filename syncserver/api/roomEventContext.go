@@ -119,7 +119,7 @@ func (ReqGetEventContext) Process(ctx context.Context, consumer interface{}, msg
 	fromTs := int64(baseEvent[0].OriginServerTS)
 	fromPos := offsets[0]
 
-	visibilityTime := c.settings.GetMessageVisilibityTime()
+	visibilityTime := common.MessageVisibilityTime(c.settings, &c.Cfg, rs.GetRetention())
 	nowTs := time.Now().Unix()
 	if visibilityTime > 0 {
 		ts := int64(baseEvent[0].OriginServerTS) / 1000
@@ -192,7 +192,7 @@ func (source GetMessagesSource) getMessages(
 		log.Debugf("get context [%s dir] from cache, but out of range, get from db, endPos: %d", dir, endPos)
 	} else { // use cache
 		cacheLoaded := true
-		visibilityTime := source.c.settings.GetMessageVisilibityTime()
+		visibilityTime := common.MessageVisibilityTime(source.c.settings, &source.c.Cfg, source.rs.GetRetention())
 		nowTs := time.Now().Unix()
 		if dir == "b" {
 			source.tl.RAtomic(func(data *feedstypes.TimeLinesAtomicData) {
@@ -348,7 +348,7 @@ func (source GetMessagesSource) getFromDB(
 		return outputRoomEvents, fromPos, fromTs, nil
 	}
 
-	visibilityTime := source.c.settings.GetMessageVisilibityTime()
+	visibilityTime := common.MessageVisibilityTime(source.c.settings, &source.c.Cfg, source.rs.GetRetention())
 	nowTs := time.Now().Unix()
 	for i := range events {
 		if (dir == "b" && ((fromPos >= 0 && offsets[i] <= fromPos) || (fromPos < 0 && (offsets[i] > 0 || offsets[i] <= fromPos)))) || (dir == "f" && offsets[i] >= fromPos) {
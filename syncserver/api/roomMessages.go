@@ -202,7 +202,7 @@ func (r ReqGetRoomMessages) Process(ctx context.Context, consumer interface{}, m
 			loadFromDB     = false
 			dbFromPos      = fromPos
 			dbFromTs       = fromTs
-			visibilityTime = c.settings.GetMessageVisilibityTime()
+			visibilityTime = common.MessageVisibilityTime(c.settings, &c.Cfg, rs.GetRetention())
 			nowTs          = time.Now().Unix()
 
 			foundAll    = false
@@ -363,7 +363,7 @@ func (r ReqGetRoomMessages) selectFromDB(
 		return
 	}
 
-	visibilityTime := c.settings.GetMessageVisilibityTime()
+	visibilityTime := common.MessageVisibilityTime(c.settings, &c.Cfg, rs.GetRetention())
 	nowTs := time.Now().Unix()
 
 	for idx := range events {
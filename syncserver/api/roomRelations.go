@@ -0,0 +1,225 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/apiconsumer"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/syncapitypes"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/plugins/message/internals"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/syncserver/extra"
+)
+
+const (
+	relationsDefaultLimit = 50
+	relationsMaxLimit     = 100
+)
+
+func init() {
+	apiconsumer.SetAPIProcessor(ReqGetRoomRelations{})
+	apiconsumer.SetAPIProcessor(ReqGetRoomRelationsByRelType{})
+	apiconsumer.SetAPIProcessor(ReqGetRoomRelationsByEventType{})
+}
+
+type ReqGetRoomRelations struct{}
+
+func (ReqGetRoomRelations) GetRoute() string       { return "/rooms/{roomID}/relations/{eventID}" }
+func (ReqGetRoomRelations) GetMetricsName() string { return "room_relations" }
+func (ReqGetRoomRelations) GetMsgType() int32      { return internals.MSG_GET_ROOM_RELATIONS }
+func (ReqGetRoomRelations) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqGetRoomRelations) GetMethod() []string {
+	return []string{http.MethodGet, http.MethodOptions}
+}
+func (ReqGetRoomRelations) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqGetRoomRelations) GetPrefix() []string                  { return []string{"clientV1"} }
+func (ReqGetRoomRelations) NewRequest() core.Coder {
+	return new(external.GetRoomRelationsRequest)
+}
+func (ReqGetRoomRelations) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetRoomRelationsRequest)
+	if vars != nil {
+		msg.RoomID = vars["roomID"]
+		msg.EventID = vars["eventID"]
+		msg.RelType = vars["relType"]
+		msg.EventType = vars["eventType"]
+	}
+	req.ParseForm()
+	values := req.URL.Query()
+	msg.From = values.Get("from")
+	msg.To = values.Get("to")
+	msg.Limit = values.Get("limit")
+	msg.Dir = values.Get("dir")
+	return nil
+}
+func (ReqGetRoomRelations) NewResponse(code int) core.Coder {
+	return new(syncapitypes.RelationsResp)
+}
+func (ReqGetRoomRelations) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetRoomRelationsRequest)
+	if !common.IsRelatedRequest(req.RoomID, c.Cfg.MultiInstance.Instance, c.Cfg.MultiInstance.Total, c.Cfg.MultiInstance.MultiWrite) {
+		return internals.HTTP_RESP_DISCARD, jsonerror.MsgDiscard("msg discard")
+	}
+	return getRoomRelations(ctx, c, req, device.UserID)
+}
+
+type ReqGetRoomRelationsByRelType struct{ ReqGetRoomRelations }
+
+func (ReqGetRoomRelationsByRelType) GetRoute() string {
+	return "/rooms/{roomID}/relations/{eventID}/{relType}"
+}
+func (ReqGetRoomRelationsByRelType) GetMsgType() int32 {
+	return internals.MSG_GET_ROOM_RELATIONS_BY_REL_TYPE
+}
+
+type ReqGetRoomRelationsByEventType struct{ ReqGetRoomRelations }
+
+func (ReqGetRoomRelationsByEventType) GetRoute() string {
+	return "/rooms/{roomID}/relations/{eventID}/{relType}/{eventType}"
+}
+func (ReqGetRoomRelationsByEventType) GetMsgType() int32 {
+	return internals.MSG_GET_ROOM_RELATIONS_BY_EVENT_TYPE
+}
+
+// getRoomRelations pages through the events relating to req.EventID, the
+// batch tokens are stream positions of the relation events.
+func getRoomRelations(
+	ctx context.Context, c *InternalMsgConsumer, req *external.GetRoomRelationsRequest, userID string,
+) (int, core.Coder) {
+	roomID := req.RoomID
+	c.rsTimeline.LoadStreamStates(ctx, roomID, true)
+	rs := c.rsCurState.GetRoomState(roomID)
+	if rs == nil {
+		return http.StatusNotFound, jsonerror.NotFound("cannot find room state")
+	}
+	_, isJoin := rs.GetJoinMap().Load(userID)
+	_, isLeave := rs.GetLeaveMap().Load(userID)
+	if isJoin == false && isLeave == false {
+		return http.StatusForbidden, jsonerror.Forbidden("You aren't a member of the room and weren't previously a member of the room or just forget the room")
+	}
+
+	parents, _, err := c.db.StreamEvents(ctx, []string{req.EventID})
+	if err != nil || len(parents) == 0 || parents[0].RoomID != roomID ||
+		!rs.CheckEventVisibility(userID, int64(parents[0].OriginServerTS)) {
+		return http.StatusNotFound, jsonerror.NotFound("cannot find event")
+	}
+
+	limit := relationsDefaultLimit
+	if req.Limit != "" {
+		l, err := strconv.Atoi(req.Limit)
+		if err != nil || l <= 0 {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("limit must be a positive integer")
+		}
+		if l < relationsMaxLimit {
+			limit = l
+		} else {
+			limit = relationsMaxLimit
+		}
+	}
+	dir := req.Dir
+	if dir == "" {
+		dir = "b"
+	}
+	if dir != "b" && dir != "f" {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("dir must be b or f")
+	}
+	var from int64 = math.MaxInt64
+	if dir == "f" {
+		from = math.MinInt64
+	}
+	if req.From != "" {
+		if from, err = strconv.ParseInt(req.From, 10, 64); err != nil {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Invalid from token")
+		}
+	}
+	var to int64
+	hasTo := req.To != ""
+	if hasTo {
+		if to, err = strconv.ParseInt(req.To, 10, 64); err != nil {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Invalid to token")
+		}
+	}
+
+	eventIDs, offsets, err := c.db.SelectEventRelations(ctx, req.EventID, req.RelType, req.EventType, dir, from, limit)
+	if err != nil {
+		log.Errorf("get relations of room:%s event:%s err:%v", roomID, req.EventID, err)
+		return http.StatusInternalServerError, jsonerror.Unknown(err.Error())
+	}
+	events := loadRoomEvents(ctx, c, roomID, eventIDs)
+
+	resp := &syncapitypes.RelationsResp{
+		Chunk:     []gomatrixserverlib.ClientEvent{},
+		PrevBatch: req.From,
+	}
+	reachedTo := false
+	for i, eventID := range eventIDs {
+		if hasTo && ((dir == "b" && offsets[i] <= to) || (dir == "f" && offsets[i] >= to)) {
+			reachedTo = true
+			break
+		}
+		ev, ok := events[eventID]
+		if !ok || !rs.CheckEventVisibility(userID, int64(ev.OriginServerTS)) {
+			continue
+		}
+		extra.ExpandMessages(&ev, userID, c.rsCurState, c.displayNameRepo)
+		resp.Chunk = append(resp.Chunk, ev)
+	}
+	if !reachedTo && len(eventIDs) == limit {
+		resp.NextBatch = strconv.FormatInt(offsets[len(offsets)-1], 10)
+	}
+	return http.StatusOK, resp
+}
+
+// loadRoomEvents returns the events of the room by ID, the ones still in the
+// room timeline are taken from there.
+func loadRoomEvents(
+	ctx context.Context, c *InternalMsgConsumer, roomID string, eventIDs []string,
+) map[string]gomatrixserverlib.ClientEvent {
+	events := make(map[string]gomatrixserverlib.ClientEvent, len(eventIDs))
+	missing := []string{}
+	for _, eventID := range eventIDs {
+		if stream := c.rmHsTimeline.GetStreamEv(ctx, roomID, eventID); stream != nil {
+			events[eventID] = *stream.GetEv()
+		} else {
+			missing = append(missing, eventID)
+		}
+	}
+	if len(missing) == 0 {
+		return events
+	}
+	evs, err := c.db.Events(ctx, missing)
+	if err != nil {
+		log.Errorf("load events of room:%s err:%v", roomID, err)
+		return events
+	}
+	for _, ev := range evs {
+		if ev.RoomID == roomID {
+			events[ev.EventID] = ev
+		}
+	}
+	return events
+}
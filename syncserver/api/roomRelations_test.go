@@ -0,0 +1,224 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/finogeeks/ligase/model/repos"
+	"github.com/finogeeks/ligase/model/syncapitypes"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	mon "github.com/finogeeks/ligase/skunkworks/monitor/go-client/monitor"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+const relationsRoomID = "!room:example.com"
+
+var relationsHitCounter = mon.GetInstance().NewLabeledCounter("syncserver_test_query_hit", []string{"target", "repo", "func"})
+
+type relationRow struct {
+	eventID, relatesTo, relType string
+	offset                      int64
+}
+
+// relationsSyncDB holds the state, the events and the relations of one room
+type relationsSyncDB struct {
+	model.SyncAPIDatabase
+	states    []gomatrixserverlib.ClientEvent
+	events    map[string]gomatrixserverlib.ClientEvent
+	relations []relationRow
+}
+
+func (d *relationsSyncDB) GetStateEventsStreamForRoom(ctx context.Context, roomID string) ([]gomatrixserverlib.ClientEvent, []int64, error) {
+	offsets := make([]int64, len(d.states))
+	for i := range d.states {
+		offsets[i] = int64(i + 1)
+	}
+	return d.states, offsets, nil
+}
+
+func (d *relationsSyncDB) GetHistoryEvents(ctx context.Context, roomID string, limit int) ([]gomatrixserverlib.ClientEvent, []int64, error) {
+	return nil, nil, nil
+}
+
+func (d *relationsSyncDB) Events(ctx context.Context, eventIDs []string) ([]gomatrixserverlib.ClientEvent, error) {
+	evs := []gomatrixserverlib.ClientEvent{}
+	for _, eventID := range eventIDs {
+		if ev, ok := d.events[eventID]; ok {
+			evs = append(evs, ev)
+		}
+	}
+	return evs, nil
+}
+
+func (d *relationsSyncDB) StreamEvents(ctx context.Context, eventIDs []string) ([]gomatrixserverlib.ClientEvent, []int64, error) {
+	evs, err := d.Events(ctx, eventIDs)
+	return evs, make([]int64, len(evs)), err
+}
+
+func (d *relationsSyncDB) SelectEventRelations(
+	ctx context.Context, relatesToID, relType, eventType, dir string, from int64, limit int,
+) ([]string, []int64, error) {
+	eventIDs := []string{}
+	offsets := []int64{}
+	for i := range d.relations {
+		row := d.relations[i]
+		if dir != "f" {
+			row = d.relations[len(d.relations)-1-i]
+		}
+		if row.relatesTo != relatesToID || (relType != "" && row.relType != relType) ||
+			(eventType != "" && d.events[row.eventID].Type != eventType) ||
+			(dir == "f" && row.offset <= from) || (dir != "f" && row.offset >= from) {
+			continue
+		}
+		if len(eventIDs) == limit {
+			break
+		}
+		eventIDs = append(eventIDs, row.eventID)
+		offsets = append(offsets, row.offset)
+	}
+	return eventIDs, offsets, nil
+}
+
+func stateEvent(evType, stateKey, sender string, ts int64, content string) gomatrixserverlib.ClientEvent {
+	return gomatrixserverlib.ClientEvent{
+		EventID: "$" + evType + stateKey, RoomID: relationsRoomID, Type: evType, StateKey: &stateKey,
+		Sender: sender, OriginServerTS: gomatrixserverlib.Timestamp(ts), Content: []byte(content),
+	}
+}
+
+// newRelationsConsumer returns a consumer for a room with joined history
+// visibility. Alice created it, bob joined at 300 and left at 500, carol
+// never joined. $early at 200 and $root at 400 are messages, $r1 to $r6
+// reply in the thread of $root, $r6 once bob has left.
+func newRelationsConsumer() *InternalMsgConsumer {
+	db := &relationsSyncDB{
+		states: []gomatrixserverlib.ClientEvent{
+			stateEvent("m.room.create", "", "@alice:example.com", 100, `{"creator":"@alice:example.com"}`),
+			stateEvent("m.room.member", "@alice:example.com", "@alice:example.com", 110, `{"membership":"join"}`),
+			stateEvent("m.room.history_visibility", "", "@alice:example.com", 120, `{"history_visibility":"joined"}`),
+			stateEvent("m.room.member", "@bob:example.com", "@bob:example.com", 300, `{"membership":"join"}`),
+			stateEvent("m.room.member", "@bob:example.com", "@bob:example.com", 500, `{"membership":"leave"}`),
+		},
+		events: map[string]gomatrixserverlib.ClientEvent{},
+	}
+	addEvent := func(eventID string, ts int64, content string) {
+		db.events[eventID] = gomatrixserverlib.ClientEvent{
+			EventID: eventID, RoomID: relationsRoomID, Type: "m.room.message", Sender: "@alice:example.com",
+			OriginServerTS: gomatrixserverlib.Timestamp(ts), Content: []byte(content),
+		}
+	}
+	addEvent("$early", 200, `{"body":"early"}`)
+	addEvent("$root", 400, `{"body":"root"}`)
+	for i, ts := range []int64{401, 402, 403, 404, 405, 600} {
+		eventID := "$r" + string(rune('1'+i))
+		addEvent(eventID, ts, `{"body":"reply","m.relates_to":{"rel_type":"m.thread","event_id":"$root"}}`)
+		db.relations = append(db.relations, relationRow{eventID, "$root", "m.thread", int64(11 + i)})
+	}
+
+	rsCurState := new(repos.RoomCurStateRepo)
+	rsTimeline := repos.NewRoomStateTimeLineRepo(4, rsCurState, 500, 10)
+	rsTimeline.SetPersist(db)
+	rsTimeline.SetMonitor(relationsHitCounter)
+	rmHsTimeline := repos.NewRoomHistoryTimeLineRepo(4, 500, 10)
+	rmHsTimeline.SetPersist(db)
+	rmHsTimeline.SetMonitor(relationsHitCounter)
+	return &InternalMsgConsumer{db: db, rsCurState: rsCurState, rsTimeline: rsTimeline, rmHsTimeline: rmHsTimeline}
+}
+
+func relationsChunk(t *testing.T, code int, resp interface{}) ([]string, string) {
+	if code != http.StatusOK {
+		t.Fatalf("code = %d %v", code, resp)
+	}
+	relations := resp.(*syncapitypes.RelationsResp)
+	eventIDs := []string{}
+	for _, ev := range relations.Chunk {
+		eventIDs = append(eventIDs, ev.EventID)
+	}
+	return eventIDs, relations.NextBatch
+}
+
+func TestGetRoomRelationsPaging(t *testing.T) {
+	c := newRelationsConsumer()
+	alice := "@alice:example.com"
+	tests := []struct {
+		name      string
+		req       external.GetRoomRelationsRequest
+		eventIDs  []string
+		nextBatch string
+	}{
+		{"latest first", external.GetRoomRelationsRequest{Limit: "2"}, []string{"$r6", "$r5"}, "15"},
+		{"from", external.GetRoomRelationsRequest{Limit: "2", From: "15"}, []string{"$r4", "$r3"}, "13"},
+		{"last page", external.GetRoomRelationsRequest{Limit: "2", From: "13"}, []string{"$r2", "$r1"}, "11"},
+		{"forward", external.GetRoomRelationsRequest{Limit: "2", Dir: "f"}, []string{"$r1", "$r2"}, "12"},
+		{"forward from", external.GetRoomRelationsRequest{Limit: "3", Dir: "f", From: "12"}, []string{"$r3", "$r4", "$r5"}, "15"},
+		{"to", external.GetRoomRelationsRequest{Limit: "10", To: "13"}, []string{"$r6", "$r5", "$r4"}, ""},
+		{"forward to", external.GetRoomRelationsRequest{Limit: "2", Dir: "f", From: "11", To: "13"}, []string{"$r2"}, ""},
+		{"rel_type", external.GetRoomRelationsRequest{RelType: "m.annotation"}, []string{}, ""},
+		{"event type", external.GetRoomRelationsRequest{Limit: "1", RelType: "m.thread", EventType: "m.room.message"}, []string{"$r6"}, "16"},
+	}
+	for _, tt := range tests {
+		tt.req.RoomID = relationsRoomID
+		tt.req.EventID = "$root"
+		code, resp := getRoomRelations(context.Background(), c, &tt.req, alice)
+		eventIDs, nextBatch := relationsChunk(t, code, resp)
+		if len(eventIDs) != len(tt.eventIDs) || nextBatch != tt.nextBatch {
+			t.Fatalf("%s: got %v next %q, want %v next %q", tt.name, eventIDs, nextBatch, tt.eventIDs, tt.nextBatch)
+		}
+		for i := range eventIDs {
+			if eventIDs[i] != tt.eventIDs[i] {
+				t.Fatalf("%s: got %v, want %v", tt.name, eventIDs, tt.eventIDs)
+			}
+		}
+	}
+
+	for _, req := range []external.GetRoomRelationsRequest{{Dir: "x"}, {Limit: "0"}, {From: "x"}, {To: "x"}} {
+		req.RoomID = relationsRoomID
+		req.EventID = "$root"
+		if code, _ := getRoomRelations(context.Background(), c, &req, alice); code != http.StatusBadRequest {
+			t.Errorf("%+v: code = %d, want 400", req, code)
+		}
+	}
+}
+
+func TestGetRoomRelationsVisibility(t *testing.T) {
+	c := newRelationsConsumer()
+	req := func(eventID string) *external.GetRoomRelationsRequest {
+		return &external.GetRoomRelationsRequest{RoomID: relationsRoomID, EventID: eventID}
+	}
+
+	if code, _ := getRoomRelations(context.Background(), c, req("$root"), "@carol:example.com"); code != http.StatusForbidden {
+		t.Errorf("not a member: code = %d, want 403", code)
+	}
+	if code, _ := getRoomRelations(context.Background(), c, &external.GetRoomRelationsRequest{RoomID: "!unknown:example.com", EventID: "$root"}, "@alice:example.com"); code != http.StatusNotFound {
+		t.Errorf("unknown room: code = %d, want 404", code)
+	}
+	if code, _ := getRoomRelations(context.Background(), c, req("$unknown"), "@alice:example.com"); code != http.StatusNotFound {
+		t.Errorf("unknown event: code = %d, want 404", code)
+	}
+
+	// bob joined after $early was sent and left before $r6
+	if code, _ := getRoomRelations(context.Background(), c, req("$early"), "@bob:example.com"); code != http.StatusNotFound {
+		t.Errorf("event before the join: code = %d, want 404", code)
+	}
+	code, resp := getRoomRelations(context.Background(), c, req("$root"), "@bob:example.com")
+	eventIDs, _ := relationsChunk(t, code, resp)
+	if len(eventIDs) != 5 || eventIDs[0] != "$r5" {
+		t.Errorf("left member: got %v, want $r5 to $r1", eventIDs)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/apiconsumer"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/syncapitypes"
+	"github.com/finogeeks/ligase/model/types"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/plugins/message/internals"
+	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/syncserver/extra"
+)
+
+func init() {
+	apiconsumer.SetAPIProcessor(ReqGetRoomThreads{})
+}
+
+type ReqGetRoomThreads struct{}
+
+func (ReqGetRoomThreads) GetRoute() string       { return "/rooms/{roomID}/threads" }
+func (ReqGetRoomThreads) GetMetricsName() string { return "room_threads" }
+func (ReqGetRoomThreads) GetMsgType() int32      { return internals.MSG_GET_ROOM_THREADS }
+func (ReqGetRoomThreads) GetAPIType() int8       { return apiconsumer.APITypeAuth }
+func (ReqGetRoomThreads) GetMethod() []string {
+	return []string{http.MethodGet, http.MethodOptions}
+}
+func (ReqGetRoomThreads) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqGetRoomThreads) GetPrefix() []string                  { return []string{"clientV1"} }
+func (ReqGetRoomThreads) NewRequest() core.Coder {
+	return new(external.GetRoomThreadsRequest)
+}
+func (ReqGetRoomThreads) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetRoomThreadsRequest)
+	if vars != nil {
+		msg.RoomID = vars["roomID"]
+	}
+	req.ParseForm()
+	values := req.URL.Query()
+	msg.Include = values.Get("include")
+	msg.From = values.Get("from")
+	msg.Limit = values.Get("limit")
+	return nil
+}
+func (ReqGetRoomThreads) NewResponse(code int) core.Coder {
+	return new(syncapitypes.ThreadsResp)
+}
+
+// Process lists the thread roots of the room, the ones with the latest reply
+// first. The batch tokens are stream positions of the latest replies.
+func (ReqGetRoomThreads) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetRoomThreadsRequest)
+	if !common.IsRelatedRequest(req.RoomID, c.Cfg.MultiInstance.Instance, c.Cfg.MultiInstance.Total, c.Cfg.MultiInstance.MultiWrite) {
+		return internals.HTTP_RESP_DISCARD, jsonerror.MsgDiscard("msg discard")
+	}
+
+	userID := device.UserID
+	roomID := req.RoomID
+	c.rsTimeline.LoadStreamStates(ctx, roomID, true)
+	rs := c.rsCurState.GetRoomState(roomID)
+	if rs == nil {
+		return http.StatusNotFound, jsonerror.NotFound("cannot find room state")
+	}
+	_, isJoin := rs.GetJoinMap().Load(userID)
+	_, isLeave := rs.GetLeaveMap().Load(userID)
+	if isJoin == false && isLeave == false {
+		return http.StatusForbidden, jsonerror.Forbidden("You aren't a member of the room and weren't previously a member of the room or just forget the room")
+	}
+
+	include := req.Include
+	if include == "" {
+		include = "all"
+	}
+	if include != "all" && include != "participated" {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("include must be all or participated")
+	}
+	limit := relationsDefaultLimit
+	if req.Limit != "" {
+		l, err := strconv.Atoi(req.Limit)
+		if err != nil || l <= 0 {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("limit must be a positive integer")
+		}
+		if l < relationsMaxLimit {
+			limit = l
+		} else {
+			limit = relationsMaxLimit
+		}
+	}
+	var from int64 = math.MaxInt64
+	if req.From != "" {
+		f, err := strconv.ParseInt(req.From, 10, 64)
+		if err != nil {
+			return http.StatusBadRequest, jsonerror.InvalidArgumentValue("Invalid from token")
+		}
+		from = f
+	}
+
+	rootIDs, offsets, participated, err := c.db.SelectRoomThreads(ctx, roomID, userID, from, limit)
+	if err != nil {
+		log.Errorf("get threads of room:%s err:%v", roomID, err)
+		return http.StatusInternalServerError, jsonerror.Unknown(err.Error())
+	}
+	roots := loadRoomEvents(ctx, c, roomID, rootIDs)
+
+	resp := &syncapitypes.ThreadsResp{Chunk: []gomatrixserverlib.ClientEvent{}}
+	for i, rootID := range rootIDs {
+		ev, ok := roots[rootID]
+		if !ok || !rs.CheckEventVisibility(userID, int64(ev.OriginServerTS)) {
+			continue
+		}
+		// the sender of the root takes part in the thread as well
+		sent := participated[i] || ev.Sender == userID
+		if include == "participated" && !sent {
+			continue
+		}
+		unsigned, err := types.UpdateRelations(ev.Unsigned, func(relations *types.EventRelations) {
+			if relations.Thread != nil {
+				relations.Thread.CurrentUserParticipated = sent
+			}
+		})
+		if err == nil {
+			ev.Unsigned = unsigned
+		}
+		extra.ExpandMessages(&ev, userID, c.rsCurState, c.displayNameRepo)
+		resp.Chunk = append(resp.Chunk, ev)
+	}
+	if len(rootIDs) == limit {
+		resp.NextBatch = strconv.FormatInt(offsets[len(offsets)-1], 10)
+	}
+	return http.StatusOK, resp
+}
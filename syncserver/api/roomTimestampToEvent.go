@@ -0,0 +1,183 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/apiconsumer"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/common/jsonerror"
+	"github.com/finogeeks/ligase/core"
+	"github.com/finogeeks/ligase/model/authtypes"
+	"github.com/finogeeks/ligase/model/repos"
+	"github.com/finogeeks/ligase/model/syncapitypes"
+	"github.com/finogeeks/ligase/plugins/message/external"
+	"github.com/finogeeks/ligase/plugins/message/internals"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+func init() {
+	apiconsumer.SetAPIProcessor(ReqGetRoomTimestampToEvent{})
+}
+
+type ReqGetRoomTimestampToEvent struct{}
+
+func (ReqGetRoomTimestampToEvent) GetRoute() string       { return "/rooms/{roomID}/timestamp_to_event" }
+func (ReqGetRoomTimestampToEvent) GetMetricsName() string { return "room_timestamp_to_event" }
+func (ReqGetRoomTimestampToEvent) GetMsgType() int32 {
+	return internals.MSG_GET_ROOM_TIMESTAMP_TO_EVENT
+}
+func (ReqGetRoomTimestampToEvent) GetAPIType() int8 { return apiconsumer.APITypeAuth }
+func (ReqGetRoomTimestampToEvent) GetMethod() []string {
+	return []string{http.MethodGet, http.MethodOptions}
+}
+func (ReqGetRoomTimestampToEvent) GetTopic(cfg *config.Dendrite) string { return getProxyRpcTopic(cfg) }
+func (ReqGetRoomTimestampToEvent) GetPrefix() []string                  { return []string{"clientV1"} }
+func (ReqGetRoomTimestampToEvent) NewRequest() core.Coder {
+	return new(external.GetRoomTimestampToEventRequest)
+}
+func (ReqGetRoomTimestampToEvent) FillRequest(coder core.Coder, req *http.Request, vars map[string]string) error {
+	msg := coder.(*external.GetRoomTimestampToEventRequest)
+	if vars != nil {
+		msg.RoomID = vars["roomID"]
+	}
+	req.ParseForm()
+	values := req.URL.Query()
+	msg.TS = values.Get("ts")
+	msg.Dir = values.Get("dir")
+	return nil
+}
+func (ReqGetRoomTimestampToEvent) NewResponse(code int) core.Coder {
+	return new(syncapitypes.TimestampToEventResp)
+}
+
+// Process finds the event of the room closest to ts, at or after it when dir
+// is "f" and at or before it when dir is "b". The servers of the room are
+// asked when the local history may not hold that event, e.g. ts is older than
+// the join of this server.
+func (ReqGetRoomTimestampToEvent) Process(ctx context.Context, consumer interface{}, msg core.Coder, device *authtypes.Device) (int, core.Coder) {
+	c := consumer.(*InternalMsgConsumer)
+	req := msg.(*external.GetRoomTimestampToEventRequest)
+	if !common.IsRelatedRequest(req.RoomID, c.Cfg.MultiInstance.Instance, c.Cfg.MultiInstance.Total, c.Cfg.MultiInstance.MultiWrite) {
+		return internals.HTTP_RESP_DISCARD, jsonerror.MsgDiscard("msg discard")
+	}
+
+	if req.TS == "" {
+		return http.StatusBadRequest, jsonerror.MissingArgument("ts is required")
+	}
+	ts, err := strconv.ParseInt(req.TS, 10, 64)
+	if err != nil || ts < 0 {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("ts must be a timestamp in milliseconds")
+	}
+	if req.Dir != "f" && req.Dir != "b" {
+		return http.StatusBadRequest, jsonerror.InvalidArgumentValue("dir must be f or b")
+	}
+
+	userID := device.UserID
+	roomID := req.RoomID
+	c.rsTimeline.LoadStreamStates(ctx, roomID, true)
+	rs := c.rsCurState.GetRoomState(roomID)
+	if rs == nil {
+		return http.StatusNotFound, jsonerror.NotFound("cannot find room state")
+	}
+	_, isJoin := rs.GetJoinMap().Load(userID)
+	_, isLeave := rs.GetLeaveMap().Load(userID)
+	if isJoin == false && isLeave == false {
+		return http.StatusForbidden, jsonerror.Forbidden("You aren't a member of the room and weren't previously a member of the room or just forget the room")
+	}
+
+	eventID, originServerTS, complete, err := localTimestampToEvent(ctx, c.db, roomID, ts, req.Dir)
+	if err != nil {
+		log.Errorf("get event of room:%s by ts:%d dir:%s err:%v", roomID, ts, req.Dir, err)
+		return http.StatusInternalServerError, jsonerror.Unknown(err.Error())
+	}
+	if !complete {
+		if remoteID, remoteTS := remoteTimestampToEvent(c, rs, roomID, ts, req.Dir); remoteID != "" {
+			eventID, originServerTS = remoteID, remoteTS
+		}
+	}
+	if eventID == "" || !rs.CheckEventVisibility(userID, originServerTS) {
+		return http.StatusNotFound, jsonerror.NotFound(fmt.Sprintf("Unable to find event from %d in direction %s", ts, req.Dir))
+	}
+	return http.StatusOK, &syncapitypes.TimestampToEventResp{
+		EventID:        eventID,
+		OriginServerTS: originServerTS,
+	}
+}
+
+// localTimestampToEvent returns the local event of the room closest to ts in
+// the direction dir. complete is false when a closer event may exist on the
+// other servers: there is no local event on that side of ts, or, going
+// forward, none before ts either, so ts is older than the local history and
+// the first local event may not be the first one after ts.
+func localTimestampToEvent(
+	ctx context.Context, db model.SyncAPIDatabase, roomID string, ts int64, dir string,
+) (eventID string, originServerTS int64, complete bool, err error) {
+	eventID, originServerTS, err = db.SelectEventByTimestamp(ctx, roomID, ts, dir)
+	if err != nil || eventID == "" {
+		return
+	}
+	if dir == "b" {
+		return eventID, originServerTS, true, nil
+	}
+	before, _, err := db.SelectEventByTimestamp(ctx, roomID, ts, "b")
+	return eventID, originServerTS, before != "", err
+}
+
+// remoteTimestampToEvent asks the other servers of the room, the one of the
+// creator first as it has the whole history. The event it returns may not be
+// known locally yet.
+func remoteTimestampToEvent(
+	c *InternalMsgConsumer, rs *repos.RoomState, roomID string, ts int64, dir string,
+) (string, int64) {
+	var domains []string
+	seen := make(map[string]bool)
+	rs.GetJoinMap().Range(func(key, value interface{}) bool {
+		domain, _ := common.DomainFromID(key.(string))
+		if !seen[domain] && !common.CheckValidDomain(domain, c.Cfg.Matrix.ServerName) {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+		return true
+	})
+	sort.Strings(domains)
+	if creatorDomain, _ := common.DomainFromID(rs.GetCreator()); seen[creatorDomain] {
+		for i, domain := range domains {
+			if domain == creatorDomain {
+				domains[0], domains[i] = domains[i], domains[0]
+				break
+			}
+		}
+	}
+
+	for _, domain := range domains {
+		resp, err := c.federation.TimestampToEvent(domain, roomID, ts, dir)
+		if err != nil {
+			log.Warnf("get event of room:%s by ts:%d dir:%s from %s err:%v", roomID, ts, dir, domain, err)
+			continue
+		}
+		if resp.EventID != "" {
+			return resp.EventID, int64(resp.OriginServerTS)
+		}
+	}
+	return "", 0
+}
@@ -0,0 +1,78 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/finogeeks/ligase/storage/model"
+)
+
+type tsEvent struct {
+	eventID string
+	ts      int64
+}
+
+// timestampSyncDB holds the local history of a room, sorted by timestamp
+type timestampSyncDB struct {
+	model.SyncAPIDatabase
+	events []tsEvent
+}
+
+func (d *timestampSyncDB) SelectEventByTimestamp(ctx context.Context, roomID string, ts int64, dir string) (string, int64, error) {
+	if dir == "f" {
+		for _, ev := range d.events {
+			if ev.ts >= ts {
+				return ev.eventID, ev.ts, nil
+			}
+		}
+		return "", 0, nil
+	}
+	for i := len(d.events) - 1; i >= 0; i-- {
+		if d.events[i].ts <= ts {
+			return d.events[i].eventID, d.events[i].ts, nil
+		}
+	}
+	return "", 0, nil
+}
+
+func TestLocalTimestampToEvent(t *testing.T) {
+	db := &timestampSyncDB{events: []tsEvent{{"$join", 100}, {"$msg", 200}, {"$last", 300}}}
+	tests := []struct {
+		name     string
+		ts       int64
+		dir      string
+		eventID  string
+		complete bool
+	}{
+		{"forward", 150, "f", "$msg", true},
+		{"forward exact", 200, "f", "$msg", true},
+		{"forward before the join", 50, "f", "$join", false},
+		{"forward after the last", 400, "f", "", false},
+		{"backward", 250, "b", "$msg", true},
+		{"backward after the last", 400, "b", "$last", true},
+		{"backward before the join", 50, "b", "", false},
+	}
+	for _, tt := range tests {
+		eventID, _, complete, err := localTimestampToEvent(context.Background(), db, "!room:example.com", tt.ts, tt.dir)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if eventID != tt.eventID || complete != tt.complete {
+			t.Errorf("%s: got %q complete %t, want %q complete %t", tt.name, eventID, complete, tt.eventID, tt.complete)
+		}
+	}
+}
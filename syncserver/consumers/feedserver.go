@@ -218,7 +218,9 @@ func (s *RoomEventFeedConsumer) processRedactEv(ctx context.Context, ev *gomatri
 			return
 		}
 	}
+	origEv := redactEv
 	unsigned := types.RedactUnsigned{}
+	unsigned.Relations = types.GetRelations(redactEv.Unsigned)
 	if ev.Type == "m.room.redaction" {
 		content := map[string]interface{}{}
 		empty, _ := json.Marshal(content)
@@ -239,6 +241,28 @@ func (s *RoomEventFeedConsumer) processRedactEv(ctx context.Context, ev *gomatri
 	if stream != nil {
 		stream.Ev = &redactEv //更新timeline
 	}
+
+	if rel := types.GetRelatesTo(origEv.Content); rel != nil && ev.Type == "m.room.redaction" {
+		s.processRelationEv(ctx, &origEv, rel, true)
+	}
+}
+
+// processRelationEv updates the m.relations in the unsigned of the event ev
+// relates to in the timeline, removed is true when ev has been redacted. The
+// syncwriter updates the event in db.
+func (s *RoomEventFeedConsumer) processRelationEv(ctx context.Context, ev *gomatrixserverlib.ClientEvent, rel *types.RelatesTo, removed bool) {
+	stream := s.roomHistoryTimeLine.GetStreamEv(ctx, ev.RoomID, rel.EventID)
+	if stream == nil {
+		return
+	}
+	parentEv := *stream.Ev
+	unsigned, err := common.UpdateEventRelations(ctx, s.db, &parentEv, ev, rel, removed)
+	if err != nil {
+		log.Errorf("processRelationEv update relations of ev:%s err:%v", rel.EventID, err)
+		return
+	}
+	parentEv.Unsigned = unsigned
+	stream.Ev = &parentEv
 }
 
 func (s *RoomEventFeedConsumer) onNewRoomEvent(
@@ -273,6 +297,8 @@ func (s *RoomEventFeedConsumer) onNewRoomEvent(
 		ev, _ = s.processStateEv(&ev)
 	} else if ev.Type == "m.room.redaction" || ev.Type == "m.room.update" {
 		s.processRedactEv(ctx, &ev)
+	} else if rel := types.GetRelatesTo(ev.Content); rel != nil {
+		s.processRelationEv(ctx, &ev, rel, false)
 	}
 	log.Infof("feedserver onNewRoomEvent update unsigned roomID:%s eventID:%s sender:%s type:%s eventoffset:%d", ev.RoomID, ev.EventID, ev.Sender, ev.Type, ev.EventOffset)
 	transId := ""
@@ -487,6 +513,8 @@ func (s *RoomEventFeedConsumer) onBackFillEvent(
 		s.roomStateTimeLine.AddBackfillEv(ctx, &ev, ev.EventOffset, true)
 	} else if ev.Type == "m.room.redaction" || ev.Type == "m.room.update" {
 		s.processRedactEv(ctx, &ev)
+	} else if rel := types.GetRelatesTo(ev.Content); rel != nil {
+		s.processRelationEv(ctx, &ev, rel, false)
 	}
 
 	return nil
@@ -155,6 +155,10 @@ func (s *SyncServer) processSync(ctx context.Context, req *syncapitypes.SyncServ
 	}()
 	//bytes, _ := json.Marshal(*req)
 	log.Infof("SyncServer.processSync received request traceid:%s slot:%d rslot:%d user %s device %s", req.TraceID, req.Slot, req.RSlot, req.UserID, req.DeviceID)
+	if req.RequestType == "visibility" {
+		s.responseVisibility(ctx, req)
+		return
+	}
 	if req.IsFullSync {
 		s.fullSyncLoading(ctx, req)
 	} else {
@@ -281,6 +285,33 @@ func (s *SyncServer) responseLoad(ctx context.Context, req *syncapitypes.SyncSer
 	s.rpcClient.PubObj(req.Reply, res)
 }
 
+// responseVisibility replies the visibility ranges of the user in the joined
+// rooms of req, the rooms the user has never been a member of are left out.
+func (s *SyncServer) responseVisibility(ctx context.Context, req *syncapitypes.SyncServerRequest) {
+	resp := syncapitypes.SyncServerResponse{
+		Visibility: make(map[string][]syncapitypes.VisibilityRange),
+	}
+	for _, roomID := range req.JoinedRooms {
+		s.rsTimeline.LoadStreamStates(ctx, roomID, true)
+		rs := s.rsCurState.GetRoomState(roomID)
+		if rs == nil {
+			continue
+		}
+		_, isJoin := rs.GetJoinMap().Load(req.UserID)
+		_, isLeave := rs.GetLeaveMap().Load(req.UserID)
+		if isJoin == false && isLeave == false {
+			continue
+		}
+		ranges := []syncapitypes.VisibilityRange{}
+		for _, item := range rs.GetEventVisibility(req.UserID) {
+			ranges = append(ranges, syncapitypes.VisibilityRange{Start: item.Start, End: item.End})
+		}
+		resp.Visibility[roomID] = ranges
+	}
+	resp.AllLoaded = true
+	s.responseSync(req, &resp)
+}
+
 func (s *SyncServer) responseSync(req *syncapitypes.SyncServerRequest, resp *syncapitypes.SyncServerResponse) {
 	device := authtypes.Device{
 		UserID:  req.UserID,
@@ -687,7 +718,7 @@ func (s *SyncServer) buildRoomJoinResp(ctx context.Context, req *syncapitypes.Sy
 		reqStart = -1
 	}
 
-	visibilityTime := s.settings.GetMessageVisilibityTime()
+	visibilityTime := common.MessageVisibilityTime(s.settings, s.cfg, rs.GetRetention())
 	nowTs := time.Now().Unix()
 
 	evRecords := make(map[string]int)
@@ -976,7 +1007,7 @@ func (s *SyncServer) buildRoomLeaveResp(ctx context.Context, req *syncapitypes.S
 	msgEvent = []gomatrixserverlib.ClientEvent{}
 	minStream := s.roomHistory.GetRoomMinStream(ctx, roomID)
 
-	visibilityTime := s.settings.GetMessageVisilibityTime()
+	visibilityTime := common.MessageVisibilityTime(s.settings, s.cfg, rs.GetRetention())
 	nowTs := time.Now().Unix()
 
 	for i, feed := range feeds {
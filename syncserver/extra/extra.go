@@ -18,6 +18,7 @@ import (
 	"github.com/finogeeks/ligase/model/authtypes"
 	"github.com/finogeeks/ligase/model/repos"
 	"github.com/finogeeks/ligase/model/syncapitypes"
+	"github.com/finogeeks/ligase/model/types"
 	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
 )
 
@@ -31,4 +32,5 @@ func ExpandMessages(event *gomatrixserverlib.ClientEvent, userID string, repo *r
 		IsHuman: true,
 	}
 	ExpandEventHint(event, device, repo, displayNameRepo)
+	event.Unsigned = types.RelationsForUser(event.Unsigned, userID, event.Sender)
 }
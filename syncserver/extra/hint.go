@@ -22,6 +22,7 @@ import (
 	"github.com/finogeeks/ligase/model/authtypes"
 	"github.com/finogeeks/ligase/model/repos"
 	"github.com/finogeeks/ligase/model/syncapitypes"
+	"github.com/finogeeks/ligase/model/types"
 	"github.com/finogeeks/ligase/skunkworks/gomatrixserverlib"
 	jsoniter "github.com/json-iterator/go"
 )
@@ -845,6 +846,7 @@ func ExpandHints(repo *repos.RoomCurStateRepo, device *authtypes.Device, display
 			// }
 			doExtra(repo, device, roomID, displayNameRepo, &e, prevStatesJoin)
 			j.Timeline.Events[i].Hint = e.Hint
+			j.Timeline.Events[i].Unsigned = types.RelationsForUser(e.Unsigned, device.UserID, e.Sender)
 		}
 	}
 
@@ -861,6 +863,7 @@ func ExpandHints(repo *repos.RoomCurStateRepo, device *authtypes.Device, display
 		for i, e := range l.Timeline.Events {
 			doExtra(repo, device, roomID, displayNameRepo, &e, prevStatesInvite)
 			l.Timeline.Events[i].Hint = e.Hint
+			l.Timeline.Events[i].Unsigned = types.RelationsForUser(e.Unsigned, device.UserID, e.Sender)
 		}
 	}
 	/*for i, e := range res.AccountData.Events {
@@ -0,0 +1,87 @@
+// Copyright (C) 2020 Finogeeks Co., Ltd
+//
+// This program is free software: you can redistribute it and/or  modify
+// it under the terms of the GNU Affero General Public License, version 3,
+// as published by the Free Software Foundation.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+
+	"github.com/finogeeks/ligase/common"
+	"github.com/finogeeks/ligase/common/config"
+	"github.com/finogeeks/ligase/model/repos"
+	"github.com/finogeeks/ligase/model/types"
+	"github.com/finogeeks/ligase/skunkworks/log"
+	"github.com/nats-io/go-nats"
+)
+
+type RoomHistoryPurgeRpcConsumer struct {
+	rpcClient   *common.RpcClient
+	roomHistory *repos.RoomHistoryTimeLineRepo
+	rsTimeline  *repos.RoomStateTimeLineRepo
+	chanSize    uint32
+	msgChan     []chan common.ContextMsg
+	cfg         *config.Dendrite
+}
+
+func NewRoomHistoryPurgeRpcConsumer(
+	roomHistory *repos.RoomHistoryTimeLineRepo,
+	rsTimeline *repos.RoomStateTimeLineRepo,
+	rpcClient *common.RpcClient,
+	cfg *config.Dendrite,
+) *RoomHistoryPurgeRpcConsumer {
+	s := &RoomHistoryPurgeRpcConsumer{
+		roomHistory: roomHistory,
+		rsTimeline:  rsTimeline,
+		rpcClient:   rpcClient,
+		chanSize:    4,
+		cfg:         cfg,
+	}
+	return s
+}
+
+func (s *RoomHistoryPurgeRpcConsumer) GetTopic() string {
+	return types.RoomHistoryPurgeTopicDef
+}
+
+func (s *RoomHistoryPurgeRpcConsumer) cb(ctx context.Context, msg *nats.Msg) {
+	var result types.RoomHistoryPurge
+	if err := json.Unmarshal(msg.Data, &result); err != nil {
+		log.Errorf("rpc room history purge cb error %v", err)
+		return
+	}
+	if common.IsRelatedRequest(result.RoomID, s.cfg.MultiInstance.Instance, s.cfg.MultiInstance.Total, s.cfg.MultiInstance.MultiWrite) {
+		idx := common.CalcStringHashCode(result.RoomID) % s.chanSize
+		s.msgChan[idx] <- common.ContextMsg{Ctx: ctx, Msg: &result}
+	}
+}
+
+func (s *RoomHistoryPurgeRpcConsumer) startWorker(msgChan chan common.ContextMsg) {
+	for msg := range msgChan {
+		data := msg.Msg.(*types.RoomHistoryPurge)
+		log.Infof("room:%s history before %d purged", data.RoomID, data.BeforeTS)
+		s.roomHistory.RemoveHistory(data.RoomID)
+		s.rsTimeline.RemoveStateStreams(data.RoomID)
+	}
+}
+
+func (s *RoomHistoryPurgeRpcConsumer) Start() error {
+	s.msgChan = make([]chan common.ContextMsg, s.chanSize)
+	for i := uint32(0); i < s.chanSize; i++ {
+		s.msgChan[i] = make(chan common.ContextMsg, 512)
+		go s.startWorker(s.msgChan[i])
+	}
+
+	s.rpcClient.ReplyWithContext(s.GetTopic(), s.cb)
+	return nil
+}
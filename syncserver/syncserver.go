@@ -167,6 +167,11 @@ func SetupSyncServerComponent(
 		log.Panicf("failed to start sync unread rpc consumer err:%v", err)
 	}
 
+	purgeRpcConsumer := rpc.NewRoomHistoryPurgeRpcConsumer(roomHistory, rsTimeline, rpcClient, base.Cfg)
+	if err := purgeRpcConsumer.Start(); err != nil {
+		log.Panicf("failed to start sync room history purge rpc consumer err:%v", err)
+	}
+
 	log.Infof("instance:%d,syncserver total:%d", base.Cfg.MultiInstance.Instance, base.Cfg.MultiInstance.SyncServerTotal)
 	apiConsumer := api.NewInternalMsgConsumer(*base.Cfg, rpcClient, idg, syncDB, rsCurState, rsTimeline, roomHistory, displayNameRepo, receiptConsumer, settings, cacheIn)
 	apiConsumer.Start()
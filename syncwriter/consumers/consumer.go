@@ -204,7 +204,9 @@ func (s *RoomEventConsumer) processRedactEv(ctx context.Context, ev *gomatrixser
 		}
 	}
 
+	origEv := redactEv
 	unsigned := types.RedactUnsigned{}
+	unsigned.Relations = types.GetRelations(redactEv.Unsigned)
 	if ev.Type == "m.room.redaction" {
 		content := map[string]interface{}{}
 		empty, _ := json.Marshal(content)
@@ -229,6 +231,77 @@ func (s *RoomEventConsumer) processRedactEv(ctx context.Context, ev *gomatrixser
 	} else {
 		log.Infof("processRedactEv update redact:%s ev:%v to db succ", ev.Redacts, redactEv)
 	}
+
+	if ev.Type == "m.room.redaction" {
+		if err := s.db.DeleteEventSearch(ctx, ev.Redacts); err != nil {
+			log.Errorf("processRedactEv delete search index of redact:%s err:%v", ev.Redacts, err)
+		}
+	} else if stream != nil {
+		s.writeSearchEv(ctx, &redactEv, stream.GetOffset())
+	} else if _, offsets, err := s.db.StreamEvents(ctx, []string{ev.Redacts}); err == nil && len(offsets) > 0 {
+		s.writeSearchEv(ctx, &redactEv, offsets[0])
+	}
+
+	if rel := types.GetRelatesTo(origEv.Content); rel != nil && ev.Type == "m.room.redaction" {
+		if err := s.db.DeleteEventRelation(ctx, origEv.EventID); err != nil {
+			log.Errorf("processRedactEv delete relation of redact:%s err:%v", ev.Redacts, err)
+		}
+		s.processRelationEv(ctx, &origEv, rel, true)
+	}
+}
+
+// processRelationEv updates the m.relations in the unsigned of the event ev
+// relates to, removed is true when ev has been redacted.
+func (s *RoomEventConsumer) processRelationEv(ctx context.Context, ev *gomatrixserverlib.ClientEvent, rel *types.RelatesTo, removed bool) {
+	var parentEv gomatrixserverlib.ClientEvent
+
+	stream := s.roomHistoryTimeLine.GetStreamEv(ctx, ev.RoomID, rel.EventID)
+	if stream != nil {
+		parentEv = *stream.Ev
+	} else {
+		evs, err := s.db.Events(ctx, []string{rel.EventID})
+		if err != nil || len(evs) == 0 {
+			log.Warnf("processRelationEv cannot found ev:%s related by:%s, err:%v", rel.EventID, ev.EventID, err)
+			return
+		}
+		parentEv = evs[0]
+	}
+
+	unsigned, err := common.UpdateEventRelations(ctx, s.db, &parentEv, ev, rel, removed)
+	if err != nil {
+		log.Errorf("processRelationEv update relations of ev:%s err:%v", rel.EventID, err)
+		return
+	}
+	parentEv.Unsigned = unsigned
+	if stream != nil {
+		stream.Ev = &parentEv
+	}
+	if err := s.db.UpdateEvent(ctx, parentEv, rel.EventID, parentEv.Type, ev.RoomID); err != nil {
+		log.Errorf("processRelationEv update ev:%s to db err:%v", rel.EventID, err)
+	}
+}
+
+// writeRelationEv records ev, written at offset, if it relates to another event
+func (s *RoomEventConsumer) writeRelationEv(ctx context.Context, ev *gomatrixserverlib.ClientEvent, offset int64) {
+	if common.IsStateClientEv(ev) {
+		return
+	}
+	rel := types.GetRelatesTo(ev.Content)
+	if rel == nil {
+		return
+	}
+	if err := s.db.InsertEventRelation(ctx, ev.EventID, ev.RoomID, rel.EventID, rel.RelType, ev.Type, ev.Sender, rel.Key, offset); err != nil {
+		log.Errorf("writeRelationEv insert relation of ev:%s err:%v", ev.EventID, err)
+		return
+	}
+	s.processRelationEv(ctx, ev, rel, false)
+}
+
+// writeSearchEv indexes the text of ev, written at offset, for /search.
+func (s *RoomEventConsumer) writeSearchEv(ctx context.Context, ev *gomatrixserverlib.ClientEvent, offset int64) {
+	if err := common.IndexSearchEvent(ctx, s.db, ev, offset, s.cfg.Search.Tokenizer); err != nil {
+		log.Errorf("writeSearchEv index ev:%s err:%v", ev.EventID, err)
+	}
 }
 
 func (s *RoomEventConsumer) onNewRoomEvent(
@@ -280,6 +353,9 @@ func (s *RoomEventConsumer) onNewRoomEvent(
 		return err
 	}
 
+	s.writeRelationEv(ctx, &ev, ev.EventOffset)
+	s.writeSearchEv(ctx, &ev, ev.EventOffset)
+
 	membership := ""
 	if common.IsStateClientEv(&ev) {
 		if ev.Type == "m.room.member" {
@@ -323,6 +399,8 @@ func (s *RoomEventConsumer) onBackFillEvent(
 		log.Errorw("syncwriter: write event failure", log.KeysAndValues{"event_id", string(ev.EventID), "error", err, "add", msg.AddsStateEventIDs, "del", msg.RemovesStateEventIDs})
 		return err
 	}
+	s.writeRelationEv(ctx, &ev, -ev.EventOffset)
+	s.writeSearchEv(ctx, &ev, -ev.EventOffset)
 
 	return nil
 }